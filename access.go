@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Principal — пользователь, от имени которого выполняются запросы.
+// Администратор видит и изменяет всех клиентов независимо от владельца.
+type Principal struct {
+	ID    string
+	Admin bool
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с пользователем, от имени которого
+// выполняются запросы.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext возвращает пользователя из контекста.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+
+	return p, ok
+}
+
+// WithOwnerRestriction ограничивает чтение и изменение клиентов строками,
+// владельцем которых является пользователь из контекста. Чужие и
+// несуществующие клиенты неразличимы: в обоих случаях возвращается
+// ErrClientNotFound. Без пользователя в контексте доступ запрещён полностью.
+func WithOwnerRestriction() Option {
+	return func(r *Repository) {
+		r.ownerRestricted = true
+	}
+}
+
+// ownerScope возвращает условие для WHERE, ограничивающее выборку
+// клиентами пользователя из контекста, и его аргументы.
+func (r *Repository) ownerScope(ctx context.Context) (string, []any) {
+	if !r.ownerRestricted {
+		return "", nil
+	}
+
+	p, ok := PrincipalFromContext(ctx)
+	switch {
+	case !ok || p.ID == "" && !p.Admin:
+		return " AND 0", nil
+	case p.Admin:
+		return "", nil
+	default:
+		return " AND owner_id = :owner_id", []any{sql.Named("owner_id", p.ID)}
+	}
+}
+
+// ownerFilter — как ownerScope, но для запросов clientsdb: restricted
+// сообщает, что пользователю доступны только клиенты владельца owner, а
+// ok — false, если ему не доступен ни один клиент.
+func (r *Repository) ownerFilter(ctx context.Context) (owner string, restricted, ok bool) {
+	if !r.ownerRestricted {
+		return "", false, true
+	}
+
+	p, found := PrincipalFromContext(ctx)
+	switch {
+	case !found || p.ID == "" && !p.Admin:
+		return "", false, false
+	case p.Admin:
+		return "", false, true
+	default:
+		return p.ID, true, true
+	}
+}
+
+// clientExists возвращает ErrClientNotFound, если клиента с ID id нет, он
+// мягко удалён или недоступен пользователю из контекста. Поля клиента
+// не читаются.
+func (r *Repository) clientExists(ctx context.Context, q querier, id int) error {
+	return r.clientStored(ctx, q, id, notDeleted)
+}
+
+// clientStored — как clientExists, но мягко удалённые клиенты исключаются,
+// только если это указано в cond.
+func (r *Repository) clientStored(ctx context.Context, q querier, id int, cond string) error {
+	scope, args := r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	var exists int
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM clients WHERE id = :id"+cond+scope, args...).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrClientNotFound
+	}
+
+	return nil
+}
+
+// clientRecorded возвращает ErrClientNotFound, если в режиме ограничения
+// по владельцу клиент id не принадлежит пользователю из контекста ни как
+// текущий, ни как архивный, ни по прежним версиям в clients_history. Так
+// проверяется доступ к данным, которые переживают клиента: журналу аудита
+// и квитанциям об удалении. Следы полностью удалённого клиента
+// (EraseClient) доступны только администратору. Без ограничения и для
+// администратора проверка ничего не запрашивает.
+func (r *Repository) clientRecorded(ctx context.Context, q querier, id int) error {
+	scope, args := r.ownerScope(ctx)
+	if scope == "" {
+		return nil
+	}
+	args = append(args, sql.Named("id", id))
+
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM clients WHERE id = :id`+scope+`)
+		OR EXISTS (SELECT 1 FROM clients_archive WHERE id = :id`+scope+`)
+		OR EXISTS (SELECT 1 FROM clients_history WHERE client_id = :id`+scope+`)`, args...).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrClientNotFound
+	}
+
+	return nil
+}
+
+// requireAdmin возвращает ErrAccessDenied, если в режиме ограничения по
+// владельцу пользователь из контекста не администратор: так защищены
+// операции над данными всех клиентов сразу.
+func (r *Repository) requireAdmin(ctx context.Context) error {
+	if !r.ownerRestricted {
+		return nil
+	}
+	if p, ok := PrincipalFromContext(ctx); !ok || !p.Admin {
+		return ErrAccessDenied
+	}
+
+	return nil
+}
+
+// ownerFor возвращает владельца для нового клиента: в режиме ограничения
+// обычный пользователь может создавать клиентов только для себя.
+func (r *Repository) ownerFor(ctx context.Context, client Client) (string, error) {
+	if !r.ownerRestricted {
+		return client.OwnerID, nil
+	}
+
+	p, ok := PrincipalFromContext(ctx)
+	switch {
+	case !ok || p.ID == "" && !p.Admin:
+		return "", ErrAccessDenied
+	case p.Admin:
+		return client.OwnerID, nil
+	default:
+		return p.ID, nil
+	}
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что в режиме ограничения по владельцу чужие клиенты
+// неотличимы от несуществующих, а администратор видит всех
+func Test_OwnerRestriction_NonOwnerGetsNotFound(t *testing.T) {
+	db, repo := setupTestDB(t, WithOwnerRestriction())
+
+	owner := WithPrincipal(context.Background(), Principal{ID: "manager-1"})
+	stranger := WithPrincipal(context.Background(), Principal{ID: "manager-2"})
+	admin := WithPrincipal(context.Background(), Principal{ID: "root", Admin: true})
+
+	cl := Client{
+		FIO:      "Test",
+		Login:    "Test",
+		Birthday: "19700101",
+		Email:    "mail@mail.com",
+		// Обычный пользователь не может назначить клиенту другого владельца
+		OwnerID: "manager-2",
+	}
+	var err error
+	cl.ID, err = repo.Insert(owner, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	client, err := repo.Select(owner, cl.ID)
+	require.NoError(t, err, "owner should see own client")
+	assert.Equal(t, "manager-1", client.OwnerID, "owner should be taken from the principal")
+
+	t.Run("NonOwner", func(t *testing.T) {
+		_, err := repo.Select(stranger, cl.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+
+		// Ошибка для чужого клиента совпадает с ошибкой для несуществующего
+		// с точностью до запрошенного ID
+		_, errMissing := repo.Select(stranger, -1)
+		assert.Equal(t, errors.Unwrap(errMissing), errors.Unwrap(err), "non-owner must not learn that the client exists")
+		assert.EqualError(t, err, fmt.Sprintf("select client %d: client not found", cl.ID))
+
+		require.ErrorIs(t, repo.Update(stranger, client), ErrClientNotFound)
+		require.ErrorIs(t, repo.Delete(stranger, cl.ID), ErrClientNotFound)
+		_, err = repo.EraseClient(stranger, cl.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+
+		_, err = repo.AuditLog(stranger, cl.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+		_, err = repo.ErasureReceipts(stranger, cl.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+
+		err = repo.ForEach(stranger, func(c Client) error {
+			assert.NotEqual(t, cl.ID, c.ID, "non-owner should not list foreign clients")
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("NoPrincipal", func(t *testing.T) {
+		_, err := repo.Select(context.Background(), cl.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+
+		_, err = repo.Insert(context.Background(), cl)
+		require.ErrorIs(t, err, ErrAccessDenied)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		client, err := repo.Select(admin, cl.ID)
+		require.NoError(t, err, "admin should bypass owner restriction")
+		assert.Equal(t, cl.ID, client.ID)
+
+		client.FIO = "Updated"
+		require.NoError(t, repo.Update(admin, client))
+	})
+
+	t.Run("Records", func(t *testing.T) {
+		entries, err := repo.AuditLog(owner, cl.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, entries)
+		_, err = repo.AuditLog(context.Background(), cl.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+
+		// После удаления журнал остаётся доступен владельцу по прежней
+		// версии клиента
+		deleted, err := repo.Insert(owner, cl)
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(owner, deleted))
+		_, err = repo.AuditLog(owner, deleted)
+		require.NoError(t, err)
+		_, err = repo.AuditLog(stranger, deleted)
+		require.ErrorIs(t, err, ErrClientNotFound)
+
+		// Квитанции полностью удалённого клиента доступны только
+		// администратору
+		erased, err := repo.Insert(owner, cl)
+		require.NoError(t, err)
+		_, err = repo.EraseClient(owner, erased)
+		require.NoError(t, err)
+		_, err = repo.ErasureReceipts(owner, erased)
+		require.ErrorIs(t, err, ErrClientNotFound)
+		receipts, err := repo.ErasureReceipts(admin, erased)
+		require.NoError(t, err)
+		assert.Len(t, receipts, 1)
+	})
+
+	// Без режима ограничения владелец не учитывается
+	_, err = NewRepository(db).Select(stranger, cl.ID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MonthlyCount — число клиентов, добавленных за календарный месяц.
+type MonthlyCount struct {
+	// Month — месяц в формате ГГГГ-ММ (UTC).
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+// BucketCount — число клиентов с одним значением признака распределения.
+type BucketCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// ClientStats — распределения клиентов для панелей мониторинга.
+type ClientStats struct {
+	Total int `json:"total"`
+	// ByBirthDecade — число клиентов по десятилетиям рождения («1980s») в
+	// порядке возрастания; клиенты без даты рождения не учитываются.
+	ByBirthDecade []BucketCount `json:"by_birth_decade"`
+	// ByEmailDomain — число клиентов по домену email в нижнем регистре по
+	// убыванию числа, при равном числе — по домену; клиенты без email или
+	// с email без «@» не учитываются.
+	ByEmailDomain []BucketCount `json:"by_email_domain"`
+	// ByStatus — число клиентов по статусу в порядке статуса.
+	ByStatus []BucketCount `json:"by_status"`
+}
+
+// clientStatsGroups — запросы распределений ClientStats: выражение
+// признака, условие учёта клиента и порядок групп.
+var clientStatsGroups = []struct {
+	key, cond, order string
+	dst              func(*ClientStats) *[]BucketCount
+}{
+	{
+		key:   "substr(birthday, 1, 3) || '0s'",
+		cond:  " AND birthday != ''",
+		order: "key",
+		dst:   func(s *ClientStats) *[]BucketCount { return &s.ByBirthDecade },
+	},
+	{
+		key:   "lower(substr(email, instr(email, '@') + 1))",
+		cond:  " AND instr(email, '@') > 0",
+		order: "count DESC, key",
+		dst:   func(s *ClientStats) *[]BucketCount { return &s.ByEmailDomain },
+	},
+	{
+		key:   "status",
+		order: "key",
+		dst:   func(s *ClientStats) *[]BucketCount { return &s.ByStatus },
+	},
+}
+
+// ClientStats возвращает распределения клиентов, подходящих под условие
+// f, по десятилетиям рождения, доменам email и статусам. Распределения
+// вычисляются запросами GROUP BY, поэтому при включённом шифровании, когда
+// email и дата рождения хранятся зашифрованными, запрос отклоняется с
+// ErrValidation. Мягко удалённые клиенты не учитываются, клиенты читаются
+// с ограничением по владельцу (см. WithOwnerRestriction).
+func (r *Repository) ClientStats(ctx context.Context, f Filter) (_ ClientStats, err error) {
+	ctx, end := r.startOperation(ctx, "client_stats")
+	defer end(&err)
+
+	if r.cipher != nil {
+		return ClientStats{}, fmt.Errorf("%w: email and birthday are encrypted and cannot be grouped by query", ErrValidation)
+	}
+	cond, condArgs, err := r.filterCond(f)
+	if err != nil {
+		return ClientStats{}, err
+	}
+	scope, args := r.ownerScope(ctx)
+	args = append(args, condArgs...)
+	where := " WHERE 1" + notDeleted + scope + cond
+
+	var stats ClientStats
+	if err := r.conn().QueryRowContext(ctx, "SELECT COUNT(*) FROM clients"+where, args...).Scan(&stats.Total); err != nil {
+		return ClientStats{}, err
+	}
+	for _, g := range clientStatsGroups {
+		counts, err := queryBucketCounts(ctx, r.conn(), "SELECT "+g.key+" AS key, COUNT(*) AS count FROM clients"+where+g.cond+
+			" GROUP BY key ORDER BY "+g.order, args...)
+		if err != nil {
+			return ClientStats{}, err
+		}
+		*g.dst(&stats) = counts
+	}
+
+	return stats, nil
+}
+
+func queryBucketCounts(ctx context.Context, q querier, query string, args ...any) ([]BucketCount, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Пустые распределения выводятся как [], а не null
+	counts := []BucketCount{}
+	for rows.Next() {
+		var c BucketCount
+		if err := rows.Scan(&c.Key, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+// Причины, по которым клиенты считаются возможными дубликатами.
+const (
+	DuplicateEmail       = "email"
+	DuplicateFIOBirthday = "fio_birthday"
+)
+
+// DuplicateGroup — клиенты, которые, возможно, являются одним человеком.
+type DuplicateGroup struct {
+	// Reason — совпадающие данные: DuplicateEmail или DuplicateFIOBirthday.
+	Reason string `json:"reason"`
+	// Key — совпадающее значение после нормализации.
+	Key       string `json:"key"`
+	ClientIDs []int  `json:"client_ids"`
+}
+
+// ClientsAddedPerMonth возвращает число добавленных клиентов по месяцам
+// в порядке возрастания. Добавления считаются по журналу аудита, поэтому
+// клиенты, записанные в обход репозитория, и стёртые клиенты не учитываются;
+// удалённые клиенты учитываются в месяце добавления.
+func (r *Repository) ClientsAddedPerMonth(ctx context.Context) (_ []MonthlyCount, err error) {
+	ctx, end := r.startOperation(ctx, "clients_added_per_month")
+	defer end(&err)
+
+	rows, err := r.conn().QueryContext(ctx, `SELECT substr(occurred_at, 1, 7) AS month, COUNT(*)
+		FROM audit_log WHERE operation = :operation
+		GROUP BY month ORDER BY month`,
+		sql.Named("operation", string(AuditInsert)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []MonthlyCount
+	for rows.Next() {
+		var c MonthlyCount
+		if err := rows.Scan(&c.Month, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+// ReportPeriod — длительность периода отчёта ClientsCreated.
+type ReportPeriod string
+
+const (
+	PeriodDay ReportPeriod = "day"
+	// PeriodWeek — неделя с понедельника по воскресенье.
+	PeriodWeek  ReportPeriod = "week"
+	PeriodMonth ReportPeriod = "month"
+)
+
+// ReportDateLayout — формат дат границ и начал периодов отчёта
+// ClientsCreated.
+const ReportDateLayout = "2006-01-02"
+
+// MaxReportPeriods — наибольшее число периодов в отчёте ClientsCreated.
+const MaxReportPeriods = 3660
+
+// PeriodCount — число клиентов, добавленных за период.
+type PeriodCount struct {
+	// Start — первый день периода в формате ReportDateLayout (UTC).
+	Start string `json:"start"`
+	Count int    `json:"count"`
+}
+
+// ParseReportPeriod разбирает название периода отчёта.
+func ParseReportPeriod(s string) (ReportPeriod, error) {
+	switch p := ReportPeriod(s); p {
+	case PeriodDay, PeriodWeek, PeriodMonth:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown report period %q, want day, week or month", ErrValidation, s)
+}
+
+// parseReportDate разбирает дату name границы отчёта в формате
+// ReportDateLayout как полночь UTC.
+func parseReportDate(name, s string) (time.Time, error) {
+	t, err := time.Parse(ReportDateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: report %s %q is not a YYYY-MM-DD date", ErrValidation, name, s)
+	}
+
+	return t, nil
+}
+
+// start возвращает начало периода p, содержащего t, в UTC.
+func (p ReportPeriod) start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case PeriodMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+
+	return day
+}
+
+// next возвращает начало периода p, следующего за периодом с началом start.
+func (p ReportPeriod) next(start time.Time) time.Time {
+	switch p {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+// key возвращает выражение SQL, переводящее created_at в начало периода p
+// в формате ReportDateLayout.
+func (p ReportPeriod) key() string {
+	switch p {
+	case PeriodWeek:
+		// «weekday 0» переводит дату на ближайшее воскресенье, не раньше её
+		return "date(substr(created_at, 1, 10), 'weekday 0', '-6 days')"
+	case PeriodMonth:
+		return "substr(created_at, 1, 7) || '-01'"
+	}
+
+	return "substr(created_at, 1, 10)"
+}
+
+// ClientsCreated возвращает число клиентов, добавленных за каждый период p
+// от периода, содержащего from, до to (не включая to), в порядке
+// возрастания; периоды без клиентов входят в отчёт с нулём. Периоды
+// отсчитываются в UTC. Добавления считаются по created_at, поэтому мягко
+// удалённые клиенты учитываются, а клиенты, добавленные в обход
+// репозитория до миграции 28, — нет. Клиенты читаются с ограничением по
+// владельцу (см. WithOwnerRestriction).
+func (r *Repository) ClientsCreated(ctx context.Context, p ReportPeriod, from, to time.Time) (_ []PeriodCount, err error) {
+	ctx, end := r.startOperation(ctx, "clients_created")
+	defer end(&err)
+
+	if _, err := ParseReportPeriod(string(p)); err != nil {
+		return nil, err
+	}
+	from = p.start(from)
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: report range end %s is not after its start %s", ErrValidation, formatTime(to), formatTime(from))
+	}
+
+	var counts []PeriodCount
+	index := make(map[string]int)
+	for start := from; start.Before(to); start = p.next(start) {
+		if len(counts) == MaxReportPeriods {
+			return nil, fmt.Errorf("%w: report range spans more than %d periods", ErrValidation, MaxReportPeriods)
+		}
+		index[start.Format(ReportDateLayout)] = len(counts)
+		counts = append(counts, PeriodCount{Start: start.Format(ReportDateLayout)})
+	}
+
+	scope, args := r.ownerScope(ctx)
+	args = append(args,
+		sql.Named("from", formatTime(from)),
+		sql.Named("to", formatTime(to)))
+	found, err := queryBucketCounts(ctx, r.conn(), "SELECT "+p.key()+" AS key, COUNT(*) FROM clients"+
+		" WHERE created_at >= :from AND created_at < :to"+scope+" GROUP BY key", args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range found {
+		i, ok := index[c.Key]
+		if !ok {
+			return nil, fmt.Errorf("clients created at %q fall outside the report periods", c.Key)
+		}
+		counts[i].Count = c.Count
+	}
+
+	return counts, nil
+}
+
+// DuplicateCandidates находит группы клиентов с одинаковым email или
+// одинаковыми ФИО и датой рождения без учёта регистра и лишних пробелов.
+// Сравнение выполняется после расшифровки. Группы упорядочены по причине
+// и наименьшему ID; клиенты в группе — по возрастанию ID.
+func (r *Repository) DuplicateCandidates(ctx context.Context) (_ []DuplicateGroup, err error) {
+	ctx, end := r.startOperation(ctx, "duplicate_candidates")
+	defer end(&err)
+
+	type groupKey struct{ reason, key string }
+	ids := make(map[groupKey][]int)
+	err = r.forEach(ctx, "", nil, func(cl Client) error {
+		if email := strings.ToLower(strings.TrimSpace(cl.Email)); email != "" {
+			k := groupKey{DuplicateEmail, email}
+			ids[k] = append(ids[k], cl.ID)
+		}
+		if fio := strings.Join(strings.Fields(strings.ToLower(cl.FIO)), " "); fio != "" && cl.Birthday != "" {
+			k := groupKey{DuplicateFIOBirthday, fio + " " + cl.Birthday}
+			ids[k] = append(ids[k], cl.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []DuplicateGroup
+	for k, clientIDs := range ids {
+		if len(clientIDs) > 1 {
+			groups = append(groups, DuplicateGroup{Reason: k.reason, Key: k.key, ClientIDs: clientIDs})
+		}
+	}
+	// forEach обходит клиентов по возрастанию ID, поэтому ClientIDs уже упорядочены
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Reason != groups[j].Reason {
+			return groups[i].Reason < groups[j].Reason
+		}
+		return groups[i].ClientIDs[0] < groups[j].ClientIDs[0]
+	})
+
+	return groups, nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет подсчёт добавленных клиентов по месяцам на границе года
+// и учёт удалённых клиентов
+func Test_ClientsAddedPerMonth(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+
+	clock := testutil.NewFakeClock(time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC))
+	repo := NewRepository(db, WithClock(clock))
+
+	counts, err := repo.ClientsAddedPerMonth(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+
+	insert := func() int {
+		t.Helper()
+		id, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		return id
+	}
+
+	insert()
+	deleted := insert()
+	clock.Advance(2 * time.Minute)
+	insert()
+	clock.Set(time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC))
+	insert()
+	require.NoError(t, repo.Delete(ctx, deleted))
+
+	counts, err = repo.ClientsAddedPerMonth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []MonthlyCount{{"2024-12", 2}, {"2025-01", 1}, {"2025-03", 1}}, counts)
+}
+
+// Тест проверяет поиск возможных дубликатов по email и по ФИО с датой
+// рождения, в том числе при шифровании полей
+func Test_DuplicateCandidates(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	clients := []Client{
+		{FIO: "Иванов Иван", Login: "ivanov", Birthday: "19850615", Email: "ivanov@mail.com"},
+		{FIO: "иванов  иван", Login: "ivan85", Birthday: "19850615", Email: "Ivanov@Mail.com"},
+		{FIO: "Иванов Иван", Login: "ivanov2", Birthday: "19900101", Email: "ivan@corp.ru"},
+		{FIO: "Петров Пётр", Login: "petrov", Birthday: "19900101", Email: "petrov@corp.ru"},
+		{FIO: "Петров Пётр", Login: "petrov2", Birthday: "19900101", Email: "p2@corp.ru"},
+	}
+	ids := make([]int, len(clients))
+	for i, cl := range clients {
+		var err error
+		ids[i], err = repo.Insert(ctx, cl)
+		require.NoError(t, err)
+	}
+
+	groups, err := repo.DuplicateCandidates(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []DuplicateGroup{
+		{Reason: DuplicateEmail, Key: "ivanov@mail.com", ClientIDs: []int{ids[0], ids[1]}},
+		{Reason: DuplicateFIOBirthday, Key: "иванов иван 19850615", ClientIDs: []int{ids[0], ids[1]}},
+		{Reason: DuplicateFIOBirthday, Key: "петров пётр 19900101", ClientIDs: []int{ids[3], ids[4]}},
+	}, groups)
+
+	// Объединённые дубликаты больше не предлагаются
+	require.NoError(t, repo.MergeClients(ctx, ids[3], ids[4]))
+	groups, err = repo.DuplicateCandidates(ctx)
+	require.NoError(t, err)
+	assert.Len(t, groups, 2)
+}
+
+// Тест проверяет распределения клиентов по десятилетиям рождения, доменам
+// email и статусам на известном наборе, их отбор условием и пропуск
+// удалённых клиентов
+func Test_ClientStats(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	var ids []int
+	for _, cl := range []Client{
+		{FIO: "Иванов Иван", Login: "ivanov", Birthday: "19800101", Email: "ivanov@mail.com"},
+		{FIO: "Петров Пётр", Login: "petrov", Birthday: "19891231", Email: "petrov@Mail.com"},
+		{FIO: "Сидоров Олег", Login: "sidorov", Birthday: "19900615", Email: "sidorov@corp.ru"},
+		{FIO: "Смирнова Анна", Login: "smirnova", Birthday: "20000229", Email: "anna@yandex.ru"},
+		{FIO: "Кузнецов Олег", Login: "kuznetsov", Birthday: "19751010", Email: "oleg@corp.ru"},
+		{FIO: "Удалённый Клиент", Login: "deleted", Birthday: "19551010", Email: "deleted@gone.ru"},
+	} {
+		id, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	require.NoError(t, repo.ChangeStatus(ctx, ids[2], StatusBlocked))
+	require.NoError(t, repo.Delete(ctx, ids[5]))
+
+	stats, err := repo.ClientStats(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, ClientStats{
+		Total:         5,
+		ByBirthDecade: []BucketCount{{"1970s", 1}, {"1980s", 2}, {"1990s", 1}, {"2000s", 1}},
+		ByEmailDomain: []BucketCount{{"corp.ru", 2}, {"mail.com", 2}, {"yandex.ru", 1}},
+		ByStatus:      []BucketCount{{"active", 4}, {"blocked", 1}},
+	}, stats)
+
+	stats, err = repo.ClientStats(ctx, Filter{FIOContains: "Олег"})
+	require.NoError(t, err)
+	assert.Equal(t, ClientStats{
+		Total:         2,
+		ByBirthDecade: []BucketCount{{"1970s", 1}, {"1990s", 1}},
+		ByEmailDomain: []BucketCount{{"corp.ru", 2}},
+		ByStatus:      []BucketCount{{"active", 1}, {"blocked", 1}},
+	}, stats)
+
+	stats, err = repo.ClientStats(ctx, Filter{FIOContains: "Никто"})
+	require.NoError(t, err)
+	assert.Equal(t, ClientStats{ByBirthDecade: []BucketCount{}, ByEmailDomain: []BucketCount{}, ByStatus: []BucketCount{}}, stats)
+
+	encrypted := NewRepository(db, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+	_, err = encrypted.ClientStats(ctx, Filter{})
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+// Тест проверяет распределения на наборе сгенерированных клиентов по
+// подсчёту тех же признаков в Go
+func Test_ClientStats_Seeded(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	decades := make(map[string]int)
+	domains := make(map[string]int)
+	clients := fakeClients(7, 300)
+	for _, cl := range clients {
+		_, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+		decades[cl.Birthday[:3]+"0s"]++
+		domains[strings.ToLower(cl.Email[strings.Index(cl.Email, "@")+1:])]++
+	}
+
+	stats, err := repo.ClientStats(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, len(clients), stats.Total)
+	gotDecades := make(map[string]int)
+	for _, c := range stats.ByBirthDecade {
+		gotDecades[c.Key] = c.Count
+	}
+	assert.Equal(t, decades, gotDecades)
+	gotDomains := make(map[string]int)
+	for i, c := range stats.ByEmailDomain {
+		gotDomains[c.Key] = c.Count
+		if i > 0 {
+			assert.GreaterOrEqual(t, stats.ByEmailDomain[i-1].Count, c.Count, "domains should be ordered by count")
+		}
+	}
+	assert.Equal(t, domains, gotDomains)
+}
+
+// Тест проверяет отчёт о добавленных клиентах по дням, неделям и месяцам
+// на границах месяцев и года, включая периоды без клиентов
+func Test_ClientsCreated(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	clock := testutil.NewFakeClock(time.Time{})
+	repo := NewRepository(db, WithClock(clock))
+
+	for _, at := range []time.Time{
+		time.Date(2024, 12, 30, 10, 0, 0, 0, time.UTC), // понедельник
+		time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 5, 12, 0, 0, 0, time.UTC), // воскресенье
+		time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 2, 28, 23, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		clock.Set(at)
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		period   ReportPeriod
+		from, to time.Time
+		want     []PeriodCount
+	}{
+		{"Days", PeriodDay, date(2024, 12, 31), date(2025, 1, 3), []PeriodCount{
+			{"2024-12-31", 1}, {"2025-01-01", 1}, {"2025-01-02", 0},
+		}},
+		{"WeeksAcrossYear", PeriodWeek, date(2025, 1, 1), date(2025, 1, 20), []PeriodCount{
+			{"2024-12-30", 4}, {"2025-01-06", 1}, {"2025-01-13", 0},
+		}},
+		{"Months", PeriodMonth, date(2024, 11, 15), date(2025, 3, 2), []PeriodCount{
+			{"2024-11-01", 0}, {"2024-12-01", 2}, {"2025-01-01", 3}, {"2025-02-01", 1}, {"2025-03-01", 1},
+		}},
+		{"EndExclusive", PeriodMonth, date(2025, 2, 1), date(2025, 3, 1), []PeriodCount{
+			{"2025-02-01", 1},
+		}},
+		{"PartialPeriod", PeriodMonth, date(2024, 12, 1), time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC), []PeriodCount{
+			{"2024-12-01", 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := repo.ClientsCreated(ctx, tt.period, tt.from, tt.to)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, counts)
+		})
+	}
+
+	for name, tt := range map[string]struct {
+		period   ReportPeriod
+		from, to time.Time
+	}{
+		"UnknownPeriod":  {"year", date(2025, 1, 1), date(2026, 1, 1)},
+		"EmptyRange":     {PeriodDay, date(2025, 1, 1), date(2025, 1, 1)},
+		"ReversedRange":  {PeriodDay, date(2025, 1, 2), date(2025, 1, 1)},
+		"TooManyPeriods": {PeriodDay, date(2000, 1, 1), date(2025, 1, 1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.ClientsCreated(ctx, tt.period, tt.from, tt.to)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Тест проверяет, что миграция заполняет время добавления существующих
+// клиентов по журналу аудита и оставляет пустым у клиентов без записи
+func Test_ClientsCreated_Backfill(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, MigrateTo(ctx, db, 27))
+
+	for _, fio := range []string{"Audited", "Unaudited"} {
+		_, err := db.ExecContext(ctx, "INSERT INTO clients (fio, login, birthday, email) VALUES (?, 'login', '19700101', 'mail@mail.com')", fio)
+		require.NoError(t, err)
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO audit_log (occurred_at, operation, client_id) VALUES
+		('2024-05-01T10:00:00Z', 'insert', 1), ('2024-06-01T10:00:00Z', 'update', 1)`)
+	require.NoError(t, err)
+
+	require.NoError(t, Migrate(ctx, db))
+	createdAt, err := queryStrings(ctx, db, "SELECT created_at FROM clients ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-05-01T10:00:00Z", ""}, createdAt)
+	assertRowCount(t, db, "change_log", 2, "op = 'insert'")
+	assertRowCount(t, db, "change_log", 0, "op = 'update'")
+
+	counts, err := NewRepository(db).ClientsCreated(ctx, PeriodMonth, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []PeriodCount{{"2024-05-01", 1}, {"2024-06-01", 0}}, counts)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// FilterQueryParam — параметр строки запроса с выражением условия отбора
+// (см. ParseFilterExpr).
+const FilterQueryParam = "filter"
+
+// APIError — тело ответа API с ошибкой: Error — причина на английском для
+// разработчиков, Message — сообщение для пользователя на языке из
+// заголовка Accept-Language (см. LocalizeError).
+type APIError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Параметры строки запроса ClientsHandler.
+const (
+	ClientsLimitParam = "limit"
+	ClientsAfterParam = "after"
+)
+
+// ClientsHandler возвращает обработчик GET-запроса страницы списка
+// клиентов в JSON в порядке возрастания ID (см. Repository.FindPage):
+// до limit клиентов (по умолчанию DefaultPageLimit, не больше
+// MaxPageLimit) с ID больше after. Следующая страница запрашивается с
+// after, равным ID последнего клиента, пока страница не окажется короче
+// limit. Параметр filter отбирает клиентов выражением ParseFilterExpr,
+// например ?filter=fio==Иван*;birthday=ge=1990-01-01. Некорректные
+// параметры и условие, которое нельзя проверить (см. Filter), возвращают
+// 400 с причиной, прочие ошибки — код по их категории (см.
+// writeAPIFailure).
+func ClientsHandler(repo *Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowGet(w, r) {
+			return
+		}
+
+		query := r.URL.Query()
+		f, err := ParseFilterExpr(query.Get(FilterQueryParam))
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		limit, after := 0, 0
+		if v := query.Get(ClientsLimitParam); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > MaxPageLimit {
+				writeAPIError(w, r, http.StatusBadRequest, fmt.Errorf("%w: limit must be an integer between 1 and %d, got %q", ErrValidation, MaxPageLimit, v))
+				return
+			}
+		}
+		if v := query.Get(ClientsAfterParam); v != "" {
+			if after, err = strconv.Atoi(v); err != nil || after < 0 {
+				writeAPIError(w, r, http.StatusBadRequest, fmt.Errorf("%w: after must be a non-negative integer, got %q", ErrValidation, v))
+				return
+			}
+		}
+
+		clients, err := repo.FindPage(r.Context(), f, after, limit)
+		if err != nil {
+			writeAPIFailure(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(clients)
+	})
+}
+
+// Параметры строки запроса AutocompleteHandler.
+const (
+	AutocompleteQueryParam = "q"
+	AutocompleteLimitParam = "limit"
+)
+
+// AutocompleteHandler возвращает обработчик GET-запроса подсказок для
+// поля ввода: до limit клиентов, ФИО, слово ФИО или логин которых
+// начинаются с q (см. Repository.Autocomplete), в JSON. Пустой q и
+// некорректный limit возвращают 400 с причиной, прочие ошибки — код по
+// их категории (см. writeAPIFailure).
+func AutocompleteHandler(repo *Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowGet(w, r) {
+			return
+		}
+
+		query := r.URL.Query()
+		limit := 0
+		if v := query.Get(AutocompleteLimitParam); v != "" {
+			var err error
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+				writeAPIError(w, r, http.StatusBadRequest, fmt.Errorf("%w: limit must be a positive integer, got %q", ErrValidation, v))
+				return
+			}
+		}
+
+		suggestions, err := repo.Autocomplete(r.Context(), query.Get(AutocompleteQueryParam), limit)
+		if err != nil {
+			writeAPIFailure(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(suggestions)
+	})
+}
+
+// ClientStatsHandler возвращает обработчик GET-запроса распределений
+// клиентов (см. Repository.ClientStats) в JSON для панелей мониторинга.
+// Параметр filter ограничивает клиентов выражением ParseFilterExpr, как в
+// ClientsHandler.
+func ClientStatsHandler(repo *Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowGet(w, r) {
+			return
+		}
+
+		f, err := ParseFilterExpr(r.URL.Query().Get(FilterQueryParam))
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		stats, err := repo.ClientStats(r.Context(), f)
+		if err != nil {
+			writeAPIFailure(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	})
+}
+
+// Параметры строки запроса ClientsCreatedHandler.
+const (
+	ReportPeriodParam = "period"
+	ReportFromParam   = "from"
+	ReportToParam     = "to"
+)
+
+// ClientsCreatedHandler возвращает обработчик GET-запроса числа клиентов,
+// добавленных за каждый период (см. Repository.ClientsCreated), в JSON:
+// ?period=week&from=2025-01-01&to=2025-04-01. Без period отчёт строится
+// по месяцам, без to — по текущий момент. Некорректные параметры
+// возвращают 400 с причиной.
+func ClientsCreatedHandler(repo *Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowGet(w, r) {
+			return
+		}
+
+		query := r.URL.Query()
+		period := PeriodMonth
+		if v := query.Get(ReportPeriodParam); v != "" {
+			var err error
+			if period, err = ParseReportPeriod(v); err != nil {
+				writeAPIError(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+		from, err := parseReportDate(ReportFromParam, query.Get(ReportFromParam))
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		to := repo.now()
+		if v := query.Get(ReportToParam); v != "" {
+			if to, err = parseReportDate(ReportToParam, v); err != nil {
+				writeAPIError(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		counts, err := repo.ClientsCreated(r.Context(), period, from, to)
+		if err != nil {
+			writeAPIFailure(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(counts)
+	})
+}
+
+// errMethodNotAllowed — ошибка запроса с неподдерживаемым методом.
+var errMethodNotAllowed = errors.New(http.StatusText(http.StatusMethodNotAllowed))
+
+// allowGet отвечает 405 на запрос с методом, отличным от GET и HEAD, и
+// сообщает, можно ли его обрабатывать.
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeAPIError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+
+	return false
+}
+
+// apiFailureStatus — код ответа API на ошибку каждой категории Classify.
+var apiFailureStatus = map[ErrorClass]int{
+	ClassNotFound:   http.StatusNotFound,
+	ClassValidation: http.StatusBadRequest,
+	ClassAccess:     http.StatusForbidden,
+	ClassConflict:   http.StatusConflict,
+	ClassTimeout:    http.StatusGatewayTimeout,
+}
+
+// writeAPIFailure отвечает на ошибку репозитория кодом по её категории
+// (Classify): 404, 400, 403 и 409 — с причиной, 504 при истечении времени
+// или занятости БД и 500 на прочие ошибки — без подробностей.
+func writeAPIFailure(w http.ResponseWriter, r *http.Request, err error) {
+	status, ok := apiFailureStatus[Classify(err)]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError {
+		// Причина не раскрывается: в ней могут быть подробности БД
+		err = errors.New(http.StatusText(status))
+	}
+	writeAPIError(w, r, status, err)
+}
+
+// writeAPIError отвечает кодом status и ошибкой err в JSON с сообщением
+// для пользователя на языке запроса r.
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	ctx := WithLocale(r.Context(), RequestLocale(r))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{Error: err.Error(), Message: LocalizeError(ctx, err)})
+}
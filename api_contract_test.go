@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiContract — запросы к обработчику API, на которые он должен ответить
+// успехом и ошибкой проверки.
+type apiContract struct {
+	name    string
+	handler func(*Repository) http.Handler
+	// ok — корректный запрос; bad — запрос с некорректными параметрами.
+	ok, bad url.Values
+	// body — пустое значение типа, в который разбирается успешный ответ.
+	body func() any
+}
+
+var apiContracts = []apiContract{
+	{
+		name:    "Clients",
+		handler: ClientsHandler,
+		ok:      url.Values{FilterQueryParam: {"fio==Иван*"}, ClientsLimitParam: {"10"}},
+		bad:     url.Values{ClientsLimitParam: {fmt.Sprint(MaxPageLimit + 1)}},
+		body:    func() any { return &[]Client{} },
+	},
+	{
+		name:    "Autocomplete",
+		handler: AutocompleteHandler,
+		ok:      url.Values{AutocompleteQueryParam: {"Ива"}, AutocompleteLimitParam: {"5"}},
+		bad:     url.Values{AutocompleteQueryParam: {""}},
+		body:    func() any { return &[]Suggestion{} },
+	},
+	{
+		name:    "ClientStats",
+		handler: ClientStatsHandler,
+		ok:      url.Values{FilterQueryParam: {"birthday=lt=2000-01-01"}},
+		bad:     url.Values{FilterQueryParam: {"birthday=lt=1990"}},
+		body:    func() any { return &ClientStats{} },
+	},
+	{
+		name:    "ClientsCreated",
+		handler: ClientsCreatedHandler,
+		ok:      url.Values{ReportPeriodParam: {"day"}, ReportFromParam: {"2024-01-01"}, ReportToParam: {"2024-01-03"}},
+		bad:     url.Values{ReportFromParam: {"2024-13-01"}},
+		body:    func() any { return &[]PeriodCount{} },
+	},
+}
+
+// serveContract выполняет GET-запрос с параметрами query к обработчику
+// контракта c и возвращает ответ.
+func serveContract(c apiContract, repo *Repository, query url.Values) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c.handler(repo).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api?"+query.Encode(), nil))
+
+	return rec
+}
+
+// assertAPIError проверяет, что ответ — ошибка API с кодом code в JSON, а
+// её текст равен wantError, если он задан.
+func assertAPIError(t *testing.T, rec *httptest.ResponseRecorder, code int, wantError string) {
+	t.Helper()
+
+	assert.Equal(t, code, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var apiErr APIError
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiErr))
+	assert.NotEmpty(t, apiErr.Error)
+	assert.NotEmpty(t, apiErr.Message)
+	if wantError != "" {
+		assert.Equal(t, wantError, apiErr.Error)
+	}
+}
+
+// Тест проверяет контракт обработчиков API: успешный ответ в JSON, 400 на
+// некорректные параметры, 404 и 403 на ошибки репозитория этих категорий
+// и 500 без подробностей на сбой БД
+func Test_APIContract(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+	_, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванов Иван"; cl.Login = "ivanov" }))
+	require.NoError(t, err)
+	_, err = repo.RebuildAutocomplete(ctx)
+	require.NoError(t, err)
+
+	closedDB := openMemoryDB(t)
+	require.NoError(t, closedDB.Close())
+	closed := NewRepository(closedDB)
+
+	for _, c := range apiContracts {
+		t.Run(c.name, func(t *testing.T) {
+			t.Run("OK", func(t *testing.T) {
+				rec := serveContract(c, repo, c.ok)
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), c.body()))
+			})
+
+			t.Run("BadRequest", func(t *testing.T) {
+				assertAPIError(t, serveContract(c, repo, c.bad), http.StatusBadRequest, "")
+			})
+
+			for _, tt := range []struct {
+				name string
+				err  error
+				code int
+			}{
+				{"NotFound", ErrClientNotFound, http.StatusNotFound},
+				{"Forbidden", ErrAccessDenied, http.StatusForbidden},
+			} {
+				t.Run(tt.name, func(t *testing.T) {
+					mockDB, mock, err := sqlmock.New()
+					require.NoError(t, err)
+					t.Cleanup(func() { mockDB.Close() })
+					mock.ExpectQuery(".").WillReturnError(tt.err)
+
+					rec := serveContract(c, NewRepository(mockDB), c.ok)
+					assertAPIError(t, rec, tt.code, "")
+					assert.Contains(t, rec.Body.String(), tt.err.Error())
+					assert.NoError(t, mock.ExpectationsWereMet())
+				})
+			}
+
+			t.Run("InternalError", func(t *testing.T) {
+				rec := serveContract(c, closed, c.ok)
+				assertAPIError(t, rec, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+				assert.NotContains(t, rec.Body.String(), "closed", "database details should not leak")
+			})
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет отбор списка клиентов API выражением filter и ответ 400
+// с причиной на некорректное выражение
+func Test_ClientsHandler_Filter(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	ivan, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванов Иван"; cl.Birthday = "19920101" }))
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванова Анна"; cl.Birthday = "19850101" }))
+	require.NoError(t, err)
+	petr, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Петров Пётр"; cl.Birthday = "19950101" }))
+	require.NoError(t, err)
+
+	// get выполняет запрос с выражением expr и возвращает код и тело ответа
+	get := func(method, expr string) (int, []byte) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ClientsHandler(repo).ServeHTTP(rec, httptest.NewRequest(method, "/clients?"+url.Values{FilterQueryParam: {expr}}.Encode(), nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		return rec.Code, rec.Body.Bytes()
+	}
+	ids := func(body []byte) []int {
+		t.Helper()
+		var clients []Client
+		require.NoError(t, json.Unmarshal(body, &clients))
+		ids := []int{}
+		for _, cl := range clients {
+			ids = append(ids, cl.ID)
+		}
+		return ids
+	}
+
+	code, body := get(http.MethodGet, "fio==Иван*;birthday=ge=1990-01-01,fio==*Пётр*")
+	require.Equal(t, http.StatusOK, code, string(body))
+	assert.Equal(t, []int{ivan, petr}, ids(body))
+
+	code, body = get(http.MethodGet, "status==blocked")
+	require.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, "[]", string(body))
+
+	code, body = get(http.MethodGet, "birthday=ge=1990-13-01")
+	assert.Equal(t, http.StatusBadRequest, code)
+	var apiErr APIError
+	require.NoError(t, json.Unmarshal(body, &apiErr))
+	assert.Equal(t, `validation failed: filter expression at position 9: birthday=ge=: "1990-13-01" is not a YYYY-MM-DD or YYYYMMDD date`, apiErr.Error)
+
+	code, _ = get(http.MethodPost, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	encrypted := NewRepository(db, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+	rec := httptest.NewRecorder()
+	ClientsHandler(encrypted).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clients?filter=email==mail@mail.com", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "filters on encrypted fields should be rejected")
+}
+
+// Тест проверяет постраничную выдачу списка клиентов API параметрами limit
+// и after и ответ 400 на некорректные и слишком большие значения
+func Test_ClientsHandler_Pagination(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	var all []int
+	for i := 0; i < 5; i++ {
+		id, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		all = append(all, id)
+	}
+
+	// page выполняет запрос с параметрами query и возвращает код и ID клиентов
+	page := func(query url.Values) (int, []int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ClientsHandler(repo).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clients?"+query.Encode(), nil))
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+		var clients []Client
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &clients))
+		ids := []int{}
+		for _, cl := range clients {
+			ids = append(ids, cl.ID)
+		}
+		return rec.Code, ids
+	}
+
+	var got []int
+	after := 0
+	for {
+		code, ids := page(url.Values{ClientsLimitParam: {"2"}, ClientsAfterParam: {fmt.Sprint(after)}})
+		require.Equal(t, http.StatusOK, code)
+		got = append(got, ids...)
+		if len(ids) < 2 {
+			break
+		}
+		after = ids[len(ids)-1]
+	}
+	assert.Equal(t, all, got)
+
+	code, ids := page(url.Values{})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, all, ids, "without limit the page should hold up to DefaultPageLimit clients")
+
+	code, ids = page(url.Values{ClientsLimitParam: {fmt.Sprint(MaxPageLimit)}, ClientsAfterParam: {fmt.Sprint(all[4])}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, ids)
+
+	for _, query := range []url.Values{
+		{ClientsLimitParam: {fmt.Sprint(MaxPageLimit + 1)}},
+		{ClientsLimitParam: {"0"}},
+		{ClientsLimitParam: {"many"}},
+		{ClientsAfterParam: {"-1"}},
+		{ClientsAfterParam: {"x"}},
+	} {
+		code, _ := page(query)
+		assert.Equal(t, http.StatusBadRequest, code, query.Encode())
+	}
+
+	_, err := repo.FindPage(ctx, Filter{}, 0, MaxPageLimit+1)
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+// Тест проверяет ответ API подсказок и ответ 400 на пустое начало и
+// некорректное число подсказок
+func Test_AutocompleteHandler(t *testing.T) {
+	_, repo, ids := setupAutocomplete(t,
+		newTestClient(func(cl *Client) { cl.FIO = "Соколов Дмитрий"; cl.Login = "sokol" }),
+		newTestClient(func(cl *Client) { cl.FIO = "Соколова Мария"; cl.Login = "maria" }),
+	)
+
+	get := func(query url.Values) (int, []byte) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		AutocompleteHandler(repo).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clients/autocomplete?"+query.Encode(), nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	code, body := get(url.Values{AutocompleteQueryParam: {"сокол"}, AutocompleteLimitParam: {"1"}})
+	require.Equal(t, http.StatusOK, code, string(body))
+	var suggestions []Suggestion
+	require.NoError(t, json.Unmarshal(body, &suggestions))
+	assert.Equal(t, []Suggestion{{ClientID: ids[0], Field: "fio", FIO: "Соколов Дмитрий", Login: "sokol"}}, suggestions)
+
+	code, body = get(url.Values{AutocompleteQueryParam: {"Петр"}})
+	require.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, "[]", string(body))
+
+	for _, query := range []url.Values{
+		{},
+		{AutocompleteQueryParam: {"сокол"}, AutocompleteLimitParam: {"ten"}},
+		{AutocompleteQueryParam: {"сокол"}, AutocompleteLimitParam: {"0"}},
+		{AutocompleteQueryParam: {"сокол"}, AutocompleteLimitParam: {"1000"}},
+	} {
+		code, body := get(query)
+		assert.Equal(t, http.StatusBadRequest, code, "%v: %s", query, body)
+	}
+}
+
+// Тест проверяет ответ API распределений клиентов с выражением filter и
+// ответ 400 на некорректное выражение
+func Test_ClientStatsHandler(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	for _, cl := range []Client{
+		{FIO: "Иванов Иван", Login: "ivanov", Birthday: "19850101", Email: "ivanov@mail.com"},
+		{FIO: "Петров Пётр", Login: "petrov", Birthday: "19950101", Email: "petrov@corp.ru"},
+	} {
+		_, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+	}
+
+	get := func(expr string) (int, []byte) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ClientStatsHandler(repo).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clients/stats?"+url.Values{FilterQueryParam: {expr}}.Encode(), nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	code, body := get("birthday=lt=1990-01-01")
+	require.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `{
+		"total": 1,
+		"by_birth_decade": [{"key": "1980s", "count": 1}],
+		"by_email_domain": [{"key": "mail.com", "count": 1}],
+		"by_status": [{"key": "active", "count": 1}]
+	}`, string(body))
+
+	code, _ = get("birthday=lt=1990")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	rec := httptest.NewRecorder()
+	ClientStatsHandler(repo).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/clients/stats", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
+
+// Тест проверяет ответ API отчёта о добавленных клиентах по периодам и
+// ответ 400 на некорректные параметры
+func Test_ClientsCreatedHandler(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	clock := testutil.NewFakeClock(time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC))
+	repo := NewRepository(db, WithClock(clock))
+	for i := 0; i < 2; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		clock.Advance(2 * time.Hour)
+	}
+
+	get := func(query url.Values) (int, []byte) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ClientsCreatedHandler(repo).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clients/created?"+query.Encode(), nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	code, body := get(url.Values{ReportPeriodParam: {"day"}, ReportFromParam: {"2024-12-31"}, ReportToParam: {"2025-01-02"}})
+	require.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `[{"start": "2024-12-31", "count": 1}, {"start": "2025-01-01", "count": 1}]`, string(body))
+
+	code, body = get(url.Values{ReportFromParam: {"2024-12-15"}})
+	require.Equal(t, http.StatusOK, code, string(body))
+	assert.JSONEq(t, `[{"start": "2024-12-01", "count": 1}, {"start": "2025-01-01", "count": 1}]`, string(body), "months up to the repository clock by default")
+
+	for _, query := range []url.Values{
+		{},
+		{ReportFromParam: {"2024-12-32"}},
+		{ReportFromParam: {"2024-12-01"}, ReportToParam: {"tomorrow"}},
+		{ReportFromParam: {"2024-12-01"}, ReportPeriodParam: {"hour"}},
+		{ReportFromParam: {"2025-12-01"}},
+	} {
+		code, body := get(query)
+		assert.Equal(t, http.StatusBadRequest, code, "%v: %s", query, body)
+	}
+}
+
+// Тест проверяет коды ответов API на ошибки каждой категории Classify:
+// причина пользовательских ошибок возвращается, подробности сбоев — нет
+func Test_WriteAPIFailure(t *testing.T) {
+	constraint, busy := sqliteErrors(t)
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  int
+		wantError string
+	}{
+		{"NotFound", &OpError{Op: "select", ClientID: 7, Err: ErrClientNotFound}, http.StatusNotFound, "select client 7: client not found"},
+		{"Validation", fmt.Errorf("%w: bad email", ErrValidation), http.StatusBadRequest, "validation failed: bad email"},
+		{"Access", fmt.Errorf("select client 7: %w", ErrAccessDenied), http.StatusForbidden, "select client 7: " + ErrAccessDenied.Error()},
+		{"Conflict", ErrClientHasOrders, http.StatusConflict, ErrClientHasOrders.Error()},
+		{"Constraint", constraint, http.StatusConflict, constraint.Error()},
+		{"Deadline", fmt.Errorf("list: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "Gateway Timeout"},
+		{"Canceled", context.Canceled, http.StatusGatewayTimeout, "Gateway Timeout"},
+		{"Busy", busy, http.StatusGatewayTimeout, "Gateway Timeout"},
+		{"Internal", errors.New("disk I/O error at /var/lib/clients.db"), http.StatusInternalServerError, "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeAPIFailure(w, r, tt.err)
+			})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clients", nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var apiErr APIError
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiErr))
+			assert.Equal(t, tt.wantError, apiErr.Error)
+			assert.NotEmpty(t, apiErr.Message)
+		})
+	}
+}
+
+// Тест проверяет, что запрос, отменённый во время чтения клиентов,
+// получает 504, а не 500
+func Test_ClientsHandler_Canceled(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	ClientsHandler(NewRepository(db)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clients", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code, rec.Body.String())
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrForeignKeysEnforced возвращается ArchiveClients, если в соединении
+// включён PRAGMA foreign_keys: удаление клиента из clients удалило бы его
+// заметки, метки и документы или было бы запрещено из-за заказов.
+var ErrForeignKeysEnforced = errors.New("archiving requires foreign key enforcement to be off")
+
+// archiveColumns — столбцы, общие для clients и clients_archive.
+const archiveColumns = "id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status, valid_from, deleted_at, merged_into, preferences, legal_hold, created_at, version"
+
+// ArchiveClients переносит в clients_archive клиентов, неактивных с
+// момента olderThan: клиент не менялся с этого момента и у него нет более
+// поздних заказов и заметок. Мягко удалённые клиенты не переносятся.
+// Перенос всех клиентов выполняется в одной транзакции: клиент либо
+// целиком в clients_archive, либо остаётся в clients. Архивные клиенты с
+// прежними ID не читаются и не изменяются обычными операциями
+// репозитория, их заказы, заметки, метки и документы остаются на месте, а
+// EraseClient удаляет и архивных клиентов. Возвращает ID перенесённых
+// клиентов по возрастанию.
+func (r *Repository) ArchiveClients(ctx context.Context, olderThan time.Time) (_ []int, err error) {
+	ctx, end := r.startOperation(ctx, "archive_clients")
+	defer end(&err)
+
+	var ids []int
+	err = r.inTx(ctx, func(q querier) error {
+		ids = nil
+
+		var enforced bool
+		if err := q.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enforced); err != nil {
+			return err
+		}
+		if enforced {
+			return ErrForeignKeysEnforced
+		}
+
+		scope, args := r.ownerScope(ctx)
+		args = append(args, sql.Named("cutoff", formatTime(olderThan)))
+		found, err := queryStrings(ctx, q, `SELECT id FROM clients c WHERE valid_from < :cutoff`+notDeleted+scope+`
+	AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.client_id = c.id AND julianday(o.created_at) >= julianday(:cutoff))
+	AND NOT EXISTS (SELECT 1 FROM client_notes n WHERE n.client_id = c.id AND julianday(n.created_at) >= julianday(:cutoff))
+	ORDER BY id`, args...)
+		if err != nil {
+			return err
+		}
+
+		archivedAt := formatTime(r.now())
+		for _, s := range found {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			if err := r.moveClient(ctx, q, id, "clients", "clients_archive", archivedAt); err != nil {
+				return err
+			}
+			if err := r.auditDiff(ctx, q, AuditArchive, id, nil); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// UnarchiveClient возвращает архивного клиента id в clients с прежним ID
+// и данными или возвращает ErrClientNotFound, если в архиве его нет.
+func (r *Repository) UnarchiveClient(ctx context.Context, id int) (err error) {
+	ctx, end := r.startClientOperation(ctx, "unarchive_client", id)
+	defer end(&err)
+
+	return r.inTx(ctx, func(q querier) error {
+		if err := r.clientArchived(ctx, q, id); err != nil {
+			return err
+		}
+		if err := r.moveClient(ctx, q, id, "clients_archive", "clients", ""); err != nil {
+			return err
+		}
+
+		return r.auditDiff(ctx, q, AuditUnarchive, id, nil)
+	})
+}
+
+// ArchivedClients возвращает ID архивных клиентов по возрастанию.
+func (r *Repository) ArchivedClients(ctx context.Context) (_ []int, err error) {
+	ctx, end := r.startOperation(ctx, "archived_clients")
+	defer end(&err)
+
+	scope, args := r.ownerScope(ctx)
+	found, err := queryStrings(ctx, r.conn(), "SELECT id FROM clients_archive WHERE 1"+scope+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(found))
+	for i, s := range found {
+		if ids[i], err = strconv.Atoi(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+// moveClient переносит строку клиента id из таблицы from в to в
+// транзакции q. Непустой archivedAt записывается в clients_archive.
+func (r *Repository) moveClient(ctx context.Context, q querier, id int, from, to, archivedAt string) error {
+	insert := "INSERT INTO " + to + " (" + archiveColumns + ") SELECT " + archiveColumns + " FROM " + from + " WHERE id = :id"
+	args := []any{sql.Named("id", id)}
+	if archivedAt != "" {
+		insert = "INSERT INTO " + to + " (" + archiveColumns + ", archived_at) SELECT " + archiveColumns + ", :archived_at FROM " + from + " WHERE id = :id"
+		args = append(args, sql.Named("archived_at", archivedAt))
+	}
+	if _, err := q.ExecContext(ctx, insert, args...); err != nil {
+		return err
+	}
+
+	_, err := q.ExecContext(ctx, "DELETE FROM "+from+" WHERE id = :id", sql.Named("id", id))
+
+	return err
+}
+
+// clientArchived возвращает ErrClientNotFound, если архивного клиента с
+// ID id нет или он недоступен пользователю из контекста.
+func (r *Repository) clientArchived(ctx context.Context, q querier, id int) error {
+	scope, args := r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	var exists int
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM clients_archive WHERE id = :id"+scope, args...).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrClientNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupArchive создаёт клиентов, активность которых закончилась до или
+// после cutoff, и возвращает БД, репозиторий, часы и ID клиентов: idle —
+// без активности после cutoff, ordered, noted и updated — с заказом,
+// заметкой и изменением после cutoff.
+func setupArchive(t *testing.T) (db *sql.DB, repo *Repository, cutoff time.Time, idle, ordered, noted, updated int) {
+	t.Helper()
+
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(start)
+	db = openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo = NewRepository(db, WithClock(clock))
+
+	ids := make([]int, 4)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	idle, ordered, noted, updated = ids[0], ids[1], ids[2], ids[3]
+	// Давняя активность не мешает переносу
+	_, err := repo.Orders().Create(ctx, Order{ClientID: idle, Amount: 100})
+	require.NoError(t, err)
+	_, err = repo.AddNote(ctx, idle, "old note")
+	require.NoError(t, err)
+
+	clock.Advance(30 * 24 * time.Hour)
+	cutoff = clock.Now()
+	clock.Advance(time.Hour)
+	_, err = repo.Orders().Create(ctx, Order{ClientID: ordered, Amount: 100})
+	require.NoError(t, err)
+	_, err = repo.AddNote(ctx, noted, "recent note")
+	require.NoError(t, err)
+	cl, err := repo.Select(ctx, updated)
+	require.NoError(t, err)
+	cl.FIO = "Updated"
+	require.NoError(t, repo.Update(ctx, cl))
+
+	return db, repo, cutoff, idle, ordered, noted, updated
+}
+
+// Тест проверяет перенос в архив только неактивных клиентов, исключение
+// архивных клиентов из обычных операций и сохранение их связанных строк
+func Test_ArchiveClients(t *testing.T) {
+	ctx := context.Background()
+	db, repo, cutoff, idle, ordered, noted, updated := setupArchive(t)
+	before, err := repo.Select(ctx, idle)
+	require.NoError(t, err)
+
+	ids, err := repo.ArchiveClients(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, []int{idle}, ids)
+
+	_, err = repo.Select(ctx, idle)
+	require.ErrorIs(t, err, ErrClientNotFound)
+	require.ErrorIs(t, repo.Update(ctx, before), ErrClientNotFound)
+	var seen []int
+	require.NoError(t, repo.ForEach(ctx, func(cl Client) error {
+		seen = append(seen, cl.ID)
+		return nil
+	}))
+	assert.Equal(t, []int{ordered, noted, updated}, seen)
+
+	archived, err := repo.ArchivedClients(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{idle}, archived)
+	assertRowCount(t, db, "orders", 1, "client_id = ?", idle)
+	assertRowCount(t, db, "client_notes", 1, "client_id = ?", idle)
+
+	report, err := repo.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "orders and notes of archived clients are not orphans: %v", report.Issues)
+
+	entries, err := repo.AuditLog(ctx, idle)
+	require.NoError(t, err)
+	assert.Equal(t, AuditArchive, entries[len(entries)-1].Operation)
+
+	// Повторный перенос ничего не находит
+	ids, err = repo.ArchiveClients(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+// Тест проверяет возврат клиента из архива с прежними ID и данными
+func Test_UnarchiveClient(t *testing.T) {
+	ctx := context.Background()
+	_, repo, cutoff, idle, _, _, _ := setupArchive(t)
+	before, err := repo.Select(ctx, idle)
+	require.NoError(t, err)
+	_, err = repo.ArchiveClients(ctx, cutoff)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UnarchiveClient(ctx, idle))
+	after, err := repo.Select(ctx, idle)
+	require.NoError(t, err)
+	assertClientEqual(t, before, after)
+	archived, err := repo.ArchivedClients(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, archived)
+
+	require.ErrorIs(t, repo.UnarchiveClient(ctx, idle), ErrClientNotFound)
+	entries, err := repo.AuditLog(ctx, idle)
+	require.NoError(t, err)
+	assert.Equal(t, AuditUnarchive, entries[len(entries)-1].Operation)
+}
+
+// Тест проверяет, что перенос атомарен: если одного клиента перенести
+// нельзя, в архив не попадает никто и клиенты остаются на месте
+func Test_ArchiveClients_Atomic(t *testing.T) {
+	ctx := context.Background()
+	db, repo, _, idle, ordered, noted, updated := setupArchive(t)
+
+	// Строка архива с ID третьего из четырёх клиентов не даёт его
+	// перенести, когда первые два уже перенесены
+	_, err := db.Exec(`INSERT INTO clients_archive (`+archiveColumns+`, archived_at)
+		SELECT `+archiveColumns+`, '' FROM clients WHERE id = ?`, noted)
+	require.NoError(t, err)
+
+	_, err = repo.ArchiveClients(ctx, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.Error(t, err)
+	for _, id := range []int{idle, ordered, noted, updated} {
+		_, err := repo.Select(ctx, id)
+		require.NoError(t, err, "client %d should stay after the failed archive", id)
+	}
+	assertRowCount(t, db, "clients_archive", 1, "1")
+}
+
+// Тест проверяет отказ переноса при включённых внешних ключах: удаление
+// клиента удалило бы его заметки
+func Test_ArchiveClients_ForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "clients.db")
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	_, err = repo.AddNote(ctx, id, "note")
+	require.NoError(t, err)
+
+	_, err = repo.ArchiveClients(ctx, time.Now().Add(time.Hour))
+	require.ErrorIs(t, err, ErrForeignKeysEnforced)
+	assertRowCount(t, db, "client_notes", 1, "client_id = ?", id)
+	_, err = repo.Select(ctx, id)
+	require.NoError(t, err)
+}
+
+// Тест проверяет, что EraseClient удаляет и архивного клиента
+func Test_EraseClient_Archived(t *testing.T) {
+	ctx := context.Background()
+	db, repo, cutoff, idle, _, _, _ := setupArchive(t)
+	_, err := repo.ArchiveClients(ctx, cutoff)
+	require.NoError(t, err)
+
+	receipt, err := repo.EraseClient(ctx, idle)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, receipt.Deleted["clients_archive"])
+	assertRowCount(t, db, "clients_archive", 0, "1")
+	assertRowCount(t, db, "orders", 0, "client_id = ?", idle)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AuditOperation — вид изменения клиента, записанного в журнал аудита.
+type AuditOperation string
+
+const (
+	AuditInsert AuditOperation = "insert"
+	AuditUpdate AuditOperation = "update"
+	AuditDelete AuditOperation = "delete"
+	AuditErase  AuditOperation = "erase"
+	// AuditConsent — изменение согласия на маркетинговые коммуникации.
+	AuditConsent AuditOperation = "consent"
+	// AuditStatus — переход клиента в другой статус.
+	AuditStatus AuditOperation = "status"
+	// AuditMerge — объединение клиента с дубликатами.
+	AuditMerge AuditOperation = "merge"
+	// AuditPreferences — изменение настроек клиента.
+	AuditPreferences AuditOperation = "preferences"
+	// AuditLegalHold — установка или снятие удержания клиента.
+	AuditLegalHold AuditOperation = "legal_hold"
+	// AuditArchive и AuditUnarchive — перенос клиента в архив и обратно.
+	AuditArchive   AuditOperation = "archive"
+	AuditUnarchive AuditOperation = "unarchive"
+)
+
+// systemActor подставляется в журнал, если в контексте не указан инициатор.
+const systemActor = "system"
+
+// FieldChange — значение поля до и после изменения. Отсутствующая сторона
+// (до вставки или после удаления) равна nil.
+type FieldChange struct {
+	Before *string `json:"before,omitempty"`
+	After  *string `json:"after,omitempty"`
+}
+
+// AuditEntry — запись журнала аудита.
+type AuditEntry struct {
+	ID         int                    `json:"id"`
+	Actor      string                 `json:"actor"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Operation  AuditOperation         `json:"operation"`
+	ClientID   int                    `json:"client_id"`
+	Diff       map[string]FieldChange `json:"diff,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor возвращает контекст, в котором инициатором изменений указан actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext возвращает инициатора изменений из контекста.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return systemActor
+}
+
+// diffClients возвращает изменившиеся поля клиента. Для вставки (before == nil)
+// и удаления (after == nil) в разницу попадают все поля.
+func diffClients(before, after *Client) map[string]FieldChange {
+	fields := func(cl *Client) map[string]string {
+		if cl == nil {
+			return nil
+		}
+
+		return map[string]string{
+			"fio":      cl.FIO,
+			"login":    cl.Login,
+			"birthday": cl.Birthday,
+			"email":    cl.Email,
+		}
+	}
+
+	b, a := fields(before), fields(after)
+	diff := make(map[string]FieldChange)
+	for _, name := range []string{"fio", "login", "birthday", "email"} {
+		var change FieldChange
+		if b != nil {
+			v := b[name]
+			change.Before = &v
+		}
+		if a != nil {
+			v := a[name]
+			change.After = &v
+		}
+		if change.Before != nil && change.After != nil && *change.Before == *change.After {
+			continue
+		}
+		diff[name] = change
+	}
+
+	return diff
+}
+
+// audit записывает изменение клиента в журнал в рамках транзакции изменения.
+// Если включено шифрование, зашифрованные поля попадают в журнал также
+// в зашифрованном виде.
+func (r *Repository) audit(ctx context.Context, q querier, op AuditOperation, clientID int, before, after *Client) error {
+	var diff map[string]FieldChange
+	if op != AuditErase {
+		diff = diffClients(before, after)
+	}
+
+	return r.auditDiff(ctx, q, op, clientID, diff)
+}
+
+// auditDiff записывает в журнал готовую разницу полей, а в outbox —
+// событие об изменении (см. OutboxRelay).
+func (r *Repository) auditDiff(ctx context.Context, q querier, op AuditOperation, clientID int, diff map[string]FieldChange) error {
+	if err := r.transformDiff(clientID, diff, r.encryptField); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	occurredAt := r.now()
+	_, err = q.ExecContext(ctx, `INSERT INTO audit_log (actor, occurred_at, operation, client_id, diff, request_id)
+		VALUES (:actor, :occurred_at, :operation, :client_id, :diff, :request_id)`,
+		sql.Named("actor", ActorFromContext(ctx)),
+		sql.Named("occurred_at", occurredAt.Format(time.RFC3339Nano)),
+		sql.Named("operation", string(op)),
+		sql.Named("client_id", clientID),
+		sql.Named("diff", string(data)),
+		sql.Named("request_id", RequestIDFromContext(ctx)))
+	if err != nil {
+		return err
+	}
+
+	return r.enqueueEvent(ctx, q, op, clientID, occurredAt, diff)
+}
+
+// AuditLog возвращает записи журнала по клиенту в порядке их появления.
+// С WithOwnerRestriction журнал чужого клиента неотличим от журнала
+// несуществующего: возвращается ErrClientNotFound (см. clientRecorded).
+func (r *Repository) AuditLog(ctx context.Context, clientID int) (_ []AuditEntry, err error) {
+	ctx, end := r.startClientOperation(ctx, "audit_log", clientID)
+	defer end(&err)
+
+	if err := r.clientRecorded(ctx, r.conn(), clientID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn().QueryContext(ctx, "SELECT id, actor, occurred_at, operation, client_id, diff, request_id FROM audit_log WHERE client_id = :client_id ORDER BY id",
+		sql.Named("client_id", clientID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var (
+			entry      AuditEntry
+			occurredAt string
+			diff       string
+		)
+		if err := rows.Scan(&entry.ID, &entry.Actor, &occurredAt, &entry.Operation, &entry.ClientID, &diff, &entry.RequestID); err != nil {
+			return nil, err
+		}
+		if entry.OccurredAt, err = time.Parse(time.RFC3339Nano, occurredAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(diff), &entry.Diff); err != nil {
+			return nil, err
+		}
+		if err := r.transformDiff(entry.ClientID, entry.Diff, r.decryptField); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *Repository) transformDiff(clientID int, diff map[string]FieldChange, fn func(clientID int, field, value string) (string, error)) error {
+	for name, change := range diff {
+		for _, v := range []*string{change.Before, change.After} {
+			if v == nil {
+				continue
+			}
+
+			out, err := fn(clientID, name, *v)
+			if err != nil {
+				return err
+			}
+			*v = out
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// Тест проверяет содержимое журнала аудита для вставки, изменения и удаления клиента
+func Test_AuditLog_InsertUpdateDelete(t *testing.T) {
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(started)
+	_, repo := setupTestDB(t, WithClock(clock))
+
+	ctx := WithActor(context.Background(), "operator@example.com")
+
+	cl := Client{
+		FIO:      "Test",
+		Login:    "Test",
+		Birthday: "19700101",
+		Email:    "mail@mail.com",
+	}
+
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	updated := cl
+	updated.Email = "new@mail.com"
+	updated.Login = "Test2"
+	clock.Advance(time.Minute)
+	require.NoError(t, repo.Update(ctx, updated), "error updating client")
+	clock.Advance(time.Minute)
+	require.NoError(t, repo.Delete(ctx, cl.ID), "error deleting client")
+
+	entries, err := repo.AuditLog(ctx, cl.ID)
+	require.NoError(t, err, "error reading audit log: %v", err)
+	require.Len(t, entries, 3, "expected insert, update and delete entries")
+
+	for i, e := range entries {
+		assert.Equal(t, "operator@example.com", e.Actor, "actor should come from context")
+		assert.Equal(t, cl.ID, e.ClientID)
+		assert.Equal(t, started.Add(time.Duration(i)*time.Minute), e.OccurredAt, "timestamp should come from the repository clock")
+	}
+
+	t.Run("Insert", func(t *testing.T) {
+		assert.Equal(t, AuditInsert, entries[0].Operation)
+		assert.Equal(t, map[string]FieldChange{
+			"fio":      {After: strPtr("Test")},
+			"login":    {After: strPtr("Test")},
+			"birthday": {After: strPtr("19700101")},
+			"email":    {After: strPtr("mail@mail.com")},
+		}, entries[0].Diff)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		assert.Equal(t, AuditUpdate, entries[1].Operation)
+		// В разницу попадают только изменившиеся поля
+		assert.Equal(t, map[string]FieldChange{
+			"login": {Before: strPtr("Test"), After: strPtr("Test2")},
+			"email": {Before: strPtr("mail@mail.com"), After: strPtr("new@mail.com")},
+		}, entries[1].Diff)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.Equal(t, AuditDelete, entries[2].Operation)
+		assert.Equal(t, map[string]FieldChange{
+			"fio":      {Before: strPtr("Test")},
+			"login":    {Before: strPtr("Test2")},
+			"birthday": {Before: strPtr("19700101")},
+			"email":    {Before: strPtr("new@mail.com")},
+		}, entries[2].Diff)
+	})
+}
+
+// Тест проверяет, что без инициатора в контексте записывается системный,
+// а изменение несуществующего клиента не попадает в журнал
+func Test_AuditLog_DefaultActorAndMissingClient(t *testing.T) {
+	_, repo := setupTestDB(t)
+
+	ctx := context.Background()
+
+	assert.Equal(t, systemActor, ActorFromContext(ctx))
+
+	err := repo.Update(ctx, Client{ID: -1, FIO: "Test", Login: "Test", Birthday: "19700101", Email: "mail@mail.com"})
+	require.ErrorIs(t, err, ErrClientNotFound)
+
+	entries, err := repo.AuditLog(ctx, -1)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "failed update should not be audited")
+}
+
+// Тест проверяет, что при включённом шифровании PII хранится в журнале зашифрованной
+func Test_AuditLog_EncryptedValues(t *testing.T) {
+	db, repo := setupTestDB(t, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	ctx := context.Background()
+
+	cl := Client{FIO: "Test", Login: "Test", Birthday: "19700101", Email: "mail@mail.com"}
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	var raw string
+	require.NoError(t, db.QueryRow("SELECT diff FROM audit_log WHERE client_id = :id", sql.Named("id", cl.ID)).Scan(&raw))
+	assert.False(t, strings.Contains(raw, cl.Email), "email should be encrypted in audit log: %s", raw)
+
+	entries, err := repo.AuditLog(ctx, cl.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, cl.Email, *entries[0].Diff["email"].After, "audit log should be decrypted on read")
+}
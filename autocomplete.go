@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Параметры Autocomplete.
+const (
+	// DefaultAutocompleteLimit — сколько подсказок Autocomplete
+	// возвращает, если limit не задан.
+	DefaultAutocompleteLimit = 10
+	// MaxAutocompleteLimit — наибольшее число подсказок за запрос.
+	MaxAutocompleteLimit = 100
+	// AutocompleteBudget — допустимая задержка Autocomplete для поля ввода
+	// с подсказками. Запрос читает индекс client_autocomplete_key по
+	// диапазону ключей и останавливается на limit-й подсказке, поэтому его
+	// время не зависит от числа клиентов (см. BenchmarkAutocomplete).
+	AutocompleteBudget = 10 * time.Millisecond
+)
+
+// Suggestion — подсказка для поля ввода: клиент, поле, по которому он
+// найден, и его ФИО и логин для показа.
+type Suggestion struct {
+	ClientID int    `json:"client_id"`
+	Field    string `json:"field"`
+	FIO      string `json:"fio"`
+	Login    string `json:"login"`
+}
+
+// autocompleteKey приводит текст к виду ключа подсказок: нижний регистр,
+// «ё» как «е», слова через один пробел.
+func autocompleteKey(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(strings.ToLower(s), "ё", "е")), " ")
+}
+
+// autocompleteKeys возвращает ключи подсказок клиента по полям: для ФИО —
+// ФИО с каждого слова до конца, чтобы клиент находился и по имени, для
+// логина — логин целиком.
+func autocompleteKeys(cl Client) map[string][]string {
+	words := strings.Fields(autocompleteKey(cl.FIO))
+	fio := make([]string, len(words))
+	for i := range words {
+		fio[i] = strings.Join(words[i:], " ")
+	}
+
+	keys := map[string][]string{"fio": fio}
+	if login := autocompleteKey(cl.Login); login != "" {
+		keys["login"] = []string{login}
+	}
+
+	return keys
+}
+
+// AutocompleteProjector — EventSink для OutboxRelay, поддерживающий
+// ключи подсказок client_autocomplete: по каждому событию ключи клиента
+// вычисляются заново по clients или удаляются, если клиента больше нет.
+// Как и client_summary, ключи отстают от clients на время публикации
+// событий; существующих клиентов в них добавляет RebuildAutocomplete.
+type AutocompleteProjector struct {
+	repo *Repository
+}
+
+// NewAutocompleteProjector создаёт проекцию событий в client_autocomplete
+// БД репозитория repo.
+func NewAutocompleteProjector(repo *Repository) *AutocompleteProjector {
+	return &AutocompleteProjector{repo: repo}
+}
+
+// Publish обновляет ключи подсказок клиента события.
+func (p *AutocompleteProjector) Publish(ctx context.Context, event ClientEvent) (err error) {
+	r := p.repo
+	ctx, end := r.startOperation(ctx, "project_autocomplete")
+	defer end(&err)
+
+	return r.inTx(ctx, func(q querier) error {
+		if _, err := q.ExecContext(ctx, "DELETE FROM client_autocomplete WHERE client_id = :id", sql.Named("id", event.ClientID)); err != nil {
+			return err
+		}
+
+		var cl Client
+		err := q.QueryRowContext(ctx, "SELECT id, fio, login FROM clients WHERE id = :id"+notDeleted, sql.Named("id", event.ClientID)).
+			Scan(&cl.ID, &cl.FIO, &cl.Login)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return insertAutocompleteKeys(ctx, q, cl)
+	})
+}
+
+func insertAutocompleteKeys(ctx context.Context, q querier, cl Client) error {
+	for field, keys := range autocompleteKeys(cl) {
+		for _, key := range keys {
+			// Ключ ФИО начинается со своего слова, поэтому фонетический
+			// ключ этого слова хранится в той же строке
+			var phonetic string
+			if field == "fio" {
+				phonetic = phoneticKey(strings.Fields(key)[0])
+			}
+
+			_, err := q.ExecContext(ctx, "INSERT INTO client_autocomplete (client_id, field, key, phonetic) VALUES (:client_id, :field, :key, :phonetic)",
+				sql.Named("client_id", cl.ID),
+				sql.Named("field", field),
+				sql.Named("key", key),
+				sql.Named("phonetic", phonetic))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RebuildAutocomplete пересоздаёт ключи подсказок всех клиентов в одной
+// транзакции и возвращает число клиентов. Используется при подключении
+// подсказок к существующей БД и после сбоев проекции.
+func (r *Repository) RebuildAutocomplete(ctx context.Context) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "rebuild_autocomplete")
+	defer end(&err)
+
+	var n int
+	err = r.inTx(ctx, func(q querier) error {
+		n = 0
+		if _, err := q.ExecContext(ctx, "DELETE FROM client_autocomplete"); err != nil {
+			return err
+		}
+
+		rows, err := q.QueryContext(ctx, "SELECT id, fio, login FROM clients WHERE 1"+notDeleted+" ORDER BY id")
+		if err != nil {
+			return err
+		}
+		var clients []Client
+		for rows.Next() {
+			var cl Client
+			if err := scanRows(rows, &cl); err != nil {
+				rows.Close()
+				return err
+			}
+			clients = append(clients, cl)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, cl := range clients {
+			if err := insertAutocompleteKeys(ctx, q, cl); err != nil {
+				return err
+			}
+		}
+		n = len(clients)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Autocomplete возвращает до limit (0 — DefaultAutocompleteLimit)
+// клиентов, у которых ФИО, любое слово ФИО или логин начинаются с prefix
+// без учёта регистра и различия «е» и «ё». Подсказки упорядочены по
+// совпавшему ключу, а при равных ключах — по ID клиента, поэтому порядок
+// определяется только данными клиентов. Клиент, найденный по нескольким
+// ключам, выдаётся один раз — по первому из них. Клиенты читаются с
+// ограничением по владельцу (см. WithOwnerRestriction).
+func (r *Repository) Autocomplete(ctx context.Context, prefix string, limit int) (_ []Suggestion, err error) {
+	ctx, end := r.startOperation(ctx, "autocomplete")
+	defer end(&err)
+
+	switch {
+	case limit == 0:
+		limit = DefaultAutocompleteLimit
+	case limit < 0 || limit > MaxAutocompleteLimit:
+		return nil, fmt.Errorf("%w: autocomplete limit must be between 1 and %d, got %d", ErrValidation, MaxAutocompleteLimit, limit)
+	}
+	if !utf8.ValidString(prefix) {
+		return nil, fmt.Errorf("%w: autocomplete prefix is not valid UTF-8", ErrValidation)
+	}
+	key := autocompleteKey(prefix)
+	if key == "" {
+		return nil, fmt.Errorf("%w: autocomplete prefix is required", ErrValidation)
+	}
+	// Пробел после последнего слова оставляет его словом целиком: ключ
+	// должен совпадать с key или продолжаться после него пробелом
+	to := key + string(utf8.MaxRune)
+	if strings.HasSuffix(prefix, " ") {
+		to = key + " " + string(utf8.MaxRune)
+	}
+
+	scope, args := r.ownerScope(ctx)
+	args = append(args,
+		sql.Named("from", key),
+		sql.Named("to", to))
+
+	// Строки читаются в порядке индекса, пока не набрано limit клиентов
+	rows, err := r.conn().QueryContext(ctx, `SELECT a.client_id, a.field, c.fio, c.login
+		FROM client_autocomplete a JOIN clients c ON c.id = a.client_id
+		WHERE a.key >= :from AND a.key < :to`+notDeleted+scope+`
+		ORDER BY a.key, a.client_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	seen := make(map[int]bool)
+	for len(suggestions) < limit && rows.Next() {
+		var s Suggestion
+		if err := rows.Scan(&s.ClientID, &s.Field, &s.FIO, &s.Login); err != nil {
+			return nil, err
+		}
+		if seen[s.ClientID] {
+			continue
+		}
+		seen[s.ClientID] = true
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
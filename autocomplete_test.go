@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAutocomplete создаёт БД в памяти с клиентами clients и ключами
+// подсказок для них.
+func setupAutocomplete(t testing.TB, clients ...Client) (*sql.DB, *Repository, []int) {
+	t.Helper()
+
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	ids := make([]int, len(clients))
+	for i, cl := range clients {
+		ids[i], err = repo.Insert(ctx, cl)
+		require.NoError(t, err)
+	}
+	_, err = repo.RebuildAutocomplete(ctx)
+	require.NoError(t, err)
+
+	return db, repo, ids
+}
+
+// suggestedIDs возвращает ID клиентов подсказок для prefix.
+func suggestedIDs(t *testing.T, repo *Repository, prefix string, limit int) []int {
+	t.Helper()
+
+	suggestions, err := repo.Autocomplete(context.Background(), prefix, limit)
+	require.NoError(t, err)
+	ids := []int{}
+	for _, s := range suggestions {
+		ids = append(ids, s.ClientID)
+	}
+
+	return ids
+}
+
+// Тест проверяет подсказки по кириллическим началам ФИО и логина: без
+// учёта регистра, с «ё» как «е», по любому слову ФИО и по логину
+func Test_Autocomplete_Cyrillic(t *testing.T) {
+	client := func(fio, login string) Client {
+		return newTestClient(func(cl *Client) { cl.FIO = fio; cl.Login = login })
+	}
+	_, repo, ids := setupAutocomplete(t,
+		client("Иванов Иван Иванович", "ivanov"),
+		client("Иванова Анна", "anna"),
+		client("Ёлкин Пётр", "elkin"),
+		client("Петров  Иван", "petrov"),
+	)
+	ivanov, ivanova, elkin, petrov := ids[0], ids[1], ids[2], ids[3]
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   []int
+	}{
+		{"ShortestKeyFirst", "ива", []int{petrov, ivanov, ivanova}},
+		{"CaseInsensitive", "ИвАнОв", []int{ivanov, ivanova}},
+		{"WholeWord", "иванов ", []int{ivanov}},
+		{"WholeLastWord", "петров иван ", []int{petrov}},
+		{"SeveralWords", "Иван Ив", []int{ivanov}},
+		{"ExtraSpaces", "  петров   иван ", []int{petrov}},
+		{"YoAsYe", "елк", []int{elkin}},
+		{"YeAsYo", "Пётр", []int{elkin, petrov}},
+		{"Login", "ann", []int{ivanova}},
+		{"LatinLogin", "E", []int{elkin}},
+		{"NoMatch", "Сидор", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, suggestedIDs(t, repo, tt.prefix, 0))
+		})
+	}
+
+	suggestions, err := repo.Autocomplete(context.Background(), "ann", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{{ClientID: ivanova, Field: "login", FIO: "Иванова Анна", Login: "anna"}}, suggestions)
+
+	assert.Equal(t, []int{petrov}, suggestedIDs(t, repo, "ива", 1))
+}
+
+// Тест проверяет, что порядок подсказок определяется только данными
+// клиентов: клиенты с одинаковым ключом идут по ID при любом порядке
+// обновления ключей, а изменение других клиентов порядок не меняет
+func Test_Autocomplete_RankingStability(t *testing.T) {
+	ctx := context.Background()
+	same := func(cl *Client) { cl.FIO = "Смирнов Алексей" }
+	_, repo, ids := setupAutocomplete(t, newTestClient(same), newTestClient(same), newTestClient(same),
+		newTestClient(func(cl *Client) { cl.FIO = "Смирнова Ольга" }))
+
+	want := ids
+	assert.Equal(t, want, suggestedIDs(t, repo, "смирнов", 0))
+
+	projector := NewAutocompleteProjector(repo)
+	for i := len(ids) - 1; i >= 0; i-- {
+		require.NoError(t, projector.Publish(ctx, ClientEvent{ClientID: ids[i]}))
+	}
+	assert.Equal(t, want, suggestedIDs(t, repo, "смирнов", 0), "order should not depend on the order keys were written")
+
+	other, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Смирнов Борис" }))
+	require.NoError(t, err)
+	require.NoError(t, projector.Publish(ctx, ClientEvent{ClientID: other}))
+	assert.Equal(t, want[:3], suggestedIDs(t, repo, "смирнов а", 0))
+	assert.Equal(t, append(append([]int{}, want[:3]...), other, want[3]), suggestedIDs(t, repo, "смирнов", 0))
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, want[:2], suggestedIDs(t, repo, "смирнов", 2))
+	}
+}
+
+// Тест проверяет, что проекция событий обновляет ключи изменённого
+// клиента и удаляет ключи удалённого
+func Test_AutocompleteProjector(t *testing.T) {
+	ctx := context.Background()
+	db, repo, ids := setupAutocomplete(t, newTestClient(func(cl *Client) { cl.FIO = "Кузнецов Олег" }))
+	projector := NewAutocompleteProjector(repo)
+
+	cl, err := repo.Select(ctx, ids[0])
+	require.NoError(t, err)
+	cl.FIO = "Кузнецова Ольга"
+	require.NoError(t, repo.Update(ctx, cl))
+	assert.Equal(t, []int{ids[0]}, suggestedIDs(t, repo, "олег", 0), "keys lag until the event is published")
+
+	require.NoError(t, projector.Publish(ctx, ClientEvent{ClientID: ids[0]}))
+	assert.Equal(t, []int{}, suggestedIDs(t, repo, "олег", 0))
+	assert.Equal(t, []int{ids[0]}, suggestedIDs(t, repo, "ольга", 0))
+
+	require.NoError(t, repo.Delete(ctx, ids[0]))
+	require.NoError(t, projector.Publish(ctx, ClientEvent{ClientID: ids[0]}))
+	assertRowCount(t, db, "client_autocomplete", 0, "1")
+}
+
+// Тест проверяет отказ от пустого начала и недопустимого числа подсказок
+func Test_Autocomplete_Errors(t *testing.T) {
+	_, repo, _ := setupAutocomplete(t)
+
+	for name, tt := range map[string]struct {
+		prefix string
+		limit  int
+	}{
+		"EmptyPrefix":   {" ", 0},
+		"InvalidUTF8":   {"\xff", 0},
+		"NegativeLimit": {"ива", -1},
+		"LimitTooLarge": {"ива", MaxAutocompleteLimit + 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.Autocomplete(context.Background(), tt.prefix, tt.limit)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Бенчмарк подсказок на 20 000 клиентах: время запроса не должно
+// превышать AutocompleteBudget ни для короткого начала с тысячами
+// совпадений, ни для длинного
+func BenchmarkAutocomplete(b *testing.B) {
+	ctx := context.Background()
+	db, repo, _ := setupAutocomplete(b)
+
+	tx, err := db.Begin()
+	require.NoError(b, err)
+	for _, cl := range fakeClients(1, 20000) {
+		_, err := tx.Exec("INSERT INTO clients (fio, login, birthday, email) VALUES (?, ?, ?, ?)", cl.FIO, cl.Login, cl.Birthday, cl.Email)
+		require.NoError(b, err)
+	}
+	require.NoError(b, tx.Commit())
+	_, err = repo.RebuildAutocomplete(ctx)
+	require.NoError(b, err)
+
+	for _, prefix := range []string{"а", "иван", "смирнова ольга"} {
+		b.Run(prefix, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.Autocomplete(ctx, prefix, DefaultAutocompleteLimit); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if perOp := b.Elapsed() / time.Duration(b.N); perOp > AutocompleteBudget {
+				b.Errorf("autocomplete %q took %v, budget %v", prefix, perOp, AutocompleteBudget)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backup сохраняет согласованную копию БД в новый файл path командой
+// VACUUM INTO. Другие соединения во время копирования продолжают читать
+// и писать; в копию попадают данные, зафиксированные к её началу. Если
+// файл path уже существует, возвращается ошибка. Репозиторий, созданный
+// через NewTxRepository, копию не создаёт: VACUUM невозможен внутри
+// транзакции.
+func (r *Repository) Backup(ctx context.Context, path string) (err error) {
+	ctx, end := r.startOperation(ctx, "backup")
+	defer end(&err)
+
+	if r.tx != nil {
+		return errors.New("backup cannot run inside a transaction")
+	}
+
+	_, err = r.conn().ExecContext(ctx, "VACUUM INTO ?", path)
+
+	return err
+}
+
+// BackupSchedule задаёт моменты резервного копирования.
+type BackupSchedule interface {
+	// Next возвращает первый момент копирования строго после t или нулевое
+	// время, если такого момента нет.
+	Next(t time.Time) time.Time
+}
+
+// Every возвращает расписание с копированием через каждые d после
+// предыдущей проверки расписания.
+func Every(d time.Duration) BackupSchedule {
+	return everySchedule(d)
+}
+
+type everySchedule time.Duration
+
+func (d everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(d))
+}
+
+// cronSchedule — расписание cron; поля — множества допустимых значений.
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domAny и dowAny — день месяца и день недели заданы как *. Если
+	// ограничены оба, подходит день, удовлетворяющий любому из них.
+	domAny, dowAny bool
+}
+
+// cronFields — поля cron-выражения в порядке записи и их допустимые значения.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// ParseCron разбирает cron-выражение из пяти полей: минута, час, день
+// месяца, месяц и день недели (0 — воскресенье). Поле — * или список через
+// запятую из чисел и диапазонов A-B, к * и диапазону можно добавить шаг /N.
+// Время расписания — UTC. Некорректное выражение возвращает ErrValidation.
+func ParseCron(expr string) (BackupSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w: cron expression %q must have %d fields", ErrValidation, expr, len(cronFields))
+	}
+
+	sets := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cron %s %q: %v", ErrValidation, cronFields[i].name, field, err)
+		}
+		sets[i] = set
+	}
+
+	return cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				return nil, errors.New("step requires * or a range")
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%s is out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// cronSearchYears — на сколько лет вперёд ищется момент по расписанию;
+// если за это время подходящего момента нет (например, 30 февраля), его
+// нет совсем.
+const cronSearchYears = 5
+
+func (c cronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !c.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.hour[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !c.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Имена файлов резервных копий: backup-<время UTC>.db. Копию, не
+// прошедшую проверку, отмечает пустой файл с тем же именем и суффиксом
+// backupUnverifiedSuffix: отметка переживает перезапуск планировщика.
+const (
+	backupPrefix           = "backup-"
+	backupSuffix           = ".db"
+	backupTimeLayout       = "20060102T150405Z"
+	backupUnverifiedSuffix = ".unverified"
+)
+
+// BackupSink — внешнее хранилище, в которое планировщик выгружает
+// резервные копии, например S3 (S3BackupSink).
+type BackupSink interface {
+	// Upload сохраняет содержимое src длиной size байт под именем name.
+	// Повторная выгрузка под тем же именем заменяет содержимое.
+	Upload(ctx context.Context, name string, src io.Reader, size int64) error
+}
+
+// BackupScheduler создаёт резервные копии БД по расписанию в каталоге,
+// выгружает их во внешние хранилища и хранит в каталоге только несколько
+// последних копий. Время берётся из часов репозитория (WithClock).
+// Планировщик запускается в процессе приложения через Run.
+type BackupScheduler struct {
+	repo     *Repository
+	dir      string
+	schedule BackupSchedule
+	keep     int
+	sinks    []BackupSink
+
+	mu   sync.Mutex
+	next time.Time
+	// pending — копии, которые ещё не удалось выгрузить в хранилище.
+	pending []pendingUpload
+	report  *backupVerificationReport
+}
+
+type pendingUpload struct {
+	path string
+	sink BackupSink
+}
+
+// NewBackupScheduler создаёт планировщик, который сохраняет копии в dir по
+// расписанию schedule, выгружает каждую копию в sinks и удаляет из dir
+// старые копии сверх keep последних, прошедших проверку (keep <= 0 —
+// хранить все). Копии в
+// хранилищах планировщик не удаляет: срок их хранения задаётся средствами
+// хранилища, например правилами жизненного цикла бакета S3.
+func NewBackupScheduler(repo *Repository, dir string, schedule BackupSchedule, keep int, sinks ...BackupSink) *BackupScheduler {
+	return &BackupScheduler{
+		repo:     repo,
+		dir:      dir,
+		schedule: schedule,
+		keep:     keep,
+		sinks:    sinks,
+	}
+}
+
+// Tick создаёт копию, если наступило время очередной копии, проверяет её
+// восстановлением (VerifyBackup), выгружает в хранилища, удаляет лишние
+// старые копии и возвращает путь созданной копии; если время ещё не
+// наступило, возвращает пустую строку. Первое
+// время копии отсчитывается от первого вызова Tick. Пропущенные моменты
+// расписания не навёрстываются: после копии следующее время отсчитывается
+// от текущего. Если копию создать не удалось, она повторяется при
+// следующем вызове. Неудачная выгрузка тоже повторяется при каждом
+// следующем вызове, пока копия остаётся в каталоге. Копия, не прошедшая
+// проверку, выгружается как обычно и отмечается в каталоге, а Tick
+// возвращает ErrBackupUnverified; в число хранимых копий она не
+// засчитывается (см. prune).
+func (s *BackupScheduler) Tick(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.repo.now()
+	if s.next.IsZero() {
+		s.next = s.schedule.Next(now)
+	}
+	if s.next.IsZero() || now.Before(s.next) {
+		return "", s.upload(ctx)
+	}
+
+	// Число строк снимается до и после копии: при одновременной записи
+	// копия может содержать любое число строк между ними
+	before, err := tableRowCounts(ctx, s.repo.conn())
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, backupPrefix+now.Format(backupTimeLayout)+backupSuffix)
+	if err := s.repo.Backup(ctx, path); err != nil {
+		return "", fmt.Errorf("backup to %s: %w", path, err)
+	}
+	s.next = s.schedule.Next(now)
+	for _, sink := range s.sinks {
+		s.pending = append(s.pending, pendingUpload{path: path, sink: sink})
+	}
+
+	return path, errors.Join(s.verify(ctx, path, before, now), s.upload(ctx), s.prune())
+}
+
+// verify проверяет созданную копию path (VerifyBackup), допуская в каждой
+// таблице число строк между before и текущим, сообщает результат в
+// ReportVerification и отмечает копию, не прошедшую проверку.
+func (s *BackupScheduler) verify(ctx context.Context, path string, before map[string]int64, now time.Time) error {
+	after, err := tableRowCounts(ctx, s.repo.conn())
+	if err == nil {
+		lo, hi := make(map[string]int64), make(map[string]int64)
+		for table, n := range after {
+			lo[table], hi[table] = n, n
+			if m, ok := before[table]; ok {
+				lo[table], hi[table] = min(m, n), max(m, n)
+			}
+		}
+		err = s.repo.verifyBackup(ctx, path, lo, hi)
+	}
+	s.report.done(ctx, path, err, now)
+	if err != nil {
+		if markErr := os.WriteFile(path+backupUnverifiedSuffix, nil, 0o600); markErr != nil {
+			err = errors.Join(err, markErr)
+		}
+	}
+
+	return err
+}
+
+// upload выгружает ожидающие копии в хранилища. Копии, удалённые из
+// каталога до выгрузки, пропускаются.
+func (s *BackupScheduler) upload(ctx context.Context) error {
+	var errs []error
+	pending := s.pending[:0]
+	for _, u := range s.pending {
+		err := uploadBackup(ctx, u.sink, filepath.Base(u.path), u.path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			errs = append(errs, fmt.Errorf("upload %s: %w", u.path, err))
+			pending = append(pending, u)
+		}
+	}
+	s.pending = pending
+
+	return errors.Join(errs...)
+}
+
+// uploadBackup выгружает файл path в sink под именем name.
+func uploadBackup(ctx context.Context, sink BackupSink, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return sink.Upload(ctx, name, f, info.Size())
+}
+
+// prune удаляет старые копии из каталога. В keep засчитываются только
+// копии, прошедшие проверку: хранятся keep последних из них, поэтому
+// неудачные проверки не вытесняют последнюю пригодную копию. Копии, не
+// прошедшие проверку, удаляются вместе с отметкой, как только появляется
+// более новая проверенная копия; до этого они хранятся для разбора.
+func (s *BackupScheduler) prune() error {
+	if s.keep <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		names[e.Name()] = true
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		stamp, ok := strings.CutPrefix(name, backupPrefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, backupSuffix)
+		if _, err := time.Parse(backupTimeLayout, stamp); !ok || err != nil {
+			continue
+		}
+		backups = append(backups, name)
+	}
+	// Время в имени упорядочено так же, как строки; копии перебираются от
+	// новых к старым
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	verified := 0
+	for _, name := range backups {
+		if !names[name+backupUnverifiedSuffix] {
+			if verified++; verified > s.keep {
+				if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if verified == 0 {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+			return err
+		}
+		if err := os.Remove(filepath.Join(s.dir, name+backupUnverifiedSuffix)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run проверяет расписание сразу и затем каждые poll до отмены ctx.
+// Ошибки копирования и выгрузки передаются в onError.
+func (s *BackupScheduler) Run(ctx context.Context, poll time.Duration, onError func(error)) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Tick(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// Шифрование копий на стороне сервера S3.
+const (
+	// SSENone — копии хранятся без шифрования на стороне сервера.
+	SSENone = ""
+	// SSES3 — шифрование ключами хранилища (SSE-S3).
+	SSES3 = "AES256"
+	// SSEKMS — шифрование ключом KMS (SSE-KMS) из S3Config.KMSKeyID.
+	SSEKMS = "aws:kms"
+)
+
+// MinS3PartSize — минимальный размер части multipart-выгрузки в S3.
+const MinS3PartSize = 5 << 20
+
+// S3Config — параметры хранилища, совместимого с S3 (AWS S3, MinIO).
+type S3Config struct {
+	// Endpoint — адрес хранилища host[:port] без схемы.
+	Endpoint string `yaml:"endpoint"`
+	// Insecure — подключаться по HTTP вместо HTTPS.
+	Insecure  bool   `yaml:"insecure"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	// Prefix добавляется к имени копии в ключе объекта, например
+	// "backups/clients/".
+	Prefix string `yaml:"prefix"`
+	// PartSize — размер части multipart-выгрузки, не меньше
+	// MinS3PartSize; 0 — размер по умолчанию (16 МБ). Копии больше одной
+	// части выгружаются по частям.
+	PartSize uint64 `yaml:"part_size"`
+	// ServerSideEncryption — SSENone, SSES3 или SSEKMS.
+	ServerSideEncryption string `yaml:"server_side_encryption"`
+	KMSKeyID             string `yaml:"kms_key_id"`
+}
+
+// S3BackupSink выгружает резервные копии в бакет S3.
+type S3BackupSink struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	opts   minio.PutObjectOptions
+}
+
+var (
+	_ BackupSink   = (*S3BackupSink)(nil)
+	_ BackupSource = (*S3BackupSink)(nil)
+)
+
+// NewS3BackupSink создаёт хранилище копий по cfg. Бакет должен уже
+// существовать; соединение с хранилищем при создании не проверяется.
+// Некорректные параметры возвращают ErrValidation.
+func NewS3BackupSink(cfg S3Config) (*S3BackupSink, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("%w: S3 endpoint and bucket are required", ErrValidation)
+	}
+	if cfg.PartSize != 0 && cfg.PartSize < MinS3PartSize {
+		return nil, fmt.Errorf("%w: S3 part size must be at least %d bytes, got %d", ErrValidation, MinS3PartSize, cfg.PartSize)
+	}
+
+	opts := minio.PutObjectOptions{
+		ContentType: "application/vnd.sqlite3",
+		PartSize:    cfg.PartSize,
+	}
+	switch cfg.ServerSideEncryption {
+	case SSENone:
+	case SSES3:
+		opts.ServerSideEncryption = encrypt.NewSSE()
+	case SSEKMS:
+		if cfg.KMSKeyID == "" {
+			return nil, fmt.Errorf("%w: %s encryption requires a KMS key ID", ErrValidation, SSEKMS)
+		}
+		sse, err := encrypt.NewSSEKMS(cfg.KMSKeyID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		opts.ServerSideEncryption = sse
+	default:
+		return nil, fmt.Errorf("%w: unknown S3 server-side encryption %q", ErrValidation, cfg.ServerSideEncryption)
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+
+	return &S3BackupSink{
+		client: client,
+		bucket: cfg.Bucket,
+		prefix: cfg.Prefix,
+		opts:   opts,
+	}, nil
+}
+
+// Upload сохраняет копию в объект Prefix+name. Копии больше части
+// выгружаются multipart-загрузкой; при ошибке незавершённая загрузка
+// отменяется.
+func (s *S3BackupSink) Upload(ctx context.Context, name string, src io.Reader, size int64) error {
+	if name == "" {
+		return errors.New("empty backup name")
+	}
+
+	_, err := s.client.PutObject(ctx, s.bucket, s.prefix+name, src, size, s.opts)
+
+	return err
+}
+
+// List возвращает имена копий с префиксом prefix без Prefix хранилища.
+func (s *S3BackupSink) List(ctx context.Context, prefix string) ([]string, error) {
+	// Отмена контекста останавливает листинг при досрочном выходе
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var names []string
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix + prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		names = append(names, strings.TrimPrefix(obj.Key, s.prefix))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Open открывает объект Prefix+name.
+func (s *S3BackupSink) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.client.GetObject(ctx, s.bucket, s.prefix+name, minio.GetObjectOptions{})
+}
@@ -0,0 +1,122 @@
+//go:build minio
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тесты выгрузки в MinIO запускаются с тегом сборки minio против
+// запущенного контейнера MinIO:
+//
+//	docker run -d -p 9000:9000 minio/minio server /data
+//	go test -tags minio -run S3 ./...
+//
+// Адрес и учётные данные задаются переменными окружения MinIOEndpointEnv,
+// MinIOAccessKeyEnv и MinIOSecretKeyEnv (по умолчанию — значения образа
+// minio/minio на localhost:9000).
+const (
+	MinIOEndpointEnv  = "CLIENTS_TEST_MINIO_ENDPOINT"
+	MinIOAccessKeyEnv = "CLIENTS_TEST_MINIO_ACCESS_KEY"
+	MinIOSecretKeyEnv = "CLIENTS_TEST_MINIO_SECRET_KEY"
+)
+
+// setupMinIO создаёт в MinIO временный бакет, который удаляется вместе с
+// содержимым после завершения теста, и возвращает параметры хранилища и
+// клиент для проверки объектов.
+func setupMinIO(t *testing.T) (S3Config, *minio.Client) {
+	t.Helper()
+
+	cfg := S3Config{
+		Endpoint:  envOr(MinIOEndpointEnv, "localhost:9000"),
+		Insecure:  true,
+		AccessKey: envOr(MinIOAccessKeyEnv, "minioadmin"),
+		SecretKey: envOr(MinIOSecretKeyEnv, "minioadmin"),
+		Bucket:    "clients-backup-test",
+		Prefix:    "backups/",
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{}), "MinIO should be running at %s", cfg.Endpoint)
+	t.Cleanup(func() {
+		for obj := range client.ListObjects(ctx, cfg.Bucket, minio.ListObjectsOptions{Recursive: true}) {
+			_ = client.RemoveObject(ctx, cfg.Bucket, obj.Key, minio.RemoveObjectOptions{})
+		}
+		_ = client.RemoveBucket(ctx, cfg.Bucket)
+	})
+
+	return cfg, client
+}
+
+// Тест проверяет выгрузку копии больше одной части multipart-загрузкой и
+// её совпадение с исходными данными
+func Test_S3BackupSink_MultipartUpload(t *testing.T) {
+	cfg, client := setupMinIO(t)
+	cfg.PartSize = MinS3PartSize
+	sink, err := NewS3BackupSink(cfg)
+	require.NoError(t, err)
+
+	data := make([]byte, 2*MinS3PartSize+1)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, sink.Upload(ctx, "backup.db", bytes.NewReader(data), int64(len(data))))
+
+	obj, err := client.GetObject(ctx, cfg.Bucket, "backups/backup.db", minio.GetObjectOptions{})
+	require.NoError(t, err)
+	defer obj.Close()
+	got, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	assert.Equal(t, data, got, "downloaded backup should match the uploaded one")
+
+	info, err := client.StatObject(ctx, cfg.Bucket, "backups/backup.db", minio.StatObjectOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, info.ETag, "-", "backup should be uploaded in parts")
+}
+
+// Тест проверяет выгрузку копий планировщиком в бакет MinIO
+func Test_BackupScheduler_S3Sink(t *testing.T) {
+	cfg, client := setupMinIO(t)
+	sink, err := NewS3BackupSink(cfg)
+	require.NoError(t, err)
+
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	scheduler := NewBackupScheduler(NewRepository(db, WithClock(clock)), t.TempDir(), Every(time.Hour), 0, sink)
+
+	_, err = scheduler.Tick(ctx)
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	path, err := scheduler.Tick(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, path)
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	obj, err := client.GetObject(ctx, cfg.Bucket, cfg.Prefix+filepath.Base(path), minio.GetObjectOptions{})
+	require.NoError(t, err)
+	defer obj.Close()
+	got, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет разбор cron-выражений и вычисление следующего момента
+// копирования
+func Test_ParseCron(t *testing.T) {
+	from := time.Date(2025, 1, 31, 10, 30, 0, 0, time.UTC) // пятница
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"EveryMinute", "* * * * *", time.Date(2025, 1, 31, 10, 31, 0, 0, time.UTC)},
+		{"DailyTomorrow", "0 3 * * *", time.Date(2025, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"StepMinutes", "*/20 * * * *", time.Date(2025, 1, 31, 10, 40, 0, 0, time.UTC)},
+		{"HourRangeWithStep", "0 8-18/4 * * *", time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"List", "15,45 10 * * *", time.Date(2025, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"NextMonth", "0 0 1 * *", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"SkipsShortMonths", "0 0 31 * *", time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"NextYear", "0 0 1 1 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"DayOfWeek", "0 0 * * 1", time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"DayOfMonthOrWeek", "0 0 15 * 0", time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"LeapDay", "0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Never", "0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(from))
+		})
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 7", "5/10 * * * *", "*/0 * * * *", "10-5 * * * *", "a * * * *"} {
+		t.Run("Invalid "+expr, func(t *testing.T) {
+			_, err := ParseCron(expr)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Тест проверяет, что планировщик по фальшивым часам создаёт копии в два
+// цикла расписания с текущими данными и удаляет копии сверх лимита, не
+// трогая посторонние файлы каталога
+func Test_BackupScheduler(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(clock))
+
+	dir := t.TempDir()
+	other := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(other, nil, 0o600))
+
+	schedule, err := ParseCron("0 * * * *")
+	require.NoError(t, err)
+	scheduler := NewBackupScheduler(repo, dir, schedule, 2)
+
+	// tick выполняет проверку расписания и возвращает путь новой копии
+	tick := func() string {
+		t.Helper()
+		path, err := scheduler.Tick(ctx)
+		require.NoError(t, err)
+		return path
+	}
+
+	assert.Empty(t, tick(), "first tick should only schedule the next backup")
+	clock.Advance(30 * time.Minute)
+	assert.Empty(t, tick(), "backup is not due yet")
+
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	clock.Advance(30 * time.Minute)
+	first := tick()
+	assert.Equal(t, filepath.Join(dir, "backup-20250101T010000Z.db"), first)
+	assert.Empty(t, tick(), "backup should not repeat within one cycle")
+
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	second := tick()
+	assert.Equal(t, filepath.Join(dir, "backup-20250101T020000Z.db"), second)
+
+	assertBackupClients(t, first, 1)
+	assertBackupClients(t, second, 2)
+
+	// Пропущенные циклы не навёрстываются, а лишняя копия удаляется
+	clock.Advance(3 * time.Hour)
+	third := tick()
+	assert.Equal(t, filepath.Join(dir, "backup-20250101T050000Z.db"), third)
+	assert.NoFileExists(t, first)
+	assert.FileExists(t, second)
+	assert.FileExists(t, third)
+	assert.FileExists(t, other)
+}
+
+// Тест проверяет расписание с интервалом и повтор неудачной копии при
+// следующей проверке
+func Test_BackupScheduler_EveryAndRetry(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(clock))
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	scheduler := NewBackupScheduler(repo, dir, Every(90*time.Minute), 0)
+
+	path, err := scheduler.Tick(ctx)
+	require.NoError(t, err)
+	require.Empty(t, path)
+
+	clock.Advance(90 * time.Minute)
+	_, err = scheduler.Tick(ctx)
+	require.Error(t, err, "backup directory does not exist")
+
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	path, err = scheduler.Tick(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "backup-20250101T013000Z.db"), path)
+
+	clock.Advance(90 * time.Minute)
+	path, err = scheduler.Tick(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "backup-20250101T030000Z.db"), path)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "keep 0 should retain all backups")
+}
+
+// fakeBackupSink — хранилище копий в памяти; пока fail не nil, выгрузка
+// возвращает fail. Выгруженные копии читаются через BackupSource.
+type fakeBackupSink struct {
+	uploads map[string][]byte
+	fail    error
+}
+
+func (s *fakeBackupSink) Upload(_ context.Context, name string, src io.Reader, size int64) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return errors.New("size mismatch")
+	}
+	if s.uploads == nil {
+		s.uploads = make(map[string][]byte)
+	}
+	s.uploads[name] = data
+
+	return nil
+}
+
+func (s *fakeBackupSink) List(_ context.Context, prefix string) ([]string, error) {
+	var names []string
+	for name := range s.uploads {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s *fakeBackupSink) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	data, ok := s.uploads[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Тест проверяет выгрузку копий в хранилище в два цикла, повтор неудачной
+// выгрузки и пропуск копии, удалённой до выгрузки
+func Test_BackupScheduler_Sink(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(clock))
+
+	dir := t.TempDir()
+	sink := &fakeBackupSink{}
+	scheduler := NewBackupScheduler(repo, dir, Every(time.Hour), 1, sink)
+
+	// tick переводит часы на час и выполняет проверку расписания
+	tick := func() (string, error) {
+		t.Helper()
+		clock.Advance(time.Hour)
+		return scheduler.Tick(ctx)
+	}
+
+	_, err := scheduler.Tick(ctx)
+	require.NoError(t, err)
+
+	first, err := tick()
+	require.NoError(t, err)
+	second, err := tick()
+	require.NoError(t, err)
+
+	require.Len(t, sink.uploads, 2)
+	data, err := os.ReadFile(second)
+	require.NoError(t, err)
+	assert.Equal(t, data, sink.uploads[filepath.Base(second)], "uploaded backup should match the local one")
+	assert.Contains(t, sink.uploads, filepath.Base(first), "pruned local backup should stay in the sink")
+
+	// Неудачная выгрузка возвращает ошибку, но копия создаётся и
+	// выгружается при следующей проверке, даже если новая копия не нужна
+	errUnavailable := errors.New("sink unavailable")
+	sink.fail = errUnavailable
+	third, err := tick()
+	require.ErrorIs(t, err, errUnavailable)
+	assert.FileExists(t, third)
+
+	sink.fail = nil
+	clock.Advance(time.Minute)
+	path, err := scheduler.Tick(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, path)
+	assert.Contains(t, sink.uploads, filepath.Base(third))
+
+	// Копия, удалённая по сроку хранения до выгрузки, больше не выгружается
+	sink.fail = errUnavailable
+	fourth, err := tick()
+	require.ErrorIs(t, err, errUnavailable)
+	fifth, err := tick()
+	require.ErrorIs(t, err, errUnavailable)
+	assert.NoFileExists(t, fourth)
+
+	sink.fail = nil
+	clock.Advance(time.Minute)
+	_, err = scheduler.Tick(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, sink.uploads, filepath.Base(fourth))
+	assert.Contains(t, sink.uploads, filepath.Base(fifth))
+}
+
+// Тест проверяет проверку параметров хранилища S3
+func Test_NewS3BackupSink_Validation(t *testing.T) {
+	valid := S3Config{Endpoint: "localhost:9000", Bucket: "backups"}
+
+	_, err := NewS3BackupSink(valid)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		modify func(cfg *S3Config)
+	}{
+		{"NoEndpoint", func(cfg *S3Config) { cfg.Endpoint = "" }},
+		{"NoBucket", func(cfg *S3Config) { cfg.Bucket = "" }},
+		{"EndpointWithScheme", func(cfg *S3Config) { cfg.Endpoint = "http://localhost:9000" }},
+		{"SmallPart", func(cfg *S3Config) { cfg.PartSize = MinS3PartSize - 1 }},
+		{"UnknownEncryption", func(cfg *S3Config) { cfg.ServerSideEncryption = "rot13" }},
+		{"KMSWithoutKey", func(cfg *S3Config) { cfg.ServerSideEncryption = SSEKMS }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			_, err := NewS3BackupSink(cfg)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Тест проверяет проверку копии восстановлением: целая копия проходит
+// проверку, а копия с другим числом строк, усечённая и пустая — нет
+func Test_VerifyBackup(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+	for i := 0; i < 50; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "backup.db")
+	require.NoError(t, repo.Backup(ctx, path))
+	stats, err := repo.Stats(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.VerifyBackup(ctx, path, stats.TableRows))
+
+	want := make(map[string]int64)
+	for table, n := range stats.TableRows {
+		want[table] = n
+	}
+	want["clients"]++
+	err = repo.VerifyBackup(ctx, path, want)
+	require.ErrorIs(t, err, ErrBackupUnverified)
+	assert.ErrorContains(t, err, "table clients has 50 rows, want 51")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for name, size := range map[string]int{"Truncated": len(data) / 2, "Empty": 0} {
+		t.Run(name, func(t *testing.T) {
+			broken := filepath.Join(dir, name+".db")
+			require.NoError(t, os.WriteFile(broken, data[:size], 0o600))
+
+			require.ErrorIs(t, repo.VerifyBackup(ctx, broken, stats.TableRows), ErrBackupUnverified)
+			got, err := os.ReadFile(broken)
+			require.NoError(t, err)
+			assert.Len(t, got, size, "verification must not modify the backup")
+		})
+	}
+}
+
+// Тест проверяет, что планировщик проверяет каждую копию и сообщает
+// результат в метриках, а непроверенную копию — ещё и в журнале
+func Test_BackupScheduler_Verification(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(clock))
+	_, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	var logs bytes.Buffer
+	scheduler := NewBackupScheduler(repo, t.TempDir(), Every(time.Hour), 0)
+	scheduler.ReportVerification(reg, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	_, err = scheduler.Tick(ctx)
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	path, err := scheduler.Tick(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, path)
+
+	assert.Equal(t, 1.0, metricValue(t, reg, "clients_backup_verifications_total", map[string]string{"outcome": outcomeSuccess}))
+	assert.Equal(t, float64(clock.Now().Unix()), metricValue(t, reg, "clients_backup_last_verified_timestamp_seconds", nil))
+	assert.Empty(t, logs.String())
+
+	// Копия, усечённая после создания, не проходит проверку
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)/2], 0o600))
+	stats, err := repo.Stats(ctx)
+	require.NoError(t, err)
+	err = scheduler.verify(ctx, path, stats.TableRows, clock.Now().Add(time.Hour))
+	require.ErrorIs(t, err, ErrBackupUnverified)
+
+	assert.Equal(t, 1.0, metricValue(t, reg, "clients_backup_verifications_total", map[string]string{"outcome": outcomeError}))
+	assert.Equal(t, float64(clock.Now().Unix()), metricValue(t, reg, "clients_backup_last_verified_timestamp_seconds", nil), "failed verification must not update the last verified time")
+	assert.Contains(t, logs.String(), "backup verification failed")
+	assert.Contains(t, logs.String(), path)
+	assert.FileExists(t, path+backupUnverifiedSuffix, "the failed backup should be marked")
+
+	// Без журнала непроверенная копия пишется в журнал по умолчанию
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	logs.Reset()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	scheduler.ReportVerification(prometheus.NewRegistry(), nil)
+	require.NotPanics(t, func() {
+		err = scheduler.verify(ctx, path, stats.TableRows, clock.Now())
+	})
+	require.ErrorIs(t, err, ErrBackupUnverified)
+	assert.Contains(t, logs.String(), "backup verification failed")
+}
+
+// Тест проверяет, что в число хранимых копий засчитываются только
+// прошедшие проверку: непроверенные копии удаляются, когда есть более
+// новая проверенная, а последняя проверенная копия хранится, даже если
+// все более новые копии проверку не прошли
+func Test_BackupScheduler_PruneUnverified(t *testing.T) {
+	db := openMemoryDB(t)
+	dir := t.TempDir()
+
+	// backup создаёт в dir копию с временем hour; unverified отмечает её
+	// как не прошедшую проверку
+	backup := func(hour int, unverified bool) string {
+		name := backupPrefix + time.Date(2025, 1, 1, hour, 0, 0, 0, time.UTC).Format(backupTimeLayout) + backupSuffix
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+		if unverified {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name+backupUnverifiedSuffix), nil, 0o600))
+		}
+		return name
+	}
+	files := func() []string {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+
+	b1, b2, b3 := backup(1, false), backup(2, false), backup(3, true)
+	b4, b5, b6 := backup(4, true), backup(5, false), backup(6, true)
+
+	require.NoError(t, NewBackupScheduler(NewRepository(db), dir, Every(time.Hour), 2).prune())
+	assert.Equal(t, []string{b2, b5, b6, b6 + backupUnverifiedSuffix}, files(), "%s, %s and %s should be pruned", b1, b3, b4)
+
+	b7 := backup(7, true)
+	require.NoError(t, NewBackupScheduler(NewRepository(db), dir, Every(time.Hour), 1).prune())
+	assert.Equal(t, []string{b5, b6, b6 + backupUnverifiedSuffix, b7, b7 + backupUnverifiedSuffix}, files(),
+		"the newest verified backup should be kept while newer ones fail verification")
+}
+
+// assertBackupClients проверяет, что копия БД path открывается и содержит
+// want клиентов.
+func assertBackupClients(t *testing.T, path string, want int) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM clients").Scan(&n))
+	assert.Equal(t, want, n)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrBackupUnverified возвращается, если резервную копию не удалось
+// восстановить или восстановленная БД не прошла проверку.
+var ErrBackupUnverified = errors.New("backup verification failed")
+
+// VerifyBackup восстанавливает копию path во временную БД и проверяет её:
+// копия должна открываться, проходить IntegrityCheck без нарушений и
+// содержать ровно want строк в каждой таблице (ключи — имена таблиц, как
+// в DBStats.TableRows). Поля клиентов проверяются после расшифровки
+// ключами репозитория. Копия, не прошедшая проверку, возвращает
+// ErrBackupUnverified; сам файл path не изменяется.
+func (r *Repository) VerifyBackup(ctx context.Context, path string, want map[string]int64) error {
+	return r.verifyBackup(ctx, path, want, want)
+}
+
+// verifyBackup проверяет копию так же, как VerifyBackup, но допускает
+// число строк в таблице от lo до hi включительно.
+func (r *Repository) verifyBackup(ctx context.Context, path string, lo, hi map[string]int64) (err error) {
+	ctx, end := r.startOperation(ctx, "verify_backup")
+	defer end(&err)
+
+	dir, err := os.MkdirTemp("", "verify-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	restored := filepath.Join(dir, filepath.Base(path))
+	if err := copyFile(path, restored); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", restored)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := checkRestoredBackup(ctx, r, db, lo, hi); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrBackupUnverified, path, err)
+	}
+
+	return nil
+}
+
+// checkRestoredBackup проверяет целостность и число строк восстановленной
+// БД db. Ошибка чтения db тоже означает непригодную копию.
+func checkRestoredBackup(ctx context.Context, r *Repository, db *sql.DB, lo, hi map[string]int64) error {
+	repo := NewRepository(db, WithClock(r.clock))
+	repo.cipher = r.cipher
+
+	report, err := repo.IntegrityCheck(ctx)
+	if err != nil {
+		return err
+	}
+	if !report.OK() {
+		first := report.Issues[0]
+		return fmt.Errorf("%d integrity issue(s), first: %s %s", len(report.Issues), first.Check, first.Detail)
+	}
+
+	counts, err := tableRowCounts(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var mismatches []string
+	for table, min := range lo {
+		max := hi[table]
+		got, ok := counts[table]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("table %s is missing", table))
+		case got < min || got > max:
+			mismatches = append(mismatches, fmt.Sprintf("table %s has %d rows, want %s", table, got, rowRange(min, max)))
+		}
+	}
+	for table := range counts {
+		if _, ok := lo[table]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("unexpected table %s", table))
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return errors.New(strings.Join(mismatches, "; "))
+	}
+
+	return nil
+}
+
+func rowRange(lo, hi int64) string {
+	if lo == hi {
+		return fmt.Sprint(lo)
+	}
+
+	return fmt.Sprintf("%d-%d", lo, hi)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// backupVerificationReport публикует результаты проверки копий
+// планировщика в метриках и журнале.
+type backupVerificationReport struct {
+	logger       *slog.Logger
+	verified     *prometheus.CounterVec
+	lastVerified prometheus.Gauge
+}
+
+// ReportVerification публикует результаты проверки копий планировщика:
+// метрики регистрируются в reg, копии, не прошедшие проверку, пишутся в
+// logger (nil — slog.Default()) с уровнем Error. Вызывается до Run.
+func (s *BackupScheduler) ReportVerification(reg prometheus.Registerer, logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	report := &backupVerificationReport{
+		logger: logger,
+		verified: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clients",
+			Subsystem: "backup",
+			Name:      "verifications_total",
+			Help:      "Number of verified backups by outcome.",
+		}, []string{"outcome"}),
+		lastVerified: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "clients",
+			Subsystem: "backup",
+			Name:      "last_verified_timestamp_seconds",
+			Help:      "Time of the newest backup that passed verification.",
+		}),
+	}
+	reg.MustRegister(report.verified, report.lastVerified)
+
+	s.mu.Lock()
+	s.report = report
+	s.mu.Unlock()
+}
+
+func (r *backupVerificationReport) done(ctx context.Context, path string, err error, at time.Time) {
+	if r == nil {
+		return
+	}
+	if err == nil {
+		r.verified.WithLabelValues(outcomeSuccess).Inc()
+		r.lastVerified.Set(float64(at.Unix()))
+		return
+	}
+
+	r.verified.WithLabelValues(outcomeError).Inc()
+	r.logger.LogAttrs(ctx, slog.LevelError, "backup verification failed",
+		slog.String("path", path),
+		slog.String("error", err.Error()),
+	)
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoWALSnapshot возвращается RestoreWAL, если в хранилище нет ни
+// одного поколения выгрузки WAL.
+var ErrNoWALSnapshot = errors.New("no WAL snapshot found")
+
+// BackupSource — хранилище, из которого читаются выгруженные копии, например
+// S3 (S3BackupSink).
+type BackupSource interface {
+	// List возвращает имена сохранённых копий с префиксом prefix по
+	// возрастанию.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Open открывает содержимое копии name.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// Имена объектов выгрузки WAL в хранилище: wal/<время>-<N>/snapshot.db —
+// файл БД в начале поколения, wal/<время>-<N>/<номер>-<время>.wal —
+// сегменты журнала в порядке номеров. Время поколения — время копии файла
+// БД, время сегмента — время чтения журнала: все транзакции сегмента
+// зафиксированы не позже него.
+const (
+	walPrefix        = "wal/"
+	walSnapshotName  = "snapshot.db"
+	walSegmentSuffix = ".wal"
+	walTimeLayout    = "20060102T150405.000000000Z"
+)
+
+// WALCheckpointFrames — после скольких выгруженных кадров журнала
+// WALShipper переносит журнал в файл БД (checkpoint), чтобы журнал не рос
+// бесконечно.
+const WALCheckpointFrames = 1000
+
+// Формат журнала WAL SQLite: заголовок файла, затем кадры из заголовка
+// кадра и страницы БД. Все числа — big-endian.
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	// walMagicBigEndian — магическое число журнала, контрольные суммы
+	// которого считаются по big-endian словам; у 0x377f0682 — по
+	// little-endian.
+	walMagicBigEndian = 0x377f0683
+)
+
+// errWALGap — журнал начался заново раньше, чем все его кадры были
+// выгружены; продолжить поколение нельзя.
+var errWALGap = errors.New("WAL restarted before all frames were shipped")
+
+// WALShipper непрерывно выгружает журнал WAL БД в хранилище: при каждой
+// синхронизации новые зафиксированные транзакции отправляются сегментом,
+// поэтому восстановление через RestoreWAL теряет данные только за время
+// после последней синхронизации, а не с последней резервной копии.
+//
+// Выгрузка идёт поколениями: поколение начинается с копии файла БД, за
+// которой следуют сегменты журнала. Переносом журнала в файл БД управляет
+// WALShipper: БД должна работать в режиме WAL, а автоматический checkpoint
+// в соединениях приложения должен быть выключен, например DSN
+// "file.db?_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)". Если
+// журнал всё же начался заново до выгрузки всех кадров, WALShipper начинает
+// новое поколение со свежей копией файла БД.
+type WALShipper struct {
+	repo             *Repository
+	sink             BackupSink
+	checkpointFrames int
+
+	mu sync.Mutex
+	// db — собственные соединения с файлом БД path.
+	db          *sql.DB
+	path        string
+	generation  string
+	generations int
+	seq         int
+	pos         walPosition
+	// pending — объекты, которые ещё не удалось выгрузить, в порядке
+	// выгрузки.
+	pending []walObject
+}
+
+// walPosition — выгруженная часть журнала.
+type walPosition struct {
+	salt    [2]uint32
+	ckptSeq uint32
+	// frames — число выгруженных кадров, cksum — контрольная сумма
+	// последнего из них.
+	frames int
+	cksum  [2]uint32
+	// fresh — поколение только началось, подходит любой журнал;
+	// restartable — все кадры журнала выгружены и перенесены в файл БД,
+	// поэтому журнал может начаться заново.
+	fresh, restartable bool
+}
+
+// walObject — объект для выгрузки: содержимое data или файл file.
+type walObject struct {
+	name string
+	data []byte
+	file string
+}
+
+// NewWALShipper создаёт выгрузку журнала БД репозитория repo в sink.
+// Соединения с файлом БД открываются при первой синхронизации.
+func NewWALShipper(repo *Repository, sink BackupSink) *WALShipper {
+	return &WALShipper{
+		repo:             repo,
+		sink:             sink,
+		checkpointFrames: WALCheckpointFrames,
+	}
+}
+
+// Sync выгружает транзакции, зафиксированные после предыдущей
+// синхронизации; первая синхронизация начинает поколение. Если выгрузка не
+// удалась, неотправленные объекты повторяются при следующем вызове, а
+// журнал до этого не переносится в файл БД. БД в памяти возвращает
+// ErrValidation.
+func (s *WALShipper) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flush(ctx); err != nil {
+		return err
+	}
+	if s.db == nil {
+		if err := s.open(ctx); err != nil {
+			return err
+		}
+	}
+
+	err := s.sync(ctx)
+	if errors.Is(err, errWALGap) {
+		err = s.startGeneration(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.flush(ctx)
+}
+
+func (s *WALShipper) sync(ctx context.Context) error {
+	if s.generation == "" {
+		return s.startGeneration(ctx)
+	}
+	if err := s.ship(); err != nil {
+		return err
+	}
+	if s.pos.frames >= s.checkpointFrames {
+		return s.checkpoint(ctx)
+	}
+
+	return nil
+}
+
+// open открывает собственные соединения с файлом БД: checkpoint и
+// блокировка записи не должны зависеть от пула соединений репозитория.
+func (s *WALShipper) open(ctx context.Context) error {
+	path, err := s.repo.databaseFile(ctx)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("%w: WAL shipping requires a database file", ErrValidation)
+	}
+
+	var mode string
+	if err := s.repo.conn().QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil {
+		return err
+	}
+	if !strings.EqualFold(mode, "wal") {
+		return fmt.Errorf("%w: WAL shipping requires journal_mode=WAL, got %s", ErrValidation, mode)
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=wal_autocheckpoint(0)")
+	if err != nil {
+		return err
+	}
+	s.db, s.path = db, path
+
+	return nil
+}
+
+// startGeneration начинает новое поколение: копирует файл БД и выгружает
+// весь текущий журнал. Пока файл и журнал читаются, запись в БД
+// заблокирована.
+func (s *WALShipper) startGeneration(ctx context.Context) error {
+	return s.withWriteLock(ctx, func() error {
+		snapshot, err := os.CreateTemp("", "wal-snapshot-*")
+		if err != nil {
+			return err
+		}
+		snapshot.Close()
+		if err := copyFile(s.path, snapshot.Name()); err != nil {
+			os.Remove(snapshot.Name())
+			return err
+		}
+
+		s.generation = fmt.Sprintf("%s-%04d", s.repo.now().UTC().Format(walTimeLayout), s.generations)
+		s.generations++
+		s.seq = 0
+		s.pos = walPosition{fresh: true}
+		s.pending = append(s.pending, walObject{
+			name: walPrefix + s.generation + "/" + walSnapshotName,
+			file: snapshot.Name(),
+		})
+
+		return s.ship()
+	})
+}
+
+// checkpoint выгружает оставшиеся кадры и переносит журнал в файл БД.
+// Запись заблокирована, поэтому после переноса в журнале нет
+// невыгруженных кадров и следующая транзакция может начать его заново.
+func (s *WALShipper) checkpoint(ctx context.Context) error {
+	return s.withWriteLock(ctx, func() error {
+		if err := s.ship(); err != nil {
+			return err
+		}
+
+		var busy, log, done int
+		if err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &log, &done); err != nil {
+			return err
+		}
+		s.pos.restartable = log == s.pos.frames && done == log
+
+		return nil
+	})
+}
+
+// withWriteLock выполняет fn, удерживая блокировку записи в БД.
+func (s *WALShipper) withWriteLock(ctx context.Context, fn func() error) (err error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return err
+	}
+	defer func() {
+		if _, rbErr := conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK"); rbErr != nil {
+			err = errors.Join(err, rbErr)
+		}
+	}()
+
+	return fn()
+}
+
+// ship читает журнал и ставит в очередь выгрузки сегмент из кадров,
+// зафиксированных после выгруженной позиции. Незафиксированные и
+// недописанные кадры остаются до следующего вызова.
+func (s *WALShipper) ship() error {
+	data, err := os.ReadFile(s.path + "-wal")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	hdr, ok := parseWALHeader(data)
+	if !ok {
+		// Журнал пуст: его перенесли в файл БД и обрезали
+		if s.pos.frames > 0 && !s.pos.restartable {
+			return errWALGap
+		}
+		return nil
+	}
+
+	if hdr.salt != s.pos.salt {
+		switch {
+		case s.pos.fresh:
+		case s.pos.restartable && hdr.ckptSeq == s.pos.ckptSeq+1:
+		default:
+			return errWALGap
+		}
+		s.pos = walPosition{salt: hdr.salt, ckptSeq: hdr.ckptSeq, cksum: hdr.cksum}
+	}
+
+	frameSize := walFrameHeaderSize + hdr.pageSize
+	start := walHeaderSize + s.pos.frames*frameSize
+	end, frames, cksum := start, s.pos.frames, s.pos.cksum
+	sum := cksum
+	for off, n := start, s.pos.frames; off+frameSize <= len(data); off, n = off+frameSize, n+1 {
+		frame := data[off : off+frameSize]
+		if binary.BigEndian.Uint32(frame[8:]) != hdr.salt[0] || binary.BigEndian.Uint32(frame[12:]) != hdr.salt[1] {
+			break
+		}
+		sum = walChecksum(hdr.bigEndian, frame[:8], sum)
+		sum = walChecksum(hdr.bigEndian, frame[walFrameHeaderSize:], sum)
+		if sum != [2]uint32{binary.BigEndian.Uint32(frame[16:]), binary.BigEndian.Uint32(frame[20:])} {
+			break
+		}
+		// Журнал дописан после выгруженной позиции
+		s.pos.restartable = false
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			end, frames, cksum = off+frameSize, n+1, sum
+		}
+	}
+	if end == start {
+		return nil
+	}
+
+	segment := make([]byte, 0, walHeaderSize+end-start)
+	segment = append(segment, data[:walHeaderSize]...)
+	segment = append(segment, data[start:end]...)
+	s.pending = append(s.pending, walObject{
+		name: fmt.Sprintf("%s%s/%08d-%s%s", walPrefix, s.generation, s.seq, s.repo.now().UTC().Format(walTimeLayout), walSegmentSuffix),
+		data: segment,
+	})
+	s.seq++
+	s.pos.frames, s.pos.cksum = frames, cksum
+
+	return nil
+}
+
+// flush выгружает объекты очереди по порядку до первой ошибки.
+func (s *WALShipper) flush(ctx context.Context) error {
+	for len(s.pending) > 0 {
+		obj := s.pending[0]
+		var err error
+		if obj.file != "" {
+			err = uploadBackup(ctx, s.sink, obj.name, obj.file)
+		} else {
+			err = s.sink.Upload(ctx, obj.name, bytes.NewReader(obj.data), int64(len(obj.data)))
+		}
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", obj.name, err)
+		}
+		if obj.file != "" {
+			os.Remove(obj.file)
+		}
+		s.pending = s.pending[1:]
+	}
+
+	return nil
+}
+
+// Run синхронизирует журнал сразу и затем каждые poll до отмены ctx.
+// Ошибки синхронизации передаются в onError.
+func (s *WALShipper) Run(ctx context.Context, poll time.Duration, onError func(error)) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close закрывает соединения WALShipper с БД; невыгруженные объекты
+// очереди теряются.
+func (s *WALShipper) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, obj := range s.pending {
+		if obj.file != "" {
+			os.Remove(obj.file)
+		}
+	}
+	s.pending = nil
+	if s.db == nil {
+		return nil
+	}
+
+	return s.db.Close()
+}
+
+// walHeader — разобранный заголовок журнала.
+type walHeader struct {
+	bigEndian bool
+	pageSize  int
+	ckptSeq   uint32
+	salt      [2]uint32
+	cksum     [2]uint32
+}
+
+// parseWALHeader разбирает заголовок журнала data; ok равен false, если
+// заголовка нет или он повреждён.
+func parseWALHeader(data []byte) (_ walHeader, ok bool) {
+	if len(data) < walHeaderSize {
+		return walHeader{}, false
+	}
+
+	magic := binary.BigEndian.Uint32(data)
+	if magic&^1 != walMagicBigEndian&^1 {
+		return walHeader{}, false
+	}
+	hdr := walHeader{
+		bigEndian: magic == walMagicBigEndian,
+		pageSize:  int(binary.BigEndian.Uint32(data[8:])),
+		ckptSeq:   binary.BigEndian.Uint32(data[12:]),
+		salt:      [2]uint32{binary.BigEndian.Uint32(data[16:]), binary.BigEndian.Uint32(data[20:])},
+		cksum:     [2]uint32{binary.BigEndian.Uint32(data[24:]), binary.BigEndian.Uint32(data[28:])},
+	}
+	if walChecksum(hdr.bigEndian, data[:24], [2]uint32{}) != hdr.cksum {
+		return walHeader{}, false
+	}
+
+	return hdr, true
+}
+
+// walChecksum продолжает контрольную сумму журнала sum по данным b, длина
+// которых кратна 8.
+func walChecksum(bigEndian bool, b []byte, sum [2]uint32) [2]uint32 {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+
+	s0, s1 := sum[0], sum[1]
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+
+	return [2]uint32{s0, s1}
+}
+
+// RestoreWAL восстанавливает БД в новый файл path из последнего поколения
+// выгрузки WAL в src: копирует файл БД поколения и применяет к нему все
+// сегменты журнала по порядку. Если файл path уже существует, возвращается
+// ошибка; если выгрузок нет — ErrNoWALSnapshot. Восстановленную БД
+// стоит проверить через IntegrityCheck.
+func RestoreWAL(ctx context.Context, src BackupSource, path string) error {
+	return restoreWAL(ctx, src, path, time.Time{})
+}
+
+// RestoreToTimestamp восстанавливает в новый файл target состояние БД на
+// момент ts из выгрузки WAL в src: берёт последнее поколение, начатое не
+// позже ts, и применяет только сегменты, прочитанные из журнала не позже
+// ts. Точность восстановления — интервал синхронизации WALShipper:
+// транзакции, зафиксированные после последней синхронизации перед ts, в
+// БД не попадают. Если поколений до ts нет, возвращается ErrNoWALSnapshot.
+func RestoreToTimestamp(ctx context.Context, src BackupSource, target string, ts time.Time) error {
+	if ts.IsZero() {
+		return fmt.Errorf("%w: restore timestamp is required", ErrValidation)
+	}
+
+	return restoreWAL(ctx, src, target, ts)
+}
+
+// restoreWAL восстанавливает БД из выгрузки WAL на момент until; нулевое
+// until — последнее выгруженное состояние.
+func restoreWAL(ctx context.Context, src BackupSource, path string, until time.Time) (err error) {
+	names, err := src.List(ctx, walPrefix)
+	if err != nil {
+		return err
+	}
+
+	var generation string
+	for _, name := range names {
+		gen, file, ok := strings.Cut(strings.TrimPrefix(name, walPrefix), "/")
+		if !ok || file != walSnapshotName || gen <= generation {
+			continue
+		}
+		if !until.IsZero() && !walNameBefore(gen, until) {
+			continue
+		}
+		generation = gen
+	}
+	if generation == "" {
+		return ErrNoWALSnapshot
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	dir := walPrefix + generation + "/"
+	if err := copyObject(ctx, src, dir+walSnapshotName, f); err != nil {
+		return err
+	}
+
+	var segments []string
+	for _, name := range names {
+		segment, ok := strings.CutPrefix(name, dir)
+		if !ok || !strings.HasSuffix(segment, walSegmentSuffix) {
+			continue
+		}
+		if !until.IsZero() {
+			_, shipped, _ := strings.Cut(strings.TrimSuffix(segment, walSegmentSuffix), "-")
+			if !walNameBefore(shipped, until) {
+				continue
+			}
+		}
+		segments = append(segments, name)
+	}
+	sort.Strings(segments)
+
+	for _, name := range segments {
+		var segment bytes.Buffer
+		if err := copyObject(ctx, src, name, &segment); err != nil {
+			return err
+		}
+		if err := applyWALSegment(f, segment.Bytes()); err != nil {
+			return fmt.Errorf("apply %s: %w", name, err)
+		}
+	}
+
+	return f.Sync()
+}
+
+// walNameBefore сообщает, что имя поколения или сегмента начинается с
+// времени не позже t.
+func walNameBefore(name string, t time.Time) bool {
+	stamp, _, _ := strings.Cut(name, "-")
+	at, err := time.Parse(walTimeLayout, stamp)
+
+	return err == nil && !at.After(t)
+}
+
+func copyObject(ctx context.Context, src BackupSource, name string, dst io.Writer) error {
+	r, err := src.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(dst, r)
+
+	return err
+}
+
+// applyWALSegment записывает страницы кадров сегмента в файл БД f и
+// обрезает файл до размера БД, указанного в кадре фиксации.
+func applyWALSegment(f *os.File, segment []byte) error {
+	hdr, ok := parseWALHeader(segment)
+	if !ok {
+		return errors.New("invalid WAL segment header")
+	}
+
+	frameSize := walFrameHeaderSize + hdr.pageSize
+	frames := segment[walHeaderSize:]
+	if len(frames)%frameSize != 0 {
+		return errors.New("truncated WAL segment")
+	}
+
+	pageSize := int64(hdr.pageSize)
+	for off := 0; off < len(frames); off += frameSize {
+		frame := frames[off : off+frameSize]
+		pgno := int64(binary.BigEndian.Uint32(frame))
+		if _, err := f.WriteAt(frame[walFrameHeaderSize:], (pgno-1)*pageSize); err != nil {
+			return err
+		}
+		if commit := int64(binary.BigEndian.Uint32(frame[4:])); commit != 0 {
+			if err := f.Truncate(commit * pageSize); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
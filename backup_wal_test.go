@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openWALRepository открывает файловую БД в режиме WAL с выключенным
+// автоматическим checkpoint, как того требует WALShipper.
+func openWALRepository(t *testing.T, opts ...Option) (*sql.DB, *Repository) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "clients.db")
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Migrate(context.Background(), db))
+
+	return db, NewRepository(db, opts...)
+}
+
+// newWALShipper создаёт выгрузку журнала, которая закрывается после
+// завершения теста.
+func newWALShipper(t *testing.T, repo *Repository, sink BackupSink) *WALShipper {
+	t.Helper()
+
+	shipper := NewWALShipper(repo, sink)
+	t.Cleanup(func() { shipper.Close() })
+
+	return shipper
+}
+
+// dumpClients возвращает клиентов БД в виде строк для сравнения.
+func dumpClients(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT id, fio, login, email FROM clients ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var dump []string
+	for rows.Next() {
+		var (
+			id                int
+			fio, login, email string
+		)
+		require.NoError(t, rows.Scan(&id, &fio, &login, &email))
+		dump = append(dump, strings.Join([]string{fio, login, email}, "|"))
+	}
+	require.NoError(t, rows.Err())
+
+	return dump
+}
+
+// restoreWALClients восстанавливает БД из выгрузки журнала в sink и
+// возвращает клиентов восстановленной БД (см. restoredClients).
+func restoreWALClients(t *testing.T, sink *fakeBackupSink) []string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "restored.db")
+	require.NoError(t, RestoreWAL(context.Background(), sink, path))
+
+	return restoredClients(t, path)
+}
+
+// restoredClients проверяет целостность восстановленной БД path и
+// возвращает её клиентов.
+func restoredClients(t *testing.T, path string) []string {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	report, err := NewRepository(db).IntegrityCheck(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues, "restored database should pass the integrity check")
+
+	return dumpClients(t, db)
+}
+
+// walSnapshots возвращает имена копий файла БД, с которых начинаются
+// поколения выгрузки журнала.
+func walSnapshots(sink *fakeBackupSink) []string {
+	var snapshots []string
+	for name := range sink.uploads {
+		if strings.HasSuffix(name, "/"+walSnapshotName) {
+			snapshots = append(snapshots, name)
+		}
+	}
+
+	return snapshots
+}
+
+// Тест проверяет восстановление БД из копии файла и сегментов журнала,
+// выгруженных за несколько синхронизаций: восстанавливаются все
+// транзакции до последней синхронизации и только они
+func Test_WALShipper_Replay(t *testing.T) {
+	db, repo := openWALRepository(t)
+	ctx := context.Background()
+	sink := &fakeBackupSink{}
+	shipper := newWALShipper(t, repo, sink)
+
+	for i := 0; i < 5; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	require.NoError(t, shipper.Sync(ctx))
+
+	ids := make([]int, 0, 5)
+	for i := 0; i < 5; i++ {
+		id, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Second batch" }))
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	cl, err := repo.Select(ctx, ids[0])
+	require.NoError(t, err)
+	cl.Email = "updated@mail.com"
+	require.NoError(t, repo.Update(ctx, cl))
+	require.NoError(t, repo.Delete(ctx, ids[1]))
+	require.NoError(t, shipper.Sync(ctx))
+
+	want := dumpClients(t, db)
+	require.Len(t, want, 9)
+	assert.Equal(t, want, restoreWALClients(t, sink))
+	assert.Len(t, walSnapshots(sink), 1, "all syncs should belong to one generation")
+
+	// Транзакции после последней синхронизации не восстанавливаются
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	assert.Equal(t, want, restoreWALClients(t, sink))
+}
+
+// Тест проверяет, что журнал переносится в файл БД и начинается заново, а
+// выгрузка продолжается в том же поколении
+func Test_WALShipper_Checkpoint(t *testing.T) {
+	db, repo := openWALRepository(t)
+	ctx := context.Background()
+	sink := &fakeBackupSink{}
+	shipper := newWALShipper(t, repo, sink)
+	shipper.checkpointFrames = 4
+
+	var restarted bool
+	for i := 0; i < 20; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		before := shipper.pos.salt
+		require.NoError(t, shipper.Sync(ctx))
+		restarted = restarted || (before != [2]uint32{} && shipper.pos.salt != before)
+	}
+
+	assert.True(t, restarted, "WAL should restart after a checkpoint")
+	assert.Len(t, walSnapshots(sink), 1, "checkpoints should not start a new generation")
+	assert.Equal(t, dumpClients(t, db), restoreWALClients(t, sink))
+}
+
+// Тест проверяет, что после переноса журнала в файл БД в обход
+// WALShipper невыгруженные транзакции не теряются: начинается новое
+// поколение, и восстанавливается последнее
+func Test_WALShipper_GapStartsNewGeneration(t *testing.T) {
+	db, repo := openWALRepository(t)
+	ctx := context.Background()
+	sink := &fakeBackupSink{}
+	shipper := newWALShipper(t, repo, sink)
+
+	_, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	require.NoError(t, shipper.Sync(ctx))
+
+	_, err = repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Unshipped" }))
+	require.NoError(t, err)
+	_, err = db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	require.NoError(t, shipper.Sync(ctx))
+
+	assert.Len(t, walSnapshots(sink), 2, "lost WAL frames should start a new generation")
+	want := dumpClients(t, db)
+	require.Len(t, want, 3)
+	assert.Equal(t, want, restoreWALClients(t, sink))
+}
+
+// Тест проверяет повтор неудачной выгрузки при следующей синхронизации
+func Test_WALShipper_UploadRetry(t *testing.T) {
+	db, repo := openWALRepository(t)
+	ctx := context.Background()
+	sink := &fakeBackupSink{}
+	shipper := newWALShipper(t, repo, sink)
+
+	require.NoError(t, shipper.Sync(ctx))
+	_, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	sink.fail = os.ErrDeadlineExceeded
+	require.ErrorIs(t, shipper.Sync(ctx), os.ErrDeadlineExceeded)
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	sink.fail = nil
+	require.NoError(t, shipper.Sync(ctx))
+	assert.Equal(t, dumpClients(t, db), restoreWALClients(t, sink))
+}
+
+// Тест проверяет ошибки выгрузки и восстановления: БД в памяти, пустое
+// хранилище и уже существующий файл
+func Test_WALShipper_Errors(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	sink := &fakeBackupSink{}
+	require.ErrorIs(t, newWALShipper(t, NewRepository(db), sink).Sync(ctx), ErrValidation)
+
+	path := filepath.Join(t.TempDir(), "restored.db")
+	require.ErrorIs(t, RestoreWAL(ctx, sink, path), ErrNoWALSnapshot)
+
+	_, repo := openWALRepository(t)
+	require.NoError(t, newWALShipper(t, repo, sink).Sync(ctx))
+	require.NoError(t, os.WriteFile(path, []byte("keep"), 0o600))
+	require.Error(t, RestoreWAL(ctx, sink, path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "keep", string(data), "restore must not overwrite an existing file")
+}
+
+// Тест проверяет восстановление на момент между известными изменениями:
+// восстанавливается состояние после предыдущего изменения, а поколение
+// выбирается последнее из начатых до этого момента
+func Test_RestoreToTimestamp(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(start)
+	db, repo := openWALRepository(t, WithClock(clock))
+	ctx := context.Background()
+	sink := &fakeBackupSink{}
+	shipper := newWALShipper(t, repo, sink)
+
+	// restore восстанавливает БД на момент ts и возвращает её клиентов
+	restore := func(ts time.Time) []string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "restored.db")
+		require.NoError(t, RestoreToTimestamp(ctx, sink, path, ts))
+		return restoredClients(t, path)
+	}
+
+	id, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Email = "before@mail.com" }))
+	require.NoError(t, err)
+	require.NoError(t, shipper.Sync(ctx))
+	first := dumpClients(t, db)
+
+	clock.Advance(time.Hour)
+	cl, err := repo.Select(ctx, id)
+	require.NoError(t, err)
+	cl.Email = "after@mail.com"
+	require.NoError(t, repo.Update(ctx, cl))
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	require.NoError(t, shipper.Sync(ctx))
+	second := dumpClients(t, db)
+
+	clock.Advance(time.Hour)
+	require.NoError(t, repo.Delete(ctx, id))
+	require.NoError(t, shipper.Sync(ctx))
+	third := dumpClients(t, db)
+
+	// Перенос журнала в обход WALShipper начинает второе поколение
+	clock.Advance(time.Hour)
+	_, err = db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	require.NoError(t, shipper.Sync(ctx))
+	require.Len(t, walSnapshots(sink), 2)
+
+	assert.Equal(t, first, restore(start.Add(30*time.Minute)))
+	assert.Contains(t, first[0], "before@mail.com")
+	assert.Equal(t, second, restore(start.Add(90*time.Minute)))
+	assert.Equal(t, second, restore(start.Add(time.Hour)), "segment shipped exactly at ts should be applied")
+	assert.Equal(t, third, restore(start.Add(150*time.Minute)))
+	assert.Equal(t, dumpClients(t, db), restore(start.Add(24*time.Hour)))
+
+	require.ErrorIs(t, RestoreToTimestamp(ctx, sink, filepath.Join(t.TempDir(), "early.db"), start.Add(-time.Second)), ErrNoWALSnapshot)
+	require.ErrorIs(t, RestoreToTimestamp(ctx, sink, filepath.Join(t.TempDir(), "zero.db"), time.Time{}), ErrValidation)
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LeapDayPolicy определяет, когда родившиеся 29 февраля отмечают день
+// рождения в невисокосные годы.
+type LeapDayPolicy int
+
+const (
+	// LeapDayFeb28 — 28 февраля (по умолчанию).
+	LeapDayFeb28 LeapDayPolicy = iota
+	// LeapDayMar1 — 1 марта.
+	LeapDayMar1
+)
+
+// WithBirthdays задаёт часовой пояс, по которому определяется «сегодня»
+// для дней рождения, и правило для родившихся 29 февраля. По умолчанию
+// используются UTC и LeapDayFeb28.
+func WithBirthdays(loc *time.Location, policy LeapDayPolicy) Option {
+	return func(r *Repository) {
+		r.birthdayLoc = loc
+		r.leapDay = policy
+	}
+}
+
+// birthdayLocation возвращает часовой пояс дней рождения репозитория.
+func (r *Repository) birthdayLocation() *time.Location {
+	if r.birthdayLoc == nil {
+		return time.UTC
+	}
+
+	return r.birthdayLoc
+}
+
+// calendarDay возвращает календарный день момента t в часовом поясе loc
+// как полночь UTC этого дня.
+func calendarDay(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// today возвращает текущий день в часовом поясе дней рождения как
+// полночь UTC этого дня.
+func (r *Repository) today() time.Time {
+	return calendarDay(r.clock.Now(), r.birthdayLocation())
+}
+
+// UpcomingBirthday — клиент и дата его ближайшего дня рождения.
+type UpcomingBirthday struct {
+	Client
+	// Date — дата ближайшего дня рождения (полночь UTC).
+	Date time.Time `json:"date"`
+	// Age — сколько лет исполнится клиенту в этот день.
+	Age int `json:"age"`
+}
+
+// upcomingBirthdayFields — поля UpcomingBirthday помимо полей клиента.
+type upcomingBirthdayFields struct {
+	Date time.Time `json:"date"`
+	Age  int       `json:"age"`
+}
+
+// MarshalJSON выводит поля клиента, дату дня рождения и возраст одним
+// объектом.
+func (b UpcomingBirthday) MarshalJSON() ([]byte, error) {
+	return marshalWithClient(b.Client, upcomingBirthdayFields{b.Date, b.Age})
+}
+
+// UnmarshalJSON разбирает объект, выведенный MarshalJSON.
+func (b *UpcomingBirthday) UnmarshalJSON(data []byte) error {
+	var extra upcomingBirthdayFields
+	if err := unmarshalWithClient(data, &b.Client, &extra, "date", "age"); err != nil {
+		return err
+	}
+	b.Date, b.Age = extra.Date, extra.Age
+
+	return nil
+}
+
+// UpcomingBirthdays возвращает клиентов, у которых день рождения сегодня или
+// в ближайшие days дней, в порядке наступления дней рождения. «Сегодня» и
+// дни рождения 29 февраля определяются настройками WithBirthdays.
+func (r *Repository) UpcomingBirthdays(ctx context.Context, days int) (_ []UpcomingBirthday, err error) {
+	ctx, end := r.startOperation(ctx, "upcoming_birthdays")
+	defer end(&err)
+
+	return r.clientsWithBirthdayInNextNDays(ctx, days)
+}
+
+// clientsWithBirthdayInNextNDays отбирает клиентов по месяцу и дню даты
+// рождения ГГГГММДД. Дата рождения может храниться зашифрованной, поэтому
+// отбор выполняется после расшифровки, а не запросом. Клиенты с
+// некорректной датой рождения (записанные до её проверки) пропускаются.
+func (r *Repository) clientsWithBirthdayInNextNDays(ctx context.Context, days int) ([]UpcomingBirthday, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("%w: days must not be negative, got %d", ErrValidation, days)
+	}
+
+	today := r.today()
+	last := today.AddDate(0, 0, days)
+
+	var upcoming []UpcomingBirthday
+	err := r.forEach(ctx, "", nil, func(cl Client) error {
+		birthday, err := time.Parse(birthdayLayout, cl.Birthday)
+		if err != nil {
+			return nil
+		}
+
+		date := nextBirthday(birthday, today, r.leapDay)
+		if date.After(last) {
+			return nil
+		}
+		upcoming = append(upcoming, UpcomingBirthday{Client: cl, Date: date, Age: date.Year() - birthday.Year()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Date.Before(upcoming[j].Date)
+	})
+
+	return upcoming, nil
+}
+
+// nextBirthday возвращает ближайший день рождения не раньше today.
+func nextBirthday(birthday, today time.Time, policy LeapDayPolicy) time.Time {
+	date := birthdayIn(birthday, today.Year(), policy)
+	if date.Before(today) {
+		date = birthdayIn(birthday, today.Year()+1, policy)
+	}
+
+	return date
+}
+
+// birthdayIn возвращает день рождения в году year. Родившиеся 29 февраля
+// в невисокосные годы отмечают день рождения 28 февраля или 1 марта по
+// правилу policy.
+func birthdayIn(birthday time.Time, year int, policy LeapDayPolicy) time.Time {
+	if birthday.Month() == time.February && birthday.Day() == 29 && !isLeapYear(year) {
+		if policy == LeapDayMar1 {
+			return time.Date(year, time.March, 1, 0, 0, 0, 0, time.UTC)
+		}
+		return time.Date(year, time.February, 28, 0, 0, 0, 0, time.UTC)
+	}
+
+	return time.Date(year, birthday.Month(), birthday.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// birthdayMonthDays возвращает месяц и день дат рождения ММДД, которые
+// отмечаются в день day по правилу policy: в невисокосный год к 28
+// февраля или 1 марта добавляется 29 февраля.
+func birthdayMonthDays(day time.Time, policy LeapDayPolicy) []string {
+	monthDays := []string{day.Format("0102")}
+	if isLeapYear(day.Year()) {
+		return monthDays
+	}
+	if policy == LeapDayMar1 && day.Month() == time.March && day.Day() == 1 ||
+		policy == LeapDayFeb28 && day.Month() == time.February && day.Day() == 28 {
+		monthDays = append(monthDays, "0229")
+	}
+
+	return monthDays
+}
+
+// BirthdaysToday возвращает клиентов, у которых сегодня день рождения, по
+// часовому поясу и правилу 29 февраля из WithBirthdays, в порядке
+// возрастания ID.
+func (r *Repository) BirthdaysToday(ctx context.Context) (_ []UpcomingBirthday, err error) {
+	ctx, end := r.startOperation(ctx, "birthdays_today")
+	defer end(&err)
+
+	return r.clientsWithBirthdayOn(ctx, r.clock.Now(), r.birthdayLocation())
+}
+
+// clientsWithBirthdayOn возвращает клиентов, у которых день рождения
+// приходится на календарный день момента date в часовом поясе loc, в
+// порядке возрастания ID. Месяц и день даты рождения ГГГГММДД сравниваются
+// запросом, если дата рождения хранится открыто, и после расшифровки, если
+// зашифрована. Клиенты с некорректной датой рождения пропускаются.
+func (r *Repository) clientsWithBirthdayOn(ctx context.Context, date time.Time, loc *time.Location) ([]UpcomingBirthday, error) {
+	day := calendarDay(date, loc)
+	monthDays := birthdayMonthDays(day, r.leapDay)
+
+	var (
+		cond string
+		args []any
+	)
+	if r.cipher == nil {
+		cond = " AND substr(birthday, 5, 4) IN (:md0"
+		args = []any{sql.Named("md0", monthDays[0])}
+		if len(monthDays) > 1 {
+			cond += ", :md1"
+			args = append(args, sql.Named("md1", monthDays[1]))
+		}
+		cond += ")"
+	}
+
+	var found []UpcomingBirthday
+	err := r.forEach(ctx, cond, args, func(cl Client) error {
+		birthday, err := time.Parse(birthdayLayout, cl.Birthday)
+		if err != nil || !slices.Contains(monthDays, cl.Birthday[4:]) || birthday.After(day) {
+			return nil
+		}
+		found = append(found, UpcomingBirthday{Client: cl, Date: day, Age: day.Year() - birthday.Year()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// BirthdayReminderEvent — напоминание о дне рождения клиента.
+type BirthdayReminderEvent struct {
+	ClientID int       `json:"client_id"`
+	FIO      string    `json:"fio"`
+	Email    string    `json:"email"`
+	Date     time.Time `json:"date"`
+	Age      int       `json:"age"`
+	DaysLeft int       `json:"days_left"`
+}
+
+// BirthdayReminder периодически ищет клиентов с днём рождения в ближайшие
+// дни и отправляет о каждом дне рождения одно напоминание. Отправленные
+// напоминания помнятся только в памяти: после перезапуска напоминания
+// о днях рождения в окне отправляются повторно.
+type BirthdayReminder struct {
+	repo   *Repository
+	days   int
+	notify func(context.Context, BirthdayReminderEvent) error
+
+	mu   sync.Mutex
+	sent map[birthdayKey]struct{}
+}
+
+// birthdayKey — день рождения конкретного клиента в конкретном году.
+type birthdayKey struct {
+	clientID int
+	date     time.Time
+}
+
+// NewBirthdayReminder создаёт задачу, которая передаёт в notify напоминания
+// о днях рождения в ближайшие days дней.
+func NewBirthdayReminder(repo *Repository, days int, notify func(context.Context, BirthdayReminderEvent) error) *BirthdayReminder {
+	return &BirthdayReminder{
+		repo:   repo,
+		days:   days,
+		notify: notify,
+		sent:   make(map[birthdayKey]struct{}),
+	}
+}
+
+// Check отправляет напоминания о днях рождения, о которых ещё не напоминали,
+// и возвращает число отправленных напоминаний. Напоминание, которое не
+// удалось отправить, будет отправлено при следующей проверке.
+func (b *BirthdayReminder) Check(ctx context.Context) (int, error) {
+	upcoming, err := b.repo.UpcomingBirthdays(ctx, b.days)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	today := b.repo.today()
+	// Прошедшие дни рождения больше не встретятся в окне
+	for key := range b.sent {
+		if key.date.Before(today) {
+			delete(b.sent, key)
+		}
+	}
+
+	sent := 0
+	for _, u := range upcoming {
+		key := birthdayKey{clientID: u.ID, date: u.Date}
+		if _, ok := b.sent[key]; ok {
+			continue
+		}
+
+		err := b.notify(ctx, BirthdayReminderEvent{
+			ClientID: u.ID,
+			FIO:      u.FIO,
+			Email:    u.Email,
+			Date:     u.Date,
+			Age:      u.Age,
+			DaysLeft: int(u.Date.Sub(today).Hours() / 24),
+		})
+		if err != nil {
+			return sent, fmt.Errorf("birthday reminder for client %d: %w", u.ID, err)
+		}
+		b.sent[key] = struct{}{}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// Run проверяет дни рождения сразу и затем каждые interval до отмены ctx.
+// Ошибки проверки передаются в onError.
+func (b *BirthdayReminder) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := b.Check(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
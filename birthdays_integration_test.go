@@ -0,0 +1,158 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// insertBirthdays добавляет клиентов с датами рождения birthdays и
+// возвращает их ID по дате рождения
+func insertBirthdays(t *testing.T, repo *Repository, birthdays ...string) map[string]int {
+	t.Helper()
+
+	ids := make(map[string]int, len(birthdays))
+	for _, birthday := range birthdays {
+		id, err := repo.Insert(context.Background(), newTestClient(func(cl *Client) { cl.Birthday = birthday }))
+		require.NoError(t, err)
+		ids[birthday] = id
+	}
+
+	return ids
+}
+
+// Тест проверяет отбор ближайших дней рождения на границе года и для
+// родившихся 29 февраля
+func Test_UpcomingBirthdays(t *testing.T) {
+	t.Parallel()
+	clock := testutil.NewFakeClock(time.Date(2024, 12, 28, 10, 0, 0, 0, time.UTC))
+	_, repo := setupTestTx(t, WithClock(clock))
+
+	ctx := context.Background()
+
+	ids := insertBirthdays(t, repo, "19851228", "19801231", "19900101", "19750105", "20000229")
+
+	// upcoming возвращает даты рождения клиентов теста в порядке дней рождения
+	upcoming := func(days int) ([]string, []UpcomingBirthday) {
+		t.Helper()
+		all, err := repo.UpcomingBirthdays(ctx, days)
+		require.NoError(t, err)
+
+		var (
+			birthdays []string
+			ours      []UpcomingBirthday
+		)
+		for _, u := range all {
+			if ids[u.Birthday] == u.ID {
+				birthdays = append(birthdays, u.Birthday)
+				ours = append(ours, u)
+			}
+		}
+		return birthdays, ours
+	}
+
+	birthdays, ours := upcoming(7)
+	assert.Equal(t, []string{"19851228", "19801231", "19900101"}, birthdays)
+	assert.Equal(t, time.Date(2024, 12, 28, 0, 0, 0, 0, time.UTC), ours[0].Date)
+	assert.Equal(t, 39, ours[0].Age)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ours[2].Date)
+	assert.Equal(t, 35, ours[2].Age)
+
+	birthdays, _ = upcoming(0)
+	assert.Equal(t, []string{"19851228"}, birthdays, "zero days should include only today")
+
+	birthdays, _ = upcoming(8)
+	assert.Equal(t, []string{"19851228", "19801231", "19900101", "19750105"}, birthdays)
+
+	// В невисокосный 2025 год день рождения 29 февраля отмечается 28 февраля
+	clock.Set(time.Date(2025, 2, 25, 0, 0, 0, 0, time.UTC))
+	birthdays, ours = upcoming(3)
+	assert.Equal(t, []string{"20000229"}, birthdays)
+	assert.Equal(t, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), ours[0].Date)
+	assert.Equal(t, 25, ours[0].Age)
+	birthdays, _ = upcoming(2)
+	assert.Empty(t, birthdays)
+
+	_, err := repo.UpcomingBirthdays(ctx, -1)
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+// Тест проверяет, что задача напоминает о каждом дне рождения один раз,
+// повторяет неотправленные напоминания и снова напоминает через год
+func Test_BirthdayReminder(t *testing.T) {
+	t.Parallel()
+	clock := testutil.NewFakeClock(time.Date(2024, 12, 30, 9, 0, 0, 0, time.UTC))
+	_, repo := setupTestTx(t, WithClock(clock))
+
+	ctx := context.Background()
+
+	ids := insertBirthdays(t, repo, "19801231", "19900102", "19750105")
+	ours := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		ours[id] = true
+	}
+
+	var (
+		events  []BirthdayReminderEvent
+		failFor int
+	)
+	errNotify := errors.New("smtp unavailable")
+	reminder := NewBirthdayReminder(repo, 3, func(_ context.Context, e BirthdayReminderEvent) error {
+		if e.ClientID == failFor {
+			return errNotify
+		}
+		if ours[e.ClientID] {
+			events = append(events, e)
+		}
+		return nil
+	})
+	check := func() []BirthdayReminderEvent {
+		t.Helper()
+		events = nil
+		_, err := reminder.Check(ctx)
+		require.NoError(t, err)
+		return events
+	}
+
+	got := check()
+	require.Len(t, got, 2)
+	assert.Equal(t, BirthdayReminderEvent{
+		ClientID: ids["19801231"],
+		FIO:      "Test",
+		Email:    got[0].Email,
+		Date:     time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+		Age:      44,
+		DaysLeft: 1,
+	}, got[0])
+	assert.Equal(t, ids["19900102"], got[1].ClientID)
+	assert.Equal(t, 3, got[1].DaysLeft)
+
+	assert.Empty(t, check(), "reminders should not repeat on the same day")
+	clock.Advance(12 * time.Hour)
+	assert.Empty(t, check(), "reminders should not repeat on the next run")
+
+	// Неотправленное напоминание отправляется при следующей проверке
+	failFor = ids["19750105"]
+	clock.Set(time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC))
+	_, err := reminder.Check(ctx)
+	require.ErrorIs(t, err, errNotify)
+	failFor = 0
+	got = check()
+	require.Len(t, got, 1)
+	assert.Equal(t, ids["19750105"], got[0].ClientID)
+	assert.Equal(t, 50, got[0].Age)
+
+	// Через год о том же клиенте напоминают снова
+	clock.Set(time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC))
+	got = check()
+	require.Len(t, got, 2)
+	assert.Equal(t, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), got[0].Date)
+	assert.Equal(t, 45, got[0].Age)
+}
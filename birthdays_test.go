@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет вычисление ближайшего дня рождения на границе года
+// и для родившихся 29 февраля
+func Test_NextBirthday(t *testing.T) {
+	date := func(s string) time.Time {
+		t.Helper()
+		d, err := time.Parse(birthdayLayout, s)
+		require.NoError(t, err)
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		birthday string
+		today    string
+		want     string
+	}{
+		{"Today", "19850615", "20240615", "20240615"},
+		{"LaterThisYear", "19850615", "20240101", "20240615"},
+		{"PassedThisYear", "19850615", "20240616", "20250615"},
+		{"NewYearFromDecember", "19900101", "20241228", "20250101"},
+		{"DecemberFromDecember", "19901231", "20241228", "20241231"},
+		{"NewYearsEve", "19901231", "20250101", "20251231"},
+		{"LeapDayInLeapYear", "20000229", "20240201", "20240229"},
+		{"LeapDayInCommonYear", "20000229", "20230201", "20230228"},
+		{"LeapDayAfterFeb28InCommonYear", "20000229", "20230301", "20240229"},
+		{"LeapDayAcrossYearBoundary", "20000229", "20231231", "20240229"},
+		{"LeapDayIn2100", "20000229", "21000101", "21000228"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, date(tt.want), nextBirthday(date(tt.birthday), date(tt.today), LeapDayFeb28))
+		})
+	}
+}
+
+// Тест проверяет правило LeapDayMar1: в невисокосные годы родившиеся
+// 29 февраля отмечают день рождения 1 марта
+func Test_NextBirthday_LeapDayMar1(t *testing.T) {
+	date := func(s string) time.Time {
+		t.Helper()
+		d, err := time.Parse(birthdayLayout, s)
+		require.NoError(t, err)
+		return d
+	}
+
+	tests := []struct {
+		name  string
+		today string
+		want  string
+	}{
+		{"LeapYear", "20240201", "20240229"},
+		{"CommonYear", "20230201", "20230301"},
+		{"CommonYearOnFeb28", "20230228", "20230301"},
+		{"CommonYearOnMar1", "20230301", "20230301"},
+		{"CommonYearAfterMar1", "20230302", "20240229"},
+		{"AcrossYearBoundary", "20241231", "20250301"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, date(tt.want), nextBirthday(date("20000229"), date(tt.today), LeapDayMar1))
+		})
+	}
+}
+
+// Тест проверяет месяц и день дат рождения, отмечаемых в заданный день,
+// при обоих правилах для 29 февраля
+func Test_BirthdayMonthDays(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	assert.Equal(t, []string{"0228", "0229"}, birthdayMonthDays(day(2025, 2, 28), LeapDayFeb28))
+	assert.Equal(t, []string{"0301"}, birthdayMonthDays(day(2025, 3, 1), LeapDayFeb28))
+	assert.Equal(t, []string{"0228"}, birthdayMonthDays(day(2025, 2, 28), LeapDayMar1))
+	assert.Equal(t, []string{"0301", "0229"}, birthdayMonthDays(day(2025, 3, 1), LeapDayMar1))
+	assert.Equal(t, []string{"0228"}, birthdayMonthDays(day(2024, 2, 28), LeapDayFeb28))
+	assert.Equal(t, []string{"0229"}, birthdayMonthDays(day(2024, 2, 29), LeapDayMar1))
+	assert.Equal(t, []string{"0301"}, birthdayMonthDays(day(2024, 3, 1), LeapDayMar1))
+}
+
+// Тест проверяет отбор клиентов с днём рождения в текущий день по часам
+// репозитория: «сегодня» определяется в заданном часовом поясе, а
+// родившиеся 29 февраля отмечают день рождения по выбранному правилу, в
+// том числе при шифровании даты рождения
+func Test_BirthdaysToday(t *testing.T) {
+	ctx := context.Background()
+	moscow := time.FixedZone("MSK", 3*60*60)
+	// В UTC ещё 28 февраля невисокосного года, в Москве уже 1 марта
+	clock := testutil.NewFakeClock(time.Date(2025, 2, 28, 22, 0, 0, 0, time.UTC))
+
+	for _, encrypted := range []bool{false, true} {
+		db := openMemoryDB(t)
+		require.NoError(t, Migrate(ctx, db))
+		var opts []Option
+		if encrypted {
+			opts = append(opts, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+		}
+
+		ids := make(map[string]int)
+		for _, birthday := range []string{"20000229", "19900228", "19850301", "19850302"} {
+			id, err := NewRepository(db, append(opts, WithClock(clock))...).Insert(ctx, newTestClient(func(cl *Client) { cl.Birthday = birthday }))
+			require.NoError(t, err)
+			ids[birthday] = id
+		}
+
+		tests := []struct {
+			name   string
+			loc    *time.Location
+			policy LeapDayPolicy
+			want   []string
+		}{
+			{"UTCFeb28", time.UTC, LeapDayFeb28, []string{"20000229", "19900228"}},
+			{"UTCMar1", time.UTC, LeapDayMar1, []string{"19900228"}},
+			{"MoscowFeb28", moscow, LeapDayFeb28, []string{"19850301"}},
+			{"MoscowMar1", moscow, LeapDayMar1, []string{"20000229", "19850301"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/encrypted=%t", tt.name, encrypted), func(t *testing.T) {
+				repo := NewRepository(db, append(opts, WithClock(clock), WithBirthdays(tt.loc, tt.policy))...)
+				found, err := repo.BirthdaysToday(ctx)
+				require.NoError(t, err)
+
+				want := []int{}
+				for _, birthday := range tt.want {
+					want = append(want, ids[birthday])
+				}
+				got := []int{}
+				for _, b := range found {
+					got = append(got, b.ID)
+					assert.Equal(t, calendarDay(clock.Now(), tt.loc), b.Date)
+				}
+				assert.Equal(t, want, got)
+			})
+		}
+	}
+
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(clock), WithBirthdays(moscow, LeapDayMar1))
+	_, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Birthday = "20000229" }))
+	require.NoError(t, err)
+	found, err := repo.BirthdaysToday(ctx)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, 25, found[0].Age)
+
+	upcoming, err := repo.UpcomingBirthdays(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, upcoming, 1, "upcoming birthdays should use the same day and policy")
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), upcoming[0].Date)
+}
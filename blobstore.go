@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrBlobNotFound возвращается хранилищем, если содержимого с указанным
+// ключом нет.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore — хранилище содержимого документов клиентов. Ключи формирует
+// репозиторий; это относительные пути через «/». Реализация для файловой
+// системы — FSBlobStore; хранилище в S3 подключается реализацией того же
+// интерфейса.
+type BlobStore interface {
+	// Put сохраняет содержимое src под ключом key и возвращает число
+	// записанных байт. Повторная запись заменяет содержимое.
+	Put(ctx context.Context, key string, src io.Reader) (int64, error)
+	// Get открывает содержимое по ключу или возвращает ErrBlobNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete удаляет содержимое по ключу; отсутствие содержимого не ошибка.
+	Delete(ctx context.Context, key string) error
+}
+
+// FSBlobStore хранит содержимое в файлах внутри каталога.
+type FSBlobStore struct {
+	dir string
+}
+
+var _ BlobStore = (*FSBlobStore)(nil)
+
+// NewFSBlobStore создаёт хранилище в каталоге dir; каталог создаётся при
+// необходимости.
+func NewFSBlobStore(dir string) (*FSBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &FSBlobStore{dir: dir}, nil
+}
+
+func (s *FSBlobStore) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("%w: invalid blob key %q", ErrValidation, key)
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put записывает содержимое во временный файл и переименовывает его, чтобы
+// читатели не увидели частично записанный файл.
+func (s *FSBlobStore) Put(ctx context.Context, key string, src io.Reader) (_ int64, err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	n, err := io.Copy(tmp, contextReader{ctx: ctx, r: src})
+	if err != nil {
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	return n, os.Rename(tmp.Name(), path)
+}
+
+// Get открывает файл с содержимым.
+func (s *FSBlobStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+
+	return f, err
+}
+
+// Delete удаляет файл с содержимым.
+func (s *FSBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// contextReader прерывает чтение после отмены контекста.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
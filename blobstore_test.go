@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет запись, чтение, замену и удаление содержимого в FSBlobStore
+func Test_FSBlobStore_PutGetDelete(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFSBlobStore(filepath.Join(dir, "blobs"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	read := func(key string) string {
+		t.Helper()
+		rc, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	n, err := store.Put(ctx, "clients/1/doc", strings.NewReader("first"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, n)
+	assert.Equal(t, "first", read("clients/1/doc"))
+
+	_, err = store.Put(ctx, "clients/1/doc", strings.NewReader("second"))
+	require.NoError(t, err)
+	assert.Equal(t, "second", read("clients/1/doc"))
+
+	require.NoError(t, store.Delete(ctx, "clients/1/doc"))
+	_, err = store.Get(ctx, "clients/1/doc")
+	require.ErrorIs(t, err, ErrBlobNotFound)
+	require.NoError(t, store.Delete(ctx, "clients/1/doc"), "deleting missing content should succeed")
+
+	// Временные файлы не остаются в каталоге
+	entries, err := os.ReadDir(filepath.Join(dir, "blobs", "clients", "1"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// Тест проверяет, что ключи вне каталога хранилища отклоняются
+func Test_FSBlobStore_InvalidKey(t *testing.T) {
+	store, err := NewFSBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, key := range []string{"", "../escape", "/etc/passwd", "clients/../../escape"} {
+		_, err := store.Put(ctx, key, strings.NewReader("data"))
+		require.ErrorIs(t, err, ErrValidation, "key %q", key)
+		_, err = store.Get(ctx, key)
+		require.ErrorIs(t, err, ErrValidation, "key %q", key)
+	}
+}
+
+// Тест проверяет, что запись прерывается отменой контекста и не оставляет файла
+func Test_FSBlobStore_PutCanceled(t *testing.T) {
+	store, err := NewFSBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = store.Put(ctx, "doc", strings.NewReader("data"))
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = store.Get(context.Background(), "doc")
+	require.ErrorIs(t, err, ErrBlobNotFound)
+}
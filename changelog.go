@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultChangeBatch — сколько записей журнала изменений ChangeConsumer
+// обрабатывает за один проход по умолчанию.
+const DefaultChangeBatch = 100
+
+// Виды изменений в журнале change_log.
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// changeLogTriggers возвращает триггеры, которые записывают в change_log
+// каждую вставку, изменение и удаление строки table со значениями
+// столбцов columns. Изменение, не поменявшее ни одного значения, не
+// записывается. Столбцы, добавленные в table позже, попадут в журнал
+// только после пересоздания триггеров.
+func changeLogTriggers(table string, columns []string) string {
+	row := func(ref string) string {
+		pairs := make([]string, len(columns))
+		for i, c := range columns {
+			pairs[i] = fmt.Sprintf("'%s', %s.%s", c, ref, c)
+		}
+		return "json_object(" + strings.Join(pairs, ", ") + ")"
+	}
+
+	return fmt.Sprintf(`
+CREATE TRIGGER %[1]s_change_log_insert AFTER INSERT ON %[1]s BEGIN
+	INSERT INTO change_log (table_name, op, row_id, new) VALUES ('%[1]s', 'insert', NEW.rowid, %[2]s);
+END;
+CREATE TRIGGER %[1]s_change_log_update AFTER UPDATE ON %[1]s WHEN %[3]s IS NOT %[2]s BEGIN
+	INSERT INTO change_log (table_name, op, row_id, old, new) VALUES ('%[1]s', 'update', NEW.rowid, %[3]s, %[2]s);
+END;
+CREATE TRIGGER %[1]s_change_log_delete AFTER DELETE ON %[1]s BEGIN
+	INSERT INTO change_log (table_name, op, row_id, old) VALUES ('%[1]s', 'delete', OLD.rowid, %[3]s);
+END;`, table, row("NEW"), row("OLD"))
+}
+
+// Change — запись журнала изменений: одна вставка, изменение или удаление
+// строки таблицы.
+type Change struct {
+	// ID — возрастающий номер записи; по нему потребители продолжают
+	// чтение.
+	ID    int64  `json:"id"`
+	Table string `json:"table"`
+	// Op — вид изменения: ChangeInsert, ChangeUpdate или ChangeDelete.
+	Op    string `json:"op"`
+	RowID int64  `json:"row_id"`
+	// Old и New — значения столбцов строки до и после изменения в виде
+	// JSON-объекта; Old пуст для вставки, New — для удаления. Оба пусты,
+	// если данные клиента удалены EraseClient.
+	Old       json.RawMessage `json:"old,omitempty"`
+	New       json.RawMessage `json:"new,omitempty"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// Changes возвращает до limit (0 — DefaultChangeBatch) записей журнала
+// изменений с ID больше after по возрастанию ID.
+//
+// Журнал ведут триггеры SQLite, поэтому в него попадает любое изменение
+// clients, в том числе сделанное в обход репозитория. Это облегчённая
+// альтернатива outbox для потребителей, которым нужны только данные:
+// записи не содержат автора и ID запроса, значения хранятся как в
+// таблице (email и дата рождения — зашифрованными, если включено
+// шифрование), а время изменения берётся из часов БД, а не из WithClock.
+// Журнал содержит строки всех владельцев, поэтому с WithOwnerRestriction
+// его читает только администратор, иначе возвращается ErrAccessDenied.
+func (r *Repository) Changes(ctx context.Context, after int64, limit int) (_ []Change, err error) {
+	ctx, end := r.startOperation(ctx, "changes")
+	defer end(&err)
+
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultChangeBatch
+	}
+
+	rows, err := r.conn().QueryContext(ctx, `SELECT id, table_name, op, row_id, COALESCE(old, ''), COALESCE(new, ''), changed_at
+	FROM change_log WHERE id > :after ORDER BY id LIMIT :limit`, sql.Named("after", after), sql.Named("limit", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		var (
+			change                    Change
+			oldRow, newRow, changedAt string
+		)
+		if err := rows.Scan(&change.ID, &change.Table, &change.Op, &change.RowID, &oldRow, &newRow, &changedAt); err != nil {
+			return nil, err
+		}
+		if oldRow != "" {
+			change.Old = json.RawMessage(oldRow)
+		}
+		if newRow != "" {
+			change.New = json.RawMessage(newRow)
+		}
+		if change.ChangedAt, err = time.Parse(time.RFC3339Nano, changedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// ChangeConsumer передаёт записи журнала изменений обработчику по порядку
+// и хранит свою позицию в sync_cursors под именем "changes:" + name, так
+// что после перезапуска чтение продолжается с первой необработанной
+// записи. Позиция сохраняется после обработки, поэтому после сбоя запись
+// может быть обработана повторно. На одно имя запускается один
+// ChangeConsumer.
+type ChangeConsumer struct {
+	repo   *Repository
+	name   string
+	handle func(context.Context, Change) error
+	// batch — сколько записей обрабатывается за один проход.
+	batch int
+
+	mu sync.Mutex
+}
+
+// NewChangeConsumer создаёт потребителя name журнала изменений БД
+// репозитория repo с обработчиком handle.
+func NewChangeConsumer(repo *Repository, name string, handle func(context.Context, Change) error) *ChangeConsumer {
+	return &ChangeConsumer{repo: repo, name: name, handle: handle, batch: DefaultChangeBatch}
+}
+
+func (c *ChangeConsumer) cursor() string {
+	return "changes:" + c.name
+}
+
+// Consume обрабатывает до DefaultChangeBatch записей после сохранённой
+// позиции и возвращает число обработанных. Ошибка обработчика прерывает
+// проход; позиция остаётся на последней успешно обработанной записи.
+func (c *ChangeConsumer) Consume(ctx context.Context) (_ int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := c.repo
+	ctx, end := r.startOperation(ctx, "change_consumer")
+	defer end(&err)
+
+	after, err := r.syncCursor(ctx, c.cursor())
+	if err != nil {
+		return 0, err
+	}
+	changes, err := r.Changes(ctx, after, c.batch)
+	if err != nil || len(changes) == 0 {
+		return 0, err
+	}
+
+	for i, change := range changes {
+		if err := c.handle(ctx, change); err != nil {
+			if i > 0 {
+				if saveErr := r.saveSyncCursor(ctx, r.conn(), c.cursor(), changes[i-1].ID); saveErr != nil {
+					return i, saveErr
+				}
+			}
+			return i, fmt.Errorf("handle change %d: %w", change.ID, err)
+		}
+	}
+	if err := r.saveSyncCursor(ctx, r.conn(), c.cursor(), changes[len(changes)-1].ID); err != nil {
+		return 0, err
+	}
+
+	return len(changes), nil
+}
+
+// Run обрабатывает журнал каждые poll, пока не отменён ctx; за один раз
+// обрабатываются все накопившиеся записи. Ошибки передаются в onError
+// (если задан) и не прерывают работу.
+func (c *ChangeConsumer) Run(ctx context.Context, poll time.Duration, onError func(error)) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := c.Consume(ctx)
+			if err != nil && onError != nil {
+				onError(err)
+			}
+			if err != nil || n == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
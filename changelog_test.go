@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changeEntry — вид изменения и строка записи журнала изменений.
+type changeEntry struct {
+	op    string
+	rowID int64
+}
+
+// newChanges возвращает записи журнала после after и ID последней из них.
+func newChanges(t *testing.T, repo *Repository, after int64) ([]Change, int64) {
+	t.Helper()
+
+	changes, err := repo.Changes(context.Background(), after, 1000)
+	require.NoError(t, err)
+	if len(changes) > 0 {
+		after = changes[len(changes)-1].ID
+	}
+
+	return changes, after
+}
+
+// changeValue возвращает значение столбца column из JSON строки журнала.
+func changeValue(t *testing.T, row json.RawMessage, column string) any {
+	t.Helper()
+
+	var values map[string]any
+	require.NoError(t, json.Unmarshal(row, &values))
+
+	return values[column]
+}
+
+// Тест проверяет, что триггеры записывают в журнал изменения clients,
+// сделанные любым путём: операциями репозитория, загрузкой, архивом,
+// удалением данных и SQL в обход репозитория
+func Test_ChangeLog_AllMutationPaths(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(clock))
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	other, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	changes, cursor := newChanges(t, repo, 0)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "clients", changes[0].Table)
+	assert.Equal(t, changeEntry{ChangeInsert, int64(id)}, changeEntry{changes[0].Op, changes[0].RowID})
+	assert.Nil(t, changes[0].Old)
+	assert.Equal(t, "Test", changeValue(t, changes[0].New, "fio"))
+	assert.WithinDuration(t, time.Now(), changes[0].ChangedAt, time.Minute, "time is taken from the database clock")
+
+	// Каждая операция — ожидаемые записи журнала
+	for _, step := range []struct {
+		name string
+		do   func() error
+		want []changeEntry
+	}{
+		{"update", func() error {
+			cl, err := repo.Select(ctx, id)
+			if err != nil {
+				return err
+			}
+			cl.FIO = "Updated"
+			return repo.Update(ctx, cl)
+		}, []changeEntry{{ChangeUpdate, int64(id)}}},
+		{"consent", func() error { return repo.RecordConsent(ctx, id, true) }, []changeEntry{{ChangeUpdate, int64(id)}}},
+		{"status", func() error { return repo.ChangeStatus(ctx, id, StatusBlocked) }, []changeEntry{{ChangeUpdate, int64(id)}}},
+		{"preference", func() error { return SetPreference(ctx, repo, id, "lang", "ru") }, []changeEntry{{ChangeUpdate, int64(id)}}},
+		{"legal hold", func() error { return repo.SetLegalHold(ctx, id, true) }, []changeEntry{{ChangeUpdate, int64(id)}}},
+		{"merge", func() error { return repo.MergeClients(ctx, id, other) }, []changeEntry{{ChangeUpdate, int64(other)}}},
+		{"raw sql", func() error {
+			_, err := db.Exec("UPDATE clients SET login = 'raw' WHERE id = ?", id)
+			return err
+		}, []changeEntry{{ChangeUpdate, int64(id)}}},
+		{"no-op update", func() error {
+			_, err := db.Exec("UPDATE clients SET login = login")
+			return err
+		}, nil},
+		{"import", func() error {
+			_, err := repo.Import(ctx, strings.NewReader("id,fio,login,birthday,email\n0,Imported,imported,19900101,imported@mail.com\n"))
+			return err
+		}, []changeEntry{{ChangeInsert, int64(other + 1)}}},
+		{"delete", func() error { return repo.Delete(ctx, other+1) }, []changeEntry{{ChangeDelete, int64(other + 1)}}},
+		{"archive", func() error {
+			if err := repo.SetLegalHold(ctx, id, false); err != nil {
+				return err
+			}
+			clock.Advance(48 * time.Hour)
+			archived, err := repo.ArchiveClients(ctx, clock.Now().Add(-time.Hour))
+			if err == nil && len(archived) != 1 {
+				err = errors.New("client is not archived")
+			}
+			return err
+		}, []changeEntry{{ChangeUpdate, int64(id)}, {ChangeDelete, int64(id)}}},
+		{"unarchive", func() error { return repo.UnarchiveClient(ctx, id) }, []changeEntry{{ChangeInsert, int64(id)}}},
+	} {
+		require.NoError(t, step.do(), step.name)
+		changes, cursor = newChanges(t, repo, cursor)
+		var got []changeEntry
+		for _, change := range changes {
+			got = append(got, changeEntry{change.Op, change.RowID})
+		}
+		assert.Equal(t, step.want, got, step.name)
+	}
+
+	// Изменение хранит значения до и после
+	_, err = db.Exec("UPDATE clients SET fio = 'After' WHERE id = ?", id)
+	require.NoError(t, err)
+	changes, cursor = newChanges(t, repo, cursor)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "Updated", changeValue(t, changes[0].Old, "fio"))
+	assert.Equal(t, "After", changeValue(t, changes[0].New, "fio"))
+
+	// Удаление данных стирает значения во всех записях клиента, но
+	// оставляет сами записи
+	_, err = repo.EraseClient(ctx, id)
+	require.NoError(t, err)
+	changes, _ = newChanges(t, repo, cursor)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ChangeDelete, changes[0].Op)
+	all, _ := newChanges(t, repo, 0)
+	for _, change := range all {
+		if change.RowID == int64(id) {
+			assert.Nil(t, change.Old, "change %d", change.ID)
+			assert.Nil(t, change.New, "change %d", change.ID)
+		}
+	}
+}
+
+// Тест проверяет, что с ограничением по владельцу журнал изменений,
+// содержащий клиентов всех владельцев, читает только администратор
+func Test_ChangeLog_OwnerRestriction(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithOwnerRestriction())
+
+	owner := WithPrincipal(ctx, Principal{ID: "manager-1"})
+	stranger := WithPrincipal(ctx, Principal{ID: "manager-2"})
+	admin := WithPrincipal(ctx, Principal{ID: "root", Admin: true})
+
+	_, err := repo.Insert(owner, newTestClient())
+	require.NoError(t, err)
+
+	for name, ctx := range map[string]context.Context{"Owner": owner, "NonOwner": stranger, "NoPrincipal": ctx} {
+		changes, err := repo.Changes(ctx, 0, 0)
+		assert.ErrorIs(t, err, ErrAccessDenied, name)
+		assert.Empty(t, changes, name)
+	}
+
+	changes, err := repo.Changes(admin, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "Test", changeValue(t, changes[0].New, "fio"))
+
+	consumed := 0
+	consumer := NewChangeConsumer(repo, "audit", func(context.Context, Change) error {
+		consumed++
+		return nil
+	})
+	_, err = consumer.Consume(stranger)
+	require.ErrorIs(t, err, ErrAccessDenied)
+	_, err = consumer.Consume(admin)
+	require.NoError(t, err)
+	assert.Equal(t, 1, consumed)
+}
+
+// Тест проверяет, что потребитель продолжает чтение журнала со своей
+// позиции, а после ошибки обработчика повторяет только необработанные
+// записи
+func Test_ChangeConsumer_Consume(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+
+	var (
+		seen []int64
+		fail int64
+	)
+	consumer := NewChangeConsumer(repo, "replica", func(_ context.Context, change Change) error {
+		if change.ID == fail {
+			return errors.New("replica is down")
+		}
+		seen = append(seen, change.ID)
+		return nil
+	})
+	consumer.batch = 2
+
+	n, err := consumer.Consume(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	fail = 3
+	_, err = repo.db.Exec("UPDATE clients SET fio = 'Changed' WHERE id = 1")
+	require.NoError(t, err)
+	n, err = consumer.Consume(ctx)
+	require.ErrorContains(t, err, "handle change 3: replica is down")
+	assert.Zero(t, n)
+
+	fail = 4
+	n, err = consumer.Consume(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 1, n)
+
+	fail = 0
+	for n = 1; n > 0; {
+		n, err = consumer.Consume(ctx)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4}, seen)
+
+	// Другой потребитель читает журнал со своей позиции
+	var other []int64
+	n, err = NewChangeConsumer(repo, "audit", func(_ context.Context, change Change) error {
+		other = append(other, change.ID)
+		return nil
+	}).Consume(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, seen, other)
+}
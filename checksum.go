@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChecksumClients возвращает контрольную сумму SHA-256 таблицы клиентов,
+// не зависящую от порядка строк и столбцов: суммы отдельных строк
+// складываются, а в сумму строки входят имена столбцов и приведённые к
+// общему виду значения. Поэтому у двух БД (SQLite или Postgres) с
+// одинаковыми клиентами суммы совпадают, а изменение любого поля любой
+// строки меняет сумму. Сравниваются хранимые значения: зашифрованные поля
+// должны быть зашифрованы одними ключами.
+func ChecksumClients(ctx context.Context, db *sql.DB) (string, error) {
+	return unorderedChecksum(ctx, db, "clients")
+}
+
+// unorderedChecksum вычисляет контрольную сумму таблицы, не зависящую от
+// порядка строк и столбцов (см. ChecksumClients).
+func unorderedChecksum(ctx context.Context, q querier, table string) (string, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %q", table))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	// Столбцы хэшируются в порядке имён
+	order := make([]int, len(columns))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return strings.Compare(columns[a], columns[b]) })
+
+	var (
+		acc    [sha256.Size]byte
+		values = make([]any, len(columns))
+		dest   = make([]any, len(columns))
+	)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+
+		row := sha256.New()
+		for _, i := range order {
+			writeChecksumValue(row, columns[i])
+			writeChecksumValue(row, values[i])
+		}
+		addChecksum(&acc, row.Sum(nil))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(acc[:])
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// addChecksum прибавляет sum к acc как 256-битные числа по модулю 2^256.
+// В отличие от XOR, сложение не сокращает одинаковые строки.
+func addChecksum(acc *[sha256.Size]byte, sum []byte) {
+	var carry uint16
+	for i := len(acc) - 1; i >= 0; i-- {
+		v := uint16(acc[i]) + uint16(sum[i]) + carry
+		acc[i] = byte(v)
+		carry = v >> 8
+	}
+}
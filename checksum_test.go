@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что контрольная сумма не зависит от порядка вставки
+// строк, а у пустой таблицы отличается от непустой
+func Test_ChecksumClients_OrderIndependent(t *testing.T) {
+	ctx := context.Background()
+	a, b := openMemoryDB(t), openMemoryDB(t)
+	seedTransferSource(t, a)
+	require.NoError(t, Migrate(ctx, b))
+
+	empty, err := ChecksumClients(ctx, b)
+	require.NoError(t, err)
+
+	// Клиенты вставляются в b в обратном порядке ID
+	columns := mustColumns(t, a, "clients")
+	rows, err := a.Query(fmt.Sprintf("SELECT %s FROM clients ORDER BY id DESC", strings.Join(columns, ", ")))
+	require.NoError(t, err)
+	var inserts [][]any
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(dest...))
+		inserts = append(inserts, values)
+	}
+	require.NoError(t, rows.Err())
+	rows.Close()
+	insert := fmt.Sprintf("INSERT INTO clients (%s) VALUES (?%s)", strings.Join(columns, ", "), strings.Repeat(", ?", len(columns)-1))
+	for _, values := range inserts {
+		_, err := b.Exec(insert, values...)
+		require.NoError(t, err)
+	}
+
+	sumA, err := ChecksumClients(ctx, a)
+	require.NoError(t, err)
+	sumB, err := ChecksumClients(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, sumA, sumB)
+	assert.NotEqual(t, empty, sumA)
+	assert.Len(t, sumA, 64)
+}
+
+// Тест проверяет, что изменение любого поля одной строки меняет
+// контрольную сумму, а возврат прежнего значения восстанавливает её
+func Test_ChecksumClients_FieldSensitivity(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	seedTransferSource(t, db)
+
+	base, err := ChecksumClients(ctx, db)
+	require.NoError(t, err)
+	var id int64
+	require.NoError(t, db.QueryRow("SELECT MIN(id) FROM clients").Scan(&id))
+
+	for _, col := range mustColumns(t, db, "clients") {
+		var original any
+		require.NoError(t, db.QueryRow(fmt.Sprintf("SELECT %s FROM clients WHERE id = ?", col), id).Scan(&original))
+
+		var changed any
+		switch v := original.(type) {
+		case int64:
+			changed = v + 1000
+		case string:
+			changed = v + "x"
+			if col == "preferences" {
+				changed = `{"changed":true}`
+			}
+		default:
+			t.Fatalf("unexpected type %T of column %s", original, col)
+		}
+
+		// set меняет поле строки; ID строки тоже может меняться
+		set := func(from, to any) {
+			t.Helper()
+			where := id
+			if col == "id" {
+				where = from.(int64)
+			}
+			_, err := db.Exec(fmt.Sprintf("UPDATE clients SET %s = ? WHERE id = ?", col), to, where)
+			require.NoError(t, err)
+		}
+
+		set(original, changed)
+		sum, err := ChecksumClients(ctx, db)
+		require.NoError(t, err)
+		assert.NotEqual(t, base, sum, "changing %s should change the checksum", col)
+
+		set(changed, original)
+		sum, err = ChecksumClients(ctx, db)
+		require.NoError(t, err)
+		assert.Equal(t, base, sum, "restoring %s should restore the checksum", col)
+	}
+}
+
+// Тест проверяет, что одинаковые строки не сокращают друг друга
+func Test_ChecksumClients_Duplicates(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	_, err := db.Exec("CREATE TABLE clients (fio TEXT)")
+	require.NoError(t, err)
+
+	var sums []string
+	for i := 0; i < 3; i++ {
+		sum, err := ChecksumClients(ctx, db)
+		require.NoError(t, err)
+		sums = append(sums, sum)
+		_, err = db.Exec("INSERT INTO clients (fio) VALUES ('Same')")
+		require.NoError(t, err)
+	}
+	assert.NotEqual(t, sums[0], sums[2], "two equal rows should not cancel out")
+	assert.NotEqual(t, sums[1], sums[2])
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrorClass — категория ошибки для ответов API и алертинга: пользовательские
+// ошибки (not_found, conflict, validation, access) отделяются от сбоев
+// (timeout, internal).
+type ErrorClass string
+
+const (
+	ClassNone       ErrorClass = ""
+	ClassNotFound   ErrorClass = "not_found"
+	ClassConflict   ErrorClass = "conflict"
+	ClassValidation ErrorClass = "validation"
+	ClassAccess     ErrorClass = "access"
+	ClassTimeout    ErrorClass = "timeout"
+	ClassInternal   ErrorClass = "internal"
+)
+
+// Classify относит ошибку к одной из категорий ErrorClass. Обёрнутые
+// ошибки классифицируются по исходной; сбои окружения (незавершённые
+// миграции, расхождение схемы, ключи шифрования и т. п.) и всё
+// нераспознанное считаются внутренней ошибкой. Для nil возвращается
+// ClassNone.
+func Classify(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ClassNone
+	case errors.Is(err, ErrClientNotFound), errors.Is(err, ErrOrderNotFound), errors.Is(err, ErrNoteNotFound), errors.Is(err, ErrTagNotFound),
+		errors.Is(err, ErrDocumentNotFound), errors.Is(err, ErrBlobNotFound), errors.Is(err, ErrSegmentNotFound), errors.Is(err, ErrWebhookNotFound),
+		errors.Is(err, ErrWebhookDeliveryNotFound), errors.Is(err, sql.ErrNoRows):
+		return ClassNotFound
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrProductionDatabase), errors.Is(err, ErrTooManyClients):
+		return ClassValidation
+	case errors.Is(err, ErrAccessDenied):
+		return ClassAccess
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return ClassTimeout
+	case errors.Is(err, ErrClientHasOrders), errors.Is(err, ErrInvalidStatusTransition), errors.Is(err, ErrVersionConflict):
+		return ClassConflict
+	case errors.Is(err, ErrPendingMigrations), errors.Is(err, ErrSchemaDrift), errors.Is(err, ErrForeignKeysEnforced),
+		errors.Is(err, ErrTransferMismatch), errors.Is(err, ErrClockOutOfRange), errors.Is(err, ErrUnknownKey), errors.Is(err, ErrMalformedCipher):
+		// Схема БД, настройки соединения, часы, ключи шифрования и
+		// повреждённые данные исправляет оператор, а не вызывающий
+		return ClassInternal
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// Расширенные коды SQLite содержат основной код в младшем байте
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_CONSTRAINT:
+			return ClassConflict
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return ClassTimeout
+		}
+	}
+
+	return ClassInternal
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteErrors возвращает настоящие ошибки драйвера SQLite:
+// нарушение ограничения и занятость БД другой транзакцией
+func sqliteErrors(t *testing.T) (constraint, busy error) {
+	t.Helper()
+
+	// БД в памяти (VFS memdb) доступна нескольким соединениям процесса
+	path := "file:/" + t.Name() + "?vfs=memdb"
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	_, constraint = db.Exec("INSERT INTO t VALUES (1)")
+	require.Error(t, constraint)
+
+	// Первое соединение держит блокировку на запись
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	t.Cleanup(func() { tx.Rollback() })
+	_, err = tx.Exec("INSERT INTO t VALUES (2)")
+	require.NoError(t, err)
+
+	// Второе соединение не ждёт освобождения блокировки
+	other, err := sql.Open("sqlite", path+"&_pragma=busy_timeout(0)")
+	require.NoError(t, err)
+	t.Cleanup(func() { other.Close() })
+	_, busy = other.Exec("INSERT INTO t VALUES (3)")
+	require.Error(t, busy)
+
+	return constraint, busy
+}
+
+// Тест проверяет классификацию всех известных видов ошибок,
+// в том числе обёрнутых
+func Test_Classify(t *testing.T) {
+	constraint, busy := sqliteErrors(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, ClassNone},
+		{"client not found", ErrClientNotFound, ClassNotFound},
+		{"wrapped client not found", fmt.Errorf("select client 1: %w", ErrClientNotFound), ClassNotFound},
+		{"sql no rows", sql.ErrNoRows, ClassNotFound},
+		{"document not found", ErrDocumentNotFound, ClassNotFound},
+		{"segment not found", ErrSegmentNotFound, ClassNotFound},
+		{"webhook not found", fmt.Errorf("delete webhook 3: %w", ErrWebhookNotFound), ClassNotFound},
+		{"webhook delivery not found", fmt.Errorf("redeliver 12: %w", ErrWebhookDeliveryNotFound), ClassNotFound},
+		{"validation", ErrValidation, ClassValidation},
+		{"wrapped validation", fmt.Errorf("%w: bad email", ErrValidation), ClassValidation},
+		{"access denied", ErrAccessDenied, ClassAccess},
+		{"wrapped access denied", fmt.Errorf("select client 1: %w", ErrAccessDenied), ClassAccess},
+		{"production database", ErrProductionDatabase, ClassValidation},
+		{"too many clients", ErrTooManyClients, ClassValidation},
+		{"wrapped too many clients", fmt.Errorf("%w: filter matches 120 clients, max 100", ErrTooManyClients), ClassValidation},
+		{"version conflict", fmt.Errorf("%w: client 3 has version 2, expected 1", ErrVersionConflict), ClassConflict},
+		{"deadline exceeded", context.DeadlineExceeded, ClassTimeout},
+		{"canceled context", ctx.Err(), ClassTimeout},
+		{"sqlite constraint", constraint, ClassConflict},
+		{"wrapped sqlite constraint", fmt.Errorf("insert: %w", constraint), ClassConflict},
+		{"sqlite busy", busy, ClassTimeout},
+		{"pending migrations", fmt.Errorf("%w: 1 not applied, starting with 12 \"client_summary\"", ErrPendingMigrations), ClassInternal},
+		{"schema drift", fmt.Errorf("%w: clients: missing column status", ErrSchemaDrift), ClassInternal},
+		{"foreign keys enforced", fmt.Errorf("archive client 5: %w", ErrForeignKeysEnforced), ClassInternal},
+		{"transfer mismatch", fmt.Errorf("%w: clients: 3 rows, expected 4", ErrTransferMismatch), ClassInternal},
+		{"clock out of range", fmt.Errorf("insert: %w", ErrClockOutOfRange), ClassInternal},
+		{"unknown key", fmt.Errorf("select client 1: %w: %q", ErrUnknownKey, "v0"), ClassInternal},
+		{"malformed ciphertext", ErrMalformedCipher, ClassInternal},
+		{"wrapped malformed ciphertext", fmt.Errorf("select client 1: %w: illegal base64 data", ErrMalformedCipher), ClassInternal},
+		{"unknown error", errors.New("disk on fire"), ClassInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err), "error: %v", tt.err)
+		})
+	}
+}
+
+// Тест проверяет учёт ошибок операций по категориям в метриках
+func Test_Classify_Metrics(t *testing.T) {
+	db := openMemoryDB(t)
+
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+
+	reg := prometheus.NewRegistry()
+	repo := NewRepository(db, WithMetrics(reg))
+
+	_, err := repo.Select(ctx, 42)
+	require.ErrorIs(t, err, ErrClientNotFound)
+	err = repo.Export(ctx, nil, ExportOptions{Format: "xml"})
+	require.ErrorIs(t, err, ErrValidation)
+
+	assert.Equal(t, 1.0, metricValue(t, reg, "clients_repository_errors_total", map[string]string{"operation": "select", "class": "not_found"}))
+	assert.Equal(t, 1.0, metricValue(t, reg, "clients_repository_errors_total", map[string]string{"operation": "export", "class": "validation"}))
+}
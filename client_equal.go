@@ -0,0 +1,39 @@
+package main
+
+// Clone возвращает копию клиента, которую можно менять, не затрагивая
+// исходного клиента (например, общего для нескольких горутин). Сейчас все
+// поля Client — значения, и копия совпадает с присваиванием; поля-ссылки,
+// если они появятся, нужно копировать здесь.
+func (cl Client) Clone() Client {
+	return cl
+}
+
+// EqualOptions — поля, которые Client.Equal не сравнивает.
+type EqualOptions struct {
+	// IgnoreID не сравнивает ID: например, клиента из выгрузки с тем же
+	// клиентом, загруженным в другую БД.
+	IgnoreID bool
+	// IgnoreTimestamps не сравнивает время изменения согласия
+	// (ConsentUpdatedAt), которое задают часы репозитория.
+	IgnoreTimestamps bool
+}
+
+// Equal сообщает, совпадают ли клиенты cl и other по всем полям, кроме
+// исключённых opts. Время сравнивается как момент (time.Time.Equal), без
+// учёта часового пояса. Новое поле Client нужно добавить и в сравнение.
+func (cl Client) Equal(other Client, opts EqualOptions) bool {
+	if !opts.IgnoreID && cl.ID != other.ID {
+		return false
+	}
+	if !opts.IgnoreTimestamps && !cl.ConsentUpdatedAt.Equal(other.ConsentUpdatedAt) {
+		return false
+	}
+
+	return cl.FIO == other.FIO &&
+		cl.Login == other.Login &&
+		cl.Birthday == other.Birthday &&
+		cl.Email == other.Email &&
+		cl.OwnerID == other.OwnerID &&
+		cl.MarketingConsent == other.MarketingConsent &&
+		cl.Status == other.Status
+}
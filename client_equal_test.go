@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientFieldOptions — параметры Equal, при которых поле Client не
+// сравнивается; пустые параметры — поле сравнивается всегда. Новое поле
+// Client нужно добавить сюда, в Clone и в Equal.
+var clientFieldOptions = map[string]EqualOptions{
+	"ID":               {IgnoreID: true},
+	"FIO":              {},
+	"Login":            {},
+	"Birthday":         {},
+	"Email":            {},
+	"OwnerID":          {},
+	"MarketingConsent": {},
+	"ConsentUpdatedAt": {IgnoreTimestamps: true},
+	"Status":           {},
+}
+
+// fillClientField записывает в поле v ненулевое значение, отличное от
+// прежнего. Тест с полем неизвестного типа падает.
+func fillClientField(t *testing.T, name string, v reflect.Value) {
+	t.Helper()
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(v.String() + "x")
+	case reflect.Int:
+		v.SetInt(v.Int() + 1)
+	case reflect.Bool:
+		v.SetBool(!v.Bool())
+	default:
+		if v.Type() != reflect.TypeOf(time.Time{}) {
+			t.Fatalf("field %s of type %s is not handled by the test", name, v.Type())
+		}
+		v.Set(reflect.ValueOf(v.Interface().(time.Time).Add(time.Hour)))
+	}
+}
+
+// Тест проверяет, что тесты Clone и Equal знают все поля Client
+func Test_Client_FieldCoverage(t *testing.T) {
+	typ := reflect.TypeOf(Client{})
+	names := make(map[string]bool)
+	for i := 0; i < typ.NumField(); i++ {
+		names[typ.Field(i).Name] = true
+		assert.Contains(t, clientFieldOptions, typ.Field(i).Name,
+			"new Client field %s: handle it in Clone and Equal and add it to clientFieldOptions", typ.Field(i).Name)
+	}
+	for name := range clientFieldOptions {
+		assert.True(t, names[name], "clientFieldOptions lists unknown field %s", name)
+	}
+}
+
+// Тест проверяет, что копия клиента равна исходному по всем полям и что
+// изменение копии не затрагивает исходного клиента
+func Test_Client_Clone(t *testing.T) {
+	var cl Client
+	v := reflect.ValueOf(&cl).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		fillClientField(t, v.Type().Field(i).Name, v.Field(i))
+	}
+
+	clone := cl.Clone()
+	assert.Equal(t, cl, clone)
+	assert.True(t, clone.Equal(cl, EqualOptions{}))
+
+	before := cl
+	c := reflect.ValueOf(&clone).Elem()
+	for i := 0; i < c.NumField(); i++ {
+		fillClientField(t, c.Type().Field(i).Name, c.Field(i))
+	}
+	assert.Equal(t, before, cl, "changing the clone must not change the original")
+}
+
+// Тест проверяет, что Equal замечает отличие в каждом поле клиента и
+// пропускает только поля, исключённые параметрами
+func Test_Client_Equal(t *testing.T) {
+	base := newTestClient(func(cl *Client) {
+		cl.ID = 1
+		cl.Status = StatusActive
+		cl.ConsentUpdatedAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	})
+	ignoreAll := EqualOptions{IgnoreID: true, IgnoreTimestamps: true}
+
+	typ := reflect.TypeOf(base)
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Name
+		t.Run(name, func(t *testing.T) {
+			opts, ok := clientFieldOptions[name]
+			require.True(t, ok, "field %s is not covered", name)
+
+			other := base.Clone()
+			fillClientField(t, name, reflect.ValueOf(&other).Elem().Field(i))
+
+			assert.False(t, base.Equal(other, EqualOptions{}), "Equal must compare %s", name)
+			assert.Equal(t, opts != EqualOptions{}, base.Equal(other, opts), "options %+v for %s", opts, name)
+			assert.Equal(t, opts != EqualOptions{}, base.Equal(other, ignoreAll), "ignoring all volatile fields, %s", name)
+		})
+	}
+
+	moscow := base.Clone()
+	moscow.ConsentUpdatedAt = base.ConsentUpdatedAt.In(time.FixedZone("MSK", 3*60*60))
+	assert.True(t, base.Equal(moscow, EqualOptions{}), "the same moment in another time zone is equal")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonBirthdayLayout — формат даты рождения в JSON (ISO 8601). В БД и в
+// Client.Birthday дата хранится в формате birthdayLayout.
+const jsonBirthdayLayout = "2006-01-02"
+
+// clientJSON — представление Client в JSON: дата рождения в формате
+// jsonBirthdayLayout, нулевое время согласия не выводится.
+type clientJSON struct {
+	ID       int    `json:"id"`
+	FIO      string `json:"fio"`
+	Login    string `json:"login"`
+	Birthday string `json:"birthday"`
+	Email    string `json:"email"`
+	OwnerID  string `json:"owner_id,omitempty"`
+
+	MarketingConsent bool       `json:"marketing_consent"`
+	ConsentUpdatedAt *time.Time `json:"consent_updated_at,omitempty"`
+
+	Status ClientStatus `json:"status"`
+}
+
+// MarshalJSON выводит клиента с датой рождения в формате ISO 8601
+// (ГГГГ-ММ-ДД). Дата, которая не разбирается как ГГГГММДД (например,
+// пустая у стёртого клиента), выводится как есть; нулевое время согласия
+// опускается.
+func (cl Client) MarshalJSON() ([]byte, error) {
+	out := clientJSON{
+		ID: cl.ID, FIO: cl.FIO, Login: cl.Login, Birthday: cl.Birthday, Email: cl.Email, OwnerID: cl.OwnerID,
+		MarketingConsent: cl.MarketingConsent, Status: cl.Status,
+	}
+	if birthday, err := time.Parse(birthdayLayout, cl.Birthday); err == nil {
+		out.Birthday = birthday.Format(jsonBirthdayLayout)
+	}
+	if !cl.ConsentUpdatedAt.IsZero() {
+		out.ConsentUpdatedAt = &cl.ConsentUpdatedAt
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON разбирает клиента, выведенного MarshalJSON. Неизвестные
+// поля, дата рождения не в формате ГГГГ-ММ-ДД и неизвестный статус
+// возвращают ErrValidation; остальные поля проверяет Validate.
+func (cl *Client) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var in clientJSON
+	if err := dec.Decode(&in); err != nil {
+		return fmt.Errorf("%w: client: %v", ErrValidation, err)
+	}
+
+	birthday := in.Birthday
+	if birthday != "" {
+		t, err := time.Parse(jsonBirthdayLayout, birthday)
+		if err != nil {
+			return fmt.Errorf("%w: birthday %q is not a valid YYYY-MM-DD date", ErrValidation, birthday)
+		}
+		birthday = t.Format(birthdayLayout)
+	}
+	if in.Status != "" && !in.Status.Valid() {
+		return fmt.Errorf("%w: unknown client status %q", ErrValidation, in.Status)
+	}
+
+	*cl = Client{
+		ID: in.ID, FIO: in.FIO, Login: in.Login, Birthday: birthday, Email: in.Email, OwnerID: in.OwnerID,
+		MarketingConsent: in.MarketingConsent, Status: in.Status,
+	}
+	if in.ConsentUpdatedAt != nil {
+		cl.ConsentUpdatedAt = *in.ConsentUpdatedAt
+	}
+
+	return nil
+}
+
+// importBirthday переводит дату рождения из импортируемого JSON в формат
+// birthdayLayout: принимается и ГГГГ-ММ-ДД из экспорта, и ГГГГММДД. Прочие
+// значения возвращаются как есть, их отклоняет Validate.
+func importBirthday(s string) string {
+	if t, err := time.Parse(jsonBirthdayLayout, s); err == nil {
+		return t.Format(birthdayLayout)
+	}
+
+	return s
+}
+
+// marshalWithClient выводит один объект JSON с полями клиента cl и полями
+// структуры extra. Нужен типам, которые встраивают Client: иначе
+// MarshalJSON клиента заменил бы их собственные поля.
+func marshalWithClient(cl Client, extra any) ([]byte, error) {
+	client, err := json.Marshal(cl)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := json.Marshal(extra)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) <= 2 {
+		return client, nil
+	}
+
+	// оба значения — непустые объекты: {клиент,поля}
+	out := append(client[:len(client)-1:len(client)-1], ',')
+
+	return append(out, fields[1:]...), nil
+}
+
+// unmarshalWithClient разбирает объект JSON, выведенный marshalWithClient:
+// поля keys — в структуру extra, остальные — в клиента cl.
+func unmarshalWithClient(data []byte, cl *Client, extra any, keys ...string) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, extra); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(fields, key)
+	}
+	client, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(client, cl)
+}
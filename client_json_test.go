@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что клиент выводится в JSON с датой рождения в формате
+// ISO 8601 и без нулевого времени согласия, а разбор возвращает того же
+// клиента
+func Test_Client_JSON_RoundTrip(t *testing.T) {
+	consent := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		client Client
+		want   string
+	}{
+		{"Minimal", Client{ID: 1, FIO: "Иванов Иван", Login: "ivanov", Birthday: "19850615", Email: "ivanov@mail.com", Status: StatusActive},
+			`{"id":1,"fio":"Иванов Иван","login":"ivanov","birthday":"1985-06-15","email":"ivanov@mail.com","marketing_consent":false,"status":"active"}`},
+		{"Consent", Client{ID: 2, FIO: "Петров Пётр", Login: "petrov", Birthday: "19900101", Email: "petrov@corp.ru", OwnerID: "alice",
+			MarketingConsent: true, ConsentUpdatedAt: consent, Status: StatusBlocked},
+			`{"id":2,"fio":"Петров Пётр","login":"petrov","birthday":"1990-01-01","email":"petrov@corp.ru","owner_id":"alice",` +
+				`"marketing_consent":true,"consent_updated_at":"2024-03-01T12:30:00Z","status":"blocked"}`},
+		{"Erased", Client{ID: 3, Status: StatusArchived},
+			`{"id":3,"fio":"","login":"","birthday":"","email":"","marketing_consent":false,"status":"archived"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.client)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+
+			var got Client
+			require.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, tt.client, got)
+		})
+	}
+}
+
+// Тест проверяет, что типы, встраивающие Client, выводят и разбирают
+// собственные поля вместе с полями клиента
+func Test_Client_JSON_Embedded(t *testing.T) {
+	cl := newTestClient(func(cl *Client) { cl.ID = 7; cl.Status = StatusActive })
+
+	withOrders := ClientWithOrders{Client: cl, Orders: []Order{{ID: 1, ClientID: 7, Amount: 100, Status: OrderNew,
+		CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}}}
+	data, err := json.Marshal(withOrders)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"birthday":"1970-01-01"`)
+	assert.Contains(t, string(data), `"orders":[{`)
+	var gotOrders ClientWithOrders
+	require.NoError(t, json.Unmarshal(data, &gotOrders))
+	assert.Equal(t, withOrders, gotOrders)
+
+	upcoming := UpcomingBirthday{Client: cl, Date: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC), Age: 40}
+	data, err = json.Marshal(upcoming)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"date":"2025-06-15T00:00:00Z","age":40}`)
+	var gotUpcoming UpcomingBirthday
+	require.NoError(t, json.Unmarshal(data, &gotUpcoming))
+	assert.Equal(t, upcoming, gotUpcoming)
+}
+
+// Тест проверяет, что некорректный JSON клиента отклоняется с ErrValidation
+func Test_Client_UnmarshalJSON_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"UnknownField", `{"id":1,"fio":"Иванов","phone":"+7"}`, `unknown field "phone"`},
+		{"InternalDate", `{"birthday":"19850615"}`, `birthday "19850615" is not a valid YYYY-MM-DD date`},
+		{"BadDate", `{"birthday":"1985-13-01"}`, `birthday "1985-13-01" is not a valid YYYY-MM-DD date`},
+		{"DateTime", `{"birthday":"1985-06-15T00:00:00Z"}`, "is not a valid YYYY-MM-DD date"},
+		{"WrongType", `{"id":"1"}`, "cannot unmarshal string"},
+		{"BadTimestamp", `{"consent_updated_at":"yesterday"}`, `cannot parse "yesterday"`},
+		{"UnknownStatus", `{"status":"deleted"}`, `unknown client status "deleted"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cl Client
+			err := json.Unmarshal([]byte(tt.json), &cl)
+			require.ErrorIs(t, err, ErrValidation)
+			assert.ErrorContains(t, err, tt.want)
+			assert.Equal(t, Client{}, cl, "the client must not change on error")
+		})
+	}
+
+	var cl Client
+	require.Error(t, json.Unmarshal([]byte(`{"id":1`), &cl))
+}
+
+// Тест проверяет, что выгрузка JSON с датами ISO 8601 снова загружается
+// импортом без потери дат рождения
+func Test_Client_JSON_ExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, src))
+	_, err := NewRepository(src).Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, NewRepository(src).Export(ctx, &buf, ExportOptions{Format: FormatJSON}))
+	assert.Contains(t, buf.String(), `"birthday":"1970-01-01"`)
+
+	dst := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, dst))
+	repo := NewRepository(dst)
+	n, err := repo.ImportWith(ctx, &buf, ImportOptions{Format: FormatJSON})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	got, err := repo.Select(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "19700101", got.Birthday)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// clientctl — команды управления клиентами для скриптов эксплуатации.
+type clientctl struct {
+	// cfg — настройки из --config и переменных окружения (см. Load);
+	// флаг --db заменяет cfg.DB.DSN.
+	cfg        Config
+	configPath string
+	dsn        string
+	output     string
+	tags       []string
+	// open открывает БД по настройкам и возвращает функцию её закрытия;
+	// тесты подменяют её БД в памяти.
+	open func(cfg DBConfig) (*sql.DB, func() error, error)
+}
+
+// runClientctl выполняет команду clientctl с аргументами args и выводит
+// результат в w.
+func runClientctl(ctx context.Context, args []string, w io.Writer) error {
+	cmd := newClientctlCmd(openClientctlDB)
+	cmd.SetArgs(args)
+	cmd.SetOut(w)
+	cmd.SetErr(w)
+
+	return cmd.ExecuteContext(ctx)
+}
+
+// openClientctlDB открывает БД SQLite по настройкам cfg.
+func openClientctlDB(cfg DBConfig) (*sql.DB, func() error, error) {
+	db, err := cfg.OpenDB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, db.Close, nil
+}
+
+// newClientctlCmd создаёт корневую команду clientctl с подкомандами
+// управления клиентами, схемой и данными БД.
+func newClientctlCmd(open func(cfg DBConfig) (*sql.DB, func() error, error)) *cobra.Command {
+	c := &clientctl{open: open}
+
+	root := &cobra.Command{
+		Use:   "clientctl",
+		Short: "Manage clients in the database",
+		// Ошибку выводит вызывающий код, справка при ошибке мешает разбору вывода
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := validateOutput(c.output); err != nil {
+				return err
+			}
+			var err error
+			if c.cfg, err = Load(c.configPath); err != nil {
+				return err
+			}
+			if cmd.Flag("db").Changed {
+				c.cfg.DB.DSN, c.cfg.DB.SecretsDir = c.dsn, ""
+			}
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&c.configPath, "config", "", "YAML settings file; environment variables override it")
+	root.PersistentFlags().StringVar(&c.dsn, "db", "", "SQLite database DSN (overrides db.dsn and db.secrets_dir of --config)")
+	root.PersistentFlags().StringVarP(&c.output, "output", "o", outputTable, "output format: table, json or yaml")
+
+	root.AddCommand(c.getCmd(), c.createCmd(), c.updateCmd(), c.deleteCmd(), c.mergeCmd(), c.listCmd(), c.migrateCmd(), c.envCmd(), c.seedCmd(), c.exportCmd(), c.importCmd(), c.statsCmd(), c.maintainCmd(), c.checkCmd(), c.purgeCmd(), c.holdCmd(), c.webhooksCmd(), c.elasticCmd(), c.directoryCmd(), c.configCmd())
+
+	return root
+}
+
+// withDB открывает БД на время выполнения fn.
+func (c *clientctl) withDB(cmd *cobra.Command, fn func(ctx context.Context, db *sql.DB) error) (err error) {
+	db, closeDB, err := c.open(c.cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := closeDB(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return fn(cmd.Context(), db)
+}
+
+// withMigratedDB открывает БД на время выполнения fn, если к ней применены
+// все миграции. Миграции применяет только clientctl migrate up: иначе
+// любая команда после migrate down молча вернула бы откаченную схему.
+func (c *clientctl) withMigratedDB(cmd *cobra.Command, fn func(ctx context.Context, db *sql.DB) error) error {
+	return c.withDB(cmd, func(ctx context.Context, db *sql.DB) error {
+		if err := CheckMigrated(ctx, db); err != nil {
+			if errors.Is(err, ErrPendingMigrations) {
+				return fmt.Errorf("%w; run `clientctl migrate up`", err)
+			}
+			return err
+		}
+		return fn(ctx, db)
+	})
+}
+
+// withRepo открывает репозиторий над БД с актуальной схемой на время
+// выполнения fn.
+func (c *clientctl) withRepo(cmd *cobra.Command, fn func(ctx context.Context, repo *Repository) error) error {
+	return c.withMigratedDB(cmd, func(ctx context.Context, db *sql.DB) error {
+		opts, err := c.cfg.RepositoryOptions()
+		if err != nil {
+			return err
+		}
+		repo, err := NewCheckedRepository(ctx, db, opts...)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, repo)
+	})
+}
+
+func (c *clientctl) configCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "config",
+		Short: "Print the effective settings with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return DumpEffectiveConfig(cmd.OutOrStdout(), c.cfg)
+		},
+	}
+}
+
+func (c *clientctl) getCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get ID",
+		Short: "Show a client",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseClientID(args[0])
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				cl, err := repo.Select(ctx, id)
+				if err != nil {
+					return err
+				}
+				return c.printClients(cmd.OutOrStdout(), []Client{cl}, false)
+			})
+		},
+	}
+}
+
+// clientFlags добавляет в cmd флаги полей клиента.
+func clientFlags(cmd *cobra.Command, cl *Client) {
+	cmd.Flags().StringVar(&cl.FIO, "fio", "", "full name")
+	cmd.Flags().StringVar(&cl.Login, "login", "", "login")
+	cmd.Flags().StringVar(&cl.Birthday, "birthday", "", "birthday in YYYYMMDD format")
+	cmd.Flags().StringVar(&cl.Email, "email", "", "email")
+}
+
+// interactiveFlag добавляет в cmd флаг запроса полей клиента по одному.
+// Подсказки выводятся в stderr, поэтому stdout остаётся пригодным для
+// разбора.
+func interactiveFlag(cmd *cobra.Command, interactive *bool) {
+	cmd.Flags().BoolVarP(interactive, "interactive", "i", false, "prompt for each field with validation; flag values and current values are defaults")
+}
+
+// whereFlag добавляет в cmd флаг условия отбора клиентов (см. ParseFilter).
+func whereFlag(cmd *cobra.Command, where *string) {
+	cmd.Flags().StringVar(where, "where", "", `only clients matching the filter in JSON, e.g. {"any":[{"statuses":["blocked"]},{"tags":["vip"]}]}`)
+}
+
+func (c *clientctl) createCmd() *cobra.Command {
+	var (
+		cl          Client
+		interactive bool
+	)
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a client and print it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if interactive {
+				if err := promptClient(cmd.InOrStdin(), cmd.ErrOrStderr(), &cl, time.Now()); err != nil {
+					return err
+				}
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				id, err := repo.Insert(ctx, cl)
+				if err != nil {
+					return err
+				}
+				created, err := repo.Select(ctx, id)
+				if err != nil {
+					return err
+				}
+				return c.printClients(cmd.OutOrStdout(), []Client{created}, false)
+			})
+		},
+	}
+	clientFlags(cmd, &cl)
+	interactiveFlag(cmd, &interactive)
+
+	return cmd
+}
+
+func (c *clientctl) updateCmd() *cobra.Command {
+	var (
+		changes     Client
+		interactive bool
+	)
+	cmd := &cobra.Command{
+		Use:   "update ID",
+		Short: "Change the given fields of a client and print it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseClientID(args[0])
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				cl, err := repo.Select(ctx, id)
+				if err != nil {
+					return err
+				}
+				// Меняются только явно заданные поля, остальные остаются прежними
+				flags := cmd.Flags()
+				for name, field := range map[string]struct{ dst, src *string }{
+					"fio":      {&cl.FIO, &changes.FIO},
+					"login":    {&cl.Login, &changes.Login},
+					"birthday": {&cl.Birthday, &changes.Birthday},
+					"email":    {&cl.Email, &changes.Email},
+				} {
+					if flags.Changed(name) {
+						*field.dst = *field.src
+					}
+				}
+				if interactive {
+					if err := promptClient(cmd.InOrStdin(), cmd.ErrOrStderr(), &cl, time.Now()); err != nil {
+						return err
+					}
+				}
+
+				if err := repo.Update(ctx, cl); err != nil {
+					return err
+				}
+				return c.printClients(cmd.OutOrStdout(), []Client{cl}, false)
+			})
+		},
+	}
+	clientFlags(cmd, &changes)
+	interactiveFlag(cmd, &interactive)
+
+	return cmd
+}
+
+func (c *clientctl) deleteCmd() *cobra.Command {
+	var (
+		opts    DestructiveOptions
+		where   string
+		maxRows int
+	)
+	cmd := &cobra.Command{
+		Use:   "delete ID... | --where FILTER [--max-rows N]",
+		Short: "Delete clients in one transaction",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 0) == (where == "") {
+				return fmt.Errorf("%w: pass either client IDs or --where", ErrValidation)
+			}
+			if maxRows != 0 && where == "" {
+				return fmt.Errorf("%w: --max-rows needs --where", ErrValidation)
+			}
+			var (
+				ids []int
+				f   Filter
+				err error
+			)
+			if where != "" {
+				f, err = ParseFilter([]byte(where))
+			} else {
+				ids, err = parseClientIDs(args)
+			}
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				var affected Affected
+				switch {
+				case maxRows != 0:
+					affected, err = repo.DeleteClientsWhere(ctx, f, DeleteWhereOptions{DestructiveOptions: opts, MaxRows: maxRows})
+				case where != "":
+					affected, err = repo.DeleteWhere(ctx, f, opts)
+				default:
+					affected, err = repo.DeleteClients(ctx, ids, opts)
+				}
+				if err != nil || !opts.DryRun {
+					return err
+				}
+				return c.printAffected(cmd.OutOrStdout(), "delete", affected)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "print the rows that would be deleted without deleting them")
+	cmd.Flags().IntVar(&maxRows, "max-rows", 0, "with --where, delete nothing if more than `N` clients match")
+	whereFlag(cmd, &where)
+
+	return cmd
+}
+
+func (c *clientctl) listCmd() *cobra.Command {
+	var (
+		where string
+		count bool
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List clients in ID order",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var f Filter
+			if where != "" {
+				if len(c.tags) > 0 {
+					return fmt.Errorf("%w: pass either --tag or --where", ErrValidation)
+				}
+				var err error
+				if f, err = ParseFilter([]byte(where)); err != nil {
+					return err
+				}
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				if count {
+					if len(c.tags) > 0 {
+						f = Filter{Tags: c.tags}
+					}
+					n, err := repo.Count(ctx, f)
+					if err != nil {
+						return err
+					}
+					return c.printCount(cmd.OutOrStdout(), n)
+				}
+
+				var clients []Client
+				collect := func(cl Client) error {
+					clients = append(clients, cl)
+					return nil
+				}
+
+				var err error
+				switch {
+				case len(c.tags) > 0:
+					err = repo.ClientsByTags(ctx, MatchAllTags, c.tags, collect)
+				case where != "":
+					err = repo.Find(ctx, f, collect)
+				default:
+					err = repo.ForEach(ctx, collect)
+				}
+				if err != nil {
+					return err
+				}
+
+				return c.printClients(cmd.OutOrStdout(), clients, true)
+			})
+		},
+	}
+	cmd.Flags().StringSliceVar(&c.tags, "tag", nil, "list only clients with all of the given tags")
+	whereFlag(cmd, &where)
+	cmd.Flags().BoolVar(&count, "count", false, "print the number of matching clients instead of the clients")
+
+	return cmd
+}
+
+func parseClientID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: client ID must be a positive integer, got %q", ErrValidation, s)
+	}
+
+	return id, nil
+}
+
+// parseClientIDs разбирает ID клиентов из аргументов команды.
+func parseClientIDs(args []string) ([]int, error) {
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := parseClientID(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
+
+// printAffected выводит результат пробного выполнения операции verb:
+// затронутых клиентов и число строк по таблицам, или Affected в JSON или
+// YAML.
+func (c *clientctl) printAffected(w io.Writer, verb string, affected Affected) error {
+	if c.output != outputTable {
+		return c.encode(w, affected)
+	}
+
+	ids := make([]string, len(affected.ClientIDs))
+	for i, id := range affected.ClientIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	fmt.Fprintf(w, "would %s clients %s\n", verb, strings.Join(ids, ", "))
+
+	tables := make([]string, 0, len(affected.Rows))
+	for table := range affected.Rows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tROWS")
+	for _, table := range tables {
+		fmt.Fprintf(tw, "%s\t%d\n", table, affected.Rows[table])
+	}
+
+	return tw.Flush()
+}
+
+// printCount выводит число клиентов строкой или объектом JSON или YAML.
+func (c *clientctl) printCount(w io.Writer, n int) error {
+	if c.output != outputTable {
+		return c.encode(w, struct {
+			Count int `json:"count"`
+		}{n})
+	}
+	_, err := fmt.Fprintln(w, n)
+
+	return err
+}
+
+// printClients выводит клиентов таблицей, в JSON или YAML. В JSON и YAML
+// список выводится массивом, а одиночный клиент — объектом.
+func (c *clientctl) printClients(w io.Writer, clients []Client, list bool) error {
+	if c.output != outputTable {
+		if !list {
+			return c.encode(w, clients[0])
+		}
+		if clients == nil {
+			clients = []Client{}
+		}
+		return c.encode(w, clients)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tFIO\tLOGIN\tBIRTHDAY\tEMAIL\tSTATUS")
+	for _, cl := range clients {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", cl.ID, cl.FIO, cl.Login, cl.Birthday, cl.Email, cl.Status)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func (c *clientctl) checkCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Check database file integrity and orphaned or invalid rows; fails if issues are found",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				report, err := repo.IntegrityCheck(ctx)
+				if err != nil {
+					return err
+				}
+
+				w := cmd.OutOrStdout()
+				if c.output != outputTable {
+					// Пустой список выводится как [], а не null
+					if report.Issues == nil {
+						report.Issues = []IntegrityIssue{}
+					}
+					if err := c.encode(w, report); err != nil {
+						return err
+					}
+				} else if report.OK() {
+					fmt.Fprintln(w, "ok")
+				} else {
+					tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(tw, "CHECK\tTABLE\tID\tDETAIL")
+					for _, issue := range report.Issues {
+						fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", issue.Check, issue.Table, issue.ID, issue.Detail)
+					}
+					if err := tw.Flush(); err != nil {
+						return err
+					}
+				}
+
+				if !report.OK() {
+					return fmt.Errorf("integrity check found %d issues", len(report.Issues))
+				}
+				return nil
+			})
+		},
+	}
+}
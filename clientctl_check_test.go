@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет вывод clientctl check и ошибку при найденных нарушениях
+func Test_Clientctl_Check(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	out, err := clientctl("check")
+	require.NoError(t, err, out)
+	assert.Equal(t, "ok\n", out)
+
+	out, err = clientctl("check", "-o", "json")
+	require.NoError(t, err, out)
+	assert.JSONEq(t, `{"issues": []}`, out)
+
+	ctx := context.Background()
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	_, err = repo.AddNote(ctx, id, "note")
+	require.NoError(t, err)
+	_, err = repo.db.ExecContext(ctx, "DELETE FROM clients")
+	require.NoError(t, err)
+
+	out, err = clientctl("check", "-o", "json")
+	require.EqualError(t, err, "integrity check found 1 issues")
+	var report IntegrityReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.Equal(t, []IntegrityIssue{
+		{Check: IntegrityOrphanedNote, Table: "client_notes", ID: 1, Detail: "client 1 does not exist"},
+	}, report.Issues)
+
+	out, err = clientctl("check")
+	require.Error(t, err)
+	assert.Equal(t, "CHECK          TABLE         ID  DETAIL\norphaned_note  client_notes  1   client 1 does not exist\n", out)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// directorySchemas — сопоставления атрибутов для флага --schema.
+var directorySchemas = map[string]DirectoryMapping{
+	"ad":       ActiveDirectoryMapping,
+	"openldap": OpenLDAPMapping,
+}
+
+func (c *clientctl) directoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "directory",
+		Short: "Load clients from an LDAP or Active Directory directory",
+	}
+	cmd.AddCommand(c.directorySyncCmd())
+
+	return cmd
+}
+
+func (c *clientctl) directorySyncCmd() *cobra.Command {
+	var (
+		cfg      LDAPConfig
+		name     string
+		schema   string
+		birthday string
+	)
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Create and update clients from directory users; the bind password is read from " + LDAPPasswordSecret,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mapping, ok := directorySchemas[schema]
+			if !ok {
+				return fmt.Errorf("%w: unknown directory schema %q, want ad or openldap", ErrValidation, schema)
+			}
+			mapping.Birthday = birthday
+			if strings.TrimSpace(cfg.URL) == "" || strings.TrimSpace(cfg.BaseDN) == "" {
+				return fmt.Errorf("%w: --url and --base-dn are required", ErrValidation)
+			}
+			if cfg.BindDN != "" {
+				password, err := (EnvSecrets{}).Secret(cmd.Context(), LDAPPasswordSecret)
+				if err != nil {
+					return err
+				}
+				cfg.BindPassword = password
+			}
+			cfg.Attributes = mapping.attributes()
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				report, err := NewDirectorySync(repo, name, NewLDAPDirectory(cfg), mapping).Sync(ctx)
+				if err != nil {
+					return err
+				}
+
+				w := cmd.OutOrStdout()
+				if c.output != outputTable {
+					return c.encode(w, report)
+				}
+				fmt.Fprintf(w, "created: %d\nupdated: %d\nskipped: %d\n", report.Created, report.Updated, report.Skipped)
+				for _, reason := range report.Rejected {
+					fmt.Fprintf(w, "rejected: %s\n", reason)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&cfg.URL, "url", "", "directory URL, ldap://host:389 or ldaps://host:636")
+	cmd.Flags().StringVar(&cfg.BaseDN, "base-dn", "", "branch of the directory to read users from")
+	cmd.Flags().StringVar(&cfg.BindDN, "bind-dn", "", "account to read the directory as; anonymous if empty")
+	cmd.Flags().StringVar(&cfg.Filter, "filter", DefaultLDAPFilter, "LDAP filter selecting users")
+	cmd.Flags().StringVar(&name, "name", "ldap", "directory name that client links are stored under")
+	cmd.Flags().StringVar(&schema, "schema", "ad", "attribute mapping: ad or openldap")
+	cmd.Flags().StringVar(&birthday, "birthday-attr", "", "attribute holding the birth date; clients are created only for users that have it")
+
+	return cmd
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет загрузку клиентов из каталога командой clientctl
+// directory sync и вывод отчёта
+func Test_Clientctl_DirectorySync(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+	server := newFakeLDAP(t,
+		adUser("guid-1", "Ivan Ivanov", "ivanov", "birthDate", "19900501"),
+		adUser("guid-2", "No Birthday", "nobirthday"),
+	)
+	t.Setenv(LDAPPasswordSecret, server.password)
+	run := func(args ...string) (string, error) {
+		return clientctl(append([]string{"directory", "sync", "--url", server.URL(), "--base-dn", "DC=corp", "--bind-dn", server.bindDN, "--birthday-attr", "birthDate"}, args...)...)
+	}
+
+	out, err := run()
+	require.NoError(t, err, out)
+	assert.Equal(t, "created: 1\nupdated: 0\nskipped: 1\nrejected: CN=No Birthday,OU=Users,DC=corp: validation failed: birthday is required\n", out)
+	assertRowCount(t, repo.db, "directory_links", 1, "source = 'ldap' AND external_id = 'guid-1'")
+	assert.Equal(t, []string{"objectGUID", "displayName", "sAMAccountName", "mail", "birthDate"}, server.attributes[0])
+
+	out, err = run("-o", "json")
+	require.NoError(t, err, out)
+	var report DirectorySyncReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.Equal(t, 2, report.Skipped)
+
+	out, err = run("--schema", "novell")
+	require.ErrorIs(t, err, ErrValidation, out)
+	out, err = clientctl("directory", "sync", "--url", server.URL())
+	require.ErrorIs(t, err, ErrValidation, out)
+
+	t.Setenv(LDAPPasswordSecret, "")
+	out, err = run()
+	require.ErrorIs(t, err, ErrSecretNotFound, out)
+}
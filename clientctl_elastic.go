@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// elasticFlags — адрес кластера и индекс для команд elastic.
+type elasticFlags struct {
+	url   string
+	index string
+}
+
+func (c *clientctl) elasticCmd() *cobra.Command {
+	var f elasticFlags
+	cmd := &cobra.Command{
+		Use:   "elastic",
+		Short: "Mirror clients into an Elasticsearch or OpenSearch index",
+	}
+	cmd.PersistentFlags().StringVar(&f.url, "url", "http://localhost:9200", "cluster URL, credentials may be given as user:password@host")
+	cmd.PersistentFlags().StringVar(&f.index, "index", "clients", "index name")
+	cmd.AddCommand(c.elasticReindexCmd(&f), c.elasticSyncCmd(&f), c.elasticCheckCmd(&f))
+
+	return cmd
+}
+
+// withElastic открывает синхронизацию с индексом из f на время выполнения fn.
+func (c *clientctl) withElastic(cmd *cobra.Command, f *elasticFlags, fn func(ctx context.Context, es *ElasticSync) error) error {
+	if strings.TrimSpace(f.index) == "" {
+		return fmt.Errorf("%w: index name is required", ErrValidation)
+	}
+
+	return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+		return fn(ctx, NewElasticSync(repo, f.url, f.index, ElasticConfig{}))
+	})
+}
+
+func (c *clientctl) elasticReindexCmd(f *elasticFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex",
+		Short: "Recreate the index with all clients",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withElastic(cmd, f, func(ctx context.Context, es *ElasticSync) error {
+				n, err := es.Reindex(ctx)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "indexed %d clients\n", n)
+				return err
+			})
+		},
+	}
+}
+
+func (c *clientctl) elasticSyncCmd(f *elasticFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Send all changes since the last sync to the index",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withElastic(cmd, f, func(ctx context.Context, es *ElasticSync) error {
+				total := 0
+				for {
+					n, err := es.Sync(ctx)
+					if err != nil {
+						return err
+					}
+					if n == 0 {
+						break
+					}
+					total += n
+				}
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "synced %d events\n", total)
+				return err
+			})
+		},
+	}
+}
+
+func (c *clientctl) elasticCheckCmd(f *elasticFlags) *cobra.Command {
+	var sample int
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Compare client and document counts and sampled documents; fails if they differ",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if sample < 0 {
+				return fmt.Errorf("%w: sample must not be negative", ErrValidation)
+			}
+
+			return c.withElastic(cmd, f, func(ctx context.Context, es *ElasticSync) error {
+				report, err := es.Check(ctx, sample)
+				if err != nil {
+					return err
+				}
+
+				w := cmd.OutOrStdout()
+				if c.output != outputTable {
+					// Пустой список выводится как [], а не null
+					if report.Mismatched == nil {
+						report.Mismatched = []int{}
+					}
+					if err := c.encode(w, report); err != nil {
+						return err
+					}
+				} else {
+					fmt.Fprintf(w, "clients: %d\ndocuments: %d\nsampled: %d\n", report.DBCount, report.IndexCount, report.Sampled)
+					for _, id := range report.Mismatched {
+						fmt.Fprintf(w, "mismatched: %d\n", id)
+					}
+				}
+
+				if !report.Consistent() {
+					return fmt.Errorf("index %s differs from the database: %d clients, %d documents, %d of %d sampled mismatched",
+						f.index, report.DBCount, report.IndexCount, len(report.Mismatched), report.Sampled)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&sample, "sample", 100, "number of random clients whose documents are compared")
+
+	return cmd
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет переиндексацию, синхронизацию и сверку индекса командой
+// clientctl elastic
+func Test_Clientctl_Elastic(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+	es := newFakeElastic(t)
+	elastic := func(args ...string) (string, error) {
+		return clientctl(append([]string{"elastic", "--url", es.URL, "--index", "crm"}, args...)...)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := clientctl("create", "--fio", "Test", "--login", "test", "--birthday", "19700101", "--email", "mail@mail.com")
+		require.NoError(t, err)
+	}
+
+	out, err := elastic("reindex")
+	require.NoError(t, err, out)
+	assert.Equal(t, "indexed 3 clients\n", out)
+	out, err = elastic("check")
+	require.NoError(t, err, out)
+	assert.Equal(t, "clients: 3\ndocuments: 3\nsampled: 3\n", out)
+
+	_, err = repo.db.Exec("UPDATE clients SET fio = 'Changed' WHERE id = 1")
+	require.NoError(t, err)
+	out, err = elastic("check", "-o", "json")
+	require.ErrorContains(t, err, "index crm differs from the database", out)
+	var report ElasticReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.Equal(t, []int{1}, report.Mismatched)
+
+	out, err = clientctl("delete", "2")
+	require.NoError(t, err, out)
+	out, err = elastic("sync")
+	require.NoError(t, err, out)
+	assert.Equal(t, "synced 1 events\n", out)
+	assert.Len(t, es.docs("crm"), 2)
+
+	out, err = elastic("check", "--sample", "-1")
+	require.ErrorIs(t, err, ErrValidation, out)
+	out, err = elastic("reindex", "--index", " ")
+	require.ErrorIs(t, err, ErrValidation, out)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// transferFlags — общие флаги команд export и import.
+type transferFlags struct {
+	format  string
+	columns map[string]string
+}
+
+func (f *transferFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.format, "format", string(FormatCSV), "data format: csv, json or ndjson")
+	cmd.Flags().StringToStringVar(&f.columns, "map", nil, "CSV column names for client fields, e.g. email=E-mail,fio=Name")
+}
+
+func (c *clientctl) exportCmd() *cobra.Command {
+	var (
+		flags         transferFlags
+		segment       string
+		filter        string
+		where         string
+		marketingOnly bool
+		nonProduction bool
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write clients to stdout",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if segment != "" && filter != "" {
+				return fmt.Errorf("%w: pass either --segment or --filter", ErrValidation)
+			}
+			opts := ExportOptions{
+				Format:        ExportFormat(flags.format),
+				Columns:       flags.columns,
+				MarketingOnly: marketingOnly,
+				NonProduction: nonProduction,
+			}
+			if filter != "" {
+				criteria, err := ParseSegmentCriteria([]byte(filter))
+				if err != nil {
+					return err
+				}
+				opts.Filter = criteria
+			}
+			if where != "" {
+				f, err := ParseFilter([]byte(where))
+				if err != nil {
+					return err
+				}
+				opts.Where = f
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				if segment != "" {
+					s, err := repo.Segment(ctx, segment)
+					if err != nil {
+						return err
+					}
+					opts.Filter = s.Criteria
+				}
+				return repo.Export(ctx, cmd.OutOrStdout(), opts)
+			})
+		},
+	}
+	flags.register(cmd)
+	cmd.Flags().StringVar(&segment, "segment", "", "export only clients of the saved segment")
+	cmd.Flags().StringVar(&filter, "filter", "", `export only clients matching segment criteria in JSON, e.g. {"email_domain":"mail.com"}`)
+	whereFlag(cmd, &where)
+	cmd.Flags().BoolVar(&marketingOnly, "marketing-only", false, "export only clients with marketing consent")
+	cmd.Flags().BoolVar(&nonProduction, "non-production", false, "mask personal data")
+
+	return cmd
+}
+
+func (c *clientctl) importCmd() *cobra.Command {
+	var (
+		flags  transferFlags
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "import [FILE]",
+		Short: "Load clients from FILE or stdin in one transaction",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src := cmd.InOrStdin()
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				src = f
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				n, err := repo.ImportWith(ctx, src, ImportOptions{
+					Format:  ExportFormat(flags.format),
+					Columns: flags.columns,
+					DryRun:  dryRun,
+				})
+				if err != nil {
+					return err
+				}
+				return c.printImported(cmd.OutOrStdout(), n, dryRun)
+			})
+		},
+	}
+	flags.register(cmd)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate and count clients without saving them")
+
+	return cmd
+}
+
+// printImported выводит число загруженных клиентов строкой или объектом
+// JSON или YAML.
+func (c *clientctl) printImported(w io.Writer, n int, dryRun bool) error {
+	if c.output != outputTable {
+		return c.encode(w, struct {
+			Imported int  `json:"imported"`
+			DryRun   bool `json:"dry_run"`
+		}{n, dryRun})
+	}
+
+	verb := "imported"
+	if dryRun {
+		verb = "would import"
+	}
+	_, err := fmt.Fprintf(w, "%s %d clients\n", verb, n)
+
+	return err
+}
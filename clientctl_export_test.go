@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// insertTransferClients заполняет БД клиентами для проверки выгрузки:
+// два клиента с почтой mail.com, один из них с меткой vip
+func insertTransferClients(t *testing.T, repo *Repository) {
+	t.Helper()
+
+	ctx := context.Background()
+	clients := []Client{
+		{FIO: "Иванов Иван", Login: "ivanov", Birthday: "19850615", Email: "ivanov@mail.com"},
+		{FIO: "Петров, Пётр", Login: "petrov", Birthday: "19900101", Email: "petrov@corp.ru"},
+		{FIO: "Сидоров Сидор", Login: "sidorov", Birthday: "19791231", Email: "sidorov@mail.com"},
+	}
+	for _, cl := range clients {
+		_, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.TagClient(ctx, 3, "vip"))
+	_, err := repo.SaveSegment(ctx, "vip", SegmentCriteria{Tags: []string{"vip"}})
+	require.NoError(t, err)
+}
+
+// Тест сравнивает вывод clientctl export в разных форматах, с отбором и
+// сопоставлением столбцов с файлами в testdata/golden; после намеренного
+// изменения формата выполните go test -run Test_Clientctl_ExportGolden -update
+func Test_Clientctl_ExportGolden(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+	insertTransferClients(t, repo)
+
+	tests := []struct {
+		golden string
+		args   []string
+	}{
+		{"clientctl_export.csv", nil},
+		{"clientctl_export.json", []string{"--format", "json"}},
+		{"clientctl_export.ndjson", []string{"--format", "ndjson"}},
+		{"clientctl_export_mapped.csv", []string{"--map", "fio=ФИО,email=E-mail", "--filter", `{"email_domain":"mail.com"}`}},
+		{"clientctl_export_segment.ndjson", []string{"--format", "ndjson", "--segment", "vip", "--non-production"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			out, err := clientctl(append([]string{"export"}, tt.args...)...)
+			require.NoError(t, err, out)
+			assertGolden(t, tt.golden, []byte(out))
+		})
+	}
+
+	for name, args := range map[string][]string{
+		"UnknownFormat":      {"export", "--format", "xml"},
+		"MappingForJSON":     {"export", "--format", "json", "--map", "email=E-mail"},
+		"UnknownMappedField": {"export", "--map", "phone=Телефон"},
+		"DuplicateColumn":    {"export", "--map", "fio=login"},
+		"InvalidFilter":      {"export", "--filter", `{"born_from":"1980"}`},
+		"SegmentAndFilter":   {"export", "--segment", "vip", "--filter", "{}"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := clientctl(args...)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+	_, err := clientctl("export", "--segment", "missing")
+	require.ErrorIs(t, err, ErrSegmentNotFound)
+}
+
+// Тест проверяет, что выгрузка в каждом формате загружается командой
+// import из stdin в другую БД, и сравнивает результат с эталоном
+func Test_Clientctl_ImportGolden(t *testing.T) {
+	source, repo := newClientctlTest(t)
+	insertTransferClients(t, repo)
+
+	for _, tt := range []struct {
+		format string
+		args   []string
+	}{
+		{"csv", nil},
+		{"csv", []string{"--map", "fio=ФИО,email=E-mail"}},
+		{"json", nil},
+		{"ndjson", nil},
+	} {
+		t.Run(tt.format+strings.Join(tt.args, ""), func(t *testing.T) {
+			args := append([]string{"--format", tt.format}, tt.args...)
+			exported, err := source(append([]string{"export"}, args...)...)
+			require.NoError(t, err, exported)
+
+			target, targetRepo := newClientctlTest(t)
+			out, err := execClientctl(targetRepo.db, strings.NewReader(exported), append([]string{"import"}, args...)...)
+			require.NoError(t, err, out)
+			assert.Equal(t, "imported 3 clients\n", out)
+
+			out, err = target("list")
+			require.NoError(t, err, out)
+			assertGolden(t, "clientctl_import_list.txt", []byte(out))
+		})
+	}
+}
+
+// Тест проверяет пробную загрузку, загрузку из файла и ошибки с номером
+// строки или записи
+func Test_Clientctl_ImportDryRunAndErrors(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	csv := "id,fio,login,birthday,email\n0,Иванов Иван,ivanov,19850615,ivanov@mail.com\n"
+	out, err := execClientctl(repo.db, strings.NewReader(csv), "import", "--dry-run")
+	require.NoError(t, err, out)
+	assert.Equal(t, "would import 1 clients\n", out)
+	assertRowCount(t, repo.db, "clients", 0, "1 = 1")
+	assertRowCount(t, repo.db, "audit_log", 0, "1 = 1")
+
+	file := filepath.Join(t.TempDir(), "clients.csv")
+	require.NoError(t, os.WriteFile(file, []byte(csv), 0o600))
+	out, err = clientctl("import", file)
+	require.NoError(t, err, out)
+	assert.Equal(t, "imported 1 clients\n", out)
+
+	tests := []struct {
+		name    string
+		args    []string
+		input   string
+		wantMsg string
+	}{
+		{"CSVInvalidClient", nil, csv + "0,Bad,bad,1990,bad\n", "line 3"},
+		{"CSVMissingColumn", nil, "fio,login,birthday\n", "missing column"},
+		{"CSVUnmappedColumn", []string{"--map", "email=E-mail"}, csv, "missing column"},
+		{"JSONInvalidClient", []string{"--format", "json"}, `[{"fio":"Ok","login":"ok","birthday":"19900101","email":"ok@mail.com"},{"fio":"Bad"}]`, "record 2"},
+		{"JSONNotArray", []string{"--format", "json"}, `{"fio":"Ok"}`, "array"},
+		{"JSONTruncated", []string{"--format", "json"}, `[{"fio":"Ok"`, "JSON"},
+		{"NDJSONSyntax", []string{"--format", "ndjson"}, "{\"fio\":\"Ok\",\"login\":\"ok\",\"birthday\":\"19900101\",\"email\":\"ok@mail.com\"}\nnot json\n", "record 2"},
+		{"UnknownFormat", []string{"--format", "xml"}, csv, "format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := execClientctl(repo.db, strings.NewReader(tt.input), append([]string{"import"}, tt.args...)...)
+			require.ErrorIs(t, err, ErrValidation)
+			assert.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+	assertRowCount(t, repo.db, "clients", 1, "1 = 1")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// promptFields — поля клиента в порядке запроса в интерактивном режиме.
+var promptFields = []struct {
+	name, label string
+	field       func(*Client) *string
+	max         int
+}{
+	{"fio", "FIO", func(cl *Client) *string { return &cl.FIO }, maxFIOLen},
+	{"login", "Login", func(cl *Client) *string { return &cl.Login }, maxLoginLen},
+	{"birthday", "Birthday (YYYYMMDD)", func(cl *Client) *string { return &cl.Birthday }, 0},
+	{"email", "Email", func(cl *Client) *string { return &cl.Email }, maxEmailLen},
+}
+
+// promptClient запрашивает поля клиента cl по одному: подсказки пишутся в
+// out, ответы читаются из in. Текущее значение поля показывается в скобках
+// и остаётся, если ответ пустой. Каждое поле сразу проверяется так же, как
+// в Client.Validate (дата рождения — относительно now); при ошибке она
+// выводится, и поле запрашивается снова.
+func promptClient(in io.Reader, out io.Writer, cl *Client, now time.Time) error {
+	sc := bufio.NewScanner(in)
+	for _, f := range promptFields {
+		value := f.field(cl)
+		for {
+			if *value != "" {
+				fmt.Fprintf(out, "%s [%s]: ", f.label, *value)
+			} else {
+				fmt.Fprintf(out, "%s: ", f.label)
+			}
+			if !sc.Scan() {
+				if err := sc.Err(); err != nil {
+					return err
+				}
+				return fmt.Errorf("reading %s: %w", f.name, io.ErrUnexpectedEOF)
+			}
+
+			answer := *value
+			if s := strings.TrimSpace(sc.Text()); s != "" {
+				answer = s
+			}
+
+			var err error
+			if f.name == "birthday" {
+				_, err = parseBirthday(answer, now)
+			} else {
+				err = validateField(f.name, answer, f.max)
+			}
+			if err != nil {
+				fmt.Fprintf(out, "  %v\n", err)
+				continue
+			}
+
+			*value = answer
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func (c *clientctl) maintainCmd() *cobra.Command {
+	var tasks []string
+	cmd := &cobra.Command{
+		Use:   "maintain",
+		Short: "Run VACUUM, ANALYZE and PRAGMA optimize; progress is logged to stderr",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			plan := make([]MaintenanceTask, len(tasks))
+			for i, task := range tasks {
+				plan[i] = MaintenanceTask(task)
+			}
+			logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil))
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				steps, err := repo.Maintain(ctx, logger, plan...)
+				if err != nil {
+					return err
+				}
+
+				w := cmd.OutOrStdout()
+				if c.output != outputTable {
+					return c.encode(w, steps)
+				}
+
+				tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, "TASK\tDURATION\tSIZE BEFORE\tSIZE AFTER")
+				for _, s := range steps {
+					fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", s.Task, s.Duration, s.SizeBefore, s.SizeAfter)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	cmd.Flags().StringSliceVar(&tasks, "task", nil, "tasks to run: vacuum, analyze, optimize (default all)")
+
+	return cmd
+}
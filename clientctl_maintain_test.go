@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет вывод clientctl maintain, ход выполнения в stderr и
+// проверку списка операций
+func Test_Clientctl_Maintain(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+	maintain := func(args ...string) (string, string, error) {
+		return execClientctlSplit(db, strings.NewReader(""), append([]string{"maintain"}, args...)...)
+	}
+
+	out, log, err := maintain("-o", "json", "--task", "optimize,analyze")
+	require.NoError(t, err, log)
+	var steps []MaintenanceStep
+	require.NoError(t, json.Unmarshal([]byte(out), &steps))
+	require.Len(t, steps, 2)
+	assert.Equal(t, MaintenanceAnalyze, steps[0].Task)
+	assert.Equal(t, MaintenanceOptimize, steps[1].Task)
+	assert.Contains(t, log, `msg="maintenance task started" task=analyze step=1 steps=2`)
+
+	out, _, err = maintain()
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"vacuum", "analyze", "optimize"},
+		[]string{strings.Fields(lines[1])[0], strings.Fields(lines[2])[0], strings.Fields(lines[3])[0]})
+
+	_, _, err = maintain("--task", "reindex")
+	require.ErrorIs(t, err, ErrValidation)
+}
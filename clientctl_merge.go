@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+func (c *clientctl) mergeCmd() *cobra.Command {
+	var opts DestructiveOptions
+	cmd := &cobra.Command{
+		Use:   "merge KEEP_ID DUPLICATE_ID...",
+		Short: "Merge duplicate clients into KEEP_ID and soft-delete the duplicates",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseClientIDs(args)
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				affected, err := repo.MergeClientsWith(ctx, ids[0], ids[1:], opts)
+				if err != nil || !opts.DryRun {
+					return err
+				}
+				return c.printAffected(cmd.OutOrStdout(), "merge", affected)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "print the rows that would be moved and deleted without merging")
+
+	return cmd
+}
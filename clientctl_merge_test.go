@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет clientctl merge и вывод --dry-run у merge и delete
+func Test_Clientctl_MergeAndDeleteDryRun(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		id, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		_, err = repo.AddNote(ctx, id, "note")
+		require.NoError(t, err)
+	}
+
+	out, err := clientctl("merge", "1", "2", "--dry-run")
+	require.NoError(t, err, out)
+	assert.Equal(t, "would merge clients 2\nTABLE         ROWS\nclient_notes  1\nclients       1\n", out)
+	assertRowCount(t, repo.db, "clients", 0, "deleted_at != ''")
+
+	out, err = clientctl("merge", "1", "2")
+	require.NoError(t, err, out)
+	assert.Empty(t, out)
+	assertRowCount(t, repo.db, "clients", 1, "deleted_at != ''")
+
+	out, err = clientctl("delete", "1", "3", "--dry-run", "-o", "json")
+	require.NoError(t, err, out)
+	var affected Affected
+	require.NoError(t, json.Unmarshal([]byte(out), &affected))
+	assert.Equal(t, Affected{ClientIDs: []int{1, 3}, Rows: map[string]int64{"clients": 2, "client_notes": 3}}, affected)
+	assertRowCount(t, repo.db, "clients", 2, "deleted_at = ''")
+
+	out, err = clientctl("purge", "--dry-run")
+	require.NoError(t, err, out)
+	assert.Equal(t, "would purge 0 clients\n", out)
+
+	out, err = clientctl("delete", "1", "3")
+	require.NoError(t, err, out)
+	assertRowCount(t, repo.db, "clients", 0, "deleted_at = ''")
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func (c *clientctl) migrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, roll back and show schema migrations",
+	}
+	cmd.AddCommand(c.migrateUpCmd(), c.migrateDownCmd(), c.migrateStatusCmd())
+
+	return cmd
+}
+
+func (c *clientctl) migrateUpCmd() *cobra.Command {
+	var (
+		target int
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if target < 0 {
+				return fmt.Errorf("%w: target version must not be negative, got %d", ErrValidation, target)
+			}
+			if target == 0 {
+				target = migrations[len(migrations)-1].version
+			}
+
+			return c.withDB(cmd, func(ctx context.Context, db *sql.DB) error {
+				plan, err := migratePlan(ctx, db, target)
+				if err != nil {
+					return err
+				}
+				if dryRun {
+					return c.printMigrationSQL(cmd.OutOrStdout(), plan, "up")
+				}
+
+				if err := MigrateTo(ctx, db, target); err != nil {
+					return err
+				}
+				return c.printMigrated(cmd.OutOrStdout(), plan, "applied")
+			})
+		},
+	}
+	cmd.Flags().IntVar(&target, "to", 0, "apply migrations up to this version (0 means all)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the SQL that would run without running it")
+
+	return cmd
+}
+
+func (c *clientctl) migrateDownCmd() *cobra.Command {
+	var (
+		steps  int
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Roll back the latest applied migrations; data in dropped tables and columns is lost",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withDB(cmd, func(ctx context.Context, db *sql.DB) error {
+				plan, err := rollbackPlan(ctx, db, steps)
+				if err != nil {
+					return err
+				}
+				if dryRun {
+					return c.printMigrationSQL(cmd.OutOrStdout(), plan, "down")
+				}
+
+				if err := Rollback(ctx, db, steps); err != nil {
+					return err
+				}
+				return c.printMigrated(cmd.OutOrStdout(), plan, "rolled back")
+			})
+		},
+	}
+	cmd.Flags().IntVar(&steps, "steps", 1, "number of migrations to roll back")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the SQL that would run without running it")
+
+	return cmd
+}
+
+func (c *clientctl) migrateStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withDB(cmd, func(ctx context.Context, db *sql.DB) error {
+				statuses, err := MigrationStatuses(ctx, db)
+				if err != nil {
+					return err
+				}
+
+				w := cmd.OutOrStdout()
+				if c.output != outputTable {
+					return c.encode(w, statuses)
+				}
+
+				tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+				for _, s := range statuses {
+					appliedAt := s.AppliedAt
+					if !s.Applied() {
+						appliedAt = "pending"
+					}
+					fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+}
+
+// printMigrationSQL выводит SQL шагов direction ("up" или "down")
+// миграций plan в порядке выполнения: скриптом с комментариями или списком
+// в JSON или YAML.
+func (c *clientctl) printMigrationSQL(w io.Writer, plan []migration, direction string) error {
+	if c.output != outputTable {
+		type step struct {
+			Version int    `json:"version"`
+			Name    string `json:"name"`
+			SQL     string `json:"sql"`
+		}
+		steps := make([]step, len(plan))
+		for i, m := range plan {
+			query := m.up
+			if direction == "down" {
+				query = m.down
+			}
+			steps[i] = step{m.version, m.name, strings.TrimSpace(query)}
+		}
+		return c.encode(w, steps)
+	}
+
+	if len(plan) == 0 {
+		_, err := fmt.Fprintln(w, "-- nothing to do")
+		return err
+	}
+
+	for _, m := range plan {
+		query := m.up
+		if direction == "down" {
+			query = m.down
+		}
+		if _, err := fmt.Fprintf(w, "-- %s %d %s\n%s\n\n", direction, m.version, m.name, strings.TrimSpace(query)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printMigrated выводит применённые или откаченные миграции plan строками
+// с глаголом verb или списком в JSON или YAML.
+func (c *clientctl) printMigrated(w io.Writer, plan []migration, verb string) error {
+	if c.output != outputTable {
+		done := make([]MigrationStatus, len(plan))
+		for i, m := range plan {
+			done[i] = MigrationStatus{Version: m.version, Name: m.name}
+		}
+		return c.encode(w, done)
+	}
+
+	for _, m := range plan {
+		if _, err := fmt.Fprintf(w, "%s %d %s\n", verb, m.version, m.name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
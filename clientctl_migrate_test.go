@@ -0,0 +1,156 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFileClientctlTest возвращает функцию, выполняющую команду clientctl над
+// временной БД, и соединение с этой БД.
+func newFileClientctlTest(t *testing.T) (func(args ...string) (string, error), *sql.DB) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "clients.db")
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	exec := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := runClientctl(context.Background(), append([]string{"--db", dsn}, args...), &out)
+		return out.String(), err
+	}
+
+	return exec, db
+}
+
+// appliedVersions возвращает версии применённых миграций по статусу
+func appliedVersions(t *testing.T, clientctl func(args ...string) (string, error)) []int {
+	t.Helper()
+
+	out, err := clientctl("migrate", "status", "-o", "json")
+	require.NoError(t, err, out)
+	var statuses []MigrationStatus
+	require.NoError(t, json.Unmarshal([]byte(out), &statuses))
+	require.Len(t, statuses, len(migrations))
+
+	var versions []int
+	for _, s := range statuses {
+		if s.Applied() {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+// tableNames возвращает имена таблиц БД без служебных таблиц SQLite
+func tableNames(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	names, err := queryStrings(context.Background(), db,
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	require.NoError(t, err)
+	return names
+}
+
+// Тест проверяет применение миграций до версии и полностью, их статус и
+// вывод SQL без выполнения
+func Test_Clientctl_MigrateUp(t *testing.T) {
+	clientctl, db := newFileClientctlTest(t)
+
+	assert.Empty(t, appliedVersions(t, clientctl), "new database should have no applied migrations")
+
+	out, err := clientctl("migrate", "up", "--to", "2", "--dry-run")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "-- up 1 base schema\nCREATE TABLE IF NOT EXISTS clients")
+	assert.Contains(t, out, "-- up 2 erasure receipts\nCREATE TABLE erasure_receipts")
+	assert.NotContains(t, out, "audit_log")
+	assert.Empty(t, tableNames(t, db), "dry run should not change the database")
+
+	out, err = clientctl("migrate", "up", "--to", "5")
+	require.NoError(t, err, out)
+	assert.Equal(t, "applied 1 base schema\napplied 2 erasure receipts\napplied 3 audit log\napplied 4 client owner\napplied 5 marketing consent\n", out)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, appliedVersions(t, clientctl))
+
+	out, err = clientctl("migrate", "up", "--dry-run")
+	require.NoError(t, err, out)
+	assert.True(t, strings.HasPrefix(out, "-- up 6 audit request id\n"), out)
+
+	out, err = clientctl("migrate", "up")
+	require.NoError(t, err, out)
+	assert.Len(t, appliedVersions(t, clientctl), len(migrations))
+
+	out, err = clientctl("migrate", "up", "--dry-run")
+	require.NoError(t, err, out)
+	assert.Equal(t, "-- nothing to do\n", out)
+
+	out, err = clientctl("migrate", "status")
+	require.NoError(t, err, out)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, len(migrations)+1)
+	assert.Equal(t, []string{"VERSION", "NAME", "APPLIED", "AT"}, strings.Fields(lines[0]))
+	assert.NotContains(t, out, "pending")
+
+	_, err = clientctl("migrate", "up", "--to", "-1")
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+// Тест проверяет откат миграций: вывод SQL без выполнения, откат по шагам
+// и повторное применение после полного отката с данными в БД
+func Test_Clientctl_MigrateDown(t *testing.T) {
+	clientctl, db := newFileClientctlTest(t)
+
+	_, err := clientctl("migrate", "up")
+	require.NoError(t, err)
+	out, err := clientctl("create", "--fio", "Иванов Иван", "--login", "ivanov", "--birthday", "19850615", "--email", "ivanov@mail.com")
+	require.NoError(t, err, out)
+	tables := tableNames(t, db)
+
+	out, err = clientctl("migrate", "down", "--steps", "2", "--dry-run")
+	require.NoError(t, err, out)
+	last, prev := migrations[len(migrations)-1], migrations[len(migrations)-2]
+	assert.Equal(t, "-- down "+strconv.Itoa(last.version)+" "+last.name+"\n"+strings.TrimSpace(last.down)+"\n\n"+
+		"-- down "+strconv.Itoa(prev.version)+" "+prev.name+"\n"+strings.TrimSpace(prev.down)+"\n\n", out)
+	assert.Len(t, appliedVersions(t, clientctl), len(migrations), "dry run should not roll back")
+
+	out, err = clientctl("migrate", "down")
+	require.NoError(t, err, out)
+	assert.Equal(t, "rolled back "+strconv.Itoa(last.version)+" "+last.name+"\n", out)
+	assert.Len(t, appliedVersions(t, clientctl), len(migrations)-1)
+
+	// Остальные команды не применяют откаченную миграцию заново
+	for _, args := range [][]string{{"list"}, {"get", "1"}, {"env"}} {
+		out, err = clientctl(args...)
+		require.ErrorIs(t, err, ErrPendingMigrations, args)
+		assert.Contains(t, err.Error(), "run `clientctl migrate up`")
+	}
+	assert.Len(t, appliedVersions(t, clientctl), len(migrations)-1)
+
+	// Полный откат удаляет все таблицы схемы и выполняет каждый шаг down
+	out, err = clientctl("migrate", "down", "--steps", "100")
+	require.NoError(t, err, out)
+	assert.Empty(t, appliedVersions(t, clientctl))
+	assert.Equal(t, []string{"schema_migrations"}, tableNames(t, db))
+
+	out, err = clientctl("migrate", "down", "--dry-run")
+	require.NoError(t, err, out)
+	assert.Equal(t, "-- nothing to do\n", out)
+
+	_, err = clientctl("migrate", "up")
+	require.NoError(t, err)
+	assert.Equal(t, tables, tableNames(t, db), "schema should be the same after rollback and reapply")
+
+	_, err = clientctl("migrate", "down", "--steps", "-1")
+	require.ErrorIs(t, err, ErrValidation)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Форматы вывода команд clientctl.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFormats — допустимые значения флага --output.
+var outputFormats = []string{outputTable, outputJSON, outputYAML}
+
+// validateOutput проверяет значение флага --output.
+func validateOutput(output string) error {
+	if !slices.Contains(outputFormats, output) {
+		return fmt.Errorf("unknown output format %q, want %s, %s or %s", output, outputTable, outputJSON, outputYAML)
+	}
+
+	return nil
+}
+
+// encode выводит v в формате c.output, отличном от таблицы. В YAML поля
+// называются и упорядочены так же, как в JSON.
+func (c *clientctl) encode(w io.Writer, v any) error {
+	if c.output == outputYAML {
+		return encodeYAML(w, v)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// encodeYAML выводит v в YAML через JSON: так используются теги json и
+// методы MarshalJSON, а порядок полей сохраняется.
+func encodeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// JSON — подмножество YAML; разобранный документ выводится в блочном
+	// стиле, строки берутся в кавычки, только если без них меняется тип
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return err
+	}
+	resetYAMLStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func resetYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		resetYAMLStyle(child)
+	}
+}
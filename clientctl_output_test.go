@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// Тест проверяет, что YAML использует имена полей и порядок из JSON и
+// берёт в кавычки только строки, которые без кавычек сменили бы тип
+func Test_EncodeYAML(t *testing.T) {
+	v := struct {
+		ID       int                `json:"id"`
+		Birthday string             `json:"birthday"`
+		Note     string             `json:"note,omitempty"`
+		Flag     string             `json:"flag"`
+		Tags     []string           `json:"tags"`
+		Empty    []int              `json:"empty"`
+		Rows     map[string]int64   `json:"rows"`
+		Nested   struct{ A string } `json:"nested"`
+	}{
+		ID:       7,
+		Birthday: "19850615",
+		Flag:     "true",
+		Tags:     []string{"vip", "new"},
+		Empty:    []int{},
+		Rows:     map[string]int64{"clients": 2},
+	}
+	v.Nested.A = "Иванов Иван"
+
+	var buf bytes.Buffer
+	require.NoError(t, encodeYAML(&buf, v))
+	assert.Equal(t, `id: 7
+birthday: "19850615"
+flag: "true"
+tags:
+  - vip
+  - new
+empty: []
+rows:
+  clients: 2
+nested:
+  A: Иванов Иван
+`, buf.String())
+}
+
+// yamlAsJSON декодирует YAML и кодирует результат в JSON, чтобы сравнить
+// его с выводом в JSON
+func yamlAsJSON(t *testing.T, s string) string {
+	t.Helper()
+
+	var v any
+	require.NoError(t, yaml.Unmarshal([]byte(s), &v))
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+// withoutKey удаляет поле key из объекта JSON s
+func withoutKey(t *testing.T, s, key string) string {
+	t.Helper()
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s), &m))
+	delete(m, key)
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+// Тест проверяет, что каждая команда выводит в JSON и YAML одни и те же
+// данные, а в таблице — текст
+func Test_Clientctl_OutputFormats(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+	id, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Birthday = "19850615" }))
+	require.NoError(t, err)
+
+	commands := [][]string{
+		{"get", strconv.Itoa(id)},
+		{"list"},
+		{"stats"},
+		{"check"},
+		{"env"},
+		{"migrate", "status"},
+		{"migrate", "down", "--dry-run"},
+		{"merge", strconv.Itoa(id), "1000", "--dry-run"},
+		{"delete", strconv.Itoa(id), "--dry-run"},
+		{"purge", "--dry-run"},
+		{"import", "--dry-run", "--format", "json"},
+	}
+	for _, args := range commands {
+		t.Run(strings.Join(args, "_"), func(t *testing.T) {
+			run := func(output string) string {
+				t.Helper()
+				stdin := strings.NewReader(`[{"fio": "Петров Пётр", "login": "petrov", "birthday": "19900101", "email": "petrov@corp.ru"}]`)
+				out, _, err := execClientctlSplit(db, stdin, append(args, "-o", output)...)
+				if args[0] == "merge" {
+					require.ErrorIs(t, err, ErrClientNotFound)
+					return out
+				}
+				require.NoError(t, err, out)
+				return out
+			}
+
+			table := run(outputTable)
+			jsonOut := run(outputJSON)
+			yamlOut := run(outputYAML)
+			if args[0] == "merge" {
+				assert.Empty(t, table+jsonOut+yamlOut, "nothing is printed on error")
+				return
+			}
+
+			assert.True(t, json.Valid([]byte(jsonOut)), jsonOut)
+			yamlOut = yamlAsJSON(t, yamlOut)
+			if args[0] == "stats" {
+				// Время сбора статистики у запусков разное
+				jsonOut, yamlOut = withoutKey(t, jsonOut, "collected_at"), withoutKey(t, yamlOut, "collected_at")
+			}
+			assert.JSONEq(t, jsonOut, yamlOut)
+			assert.False(t, json.Valid([]byte(table)), "table output should be text: %s", table)
+		})
+	}
+
+	out, _, err := execClientctlSplit(db, strings.NewReader(""), "get", strconv.Itoa(id), "-o", "yaml")
+	require.NoError(t, err)
+	assert.Equal(t, `id: 1
+fio: Test
+login: Test
+birthday: "1985-06-15"
+email: mail@mail.com
+marketing_consent: false
+status: active
+`, out)
+}
+
+// Тест проверяет интерактивное создание и изменение клиента: подсказки с
+// текущими значениями и сообщения об ошибках выводятся в stderr, а поле
+// с ошибкой запрашивается снова
+func Test_Clientctl_Interactive(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+
+	future := time.Now().AddDate(1, 0, 0).Format(birthdayLayout)
+	stdin := strings.NewReader("\n\nivanov\n1985-06-15\n" + future + "\n19850615\nivanov@mail.com\n")
+	out, prompts, err := execClientctlSplit(db, stdin, "create", "-i", "--fio", "Иванов Иван", "-o", "json")
+	require.NoError(t, err, prompts)
+
+	var created Client
+	require.NoError(t, json.Unmarshal([]byte(out), &created))
+	assert.Equal(t, "Иванов Иван", created.FIO)
+	assert.Equal(t, "ivanov", created.Login)
+	assert.Equal(t, "19850615", created.Birthday)
+	assert.Equal(t, "ivanov@mail.com", created.Email)
+	assert.Equal(t, "FIO [Иванов Иван]: Login: "+
+		"  validation failed: login is required\nLogin: "+
+		"Birthday (YYYYMMDD): "+
+		"  validation failed: birthday is not a valid YYYYMMDD date\nBirthday (YYYYMMDD): "+
+		"  validation failed: birthday is out of range\nBirthday (YYYYMMDD): "+
+		"Email: ", prompts)
+
+	id := strconv.Itoa(created.ID)
+	out, prompts, err = execClientctlSplit(db, strings.NewReader("\n\n\nivan@corp.ru\n"), "update", id, "--interactive", "--login", "ivan85")
+	require.NoError(t, err, prompts)
+	assert.Contains(t, prompts, "Login [ivan85]: ")
+	assert.Contains(t, prompts, "Email [ivanov@mail.com]: ")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{id, "Иванов", "Иван", "ivan85", "19850615", "ivan@corp.ru", "active"}, strings.Fields(lines[1]))
+
+	// Ввод закончился раньше, чем все поля заполнены
+	_, _, err = execClientctlSplit(db, strings.NewReader("Петров Пётр\n"), "create", "-i")
+	require.ErrorContains(t, err, "reading login: unexpected EOF")
+	assertRowCount(t, db, "clients", 1, "1")
+}
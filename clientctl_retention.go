@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func (c *clientctl) purgeCmd() *cobra.Command {
+	var (
+		days int
+		opts DestructiveOptions
+	)
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently delete clients soft-deleted longer ago than the retention period, except those on legal hold",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			policy := RetentionPolicy{SoftDeletedFor: time.Duration(days) * 24 * time.Hour}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				receipts, err := repo.PurgeSoftDeleted(ctx, policy, opts)
+				if err != nil {
+					return err
+				}
+
+				if c.output != outputTable {
+					if receipts == nil {
+						receipts = []ErasureReceipt{}
+					}
+					return c.encode(cmd.OutOrStdout(), receipts)
+				}
+				verb := "purged"
+				if opts.DryRun {
+					verb = "would purge"
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s %d clients\n", verb, len(receipts))
+				return err
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "print the clients that would be purged without deleting them")
+	cmd.Flags().IntVar(&days, "days", int(DefaultRetention/(24*time.Hour)), "retention period for soft-deleted clients in days")
+
+	return cmd
+}
+
+func (c *clientctl) holdCmd() *cobra.Command {
+	var release bool
+	cmd := &cobra.Command{
+		Use:   "hold ID",
+		Short: "Put a client on legal hold so that purge keeps it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseClientID(args[0])
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				return repo.SetLegalHold(ctx, id, !release)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&release, "release", false, "release the hold instead")
+
+	return cmd
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что clientctl purge удаляет мягко удалённых клиентов,
+// кроме поставленных на удержание через clientctl hold
+func Test_Clientctl_PurgeAndHold(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	ctx := context.Background()
+	ids := make([]int, 3)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.MergeClients(ctx, ids[0], ids[1], ids[2]))
+
+	out, err := clientctl("purge")
+	require.NoError(t, err, out)
+	assert.Equal(t, "purged 0 clients\n", out)
+
+	out, err = clientctl("hold", "3")
+	require.NoError(t, err, out)
+
+	out, err = clientctl("purge", "--days", "0")
+	require.ErrorIs(t, err, ErrValidation, out)
+
+	// clientctl работает по системным часам, поэтому дата мягкого
+	// удаления сдвигается в прошлое
+	_, err = repo.db.ExecContext(ctx, "UPDATE clients SET deleted_at = '2000-01-01T00:00:00Z' WHERE deleted_at != ''")
+	require.NoError(t, err)
+
+	out, err = clientctl("purge", "-o", "json")
+	require.NoError(t, err, out)
+	var receipts []ErasureReceipt
+	require.NoError(t, json.Unmarshal([]byte(out), &receipts))
+	require.Len(t, receipts, 1)
+	assert.Equal(t, ids[1], receipts[0].ClientID)
+
+	out, err = clientctl("hold", "3", "--release")
+	require.NoError(t, err, out)
+	out, err = clientctl("purge")
+	require.NoError(t, err, out)
+	assert.Equal(t, "purged 1 clients\n", out)
+}
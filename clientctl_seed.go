@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/gen"
+	"github.com/spf13/cobra"
+)
+
+func (c *clientctl) envCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Show the environment the database is marked with",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withMigratedDB(cmd, func(ctx context.Context, db *sql.DB) error {
+				env, err := DatabaseEnvironment(ctx, db)
+				if err != nil {
+					return err
+				}
+				if env == "" {
+					env = "unmarked"
+				}
+				if c.output != outputTable {
+					return c.encode(cmd.OutOrStdout(), struct {
+						Environment Environment `json:"environment"`
+					}{env})
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), env)
+				return err
+			})
+		},
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set ENVIRONMENT",
+		Short: "Mark the database as production, staging, development or test",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.withMigratedDB(cmd, func(ctx context.Context, db *sql.DB) error {
+				return SetEnvironment(ctx, db, Environment(args[0]))
+			})
+		},
+	})
+
+	return cmd
+}
+
+func (c *clientctl) seedCmd() *cobra.Command {
+	var (
+		generate int
+		seed     int64
+	)
+	cmd := &cobra.Command{
+		Use:   "seed [FILE...]",
+		Short: "Load fixture files (.csv in export format or .sql) or generate fake clients",
+		Long: "Load fixture files (.csv in export format or .sql) or generate fake clients.\n" +
+			"The database must be marked as non-production with clientctl env set.",
+		RunE: func(cmd *cobra.Command, files []string) error {
+			if (len(files) == 0) == (generate == 0) {
+				return fmt.Errorf("%w: pass either fixture files or --generate", ErrValidation)
+			}
+			if generate < 0 {
+				return fmt.Errorf("%w: number of clients to generate must be positive, got %d", ErrValidation, generate)
+			}
+
+			return c.withMigratedDB(cmd, func(ctx context.Context, db *sql.DB) error {
+				if err := requireNonProduction(ctx, db); err != nil {
+					return err
+				}
+
+				before, err := countClients(ctx, db)
+				if err != nil {
+					return err
+				}
+
+				repo := NewRepository(db)
+				if generate > 0 {
+					err = seedGenerated(ctx, repo, gen.New(seed).Clients(generate))
+				} else {
+					err = seedFixtures(ctx, repo, db, files)
+				}
+				if err != nil {
+					return err
+				}
+
+				after, err := countClients(ctx, db)
+				if err != nil {
+					return err
+				}
+				if c.output != outputTable {
+					return c.encode(cmd.OutOrStdout(), struct {
+						Seeded int `json:"seeded"`
+					}{after - before})
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "seeded %d clients\n", after-before)
+				return err
+			})
+		},
+	}
+	cmd.Flags().IntVar(&generate, "generate", 0, "number of fake clients to generate")
+	cmd.Flags().Int64Var(&seed, "seed", 1, "seed for generated clients")
+
+	return cmd
+}
+
+// seedGenerated вставляет сгенерированных клиентов в одной транзакции.
+func seedGenerated(ctx context.Context, repo *Repository, fakes []gen.Client) error {
+	return repo.inTx(ctx, func(q querier) error {
+		for _, fake := range fakes {
+			if _, err := repo.insert(ctx, q, Client{FIO: fake.FIO, Login: fake.Login, Birthday: fake.Birthday, Email: fake.Email}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// seedFixtures загружает файлы фикстур по очереди: CSV — через Import,
+// SQL — выполнением файла целиком в транзакции. SQL-фикстуры записывают
+// данные как есть, без проверки и шифрования полей.
+func seedFixtures(ctx context.Context, repo *Repository, db *sql.DB, files []string) error {
+	for _, name := range files {
+		var err error
+		switch ext := strings.ToLower(filepath.Ext(name)); ext {
+		case ".csv":
+			err = seedCSV(ctx, repo, name)
+		case ".sql":
+			err = seedSQL(ctx, db, name)
+		default:
+			err = fmt.Errorf("%w: unsupported fixture format %q, want .csv or .sql", ErrValidation, ext)
+		}
+		if err != nil {
+			return fmt.Errorf("fixture %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func seedCSV(ctx context.Context, repo *Repository, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = repo.Import(ctx, f)
+
+	return err
+}
+
+func seedSQL(ctx context.Context, db *sql.DB, name string) error {
+	query, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(query)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func countClients(ctx context.Context, db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM clients").Scan(&n)
+
+	return n, err
+}
@@ -0,0 +1,111 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что seed отказывается работать с БД, не помеченной явно
+// как непроизводственная, и ничего в неё не записывает
+func Test_Clientctl_SeedRequiresNonProduction(t *testing.T) {
+	clientctl, db := newFileClientctlTest(t)
+	_, err := clientctl("migrate", "up")
+	require.NoError(t, err)
+
+	out, err := clientctl("env")
+	require.NoError(t, err, out)
+	assert.Equal(t, "unmarked\n", out)
+
+	_, err = clientctl("seed", "--generate", "5")
+	require.ErrorIs(t, err, ErrProductionDatabase)
+
+	_, err = clientctl("env", "set", "prod")
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = clientctl("env", "set", "production")
+	require.NoError(t, err)
+	_, err = clientctl("seed", "testdata/golden/clients.csv")
+	require.ErrorIs(t, err, ErrProductionDatabase)
+
+	assertRowCount(t, db, "clients", 0, "1 = 1")
+
+	_, err = clientctl("env", "set", "staging")
+	require.NoError(t, err)
+	out, err = clientctl("env")
+	require.NoError(t, err, out)
+	assert.Equal(t, "staging\n", out)
+	_, err = clientctl("seed", "--generate", "5")
+	require.NoError(t, err)
+}
+
+// Тест проверяет загрузку фикстур CSV и SQL и отказ от неподдерживаемого
+// формата
+func Test_Clientctl_SeedFixtures(t *testing.T) {
+	clientctl, db := newFileClientctlTest(t)
+	_, err := clientctl("migrate", "up")
+	require.NoError(t, err)
+
+	_, err = clientctl("env", "set", "test")
+	require.NoError(t, err)
+
+	out, err := clientctl("seed", "testdata/golden.sql", "testdata/golden/clients.csv")
+	require.NoError(t, err, out)
+	assert.Equal(t, "seeded 20 clients\n", out)
+	assertRowCount(t, db, "products", 3, "1 = 1")
+	assertRowCount(t, db, "clients", 2, "login = ?", "danila95")
+
+	unsupported := filepath.Join(t.TempDir(), "clients.json")
+	require.NoError(t, os.WriteFile(unsupported, []byte("[]"), 0o600))
+	_, err = clientctl("seed", unsupported)
+	require.ErrorIs(t, err, ErrValidation)
+
+	// Фикстура с некорректной строкой не загружается целиком
+	broken := filepath.Join(t.TempDir(), "broken.csv")
+	require.NoError(t, os.WriteFile(broken, []byte("id,fio,login,birthday,email\n0,Ok,ok,19900101,ok@mail.com\n0,Bad,bad,1990,bad\n"), 0o600))
+	_, err = clientctl("seed", broken)
+	require.ErrorIs(t, err, ErrValidation)
+	assertRowCount(t, db, "clients", 20, "1 = 1")
+}
+
+// Тест проверяет генерацию клиентов: число, воспроизводимость по зерну и
+// проверку аргументов
+func Test_Clientctl_SeedGenerated(t *testing.T) {
+	clientctl, db := newFileClientctlTest(t)
+	_, err := clientctl("migrate", "up")
+	require.NoError(t, err)
+
+	_, err = clientctl("env", "set", "development")
+	require.NoError(t, err)
+
+	out, err := clientctl("seed", "--generate", "25", "--seed", "7")
+	require.NoError(t, err, out)
+	assert.Equal(t, "seeded 25 clients\n", out)
+
+	var want []string
+	for _, fake := range gen.New(7).Clients(25) {
+		want = append(want, fake.Login)
+	}
+	logins, err := queryStrings(context.Background(), db, "SELECT login FROM clients ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, want, logins, "generated clients should be reproducible by seed")
+
+	for name, args := range map[string][]string{
+		"NoSource":    {"seed"},
+		"BothModes":   {"seed", "testdata/golden/clients.csv", "--generate", "5"},
+		"NegativeN":   {"seed", "--generate", "-5"},
+		"MissingFile": {"seed", filepath.Join(t.TempDir(), "missing.csv")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := clientctl(args...)
+			require.Error(t, err)
+		})
+	}
+	assertRowCount(t, db, "clients", 25, "1 = 1")
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// statsReport — вывод команды stats.
+type statsReport struct {
+	DBStats
+	ClientsPerMonth []MonthlyCount   `json:"clients_per_month"`
+	Duplicates      []DuplicateGroup `json:"duplicates"`
+}
+
+func (c *clientctl) statsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show row counts, clients added per month, duplicate candidates and database size",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				var (
+					report statsReport
+					err    error
+				)
+				if report.DBStats, err = repo.Stats(ctx); err != nil {
+					return err
+				}
+				if report.ClientsPerMonth, err = repo.ClientsAddedPerMonth(ctx); err != nil {
+					return err
+				}
+				if report.Duplicates, err = repo.DuplicateCandidates(ctx); err != nil {
+					return err
+				}
+
+				if c.output != outputTable {
+					// Пустые списки выводятся как [], а не null
+					if report.ClientsPerMonth == nil {
+						report.ClientsPerMonth = []MonthlyCount{}
+					}
+					if report.Duplicates == nil {
+						report.Duplicates = []DuplicateGroup{}
+					}
+					return c.encode(cmd.OutOrStdout(), report)
+				}
+				return printStatsReport(cmd.OutOrStdout(), report)
+			})
+		},
+	}
+	cmd.AddCommand(c.statsCreatedCmd())
+
+	return cmd
+}
+
+func (c *clientctl) statsCreatedCmd() *cobra.Command {
+	var period, from, to string
+	cmd := &cobra.Command{
+		Use:   "created --from DATE [--to DATE] [--period day|week|month]",
+		Short: "Show the number of clients created per day, week or month",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			p, err := ParseReportPeriod(period)
+			if err != nil {
+				return err
+			}
+			start, err := parseReportDate("from", from)
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				// Без --to отчёт строится по текущий момент
+				end := repo.now()
+				if to != "" {
+					if end, err = parseReportDate("to", to); err != nil {
+						return err
+					}
+				}
+
+				counts, err := repo.ClientsCreated(ctx, p, start, end)
+				if err != nil {
+					return err
+				}
+
+				if c.output != outputTable {
+					return c.encode(cmd.OutOrStdout(), counts)
+				}
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, strings.ToUpper(string(p))+"\tCLIENTS CREATED")
+				for _, pc := range counts {
+					fmt.Fprintf(tw, "%s\t%d\n", pc.Start, pc.Count)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	cmd.Flags().StringVar(&period, "period", string(PeriodMonth), "report period: day, week or month")
+	cmd.Flags().StringVar(&from, "from", "", "first day of the report, YYYY-MM-DD")
+	cmd.Flags().StringVar(&to, "to", "", "day after the last day of the report, YYYY-MM-DD (default now)")
+
+	return cmd
+}
+
+// printStatsReport выводит отчёт таблицами, разделёнными пустой строкой.
+func printStatsReport(w io.Writer, report statsReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	tables := make([]string, 0, len(report.TableRows))
+	for table := range report.TableRows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	fmt.Fprintln(tw, "TABLE\tROWS")
+	for _, table := range tables {
+		fmt.Fprintf(tw, "%s\t%d\n", table, report.TableRows[table])
+	}
+
+	fmt.Fprintln(tw, "\nMONTH\tCLIENTS ADDED")
+	for _, m := range report.ClientsPerMonth {
+		fmt.Fprintf(tw, "%s\t%d\n", m.Month, m.Count)
+	}
+
+	fmt.Fprintln(tw, "\nDUPLICATE BY\tVALUE\tCLIENTS")
+	for _, g := range report.Duplicates {
+		ids := make([]string, len(g.ClientIDs))
+		for i, id := range g.ClientIDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", g.Reason, g.Key, strings.Join(ids, ","))
+	}
+
+	fmt.Fprintln(tw, "\nFILE\tBYTES")
+	fmt.Fprintf(tw, "database\t%d\n", report.FileSize)
+	fmt.Fprintf(tw, "wal\t%d\n", report.WALSize)
+
+	return tw.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет вывод clientctl stats таблицей и в JSON
+func Test_Clientctl_Stats(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	ctx := context.Background()
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC))
+	clockRepo := NewRepository(repo.db, WithClock(clock))
+	for _, email := range []string{"ivanov@mail.com", "IVANOV@mail.com", "petrov@corp.ru"} {
+		_, err := clockRepo.Insert(ctx, newTestClient(func(cl *Client) { cl.Email = email }))
+		require.NoError(t, err)
+		clock.Set(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	}
+
+	out, err := clientctl("stats", "-o", "json")
+	require.NoError(t, err, out)
+	var report struct {
+		TableRows       map[string]int64 `json:"table_rows"`
+		FileSize        int64            `json:"file_size"`
+		ClientsPerMonth []MonthlyCount   `json:"clients_per_month"`
+		Duplicates      []DuplicateGroup `json:"duplicates"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.EqualValues(t, 3, report.TableRows["clients"])
+	assert.Positive(t, report.FileSize)
+	assert.Equal(t, []MonthlyCount{{"2025-01", 1}, {"2025-02", 2}}, report.ClientsPerMonth)
+	assert.Equal(t, []DuplicateGroup{
+		{Reason: DuplicateEmail, Key: "ivanov@mail.com", ClientIDs: []int{1, 2}},
+		{Reason: DuplicateFIOBirthday, Key: "test 19700101", ClientIDs: []int{1, 2, 3}},
+	}, report.Duplicates)
+
+	out, err = clientctl("stats")
+	require.NoError(t, err, out)
+	sections := strings.Split(strings.TrimSpace(out), "\n\n")
+	require.Len(t, sections, 4, "tables, months, duplicates and files")
+	rows := make(map[string]string)
+	for _, line := range strings.Split(sections[0], "\n")[1:] {
+		fields := strings.Fields(line)
+		rows[fields[0]] = fields[1]
+	}
+	assert.Equal(t, "3", rows["clients"])
+	assert.Equal(t, "MONTH    CLIENTS ADDED\n2025-01  1\n2025-02  2", sections[1])
+	assert.Equal(t, []string{
+		"DUPLICATE BY  VALUE            CLIENTS",
+		"email         ivanov@mail.com  1,2",
+		"fio_birthday  test 19700101    1,2,3",
+	}, strings.Split(sections[2], "\n"))
+	assert.True(t, strings.HasPrefix(sections[3], "FILE      BYTES\ndatabase  "), sections[3])
+}
+
+// Тест проверяет, что на пустой БД списки в JSON выводятся пустыми массивами
+func Test_Clientctl_StatsEmpty(t *testing.T) {
+	clientctl, _ := newClientctlTest(t)
+
+	out, err := clientctl("stats", "-o", "json")
+	require.NoError(t, err, out)
+	var report map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.JSONEq(t, "[]", string(report["clients_per_month"]))
+	assert.JSONEq(t, "[]", string(report["duplicates"]))
+}
+
+// Тест проверяет вывод clientctl stats created по неделям таблицей и по
+// месяцам в JSON и отказ от некорректных параметров
+func Test_Clientctl_StatsCreated(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	ctx := context.Background()
+	clock := testutil.NewFakeClock(time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC))
+	clockRepo := NewRepository(repo.db, WithClock(clock))
+	for _, next := range []time.Time{time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)} {
+		_, err := clockRepo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		clock.Set(next)
+	}
+
+	out, err := clientctl("stats", "created", "--period", "week", "--from", "2024-12-30", "--to", "2025-01-20")
+	require.NoError(t, err, out)
+	assert.Equal(t, "WEEK        CLIENTS CREATED\n2024-12-30  1\n2025-01-06  1\n2025-01-13  0\n", out)
+
+	out, err = clientctl("stats", "created", "--from", "2024-12-01", "--to", "2025-02-01", "-o", "json")
+	require.NoError(t, err, out)
+	assert.JSONEq(t, `[{"start": "2024-12-01", "count": 1}, {"start": "2025-01-01", "count": 1}]`, out)
+
+	for _, args := range [][]string{
+		{"stats", "created"},
+		{"stats", "created", "--from", "01.12.2024"},
+		{"stats", "created", "--from", "2024-12-01", "--period", "quarter"},
+		{"stats", "created", "--from", "2025-01-01", "--to", "2024-01-01"},
+	} {
+		_, err := clientctl(args...)
+		assert.ErrorIs(t, err, ErrValidation, "%v", args)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClientctlTest возвращает функцию, выполняющую команду clientctl над
+// БД в памяти, и репозиторий над той же БД. БД общая для всех вызовов и
+// не закрывается командой.
+func newClientctlTest(t *testing.T) (func(args ...string) (string, error), *Repository) {
+	t.Helper()
+
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+	repo := NewRepository(db)
+
+	exec := func(args ...string) (string, error) {
+		return execClientctl(db, strings.NewReader(""), args...)
+	}
+
+	return exec, repo
+}
+
+// execClientctl выполняет команду clientctl над открытой БД db, передавая
+// ей stdin, и возвращает её вывод.
+func execClientctl(db *sql.DB, stdin io.Reader, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := execClientctlTo(db, stdin, &out, &out, args...)
+
+	return out.String(), err
+}
+
+// execClientctlSplit выполняет команду как execClientctl, но возвращает
+// stdout и stderr отдельно.
+func execClientctlSplit(db *sql.DB, stdin io.Reader, args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	err := execClientctlTo(db, stdin, &stdout, &stderr, args...)
+
+	return stdout.String(), stderr.String(), err
+}
+
+func execClientctlTo(db *sql.DB, stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+	cmd := newClientctlCmd(func(DBConfig) (*sql.DB, func() error, error) {
+		return db, func() error { return nil }, nil
+	})
+	cmd.SetArgs(args)
+	cmd.SetIn(stdin)
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+
+	return cmd.ExecuteContext(context.Background())
+}
+
+// Тест проверяет полный цикл create, get, update, list и delete с выводом
+// в JSON
+func Test_Clientctl_CRUD(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	out, err := clientctl("create", "-o", "json", "--fio", "Иванов Иван", "--login", "ivanov", "--birthday", "19850615", "--email", "ivanov@mail.com")
+	require.NoError(t, err, out)
+	var created Client
+	require.NoError(t, json.Unmarshal([]byte(out), &created))
+	require.NotZero(t, created.ID)
+	assert.Equal(t, "Иванов Иван", created.FIO)
+	assert.Equal(t, StatusActive, created.Status)
+
+	id := created.ID
+	out, err = clientctl("update", strconv.Itoa(id), "--email", "ivan@corp.ru", "--output", "json")
+	require.NoError(t, err, out)
+	stored, err := repo.Select(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "ivan@corp.ru", stored.Email)
+	assert.Equal(t, "ivanov", stored.Login, "fields without flags should stay unchanged")
+
+	out, err = clientctl("get", strconv.Itoa(id), "-o", "json")
+	require.NoError(t, err, out)
+	var got Client
+	require.NoError(t, json.Unmarshal([]byte(out), &got))
+	assertClientEqual(t, stored, got)
+
+	out, err = clientctl("list", "-o", "json")
+	require.NoError(t, err, out)
+	var listed []Client
+	require.NoError(t, json.Unmarshal([]byte(out), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, id, listed[0].ID)
+
+	out, err = clientctl("delete", strconv.Itoa(id))
+	require.NoError(t, err, out)
+	assert.Empty(t, out)
+	_, err = repo.Select(context.Background(), id)
+	require.ErrorIs(t, err, ErrClientNotFound)
+
+	out, err = clientctl("list", "-o", "json")
+	require.NoError(t, err, out)
+	assert.JSONEq(t, "[]", out, "empty list should be an empty JSON array")
+}
+
+// Тест проверяет табличный вывод и отбор списка по меткам
+func Test_Clientctl_TableAndTags(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	ctx := context.Background()
+	vip, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Login = "vip" }))
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Login = "regular" }))
+	require.NoError(t, err)
+	require.NoError(t, repo.TagClient(ctx, vip, "vip"))
+
+	out, err := clientctl("list")
+	require.NoError(t, err, out)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3, "header and two clients")
+	assert.Equal(t, []string{"ID", "FIO", "LOGIN", "BIRTHDAY", "EMAIL", "STATUS"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{strconv.Itoa(vip), "Test", "vip", "19700101", "mail@mail.com", "active"}, strings.Fields(lines[1]))
+
+	out, err = clientctl("list", "--tag", "vip")
+	require.NoError(t, err, out)
+	lines = strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], "vip")
+}
+
+// Тест проверяет отбор, подсчёт и удаление клиентов по условию --where
+func Test_Clientctl_Where(t *testing.T) {
+	clientctl, repo := newClientctlTest(t)
+
+	ctx := context.Background()
+	vip, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Login = "vip" }))
+	require.NoError(t, err)
+	regular, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Login = "regular" }))
+	require.NoError(t, err)
+	require.NoError(t, repo.TagClient(ctx, vip, "vip"))
+
+	out, err := clientctl("list", "--where", `{"not":{"tags":["vip"]}}`, "-o", "json")
+	require.NoError(t, err, out)
+	var clients []Client
+	require.NoError(t, json.Unmarshal([]byte(out), &clients))
+	require.Len(t, clients, 1)
+	assert.Equal(t, regular, clients[0].ID)
+
+	out, err = clientctl("list", "--count", "--where", `{"statuses":["active"]}`)
+	require.NoError(t, err, out)
+	assert.Equal(t, "2\n", out)
+	out, err = clientctl("list", "--count", "--tag", "vip", "-o", "json")
+	require.NoError(t, err, out)
+	assert.JSONEq(t, `{"count": 1}`, out)
+
+	out, err = clientctl("delete", "--where", `{"tags":["vip"]}`, "--dry-run")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "would delete clients "+strconv.Itoa(vip))
+	out, err = clientctl("delete", "--where", `{"statuses":["active"]}`, "--max-rows", "1")
+	require.ErrorIs(t, err, ErrTooManyClients, out)
+	out, err = clientctl("delete", "--where", `{"tags":["vip"]}`, "--max-rows", "1")
+	require.NoError(t, err, out)
+	assertRowCount(t, repo.db, "clients", 1, "1")
+
+	for _, args := range [][]string{
+		{"delete"},
+		{"delete", "1", "--where", `{"tags":["vip"]}`},
+		{"delete", "--where", `{}`},
+		{"delete", "1", "--max-rows", "1"},
+		{"delete", "--where", `{"tags":["vip"]}`, "--max-rows", "-1"},
+		{"list", "--where", `{"status":"active"}`},
+		{"list", "--tag", "vip", "--where", `{"tags":["vip"]}`},
+		{"export", "--where", `{"statuses":["deleted"]}`},
+	} {
+		out, err := clientctl(args...)
+		require.ErrorIs(t, err, ErrValidation, "%v: %s", args, out)
+	}
+}
+
+// Тест проверяет ошибки команд: разбор аргументов, проверку данных и
+// отсутствующего клиента
+func Test_Clientctl_Errors(t *testing.T) {
+	clientctl, _ := newClientctlTest(t)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{name: "NotFound", args: []string{"get", "1000"}, wantErr: ErrClientNotFound},
+		{name: "DeleteNotFound", args: []string{"delete", "1000"}, wantErr: ErrClientNotFound},
+		{name: "InvalidID", args: []string{"get", "abc"}, wantErr: ErrValidation},
+		{name: "InvalidClient", args: []string{"create", "--login", "x"}, wantErr: ErrValidation},
+		{name: "MissingID", args: []string{"get"}},
+		{name: "UnknownOutput", args: []string{"list", "-o", "xml"}},
+		{name: "UnknownCommand", args: []string{"drop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := clientctl(tt.args...)
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
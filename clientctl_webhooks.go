@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func (c *clientctl) webhooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Inspect failed webhook deliveries and schedule redelivery",
+	}
+	cmd.AddCommand(c.webhookFailuresCmd(), c.webhookAttemptsCmd(), c.webhookRedeliverCmd())
+
+	return cmd
+}
+
+func (c *clientctl) webhookFailuresCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "failures",
+		Short: "List dead deliveries and deliveries waiting for a retry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				failures, err := repo.WebhookFailures(ctx)
+				if err != nil {
+					return err
+				}
+
+				if c.output != outputTable {
+					if failures == nil {
+						failures = []WebhookDelivery{}
+					}
+					return c.encode(cmd.OutOrStdout(), failures)
+				}
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, "ID\tENDPOINT\tEVENT\tTYPE\tSTATUS\tATTEMPTS\tLAST ERROR")
+				for _, d := range failures {
+					fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\t%d\t%s\n", d.ID, d.EndpointID, d.EventID, d.EventType, d.Status, d.Attempts, d.LastError)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+}
+
+func (c *clientctl) webhookAttemptsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "attempts DELIVERY_ID",
+		Short: "Show the delivery attempt log of a webhook delivery",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseDeliveryIDs(args)
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				attempts, err := repo.WebhookAttempts(ctx, ids[0])
+				if err != nil {
+					return err
+				}
+
+				if c.output != outputTable {
+					if attempts == nil {
+						attempts = []WebhookAttempt{}
+					}
+					return c.encode(cmd.OutOrStdout(), attempts)
+				}
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, "ID\tATTEMPTED AT\tSUCCEEDED\tCODE\tLATENCY\tERROR")
+				for _, a := range attempts {
+					fmt.Fprintf(tw, "%d\t%s\t%t\t%d\t%s\t%s\n", a.ID, formatTime(a.AttemptedAt), a.Succeeded, a.StatusCode, a.Latency, a.Error)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+}
+
+func (c *clientctl) webhookRedeliverCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "redeliver DELIVERY_ID...",
+		Short: "Schedule webhook deliveries for immediate redelivery by the dispatcher",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseDeliveryIDs(args)
+			if err != nil {
+				return err
+			}
+
+			return c.withRepo(cmd, func(ctx context.Context, repo *Repository) error {
+				if err := repo.RedeliverWebhooks(ctx, ids...); err != nil {
+					return err
+				}
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "scheduled %d deliveries\n", len(ids))
+				return err
+			})
+		},
+	}
+}
+
+// parseDeliveryIDs разбирает ID доставок вебхуков из аргументов команды.
+func parseDeliveryIDs(args []string) ([]int64, error) {
+	ids := make([]int64, len(args))
+	for i, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: delivery ID must be a positive integer, got %q", ErrValidation, arg)
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
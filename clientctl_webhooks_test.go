@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет вывод неудавшихся доставок и журнала попыток и повторную
+// доставку командой clientctl webhooks
+func Test_Clientctl_Webhooks(t *testing.T) {
+	repo, dispatcher, rcv, dead, recoverReceiver := setupDeadWebhook(t)
+	clientctl := func(args ...string) (string, error) {
+		return execClientctl(repo.db, strings.NewReader(""), args...)
+	}
+	id := strconv.FormatInt(dead.ID, 10)
+
+	out, err := clientctl("webhooks", "failures")
+	require.NoError(t, err, out)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"ID", "ENDPOINT", "EVENT", "TYPE", "STATUS", "ATTEMPTS", "LAST", "ERROR"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{id, "1", strconv.FormatInt(dead.EventID, 10), WebhookClientCreated, WebhookDead, "2"}, strings.Fields(lines[1])[:6])
+
+	out, err = clientctl("webhooks", "attempts", id, "-o", "json")
+	require.NoError(t, err, out)
+	var attempts []WebhookAttempt
+	require.NoError(t, json.Unmarshal([]byte(out), &attempts))
+	assert.Len(t, attempts, 2)
+
+	out, err = clientctl("webhooks", "redeliver", "0")
+	require.ErrorIs(t, err, ErrValidation, out)
+	out, err = clientctl("webhooks", "redeliver", id, "1000")
+	require.ErrorIs(t, err, ErrWebhookDeliveryNotFound, out)
+
+	recoverReceiver()
+	out, err = clientctl("webhooks", "redeliver", id)
+	require.NoError(t, err, out)
+	assert.Equal(t, "scheduled 1 deliveries\n", out)
+	// clientctl работает по системным часам, а доставка — по тестовым,
+	// поэтому время попытки сдвигается в прошлое
+	_, err = repo.db.Exec("UPDATE webhook_deliveries SET next_attempt_at = '2000-01-01T00:00:00Z'")
+	require.NoError(t, err)
+	_, err = dispatcher.Deliver(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, rcv.payloads(), 1)
+
+	out, err = clientctl("webhooks", "failures", "-o", "json")
+	require.NoError(t, err, out)
+	assert.JSONEq(t, "[]", out)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/clientsdb"
+)
+
+// Основные операции с клиентами (ClientRepository) выполняются запросами
+// пакета clientsdb, сгенерированного sqlc по clientsdb/queries.sql и
+// clientsdb/schema.sql (см. sqlc.yaml): расхождение столбцов запросов и
+// полей структур обнаруживается при генерации и компиляции. После
+// изменения запросов или миграций выполните sqlc generate.
+
+// clientsdbParams — имена параметров запросов clientsdb в порядке их
+// следования в запросе. sqlc передаёт параметры SQLite позиционно, а
+// наблюдатели за запросами различают параметры по именам: журнал
+// запросов выводит значения только разрешённых параметров (например, id).
+var clientsdbParams = map[string][]string{
+	"GetClient":         {"id"},
+	"GetClientByOwner":  {"id", "owner_id"},
+	"InsertClient":      {"id", "fio", "login", "birthday", "email", "owner_id", "marketing_consent", "consent_updated_at", "valid_from", "created_at"},
+	"UpdateClient":      {"fio", "login", "birthday", "email", "valid_from", "id"},
+	"DeleteClient":      {"id"},
+	"CountClientOrders": {"client_id"},
+}
+
+// sqlcQuery готовит запрос clientsdb для наблюдателей: убирает строку
+// «-- name: ...», которую sqlc добавляет в начало запроса, и называет
+// позиционные параметры по clientsdbParams. Прочие запросы возвращаются
+// без изменений.
+func sqlcQuery(query string, args []any) (string, []any) {
+	header, body, ok := strings.Cut(query, "\n")
+	name, found := strings.CutPrefix(header, "-- name: ")
+	if !ok || !found {
+		return query, args
+	}
+	name, _, _ = strings.Cut(name, " ")
+	body = strings.TrimSpace(body)
+
+	names := clientsdbParams[name]
+	if len(names) != len(args) {
+		return body, args
+	}
+	named := make([]any, len(args))
+	for i, arg := range args {
+		// sql.Null* выводятся значением, как при передаче через sql.Named
+		if v, ok := arg.(driver.Valuer); ok {
+			if value, err := v.Value(); err == nil {
+				arg = value
+			}
+		}
+		named[i] = sql.Named(names[i], arg)
+	}
+
+	return body, named
+}
+
+// clientFromRow преобразует строку запроса clientsdb так же, как scanClient.
+func clientFromRow(row clientsdb.GetClientRow) (Client, error) {
+	consentUpdatedAt, err := parseTime(row.ConsentUpdatedAt)
+	if err != nil {
+		return Client{}, err
+	}
+
+	return Client{
+		ID:               int(row.ID),
+		FIO:              row.Fio,
+		Login:            row.Login,
+		Birthday:         row.Birthday,
+		Email:            row.Email,
+		OwnerID:          row.OwnerID,
+		MarketingConsent: row.MarketingConsent != 0,
+		ConsentUpdatedAt: consentUpdatedAt,
+		Status:           ClientStatus(row.Status),
+	}, nil
+}
+
+// boolToInt приводит флаг к формату хранения в БД (0 или 1).
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// parseTime разбирает время в формате хранения в БД (см. formatTime).
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
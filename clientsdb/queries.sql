@@ -0,0 +1,28 @@
+-- Запросы основных операций с клиентами (ClientRepository). Go-код в
+-- queries.sql.go генерируется по ним командой sqlc generate.
+
+-- name: GetClient :one
+SELECT id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status
+FROM clients
+WHERE id = sqlc.arg(id) AND deleted_at = '';
+
+-- name: GetClientByOwner :one
+SELECT id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status
+FROM clients
+WHERE id = sqlc.arg(id) AND deleted_at = '' AND owner_id = sqlc.arg(owner_id);
+
+-- name: InsertClient :execresult
+INSERT INTO clients (id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, valid_from, created_at)
+VALUES (sqlc.narg(id), sqlc.arg(fio), sqlc.arg(login), sqlc.arg(birthday), sqlc.arg(email), sqlc.arg(owner_id),
+	sqlc.arg(marketing_consent), sqlc.arg(consent_updated_at), sqlc.arg(valid_from), sqlc.arg(created_at));
+
+-- name: UpdateClient :exec
+UPDATE clients
+SET fio = sqlc.arg(fio), login = sqlc.arg(login), birthday = sqlc.arg(birthday), email = sqlc.arg(email), valid_from = sqlc.arg(valid_from), version = version + 1
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteClient :execresult
+DELETE FROM clients WHERE id = sqlc.arg(id);
+
+-- name: CountClientOrders :one
+SELECT COUNT(*) FROM orders WHERE client_id = sqlc.arg(client_id);
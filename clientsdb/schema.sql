@@ -0,0 +1,30 @@
+-- Схема таблиц, к которым обращаются запросы queries.sql, в том виде, к
+-- которому её приводят миграции (migrate.go). sqlc проверяет по ней
+-- запросы и типы; совпадение с миграциями проверяет Test_ClientsDB_Schema.
+
+CREATE TABLE clients (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	fio VARCHAR(128) NOT NULL DEFAULT '',
+	login VARCHAR(32) NOT NULL DEFAULT '',
+	birthday CHAR(8) NOT NULL DEFAULT '',
+	email VARCHAR(64) NOT NULL DEFAULT '',
+	owner_id TEXT NOT NULL DEFAULT '',
+	marketing_consent INTEGER NOT NULL DEFAULT 0,
+	consent_updated_at TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'active',
+	valid_from TEXT NOT NULL DEFAULT '',
+	deleted_at TEXT NOT NULL DEFAULT '',
+	merged_into INTEGER NOT NULL DEFAULT 0,
+	preferences TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(preferences)),
+	legal_hold INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL REFERENCES clients (id) ON DELETE RESTRICT,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	status TEXT NOT NULL DEFAULT 'new',
+	created_at TEXT NOT NULL
+);
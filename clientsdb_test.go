@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что схема, по которой sqlc генерирует clientsdb,
+// совпадает со схемой, которую дают миграции
+func Test_ClientsDB_Schema(t *testing.T) {
+	ctx := context.Background()
+	migrated := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, migrated))
+
+	schema, err := os.ReadFile("clientsdb/schema.sql")
+	require.NoError(t, err)
+	generated := openMemoryDB(t)
+	_, err = generated.ExecContext(ctx, string(schema))
+	require.NoError(t, err)
+
+	for _, table := range []string{"clients", "orders"} {
+		want, err := inspectColumns(ctx, migrated, table)
+		require.NoError(t, err)
+		require.NotEmpty(t, want, table)
+		// миграции задают пустую строку как "", а sqlc понимает только ''
+		for name, def := range want {
+			want[name] = strings.ReplaceAll(def, `DEFAULT ""`, "DEFAULT ''")
+		}
+
+		got, err := inspectColumns(ctx, generated, table)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "clientsdb/schema.sql differs from migrations for %s", table)
+	}
+}
+
+// Тест проверяет, что имена параметров для наблюдателей за запросами
+// совпадают с параметрами запросов clientsdb/queries.sql
+func Test_ClientsDB_Params(t *testing.T) {
+	data, err := os.ReadFile("clientsdb/queries.sql")
+	require.NoError(t, err)
+
+	nameRe := regexp.MustCompile(`(?m)^-- name: (\w+) :\w+$`)
+	argRe := regexp.MustCompile(`sqlc\.n?arg\((\w+)\)`)
+	blocks := nameRe.FindAllStringSubmatchIndex(string(data), -1)
+	params := make(map[string][]string)
+	for i, b := range blocks {
+		end := len(data)
+		if i+1 < len(blocks) {
+			end = blocks[i+1][0]
+		}
+		name := string(data[b[2]:b[3]])
+		params[name] = []string{}
+		for _, m := range argRe.FindAllStringSubmatch(string(data[b[1]:end]), -1) {
+			params[name] = append(params[name], m[1])
+		}
+	}
+	assert.Equal(t, params, clientsdbParams)
+
+	query, args := sqlcQuery("-- name: GetClientByOwner :one\nSELECT 1 WHERE id = ? AND owner_id = ?\n", []any{int64(7), "alice"})
+	assert.Equal(t, "SELECT 1 WHERE id = ? AND owner_id = ?", query)
+	assert.Equal(t, []any{sql.Named("id", int64(7)), sql.Named("owner_id", "alice")}, args)
+
+	query, args = sqlcQuery("SELECT 1 WHERE id = :id", []any{sql.Named("id", 7)})
+	assert.Equal(t, "SELECT 1 WHERE id = :id", query)
+	assert.Equal(t, []any{sql.Named("id", 7)}, args)
+}
+
+// legacySelectClient — чтение клиента рукописным запросом, которым
+// репозиторий пользовался до перехода на clientsdb.
+func legacySelectClient(ctx context.Context, r *Repository, db *sql.DB, id int) (Client, error) {
+	scope, args := r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	cl, err := scanClient(db.QueryRowContext(ctx, "SELECT "+clientColumns+" FROM clients WHERE id = :id"+notDeleted+scope, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Client{}, ErrClientNotFound
+	}
+	if err != nil {
+		return Client{}, err
+	}
+
+	return r.decrypt(cl)
+}
+
+// Тест проверяет, что чтение клиента запросами clientsdb возвращает то же,
+// что и прежний рукописный запрос, с учётом владельцев, мягкого удаления,
+// согласия, статуса и шифрования
+func Test_ClientsDB_Equivalence(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	encryption := WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1}))
+	repo := NewRepository(db, encryption)
+	restricted := NewRepository(db, encryption, WithOwnerRestriction())
+
+	var ids []int
+	for _, cl := range []Client{
+		newTestClient(),
+		newTestClient(func(cl *Client) { cl.Email = "consent@mail.com"; cl.MarketingConsent = true }),
+		newTestClient(func(cl *Client) { cl.Email = "alice@mail.com"; cl.OwnerID = "alice" }),
+		newTestClient(func(cl *Client) { cl.Email = "bob@mail.com"; cl.OwnerID = "bob" }),
+		newTestClient(func(cl *Client) { cl.Email = "blocked@mail.com" }),
+		newTestClient(func(cl *Client) { cl.Email = "merged@mail.com" }),
+	} {
+		id, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	require.NoError(t, repo.ChangeStatus(ctx, ids[4], StatusBlocked))
+	require.NoError(t, repo.MergeClients(ctx, ids[0], ids[5]))
+	ids = append(ids, 999)
+
+	var consent int
+	require.NoError(t, db.QueryRow("SELECT marketing_consent FROM clients WHERE id = :id AND typeof(marketing_consent) = 'integer'",
+		sql.Named("id", ids[1])).Scan(&consent))
+	assert.Equal(t, 1, consent)
+
+	tests := []struct {
+		name string
+		repo *Repository
+		ctx  context.Context
+	}{
+		{"Unrestricted", repo, ctx},
+		{"NoPrincipal", restricted, ctx},
+		{"EmptyPrincipal", restricted, WithPrincipal(ctx, Principal{})},
+		{"Admin", restricted, WithPrincipal(ctx, Principal{ID: "root", Admin: true})},
+		{"Alice", restricted, WithPrincipal(ctx, Principal{ID: "alice"})},
+		{"Bob", restricted, WithPrincipal(ctx, Principal{ID: "bob"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, id := range ids {
+				want, wantErr := legacySelectClient(tt.ctx, tt.repo, db, id)
+				got, err := tt.repo.Select(tt.ctx, id)
+				assert.Equal(t, want, got, "client %d", id)
+				assert.Equal(t, wantErr, errors.Unwrap(err), "client %d", id)
+			}
+		})
+	}
+}
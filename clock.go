@@ -0,0 +1,28 @@
+package main
+
+import "time"
+
+// Clock — источник текущего времени для меток времени, которые репозиторий
+// записывает в БД (журнал аудита, согласие на рассылку, квитанции об
+// удалении) и по которым проверяет дату рождения. Подмена часов делает
+// такие значения детерминированными в тестах.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock — часы по умолчанию, возвращающие системное время.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// WithClock задаёт часы репозитория вместо системных.
+func WithClock(c Clock) Option {
+	return func(r *Repository) {
+		r.clock = c
+	}
+}
+
+// now возвращает текущее время часов репозитория в UTC.
+func (r *Repository) now() time.Time {
+	return r.clock.Now().UTC()
+}
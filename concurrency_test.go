@@ -0,0 +1,202 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// openContendedDB создаёт отдельную БД, соединения с которой не ждут
+// освобождения блокировок: занятость сразу возвращается как SQLITE_BUSY
+func openContendedDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "concurrency.db")+"?_pragma=busy_timeout(0)")
+	require.NoError(t, err, "database connection error: %v", err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Migrate(context.Background(), db), "migration error")
+
+	return db
+}
+
+// Тест нагружает репозиторий из многих горутин смешанными чтениями и
+// записями по пересекающимся ID. Запускайте с -race; в режиме -short
+// тест пропускается.
+func Test_Repository_ConcurrentMixedLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("concurrency test skipped in -short mode")
+	}
+
+	const (
+		workers = 16
+		ops     = 40
+		shared  = 4
+	)
+
+	repo := NewRepository(openContendedDB(t), WithBusyRetry(100, time.Millisecond))
+	ctx := context.Background()
+
+	ids := make([]int, shared)
+	for i, cl := range fakeClients(1, shared) {
+		id, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+		ids[i] = id
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		updates = make(map[int]int, shared)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(int64(w)))
+
+			for i := 0; i < ops; i++ {
+				id := ids[rnd.Intn(shared)]
+				switch rnd.Intn(3) {
+				case 0:
+					_, err := repo.Select(ctx, id)
+					assert.NoError(t, err, "select %d", id)
+				case 1:
+					cl := newTestClient(func(cl *Client) { cl.ID = id; cl.FIO = fmt.Sprintf("worker %d op %d", w, i) })
+					if assert.NoError(t, repo.Update(ctx, cl), "update %d", id) {
+						mu.Lock()
+						updates[id]++
+						mu.Unlock()
+					}
+				case 2:
+					tmp, err := repo.Insert(ctx, newTestClient())
+					if assert.NoError(t, err, "insert") {
+						assert.NoError(t, repo.Delete(ctx, tmp), "delete %d", tmp)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Каждое успешное изменение записано ровно один раз, а итоговое
+	// состояние клиента совпадает с последней записью журнала аудита
+	for _, id := range ids {
+		entries, err := repo.AuditLog(ctx, id)
+		require.NoError(t, err)
+
+		var audited int
+		var last *string
+		for _, e := range entries {
+			if e.Operation == AuditUpdate {
+				audited++
+				if change, ok := e.Diff["fio"]; ok {
+					last = change.After
+				}
+			}
+		}
+		assert.Equal(t, updates[id], audited, "audited updates of client %d", id)
+
+		client, err := repo.Select(ctx, id)
+		require.NoError(t, err)
+		if last != nil {
+			assert.Equal(t, *last, client.FIO, "client %d should hold the last audited FIO", id)
+		}
+	}
+}
+
+// Тест проверяет, что при занятой БД операция повторяется до освобождения
+// блокировки, а после исчерпания попыток возвращается ошибка занятости
+func Test_BusyRetry(t *testing.T) {
+	db := openContendedDB(t)
+	ctx := context.Background()
+
+	lock := func() *sql.Tx {
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		_, err = tx.Exec("INSERT INTO products (product) VALUES ('lock')")
+		require.NoError(t, err)
+		return tx
+	}
+
+	t.Run("GivesUp", func(t *testing.T) {
+		tx := lock()
+		defer tx.Rollback()
+
+		_, err := NewRepository(db, WithBusyRetry(3, time.Millisecond)).Insert(ctx, newTestClient())
+		require.Error(t, err)
+		assert.True(t, isBusy(err), "expected busy error, got %v", err)
+		assert.Equal(t, ClassTimeout, Classify(err))
+	})
+
+	t.Run("SucceedsAfterRelease", func(t *testing.T) {
+		tx := lock()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			tx.Rollback()
+		}()
+
+		_, err := NewRepository(db, WithBusyRetry(100, time.Millisecond)).Insert(ctx, newTestClient())
+		require.NoError(t, err, "insert should succeed once the lock is released")
+	})
+}
+
+// Тест проверяет, что из одновременных изменений по условию с одними и
+// теми же прочитанными версиями применяется ровно одно, а остальные
+// возвращают конфликт версий и не затирают его
+func Test_UpdateClientsWhere_Concurrent(t *testing.T) {
+	const workers = 8
+
+	repo := NewRepository(openContendedDB(t), WithBusyRetry(100, time.Millisecond))
+	ctx := context.Background()
+
+	for _, cl := range fakeClients(1, 5) {
+		cl.FIO = "Иванов " + cl.FIO
+		_, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+	}
+	ivanovs := Filter{FIOPrefix: "Иванов"}
+	versions, err := repo.ClientVersions(ctx, ivanovs)
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		winners   []string
+		conflicts int
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			login := fmt.Sprintf("worker%d", w)
+			_, err := repo.UpdateClientsWhere(ctx, ivanovs, ClientChanges{Login: &login}, versions)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				winners = append(winners, login)
+				return
+			}
+			if assert.ErrorIs(t, err, ErrVersionConflict) {
+				conflicts++
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	require.Len(t, winners, 1, "exactly one concurrent update should win")
+	assert.Equal(t, workers-1, conflicts)
+	assertRowCount(t, repo.db, "clients", 5, "login = ? AND version = 2", winners[0])
+	assertRowCount(t, repo.db, "audit_log", 5, "operation = 'update'")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// RecordConsent записывает согласие (granted = true) или отзыв согласия
+// клиента на маркетинговые коммуникации. Время изменения сохраняется
+// в consent_updated_at, само изменение — в журнале аудита.
+func (r *Repository) RecordConsent(ctx context.Context, id int, granted bool) (err error) {
+	ctx, end := r.startClientOperation(ctx, "record_consent", id)
+	defer end(&err)
+
+	return r.inTx(ctx, func(q querier) error {
+		before, err := r.selectClient(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		changedAt, err := r.recordHistory(ctx, q, id, AuditConsent)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(ctx, "UPDATE clients SET marketing_consent = :marketing_consent, consent_updated_at = :consent_updated_at, valid_from = :valid_from, version = version + 1 WHERE id = :id",
+			sql.Named("marketing_consent", granted),
+			sql.Named("consent_updated_at", changedAt),
+			sql.Named("valid_from", changedAt),
+			sql.Named("id", id))
+		if err != nil {
+			return err
+		}
+
+		was, now := strconv.FormatBool(before.MarketingConsent), strconv.FormatBool(granted)
+
+		return r.auditDiff(ctx, q, AuditConsent, id, map[string]FieldChange{
+			"marketing_consent": {Before: &was, After: &now},
+		})
+	})
+}
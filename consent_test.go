@@ -0,0 +1,84 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что отзыв согласия сразу исключает клиента
+// из маркетинговой выгрузки
+func Test_RecordConsent_WithdrawalExcludesFromExport(t *testing.T) {
+	t.Parallel()
+	consentAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	_, repo := setupTestTx(t, WithClock(testutil.NewFakeClock(consentAt)))
+
+	ctx := context.Background()
+
+	cl := Client{
+		FIO:      "Test",
+		Login:    "Test",
+		Birthday: "19700101",
+		Email:    "consent370@mail.com",
+	}
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	marketingExport := func() string {
+		var buf bytes.Buffer
+		require.NoError(t, repo.Export(ctx, &buf, ExportOptions{Format: FormatCSV, MarketingOnly: true}))
+		return buf.String()
+	}
+
+	// По умолчанию согласия нет
+	client, err := repo.Select(ctx, cl.ID)
+	require.NoError(t, err)
+	assert.False(t, client.MarketingConsent)
+	assert.True(t, client.ConsentUpdatedAt.IsZero())
+	assert.NotContains(t, marketingExport(), cl.Email, "client without consent must not be exported")
+
+	// Клиент даёт согласие
+	require.NoError(t, repo.RecordConsent(ctx, cl.ID, true))
+	client, err = repo.Select(ctx, cl.ID)
+	require.NoError(t, err)
+	assert.True(t, client.MarketingConsent)
+	assert.Equal(t, consentAt, client.ConsentUpdatedAt)
+	assert.Contains(t, marketingExport(), cl.Email, "consented client should be exported")
+
+	// Клиент отзывает согласие
+	require.NoError(t, repo.RecordConsent(ctx, cl.ID, false))
+	assert.NotContains(t, marketingExport(), cl.Email, "withdrawn consent must propagate to export")
+
+	// Обычная выгрузка согласие не учитывает
+	var buf bytes.Buffer
+	require.NoError(t, repo.Export(ctx, &buf, ExportOptions{Format: FormatCSV}))
+	assert.Contains(t, buf.String(), cl.Email)
+
+	// Оба изменения согласия записаны в журнал аудита
+	entries, err := repo.AuditLog(ctx, cl.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, AuditConsent, entries[1].Operation)
+	assert.Equal(t, map[string]FieldChange{"marketing_consent": {Before: strPtr("false"), After: strPtr("true")}}, entries[1].Diff)
+	assert.Equal(t, map[string]FieldChange{"marketing_consent": {Before: strPtr("true"), After: strPtr("false")}}, entries[2].Diff)
+}
+
+// Тест проверяет запись согласия для несуществующего клиента
+func Test_RecordConsent_WhenNoClient(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	err := repo.RecordConsent(ctx, -1, true)
+	require.ErrorIs(t, err, ErrClientNotFound)
+}
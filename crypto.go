@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Префикс, по которому зашифрованное значение отличается от открытого.
+const encryptedPrefix = "enc:"
+
+// cipherVersion — версия формата зашифрованного значения: первый байт
+// данных после префикса.
+const cipherVersion = 1
+
+var (
+	ErrUnknownKey       = errors.New("unknown encryption key")
+	ErrMalformedCipher  = errors.New("malformed encrypted value")
+	ErrNoEncryptionKeys = errors.New("encryption is not configured")
+)
+
+// KeyProvider выдаёт ключи AES (16, 24 или 32 байта) по идентификатору.
+// Текущий ключ используется для шифрования, остальные — только для
+// расшифровки данных, записанных до ротации.
+type KeyProvider interface {
+	CurrentKey() (id string, key []byte, err error)
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeyProvider — KeyProvider с набором ключей в памяти.
+type StaticKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeyProvider создаёт провайдер, у которого current — текущий ключ.
+func NewStaticKeyProvider(current string, keys map[string][]byte) *StaticKeyProvider {
+	return &StaticKeyProvider{current: current, keys: keys}
+}
+
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	key, err := p.Key(p.current)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return p.current, key, nil
+}
+
+func (p *StaticKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	return key, nil
+}
+
+// fieldCipher шифрует отдельные поля клиента. Зашифрованное значение —
+// enc:<base64(версия | длина ID ключа | ID ключа | nonce | шифротекст)>.
+// Заголовок (версия и ID ключа), имя поля и ID клиента передаются как
+// additional data, поэтому значение нельзя подставить ни в другое поле, ни
+// в другого клиента.
+//
+// Открытое значение, записанное до включения шифрования, читается как
+// есть: значением считается только строка с префиксом, base64 после
+// которого разбирается в заголовок известной версии. Email с «@» и дата
+// рождения из цифр таким заголовком быть не могут, даже если начинаются
+// с «enc:».
+type fieldCipher struct {
+	keys KeyProvider
+}
+
+func newFieldCipher(kp KeyProvider) *fieldCipher {
+	return &fieldCipher{keys: kp}
+}
+
+func (c *fieldCipher) encrypt(clientID int, field, plaintext string) (string, error) {
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	if len(id) > 255 {
+		return "", fmt.Errorf("key id %q is longer than 255 bytes", id)
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	header := append([]byte{cipherVersion, byte(len(id))}, id...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(append(header, nonce...), nonce, []byte(plaintext), additionalData(header, clientID, field))
+
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *fieldCipher) decrypt(clientID int, field, value string) (string, error) {
+	v, ok := parseEncrypted(value)
+	if !ok {
+		// значение записано до включения шифрования
+		return value, nil
+	}
+
+	key, err := c.keys.Key(v.keyID)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(v.sealed) < aead.NonceSize() {
+		return "", ErrMalformedCipher
+	}
+
+	nonce, ciphertext := v.sealed[:aead.NonceSize()], v.sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(v.header, clientID, field))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformedCipher, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// needsRotation сообщает, зашифровано ли значение не текущим ключом.
+// Открытые значения не трогаются: ротация меняет только ключ.
+func (c *fieldCipher) needsRotation(value string) (bool, error) {
+	current, _, err := c.keys.CurrentKey()
+	if err != nil {
+		return false, err
+	}
+
+	v, ok := parseEncrypted(value)
+
+	return ok && v.keyID != current, nil
+}
+
+// encryptedValue — разобранное зашифрованное значение.
+type encryptedValue struct {
+	// header — версия формата и ID ключа в том виде, в каком они
+	// записаны.
+	header []byte
+	keyID  string
+	// sealed — nonce и шифротекст.
+	sealed []byte
+}
+
+// parseEncrypted разбирает зашифрованное значение; ok == false, если
+// value — открытое значение.
+func parseEncrypted(value string) (_ encryptedValue, ok bool) {
+	data, found := strings.CutPrefix(value, encryptedPrefix)
+	if !found {
+		return encryptedValue{}, false
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil || len(raw) < 2 || raw[0] != cipherVersion || len(raw) < 2+int(raw[1]) {
+		return encryptedValue{}, false
+	}
+
+	n := 2 + int(raw[1])
+
+	return encryptedValue{header: raw[:n], keyID: string(raw[2:n]), sealed: raw[n:]}, true
+}
+
+// additionalData связывает шифротекст с заголовком, клиентом и полем.
+func additionalData(header []byte, clientID int, field string) []byte {
+	return fmt.Appendf(slices.Clip(header), "%d:%s", clientID, field)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// rotatedTables — таблицы с шифруемыми полями клиентов и столбцы с ID
+// клиента, к которому привязан шифротекст.
+var rotatedTables = []struct{ name, clientID string }{
+	{"clients", "id"},
+	{"clients_history", "client_id"},
+	{"clients_archive", "id"},
+}
+
+// RotateKeys перешифровывает текущим ключом все записи клиентов, их прежних
+// версий (clients_history) и архивных клиентов (clients_archive),
+// зашифрованные старыми ключами. Возвращает число
+// обновлённых записей.
+func (r *Repository) RotateKeys(ctx context.Context) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "rotate_keys")
+	defer end(&err)
+
+	if r.cipher == nil {
+		return 0, ErrNoEncryptionKeys
+	}
+
+	var rotated int
+	err = r.inTx(ctx, func(q querier) error {
+		for _, table := range rotatedTables {
+			stale, err := r.staleClients(ctx, q, table.name, table.clientID)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range stale {
+				cl, err := r.decrypt(row.Client)
+				if err != nil {
+					return err
+				}
+				if cl, err = r.encrypt(cl); err != nil {
+					return err
+				}
+
+				_, err = q.ExecContext(ctx, "UPDATE "+table.name+" SET email = :email, birthday = :birthday WHERE id = :id",
+					sql.Named("email", cl.Email),
+					sql.Named("birthday", cl.Birthday),
+					sql.Named("id", row.RowID))
+				if err != nil {
+					return err
+				}
+			}
+			rotated += len(stale)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return rotated, nil
+}
+
+// staleRow — строка таблицы с шифруемыми полями: Client содержит ID
+// клиента и шифруемые поля, RowID — ID самой строки.
+type staleRow struct {
+	RowID int `db:"row_id"`
+	Client
+}
+
+// staleClients возвращает строки таблицы table, у которых хотя бы одно
+// поле зашифровано не текущим ключом; clientID — столбец с ID клиента.
+func (r *Repository) staleClients(ctx context.Context, q querier, table, clientID string) ([]staleRow, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id AS row_id, "+clientID+" AS id, email, birthday FROM "+table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stale []staleRow
+	for rows.Next() {
+		var row staleRow
+		if err := scanRows(rows, &row); err != nil {
+			return nil, err
+		}
+
+		emailStale, err := r.cipher.needsRotation(row.Email)
+		if err != nil {
+			return nil, err
+		}
+		birthdayStale, err := r.cipher.needsRotation(row.Birthday)
+		if err != nil {
+			return nil, err
+		}
+		if emailStale || birthdayStale {
+			stale = append(stale, row)
+		}
+	}
+
+	return stale, rows.Err()
+}
@@ -0,0 +1,116 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что email и birthday хранятся в БД в зашифрованном виде,
+// а репозиторий возвращает их расшифрованными
+func Test_EncryptedRepository_InsertThenSelect(t *testing.T) {
+	db, repo := setupTestDB(t, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	ctx := context.Background()
+
+	cl := Client{
+		FIO:      "Test",
+		Login:    "Test",
+		Birthday: "19700101",
+		Email:    "mail@mail.com",
+	}
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	// Чтение сырых данных в обход репозитория
+	raw, err := selectClient(db, cl.ID)
+	require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
+	assert.NotEqual(t, cl.Email, raw.Email, "email should be stored encrypted")
+	assert.NotEqual(t, cl.Birthday, raw.Birthday, "birthday should be stored encrypted")
+	assert.NotContains(t, raw.Email, "mail.com", "ciphertext must not contain plaintext parts")
+	assert.Equal(t, "v1", encryptedKeyID(raw.Email), "unexpected ciphertext format: %s", raw.Email)
+	// Незашифрованные поля остаются как есть
+	assert.Equal(t, cl.FIO, raw.FIO, "FIO mismatch: expected %v, actual %v", cl.FIO, raw.FIO)
+
+	// Чтение через репозиторий прозрачно расшифровывает поля
+	client, err := repo.Select(ctx, cl.ID)
+	require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
+	assertClientEqual(t, cl, client)
+}
+
+// Тест проверяет ротацию ключей: записи, зашифрованные старым ключом,
+// перешифровываются новым и остаются читаемыми
+func Test_EncryptedRepository_RotateKeys(t *testing.T) {
+	db, _ := setupTestDB(t)
+
+	ctx := context.Background()
+	oldRepo := NewRepository(db, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	cl := Client{
+		FIO:      "Test",
+		Login:    "Test",
+		Birthday: "19700101",
+		Email:    "mail@mail.com",
+	}
+	var err error
+	cl.ID, err = oldRepo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	// Новый провайдер знает оба ключа, текущим является v2
+	newRepo := NewRepository(db, WithEncryption(NewStaticKeyProvider("v2", map[string][]byte{
+		"v1": testKeyV1,
+		"v2": testKeyV2,
+	})))
+
+	rotated, err := newRepo.RotateKeys(ctx)
+	require.NoError(t, err, "error rotating keys: %v", err)
+	assert.GreaterOrEqual(t, rotated, 1, "at least the test client should be rotated")
+
+	raw, err := selectClient(db, cl.ID)
+	require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
+	assert.Equal(t, "v2", encryptedKeyID(raw.Email), "email should be re-encrypted with v2: %s", raw.Email)
+	assert.Equal(t, "v2", encryptedKeyID(raw.Birthday), "birthday should be re-encrypted with v2: %s", raw.Birthday)
+
+	client, err := newRepo.Select(ctx, cl.ID)
+	require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
+	assertClientEqual(t, cl, client)
+
+	// Повторная ротация ничего не меняет
+	rotated, err = newRepo.RotateKeys(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, rotated, "no records should need rotation")
+
+	// Старый провайдер без ключа v2 больше не может прочитать запись
+	_, err = oldRepo.Select(ctx, cl.ID)
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+// Тест проверяет, что шифротекст привязан к клиенту: email, перенесённый
+// в обход репозитория в запись другого клиента, не расшифровывается
+func Test_EncryptedRepository_ClientBinding(t *testing.T) {
+	db, repo := setupTestDB(t, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	ctx := context.Background()
+
+	first, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	second, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	assert.Greater(t, second, first)
+
+	_, err = db.ExecContext(ctx, "UPDATE clients SET email = (SELECT email FROM clients WHERE id = :from) WHERE id = :to",
+		sql.Named("from", first), sql.Named("to", second))
+	require.NoError(t, err)
+
+	_, err = repo.Select(ctx, first)
+	require.NoError(t, err)
+	_, err = repo.Select(ctx, second)
+	require.ErrorIs(t, err, ErrMalformedCipher)
+}
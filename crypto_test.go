@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тестовые ключи AES-256
+var (
+	testKeyV1 = []byte("0123456789abcdef0123456789abcdef")
+	testKeyV2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+// Тест проверяет, что одинаковые значения шифруются в разные шифротексты
+// и что шифротекст одного поля или клиента нельзя расшифровать как другое
+// поле или другого клиента
+func Test_FieldCipher_NonceAndFieldBinding(t *testing.T) {
+	c := newFieldCipher(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1}))
+
+	first, err := c.encrypt(1, "email", "mail@mail.com")
+	require.NoError(t, err)
+	second, err := c.encrypt(1, "email", "mail@mail.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second, "each encryption should use a fresh nonce")
+
+	plain, err := c.decrypt(1, "email", first)
+	require.NoError(t, err)
+	assert.Equal(t, "mail@mail.com", plain)
+
+	_, err = c.decrypt(1, "birthday", first)
+	require.ErrorIs(t, err, ErrMalformedCipher, "ciphertext must be bound to its field")
+	_, err = c.decrypt(2, "email", first)
+	require.ErrorIs(t, err, ErrMalformedCipher, "ciphertext must be bound to its client")
+
+	// Открытые значения, записанные до включения шифрования, читаются как есть
+	plain, err = c.decrypt(1, "email", "mail@mail.com")
+	require.NoError(t, err)
+	assert.Equal(t, "mail@mail.com", plain)
+}
+
+// Тест проверяет, что открытое значение, начинающееся с префикса
+// шифротекста, не принимается за шифротекст, а шифротекст содержит версию
+// формата и ID ключа
+func Test_FieldCipher_Format(t *testing.T) {
+	c := newFieldCipher(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1}))
+
+	sealed, err := c.encrypt(1, "email", "mail@mail.com")
+	require.NoError(t, err)
+	v, ok := parseEncrypted(sealed)
+	require.True(t, ok, sealed)
+	assert.Equal(t, []byte{cipherVersion, 2, 'v', '1'}, v.header)
+	assert.Equal(t, "v1", v.keyID)
+
+	for _, value := range []string{
+		"enc:mail@mail.com",
+		"enc:v1:mail@mail.com",
+		"enc:v1:" + sealed[len(encryptedPrefix):],
+		"enc:AA",
+		"enc:",
+	} {
+		_, ok := parseEncrypted(value)
+		assert.False(t, ok, value)
+
+		plain, err := c.decrypt(1, "email", value)
+		require.NoError(t, err, value)
+		assert.Equal(t, value, plain)
+	}
+}
+
+// encryptedKeyID возвращает ID ключа, которым зашифровано value, или
+// пустую строку для открытого значения.
+func encryptedKeyID(value string) string {
+	v, _ := parseEncrypted(value)
+	return v.keyID
+}
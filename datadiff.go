@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DiffKind — вид расхождения строки между двумя БД.
+type DiffKind string
+
+const (
+	// DiffOnlyInA — строка есть только в первой БД.
+	DiffOnlyInA DiffKind = "only_in_a"
+	// DiffOnlyInB — строка есть только во второй БД.
+	DiffOnlyInB DiffKind = "only_in_b"
+	// DiffChanged — строка есть в обеих БД, но значения полей отличаются.
+	DiffChanged DiffKind = "changed"
+)
+
+// RowDiff — расхождение строки таблицы между БД a и b.
+type RowDiff struct {
+	Table string `json:"table"`
+	// Key — значения первичного ключа строки.
+	Key  map[string]int64 `json:"key"`
+	Kind DiffKind         `json:"kind"`
+	// Row — значения строки, которая есть только в одной БД.
+	Row map[string]any `json:"row,omitempty"`
+	// Fields — отличающиеся поля строки, которая есть в обеих БД.
+	Fields map[string]FieldDiff `json:"fields,omitempty"`
+}
+
+// FieldDiff — значения поля в БД a и b.
+type FieldDiff struct {
+	A any `json:"a"`
+	B any `json:"b"`
+}
+
+// DataDiff — итог CompareDatabases.
+type DataDiff struct {
+	Tables []TableDataDiff `json:"tables"`
+}
+
+// TableDataDiff — число расхождений в таблице.
+type TableDataDiff struct {
+	Table   string `json:"table"`
+	OnlyInA int64  `json:"only_in_a"`
+	OnlyInB int64  `json:"only_in_b"`
+	Changed int64  `json:"changed"`
+	// SkippedColumns — столбцы, которые есть только в одной БД (например,
+	// при разных версиях схемы) и поэтому не сравнивались.
+	SkippedColumns []string `json:"skipped_columns,omitempty"`
+}
+
+// Equal сообщает, совпадают ли данные сравнённых БД.
+func (d DataDiff) Equal() bool {
+	for _, t := range d.Tables {
+		if t.OnlyInA+t.OnlyInB+t.Changed > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CompareDatabases сравнивает данные клиентов и связанных таблиц в БД a и
+// b (SQLite или Postgres, как у CopyDatabase) по первичному ключу и пишет
+// в w каждое расхождение отдельной строкой JSON (RowDiff). Таблицы
+// читаются одновременно из обеих БД в порядке ключа, поэтому расхождения
+// пишутся по мере чтения, не накапливаясь в памяти. Сравниваются хранимые
+// значения: зашифрованные поля должны быть зашифрованы одними ключами.
+//
+// a и b должны быть разными пулами соединений, каждому из которых
+// доступно хотя бы одно соединение. Таблица, которой нет в одной из БД,
+// возвращает ErrValidation; таблицы, которой нет в обеих (например, в
+// схемах старых версий), в итоге нет.
+func CompareDatabases(ctx context.Context, a, b *sql.DB, w io.Writer) (DataDiff, error) {
+	txA, err := a.BeginTx(ctx, nil)
+	if err != nil {
+		return DataDiff{}, err
+	}
+	defer txA.Rollback()
+	txB, err := b.BeginTx(ctx, nil)
+	if err != nil {
+		return DataDiff{}, err
+	}
+	defer txB.Rollback()
+
+	enc := json.NewEncoder(w)
+	var diff DataDiff
+	for _, t := range transferTables {
+		table, err := compareTable(ctx, txA, txB, t, enc)
+		if errors.Is(err, errNoTable) {
+			continue
+		}
+		if err != nil {
+			return diff, fmt.Errorf("compare %s: %w", t.name, err)
+		}
+		diff.Tables = append(diff.Tables, table)
+	}
+
+	return diff, nil
+}
+
+// errNoTable возвращается compareTable, если таблицы нет в обеих БД.
+var errNoTable = errors.New("table is missing in both databases")
+
+// compareTable сравнивает строки таблицы слиянием двух упорядоченных по
+// ключу выборок.
+func compareTable(ctx context.Context, a, b querier, t transferTable, enc *json.Encoder) (TableDataDiff, error) {
+	result := TableDataDiff{Table: t.name}
+
+	columnsA, errA := tableColumns(ctx, a, t.name)
+	columnsB, errB := tableColumns(ctx, b, t.name)
+	switch {
+	case ctx.Err() != nil:
+		return result, ctx.Err()
+	case errA != nil && errB != nil:
+		return result, errNoTable
+	case errA != nil:
+		return result, fmt.Errorf("%w: table is missing in a: %v", ErrValidation, errA)
+	case errB != nil:
+		return result, fmt.Errorf("%w: table is missing in b: %v", ErrValidation, errB)
+	}
+	columns, skipped := commonColumns(columnsA, columnsB)
+	result.SkippedColumns = skipped
+
+	// Столбцы ключа идут первыми
+	columns = slices.DeleteFunc(columns, func(col string) bool { return slices.Contains(t.key, col) })
+	columns = append(slices.Clone(t.key), columns...)
+
+	query := fmt.Sprintf("SELECT %s FROM %q ORDER BY %s",
+		strings.Join(quoteIdents(columns), ", "), t.name, strings.Join(quoteIdents(t.key), ", "))
+	rowsA, err := a.QueryContext(ctx, query)
+	if err != nil {
+		return result, err
+	}
+	defer rowsA.Close()
+	rowsB, err := b.QueryContext(ctx, query)
+	if err != nil {
+		return result, err
+	}
+	defer rowsB.Close()
+
+	curA, curB := newDiffCursor(rowsA, len(t.key), len(columns)), newDiffCursor(rowsB, len(t.key), len(columns))
+	if err := curA.next(); err != nil {
+		return result, err
+	}
+	if err := curB.next(); err != nil {
+		return result, err
+	}
+	for curA.ok || curB.ok {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		var (
+			d   RowDiff
+			cmp = compareKeys(curA, curB)
+		)
+		switch {
+		case cmp < 0:
+			d = RowDiff{Kind: DiffOnlyInA, Key: curA.keyMap(t.key), Row: curA.rowMap(columns)}
+			result.OnlyInA++
+		case cmp > 0:
+			d = RowDiff{Kind: DiffOnlyInB, Key: curB.keyMap(t.key), Row: curB.rowMap(columns)}
+			result.OnlyInB++
+		default:
+			fields := make(map[string]FieldDiff)
+			for i := len(t.key); i < len(columns); i++ {
+				va, vb := normalizeDiffValue(curA.values[i]), normalizeDiffValue(curB.values[i])
+				if va != vb {
+					fields[columns[i]] = FieldDiff{A: va, B: vb}
+				}
+			}
+			if len(fields) > 0 {
+				d = RowDiff{Kind: DiffChanged, Key: curA.keyMap(t.key), Fields: fields}
+				result.Changed++
+			}
+		}
+
+		if d.Kind != "" {
+			d.Table = t.name
+			if err := enc.Encode(d); err != nil {
+				return result, err
+			}
+		}
+		if cmp <= 0 {
+			if err := curA.next(); err != nil {
+				return result, err
+			}
+		}
+		if cmp >= 0 {
+			if err := curB.next(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// diffCursor — текущая строка упорядоченной по ключу выборки.
+type diffCursor struct {
+	rows   *sql.Rows
+	keys   int
+	values []any
+	ok     bool
+}
+
+func newDiffCursor(rows *sql.Rows, keys, columns int) *diffCursor {
+	return &diffCursor{rows: rows, keys: keys, values: make([]any, columns)}
+}
+
+// next читает следующую строку; ok становится false в конце выборки.
+func (c *diffCursor) next() error {
+	c.ok = c.rows.Next()
+	if !c.ok {
+		return c.rows.Err()
+	}
+
+	dest := make([]any, len(c.values))
+	for i := range dest {
+		dest[i] = &c.values[i]
+	}
+
+	return c.rows.Scan(dest...)
+}
+
+func (c *diffCursor) key(i int) int64 {
+	n, _ := c.values[i].(int64)
+
+	return n
+}
+
+func (c *diffCursor) keyMap(names []string) map[string]int64 {
+	key := make(map[string]int64, len(names))
+	for i, name := range names {
+		key[name] = c.key(i)
+	}
+
+	return key
+}
+
+func (c *diffCursor) rowMap(columns []string) map[string]any {
+	row := make(map[string]any, len(columns)-c.keys)
+	for i := c.keys; i < len(columns); i++ {
+		row[columns[i]] = normalizeDiffValue(c.values[i])
+	}
+
+	return row
+}
+
+// compareKeys сравнивает ключи текущих строк; закончившаяся выборка
+// считается большей любой строки.
+func compareKeys(a, b *diffCursor) int {
+	switch {
+	case !b.ok:
+		return -1
+	case !a.ok:
+		return 1
+	}
+	for i := 0; i < a.keys; i++ {
+		switch ka, kb := a.key(i), b.key(i); {
+		case ka < kb:
+			return -1
+		case ka > kb:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// normalizeDiffValue приводит значение к сравнимому виду: строки могут
+// читаться как []byte.
+func normalizeDiffValue(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+
+	return v
+}
+
+// commonColumns возвращает столбцы a, которые есть и в b, и
+// отсортированный список столбцов, которые есть только в одной из БД.
+func commonColumns(a, b []string) (common, skipped []string) {
+	inB := make(map[string]bool, len(b))
+	for _, col := range b {
+		inB[col] = true
+	}
+	for _, col := range a {
+		if inB[col] {
+			common = append(common, col)
+			delete(inB, col)
+		} else {
+			skipped = append(skipped, col)
+		}
+	}
+	for col := range inB {
+		skipped = append(skipped, col)
+	}
+	sort.Strings(skipped)
+
+	return common, skipped
+}
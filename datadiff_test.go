@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeRowDiffs разбирает вывод CompareDatabases по строкам.
+func decodeRowDiffs(t *testing.T, out *bytes.Buffer) []RowDiff {
+	t.Helper()
+
+	var diffs []RowDiff
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var d RowDiff
+		require.NoError(t, dec.Decode(&d))
+		diffs = append(diffs, d)
+	}
+
+	return diffs
+}
+
+// Тест проверяет, что у одинаково заполненных БД расхождений нет
+func Test_CompareDatabases_Equal(t *testing.T) {
+	ctx := context.Background()
+	a, b := openMemoryDB(t), openMemoryDB(t)
+	seedTransferSource(t, a)
+	_, err := CopyDatabase(ctx, a, b, TransferOptions{})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	diff, err := CompareDatabases(ctx, a, b, &out)
+	require.NoError(t, err)
+	assert.True(t, diff.Equal())
+	assert.Len(t, diff.Tables, len(transferTables))
+	assert.Empty(t, out.String())
+}
+
+// Тест проверяет отчёт о строках, которые есть только в одной БД, и о
+// построчно отличающихся полях, в том числе в таблице с составным ключом
+func Test_CompareDatabases_Divergent(t *testing.T) {
+	ctx := context.Background()
+	a, b := openMemoryDB(t), openMemoryDB(t)
+	seedTransferSource(t, a)
+	_, err := CopyDatabase(ctx, a, b, TransferOptions{})
+	require.NoError(t, err)
+
+	var first, last int64
+	require.NoError(t, a.QueryRow("SELECT MIN(id), MAX(id) FROM clients").Scan(&first, &last))
+	_, err = b.Exec("UPDATE clients SET email = 'changed@mail.com', legal_hold = 1 WHERE id = ?", first)
+	require.NoError(t, err)
+	_, err = b.Exec("DELETE FROM clients WHERE id = ?", last)
+	require.NoError(t, err)
+	_, err = b.Exec("INSERT INTO clients (fio, login) VALUES ('Only B', 'onlyb')")
+	require.NoError(t, err)
+	_, err = b.Exec("DELETE FROM client_tags WHERE client_id = ?", first)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	diff, err := CompareDatabases(ctx, a, b, &out)
+	require.NoError(t, err)
+	assert.False(t, diff.Equal())
+	assert.Equal(t, TableDataDiff{Table: "clients", OnlyInA: 1, OnlyInB: 1, Changed: 1}, diff.Tables[0])
+
+	diffs := decodeRowDiffs(t, &out)
+	require.Len(t, diffs, 4)
+
+	var email string
+	require.NoError(t, a.QueryRow("SELECT email FROM clients WHERE id = ?", first).Scan(&email))
+	assert.Equal(t, RowDiff{
+		Table: "clients",
+		Key:   map[string]int64{"id": first},
+		Kind:  DiffChanged,
+		Fields: map[string]FieldDiff{
+			"email":      {A: email, B: "changed@mail.com"},
+			"legal_hold": {A: float64(0), B: float64(1)},
+		},
+	}, diffs[0])
+	assert.Equal(t, DiffOnlyInA, diffs[1].Kind)
+	assert.Equal(t, map[string]int64{"id": last}, diffs[1].Key)
+	assert.Equal(t, DiffOnlyInB, diffs[2].Kind)
+	assert.Equal(t, "Only B", diffs[2].Row["fio"])
+	assert.Equal(t, RowDiff{
+		Table: "client_tags",
+		Key:   map[string]int64{"client_id": first, "tag_id": 1},
+		Kind:  DiffOnlyInA,
+	}, diffs[3])
+}
+
+// Тест проверяет сравнение БД разных версий схемы: столбцы, которых нет в
+// одной из БД, пропускаются, как и таблицы, которых нет в обеих, а
+// таблица, которой нет в одной из БД, — ошибка
+func Test_CompareDatabases_SchemaMismatch(t *testing.T) {
+	ctx := context.Background()
+	a, b := openMemoryDB(t), openMemoryDB(t)
+	require.NoError(t, MigrateTo(ctx, a, 17))
+	require.NoError(t, MigrateTo(ctx, b, 16))
+
+	diff, err := CompareDatabases(ctx, a, b, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"legal_hold"}, diff.Tables[0].SkippedColumns)
+	assert.True(t, diff.Equal())
+	assert.Len(t, diff.Tables, len(transferTables)-9, "tables added after version 17 are missing in both")
+
+	c := openMemoryDB(t)
+	require.NoError(t, MigrateTo(ctx, c, 15))
+	_, err = CompareDatabases(ctx, a, c, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "environment")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// DebugConfig задаёт внутренний отладочный HTTP-сервер. По умолчанию
+// (нулевое значение) профилирование выключено.
+type DebugConfig struct {
+	// PprofEnabled включает обработчики net/http/pprof на /debug/pprof/.
+	PprofEnabled bool
+	// Addr — адрес отдельного внутреннего порта, например "127.0.0.1:6060".
+	// Не должен быть доступен снаружи.
+	Addr string
+}
+
+// DebugHandler возвращает обработчик отладочных маршрутов. Если
+// профилирование не включено явно, на /debug/pprof/ отвечает 404.
+func DebugHandler(cfg DebugConfig) http.Handler {
+	mux := http.NewServeMux()
+	if !cfg.PprofEnabled {
+		return mux
+	}
+
+	// Маршруты регистрируются вручную: импорт net/http/pprof сам по себе
+	// добавляет их в http.DefaultServeMux, который здесь не используется.
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
+
+// StartDebugServer запускает отладочный сервер на cfg.Addr в отдельной
+// горутине. Если профилирование выключено, сервер не запускается и
+// возвращается nil.
+func StartDebugServer(cfg DebugConfig) (*http.Server, error) {
+	if !cfg.PprofEnabled {
+		return nil, nil
+	}
+	if cfg.Addr == "" {
+		return nil, errors.New("debug server address is not set")
+	}
+
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{
+		Handler:           DebugHandler(cfg),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go srv.Serve(ln)
+
+	return srv, nil
+}
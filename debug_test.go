@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что pprof недоступен без явного включения
+func Test_DebugHandler_AbsentUnlessEnabled(t *testing.T) {
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap"} {
+		rec := httptest.NewRecorder()
+		DebugHandler(DebugConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, "%s should be absent by default", path)
+	}
+
+	// Отладочный сервер без явного включения не запускается
+	srv, err := StartDebugServer(DebugConfig{Addr: "127.0.0.1:0"})
+	require.NoError(t, err)
+	assert.Nil(t, srv, "debug server should not start when disabled")
+}
+
+// Тест проверяет доступность pprof при явном включении
+func Test_DebugHandler_Enabled(t *testing.T) {
+	h := DebugHandler(DebugConfig{PprofEnabled: true})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "heap")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/heap?debug=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, err := StartDebugServer(DebugConfig{PprofEnabled: true})
+	require.Error(t, err, "address is required when enabled")
+
+	srv, err := StartDebugServer(DebugConfig{PprofEnabled: true, Addr: "127.0.0.1:0"})
+	require.NoError(t, err)
+	require.NotNil(t, srv)
+	require.NoError(t, srv.Shutdown(context.Background()))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DebugSQLEnv — переменная окружения, включающая вывод выполняемых запросов
+// в stderr для локальной разработки: "1" — с замаскированными значениями
+// параметров, "full" — с полными значениями. Пустое значение, "0" и
+// "false" режим выключают.
+const DebugSQLEnv = "DEBUG_SQL"
+
+// WithDebugSQL выводит в w каждый выполненный запрос с параметрами и
+// временем выполнения. Значения параметров, кроме ID, скрываются, пока
+// full не задан явно.
+func WithDebugSQL(w io.Writer, full bool) Option {
+	return func(r *Repository) {
+		r.hooks = append(r.hooks, &sqlDumper{w: w, full: full})
+	}
+}
+
+// debugSQLFromEnv возвращает опцию режима DEBUG_SQL по переменной
+// окружения или nil, если режим выключен.
+func debugSQLFromEnv() Option {
+	switch strings.ToLower(os.Getenv(DebugSQLEnv)) {
+	case "", "0", "false", "off":
+		return nil
+	case "full":
+		return WithDebugSQL(os.Stderr, true)
+	default:
+		return WithDebugSQL(os.Stderr, false)
+	}
+}
+
+// sqlDumper печатает запросы в человекочитаемом виде.
+type sqlDumper struct {
+	w    io.Writer
+	full bool
+}
+
+func (d *sqlDumper) afterQuery(_ context.Context, ev queryEvent) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[sql] %s %s: %s", ev.Duration, ev.Op, strings.Join(strings.Fields(ev.SQL), " "))
+
+	for i, arg := range ev.Args {
+		name := fmt.Sprintf("$%d", i+1)
+		value := arg
+		if named, ok := arg.(sql.NamedArg); ok {
+			name, value = named.Name, named.Value
+		}
+
+		if !d.full && !defaultLoggable[name] {
+			value = redactedValue
+		}
+		fmt.Fprintf(&b, " %s=%v", name, value)
+	}
+
+	if ev.Err != nil {
+		fmt.Fprintf(&b, " error=%q", ev.Err.Error())
+	}
+	b.WriteByte('\n')
+
+	io.WriteString(d.w, b.String())
+}
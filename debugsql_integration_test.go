@@ -0,0 +1,38 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что по умолчанию значения параметров скрыты,
+// а в полном режиме выводятся
+func Test_DebugSQL_RedactionDefault(t *testing.T) {
+	var redacted, full bytes.Buffer
+	_, repo := setupTestDB(t, WithDebugSQL(&redacted, false), WithDebugSQL(&full, true))
+
+	ctx := context.Background()
+
+	cl := Client{FIO: "Test", Login: "Test", Birthday: "19700101", Email: "debug380@mail.com"}
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	_, err = repo.Select(ctx, cl.ID)
+	require.NoError(t, err)
+
+	assert.Contains(t, redacted.String(), "[sql] ")
+	assert.Contains(t, redacted.String(), "insert: INSERT INTO clients")
+	assert.Contains(t, redacted.String(), "email="+redactedValue)
+	assert.NotContains(t, redacted.String(), cl.Email, "values must be redacted by default")
+	assert.Contains(t, redacted.String(), "select: SELECT")
+
+	assert.Contains(t, full.String(), "email="+cl.Email, "full mode should print values")
+}
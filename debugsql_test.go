@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет включение режима DEBUG_SQL переменной окружения
+func Test_DebugSQL_EnvToggle(t *testing.T) {
+	tests := []struct {
+		value   string
+		enabled bool
+		full    bool
+	}{
+		{"", false, false},
+		{"0", false, false},
+		{"false", false, false},
+		{"1", true, false},
+		{"true", true, false},
+		{"full", true, true},
+		{"FULL", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(DebugSQLEnv, tt.value)
+
+			repo := NewRepository(nil)
+			if !tt.enabled {
+				assert.Empty(t, repo.hooks, "debug mode should be off for %q", tt.value)
+				return
+			}
+
+			require.Len(t, repo.hooks, 1)
+			dumper, ok := repo.hooks[0].(*sqlDumper)
+			require.True(t, ok)
+			assert.Equal(t, tt.full, dumper.full, "full mode for %q", tt.value)
+		})
+	}
+}
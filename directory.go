@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DirectoryEntry — пользователь внешнего каталога: DN и значения
+// атрибутов по именам.
+type DirectoryEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// value возвращает первое значение атрибута name или пустую строку.
+// Имена атрибутов LDAP сравниваются без учёта регистра.
+func (e DirectoryEntry) value(name string) string {
+	if name == "" {
+		return ""
+	}
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) && len(values) > 0 {
+			return strings.TrimSpace(values[0])
+		}
+	}
+
+	return ""
+}
+
+// Directory — каталог пользователей, из которого DirectorySync загружает
+// клиентов (см. LDAPDirectory).
+type Directory interface {
+	// Users возвращает всех пользователей каталога.
+	Users(ctx context.Context) ([]DirectoryEntry, error)
+}
+
+// DirectoryMapping — атрибуты каталога, из которых берутся ID
+// пользователя и поля клиента.
+type DirectoryMapping struct {
+	// ExternalID — неизменяемый ID пользователя (objectGUID, entryUUID):
+	// по нему пользователь находит своего клиента и после переименования.
+	ExternalID string
+	FIO        string
+	Login      string
+	Email      string
+	// Birthday — атрибут с датой рождения в виде ГГГГММДД, ГГГГ-ММ-ДД или
+	// GeneralizedTime. Стандартного атрибута для неё нет, поэтому он
+	// задаётся по схеме каталога; без даты рождения новый клиент не
+	// проходит Client.Validate и пропускается.
+	Birthday string
+}
+
+// Сопоставления атрибутов для распространённых каталогов; Birthday
+// задаётся отдельно.
+var (
+	ActiveDirectoryMapping = DirectoryMapping{ExternalID: "objectGUID", FIO: "displayName", Login: "sAMAccountName", Email: "mail"}
+	OpenLDAPMapping        = DirectoryMapping{ExternalID: "entryUUID", FIO: "cn", Login: "uid", Email: "mail"}
+)
+
+// attributes возвращает имена всех сопоставленных атрибутов.
+func (m DirectoryMapping) attributes() []string {
+	var names []string
+	for _, name := range []string{m.ExternalID, m.FIO, m.Login, m.Email, m.Birthday} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Validate проверяет, что заданы атрибуты ID пользователя, ФИО, логина и
+// email.
+func (m DirectoryMapping) Validate() error {
+	for _, f := range []struct{ name, attr string }{
+		{"external ID", m.ExternalID},
+		{"fio", m.FIO},
+		{"login", m.Login},
+		{"email", m.Email},
+	} {
+		if strings.TrimSpace(f.attr) == "" {
+			return fmt.Errorf("%w: directory attribute for %s is required", ErrValidation, f.name)
+		}
+	}
+
+	return nil
+}
+
+// apply переносит в cl значения сопоставленных атрибутов записи; поля,
+// которых в записи нет, сохраняют прежние значения.
+func (m DirectoryMapping) apply(entry DirectoryEntry, cl Client) Client {
+	for _, f := range []struct {
+		attr  string
+		field *string
+	}{
+		{m.FIO, &cl.FIO},
+		{m.Login, &cl.Login},
+		{m.Email, &cl.Email},
+	} {
+		if v := entry.value(f.attr); v != "" {
+			*f.field = v
+		}
+	}
+	if v := strings.ReplaceAll(entry.value(m.Birthday), "-", ""); len(v) >= 8 {
+		cl.Birthday = v[:8]
+	}
+
+	return cl
+}
+
+// DirectorySyncReport — итог синхронизации с каталогом.
+type DirectorySyncReport struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	// Skipped — пользователи без изменений и пользователи, которых не
+	// удалось загрузить.
+	Skipped int `json:"skipped"`
+	// Rejected — причины, по которым пользователи не загружены.
+	Rejected []string `json:"rejected,omitempty"`
+}
+
+// DirectorySync загружает клиентов из каталога пользователей, который
+// считается источником истины: пользователь, впервые найденный в каталоге,
+// добавляется клиентом, а у уже загруженного клиента поля, отличающиеся от
+// каталога, перезаписываются. Клиент находится по ID пользователя в
+// каталоге source (таблица directory_links), поэтому переименование
+// пользователя не создаёт нового клиента. Удаление пользователя из
+// каталога клиента не затрагивает; удалённый в БД клиент (Delete,
+// EraseClient) при следующей синхронизации добавляется заново, если
+// пользователь остался в каталоге.
+type DirectorySync struct {
+	repo    *Repository
+	source  string
+	dir     Directory
+	mapping DirectoryMapping
+
+	mu sync.Mutex
+}
+
+// NewDirectorySync создаёт синхронизацию клиентов БД репозитория repo с
+// каталогом dir под именем source по сопоставлению атрибутов mapping.
+func NewDirectorySync(repo *Repository, source string, dir Directory, mapping DirectoryMapping) *DirectorySync {
+	return &DirectorySync{repo: repo, source: source, dir: dir, mapping: mapping}
+}
+
+// Sync читает всех пользователей каталога и загружает изменения в одной
+// транзакции. Пользователи без ID, с повторяющимся ID или с данными, не
+// прошедшими Client.Validate, пропускаются с причиной в отчёте и не
+// мешают загрузке остальных; прочие ошибки отменяют синхронизацию целиком.
+func (d *DirectorySync) Sync(ctx context.Context) (_ DirectorySyncReport, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r := d.repo
+	ctx, end := r.startOperation(ctx, "directory_sync")
+	defer end(&err)
+
+	if strings.TrimSpace(d.source) == "" {
+		return DirectorySyncReport{}, fmt.Errorf("%w: directory name is required", ErrValidation)
+	}
+	if err := d.mapping.Validate(); err != nil {
+		return DirectorySyncReport{}, err
+	}
+
+	entries, err := d.dir.Users(ctx)
+	if err != nil {
+		return DirectorySyncReport{}, fmt.Errorf("read directory %s: %w", d.source, err)
+	}
+
+	var report DirectorySyncReport
+	err = r.inTx(ctx, func(q querier) error {
+		report = DirectorySyncReport{}
+		seen := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			externalID := entry.value(d.mapping.ExternalID)
+			reason := ""
+			switch {
+			case externalID == "":
+				reason = fmt.Sprintf("%s: no %s", entry.DN, d.mapping.ExternalID)
+			case seen[externalID]:
+				reason = fmt.Sprintf("%s: duplicate %s %s", entry.DN, d.mapping.ExternalID, externalID)
+			}
+			if reason == "" {
+				seen[externalID] = true
+				var err error
+				if reason, err = d.upsert(ctx, q, &report, externalID, entry); err != nil {
+					return err
+				}
+			}
+			if reason != "" {
+				report.Skipped++
+				report.Rejected = append(report.Rejected, reason)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return DirectorySyncReport{}, err
+	}
+
+	return report, nil
+}
+
+// upsert добавляет или обновляет клиента пользователя externalID и
+// учитывает результат в report. Если пользователь не загружен из-за его
+// данных, возвращается причина.
+func (d *DirectorySync) upsert(ctx context.Context, q querier, report *DirectorySyncReport, externalID string, entry DirectoryEntry) (string, error) {
+	r := d.repo
+	now := r.now().Format(time.RFC3339)
+
+	var clientID int
+	err := q.QueryRowContext(ctx, "SELECT client_id FROM directory_links WHERE source = :source AND external_id = :external_id",
+		sql.Named("source", d.source),
+		sql.Named("external_id", externalID)).Scan(&clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		id, err := r.insert(ctx, q, d.mapping.apply(entry, Client{}))
+		if errors.Is(err, ErrValidation) {
+			return fmt.Sprintf("%s: %v", entry.DN, err), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = q.ExecContext(ctx, "INSERT INTO directory_links (source, external_id, client_id, synced_at) VALUES (:source, :external_id, :client_id, :synced_at)",
+			sql.Named("source", d.source),
+			sql.Named("external_id", externalID),
+			sql.Named("client_id", id),
+			sql.Named("synced_at", now))
+		if err != nil {
+			return "", err
+		}
+		report.Created++
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	before, err := r.selectClient(ctx, q, clientID)
+	if errors.Is(err, ErrClientNotFound) {
+		return fmt.Sprintf("%s: client %d is archived or merged", entry.DN, clientID), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	after := d.mapping.apply(entry, before)
+	if after == before {
+		report.Skipped++
+	} else {
+		err := r.update(ctx, q, after)
+		if errors.Is(err, ErrValidation) {
+			return fmt.Sprintf("%s: %v", entry.DN, err), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		report.Updated++
+	}
+
+	_, err = q.ExecContext(ctx, "UPDATE directory_links SET synced_at = :synced_at WHERE source = :source AND external_id = :external_id",
+		sql.Named("synced_at", now),
+		sql.Named("source", d.source),
+		sql.Named("external_id", externalID))
+
+	return "", err
+}
+
+// Run синхронизирует клиентов с каталогом каждые poll, пока не отменён
+// ctx. Синхронизация, завершившаяся ошибкой, откатывается целиком, а
+// ошибка передаётся в onError (если задан); пропущенные пользователи
+// ошибкой не считаются и видны только в отчёте Sync.
+func (d *DirectorySync) Run(ctx context.Context, poll time.Duration, onError func(error)) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Sync(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPPasswordSecret — имя секрета с паролем учётной записи, от имени
+// которой clientctl читает каталог LDAP.
+const LDAPPasswordSecret = "CLIENTS_LDAP_PASSWORD"
+
+// Значения LDAPConfig по умолчанию.
+const (
+	DefaultLDAPFilter   = "(objectClass=person)"
+	DefaultLDAPPageSize = 500
+	DefaultLDAPTimeout  = 30 * time.Second
+)
+
+// LDAPConfig — параметры подключения к каталогу LDAP или Active Directory.
+type LDAPConfig struct {
+	// URL — адрес сервера: ldap://host:389 или ldaps://host:636.
+	URL string
+	// BindDN и BindPassword — учётная запись для чтения каталога; без
+	// BindDN каталог читается анонимно.
+	BindDN       string
+	BindPassword string
+	// BaseDN — ветка каталога, в которой ищутся пользователи.
+	BaseDN string
+	// Filter — фильтр пользователей; по умолчанию DefaultLDAPFilter. Для
+	// Active Directory обычно (&(objectCategory=person)(objectClass=user)).
+	Filter string
+	// Attributes — читаемые атрибуты; пустой список — все атрибуты.
+	Attributes []string
+	// PageSize — размер страницы результатов; по умолчанию
+	// DefaultLDAPPageSize. Active Directory не отдаёт больше 1000 записей
+	// без постраничного чтения.
+	PageSize uint32
+	// Timeout — ограничение на подключение и каждый запрос; по умолчанию
+	// DefaultLDAPTimeout.
+	Timeout time.Duration
+	// TLS — настройки TLS для ldaps://; nil — настройки по умолчанию.
+	TLS *tls.Config
+}
+
+// LDAPDirectory — Directory, читающий пользователей из LDAP постранично.
+// Значения атрибутов, не являющиеся текстом UTF-8 (objectGUID в Active
+// Directory), возвращаются в шестнадцатеричном виде.
+type LDAPDirectory struct {
+	cfg LDAPConfig
+}
+
+// NewLDAPDirectory создаёт каталог с параметрами cfg.
+func NewLDAPDirectory(cfg LDAPConfig) *LDAPDirectory {
+	if cfg.Filter == "" {
+		cfg.Filter = DefaultLDAPFilter
+	}
+	if cfg.PageSize == 0 {
+		cfg.PageSize = DefaultLDAPPageSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultLDAPTimeout
+	}
+
+	return &LDAPDirectory{cfg: cfg}
+}
+
+// Users подключается к серверу, выполняет вход и возвращает пользователей
+// ветки BaseDN, подходящих под Filter. Отмена ctx закрывает соединение.
+func (d *LDAPDirectory) Users(ctx context.Context) ([]DirectoryEntry, error) {
+	opts := []ldap.DialOpt{ldap.DialWithDialer(&net.Dialer{Timeout: d.cfg.Timeout})}
+	if d.cfg.TLS != nil {
+		opts = append(opts, ldap.DialWithTLSConfig(d.cfg.TLS))
+	}
+	conn, err := ldap.DialURL(d.cfg.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetTimeout(d.cfg.Timeout)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if d.cfg.BindDN != "" {
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("bind as %s: %w", d.cfg.BindDN, err)
+		}
+	}
+
+	req := ldap.NewSearchRequest(d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		d.cfg.Filter, d.cfg.Attributes, nil)
+	res, err := conn.SearchWithPaging(req, d.cfg.PageSize)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]DirectoryEntry, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		entry := DirectoryEntry{DN: e.DN, Attributes: make(map[string][]string, len(e.Attributes))}
+		for _, attr := range e.Attributes {
+			values := make([]string, len(attr.ByteValues))
+			for i, v := range attr.ByteValues {
+				if utf8.Valid(v) {
+					values[i] = string(v)
+				} else {
+					values[i] = hex.EncodeToString(v)
+				}
+			}
+			entry.Attributes[attr.Name] = values
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
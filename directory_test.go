@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Коды результатов LDAP, которые возвращает fakeLDAP.
+const (
+	ldapSuccess            = 0
+	ldapInvalidCredentials = 49
+)
+
+// fakeLDAP — сервер LDAP в памяти: вход по одной учётной записи и поиск
+// с постраничным чтением, возвращающий все записи ветки без учёта
+// фильтра.
+type fakeLDAP struct {
+	listener net.Listener
+	bindDN   string
+	password string
+
+	mu      sync.Mutex
+	entries []DirectoryEntry
+	// filters и attributes — фильтры и атрибуты запросов поиска.
+	filters    []string
+	attributes [][]string
+	// pages — число отданных страниц результатов.
+	pages int
+}
+
+func newFakeLDAP(t *testing.T, entries ...DirectoryEntry) *fakeLDAP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l := &fakeLDAP{listener: listener, bindDN: "cn=sync,dc=corp", password: "secret", entries: entries}
+	t.Cleanup(func() { listener.Close() })
+	go l.accept()
+
+	return l
+}
+
+func (l *fakeLDAP) URL() string {
+	return "ldap://" + l.listener.Addr().String()
+}
+
+// setEntries заменяет записи каталога.
+func (l *fakeLDAP) setEntries(entries ...DirectoryEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = entries
+}
+
+func (l *fakeLDAP) accept() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		go l.serve(conn)
+	}
+}
+
+func (l *fakeLDAP) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		req, err := ber.ReadPacket(conn)
+		if err != nil || len(req.Children) < 2 {
+			return
+		}
+		id, op := req.Children[0].Value, req.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := ldapSuccess
+			if op.Children[1].Value != l.bindDN || op.Children[2].Data.String() != l.password {
+				code = ldapInvalidCredentials
+			}
+			responses = append(responses, ldapResult(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			var controls []ldap.Control
+			if len(req.Children) > 2 {
+				for _, child := range req.Children[2].Children {
+					control, err := ldap.DecodeControl(child)
+					if err != nil {
+						return
+					}
+					controls = append(controls, control)
+				}
+			}
+			responses = l.search(op, controls)
+		default:
+			return
+		}
+
+		for _, res := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+			for _, child := range res.Children {
+				envelope.AppendChild(child)
+			}
+			if _, err := conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// search возвращает записи ветки запроса: все сразу или страницу, если в
+// запросе есть управление постраничным чтением. Курсор страницы — номер
+// первой записи следующей страницы.
+func (l *fakeLDAP) search(op *ber.Packet, controls []ldap.Control) []*ber.Packet {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	base := op.Children[0].Value.(string)
+	filter, _ := ldap.DecompileFilter(op.Children[6])
+	l.filters = append(l.filters, filter)
+	var attributes []string
+	for _, attr := range op.Children[7].Children {
+		attributes = append(attributes, attr.Value.(string))
+	}
+	l.attributes = append(l.attributes, attributes)
+
+	var entries []DirectoryEntry
+	for _, e := range l.entries {
+		if strings.HasSuffix(e.DN, base) {
+			entries = append(entries, e)
+		}
+	}
+
+	var paging *ldap.ControlPaging
+	if control := ldap.FindControl(controls, ldap.ControlTypePaging); control != nil {
+		paging = control.(*ldap.ControlPaging)
+	}
+	next := ""
+	if paging != nil {
+		start, _ := strconv.Atoi(string(paging.Cookie))
+		end := min(start+int(paging.PagingSize), len(entries))
+		if end < len(entries) {
+			next = strconv.Itoa(end)
+		}
+		entries = entries[start:end]
+		l.pages++
+	}
+
+	var responses []*ber.Packet
+	for _, e := range entries {
+		entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+		entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "Object Name"))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for name, values := range e.Attributes {
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		entry.AppendChild(attrs)
+		responses = append(responses, wrapLDAP(entry))
+	}
+
+	done := ldapResult(ldap.ApplicationSearchResultDone, ldapSuccess)
+	if paging != nil {
+		cookie := ldap.NewControlPaging(paging.PagingSize)
+		cookie.SetCookie([]byte(next))
+		controls := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		controls.AppendChild(cookie.Encode())
+		done.AppendChild(controls)
+	}
+
+	return append(responses, done)
+}
+
+// ldapResult возвращает ответ tag с кодом результата code.
+func ldapResult(tag ber.Tag, code int) *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+
+	return wrapLDAP(res)
+}
+
+// wrapLDAP возвращает пакет, дочерние элементы которого serve добавляет в
+// сообщение после ID.
+func wrapLDAP(op *ber.Packet) *ber.Packet {
+	wrapper := ber.NewSequence("Response")
+	wrapper.AppendChild(op)
+
+	return wrapper
+}
+
+// adUser возвращает пользователя Active Directory с objectGUID guid.
+func adUser(guid, name, login string, attrs ...string) DirectoryEntry {
+	entry := DirectoryEntry{
+		DN: "CN=" + name + ",OU=Users,DC=corp",
+		Attributes: map[string][]string{
+			"objectGUID":     {guid},
+			"displayName":    {name},
+			"sAMAccountName": {login},
+			"mail":           {login + "@corp.example"},
+		},
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		entry.Attributes[attrs[i]] = []string{attrs[i+1]}
+	}
+
+	return entry
+}
+
+// setupDirectory возвращает репозиторий, каталог в памяти и синхронизацию
+// с ним по сопоставлению Active Directory с датой рождения из атрибута
+// birthDate.
+func setupDirectory(t *testing.T, entries ...DirectoryEntry) (*Repository, *fakeLDAP, *DirectorySync) {
+	t.Helper()
+
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+	repo := NewRepository(db)
+	server := newFakeLDAP(t, entries...)
+	mapping := ActiveDirectoryMapping
+	mapping.Birthday = "birthDate"
+	dir := NewLDAPDirectory(LDAPConfig{URL: server.URL(), BindDN: server.bindDN, BindPassword: server.password, BaseDN: "DC=corp", PageSize: 2})
+
+	return repo, server, NewDirectorySync(repo, "corp", dir, mapping)
+}
+
+// Тест проверяет создание, обновление и пропуск клиентов по пользователям
+// каталога: клиент находится по objectGUID и после переименования, а
+// пользователи с некорректными данными пропускаются с причиной
+func Test_DirectorySync_Sync(t *testing.T) {
+	ctx := context.Background()
+	ivanov := adUser("guid-1", "Ivan Ivanov", "ivanov", "birthDate", "1990-05-01")
+	petrova := adUser("guid-2", "Anna Petrova", "petrova", "birthDate", "19850312000000Z")
+	repo, server, directory := setupDirectory(t,
+		ivanov,
+		petrova,
+		adUser("guid-3", "No Birthday", "nobirthday"),
+		adUser("", "No GUID", "noguid", "birthDate", "19900101"),
+		adUser("guid-1", "Ivan Copy", "ivancopy", "birthDate", "19900101"),
+	)
+
+	report, err := directory.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	assert.Zero(t, report.Updated)
+	assert.Equal(t, 3, report.Skipped)
+	require.Len(t, report.Rejected, 3)
+	assert.Equal(t, "CN=No Birthday,OU=Users,DC=corp: validation failed: birthday is required", report.Rejected[0])
+	assert.Equal(t, "CN=No GUID,OU=Users,DC=corp: no objectGUID", report.Rejected[1])
+	assert.Equal(t, "CN=Ivan Copy,OU=Users,DC=corp: duplicate objectGUID guid-1", report.Rejected[2])
+	assert.Equal(t, 3, server.pages, "5 users are read in pages of 2")
+
+	cl, err := repo.Select(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Client{ID: 1, FIO: "Ivan Ivanov", Login: "ivanov", Birthday: "19900501", Email: "ivanov@corp.example", Status: StatusActive}, cl)
+	cl, err = repo.Select(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "19850312", cl.Birthday)
+
+	// Повторная синхронизация без изменений в каталоге ничего не меняет
+	server.setEntries(ivanov, petrova)
+	report, err = directory.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, DirectorySyncReport{Skipped: 2}, report)
+
+	// Переименованный пользователь остаётся тем же клиентом, а поля,
+	// изменённые в БД, возвращаются к значениям каталога
+	renamed := adUser("guid-1", "Ivan Sidorov", "sidorov", "birthDate", "1990-05-01")
+	cl.Email = "local@mail.com"
+	require.NoError(t, repo.Update(ctx, cl))
+	server.setEntries(renamed, petrova)
+	report, err = directory.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, DirectorySyncReport{Updated: 2}, report)
+	cl, err = repo.Select(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Ivan Sidorov", cl.FIO)
+	cl, err = repo.Select(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "petrova@corp.example", cl.Email)
+	assertRowCount(t, repo.db, "clients", 2, "1")
+	entries, err := repo.AuditLog(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, AuditUpdate, entries[len(entries)-1].Operation)
+
+	assert.Equal(t, "(objectClass=person)", server.filters[0])
+	assert.Equal(t, []string(nil), server.attributes[0], "all attributes are read by default")
+}
+
+// Тест проверяет связь клиента с пользователем каталога при удалении,
+// объединении и удалении данных клиента
+func Test_DirectorySync_Links(t *testing.T) {
+	ctx := context.Background()
+	first := adUser("guid-1", "First User", "first", "birthDate", "19900101")
+	second := adUser("guid-2", "Second User", "second", "birthDate", "19900101")
+	repo, _, directory := setupDirectory(t, first, second)
+
+	_, err := directory.Sync(ctx)
+	require.NoError(t, err)
+	assertRowCount(t, repo.db, "directory_links", 2, "source = 'corp'")
+
+	// Объединённый дубликат: пользователь каталога следует за оставшимся
+	// клиентом
+	require.NoError(t, repo.MergeClients(ctx, 1, 2))
+	assertRowCount(t, repo.db, "directory_links", 2, "client_id = 1")
+	report, err := directory.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Updated, "client 1 takes the fields of the last user")
+	assert.Zero(t, report.Created)
+
+	// Удалённый клиент добавляется заново: каталог — источник истины
+	_, err = repo.EraseClient(ctx, 1)
+	require.NoError(t, err)
+	assertRowCount(t, repo.db, "directory_links", 0, "1")
+	report, err = directory.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	require.NoError(t, repo.Delete(ctx, 3))
+	assertRowCount(t, repo.db, "directory_links", 1, "1")
+}
+
+// Тест проверяет ошибки синхронизации: неверный пароль, недоступный
+// каталог и неполное сопоставление атрибутов не меняют БД
+func Test_DirectorySync_Errors(t *testing.T) {
+	ctx := context.Background()
+	repo, server, _ := setupDirectory(t, adUser("guid-1", "User", "user", "birthDate", "19900101"))
+
+	dir := NewLDAPDirectory(LDAPConfig{URL: server.URL(), BindDN: server.bindDN, BindPassword: "wrong", BaseDN: "DC=corp"})
+	_, err := NewDirectorySync(repo, "corp", dir, ActiveDirectoryMapping).Sync(ctx)
+	require.ErrorContains(t, err, "bind as cn=sync,dc=corp")
+	var ldapErr *ldap.Error
+	require.True(t, errors.As(err, &ldapErr))
+	assert.EqualValues(t, ldapInvalidCredentials, ldapErr.ResultCode)
+
+	dir = NewLDAPDirectory(LDAPConfig{URL: "ldap://127.0.0.1:1", BaseDN: "DC=corp"})
+	_, err = NewDirectorySync(repo, "corp", dir, ActiveDirectoryMapping).Sync(ctx)
+	require.ErrorContains(t, err, "read directory corp")
+
+	_, err = NewDirectorySync(repo, "corp", dir, DirectoryMapping{ExternalID: "objectGUID"}).Sync(ctx)
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = NewDirectorySync(repo, " ", dir, ActiveDirectoryMapping).Sync(ctx)
+	require.ErrorIs(t, err, ErrValidation)
+
+	assertRowCount(t, repo.db, "clients", 0, "1")
+}
+
+// Тест проверяет чтение каталога: двоичный objectGUID возвращается в
+// шестнадцатеричном виде, в запросе передаются фильтр и атрибуты, имена
+// атрибутов сравниваются без учёта регистра
+func Test_LDAPDirectory_Users(t *testing.T) {
+	ctx := context.Background()
+	guid := string([]byte{0x9f, 0x01, 0xfe, 0x00})
+	server := newFakeLDAP(t, adUser(guid, "User", "user", "MAIL", "upper@corp.example"))
+	delete(server.entries[0].Attributes, "mail")
+
+	dir := NewLDAPDirectory(LDAPConfig{
+		URL:        server.URL(),
+		BaseDN:     "DC=corp",
+		Filter:     "(&(objectCategory=person)(objectClass=user))",
+		Attributes: ActiveDirectoryMapping.attributes(),
+	})
+	entries, err := dir.Users(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "CN=User,OU=Users,DC=corp", entries[0].DN)
+	assert.Equal(t, "9f01fe00", entries[0].value("objectGUID"))
+	assert.Equal(t, "upper@corp.example", entries[0].value("mail"))
+	assert.Equal(t, "(&(objectCategory=person)(objectClass=user))", server.filters[0])
+	assert.Equal(t, []string{"objectGUID", "displayName", "sAMAccountName", "mail"}, server.attributes[0])
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = dir.Users(canceled)
+	require.ErrorIs(t, err, context.Canceled)
+}
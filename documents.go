@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxDocumentSize — наибольший размер документа в байтах.
+	maxDocumentSize = 10 << 20
+	// maxDocumentNameLen — наибольшая длина имени документа в символах.
+	maxDocumentNameLen = 255
+)
+
+// documentTypes — допустимые MIME-типы документов. Заявленный тип должен
+// совпадать с определённым по содержимому (http.DetectContentType).
+var documentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"text/plain":      true,
+}
+
+// Document — метаданные документа клиента. Содержимое хранится в BlobStore
+// репозитория (см. WithBlobStore).
+type Document struct {
+	ID        int       `json:"id"`
+	ClientID  int       `json:"client_id"`
+	Name      string    `json:"name"`
+	MIMEType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WithBlobStore задаёт хранилище содержимого документов клиентов.
+func WithBlobStore(store BlobStore) Option {
+	return func(r *Repository) {
+		r.blobs = store
+	}
+}
+
+// documentColumns — столбцы client_documents в порядке, ожидаемом scanDocument.
+const documentColumns = "id, client_id, name, mime_type, size, created_at"
+
+// DocumentRepository — слой доступа к документам клиентов. Как и
+// OrderRepository, использует соединение и настройки создавшего его
+// Repository; документы доступны только вместе с их клиентом.
+type DocumentRepository struct {
+	r *Repository
+}
+
+// Documents возвращает репозиторий документов клиентов r.
+func (r *Repository) Documents() *DocumentRepository {
+	return &DocumentRepository{r: r}
+}
+
+// Upload сохраняет документ name типа mimeType с содержимым src клиенту
+// clientID. Содержимое записывается в хранилище до метаданных и удаляется,
+// если метаданные сохранить не удалось.
+func (d *DocumentRepository) Upload(ctx context.Context, clientID int, name, mimeType string, src io.Reader) (_ Document, err error) {
+	ctx, end := d.r.startClientOperation(ctx, "document_upload", clientID)
+	defer end(&err)
+
+	if d.r.blobs == nil {
+		return Document{}, ErrNoBlobStore
+	}
+	if err := validateDocumentName(name); err != nil {
+		return Document{}, err
+	}
+
+	br := bufio.NewReaderSize(src, 512)
+	if err := validateDocumentType(mimeType, br); err != nil {
+		return Document{}, err
+	}
+
+	key, err := newBlobKey(clientID)
+	if err != nil {
+		return Document{}, err
+	}
+
+	// Читается на байт больше допустимого, чтобы отличить файл предельного
+	// размера от слишком большого
+	size, err := d.r.blobs.Put(ctx, key, io.LimitReader(br, maxDocumentSize+1))
+	if err == nil && size > maxDocumentSize {
+		err = fmt.Errorf("%w: document is larger than %d bytes", ErrValidation, maxDocumentSize)
+	}
+	if err != nil {
+		return Document{}, errors.Join(err, d.r.blobs.Delete(context.WithoutCancel(ctx), key))
+	}
+
+	doc := Document{
+		ClientID:  clientID,
+		Name:      name,
+		MIMEType:  mimeType,
+		Size:      size,
+		CreatedAt: d.r.now(),
+	}
+	err = d.r.inTx(ctx, func(q querier) error {
+		if err := d.r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		res, err := q.ExecContext(ctx, `INSERT INTO client_documents (client_id, name, mime_type, size, blob_key, created_at)
+			VALUES (:client_id, :name, :mime_type, :size, :blob_key, :created_at)`,
+			sql.Named("client_id", doc.ClientID),
+			sql.Named("name", doc.Name),
+			sql.Named("mime_type", doc.MIMEType),
+			sql.Named("size", doc.Size),
+			sql.Named("blob_key", key),
+			sql.Named("created_at", doc.CreatedAt.Format(time.RFC3339Nano)))
+		if err != nil {
+			return err
+		}
+
+		id, err := res.LastInsertId()
+		doc.ID = int(id)
+		return err
+	})
+	if err != nil {
+		return Document{}, errors.Join(err, d.r.blobs.Delete(context.WithoutCancel(ctx), key))
+	}
+
+	return doc, nil
+}
+
+// Download возвращает метаданные и содержимое документа или
+// ErrDocumentNotFound, если документа нет. Содержимое закрывает вызывающий.
+func (d *DocumentRepository) Download(ctx context.Context, id int) (_ Document, _ io.ReadCloser, err error) {
+	ctx, end := d.r.startOperation(ctx, "document_download")
+	defer end(&err)
+
+	if d.r.blobs == nil {
+		return Document{}, nil, ErrNoBlobStore
+	}
+
+	scope, args := d.r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	var key string
+	row := d.r.conn().QueryRowContext(ctx, "SELECT "+documentColumns+", blob_key FROM client_documents WHERE id = :id AND client_id IN (SELECT id FROM clients WHERE 1"+notDeleted+scope+")", args...)
+	doc, err := scanDocument(row, &key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Document{}, nil, fmt.Errorf("%w: %d", ErrDocumentNotFound, id)
+	}
+	if err != nil {
+		return Document{}, nil, err
+	}
+
+	content, err := d.r.blobs.Get(ctx, key)
+	if err != nil {
+		return Document{}, nil, err
+	}
+
+	return doc, content, nil
+}
+
+// ByClient возвращает документы клиента в порядке загрузки или
+// ErrClientNotFound, если клиента нет.
+func (d *DocumentRepository) ByClient(ctx context.Context, clientID int) (_ []Document, err error) {
+	ctx, end := d.r.startClientOperation(ctx, "documents_by_client", clientID)
+	defer end(&err)
+
+	var docs []Document
+	err = d.r.inTx(ctx, func(q querier) error {
+		if err := d.r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		rows, err := q.QueryContext(ctx, "SELECT "+documentColumns+" FROM client_documents WHERE client_id = :client_id ORDER BY id", sql.Named("client_id", clientID))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			doc, err := scanDocument(rows)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+		}
+
+		return rows.Err()
+	})
+
+	return docs, err
+}
+
+// Delete удаляет документ по ID или возвращает ErrDocumentNotFound, если
+// его нет. Содержимое удаляется из хранилища после удаления метаданных.
+func (d *DocumentRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, end := d.r.startOperation(ctx, "document_delete")
+	defer end(&err)
+
+	scope, args := d.r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	var keys []string
+	err = d.r.inTx(ctx, func(q querier) error {
+		keys, err = queryStrings(ctx, q, "DELETE FROM client_documents WHERE id = :id AND client_id IN (SELECT id FROM clients WHERE 1"+notDeleted+scope+") RETURNING blob_key", args...)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("%w: %d", ErrDocumentNotFound, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.r.deleteBlobs(ctx, keys)
+}
+
+// deleteBlobs удаляет из хранилища содержимое удалённых документов.
+// Вызывается после фиксации удаления метаданных: оставшийся при ошибке
+// файл безвреден, а метаданные без содержимого — нет.
+func (r *Repository) deleteBlobs(ctx context.Context, keys []string) error {
+	if r.blobs == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, key := range keys {
+		if err := r.blobs.Delete(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("deleting document content %s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateDocumentName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("%w: document name is required", ErrValidation)
+	case !utf8.ValidString(name):
+		return fmt.Errorf("%w: document name is not valid UTF-8", ErrValidation)
+	case utf8.RuneCountInString(name) > maxDocumentNameLen:
+		return fmt.Errorf("%w: document name is longer than %d characters", ErrValidation, maxDocumentNameLen)
+	}
+
+	return nil
+}
+
+// validateDocumentType проверяет, что заявленный тип допустим и совпадает
+// с типом, определённым по началу содержимого br. Пустой документ
+// не принимается.
+func validateDocumentType(mimeType string, br *bufio.Reader) error {
+	if !documentTypes[mimeType] {
+		return fmt.Errorf("%w: document type %q is not allowed", ErrValidation, mimeType)
+	}
+
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if len(head) == 0 {
+		return fmt.Errorf("%w: document is empty", ErrValidation)
+	}
+
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return err
+	}
+	if detected != mimeType {
+		return fmt.Errorf("%w: document content is %s, not %s", ErrValidation, detected, mimeType)
+	}
+
+	return nil
+}
+
+// newBlobKey возвращает новый случайный ключ содержимого документа клиента.
+func newBlobKey(clientID int) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("clients/%d/%s", clientID, hex.EncodeToString(b)), nil
+}
+
+// scanDocument читает строку документа; столбцы, выбранные после
+// documentColumns, читаются в extra.
+func scanDocument(row rowScanner, extra ...any) (Document, error) {
+	var (
+		doc       Document
+		createdAt string
+	)
+	dest := append([]any{&doc.ID, &doc.ClientID, &doc.Name, &doc.MIMEType, &doc.Size, &createdAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return Document{}, err
+	}
+
+	var err error
+	doc.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+
+	return doc, err
+}
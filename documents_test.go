@@ -0,0 +1,194 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPDF — минимальное содержимое, которое определяется как application/pdf
+const testPDF = "%PDF-1.4\n%test document\n"
+
+// setupDocuments возвращает репозиторий в тестовой транзакции с хранилищем
+// документов во временном каталоге
+func setupDocuments(t *testing.T, opts ...Option) (*Repository, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := NewFSBlobStore(dir)
+	require.NoError(t, err)
+	_, repo := setupTestTx(t, append(opts, WithBlobStore(store))...)
+
+	return repo, dir
+}
+
+// blobFiles возвращает файлы содержимого в каталоге хранилища
+func blobFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+
+	return files
+}
+
+// Тест проверяет загрузку, список, скачивание и удаление документов клиента
+func Test_Documents_UploadDownloadDelete(t *testing.T) {
+	t.Parallel()
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo, dir := setupDocuments(t, WithClock(testutil.NewFakeClock(createdAt)))
+	docs := repo.Documents()
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	contract, err := docs.Upload(ctx, id, "contract.pdf", "application/pdf", strings.NewReader(testPDF))
+	require.NoError(t, err)
+	assert.Equal(t, Document{ID: contract.ID, ClientID: id, Name: "contract.pdf", MIMEType: "application/pdf", Size: int64(len(testPDF)), CreatedAt: createdAt}, contract)
+	note, err := docs.Upload(ctx, id, "note.txt", "text/plain", strings.NewReader("call back on Monday"))
+	require.NoError(t, err)
+
+	list, err := docs.ByClient(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, []Document{contract, note}, list)
+
+	got, content, err := docs.Download(ctx, contract.ID)
+	require.NoError(t, err)
+	data, err := io.ReadAll(content)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+	assert.Equal(t, contract, got)
+	assert.Equal(t, testPDF, string(data))
+
+	require.NoError(t, docs.Delete(ctx, contract.ID))
+	_, _, err = docs.Download(ctx, contract.ID)
+	require.ErrorIs(t, err, ErrDocumentNotFound)
+	require.ErrorIs(t, docs.Delete(ctx, contract.ID), ErrDocumentNotFound)
+	assert.Len(t, blobFiles(t, dir), 1, "content of the deleted document should be removed")
+}
+
+// Тест проверяет проверку имени, типа и размера документа; отклонённый
+// документ не оставляет ни метаданных, ни содержимого
+func Test_Documents_Validation(t *testing.T) {
+	t.Parallel()
+	repo, dir := setupDocuments(t)
+	docs := repo.Documents()
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	tooLarge := io.MultiReader(strings.NewReader(testPDF), bytes.NewReader(make([]byte, maxDocumentSize)))
+	atLimit := io.MultiReader(strings.NewReader(testPDF), bytes.NewReader(make([]byte, maxDocumentSize-len(testPDF))))
+
+	tests := []struct {
+		name     string
+		clientID int
+		docName  string
+		mimeType string
+		content  io.Reader
+		wantErr  error
+	}{
+		{"EmptyName", 0, " ", "text/plain", strings.NewReader("text"), ErrValidation},
+		{"LongName", 0, strings.Repeat("я", maxDocumentNameLen+1), "text/plain", strings.NewReader("text"), ErrValidation},
+		{"UnknownType", 0, "run.exe", "application/x-msdownload", strings.NewReader("MZ"), ErrValidation},
+		{"TypeMismatch", 0, "photo.png", "image/png", strings.NewReader(testPDF), ErrValidation},
+		{"Empty", 0, "empty.txt", "text/plain", strings.NewReader(""), ErrValidation},
+		{"TooLarge", 0, "large.pdf", "application/pdf", tooLarge, ErrValidation},
+		{"MissingClient", -1, "contract.pdf", "application/pdf", strings.NewReader(testPDF), ErrClientNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientID := tt.clientID
+			if clientID == 0 {
+				clientID = id
+			}
+
+			_, err := docs.Upload(ctx, clientID, tt.docName, tt.mimeType, tt.content)
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Empty(t, blobFiles(t, dir))
+			list, err := docs.ByClient(ctx, id)
+			require.NoError(t, err)
+			assert.Empty(t, list)
+		})
+	}
+
+	doc, err := docs.Upload(ctx, id, "limit.pdf", "application/pdf", atLimit)
+	require.NoError(t, err, "document of the maximum size should be accepted")
+	assert.EqualValues(t, maxDocumentSize, doc.Size)
+}
+
+// Тест проверяет, что удаление и право на забвение удаляют документы
+// клиента вместе с содержимым, а объединение переносит их
+func Test_Documents_CleanupWithClient(t *testing.T) {
+	t.Parallel()
+	repo, dir := setupDocuments(t)
+	docs := repo.Documents()
+
+	ctx := context.Background()
+
+	upload := func(clientID int) Document {
+		t.Helper()
+		doc, err := docs.Upload(ctx, clientID, "contract.pdf", "application/pdf", strings.NewReader(testPDF))
+		require.NoError(t, err)
+		return doc
+	}
+
+	ids := make([]int, 4)
+	var err error
+	for i := range ids {
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		upload(ids[i])
+	}
+	deleted, erased, keep, dup := ids[0], ids[1], ids[2], ids[3]
+	require.Len(t, blobFiles(t, dir), 4)
+
+	require.NoError(t, repo.Delete(ctx, deleted))
+	assert.Len(t, blobFiles(t, dir), 3)
+
+	receipt, err := repo.EraseClient(ctx, erased)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, receipt.Deleted["client_documents"])
+	assert.Len(t, blobFiles(t, dir), 2)
+
+	require.NoError(t, repo.MergeClients(ctx, keep, dup))
+	list, err := docs.ByClient(ctx, keep)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Len(t, blobFiles(t, dir), 2)
+}
+
+// Тест проверяет, что без хранилища операции с документами недоступны
+func Test_Documents_NoBlobStore(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	_, err := repo.Documents().Upload(ctx, 1, "contract.pdf", "application/pdf", strings.NewReader(testPDF))
+	require.ErrorIs(t, err, ErrNoBlobStore)
+	_, _, err = repo.Documents().Download(ctx, 1)
+	require.ErrorIs(t, err, ErrNoBlobStore)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// errDryRun откатывает транзакцию пробного выполнения.
+var errDryRun = errors.New("dry run")
+
+// DestructiveOptions — параметры операций, удаляющих или переносящих данные
+// клиентов: DeleteClients, MergeClientsWith и PurgeSoftDeleted.
+type DestructiveOptions struct {
+	// DryRun выполняет операцию в транзакции, которая затем откатывается:
+	// результат описывает строки, которые были бы затронуты, а БД и
+	// хранилище документов не меняются.
+	DryRun bool
+}
+
+// Affected — строки, затронутые операцией DeleteClients, MergeClientsWith
+// или UpdateClientsWhere.
+type Affected struct {
+	// ClientIDs — удалённые клиенты в порядке обработки; для
+	// MergeClientsWith — мягко удалённые дубликаты, для UpdateClientsWhere —
+	// изменённые клиенты.
+	ClientIDs []int `json:"client_ids"`
+	// Rows — число удалённых, перенесённых или изменённых строк по
+	// таблицам. Записи журнала аудита и clients_history не учитываются.
+	Rows map[string]int64 `json:"rows"`
+}
+
+// inTxDryRun выполняет fn как inTx, а при dryRun откатывает транзакцию
+// после успешного выполнения fn. fn может выполняться повторно (см.
+// WithBusyRetry), поэтому должна заново заполнять свои результаты.
+func (r *Repository) inTxDryRun(ctx context.Context, dryRun bool, fn func(q querier) error) error {
+	err := r.inTx(ctx, func(q querier) error {
+		if err := fn(q); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+
+	return err
+}
+
+// countRows добавляет в rows число строк, затронутых запросом в таблице
+// table. Таблицы без затронутых строк не добавляются; при rows == nil
+// ничего не делает.
+func countRows(rows map[string]int64, table string, res sql.Result) error {
+	if rows == nil {
+		return nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		rows[table] += n
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dumpDB возвращает содержимое всех таблиц БД, чтобы тест мог проверить,
+// что операция ничего не записала. Строки каждой таблицы отсортированы.
+func dumpDB(t *testing.T, db *sql.DB) map[string][]string {
+	t.Helper()
+
+	ctx := context.Background()
+	tables, err := queryStrings(ctx, db, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	require.NoError(t, err)
+
+	dump := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %q", table))
+		require.NoError(t, err)
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+
+		lines := []string{}
+		for rows.Next() {
+			values := make([]any, len(columns))
+			ptrs := make([]any, len(columns))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			require.NoError(t, rows.Scan(ptrs...))
+			lines = append(lines, fmt.Sprintf("%q", values))
+		}
+		require.NoError(t, rows.Err())
+		rows.Close()
+
+		sort.Strings(lines)
+		dump[table] = lines
+	}
+
+	return dump
+}
+
+// countFiles возвращает число файлов в каталоге dir и его подкаталогах.
+func countFiles(t *testing.T, dir string) int {
+	t.Helper()
+
+	var n int
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			n++
+		}
+		return err
+	})
+	require.NoError(t, err)
+
+	return n
+}
+
+// Тест проверяет, что пробное удаление ничего не меняет в БД и хранилище
+// документов и описывает те же строки, что удаляет настоящее
+func Test_DeleteClients_DryRun(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	blobDir := t.TempDir()
+	store, err := NewFSBlobStore(blobDir)
+	require.NoError(t, err)
+	repo := NewRepository(db, WithBlobStore(store))
+
+	ids := make([]int, 3)
+	for i := range ids {
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	_, err = repo.AddNote(ctx, ids[0], "first")
+	require.NoError(t, err)
+	_, err = repo.AddNote(ctx, ids[1], "second")
+	require.NoError(t, err)
+	require.NoError(t, repo.TagClient(ctx, ids[0], "vip"))
+	_, err = repo.Documents().Upload(ctx, ids[0], "contract.txt", "text/plain", strings.NewReader("contract"))
+	require.NoError(t, err)
+	_, err = repo.Orders().Create(ctx, Order{ClientID: ids[2], Amount: 100})
+	require.NoError(t, err)
+
+	before := dumpDB(t, db)
+	want := Affected{
+		ClientIDs: []int{ids[0], ids[1]},
+		Rows:      map[string]int64{"clients": 2, "client_notes": 2, "client_tags": 1, "client_documents": 1},
+	}
+
+	affected, err := repo.DeleteClients(ctx, ids[:2], DestructiveOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, want, affected)
+	assert.Equal(t, before, dumpDB(t, db), "dry run must not write")
+	assert.Equal(t, 1, countFiles(t, blobDir), "dry run must not delete documents")
+
+	// Ошибка для любого клиента откатывает удаление всех
+	_, err = repo.DeleteClients(ctx, ids, DestructiveOptions{DryRun: true})
+	require.ErrorIs(t, err, ErrClientHasOrders)
+	_, err = repo.DeleteClients(ctx, ids, DestructiveOptions{})
+	require.ErrorIs(t, err, ErrClientHasOrders)
+	assert.Equal(t, before, dumpDB(t, db))
+
+	affected, err = repo.DeleteClients(ctx, ids[:2], DestructiveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, want, affected)
+	assertRowCount(t, db, "clients", 1, "1")
+	assert.Zero(t, countFiles(t, blobDir))
+
+	_, err = repo.DeleteClients(ctx, []int{ids[2], ids[2]}, DestructiveOptions{})
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+// Тест проверяет, что пробное объединение ничего не меняет и описывает те
+// же строки, что переносит настоящее
+func Test_MergeClientsWith_DryRun(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	ids := make([]int, 3)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		require.NoError(t, repo.TagClient(ctx, ids[i], "vip"))
+	}
+	require.NoError(t, repo.TagClient(ctx, ids[2], "new"))
+	_, err := repo.Orders().Create(ctx, Order{ClientID: ids[1], Amount: 100})
+	require.NoError(t, err)
+	_, err = repo.AddNote(ctx, ids[2], "note")
+	require.NoError(t, err)
+
+	before := dumpDB(t, db)
+	want := Affected{
+		ClientIDs: []int{ids[1], ids[2]},
+		Rows:      map[string]int64{"clients": 2, "orders": 1, "client_notes": 1, "client_tags": 3},
+	}
+
+	affected, err := repo.MergeClientsWith(ctx, ids[0], ids[1:], DestructiveOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, want, affected)
+	assert.Equal(t, before, dumpDB(t, db), "dry run must not write")
+
+	affected, err = repo.MergeClientsWith(ctx, ids[0], ids[1:], DestructiveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, want, affected)
+	assertRowCount(t, db, "clients", 2, "deleted_at != ''")
+	tags, err := repo.ClientTags(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new", "vip"}, tags)
+}
+
+// Тест проверяет, что пробная очистка ничего не удаляет и отбирает тех же
+// клиентов, что и настоящая
+func Test_PurgeSoftDeleted_DryRun(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := NewRepository(db, WithClock(clock))
+
+	ids := make([]int, 4)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.MergeClients(ctx, ids[0], ids[1], ids[2], ids[3]))
+	require.NoError(t, repo.SetLegalHold(ctx, ids[2], true))
+	clock.Advance(DefaultRetention)
+
+	policy := RetentionPolicy{SoftDeletedFor: DefaultRetention}
+	before := dumpDB(t, db)
+	planned, err := repo.PurgeSoftDeleted(ctx, policy, DestructiveOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, before, dumpDB(t, db), "dry run must not write")
+
+	purged, err := repo.PurgeSoftDeleted(ctx, policy, DestructiveOptions{})
+	require.NoError(t, err)
+	require.Len(t, planned, 2)
+	require.Len(t, purged, 2)
+	for i := range purged {
+		assert.Zero(t, planned[i].ID)
+		assert.NotZero(t, purged[i].ID)
+		planned[i].ID = purged[i].ID
+	}
+	assert.Equal(t, purged, planned)
+	assert.Equal(t, []int{ids[1], ids[3]}, []int{purged[0].ClientID, purged[1].ClientID})
+}
+
+// Тест проверяет, что пробная загрузка ничего не записывает
+func Test_ImportWith_DryRunWritesNothing(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	src := "fio,login,birthday,email\nИванов Иван,ivanov,19850615,ivanov@mail.com\n"
+	before := dumpDB(t, db)
+	n, err := repo.ImportWith(ctx, strings.NewReader(src), ImportOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, before, dumpDB(t, db), "dry run must not write")
+
+	n, err = repo.ImportWith(ctx, strings.NewReader(src), ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assertRowCount(t, db, "clients", 1, "login = ?", "ivanov")
+}
@@ -0,0 +1,59 @@
+//go:build elasticsearch
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тесты синхронизации с Elasticsearch запускаются с тегом сборки
+// elasticsearch против запущенного контейнера:
+//
+//	docker run -d -p 9200:9200 -e discovery.type=single-node -e xpack.security.enabled=false elasticsearch:8.13.0
+//	go test -tags elasticsearch -run Container ./...
+//
+// Тот же тест проходит с OpenSearch. Адрес задаётся переменной окружения
+// ElasticURLEnv.
+const ElasticURLEnv = "CLIENTS_TEST_ELASTIC_URL"
+
+// Тест проверяет переиндексацию, синхронизацию изменений и удалений и
+// сверку индекса с БД на настоящем кластере
+func Test_ElasticSync_Container(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+	index := fmt.Sprintf("clients-test-%d", time.Now().UnixNano())
+	mirror := NewElasticSync(repo, envOr(ElasticURLEnv, "http://localhost:9200"), index, ElasticConfig{Batch: 10})
+	t.Cleanup(func() { mirror.request(context.Background(), "DELETE", "/"+index, nil, nil) })
+
+	ids := make([]int, 25)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err, "Elasticsearch should be running")
+	}
+	n, err := mirror.Reindex(ctx)
+	require.NoError(t, err, "Elasticsearch should be running")
+	assert.Equal(t, len(ids), n)
+
+	cl, err := repo.Select(ctx, ids[0])
+	require.NoError(t, err)
+	cl.Email = "changed@mail.com"
+	require.NoError(t, repo.Update(ctx, cl))
+	require.NoError(t, repo.Delete(ctx, ids[1]))
+	n, err = mirror.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	report, err := mirror.Check(ctx, len(ids))
+	require.NoError(t, err)
+	assert.True(t, report.Consistent(), "%+v", report)
+	assert.Equal(t, len(ids)-1, report.IndexCount)
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeElastic — кластер Elasticsearch в памяти с API, которое использует
+// ElasticSync: создание и удаление индекса, _bulk, _refresh, _count и
+// _mget.
+type fakeElastic struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	indices map[string]map[string]json.RawMessage
+	bulks   int
+	// overloaded — сколько следующих запросов _bulk отклоняется с 429.
+	overloaded int
+	// rejectItems — сколько следующих запросов _bulk выполняется с
+	// ошибкой 429 для каждого документа.
+	rejectItems int
+}
+
+func newFakeElastic(t *testing.T) *fakeElastic {
+	t.Helper()
+
+	es := &fakeElastic{indices: make(map[string]map[string]json.RawMessage)}
+	es.Server = httptest.NewServer(http.HandlerFunc(es.serve))
+	t.Cleanup(es.Close)
+
+	return es
+}
+
+func (es *fakeElastic) serve(w http.ResponseWriter, req *http.Request) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	index, docs := path[0], es.indices[path[0]]
+	switch {
+	case req.Method == http.MethodPost && path[0] == "_bulk":
+		es.bulk(w, req)
+	case docs == nil && req.Method != http.MethodPut:
+		http.Error(w, `{"error":{"type":"index_not_found_exception"}}`, http.StatusNotFound)
+	case len(path) == 1 && req.Method == http.MethodPut:
+		if docs != nil {
+			http.Error(w, `{"error":{"type":"resource_already_exists_exception"}}`, http.StatusBadRequest)
+			return
+		}
+		es.indices[index] = make(map[string]json.RawMessage)
+		w.Write([]byte(`{"acknowledged":true}`))
+	case len(path) == 1 && req.Method == http.MethodDelete:
+		delete(es.indices, index)
+		w.Write([]byte(`{"acknowledged":true}`))
+	case path[1] == "_refresh":
+		w.Write([]byte(`{}`))
+	case path[1] == "_count":
+		json.NewEncoder(w).Encode(map[string]int{"count": len(docs)})
+	case path[1] == "_mget":
+		var body struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		res := make([]map[string]any, len(body.IDs))
+		for i, id := range body.IDs {
+			source, ok := docs[id]
+			res[i] = map[string]any{"_id": id, "found": ok}
+			if ok {
+				res[i]["_source"] = source
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"docs": res})
+	default:
+		http.Error(w, "unexpected request "+req.Method+" "+req.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func (es *fakeElastic) bulk(w http.ResponseWriter, req *http.Request) {
+	es.bulks++
+	if es.overloaded > 0 {
+		es.overloaded--
+		http.Error(w, `{"error":"rejected"}`, http.StatusTooManyRequests)
+		return
+	}
+	reject := es.rejectItems > 0
+	if reject {
+		es.rejectItems--
+	}
+
+	var items []map[string]any
+	lines := bufio.NewScanner(req.Body)
+	for lines.Scan() {
+		var meta map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if err := json.Unmarshal(lines.Bytes(), &meta); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for action, target := range meta {
+			status := http.StatusOK
+			docs := es.indices[target.Index]
+			if docs == nil {
+				// Как и Elasticsearch, индекс создаётся при первой записи
+				docs = make(map[string]json.RawMessage)
+				es.indices[target.Index] = docs
+			}
+			switch action {
+			case "index":
+				lines.Scan()
+				if !reject {
+					docs[target.ID] = append(json.RawMessage(nil), lines.Bytes()...)
+				}
+			case "delete":
+				if _, ok := docs[target.ID]; !ok {
+					status = http.StatusNotFound
+				}
+				if !reject {
+					delete(docs, target.ID)
+				}
+			}
+			if reject {
+				status = http.StatusTooManyRequests
+			}
+			items = append(items, map[string]any{action: map[string]any{"_id": target.ID, "status": status}})
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"errors": reject || hasNotFound(items), "items": items})
+}
+
+func hasNotFound(items []map[string]any) bool {
+	for _, item := range items {
+		for _, result := range item {
+			if result.(map[string]any)["status"] == http.StatusNotFound {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// docs возвращает документы индекса index.
+func (es *fakeElastic) docs(index string) map[string]elasticDocument {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	docs := make(map[string]elasticDocument)
+	for id, source := range es.indices[index] {
+		var doc elasticDocument
+		if err := json.Unmarshal(source, &doc); err == nil {
+			docs[id] = doc
+		}
+	}
+
+	return docs
+}
+
+// setupElastic возвращает репозиторий над БД в памяти, кластер в памяти и
+// синхронизацию с индексом clients с короткими паузами между повторами.
+func setupElastic(t *testing.T) (*Repository, *fakeElastic, *ElasticSync) {
+	t.Helper()
+
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+	repo := NewRepository(db)
+	es := newFakeElastic(t)
+
+	return repo, es, NewElasticSync(repo, es.URL, "clients", ElasticConfig{Backoff: time.Millisecond, MaxAttempts: 3, Batch: 2})
+}
+
+// Тест проверяет, что синхронизация отправляет изменённых и удаляет
+// удалённых клиентов по событиям outbox и продолжает со своей позиции
+func Test_ElasticSync_Sync(t *testing.T) {
+	ctx := context.Background()
+	repo, es, mirror := setupElastic(t)
+
+	ids := make([]int, 3)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	cl, err := repo.Select(ctx, ids[0])
+	require.NoError(t, err)
+	cl.FIO = "Updated"
+	require.NoError(t, repo.Update(ctx, cl))
+
+	// По Batch = 2 событий за синхронизацию
+	for _, want := range []int{2, 2, 0} {
+		n, err := mirror.Sync(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, want, n)
+	}
+	docs := es.docs("clients")
+	require.Len(t, docs, 3)
+	assert.Equal(t, elasticDocument{FIO: "Updated", Login: "Test", Email: "mail@mail.com", Status: StatusActive}, docs["1"])
+	assert.Equal(t, 2, es.bulks)
+
+	require.NoError(t, repo.Delete(ctx, ids[1]))
+	n, err := mirror.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NotContains(t, es.docs("clients"), "2")
+
+	// Повтор удаления уже удалённого документа не считается ошибкой
+	_, err = repo.db.Exec("UPDATE sync_cursors SET position = 0")
+	require.NoError(t, err)
+	for n := 1; n > 0; {
+		n, err = mirror.Sync(ctx)
+		require.NoError(t, err)
+	}
+	assert.Len(t, es.docs("clients"), 2)
+}
+
+// Тест проверяет повтор запроса bulk с паузами, когда кластер перегружен
+// целиком или отклоняет отдельные документы, и сохранение позиции, если
+// попытки исчерпаны
+func Test_ElasticSync_Backoff(t *testing.T) {
+	ctx := context.Background()
+	repo, es, mirror := setupElastic(t)
+	_, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	es.overloaded = 1
+	es.rejectItems = 1
+	n, err := mirror.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 3, es.bulks)
+	assert.Len(t, es.docs("clients"), 1)
+
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	es.overloaded = 3
+	_, err = mirror.Sync(ctx)
+	require.ErrorContains(t, err, "status 429")
+	assert.Len(t, es.docs("clients"), 1)
+
+	// Событие не потеряно: следующая синхронизация отправляет его
+	n, err = mirror.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, es.docs("clients"), 2)
+}
+
+// Тест проверяет переиндексацию: индекс пересоздаётся со всеми клиентами
+// без устаревших документов, а синхронизация продолжает с событий,
+// записанных после неё
+func Test_ElasticSync_Reindex(t *testing.T) {
+	ctx := context.Background()
+	repo, es, mirror := setupElastic(t)
+
+	for i := 0; i < 5; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.Delete(ctx, 5))
+	es.indices["clients"] = map[string]json.RawMessage{"100": json.RawMessage(`{"fio":"stale"}`)}
+
+	n, err := mirror.Reindex(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	docs := es.docs("clients")
+	assert.Len(t, docs, 4)
+	assert.NotContains(t, docs, "100")
+
+	n, err = mirror.Sync(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	n, err = mirror.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, es.docs("clients"), 5)
+}
+
+// Тест проверяет сверку индекса с БД по числу документов и выборке
+// документов
+func Test_ElasticSync_Check(t *testing.T) {
+	ctx := context.Background()
+	repo, es, mirror := setupElastic(t)
+	for i := 0; i < 4; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	_, err := mirror.Reindex(ctx)
+	require.NoError(t, err)
+
+	report, err := mirror.Check(ctx, 10)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, ElasticReport{DBCount: 4, IndexCount: 4, Sampled: 4}, report)
+
+	// Изменение без синхронизации и потерянный документ
+	cl, err := repo.Select(ctx, 2)
+	require.NoError(t, err)
+	cl.Email = "changed@mail.com"
+	require.NoError(t, repo.Update(ctx, cl))
+	es.mu.Lock()
+	delete(es.indices["clients"], "3")
+	es.mu.Unlock()
+
+	report, err = mirror.Check(ctx, 10)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, 3, report.IndexCount)
+	assert.Equal(t, []int{2, 3}, report.Mismatched)
+
+	report, err = mirror.Check(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Sampled)
+}
+
+// Тест проверяет формат запроса bulk: строки NDJSON с действием и
+// документом
+func Test_ElasticSync_BulkBody(t *testing.T) {
+	mirror := NewElasticSync(nil, "http://localhost:9200/", "clients", ElasticConfig{})
+	assert.Equal(t, "http://localhost:9200", mirror.url)
+
+	var body bytes.Buffer
+	doc := elasticDocument{FIO: "A", Status: StatusActive}
+	require.NoError(t, mirror.appendBulk(&body, "index", 1, &doc))
+	require.NoError(t, mirror.appendBulk(&body, "delete", 2, nil))
+	assert.Equal(t, `{"index":{"_id":"1","_index":"clients"}}
+{"fio":"A","login":"","email":"","status":"active","owner_id":""}
+{"delete":{"_id":"2","_index":"clients"}}
+`, body.String())
+}
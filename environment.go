@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Environment — назначение БД. Опасные для данных операции (например,
+// заполнение тестовыми данными) проверяют его, чтобы не выполниться по
+// ошибке над рабочей БД.
+type Environment string
+
+const (
+	EnvProduction  Environment = "production"
+	EnvStaging     Environment = "staging"
+	EnvDevelopment Environment = "development"
+	EnvTest        Environment = "test"
+)
+
+// Valid сообщает, является ли e известным назначением БД.
+func (e Environment) Valid() bool {
+	switch e {
+	case EnvProduction, EnvStaging, EnvDevelopment, EnvTest:
+		return true
+	}
+
+	return false
+}
+
+// SetEnvironment помечает БД назначением env. Схема БД должна быть
+// приведена к актуальной версии через Migrate.
+func SetEnvironment(ctx context.Context, db *sql.DB, env Environment) error {
+	if !env.Valid() {
+		return fmt.Errorf("%w: unknown environment %q", ErrValidation, env)
+	}
+
+	_, err := db.ExecContext(ctx, "INSERT INTO environment (id, name) VALUES (1, :name) ON CONFLICT (id) DO UPDATE SET name = excluded.name",
+		sql.Named("name", string(env)))
+
+	return err
+}
+
+// DatabaseEnvironment возвращает назначение БД или пустую строку, если
+// БД не помечена.
+func DatabaseEnvironment(ctx context.Context, db *sql.DB) (Environment, error) {
+	var env string
+	err := db.QueryRowContext(ctx, "SELECT name FROM environment WHERE id = 1").Scan(&env)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return Environment(env), nil
+}
+
+// requireNonProduction возвращает ErrProductionDatabase, если БД не
+// помечена явно как непроизводственная. Непомеченная БД считается рабочей.
+func requireNonProduction(ctx context.Context, db *sql.DB) error {
+	env, err := DatabaseEnvironment(ctx, db)
+	if err != nil {
+		return err
+	}
+	if env == "" || env == EnvProduction {
+		return fmt.Errorf("%w: environment is %q", ErrProductionDatabase, env)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErasureReceipt — подтверждение удаления персональных данных клиента.
+// Квитанция не содержит PII: только ID клиента, время и число удалённых
+// строк по таблицам.
+type ErasureReceipt struct {
+	ID       int              `json:"id"`
+	ClientID int              `json:"client_id"`
+	ErasedAt time.Time        `json:"erased_at"`
+	Deleted  map[string]int64 `json:"deleted"`
+}
+
+// erasureSteps — запросы, удаляющие данные клиента, в порядке выполнения.
+// Связанные строки удаляются раньше самого клиента. Записи журнала аудита
+// и прежние версии клиента содержат значения его полей, поэтому тоже
+// удаляются, как и строки проекций: их обновляет OutboxRelay, который
+// может и не работать. В журнале изменений значения стираются последними,
+// включая запись об удалении самого клиента, но записи остаются, чтобы
+// потребители журнала увидели удаление.
+var erasureSteps = []struct {
+	table string
+	query string
+}{
+	{"audit_log", "DELETE FROM audit_log WHERE client_id = :id"},
+	{"sales", "DELETE FROM sales WHERE client = :id"},
+	{"orders", "DELETE FROM orders WHERE client_id = :id"},
+	{"client_notes", "DELETE FROM client_notes WHERE client_id = :id"},
+	{"client_tags", "DELETE FROM client_tags WHERE client_id = :id"},
+	{"client_documents", "DELETE FROM client_documents WHERE client_id = :id"},
+	{"directory_links", "DELETE FROM directory_links WHERE client_id = :id"},
+	{"client_summary", "DELETE FROM client_summary WHERE client_id = :id"},
+	{"client_autocomplete", "DELETE FROM client_autocomplete WHERE client_id = :id"},
+	{"clients_history", "DELETE FROM clients_history WHERE client_id = :id"},
+	{"clients", "DELETE FROM clients WHERE id = :id"},
+	{"clients_archive", "DELETE FROM clients_archive WHERE id = :id"},
+	{"change_log", "UPDATE change_log SET old = NULL, new = NULL WHERE table_name = 'clients' AND row_id = :id"},
+}
+
+// EraseClient безвозвратно удаляет клиента и все связанные с ним строки
+// (право на забвение) и сохраняет квитанцию об удалении. Всё выполняется
+// в одной транзакции. В журнале аудита остаётся только запись об удалении
+// без значений полей. Содержимое документов клиента удаляется из хранилища
+// после фиксации транзакции; если это не удалось, вместе с квитанцией
+// возвращается ошибка.
+func (r *Repository) EraseClient(ctx context.Context, id int) (_ ErasureReceipt, err error) {
+	ctx, end := r.startClientOperation(ctx, "erase_client", id)
+	defer end(&err)
+
+	var (
+		receipt  ErasureReceipt
+		blobKeys []string
+	)
+	err = r.inTx(ctx, func(q querier) error {
+		// Существование проверяется без расшифровки полей: данные должны
+		// удаляться, даже если ключ шифрования уже недоступен. Мягко
+		// удалённые и архивные клиенты тоже удаляются.
+		err := r.clientStored(ctx, q, id, "")
+		if errors.Is(err, ErrClientNotFound) {
+			err = r.clientArchived(ctx, q, id)
+		}
+		if err != nil {
+			return err
+		}
+
+		receipt, blobKeys, err = r.erase(ctx, q, id)
+		return err
+	})
+	if err != nil {
+		return ErasureReceipt{}, err
+	}
+
+	return receipt, r.deleteBlobs(ctx, blobKeys)
+}
+
+// erase удаляет клиента id и связанные с ним строки в транзакции q,
+// сохраняет квитанцию и запись журнала аудита. Возвращает квитанцию и
+// ключи содержимого документов, которые нужно удалить из хранилища после
+// фиксации транзакции.
+func (r *Repository) erase(ctx context.Context, q querier, id int) (ErasureReceipt, []string, error) {
+	blobKeys, err := queryStrings(ctx, q, "SELECT blob_key FROM client_documents WHERE client_id = :id", sql.Named("id", id))
+	if err != nil {
+		return ErasureReceipt{}, nil, err
+	}
+
+	receipt := ErasureReceipt{
+		ClientID: id,
+		ErasedAt: r.now().Truncate(time.Second),
+		Deleted:  make(map[string]int64, len(erasureSteps)),
+	}
+
+	for _, step := range erasureSteps {
+		res, err := q.ExecContext(ctx, step.query, sql.Named("id", id))
+		if err != nil {
+			return ErasureReceipt{}, nil, err
+		}
+		if receipt.Deleted[step.table], err = res.RowsAffected(); err != nil {
+			return ErasureReceipt{}, nil, err
+		}
+	}
+
+	deleted, err := json.Marshal(receipt.Deleted)
+	if err != nil {
+		return ErasureReceipt{}, nil, err
+	}
+
+	res, err := q.ExecContext(ctx, "INSERT INTO erasure_receipts (client_id, erased_at, deleted) VALUES (:client_id, :erased_at, :deleted)",
+		sql.Named("client_id", receipt.ClientID),
+		sql.Named("erased_at", receipt.ErasedAt.Format(time.RFC3339)),
+		sql.Named("deleted", string(deleted)))
+	if err != nil {
+		return ErasureReceipt{}, nil, err
+	}
+
+	receiptID, err := res.LastInsertId()
+	if err != nil {
+		return ErasureReceipt{}, nil, err
+	}
+	receipt.ID = int(receiptID)
+
+	if err := r.audit(ctx, q, AuditErase, id, nil, nil); err != nil {
+		return ErasureReceipt{}, nil, err
+	}
+
+	return receipt, blobKeys, nil
+}
+
+// ErasureReceipts возвращает квитанции об удалении данных клиента. С
+// WithOwnerRestriction они доступны только администратору: после
+// удаления не остаётся записи о владельце клиента.
+func (r *Repository) ErasureReceipts(ctx context.Context, clientID int) (_ []ErasureReceipt, err error) {
+	ctx, end := r.startClientOperation(ctx, "erasure_receipts", clientID)
+	defer end(&err)
+
+	if err := r.clientRecorded(ctx, r.conn(), clientID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn().QueryContext(ctx, "SELECT id, client_id, erased_at, deleted FROM erasure_receipts WHERE client_id = :client_id ORDER BY id",
+		sql.Named("client_id", clientID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []ErasureReceipt
+	for rows.Next() {
+		var (
+			receipt  ErasureReceipt
+			erasedAt string
+			deleted  string
+		)
+		if err := rows.Scan(&receipt.ID, &receipt.ClientID, &erasedAt, &deleted); err != nil {
+			return nil, err
+		}
+		if receipt.ErasedAt, err = time.Parse(time.RFC3339, erasedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(deleted), &receipt.Deleted); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, rows.Err()
+}
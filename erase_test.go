@@ -0,0 +1,130 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что после EraseClient персональные данные клиента
+// не встречаются ни в одной таблице, а квитанция об удалении сохранена
+func Test_EraseClient_NoTraceRemains(t *testing.T) {
+	erasedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, repo := setupTestDB(t, WithClock(testutil.NewFakeClock(erasedAt)))
+
+	ctx := context.Background()
+
+	// Клиент с уникальными значениями, которые легко искать по всей БД
+	cl := Client{
+		FIO:      "Erasable Person Testovich",
+		Login:    "erasable_login_363",
+		Birthday: "19011231",
+		Email:    "erasable363@mail.com",
+	}
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	// Связанная продажа клиента
+	_, err = db.Exec("INSERT INTO sales (client, product, volume, date) VALUES (:client, 1, 1, '20240101')",
+		sql.Named("client", cl.ID))
+	require.NoError(t, err, "error inserting sale: %v", err)
+
+	// Заказ клиента
+	_, err = repo.Orders().Create(ctx, Order{ClientID: cl.ID, Amount: 100})
+	require.NoError(t, err, "error creating order: %v", err)
+
+	// Заметка о клиенте
+	_, err = repo.AddNote(ctx, cl.ID, "called about delivery")
+	require.NoError(t, err, "error adding note: %v", err)
+	require.NoError(t, repo.TagClient(ctx, cl.ID, "vip"), "error tagging client")
+
+	// Проекции с полями клиента
+	_, err = repo.RebuildClientSummaries(ctx)
+	require.NoError(t, err)
+	assertRowCount(t, db, "client_summary", 1, "client_id = :client", sql.Named("client", cl.ID))
+	_, err = repo.RebuildAutocomplete(ctx)
+	require.NoError(t, err)
+	assertRowCount(t, db, "client_autocomplete", 4, "client_id = :client", sql.Named("client", cl.ID))
+
+	receipt, err := repo.EraseClient(ctx, cl.ID)
+	require.NoError(t, err, "error erasing client with ID %d: %v", cl.ID, err)
+	assert.Equal(t, cl.ID, receipt.ClientID)
+	assert.Equal(t, erasedAt, receipt.ErasedAt)
+	assert.NotZero(t, receipt.ID, "receipt should be stored")
+	assert.Equal(t, map[string]int64{"audit_log": 1, "change_log": 2, "client_autocomplete": 4, "client_notes": 1, "client_documents": 0, "client_summary": 1, "client_tags": 1, "clients": 1, "clients_archive": 0, "clients_history": 0, "directory_links": 0, "orders": 1, "sales": 1}, receipt.Deleted)
+
+	// Клиент не находится ни через репозиторий, ни по связанным строкам
+	_, err = repo.Select(ctx, cl.ID)
+	require.ErrorIs(t, err, ErrClientNotFound)
+	assertClientNotInDB(t, db, cl.ID)
+	assertRowCount(t, db, "sales", 0, "client = :client", sql.Named("client", cl.ID))
+	assertRowCount(t, db, "orders", 0, "client_id = :client", sql.Named("client", cl.ID))
+
+	// Ни одно значение PII не встречается ни в одной таблице
+	// Ключи подсказок хранятся в нижнем регистре
+	for _, value := range []string{cl.FIO, cl.Login, cl.Birthday, cl.Email, autocompleteKey(cl.FIO)} {
+		assertValueNotInDB(t, db, value)
+	}
+
+	receipts, err := repo.ErasureReceipts(ctx, cl.ID)
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	assert.Equal(t, receipt, receipts[0])
+
+	// В журнале аудита остаётся только запись об удалении без значений полей
+	entries, err := repo.AuditLog(ctx, cl.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, AuditErase, entries[0].Operation)
+	assert.Empty(t, entries[0].Diff)
+}
+
+// Тест проверяет обработку удаления несуществующего клиента
+func Test_EraseClient_WhenNoClient(t *testing.T) {
+	db, _ := setupTestDB(t)
+
+	ctx := context.Background()
+
+	_, err := NewRepository(db).EraseClient(ctx, -1)
+	require.ErrorIs(t, err, ErrClientNotFound)
+}
+
+// assertValueNotInDB проверяет, что значение не встречается ни в одном
+// текстовом поле ни одной пользовательской таблицы
+func assertValueNotInDB(t *testing.T, db *sql.DB, value string) {
+	t.Helper()
+
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	require.NoError(t, err)
+	var tables []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		tables = append(tables, name)
+	}
+	require.NoError(t, rows.Close())
+
+	for _, table := range tables {
+		columns := mustColumns(t, db, table)
+		conds := make([]string, 0, len(columns))
+		for _, c := range columns {
+			conds = append(conds, fmt.Sprintf("instr(CAST(%q AS TEXT), :value) > 0", c))
+		}
+
+		var count int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %q WHERE %s", table, strings.Join(conds, " OR "))
+		require.NoError(t, db.QueryRow(query, sql.Named("value", value)).Scan(&count))
+		assert.Zero(t, count, "value %q found in table %s", value, table)
+	}
+}
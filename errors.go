@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrClientNotFound возвращается репозиторием, если клиента с указанным
+	// ID нет или он недоступен пользователю из контекста.
+	ErrClientNotFound = errors.New("client not found")
+	// ErrAccessDenied возвращается, если операция требует пользователя
+	// в контексте, а он не указан, или требует администратора.
+	ErrAccessDenied = errors.New("access denied")
+	// ErrValidation оборачивает ошибки некорректных входных данных.
+	ErrValidation = errors.New("validation failed")
+	// ErrOrderNotFound возвращается, если заказа с указанным ID нет или
+	// его клиент недоступен пользователю из контекста.
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrNoteNotFound возвращается, если заметки с указанным ID нет или
+	// её клиент недоступен пользователю из контекста.
+	ErrNoteNotFound = errors.New("note not found")
+	// ErrTagNotFound возвращается при удалении несуществующей метки.
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrInvalidStatusTransition возвращается при недопустимом переходе
+	// между статусами клиента.
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	// ErrClientHasOrders возвращается при удалении клиента, у которого
+	// есть заказы.
+	ErrClientHasOrders = errors.New("client has orders")
+	// ErrDocumentNotFound возвращается, если документа с указанным ID нет
+	// или его клиент недоступен пользователю из контекста.
+	ErrDocumentNotFound = errors.New("document not found")
+	// ErrSegmentNotFound возвращается, если сегмента с указанным названием нет.
+	ErrSegmentNotFound = errors.New("segment not found")
+	// ErrNoBlobStore возвращается операциями с документами, если
+	// хранилище содержимого не задано (см. WithBlobStore).
+	ErrNoBlobStore = errors.New("no blob store configured")
+	// ErrProductionDatabase возвращается операциями, которые можно
+	// выполнять только над БД, явно помеченной как непроизводственная.
+	ErrProductionDatabase = errors.New("database is not marked as non-production")
+	// ErrWebhookNotFound возвращается, если точки доставки вебхуков с
+	// указанным ID нет.
+	ErrWebhookNotFound = errors.New("webhook endpoint not found")
+	// ErrWebhookDeliveryNotFound возвращается, если доставки вебхука с
+	// указанным ID нет.
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrTooManyClients возвращается операцией по условию, если условию
+	// подходит больше клиентов, чем разрешено.
+	ErrTooManyClients = errors.New("too many clients match the filter")
+	// ErrVersionConflict возвращается изменением с ожидаемыми версиями
+	// клиентов, если клиента изменили после чтения его версии.
+	ErrVersionConflict = errors.New("client version conflict")
+)
+
+// OpError — ошибка операции репозитория: к причине Err добавляются имя
+// операции и, для операций над одним клиентом, его ID. Причина доступна
+// через errors.Is и errors.As, поэтому сравнение с ErrClientNotFound и
+// другими ошибками выше продолжает работать.
+type OpError struct {
+	// Op — имя операции, как в метриках и трассировке, например "update".
+	Op string
+	// ClientID — ID клиента операции; 0, если операция не над одним клиентом.
+	ClientID int
+	Err      error
+}
+
+func (e *OpError) Error() string {
+	if e.ClientID != 0 {
+		return fmt.Sprintf("%s client %d: %v", e.Op, e.ClientID, e.Err)
+	}
+
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// wrapOpError оборачивает *err в OpError операции op. Ошибка, уже
+// обёрнутая вложенной операцией, не оборачивается повторно, чтобы в
+// сообщении осталась операция, в которой она возникла.
+func wrapOpError(err *error, op string, clientID int) {
+	var opErr *OpError
+	if *err == nil || errors.As(*err, &opErr) {
+		return
+	}
+	*err = &OpError{Op: op, ClientID: clientID, Err: *err}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что ошибки операций репозитория называют операцию и
+// клиента и при этом сравниваются с доменными ошибками через errors.Is и
+// errors.As
+func Test_OpError(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	const missing = 999
+
+	tests := []struct {
+		name     string
+		call     func() error
+		op       string
+		clientID int
+		want     error
+		msg      string
+	}{
+		{"Select", func() error { _, err := repo.Select(ctx, missing); return err },
+			"select", missing, ErrClientNotFound, "select client 999: client not found"},
+		{"SelectAsOf", func() error { _, err := repo.SelectAsOf(ctx, missing, time.Now()); return err },
+			"select_as_of", missing, ErrClientNotFound, "select_as_of client 999: client not found"},
+		{"Insert", func() error { _, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "" })); return err },
+			"insert", 0, ErrValidation, "insert: validation failed: fio is required"},
+		{"UpdateMissing", func() error { return repo.Update(ctx, newTestClient(func(cl *Client) { cl.ID = missing })) },
+			"update", missing, ErrClientNotFound, "update client 999: client not found"},
+		{"UpdateInvalid", func() error {
+			return repo.Update(ctx, newTestClient(func(cl *Client) { cl.ID = id; cl.Birthday = "1970" }))
+		},
+			"update", id, ErrValidation, fmt.Sprintf(`update client %d: validation failed: birthday is not a valid YYYYMMDD date`, id)},
+		{"Delete", func() error { return repo.Delete(ctx, missing) },
+			"delete", missing, ErrClientNotFound, "delete client 999: client not found"},
+		{"ChangeStatus", func() error { return repo.ChangeStatus(ctx, missing, StatusBlocked) },
+			"change_status", missing, ErrClientNotFound, "change_status client 999: client not found"},
+		{"RestoreClient", func() error { return repo.RestoreClient(ctx, id) },
+			"restore_client", id, ErrInvalidStatusTransition, fmt.Sprintf("restore_client client %d: invalid status transition: active -> active", id)},
+		{"RecordConsent", func() error { return repo.RecordConsent(ctx, missing, true) },
+			"record_consent", missing, ErrClientNotFound, "record_consent client 999: client not found"},
+		{"SetLegalHold", func() error { return repo.SetLegalHold(ctx, missing, true) },
+			"set_legal_hold", missing, ErrClientNotFound, "set_legal_hold client 999: client not found"},
+		{"EraseClient", func() error { _, err := repo.EraseClient(ctx, missing); return err },
+			"erase_client", missing, ErrClientNotFound, "erase_client client 999: client not found"},
+		{"MergeClients", func() error {
+			_, err := repo.MergeClientsWith(ctx, missing, []int{id}, DestructiveOptions{})
+			return err
+		},
+			"merge_clients", missing, ErrClientNotFound, "merge_clients client 999: client not found"},
+		{"TagClient", func() error { return repo.TagClient(ctx, missing, "vip") },
+			"tag_client", missing, ErrClientNotFound, "tag_client client 999: client not found"},
+		{"DeleteTag", func() error { return repo.DeleteTag(ctx, "missing") },
+			"delete_tag", 0, ErrTagNotFound, `delete_tag: tag not found: "missing"`},
+		{"AddNote", func() error { _, err := repo.AddNote(ctx, missing, "call back"); return err },
+			"add_note", missing, ErrClientNotFound, "add_note client 999: client not found"},
+		{"DeleteNote", func() error { return repo.DeleteNote(ctx, missing) },
+			"delete_note", 0, ErrNoteNotFound, "delete_note: note not found: 999"},
+		{"SetPreference", func() error { return SetPreference(ctx, repo, missing, "theme", "dark") },
+			"set_preference", missing, ErrClientNotFound, "set_preference client 999: client not found"},
+		{"OrderCreate", func() error { _, err := repo.Orders().Create(ctx, Order{ClientID: missing, Amount: 100}); return err },
+			"order_create", missing, ErrClientNotFound, "order_create client 999: client not found"},
+		{"OrderSelect", func() error { _, err := repo.Orders().Select(ctx, missing); return err },
+			"order_select", 0, ErrOrderNotFound, "order_select: order not found: 999"},
+		{"DocumentUpload", func() error {
+			_, err := repo.Documents().Upload(ctx, id, "passport.pdf", "application/pdf", bytes.NewReader(nil))
+			return err
+		}, "document_upload", id, ErrNoBlobStore, fmt.Sprintf("document_upload client %d: no blob store configured", id)},
+		{"Segment", func() error { _, err := repo.Segment(ctx, "missing"); return err },
+			"segment", 0, ErrSegmentNotFound, `segment: segment not found: "missing"`},
+		{"DeleteWebhook", func() error { return repo.DeleteWebhook(ctx, missing) },
+			"delete_webhook", 0, ErrWebhookNotFound, "delete_webhook: webhook endpoint not found: 999"},
+		{"UpdateClientsWhere", func() error {
+			fio := "Иванов"
+			_, err := repo.UpdateClientsWhere(ctx, Filter{}, ClientChanges{FIO: &fio}, map[int]int{})
+			return err
+		}, "update_where", 0, ErrValidation, "update_where: validation failed: filter matches all clients"},
+		{"DeleteClientsWhere", func() error {
+			_, err := repo.DeleteClientsWhere(ctx, Filter{FIOPrefix: "Test"}, DeleteWhereOptions{MaxRows: 0})
+			return err
+		},
+			"delete_clients_where", 0, ErrValidation, "delete_clients_where: validation failed: set a max rows limit or force to delete by filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.ErrorIs(t, err, tt.want)
+			assert.EqualError(t, err, tt.msg)
+
+			var opErr *OpError
+			require.ErrorAs(t, err, &opErr)
+			assert.Equal(t, tt.op, opErr.Op)
+			assert.Equal(t, tt.clientID, opErr.ClientID)
+			assert.ErrorIs(t, opErr.Unwrap(), tt.want)
+		})
+	}
+}
+
+// Тест проверяет, что ошибка вложенной операции не оборачивается повторно
+// и что успешная операция не получает ошибку
+func Test_WrapOpError(t *testing.T) {
+	var err error
+	wrapOpError(&err, "select", 1)
+	assert.NoError(t, err)
+
+	err = ErrClientNotFound
+	wrapOpError(&err, "select", 1)
+	wrapOpError(&err, "select_with_orders", 2)
+	assert.EqualError(t, err, "select client 1: client not found")
+
+	err = fmt.Errorf("client 3: %w", &OpError{Op: "update", ClientID: 3, Err: ErrValidation})
+	wrapOpError(&err, "update_where", 0)
+	assert.EqualError(t, err, "client 3: update client 3: validation failed")
+	assert.ErrorIs(t, err, ErrValidation)
+}
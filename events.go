@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// EventFormat — формат сериализации событий для брокера сообщений.
+type EventFormat string
+
+const (
+	EventFormatJSON EventFormat = "json"
+	// EventFormatProtobuf — сообщение protobuf со схемой:
+	//
+	//	message ClientEvent {
+	//	  int64 id = 1;
+	//	  int64 client_id = 2;
+	//	  string operation = 3;
+	//	  google.protobuf.Timestamp occurred_at = 4;
+	//	  string actor = 5;
+	//	  string request_id = 6;
+	//	  repeated string fields = 7;
+	//	}
+	EventFormatProtobuf EventFormat = "protobuf"
+)
+
+// Заголовки сообщения о событии.
+const (
+	EventContentTypeHeader = "content-type"
+	// EventIDHeader — ClientEvent.ID, по которому потребители отбрасывают
+	// повторы.
+	EventIDHeader = "event-id"
+)
+
+// eventContentTypes — значения EventContentTypeHeader по форматам.
+var eventContentTypes = map[EventFormat]string{
+	EventFormatJSON:     "application/json",
+	EventFormatProtobuf: "application/x-protobuf",
+}
+
+// errMalformedEvent возвращается DecodeClientEvent для повреждённого
+// сообщения protobuf.
+var errMalformedEvent = errors.New("malformed protobuf client event")
+
+// EventMessage — сообщение о событии для брокера.
+type EventMessage struct {
+	// Key — ключ партиционирования, ID клиента: все события клиента
+	// попадают в одну партицию и читаются в порядке публикации.
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// EventPublisher публикует сообщения в брокер, например NATS
+// (NATSPublisher) или Kafka (KafkaPublisher).
+type EventPublisher interface {
+	// Publish возвращается после того, как брокер принял сообщение.
+	Publish(ctx context.Context, msg EventMessage) error
+}
+
+// BrokerSink — EventSink для OutboxRelay, публикующий события в
+// EventPublisher в формате format с ключом — ID клиента.
+type BrokerSink struct {
+	publisher EventPublisher
+	format    EventFormat
+}
+
+// NewBrokerSink создаёт публикацию событий в publisher в формате format.
+func NewBrokerSink(publisher EventPublisher, format EventFormat) (*BrokerSink, error) {
+	if _, ok := eventContentTypes[format]; !ok {
+		return nil, fmt.Errorf("%w: unknown event format %q", ErrValidation, format)
+	}
+
+	return &BrokerSink{publisher: publisher, format: format}, nil
+}
+
+// Publish сериализует событие и публикует его.
+func (s *BrokerSink) Publish(ctx context.Context, event ClientEvent) error {
+	value, err := EncodeClientEvent(event, s.format)
+	if err != nil {
+		return err
+	}
+
+	return s.publisher.Publish(ctx, EventMessage{
+		Key:   strconv.Itoa(event.ClientID),
+		Value: value,
+		Headers: map[string]string{
+			EventContentTypeHeader: eventContentTypes[s.format],
+			EventIDHeader:          strconv.FormatInt(event.ID, 10),
+		},
+	})
+}
+
+// EncodeClientEvent сериализует событие в формате format.
+func EncodeClientEvent(event ClientEvent, format EventFormat) ([]byte, error) {
+	switch format {
+	case EventFormatJSON:
+		return json.Marshal(event)
+	case EventFormatProtobuf:
+		return marshalEventProto(event), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown event format %q", ErrValidation, format)
+	}
+}
+
+// DecodeClientEvent разбирает событие, сериализованное EncodeClientEvent.
+func DecodeClientEvent(data []byte, format EventFormat) (ClientEvent, error) {
+	switch format {
+	case EventFormatJSON:
+		var event ClientEvent
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case EventFormatProtobuf:
+		return unmarshalEventProto(data)
+	default:
+		return ClientEvent{}, fmt.Errorf("%w: unknown event format %q", ErrValidation, format)
+	}
+}
+
+func marshalEventProto(event ClientEvent) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(event.ID))
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(event.ClientID))
+	b = appendProtoString(b, 3, string(event.Operation))
+
+	// google.protobuf.Timestamp: seconds = 1, nanos = 2
+	var ts []byte
+	ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(event.OccurredAt.Unix()))
+	ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(event.OccurredAt.Nanosecond()))
+	b = protowire.AppendTag(b, 4, protowire.BytesType)
+	b = protowire.AppendBytes(b, ts)
+
+	b = appendProtoString(b, 5, event.Actor)
+	b = appendProtoString(b, 6, event.RequestID)
+	for _, field := range event.Fields {
+		b = appendProtoString(b, 7, field)
+	}
+
+	return b
+}
+
+// appendProtoString добавляет непустую строку s полем num.
+func appendProtoString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendString(b, s)
+}
+
+func unmarshalEventProto(data []byte) (ClientEvent, error) {
+	var (
+		event         ClientEvent
+		seconds, nano int64
+	)
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			event.ID = int64(varint)
+		case num == 2 && typ == protowire.VarintType:
+			event.ClientID = int(int64(varint))
+		case num == 3 && typ == protowire.BytesType:
+			event.Operation = AuditOperation(value)
+		case num == 4 && typ == protowire.BytesType:
+			return consumeProtoFields(value, func(num protowire.Number, typ protowire.Type, _ []byte, varint uint64) error {
+				switch {
+				case num == 1 && typ == protowire.VarintType:
+					seconds = int64(varint)
+				case num == 2 && typ == protowire.VarintType:
+					nano = int64(varint)
+				}
+				return nil
+			})
+		case num == 5 && typ == protowire.BytesType:
+			event.Actor = string(value)
+		case num == 6 && typ == protowire.BytesType:
+			event.RequestID = string(value)
+		case num == 7 && typ == protowire.BytesType:
+			event.Fields = append(event.Fields, string(value))
+		}
+		return nil
+	})
+	if err != nil {
+		return ClientEvent{}, err
+	}
+	event.OccurredAt = time.Unix(seconds, nano).UTC()
+
+	return event, nil
+}
+
+// consumeProtoFields вызывает fn для каждого поля сообщения data: value —
+// содержимое поля типа BytesType, varint — значение поля VarintType. Поля
+// других типов пропускаются.
+func consumeProtoFields(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return errMalformedEvent
+		}
+		data = data[n:]
+
+		var (
+			value  []byte
+			varint uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return errMalformedEvent
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, value, varint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+//go:build brokers
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тесты публикации событий запускаются с тегом сборки brokers против
+// запущенных контейнеров NATS и Kafka:
+//
+//	docker run -d -p 4222:4222 nats
+//	docker run -d -p 9092:9092 apache/kafka
+//	go test -tags brokers -run Broker ./...
+//
+// Адреса задаются переменными окружения NATSURLEnv и KafkaBrokersEnv
+// (брокеры Kafka через запятую).
+const (
+	NATSURLEnv      = "CLIENTS_TEST_NATS_URL"
+	KafkaBrokersEnv = "CLIENTS_TEST_KAFKA_BROKERS"
+)
+
+// setupBrokerEvents создаёт клиентов с двумя событиями каждый и возвращает
+// репозиторий и ID клиентов.
+func setupBrokerEvents(t *testing.T) (*Repository, []int) {
+	t.Helper()
+
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	ids := make([]int, 4)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		require.NoError(t, repo.RecordConsent(ctx, ids[i], true))
+	}
+
+	return repo, ids
+}
+
+// Тест проверяет публикацию событий из outbox в NATS в темы по ID клиента
+func Test_Broker_NATS(t *testing.T) {
+	conn, err := nats.Connect(envOr(NATSURLEnv, nats.DefaultURL))
+	require.NoError(t, err, "NATS should be running")
+	t.Cleanup(conn.Close)
+
+	ctx := context.Background()
+	repo, ids := setupBrokerEvents(t)
+	subject := fmt.Sprintf("clients-test-%d", time.Now().UnixNano())
+	sub, err := conn.SubscribeSync(subject + ".>")
+	require.NoError(t, err)
+	require.NoError(t, conn.Flush())
+
+	sink, err := NewBrokerSink(NewNATSPublisher(conn, subject), EventFormatProtobuf)
+	require.NoError(t, err)
+	n, err := NewOutboxRelay(repo, sink).Relay(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2*len(ids), n)
+
+	ops := make(map[int][]AuditOperation)
+	for i := 0; i < n; i++ {
+		msg, err := sub.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+		event, err := DecodeClientEvent(msg.Data, EventFormatProtobuf)
+		require.NoError(t, err)
+		assert.Equal(t, subject+"."+strconv.Itoa(event.ClientID), msg.Subject)
+		assert.Equal(t, strconv.FormatInt(event.ID, 10), msg.Header.Get(nats.MsgIdHdr))
+		ops[event.ClientID] = append(ops[event.ClientID], event.Operation)
+	}
+	for _, id := range ids {
+		assert.Equal(t, []AuditOperation{AuditInsert, AuditConsent}, ops[id], "client %d", id)
+	}
+}
+
+// Тест проверяет публикацию событий из outbox в Kafka: события клиента
+// попадают в одну партицию в порядке изменений
+func Test_Broker_Kafka(t *testing.T) {
+	brokers := strings.Split(envOr(KafkaBrokersEnv, "localhost:9092"), ",")
+	topic := fmt.Sprintf("clients-test-%d", time.Now().UnixNano())
+	createKafkaTopic(t, brokers[0], topic, 3)
+
+	ctx := context.Background()
+	repo, ids := setupBrokerEvents(t)
+	publisher := NewKafkaPublisher(brokers, topic)
+	t.Cleanup(func() { publisher.Close() })
+	sink, err := NewBrokerSink(publisher, EventFormatJSON)
+	require.NoError(t, err)
+	n, err := NewOutboxRelay(repo, sink).Relay(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2*len(ids), n)
+
+	// Relay возвращается после подтверждения брокера, поэтому все события
+	// уже в партициях
+	readCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	partitionOf := make(map[int]int)
+	ops := make(map[int][]AuditOperation)
+	for partition := 0; partition < 3; partition++ {
+		reader := kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, Topic: topic, Partition: partition})
+		for {
+			lag, err := reader.ReadLag(readCtx)
+			require.NoError(t, err)
+			if lag == 0 {
+				break
+			}
+			msg, err := reader.ReadMessage(readCtx)
+			require.NoError(t, err)
+			event, err := DecodeClientEvent(msg.Value, EventFormatJSON)
+			require.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(event.ClientID), string(msg.Key))
+			if p, ok := partitionOf[event.ClientID]; ok {
+				assert.Equal(t, p, partition, "client %d events should share a partition", event.ClientID)
+			}
+			partitionOf[event.ClientID] = partition
+			ops[event.ClientID] = append(ops[event.ClientID], event.Operation)
+		}
+		require.NoError(t, reader.Close())
+	}
+	for _, id := range ids {
+		assert.Equal(t, []AuditOperation{AuditInsert, AuditConsent}, ops[id], "client %d", id)
+	}
+}
+
+// createKafkaTopic создаёт тему с partitions партициями через контроллер
+// кластера брокера broker.
+func createKafkaTopic(t *testing.T, broker, topic string, partitions int) {
+	t.Helper()
+
+	conn, err := kafka.Dial("tcp", broker)
+	require.NoError(t, err, "Kafka should be running at %s", broker)
+	defer conn.Close()
+	controller, err := conn.Controller()
+	require.NoError(t, err)
+
+	ctrl, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	require.NoError(t, err)
+	defer ctrl.Close()
+	require.NoError(t, ctrl.CreateTopics(kafka.TopicConfig{Topic: topic, NumPartitions: partitions, ReplicationFactor: 1}))
+}
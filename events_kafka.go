@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher публикует события в тему Kafka. Партиция выбирается по
+// хешу ключа — ID клиента (kafka.Hash, как в клиентах Kafka на Java),
+// поэтому события одного клиента читаются в порядке публикации.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher создаёт публикацию в тему topic брокеров brokers.
+// Сообщение считается принятым, когда его записали все синхронные
+// реплики партиции.
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+// Publish публикует сообщение и ждёт подтверждения брокера.
+func (p *KafkaPublisher) Publish(ctx context.Context, msg EventMessage) error {
+	out := kafka.Message{Key: []byte(msg.Key), Value: msg.Value}
+	for name, value := range msg.Headers {
+		out.Headers = append(out.Headers, kafka.Header{Key: name, Value: []byte(value)})
+	}
+
+	return p.writer.WriteMessages(ctx, out)
+}
+
+// Close отправляет буферизованные сообщения и закрывает соединения.
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher публикует события в NATS в тему «subject.<ID клиента>»:
+// у NATS нет партиций, поэтому ключ входит в тему, и потребитель может
+// подписаться на события одного клиента или распределить клиентов между
+// экземплярами по теме (например, партиционированием тем JetStream).
+// Заголовок Nats-Msg-Id равен ID события, поэтому JetStream отбрасывает
+// повторно опубликованные события.
+type NATSPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSPublisher создаёт публикацию в тему subject через соединение
+// conn. Соединение закрывает вызывающий код.
+func NewNATSPublisher(conn *nats.Conn, subject string) *NATSPublisher {
+	return &NATSPublisher{conn: conn, subject: subject}
+}
+
+// Publish публикует сообщение и ждёт, пока сервер его примет.
+func (p *NATSPublisher) Publish(ctx context.Context, msg EventMessage) error {
+	out := nats.NewMsg(p.subject + "." + msg.Key)
+	out.Data = msg.Value
+	for name, value := range msg.Headers {
+		out.Header.Set(name, value)
+	}
+	out.Header.Set(nats.MsgIdHdr, msg.Headers[EventIDHeader])
+
+	if err := p.conn.PublishMsg(out); err != nil {
+		return err
+	}
+
+	return p.conn.FlushWithContext(ctx)
+}
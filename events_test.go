@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPublisher — EventPublisher в памяти с партициями, выбираемыми по
+// ключу так же, как в KafkaPublisher.
+type memoryPublisher struct {
+	mu         sync.Mutex
+	partitions [][]EventMessage
+	err        error
+}
+
+func newMemoryPublisher(partitions int) *memoryPublisher {
+	return &memoryPublisher{partitions: make([][]EventMessage, partitions)}
+}
+
+func (p *memoryPublisher) Publish(_ context.Context, msg EventMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	ids := make([]int, len(p.partitions))
+	for i := range ids {
+		ids[i] = i
+	}
+	partition := (&kafka.Hash{}).Balance(kafka.Message{Key: []byte(msg.Key)}, ids...)
+	p.partitions[partition] = append(p.partitions[partition], msg)
+
+	return nil
+}
+
+// Тест проверяет сериализацию событий в JSON и protobuf и разбор
+// сериализованных событий
+func Test_EncodeClientEvent(t *testing.T) {
+	event := ClientEvent{
+		ID:         42,
+		ClientID:   7,
+		Operation:  AuditUpdate,
+		OccurredAt: time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		Actor:      "operator",
+		RequestID:  "req-1",
+		Fields:     []string{"email", "fio"},
+	}
+
+	for _, format := range []EventFormat{EventFormatJSON, EventFormatProtobuf} {
+		data, err := EncodeClientEvent(event, format)
+		require.NoError(t, err, format)
+		got, err := DecodeClientEvent(data, format)
+		require.NoError(t, err, format)
+		assert.Equal(t, event, got, format)
+
+		// Пустые необязательные поля не теряют значения по умолчанию
+		data, err = EncodeClientEvent(ClientEvent{ID: 1, ClientID: 1, Operation: AuditInsert, OccurredAt: event.OccurredAt}, format)
+		require.NoError(t, err, format)
+		got, err = DecodeClientEvent(data, format)
+		require.NoError(t, err, format)
+		assert.Equal(t, ClientEvent{ID: 1, ClientID: 1, Operation: AuditInsert, OccurredAt: event.OccurredAt}, got, format)
+	}
+
+	_, err := EncodeClientEvent(event, "xml")
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = NewBrokerSink(newMemoryPublisher(1), "xml")
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = DecodeClientEvent([]byte{0x0a, 0x05, 'a'}, EventFormatProtobuf)
+	require.Error(t, err)
+}
+
+// Тест проверяет, что события из outbox публикуются с ключом — ID
+// клиента, и все события клиента попадают в одну партицию в порядке
+// изменений
+func Test_BrokerSink_Partitioning(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))))
+
+	ids := make([]int, 6)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	for _, id := range ids {
+		cl, err := repo.Select(ctx, id)
+		require.NoError(t, err)
+		cl.FIO = "Updated"
+		require.NoError(t, repo.Update(ctx, cl))
+	}
+
+	publisher := newMemoryPublisher(3)
+	sink, err := NewBrokerSink(publisher, EventFormatProtobuf)
+	require.NoError(t, err)
+	n, err := NewOutboxRelay(repo, sink).Relay(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2*len(ids), n)
+
+	partitionOf := make(map[string]int)
+	used := make(map[int]bool)
+	for partition, messages := range publisher.partitions {
+		lastOp := make(map[string]AuditOperation)
+		var lastID int64
+		for _, msg := range messages {
+			event, err := DecodeClientEvent(msg.Value, EventFormatProtobuf)
+			require.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(event.ClientID), msg.Key)
+			assert.Equal(t, strconv.FormatInt(event.ID, 10), msg.Headers[EventIDHeader])
+			assert.Equal(t, "application/x-protobuf", msg.Headers[EventContentTypeHeader])
+
+			if p, ok := partitionOf[msg.Key]; ok {
+				assert.Equal(t, p, partition, "client %s events should share a partition", msg.Key)
+			}
+			partitionOf[msg.Key] = partition
+			used[partition] = true
+
+			// В партиции события идут в порядке записи в outbox
+			assert.Greater(t, event.ID, lastID)
+			lastID = event.ID
+			if lastOp[msg.Key] == "" {
+				assert.Equal(t, AuditInsert, event.Operation)
+			} else {
+				assert.Equal(t, AuditUpdate, event.Operation)
+			}
+			lastOp[msg.Key] = event.Operation
+		}
+	}
+	assert.Len(t, partitionOf, len(ids))
+	assert.Greater(t, len(used), 1, "clients should be spread over partitions")
+}
+
+// Тест проверяет, что событие, которое брокер не принял, остаётся в
+// outbox и публикуется после восстановления брокера
+func Test_BrokerSink_PublishFailure(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	publisher := newMemoryPublisher(1)
+	publisher.err = errors.New("broker unavailable")
+	sink, err := NewBrokerSink(publisher, EventFormatJSON)
+	require.NoError(t, err)
+
+	_, err = NewOutboxRelay(repo, sink).Relay(ctx)
+	require.ErrorContains(t, err, "broker unavailable")
+	assert.Empty(t, publisher.partitions[0])
+
+	publisher.err = nil
+	n, err := NewOutboxRelay(repo, sink).Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, publisher.partitions[0], 1)
+	event, err := DecodeClientEvent(publisher.partitions[0][0].Value, EventFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, id, event.ClientID)
+	assert.Equal(t, "application/json", publisher.partitions[0][0].Headers[EventContentTypeHeader])
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// ExportFormat — формат выгрузки клиентов.
+type ExportFormat string
+
+const (
+	FormatCSV  ExportFormat = "csv"
+	FormatJSON ExportFormat = "json"
+	// FormatNDJSON — по одному объекту JSON на строку.
+	FormatNDJSON ExportFormat = "ndjson"
+)
+
+// ExportOptions задаёт параметры выгрузки.
+type ExportOptions struct {
+	Format ExportFormat
+	// NonProduction помечает выгрузку для непродуктивного окружения:
+	// все персональные данные в ней маскируются.
+	NonProduction bool
+	// MarketingOnly оставляет в выгрузке только клиентов, давших согласие
+	// на маркетинговые коммуникации.
+	MarketingOnly bool
+	// Filter оставляет в выгрузке только клиентов, подходящих под условия
+	// (как у сегмента); пустые условия ничего не отбрасывают.
+	Filter SegmentCriteria
+	// Where оставляет в выгрузке только клиентов, подходящих под условие
+	// отбора (см. Filter); проверяется запросом вместе с Filter.
+	Where Filter
+	// Columns задаёт названия столбцов CSV для полей клиента (ключи —
+	// названия из csvHeader), например {"email": "E-mail"}. Поля без
+	// сопоставления называются по умолчанию.
+	Columns map[string]string
+}
+
+var csvHeader = []string{"id", "fio", "login", "birthday", "email"}
+
+// columnNames возвращает названия столбцов CSV для полей csvHeader с
+// учётом сопоставления columns.
+func columnNames(columns map[string]string) ([]string, error) {
+	names := slices.Clone(csvHeader)
+	for field, name := range columns {
+		i := slices.Index(csvHeader, field)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: unknown client field %q in column mapping", ErrValidation, field)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%w: empty column name for field %q", ErrValidation, field)
+		}
+		names[i] = name
+	}
+	for i, name := range names {
+		if slices.Contains(names[i+1:], name) {
+			return nil, fmt.Errorf("%w: duplicate column name %q in column mapping", ErrValidation, name)
+		}
+	}
+
+	return names, nil
+}
+
+// Export выгружает клиентов в w в указанном формате. Выгрузка пишется
+// по мере чтения клиентов, не накапливаясь в памяти.
+func (r *Repository) Export(ctx context.Context, w io.Writer, opts ExportOptions) (err error) {
+	ctx, end := r.startOperation(ctx, "export")
+	defer end(&err)
+
+	if len(opts.Columns) > 0 && opts.Format != FormatCSV {
+		return fmt.Errorf("%w: column mapping is supported only for CSV", ErrValidation)
+	}
+
+	var enc clientEncoder
+	switch opts.Format {
+	case FormatCSV:
+		header, err := columnNames(opts.Columns)
+		if err != nil {
+			return err
+		}
+		enc = newCSVEncoder(w, header)
+	case FormatJSON:
+		enc = newJSONEncoder(w)
+	case FormatNDJSON:
+		enc = newNDJSONEncoder(w)
+	default:
+		return fmt.Errorf("%w: unsupported export format %q", ErrValidation, opts.Format)
+	}
+
+	if err := opts.Filter.Validate(); err != nil {
+		return err
+	}
+	cond, args, err := opts.Filter.cond()
+	if err != nil {
+		return err
+	}
+	where, whereArgs, err := r.filterCond(opts.Where)
+	if err != nil {
+		return err
+	}
+	cond += where
+	args = append(args, whereArgs...)
+	if opts.MarketingOnly {
+		cond += " AND marketing_consent = 1"
+	}
+
+	if err := enc.begin(); err != nil {
+		return err
+	}
+
+	err = r.forEach(ctx, cond, args, func(cl Client) error {
+		// Условия проверяются по настоящим данным, до маскирования
+		if !opts.Filter.match(cl) {
+			return nil
+		}
+		if opts.NonProduction {
+			cl = maskClient(cl)
+		}
+
+		return enc.encode(cl)
+	})
+	if err != nil {
+		return err
+	}
+
+	return enc.end()
+}
+
+type clientEncoder interface {
+	begin() error
+	encode(Client) error
+	end() error
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	header []string
+}
+
+func newCSVEncoder(w io.Writer, header []string) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w), header: header}
+}
+
+func (e *csvEncoder) begin() error {
+	return e.w.Write(e.header)
+}
+
+func (e *csvEncoder) encode(cl Client) error {
+	return e.w.Write([]string{strconv.Itoa(cl.ID), cl.FIO, cl.Login, cl.Birthday, cl.Email})
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+
+	return e.w.Error()
+}
+
+// jsonEncoder пишет массив объектов построчно, не накапливая выгрузку в памяти.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) *jsonEncoder {
+	return &jsonEncoder{w: w}
+}
+
+func (e *jsonEncoder) begin() error {
+	_, err := io.WriteString(e.w, "[")
+
+	return err
+}
+
+func (e *jsonEncoder) encode(cl Client) error {
+	data, err := json.Marshal(cl)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "\n"
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+
+	return err
+}
+
+func (e *jsonEncoder) end() error {
+	_, err := io.WriteString(e.w, "\n]\n")
+
+	return err
+}
+
+// ndjsonEncoder пишет по одному объекту на строку без обрамления.
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) *ndjsonEncoder {
+	return &ndjsonEncoder{enc: json.NewEncoder(w)}
+}
+
+func (e *ndjsonEncoder) begin() error {
+	return nil
+}
+
+func (e *ndjsonEncoder) encode(cl Client) error {
+	return e.enc.Encode(cl)
+}
+
+func (e *ndjsonEncoder) end() error {
+	return nil
+}
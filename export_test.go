@@ -0,0 +1,111 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что в выгрузке для непродуктивного окружения
+// не встречается ни один настоящий email клиента
+func Test_Export_NonProductionMasksEmails(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	var emails []string
+	err := repo.ForEach(ctx, func(cl Client) error {
+		emails = append(emails, cl.Email)
+		return nil
+	})
+	require.NoError(t, err, "error listing clients: %v", err)
+	require.NotEmpty(t, emails, "demo database should contain clients")
+
+	for _, format := range []ExportFormat{FormatCSV, FormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := repo.Export(ctx, &buf, ExportOptions{Format: format, NonProduction: true})
+			require.NoError(t, err, "export error: %v", err)
+
+			out := buf.String()
+			for _, email := range emails {
+				assert.NotContains(t, out, email, "unmasked email leaked into %s export", format)
+			}
+		})
+	}
+}
+
+// Тест проверяет, что обычная выгрузка содержит исходные данные
+// и корректно разбирается в обоих форматах
+func Test_Export_Formats(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	first, err := repo.Select(ctx, 1)
+	require.NoError(t, err, "error retrieving client with ID 1: %v", err)
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, repo.Export(ctx, &buf, ExportOptions{Format: FormatCSV}))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err, "export is not valid CSV: %v", err)
+		require.Greater(t, len(records), 1)
+		assert.Equal(t, csvHeader, records[0])
+		assert.Equal(t, []string{"1", first.FIO, first.Login, first.Birthday, first.Email}, records[1])
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, repo.Export(ctx, &buf, ExportOptions{Format: FormatJSON}))
+
+		var clients []Client
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &clients), "export is not valid JSON")
+		require.NotEmpty(t, clients)
+		assert.Equal(t, first, clients[0])
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.Error(t, repo.Export(ctx, &buf, ExportOptions{Format: "xml"}))
+	})
+}
+
+// Тест сравнивает выгрузку эталонного набора данных с файлами в
+// testdata/golden; после намеренного изменения формата выполните
+// go test -tags integration -run Test_Export_Golden -update
+func Test_Export_Golden(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	tests := []struct {
+		golden string
+		opts   ExportOptions
+	}{
+		{"clients.csv", ExportOptions{Format: FormatCSV}},
+		{"clients.json", ExportOptions{Format: FormatJSON}},
+		{"clients_nonprod.csv", ExportOptions{Format: FormatCSV, NonProduction: true}},
+		{"clients_nonprod.json", ExportOptions{Format: FormatJSON, NonProduction: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, repo.Export(ctx, &buf, tt.opts), "export error")
+			assertGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
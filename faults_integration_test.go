@@ -0,0 +1,54 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест-сценарий: вызывающий выделяет каждому запросу бюджет времени;
+// запрос, превысивший бюджет из-за внедрённой задержки, завершается
+// ошибкой класса timeout, не оставляя следов в БД, а следующие запросы
+// выполняются как обычно
+func Test_Scenario_RequestTimeoutUnderInjectedLatency(t *testing.T) {
+	const budget = 200 * time.Millisecond
+	db, base := setupTestDB(t)
+	repo := testutil.NewFaultyRepository[Client](base, testutil.NewFaultInjector(testutil.FaultConfig{
+		LatencyDist: testutil.UniformLatency(0, 10*time.Millisecond),
+		Seed:        1,
+		HangEvery:   3,
+	}))
+
+	handle := func(cl Client) (int, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), budget)
+		defer cancel()
+		return repo.Insert(ctx, cl)
+	}
+
+	for i := 1; i <= 4; i++ {
+		cl := newTestClient(func(cl *Client) { cl.Email = fmt.Sprintf("latency397-%d@mail.com", i) })
+
+		started := time.Now()
+		id, err := handle(cl)
+		elapsed := time.Since(started)
+
+		if i == 3 {
+			require.ErrorIs(t, err, context.DeadlineExceeded, "request %d should exceed its budget", i)
+			assert.Equal(t, ClassTimeout, Classify(err))
+			assert.Less(t, elapsed, 2*budget, "request %d should fail at the deadline, not hang", i)
+			assertRowCount(t, db, "clients", 0, "email = ?", cl.Email)
+			continue
+		}
+
+		require.NoError(t, err, "request %d should fit into the budget", i)
+		assert.Less(t, elapsed, budget)
+		assertRowCount(t, db, "clients", 1, "id = ?", id)
+	}
+}
@@ -0,0 +1,718 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter — условие отбора клиентов, которое переводится в параметризованный
+// запрос SQL (см. SQL). Заданные поля и вложенные условия All, Any и Not
+// должны выполняться одновременно; пустой Filter отбирает всех клиентов.
+// Условия составляются функциями And, Or и Not и хранятся в JSON так же,
+// как условия сегмента.
+//
+// В отличие от SegmentCriteria, Filter проверяется только запросом, поэтому
+// условия на email и дату рождения не поддерживаются при включённом
+// шифровании: значения в БД зашифрованы.
+type Filter struct {
+	// FIOContains — подстрока ФИО с учётом регистра.
+	FIOContains string `json:"fio_contains,omitempty"`
+	// FIOPrefix — начало ФИО с учётом регистра.
+	FIOPrefix string `json:"fio_prefix,omitempty"`
+	// EmailEquals — email целиком; регистр латинских букв не учитывается.
+	EmailEquals string `json:"email_equals,omitempty"`
+	// BornFrom и BornTo — границы даты рождения включительно в формате ГГГГММДД.
+	BornFrom string         `json:"born_from,omitempty"`
+	BornTo   string         `json:"born_to,omitempty"`
+	Statuses ClientStatuses `json:"statuses,omitempty"`
+	// Tags — метки клиента: все, а при AnyTag — хотя бы одна.
+	Tags   []string `json:"tags,omitempty"`
+	AnyTag bool     `json:"any_tag,omitempty"`
+
+	// All — условия, которые должны выполняться все.
+	All []Filter `json:"all,omitempty"`
+	// Any — условия, из которых должно выполняться хотя бы одно.
+	Any []Filter `json:"any,omitempty"`
+	// Not — условие, которое не должно выполняться.
+	Not *Filter `json:"not,omitempty"`
+}
+
+// And возвращает условие, выполняющееся, когда выполняются все filters.
+func And(filters ...Filter) Filter {
+	return Filter{All: filters}
+}
+
+// Or возвращает условие, выполняющееся, когда выполняется хотя бы одно из
+// filters. Без filters условие не выполняется ни для кого.
+func Or(filters ...Filter) Filter {
+	if len(filters) == 0 {
+		return Not(Filter{})
+	}
+
+	return Filter{Any: filters}
+}
+
+// Not возвращает отрицание условия f.
+func Not(f Filter) Filter {
+	return Filter{Not: &f}
+}
+
+// ParseFilter разбирает условие отбора из JSON. Неизвестные поля считаются
+// ошибкой, чтобы опечатка в условии не расширяла выборку.
+func ParseFilter(data []byte) (Filter, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var f Filter
+	if err := dec.Decode(&f); err != nil {
+		return Filter{}, fmt.Errorf("%w: filter: %v", ErrValidation, err)
+	}
+	if _, _, err := f.SQL(); err != nil {
+		return Filter{}, err
+	}
+
+	return f, nil
+}
+
+// IsEmpty сообщает, что условие не ограничивает отбор.
+func (f Filter) IsEmpty() bool {
+	return f.FIOContains == "" && f.FIOPrefix == "" && f.EmailEquals == "" && f.BornFrom == "" && f.BornTo == "" &&
+		len(f.Statuses) == 0 && len(f.Tags) == 0 && !f.AnyTag &&
+		len(f.All) == 0 && len(f.Any) == 0 && f.Not == nil
+}
+
+// SQL возвращает условие в виде логического выражения SQL над столбцами
+// clients и его именованные параметры :f0, :f1, … Пустое условие — «1».
+// Некорректные значения полей возвращают ошибку, оборачивающую
+// ErrValidation.
+func (f Filter) SQL() (string, []any, error) {
+	var b filterBuilder
+	expr, err := b.build(f)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return expr, b.args, nil
+}
+
+// filterBuilder собирает выражение Filter и нумерует его параметры.
+type filterBuilder struct {
+	args []any
+}
+
+// param добавляет параметр со значением v и возвращает его подстановку.
+func (b *filterBuilder) param(v any) string {
+	name := fmt.Sprintf("f%d", len(b.args))
+	b.args = append(b.args, sql.Named(name, v))
+
+	return ":" + name
+}
+
+func (b *filterBuilder) build(f Filter) (string, error) {
+	var terms []string
+	if f.FIOContains != "" {
+		terms = append(terms, "instr(fio, "+b.param(f.FIOContains)+") > 0")
+	}
+	if f.FIOPrefix != "" {
+		terms = append(terms, "instr(fio, "+b.param(f.FIOPrefix)+") = 1")
+	}
+	if f.EmailEquals != "" {
+		terms = append(terms, "email = "+b.param(f.EmailEquals)+" COLLATE NOCASE")
+	}
+
+	for _, born := range []string{f.BornFrom, f.BornTo} {
+		if born == "" {
+			continue
+		}
+		if _, err := time.Parse(birthdayLayout, born); err != nil {
+			return "", fmt.Errorf("%w: birthday bound %q is not a valid YYYYMMDD date", ErrValidation, born)
+		}
+	}
+	switch {
+	case f.BornFrom != "" && f.BornTo != "":
+		if f.BornFrom > f.BornTo {
+			return "", fmt.Errorf("%w: born_from %s is after born_to %s", ErrValidation, f.BornFrom, f.BornTo)
+		}
+		terms = append(terms, "birthday BETWEEN "+b.param(f.BornFrom)+" AND "+b.param(f.BornTo))
+	case f.BornFrom != "":
+		terms = append(terms, "birthday >= "+b.param(f.BornFrom))
+	case f.BornTo != "":
+		terms = append(terms, "birthday <= "+b.param(f.BornTo))
+	}
+
+	if err := f.Statuses.Validate(); err != nil {
+		return "", err
+	}
+	if in := f.Statuses.in(b.param); in != "" {
+		terms = append(terms, in)
+	}
+
+	if f.AnyTag && len(f.Tags) == 0 {
+		return "", fmt.Errorf("%w: any_tag requires tags", ErrValidation)
+	}
+	if len(f.Tags) > 0 {
+		term, err := b.tags(f.Tags, f.AnyTag)
+		if err != nil {
+			return "", err
+		}
+		terms = append(terms, term)
+	}
+
+	for _, sub := range f.All {
+		term, err := b.build(sub)
+		if err != nil {
+			return "", err
+		}
+		terms = append(terms, term)
+	}
+	if len(f.Any) > 0 {
+		alternatives := make([]string, len(f.Any))
+		for i, sub := range f.Any {
+			var err error
+			if alternatives[i], err = b.build(sub); err != nil {
+				return "", err
+			}
+		}
+		terms = append(terms, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	if f.Not != nil {
+		term, err := b.build(*f.Not)
+		if err != nil {
+			return "", err
+		}
+		terms = append(terms, "NOT "+term)
+	}
+
+	switch len(terms) {
+	case 0:
+		return "1", nil
+	case 1:
+		return terms[0], nil
+	default:
+		return "(" + strings.Join(terms, " AND ") + ")", nil
+	}
+}
+
+// tags возвращает условие на метки клиента, как у tagsCond.
+func (b *filterBuilder) tags(tags []string, anyTag bool) (string, error) {
+	names := make(map[string]struct{}, len(tags))
+	placeholders := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := names[tag]; ok {
+			continue
+		}
+		names[tag] = struct{}{}
+		placeholders = append(placeholders, b.param(tag))
+	}
+
+	having := ""
+	if !anyTag {
+		having = " HAVING COUNT(*) = " + b.param(len(placeholders))
+	}
+
+	return "id IN (SELECT ct.client_id FROM client_tags ct JOIN tags t ON t.id = ct.tag_id WHERE t.name IN (" +
+		strings.Join(placeholders, ", ") + ") GROUP BY ct.client_id" + having + ")", nil
+}
+
+// encryptedField возвращает имя первого поля условия, значения которого
+// хранятся зашифрованными, или пустую строку.
+func (f Filter) encryptedField() string {
+	switch {
+	case f.EmailEquals != "":
+		return "email"
+	case f.BornFrom != "" || f.BornTo != "":
+		return "birthday"
+	}
+	subs := append(append([]Filter(nil), f.All...), f.Any...)
+	if f.Not != nil {
+		subs = append(subs, *f.Not)
+	}
+	for _, sub := range subs {
+		if field := sub.encryptedField(); field != "" {
+			return field
+		}
+	}
+
+	return ""
+}
+
+// filterCond возвращает условие f для forEach (фрагмент WHERE, начинающийся
+// с AND) и его параметры. Условия на зашифрованные поля при включённом
+// шифровании отклоняются с ErrValidation.
+func (r *Repository) filterCond(f Filter) (string, []any, error) {
+	expr, args, err := f.SQL()
+	if err != nil {
+		return "", nil, err
+	}
+	if r.cipher != nil {
+		if field := f.encryptedField(); field != "" {
+			return "", nil, fmt.Errorf("%w: %s is encrypted and cannot be filtered by query", ErrValidation, field)
+		}
+	}
+	if expr == "1" {
+		return "", nil, nil
+	}
+
+	return " AND " + expr, args, nil
+}
+
+// Find вызывает fn для каждого клиента, подходящего под условие f, в
+// порядке возрастания ID.
+func (r *Repository) Find(ctx context.Context, f Filter, fn func(Client) error) (err error) {
+	ctx, end := r.startOperation(ctx, "find")
+	defer end(&err)
+
+	cond, args, err := r.filterCond(f)
+	if err != nil {
+		return err
+	}
+
+	return r.forEach(ctx, cond, args, fn)
+}
+
+// Параметры FindPage.
+const (
+	// DefaultPageLimit — сколько клиентов FindPage возвращает, если limit
+	// не задан.
+	DefaultPageLimit = 100
+	// MaxPageLimit — наибольшее число клиентов на странице.
+	MaxPageLimit = 1000
+)
+
+// errPageFull останавливает чтение клиентов, когда страница заполнена.
+var errPageFull = errors.New("page is full")
+
+// FindPage возвращает до limit (0 — DefaultPageLimit) клиентов,
+// подходящих под условие f, с ID больше after в порядке возрастания ID.
+// Следующая страница начинается после ID последнего клиента; страница
+// короче limit — последняя. limit больше MaxPageLimit — ошибка проверки.
+func (r *Repository) FindPage(ctx context.Context, f Filter, after, limit int) (_ []Client, err error) {
+	ctx, end := r.startOperation(ctx, "find_page")
+	defer end(&err)
+
+	switch {
+	case limit == 0:
+		limit = DefaultPageLimit
+	case limit < 0 || limit > MaxPageLimit:
+		return nil, fmt.Errorf("%w: page limit must be between 1 and %d, got %d", ErrValidation, MaxPageLimit, limit)
+	}
+
+	cond, args, err := r.filterCond(f)
+	if err != nil {
+		return nil, err
+	}
+	cond += " AND id > :page_after"
+	args = append(args, sql.Named("page_after", after))
+
+	clients := []Client{}
+	err = r.forEach(ctx, cond, args, func(cl Client) error {
+		clients = append(clients, cl)
+		if len(clients) == limit {
+			return errPageFull
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPageFull) {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// Count возвращает число клиентов, подходящих под условие f.
+func (r *Repository) Count(ctx context.Context, f Filter) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "count")
+	defer end(&err)
+
+	cond, condArgs, err := r.filterCond(f)
+	if err != nil {
+		return 0, err
+	}
+	scope, args := r.ownerScope(ctx)
+	args = append(args, condArgs...)
+
+	var n int
+	err = r.conn().QueryRowContext(ctx, "SELECT COUNT(*) FROM clients WHERE 1"+notDeleted+scope+cond, args...).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// DeleteWhere удаляет клиентов, подходящих под условие f, в одной
+// транзакции так же, как DeleteClients. Пустое условие отклоняется с
+// ErrValidation, чтобы опечатка не удалила всех клиентов. Число удаляемых
+// клиентов не ограничивается; DeleteClientsWhere дополнительно требует
+// явного предела.
+func (r *Repository) DeleteWhere(ctx context.Context, f Filter, opts DestructiveOptions) (_ Affected, err error) {
+	ctx, end := r.startOperation(ctx, "delete_where")
+	defer end(&err)
+
+	return r.deleteWhere(ctx, f, opts, 0)
+}
+
+// DeleteWhereOptions — параметры DeleteClientsWhere.
+type DeleteWhereOptions struct {
+	DestructiveOptions
+	// MaxRows — наибольшее число клиентов, которое можно удалить; если
+	// условию подходит больше, не удаляется ни один.
+	MaxRows int
+	// Force разрешает удаление без предела MaxRows.
+	Force bool
+}
+
+// DeleteClientsWhere удаляет клиентов, подходящих под условие f, как
+// DeleteWhere, но только если в opts задан предел MaxRows или явно указан
+// Force. Если условию подходит больше MaxRows клиентов, не удаляется ни
+// один и возвращается ErrTooManyClients; при заданном MaxRows он действует
+// и вместе с Force.
+func (r *Repository) DeleteClientsWhere(ctx context.Context, f Filter, opts DeleteWhereOptions) (_ Affected, err error) {
+	ctx, end := r.startOperation(ctx, "delete_clients_where")
+	defer end(&err)
+
+	if opts.MaxRows < 0 {
+		return Affected{}, fmt.Errorf("%w: max rows must not be negative, got %d", ErrValidation, opts.MaxRows)
+	}
+	if opts.MaxRows == 0 && !opts.Force {
+		return Affected{}, fmt.Errorf("%w: set a max rows limit or force to delete by filter", ErrValidation)
+	}
+
+	return r.deleteWhere(ctx, f, opts.DestructiveOptions, opts.MaxRows)
+}
+
+// deleteWhere выполняет DeleteWhere; при maxRows > 0 отказывается удалять,
+// если условию подходит больше maxRows клиентов.
+func (r *Repository) deleteWhere(ctx context.Context, f Filter, opts DestructiveOptions, maxRows int) (Affected, error) {
+	if f.IsEmpty() {
+		return Affected{}, fmt.Errorf("%w: filter matches all clients", ErrValidation)
+	}
+	cond, condArgs, err := r.filterCond(f)
+	if err != nil {
+		return Affected{}, err
+	}
+	scope, args := r.ownerScope(ctx)
+	args = append(args, condArgs...)
+
+	var (
+		affected Affected
+		blobKeys []string
+	)
+	err = r.inTxDryRun(ctx, opts.DryRun, func(q querier) error {
+		affected = Affected{Rows: make(map[string]int64)}
+		blobKeys = nil
+		ids, err := queryInts(ctx, q, "SELECT id FROM clients WHERE 1"+notDeleted+scope+cond+" ORDER BY id", args...)
+		if err != nil {
+			return err
+		}
+		// Предел проверяется в той же транзакции, что и удаление, чтобы
+		// клиенты, добавленные после подсчёта, не обошли его
+		if maxRows > 0 && len(ids) > maxRows {
+			return fmt.Errorf("%w: filter matches %d clients, max %d", ErrTooManyClients, len(ids), maxRows)
+		}
+		for _, id := range ids {
+			keys, err := r.delete(ctx, q, id, affected.Rows)
+			if err != nil {
+				return fmt.Errorf("client %d: %w", id, err)
+			}
+			affected.ClientIDs = append(affected.ClientIDs, id)
+			blobKeys = append(blobKeys, keys...)
+		}
+		return nil
+	})
+	if err != nil {
+		return Affected{}, err
+	}
+	if opts.DryRun {
+		return affected, nil
+	}
+
+	return affected, r.deleteBlobs(ctx, blobKeys)
+}
+
+// ClientChanges — изменения полей клиентов для UpdateClientsWhere: nil
+// оставляет поле прежним.
+type ClientChanges struct {
+	FIO      *string `json:"fio,omitempty"`
+	Login    *string `json:"login,omitempty"`
+	Birthday *string `json:"birthday,omitempty"`
+	Email    *string `json:"email,omitempty"`
+}
+
+// IsEmpty сообщает, что изменения не меняют ни одного поля.
+func (c ClientChanges) IsEmpty() bool {
+	return c.FIO == nil && c.Login == nil && c.Birthday == nil && c.Email == nil
+}
+
+// apply применяет изменения к клиенту cl.
+func (c ClientChanges) apply(cl *Client) {
+	for _, field := range []struct{ dst, src *string }{
+		{&cl.FIO, c.FIO},
+		{&cl.Login, c.Login},
+		{&cl.Birthday, c.Birthday},
+		{&cl.Email, c.Email},
+	} {
+		if field.src != nil {
+			*field.dst = *field.src
+		}
+	}
+}
+
+// UpdateClientsWhere применяет изменения changes ко всем клиентам,
+// подходящим под условие f, одним запросом в транзакции и возвращает
+// изменённых клиентов. Как и при Update, каждый клиент проверяется,
+// прежняя версия сохраняется в clients_history, valid_from получает время
+// новой версии, записываются аудит и событие, а версия строки
+// увеличивается. Клиенты, у которых поля уже равны новым значениям, не
+// изменяются, и их версия остаётся прежней.
+//
+// expected — версии клиентов по ID, прочитанные ClientVersions с тем же
+// условием. Если условию подходят не те клиенты или версия хотя бы одного
+// из них изменилась, не изменяется ни один и возвращается
+// ErrVersionConflict: изменение не затирает чужие изменения, сделанные
+// после чтения. Так же не изменяется ни один клиент, если хотя бы один
+// после изменения не проходит проверку. Пустое условие и пустые изменения
+// отклоняются с ErrValidation.
+func (r *Repository) UpdateClientsWhere(ctx context.Context, f Filter, changes ClientChanges, expected map[int]int) (_ Affected, err error) {
+	ctx, end := r.startOperation(ctx, "update_where")
+	defer end(&err)
+
+	if f.IsEmpty() {
+		return Affected{}, fmt.Errorf("%w: filter matches all clients", ErrValidation)
+	}
+	if changes.IsEmpty() {
+		return Affected{}, fmt.Errorf("%w: no fields to change", ErrValidation)
+	}
+	if expected == nil {
+		return Affected{}, fmt.Errorf("%w: expected client versions are required", ErrValidation)
+	}
+	cond, condArgs, err := r.filterCond(f)
+	if err != nil {
+		return Affected{}, err
+	}
+	scope, args := r.ownerScope(ctx)
+	args = append(args, condArgs...)
+
+	var affected Affected
+	err = r.inTx(ctx, func(q querier) error {
+		affected = Affected{ClientIDs: []int{}, Rows: make(map[string]int64)}
+		matched, err := r.versionedClients(ctx, q, "SELECT "+columnList[versionedClient]()+" FROM clients WHERE 1"+notDeleted+scope+cond+" ORDER BY id", args...)
+		if err != nil {
+			return err
+		}
+		if err := checkVersions(matched, expected); err != nil {
+			return err
+		}
+
+		var before, after, stored []versionedClient
+		for _, cl := range matched {
+			changed := cl
+			changes.apply(&changed.Client)
+			if changed == cl {
+				continue
+			}
+			if err := changed.validateAt(r.now()); err != nil {
+				return fmt.Errorf("client %d: %w", cl.ID, err)
+			}
+			encrypted, err := r.encrypt(changed.Client)
+			if err != nil {
+				return fmt.Errorf("client %d: %w", cl.ID, err)
+			}
+			before, after = append(before, cl), append(after, changed)
+			stored = append(stored, versionedClient{Client: encrypted, Version: cl.Version})
+			affected.ClientIDs = append(affected.ClientIDs, cl.ID)
+		}
+		if len(stored) > 0 {
+			if err := r.updateRows(ctx, q, changes, stored); err != nil {
+				return err
+			}
+		}
+		for i, id := range affected.ClientIDs {
+			if err := r.audit(ctx, q, AuditUpdate, id, &before[i].Client, &after[i].Client); err != nil {
+				return fmt.Errorf("client %d: %w", id, err)
+			}
+		}
+		affected.Rows["clients"] = int64(len(affected.ClientIDs))
+		return nil
+	})
+	if err != nil {
+		return Affected{}, err
+	}
+
+	return affected, nil
+}
+
+// ClientVersions возвращает версии клиентов, подходящих под условие f, по
+// их ID — для UpdateClientsWhere с тем же условием.
+func (r *Repository) ClientVersions(ctx context.Context, f Filter) (_ map[int]int, err error) {
+	ctx, end := r.startOperation(ctx, "client_versions")
+	defer end(&err)
+
+	cond, condArgs, err := r.filterCond(f)
+	if err != nil {
+		return nil, err
+	}
+	scope, args := r.ownerScope(ctx)
+	args = append(args, condArgs...)
+
+	rows, err := r.conn().QueryContext(ctx, "SELECT id, version FROM clients WHERE 1"+notDeleted+scope+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]int)
+	for rows.Next() {
+		var id, version int
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, err
+		}
+		versions[id] = version
+	}
+
+	return versions, rows.Err()
+}
+
+// versionedClient — клиент с версией строки (см. ClientVersions).
+type versionedClient struct {
+	Client
+	Version int `db:"version"`
+}
+
+// versionedClients читает запросом query клиентов с версиями и
+// расшифровывает их.
+func (r *Repository) versionedClients(ctx context.Context, q querier, query string, args ...any) ([]versionedClient, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []versionedClient
+	for rows.Next() {
+		var cl versionedClient
+		if err := scanRows(rows, &cl); err != nil {
+			return nil, err
+		}
+		if cl.Client, err = r.decrypt(cl.Client); err != nil {
+			return nil, err
+		}
+		clients = append(clients, cl)
+	}
+
+	return clients, rows.Err()
+}
+
+// checkVersions возвращает ErrVersionConflict, если клиенты matched и их
+// версии отличаются от ожидаемых expected.
+func checkVersions(matched []versionedClient, expected map[int]int) error {
+	seen := make(map[int]bool, len(matched))
+	for _, cl := range matched {
+		version, ok := expected[cl.ID]
+		if !ok {
+			return fmt.Errorf("%w: client %d matches the filter but has no expected version", ErrVersionConflict, cl.ID)
+		}
+		if version != cl.Version {
+			return fmt.Errorf("%w: client %d has version %d, expected %d", ErrVersionConflict, cl.ID, cl.Version, version)
+		}
+		seen[cl.ID] = true
+	}
+
+	var missing []int
+	for id := range expected {
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Ints(missing)
+		return fmt.Errorf("%w: client %d no longer matches the filter", ErrVersionConflict, missing[0])
+	}
+
+	return nil
+}
+
+// changedColumns возвращает столбцы, которые меняют изменения, и их
+// значения у клиента cl.
+func (c ClientChanges) changedColumns(cl Client) (columns, values []string) {
+	for _, field := range []struct {
+		column string
+		change *string
+		value  string
+	}{
+		{clientColumnFIO, c.FIO, cl.FIO},
+		{clientColumnLogin, c.Login, cl.Login},
+		{clientColumnBirthday, c.Birthday, cl.Birthday},
+		{clientColumnEmail, c.Email, cl.Email},
+	} {
+		if field.change != nil {
+			columns = append(columns, field.column)
+			values = append(values, field.value)
+		}
+	}
+
+	return columns, values
+}
+
+// updateRows записывает изменённые changes столбцы клиентов stored (в
+// виде хранения, с версиями, прочитанными до изменения) одним запросом
+// UPDATE, сохранив их прежние версии в clients_history и увеличив версии
+// строк. Если версия хотя бы одного клиента успела измениться,
+// возвращается ErrVersionConflict.
+func (r *Repository) updateRows(ctx context.Context, q querier, changes ClientChanges, stored []versionedClient) error {
+	var (
+		columns []string
+		ids     = make([]int, len(stored))
+		rows    = make([]map[string]any, len(stored))
+	)
+	for i, cl := range stored {
+		var values []string
+		columns, values = changes.changedColumns(cl.Client)
+		rows[i] = map[string]any{"id": cl.ID, "version": cl.Version}
+		for j, column := range columns {
+			rows[i][column] = values[j]
+		}
+		ids[i] = cl.ID
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	set := make([]string, len(columns))
+	for i, column := range columns {
+		set[i] = fmt.Sprintf("%[1]s = u.value ->> '%[1]s'", column)
+	}
+
+	changedAt, err := r.recordHistoryOf(ctx, q, ids, AuditUpdate)
+	if err != nil {
+		return err
+	}
+	res, err := q.ExecContext(ctx, "UPDATE clients SET "+strings.Join(set, ", ")+`, valid_from = :valid_from, version = clients.version + 1
+		FROM json_each(:rows) AS u
+		WHERE clients.id = u.value ->> 'id' AND clients.version = u.value ->> 'version'`,
+		sql.Named("valid_from", changedAt),
+		sql.Named("rows", string(data)))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != int64(len(rows)) {
+		return fmt.Errorf("%w: %d of %d clients were changed concurrently", ErrVersionConflict, int64(len(rows))-n, len(rows))
+	}
+
+	return nil
+}
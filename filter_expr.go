@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения выражения условия отбора (см. ParseFilterExpr).
+const (
+	maxFilterExprLen   = 4096
+	maxFilterExprDepth = 32
+)
+
+// ParseFilterExpr разбирает условие отбора, записанное компактным
+// выражением для строки запроса, например
+//
+//	fio==Иван*;birthday=ge=1990-01-01,status=in=(blocked,archived)
+//
+// Выражение состоит из сравнений «поле оператор значение», объединённых
+// «;» (и) и «,» (или); «;» связывает сильнее, порядок меняется скобками.
+// Поля и операторы:
+//
+//   - fio: == и != со значением «начало*» или «*подстрока*»;
+//   - email: ==, !=, =in=, =out=;
+//   - birthday: ==, !=, =in=, =out=, =gt=, =ge=, =lt=, =le= с датой
+//     ГГГГ-ММ-ДД или ГГГГММДД;
+//   - status: ==, !=, =in=, =out=;
+//   - tag: == и != (метка есть или нет), =in= (хотя бы одна из меток),
+//     =out= (ни одной из меток).
+//
+// Значения с пробелами и символами ;,()'" заключаются в одинарные или
+// двойные кавычки; внутри кавычек \ экранирует следующий символ. Пустое
+// выражение — пустой Filter. Ошибка оборачивает ErrValidation и указывает
+// позицию в выражении.
+func ParseFilterExpr(expr string) (Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return Filter{}, nil
+	}
+	if len(expr) > maxFilterExprLen {
+		return Filter{}, fmt.Errorf("%w: filter expression is longer than %d bytes", ErrValidation, maxFilterExprLen)
+	}
+	if !utf8.ValidString(expr) {
+		return Filter{}, fmt.Errorf("%w: filter expression is not valid UTF-8", ErrValidation)
+	}
+
+	p := filterExprParser{s: expr}
+	f, err := p.or(0)
+	if err != nil {
+		return Filter{}, err
+	}
+	p.skipSpace()
+	if !p.done() {
+		return Filter{}, p.errorf("unexpected %q", p.peek())
+	}
+	if _, _, err := f.SQL(); err != nil {
+		return Filter{}, err
+	}
+
+	return f, nil
+}
+
+// filterExprParser — разбор выражения ParseFilterExpr рекурсивным спуском.
+type filterExprParser struct {
+	s   string
+	pos int
+}
+
+// errorf возвращает ошибку разбора с номером текущего символа выражения.
+func (p *filterExprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: filter expression at position %d: %s", ErrValidation,
+		utf8.RuneCountInString(p.s[:p.pos])+1, fmt.Sprintf(format, args...))
+}
+
+func (p *filterExprParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+// peek возвращает текущий символ или 0 в конце выражения.
+func (p *filterExprParser) peek() rune {
+	if p.done() {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(p.s[p.pos:])
+
+	return r
+}
+
+func (p *filterExprParser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// consume пропускает пробелы и символ c, если он следующий.
+func (p *filterExprParser) consume(c rune) bool {
+	p.skipSpace()
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+
+	return true
+}
+
+// or разбирает условия, разделённые «,».
+func (p *filterExprParser) or(depth int) (Filter, error) {
+	var alternatives []Filter
+	for {
+		f, err := p.and(depth)
+		if err != nil {
+			return Filter{}, err
+		}
+		alternatives = append(alternatives, f)
+		if !p.consume(',') {
+			break
+		}
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+
+	return Or(alternatives...), nil
+}
+
+// and разбирает условия, разделённые «;».
+func (p *filterExprParser) and(depth int) (Filter, error) {
+	var terms []Filter
+	for {
+		f, err := p.term(depth)
+		if err != nil {
+			return Filter{}, err
+		}
+		terms = append(terms, f)
+		if !p.consume(';') {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+
+	return And(terms...), nil
+}
+
+// term разбирает условие в скобках или сравнение.
+func (p *filterExprParser) term(depth int) (Filter, error) {
+	if !p.consume('(') {
+		return p.comparison()
+	}
+	if depth >= maxFilterExprDepth {
+		return Filter{}, p.errorf("parentheses are nested deeper than %d", maxFilterExprDepth)
+	}
+	f, err := p.or(depth + 1)
+	if err != nil {
+		return Filter{}, err
+	}
+	if !p.consume(')') {
+		return Filter{}, p.errorf("expected )")
+	}
+
+	return f, nil
+}
+
+// comparison разбирает сравнение «поле оператор значение».
+func (p *filterExprParser) comparison() (Filter, error) {
+	p.skipSpace()
+	start := p.pos
+	for c := p.peek(); c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'; c = p.peek() {
+		p.pos++
+	}
+	field := strings.ToLower(p.s[start:p.pos])
+	if field == "" {
+		return Filter{}, p.errorf("expected field name")
+	}
+	if _, ok := filterExprFields[field]; !ok {
+		p.pos = start
+		return Filter{}, p.errorf("unknown field %q, want fio, email, birthday, status or tag", field)
+	}
+
+	p.skipSpace()
+	opStart := p.pos
+	op, err := p.operator()
+	if err != nil {
+		return Filter{}, err
+	}
+
+	var values []string
+	if op == "=in=" || op == "=out=" {
+		if !p.consume('(') {
+			return Filter{}, p.errorf("expected ( with values of %s", op)
+		}
+		for {
+			v, err := p.value()
+			if err != nil {
+				return Filter{}, err
+			}
+			values = append(values, v)
+			if !p.consume(',') {
+				break
+			}
+		}
+		if !p.consume(')') {
+			return Filter{}, p.errorf("expected ) after values of %s", op)
+		}
+	} else {
+		v, err := p.value()
+		if err != nil {
+			return Filter{}, err
+		}
+		values = []string{v}
+	}
+
+	f, err := filterExprFields[field](op, values)
+	if err != nil {
+		p.pos = opStart
+		return Filter{}, p.errorf("%s%s: %s", field, op, strings.TrimPrefix(err.Error(), ErrValidation.Error()+": "))
+	}
+
+	return f, nil
+}
+
+// operator разбирает оператор сравнения: ==, != или =имя=.
+func (p *filterExprParser) operator() (string, error) {
+	rest := p.s[p.pos:]
+	for _, op := range []string{"==", "!=", "=in=", "=out=", "=gt=", "=ge=", "=lt=", "=le="} {
+		if strings.HasPrefix(rest, op) {
+			p.pos += len(op)
+			return op, nil
+		}
+	}
+
+	return "", p.errorf("expected operator ==, !=, =in=, =out=, =gt=, =ge=, =lt= or =le=")
+}
+
+// value разбирает значение: строку в кавычках или последовательность
+// символов до пробела или одного из ;,()'".
+func (p *filterExprParser) value() (string, error) {
+	p.skipSpace()
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		start := p.pos
+		for !p.done() && !strings.ContainsRune(" \t;,()'\"", p.peek()) {
+			p.pos += utf8.RuneLen(p.peek())
+		}
+		if p.pos == start {
+			return "", p.errorf("expected value")
+		}
+		return p.s[start:p.pos], nil
+	}
+
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.done() {
+			p.pos = start
+			return "", p.errorf("unterminated quoted value")
+		}
+		c := p.peek()
+		p.pos += utf8.RuneLen(c)
+		switch c {
+		case quote:
+			return b.String(), nil
+		case '\\':
+			if p.done() {
+				p.pos = start
+				return "", p.errorf("unterminated quoted value")
+			}
+			c = p.peek()
+			p.pos += utf8.RuneLen(c)
+		}
+		b.WriteRune(c)
+	}
+}
+
+// filterExprFields переводит сравнение с полем выражения в Filter.
+var filterExprFields = map[string]func(op string, values []string) (Filter, error){
+	"fio":      fioExpr,
+	"email":    equalityExpr(func(v string) (Filter, error) { return Filter{EmailEquals: v}, nil }),
+	"birthday": birthdayExpr,
+	"status":   statusExpr,
+	"tag":      tagExpr,
+}
+
+// errFilterExprOp — ошибка оператора, который не поддерживается полем.
+var errFilterExprOp = errors.New("operator is not supported for this field")
+
+// equalityExpr возвращает разбор сравнения на равенство для поля, у
+// которого eq строит условие «поле равно v»: =in= — хотя бы одно из
+// значений, != и =out= — отрицание.
+func equalityExpr(eq func(v string) (Filter, error)) func(op string, values []string) (Filter, error) {
+	return func(op string, values []string) (Filter, error) {
+		alternatives := make([]Filter, len(values))
+		for i, v := range values {
+			var err error
+			if alternatives[i], err = eq(v); err != nil {
+				return Filter{}, err
+			}
+		}
+		f := alternatives[0]
+		if len(alternatives) > 1 {
+			f = Or(alternatives...)
+		}
+
+		switch op {
+		case "==", "=in=":
+			return f, nil
+		case "!=", "=out=":
+			return Not(f), nil
+		default:
+			return Filter{}, errFilterExprOp
+		}
+	}
+}
+
+func fioExpr(op string, values []string) (Filter, error) {
+	if op != "==" && op != "!=" {
+		return Filter{}, errFilterExprOp
+	}
+
+	return equalityExpr(func(v string) (Filter, error) {
+		inner := strings.TrimSuffix(v, "*")
+		switch {
+		case inner == v || inner == "" || inner == "*":
+		case strings.HasPrefix(inner, "*") && !strings.Contains(inner[1:], "*"):
+			return Filter{FIOContains: inner[1:]}, nil
+		case !strings.Contains(inner, "*"):
+			return Filter{FIOPrefix: inner}, nil
+		}
+		return Filter{}, fmt.Errorf("pattern %q must be value* or *value*", v)
+	})(op, values)
+}
+
+func birthdayExpr(op string, values []string) (Filter, error) {
+	day := func(v string) (time.Time, error) {
+		for _, layout := range []string{"2006-01-02", birthdayLayout} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD or YYYYMMDD date", v)
+	}
+
+	switch op {
+	case "=gt=", "=ge=", "=lt=", "=le=":
+		t, err := day(values[0])
+		if err != nil {
+			return Filter{}, err
+		}
+		switch op {
+		case "=gt=":
+			return Filter{BornFrom: t.AddDate(0, 0, 1).Format(birthdayLayout)}, nil
+		case "=ge=":
+			return Filter{BornFrom: t.Format(birthdayLayout)}, nil
+		case "=lt=":
+			return Filter{BornTo: t.AddDate(0, 0, -1).Format(birthdayLayout)}, nil
+		default:
+			return Filter{BornTo: t.Format(birthdayLayout)}, nil
+		}
+	}
+
+	return equalityExpr(func(v string) (Filter, error) {
+		t, err := day(v)
+		if err != nil {
+			return Filter{}, err
+		}
+		return Filter{BornFrom: t.Format(birthdayLayout), BornTo: t.Format(birthdayLayout)}, nil
+	})(op, values)
+}
+
+func statusExpr(op string, values []string) (Filter, error) {
+	statuses := make([]ClientStatus, len(values))
+	for i, v := range values {
+		statuses[i] = ClientStatus(v)
+		if !statuses[i].Valid() {
+			return Filter{}, fmt.Errorf("unknown client status %q", v)
+		}
+	}
+
+	switch op {
+	case "==", "=in=":
+		return Filter{Statuses: statuses}, nil
+	case "!=", "=out=":
+		return Not(Filter{Statuses: statuses}), nil
+	default:
+		return Filter{}, errFilterExprOp
+	}
+}
+
+func tagExpr(op string, values []string) (Filter, error) {
+	for _, v := range values {
+		if _, err := normalizeTag(v); err != nil {
+			return Filter{}, err
+		}
+	}
+	f := Filter{Tags: values, AnyTag: true}
+
+	switch op {
+	case "==", "=in=":
+		return f, nil
+	case "!=", "=out=":
+		return Not(f), nil
+	default:
+		return Filter{}, errFilterExprOp
+	}
+}
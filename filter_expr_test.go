@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет разбор выражений условия отбора: поля, операторы,
+// кавычки и приоритет «;» над «,»
+func Test_ParseFilterExpr(t *testing.T) {
+	vip := Filter{Tags: []string{"vip"}, AnyTag: true}
+
+	tests := []struct {
+		name string
+		expr string
+		want Filter
+	}{
+		{"Empty", " ", Filter{}},
+		{"FIOPrefix", "fio==Иван*", Filter{FIOPrefix: "Иван"}},
+		{"FIOContains", "fio==*Иван*", Filter{FIOContains: "Иван"}},
+		{"FIONot", "fio!=Иван*", Not(Filter{FIOPrefix: "Иван"})},
+		{"Quoted", `fio=="Иванов Иван*"`, Filter{FIOPrefix: "Иванов Иван"}},
+		{"SingleQuotedWithEscapes", `fio=='*О\'Нил \\*'`, Filter{FIOContains: `О'Нил \`}},
+		{"Email", "email==ivan@mail.com", Filter{EmailEquals: "ivan@mail.com"}},
+		{"EmailIn", "email=in=(a@mail.com, b@mail.com)", Or(Filter{EmailEquals: "a@mail.com"}, Filter{EmailEquals: "b@mail.com"})},
+		{"EmailOut", "email=out=(a@mail.com)", Not(Filter{EmailEquals: "a@mail.com"})},
+		{"BirthdayEqual", "birthday==1990-01-01", Filter{BornFrom: "19900101", BornTo: "19900101"}},
+		{"BirthdayGe", "birthday=ge=1990-01-01", Filter{BornFrom: "19900101"}},
+		{"BirthdayGt", "birthday=gt=19901231", Filter{BornFrom: "19910101"}},
+		{"BirthdayLe", "birthday=le=1990-03-01", Filter{BornTo: "19900301"}},
+		{"BirthdayLt", "birthday=lt=1990-03-01", Filter{BornTo: "19900228"}},
+		{"Status", "status==blocked", Filter{Statuses: []ClientStatus{StatusBlocked}}},
+		{"StatusOut", "status=out=(blocked,archived)", Not(Filter{Statuses: []ClientStatus{StatusBlocked, StatusArchived}})},
+		{"Tag", "tag==vip", vip},
+		{"TagIn", "tag=in=(vip,wholesale)", Filter{Tags: []string{"vip", "wholesale"}, AnyTag: true}},
+		{"TagNot", "tag!=vip", Not(vip)},
+		{"FieldCase", "Status==active", Filter{Statuses: []ClientStatus{StatusActive}}},
+		{
+			"AndBindsTighterThanOr",
+			"fio==Иван*;tag==vip,status==blocked",
+			Or(And(Filter{FIOPrefix: "Иван"}, vip), Filter{Statuses: []ClientStatus{StatusBlocked}}),
+		},
+		{
+			"Parentheses",
+			" fio==Иван* ; ( tag==vip , status==blocked ) ",
+			And(Filter{FIOPrefix: "Иван"}, Or(vip, Filter{Statuses: []ClientStatus{StatusBlocked}})),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFilterExpr(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f)
+		})
+	}
+}
+
+// Тест проверяет, что некорректные выражения отклоняются с ErrValidation
+// и понятной причиной с позицией ошибки
+func Test_ParseFilterExpr_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"UnknownField", "login==ivan", `at position 1: unknown field "login"`},
+		{"MissingField", "==ivan", "at position 1: expected field name"},
+		{"MissingOperator", "fio Иван*", "at position 5: expected operator"},
+		{"UnknownOperator", "birthday=after=1990-01-01", "at position 9: expected operator"},
+		{"MissingValue", "status==", "at position 9: expected value"},
+		{"MissingInParenthesis", "status=in=blocked", "at position 11: expected ( with values of =in="},
+		{"UnclosedIn", "status=in=(blocked", "at position 19: expected ) after values of =in="},
+		{"UnclosedGroup", "(tag==vip", "at position 10: expected )"},
+		{"TrailingInput", "tag==vip)", `at position 9: unexpected ')'`},
+		{"DanglingAnd", "tag==vip;", "at position 10: expected field name"},
+		{"Unterminated", `fio=="Иван*`, "at position 6: unterminated quoted value"},
+		{"FIOPattern", "fio==Иван", `at position 4: fio==: pattern "Иван" must be value* or *value*`},
+		{"FIOInnerWildcard", "fio==*Ив*ан*", "must be value* or *value*"},
+		{"FIOOperator", "fio=in=(Иван*)", "at position 4: fio=in=: operator is not supported"},
+		{"InvalidDate", "birthday=ge=1990-02-30", `birthday=ge=: "1990-02-30" is not a YYYY-MM-DD or YYYYMMDD date`},
+		{"StatusOrdering", "status=gt=active", "status=gt=: operator is not supported"},
+		{"UnknownStatus", "status=in=(active,deleted)", `unknown client status "deleted"`},
+		{"InvalidTag", "tag==' '", "tag==: tag"},
+		{"TooDeep", strings.Repeat("(", maxFilterExprDepth+1) + "tag==vip" + strings.Repeat(")", maxFilterExprDepth+1), "nested deeper than"},
+		{"TooLong", "fio==" + strings.Repeat("x", maxFilterExprLen) + "*", "longer than"},
+		{"InvalidUTF8", "fio==\xff*", "not valid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilterExpr(tt.expr)
+			require.ErrorIs(t, err, ErrValidation)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+// Фаззинг разбора выражений: разбор не паникует, ошибка всегда оборачивает
+// ErrValidation, а принятое выражение переводится в SQL
+func FuzzParseFilterExpr(f *testing.F) {
+	for _, seed := range []string{
+		"fio==Иван*;birthday=ge=1990-01-01",
+		"(tag==vip,status=in=(blocked,archived));email!=a@mail.com",
+		`fio=='*О\'Нил*'`,
+		"birthday=lt=20000301,birthday=gt=19991231",
+		"((((tag==vip))))",
+		"status=out=(",
+		`fio=="`,
+		";,()",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, expr string) {
+		filter, err := ParseFilterExpr(expr)
+		if err != nil {
+			require.ErrorIs(t, err, ErrValidation)
+			return
+		}
+
+		_, _, err = filter.SQL()
+		require.NoError(t, err, "accepted expression %q should compile", expr)
+	})
+}
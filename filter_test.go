@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagsSubquery — условие на метки, которое Filter строит для параметров names.
+func tagsSubquery(names, having string) string {
+	return "id IN (SELECT ct.client_id FROM client_tags ct JOIN tags t ON t.id = ct.tag_id WHERE t.name IN (" +
+		names + ") GROUP BY ct.client_id" + having + ")"
+}
+
+// Тест проверяет SQL, в который переводится каждое поле и каждый
+// комбинатор условия отбора, и нумерацию параметров
+func Test_Filter_SQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+		args   []any
+	}{
+		{"Empty", Filter{}, "1", nil},
+		{"FIOContains", Filter{FIOContains: "Иван"}, "instr(fio, :f0) > 0", []any{"Иван"}},
+		{"FIOPrefix", Filter{FIOPrefix: "Иван"}, "instr(fio, :f0) = 1", []any{"Иван"}},
+		{"EmailEquals", Filter{EmailEquals: "ivan@mail.com"}, "email = :f0 COLLATE NOCASE", []any{"ivan@mail.com"}},
+		{"BornBetween", Filter{BornFrom: "19800101", BornTo: "19891231"}, "birthday BETWEEN :f0 AND :f1", []any{"19800101", "19891231"}},
+		{"BornFrom", Filter{BornFrom: "19800101"}, "birthday >= :f0", []any{"19800101"}},
+		{"BornTo", Filter{BornTo: "19891231"}, "birthday <= :f0", []any{"19891231"}},
+		{"Statuses", Filter{Statuses: []ClientStatus{StatusActive, StatusBlocked}}, "status IN (:f0, :f1)", []any{"active", "blocked"}},
+		{"AllTags", Filter{Tags: []string{"VIP", "vip", "wholesale"}}, tagsSubquery(":f0, :f1", " HAVING COUNT(*) = :f2"), []any{"vip", "wholesale", 2}},
+		{"AnyTag", Filter{Tags: []string{"vip", "wholesale"}, AnyTag: true}, tagsSubquery(":f0, :f1", ""), []any{"vip", "wholesale"}},
+		{
+			"FieldsAreJoinedWithAnd",
+			Filter{FIOContains: "Иван", Statuses: []ClientStatus{StatusActive}},
+			"(instr(fio, :f0) > 0 AND status IN (:f1))",
+			[]any{"Иван", "active"},
+		},
+		{
+			"And",
+			And(Filter{FIOContains: "Иван"}, Filter{BornFrom: "19800101"}),
+			"(instr(fio, :f0) > 0 AND birthday >= :f1)",
+			[]any{"Иван", "19800101"},
+		},
+		{"AndOfOne", And(Filter{FIOContains: "Иван"}), "instr(fio, :f0) > 0", []any{"Иван"}},
+		{"AndOfNone", And(), "1", nil},
+		{
+			"Or",
+			Or(Filter{FIOContains: "Иван"}, Filter{Statuses: []ClientStatus{StatusBlocked}}),
+			"(instr(fio, :f0) > 0 OR status IN (:f1))",
+			[]any{"Иван", "blocked"},
+		},
+		{"OrOfNone", Or(), "NOT 1", nil},
+		{"Not", Not(Filter{Statuses: []ClientStatus{StatusArchived}}), "NOT status IN (:f0)", []any{"archived"}},
+		{
+			"NotOfAnd",
+			Not(Filter{FIOContains: "Иван", BornTo: "19891231"}),
+			"NOT (instr(fio, :f0) > 0 AND birthday <= :f1)",
+			[]any{"Иван", "19891231"},
+		},
+		{
+			"Nested",
+			And(
+				Or(Filter{EmailEquals: "a@mail.com"}, Filter{EmailEquals: "b@mail.com"}),
+				Not(Or(Filter{Tags: []string{"churned"}}, Filter{Statuses: []ClientStatus{StatusBlocked}})),
+			),
+			"((email = :f0 COLLATE NOCASE OR email = :f1 COLLATE NOCASE) AND NOT (" +
+				tagsSubquery(":f2", " HAVING COUNT(*) = :f3") + " OR status IN (:f4)))",
+			[]any{"a@mail.com", "b@mail.com", "churned", 1, "blocked"},
+		},
+		{
+			"FieldsWithCombinators",
+			Filter{FIOContains: "Иван", Any: []Filter{{BornFrom: "19800101"}, {BornTo: "19600101"}}, Not: &Filter{EmailEquals: "x@mail.com"}},
+			"(instr(fio, :f0) > 0 AND (birthday >= :f1 OR birthday <= :f2) AND NOT email = :f3 COLLATE NOCASE)",
+			[]any{"Иван", "19800101", "19600101", "x@mail.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, args, err := tt.filter.SQL()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expr)
+
+			var want []any
+			for i, v := range tt.args {
+				want = append(want, sql.Named(fmt.Sprintf("f%d", i), v))
+			}
+			assert.Equal(t, want, args)
+		})
+	}
+}
+
+// Тест проверяет отказ от некорректных условий, в том числе вложенных
+func Test_Filter_SQLInvalid(t *testing.T) {
+	for name, filter := range map[string]Filter{
+		"InvalidBirthday":   {BornFrom: "19801301"},
+		"ReversedRange":     {BornFrom: "19900101", BornTo: "19800101"},
+		"UnknownStatus":     {Statuses: []ClientStatus{"deleted"}},
+		"EmptyTag":          {Tags: []string{" "}},
+		"AnyTagWithoutTags": {AnyTag: true},
+		"InvalidInAnd":      And(Filter{FIOContains: "Иван"}, Filter{BornTo: "1980"}),
+		"InvalidInOr":       Or(Filter{Statuses: []ClientStatus{"deleted"}}),
+		"InvalidInNot":      Not(Filter{AnyTag: true}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := filter.SQL()
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Тест проверяет разбор условия из JSON и отказ от неизвестных полей
+func Test_ParseFilter(t *testing.T) {
+	f, err := ParseFilter([]byte(`{"fio_contains":"Иван","any":[{"statuses":["blocked"]},{"tags":["vip"]}],"not":{"born_to":"19600101"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, Filter{
+		FIOContains: "Иван",
+		Any:         []Filter{{Statuses: []ClientStatus{StatusBlocked}}, {Tags: []string{"vip"}}},
+		Not:         &Filter{BornTo: "19600101"},
+	}, f)
+
+	for name, data := range map[string]string{
+		"UnknownField":  `{"fio": "Иван"}`,
+		"UnknownNested": `{"any": [{"status": "blocked"}]}`,
+		"Invalid":       `{"not": {"statuses": ["deleted"]}}`,
+		"Malformed":     `{"any": [`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseFilter([]byte(data))
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Тест проверяет отбор, подсчёт, выгрузку и удаление клиентов по условию
+func Test_Filter_Repository(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	ivan, err := repo.Insert(ctx, newTestClient(func(cl *Client) {
+		cl.FIO = "Иванов Иван"
+		cl.Email = "Ivan@Mail.com"
+		cl.Birthday = "19850615"
+	}))
+	require.NoError(t, err)
+	petr, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Петров Пётр"; cl.Birthday = "19700101" }))
+	require.NoError(t, err)
+	anna, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванова Анна"; cl.Birthday = "19950301" }))
+	require.NoError(t, err)
+	require.NoError(t, repo.TagClient(ctx, petr, "vip"))
+	require.NoError(t, repo.ChangeStatus(ctx, anna, StatusBlocked))
+
+	find := func(f Filter) []int {
+		var ids []int
+		require.NoError(t, repo.Find(ctx, f, func(cl Client) error {
+			ids = append(ids, cl.ID)
+			return nil
+		}))
+		return ids
+	}
+
+	assert.Equal(t, []int{ivan, petr, anna}, find(Filter{}))
+	assert.Equal(t, []int{ivan, anna}, find(Filter{FIOContains: "Иванов"}))
+	assert.Equal(t, []int{ivan}, find(Filter{FIOPrefix: "Иванов Иван"}))
+	assert.Equal(t, []int{ivan}, find(Filter{EmailEquals: "ivan@mail.com"}))
+	assert.Equal(t, []int{ivan, anna}, find(Filter{BornFrom: "19800101"}))
+	assert.Equal(t, []int{petr, anna}, find(Or(Filter{Tags: []string{"vip"}}, Filter{Statuses: []ClientStatus{StatusBlocked}})))
+	assert.Equal(t, []int{ivan}, find(And(Filter{FIOContains: "Иванов"}, Not(Filter{Statuses: []ClientStatus{StatusBlocked}}))))
+
+	n, err := repo.Count(ctx, Filter{FIOContains: "Иванов"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = repo.Count(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	var out bytes.Buffer
+	require.NoError(t, repo.Export(ctx, &out, ExportOptions{Format: FormatNDJSON, Where: Filter{Tags: []string{"vip"}}}))
+	assert.Contains(t, out.String(), "Петров Пётр")
+	assert.NotContains(t, out.String(), "Иванов")
+
+	_, err = repo.DeleteWhere(ctx, Filter{}, DestructiveOptions{})
+	require.ErrorIs(t, err, ErrValidation)
+
+	affected, err := repo.DeleteWhere(ctx, Filter{FIOContains: "Иванов"}, DestructiveOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int{ivan, anna}, affected.ClientIDs)
+	assertRowCount(t, db, "clients", 3, "1")
+
+	affected, err = repo.DeleteWhere(ctx, Filter{FIOContains: "Иванов"}, DestructiveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{ivan, anna}, affected.ClientIDs)
+	assert.Equal(t, []int{petr}, find(Filter{}))
+}
+
+// Тест проверяет, что условия на зашифрованные поля отклоняются при
+// включённом шифровании, а остальные условия работают
+func Test_Filter_Encrypted(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	_, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванов Иван" }))
+	require.NoError(t, err)
+
+	n, err := repo.Count(ctx, Filter{FIOContains: "Иванов"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	for name, f := range map[string]Filter{
+		"Email":       {EmailEquals: "mail@mail.com"},
+		"Birthday":    {BornFrom: "19700101"},
+		"NestedInNot": Not(Filter{BornTo: "19700101"}),
+		"NestedInOr":  Or(Filter{FIOContains: "Иванов"}, Filter{EmailEquals: "mail@mail.com"}),
+		"NestedInAnd": And(Filter{EmailEquals: "mail@mail.com"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.Count(ctx, f)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Тест проверяет изменение клиентов по условию: меняются только
+// подходящие клиенты и только заданные поля, у каждого изменённого
+// сохраняется прежняя версия, а клиенты без фактических изменений и
+// пустой отбор ничего не меняют
+func Test_UpdateClientsWhere(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	clock := testutil.NewFakeClock(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC))
+	repo := NewRepository(db, WithClock(clock), WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	ivan, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванов Иван"; cl.Email = "ivan@mail.com" }))
+	require.NoError(t, err)
+	anna, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванова Анна"; cl.Email = "anna@corp.ru" }))
+	require.NoError(t, err)
+	petr, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Петров Пётр"; cl.Email = "petr@mail.com" }))
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+
+	ivanovs := Filter{FIOPrefix: "Иванов"}
+	versions, err := repo.ClientVersions(ctx, ivanovs)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{ivan: 1, anna: 1}, versions)
+
+	email := "anna@corp.ru"
+	affected, err := repo.UpdateClientsWhere(ctx, ivanovs, ClientChanges{Email: &email}, versions)
+	require.NoError(t, err)
+	assert.Equal(t, Affected{ClientIDs: []int{ivan}, Rows: map[string]int64{"clients": 1}}, affected, "anna already has the email")
+	versions, err = repo.ClientVersions(ctx, ivanovs)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{ivan: 2, anna: 1}, versions, "only the changed client gets a new version")
+
+	cl, err := repo.Select(ctx, ivan)
+	require.NoError(t, err)
+	assert.Equal(t, "anna@corp.ru", cl.Email)
+	assert.Equal(t, "Иванов Иван", cl.FIO, "fields without changes should stay")
+	old, err := repo.SelectAsOf(ctx, ivan, clock.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "ivan@mail.com", old.Email, "the previous version should be kept")
+	assertRowCount(t, db, "clients_history", 1, "1")
+	assertRowCount(t, db, "audit_log", 1, "operation = 'update'")
+
+	cl, err = repo.Select(ctx, petr)
+	require.NoError(t, err)
+	assert.Equal(t, "petr@mail.com", cl.Email)
+
+	fio := "Сидоров Сидор"
+	affected, err = repo.UpdateClientsWhere(ctx, Filter{FIOContains: "Никто"}, ClientChanges{FIO: &fio}, map[int]int{})
+	require.NoError(t, err)
+	assert.Equal(t, Affected{ClientIDs: []int{}, Rows: map[string]int64{"clients": 0}}, affected)
+
+	_, err = repo.UpdateClientsWhere(ctx, Filter{}, ClientChanges{FIO: &fio}, versions)
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = repo.UpdateClientsWhere(ctx, ivanovs, ClientChanges{}, versions)
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = repo.UpdateClientsWhere(ctx, ivanovs, ClientChanges{FIO: &fio}, nil)
+	require.ErrorIs(t, err, ErrValidation, "expected versions are required")
+	_, err = repo.UpdateClientsWhere(ctx, Filter{EmailEquals: "ivan@mail.com"}, ClientChanges{FIO: &fio}, versions)
+	require.ErrorIs(t, err, ErrValidation, "encrypted fields cannot be matched by query")
+
+	invalid := "19701301"
+	_, err = repo.UpdateClientsWhere(ctx, Filter{FIOContains: "Иванов"}, ClientChanges{Birthday: &invalid}, versions)
+	require.ErrorIs(t, err, ErrValidation)
+	assert.ErrorContains(t, err, fmt.Sprintf("client %d", ivan))
+	cl, err = repo.Select(ctx, anna)
+	require.NoError(t, err)
+	assert.Equal(t, "19700101", cl.Birthday)
+}
+
+// Тест проверяет изменение тысяч клиентов одной операцией и откат всех
+// изменений, если один из подходящих клиентов не проходит проверку
+func Test_UpdateClientsWhere_Large(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	const n = 2000
+	for i := 0; i < n; i++ {
+		_, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = fmt.Sprintf("Клиент %d", i) }))
+		require.NoError(t, err)
+	}
+	_, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Другой" }))
+	require.NoError(t, err)
+
+	clients := Filter{FIOPrefix: "Клиент"}
+	versions, err := repo.ClientVersions(ctx, clients)
+	require.NoError(t, err)
+	login := "bulk"
+	affected, err := repo.UpdateClientsWhere(ctx, clients, ClientChanges{Login: &login}, versions)
+	require.NoError(t, err)
+	assert.Len(t, affected.ClientIDs, n)
+	assert.EqualValues(t, n, affected.Rows["clients"])
+	assertRowCount(t, db, "clients", n, "login = 'bulk'")
+	assertRowCount(t, db, "clients_history", n, "1")
+	assertRowCount(t, db, "clients", n, "version = 2")
+
+	// Клиент с некорректной датой рождения, записанный в обход проверки
+	_, err = db.ExecContext(ctx, "UPDATE clients SET birthday = '19701301' WHERE id = :id", sql.Named("id", n))
+	require.NoError(t, err)
+	versions, err = repo.ClientVersions(ctx, clients)
+	require.NoError(t, err)
+	login = "again"
+	_, err = repo.UpdateClientsWhere(ctx, clients, ClientChanges{Login: &login}, versions)
+	require.ErrorIs(t, err, ErrValidation)
+	assertRowCount(t, db, "clients", 0, "login = 'again'")
+	assertRowCount(t, db, "clients_history", n, "1")
+}
+
+// Тест проверяет, что изменение по условию с версиями, прочитанными до
+// изменения подходящего клиента другим запросом, до появления нового
+// подходящего клиента или до того, как один из клиентов перестал подходить,
+// не меняет ни одного клиента и возвращает конфликт
+func Test_UpdateClientsWhere_VersionConflict(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	ivan, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванов Иван" }))
+	require.NoError(t, err)
+	anna, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванова Анна" }))
+	require.NoError(t, err)
+	ivanovs := Filter{FIOPrefix: "Иванов"}
+	login := "bulk"
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T)
+		want   string
+	}{
+		{
+			name: "Updated",
+			modify: func(t *testing.T) {
+				require.NoError(t, repo.Update(ctx, newTestClient(func(cl *Client) { cl.ID = anna; cl.FIO = "Иванова Анна"; cl.Email = "anna@corp.ru" })))
+			},
+			want: fmt.Sprintf("client %d has version 2, expected 1", anna),
+		},
+		{
+			name: "StatusChanged",
+			modify: func(t *testing.T) {
+				require.NoError(t, repo.ChangeStatus(ctx, ivan, StatusBlocked))
+			},
+			want: fmt.Sprintf("client %d has version 2, expected 1", ivan),
+		},
+		{
+			name: "Inserted",
+			modify: func(t *testing.T) {
+				_, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Иванов Пётр" }))
+				require.NoError(t, err)
+			},
+			want: "matches the filter but has no expected version",
+		},
+		{
+			name: "NoLongerMatches",
+			modify: func(t *testing.T) {
+				fio := "Петрова Анна"
+				versions, err := repo.ClientVersions(ctx, Filter{FIOPrefix: "Иванова"})
+				require.NoError(t, err)
+				_, err = repo.UpdateClientsWhere(ctx, Filter{FIOPrefix: "Иванова"}, ClientChanges{FIO: &fio}, versions)
+				require.NoError(t, err)
+			},
+			want: fmt.Sprintf("client %d no longer matches the filter", anna),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := repo.ClientVersions(ctx, ivanovs)
+			require.NoError(t, err)
+			tt.modify(t)
+
+			_, err = repo.UpdateClientsWhere(ctx, ivanovs, ClientChanges{Login: &login}, versions)
+			require.ErrorIs(t, err, ErrVersionConflict)
+			assert.ErrorContains(t, err, tt.want)
+			assert.Equal(t, ClassConflict, Classify(err))
+			assertRowCount(t, db, "clients", 0, "login = 'bulk'")
+		})
+	}
+}
+
+// Тест проверяет, что удаление по условию требует предела или Force и
+// ничего не удаляет, если условию подходит больше клиентов, чем разрешено
+func Test_DeleteClientsWhere(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	var ids []int
+	for _, fio := range []string{"Иванов Иван", "Иванова Анна", "Иванов Пётр", "Петров Пётр"} {
+		id, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = fio }))
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	ivanovs := Filter{FIOPrefix: "Иванов"}
+
+	for name, opts := range map[string]DeleteWhereOptions{
+		"NoLimit":       {},
+		"NegativeLimit": {MaxRows: -1, Force: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.DeleteClientsWhere(ctx, ivanovs, opts)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+	_, err := repo.DeleteClientsWhere(ctx, Filter{}, DeleteWhereOptions{Force: true})
+	require.ErrorIs(t, err, ErrValidation)
+
+	_, err = repo.DeleteClientsWhere(ctx, ivanovs, DeleteWhereOptions{MaxRows: 2})
+	require.ErrorIs(t, err, ErrTooManyClients)
+	assert.ErrorContains(t, err, "filter matches 3 clients, max 2")
+	_, err = repo.DeleteClientsWhere(ctx, ivanovs, DeleteWhereOptions{MaxRows: 2, Force: true})
+	require.ErrorIs(t, err, ErrTooManyClients, "force does not lift an explicit limit")
+	_, err = repo.DeleteClientsWhere(ctx, ivanovs, DeleteWhereOptions{DestructiveOptions: DestructiveOptions{DryRun: true}, MaxRows: 2})
+	require.ErrorIs(t, err, ErrTooManyClients)
+	assertRowCount(t, db, "clients", 4, "deleted_at = ''")
+
+	affected, err := repo.DeleteClientsWhere(ctx, ivanovs, DeleteWhereOptions{DestructiveOptions: DestructiveOptions{DryRun: true}, MaxRows: 3})
+	require.NoError(t, err)
+	assert.Equal(t, ids[:3], affected.ClientIDs)
+	assertRowCount(t, db, "clients", 4, "deleted_at = ''")
+
+	affected, err = repo.DeleteClientsWhere(ctx, ivanovs, DeleteWhereOptions{MaxRows: 3})
+	require.NoError(t, err)
+	assert.Equal(t, ids[:3], affected.ClientIDs)
+	assert.EqualValues(t, 3, affected.Rows["clients"])
+
+	affected, err = repo.DeleteClientsWhere(ctx, Filter{FIOContains: "Пётр"}, DeleteWhereOptions{Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, ids[3:], affected.ClientIDs)
+	assertRowCount(t, db, "clients", 0, "deleted_at = ''")
+}
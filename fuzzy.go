@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Параметры FuzzySearch.
+const (
+	// DefaultFuzzyLimit — сколько результатов FuzzySearch возвращает,
+	// если limit не задан.
+	DefaultFuzzyLimit = 20
+	// MaxFuzzyEdits — наибольшее число опечаток в слове запроса.
+	MaxFuzzyEdits = 2
+)
+
+// FuzzyMatch — клиент, найденный FuzzySearch: поле, по которому он найден,
+// число опечаток и сходство от 0 до 1 (1 — точное совпадение слов).
+type FuzzyMatch struct {
+	ClientID int     `json:"client_id"`
+	FIO      string  `json:"fio"`
+	Login    string  `json:"login"`
+	Field    string  `json:"field"`
+	Edits    int     `json:"edits"`
+	Score    float64 `json:"score"`
+}
+
+// fuzzyEdits возвращает допустимое число опечаток в слове из n символов:
+// одна на каждые три символа, но не больше MaxFuzzyEdits, чтобы короткие
+// слова не совпадали с чем угодно.
+func fuzzyEdits(n int) int {
+	return min(n/3, MaxFuzzyEdits)
+}
+
+// editDistance возвращает расстояние Дамерау — Левенштейна (в варианте
+// optimal string alignment) между a и b по символам: перестановка двух
+// соседних символов считается одной опечаткой. Если расстояние больше
+// limit, возвращается limit+1.
+func editDistance(a, b []rune, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return min(prev[len(b)], limit+1)
+}
+
+// fuzzyFieldMatch сопоставляет слова запроса words со словами поля value:
+// каждое слово запроса должно совпасть с каким-либо словом поля с
+// допустимым числом опечаток. Возвращает суммарное число опечаток и
+// признак совпадения.
+func fuzzyFieldMatch(words [][]rune, value string) (int, bool) {
+	var fields [][]rune
+	for _, w := range strings.Fields(autocompleteKey(value)) {
+		fields = append(fields, []rune(w))
+	}
+
+	total := 0
+	for _, word := range words {
+		limit := fuzzyEdits(len(word))
+		best := limit + 1
+		for _, f := range fields {
+			best = min(best, editDistance(word, f, limit))
+		}
+		if best > limit {
+			return 0, false
+		}
+		total += best
+	}
+
+	return total, true
+}
+
+// FuzzySearch ищет клиентов, у которых ФИО или логин совпадают со словами
+// text с опечатками: в каждом слове запроса допускается одна опечатка на
+// три символа, но не больше MaxFuzzyEdits (замена, вставка, удаление
+// символа или перестановка соседних). Регистр и различие «е» и «ё» не
+// учитываются. Возвращается до limit (0 — DefaultFuzzyLimit) клиентов по
+// убыванию сходства, при равном сходстве — по ID; клиент, совпавший по
+// обоим полям, выдаётся по полю с большим сходством. Запрос проверяет
+// ФИО и логин каждого доступного клиента (см. WithOwnerRestriction),
+// поэтому его время растёт с числом клиентов; для больших БД подходит
+// SearchIndex.
+func (r *Repository) FuzzySearch(ctx context.Context, text string, limit int) (_ []FuzzyMatch, err error) {
+	ctx, end := r.startOperation(ctx, "fuzzy_search")
+	defer end(&err)
+
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: fuzzy search limit must not be negative, got %d", ErrValidation, limit)
+	}
+	if limit == 0 {
+		limit = DefaultFuzzyLimit
+	}
+	if !utf8.ValidString(text) {
+		return nil, fmt.Errorf("%w: fuzzy search text is not valid UTF-8", ErrValidation)
+	}
+	var (
+		words [][]rune
+		runes int
+	)
+	for _, w := range strings.Fields(autocompleteKey(text)) {
+		words = append(words, []rune(w))
+		runes += utf8.RuneCountInString(w)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%w: fuzzy search text is required", ErrValidation)
+	}
+
+	scope, args := r.ownerScope(ctx)
+	rows, err := r.conn().QueryContext(ctx, "SELECT id, fio, login FROM clients WHERE 1"+notDeleted+scope+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []FuzzyMatch{}
+	for rows.Next() {
+		var m FuzzyMatch
+		if err := rows.Scan(&m.ClientID, &m.FIO, &m.Login); err != nil {
+			return nil, err
+		}
+
+		m.Edits = -1
+		for _, field := range []struct{ name, value string }{{"fio", m.FIO}, {"login", m.Login}} {
+			edits, ok := fuzzyFieldMatch(words, field.value)
+			if ok && (m.Edits < 0 || edits < m.Edits) {
+				m.Field, m.Edits = field.name, edits
+			}
+		}
+		if m.Edits < 0 {
+			continue
+		}
+		m.Score = 1 - float64(m.Edits)/float64(runes)
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Клиенты прочитаны по возрастанию ID, поэтому устойчивая сортировка
+	// оставляет их в этом порядке при равном сходстве
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет расстояние между словами: замены, вставки, удаления,
+// перестановку соседних символов как одну опечатку и отсечение по limit
+func Test_EditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"иванов", "иванов", 2, 0},
+		{"иванов", "ивонов", 2, 1},
+		{"иванов", "иваанов", 2, 1},
+		{"иванов", "иванв", 2, 1},
+		{"иванов", "ивнаов", 2, 1},
+		{"иванов", "ивонав", 2, 2},
+		{"иванов", "ивнов", 2, 1},
+		{"иванов", "петров", 2, 3},
+		{"иванов", "ив", 2, 3},
+		{"", "abc", 5, 3},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, editDistance([]rune(tt.a), []rune(tt.b), tt.limit), "%s -> %s", tt.a, tt.b)
+	}
+}
+
+// Тест проверяет нечёткий поиск по ФИО и логину с одной и двумя
+// опечатками, порядок по сходству и отсечение слишком далёких слов
+func Test_FuzzySearch(t *testing.T) {
+	client := func(fio, login string) Client {
+		return newTestClient(func(cl *Client) { cl.FIO = fio; cl.Login = login })
+	}
+	_, repo, ids := setupAutocomplete(t,
+		client("Иванов Иван Иванович", "ivanov"),
+		client("Иванова Анна", "anna"),
+		client("Смирнов Алексей", "smirnov"),
+		client("Ёлкин Пётр", "elkin"),
+		client("Кузнецов Олег", "kuznetsov"),
+	)
+	ivanov, ivanova, smirnov, elkin, kuznetsov := ids[0], ids[1], ids[2], ids[3], ids[4]
+
+	search := func(text string) []FuzzyMatch {
+		t.Helper()
+		matches, err := repo.FuzzySearch(context.Background(), text, 0)
+		require.NoError(t, err)
+		return matches
+	}
+	matchIDs := func(matches []FuzzyMatch) []int {
+		ids := []int{}
+		for _, m := range matches {
+			ids = append(ids, m.ClientID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{"Exact", "Смирнов", []int{smirnov}},
+		{"OneSubstitution", "Смернов", []int{smirnov}},
+		{"OneInsertion", "Кузнецков", []int{kuznetsov}},
+		{"OneDeletion", "Кузнцов", []int{kuznetsov}},
+		{"Transposition", "Смринов", []int{smirnov}},
+		{"TwoErrors", "Кузницоф", []int{kuznetsov}},
+		{"TwoErrorsInLogin", "kuzentsof", []int{kuznetsov}},
+		{"SeveralWords", "Иваноф Иавн", []int{ivanov}},
+		{"YoAsYe", "Елкин Петр", []int{elkin}},
+		{"CaseInsensitive", "сМИРНОВ", []int{smirnov}},
+		{"ClosestFirst", "Иванова", []int{ivanova, ivanov}},
+		{"ThreeErrors", "Кузницофф", []int{}},
+		{"ShortWordOneError", "Ана", []int{ivanova}},
+		{"ShortWordTwoErrors", "Аня", []int{}},
+		{"AllWordsMustMatch", "Смирнов Олег", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchIDs(search(tt.text)))
+		})
+	}
+
+	assert.Equal(t, []FuzzyMatch{
+		{ClientID: kuznetsov, FIO: "Кузнецов Олег", Login: "kuznetsov", Field: "fio", Edits: 2, Score: 0.75},
+	}, search("Кузницоф"))
+	assert.Equal(t, "login", search("smernov")[0].Field)
+
+	matches, err := repo.FuzzySearch(context.Background(), "иванов", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{ivanov}, matchIDs(matches), "exact match ranks above a one-letter difference")
+}
+
+// Тест проверяет отказ от пустого запроса и отрицательного числа
+// результатов
+func Test_FuzzySearch_Errors(t *testing.T) {
+	_, repo, _ := setupAutocomplete(t)
+
+	for name, tt := range map[string]struct {
+		text  string
+		limit int
+	}{
+		"EmptyText":     {" ", 0},
+		"InvalidUTF8":   {"\xff", 0},
+		"NegativeLimit": {"иванов", -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.FuzzySearch(context.Background(), tt.text, tt.limit)
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
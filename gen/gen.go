@@ -0,0 +1,158 @@
+// Package gen генерирует правдоподобные случайные данные клиентов на
+// русском языке: ФИО с отчеством в согласованном роде, логины и email на
+// их основе и даты рождения из заданного диапазона. Генератор детерминирован:
+// при одном и том же зерне выдаёт одну и ту же последовательность клиентов,
+// поэтому подходит для фикстур, бенчмарков и нагрузочного тестирования.
+package gen
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// BirthdayLayout — формат даты рождения клиента (ГГГГММДД).
+const BirthdayLayout = "20060102"
+
+// Ограничения длины полей, совпадающие со столбцами таблицы clients.
+const (
+	maxLoginLen = 32
+	maxEmailLen = 64
+)
+
+// Client — сгенерированные данные клиента без ID.
+type Client struct {
+	FIO      string
+	Login    string
+	Birthday string
+	Email    string
+}
+
+// Generator выдаёт случайных клиентов. Генератор не безопасен для
+// одновременного использования из нескольких горутин.
+type Generator struct {
+	rnd  *rand.Rand
+	from time.Time
+	to   time.Time
+}
+
+// Option настраивает Generator при создании.
+type Option func(*Generator)
+
+// WithBirthdayRange задаёт диапазон дат рождения [from, to].
+func WithBirthdayRange(from, to time.Time) Option {
+	return func(g *Generator) {
+		g.from, g.to = from, to
+	}
+}
+
+// New создаёт генератор с зерном seed. По умолчанию даты рождения лежат
+// в диапазоне с 1950 по 2005 год.
+func New(seed int64, opts ...Option) *Generator {
+	g := &Generator{
+		rnd:  rand.New(rand.NewSource(seed)),
+		from: time.Date(1950, time.January, 1, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2005, time.December, 31, 0, 0, 0, 0, time.UTC),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Client возвращает очередного клиента.
+func (g *Generator) Client() Client {
+	female := g.rnd.Intn(2) == 0
+
+	surname := pick(g.rnd, surnames)
+	name := pick(g.rnd, maleNames)
+	father := pick(g.rnd, fathers)
+	patronymic := father.son
+	if female {
+		surname += "а"
+		name = pick(g.rnd, femaleNames)
+		patronymic = father.daughter
+	}
+
+	birthday := g.birthday()
+	login := g.login(name, surname, birthday)
+
+	return Client{
+		FIO:      surname + " " + name + " " + patronymic,
+		Login:    login,
+		Birthday: birthday.Format(BirthdayLayout),
+		Email:    login + "@" + pick(g.rnd, emailDomains),
+	}
+}
+
+// Clients возвращает n очередных клиентов.
+func (g *Generator) Clients(n int) []Client {
+	clients := make([]Client, n)
+	for i := range clients {
+		clients[i] = g.Client()
+	}
+
+	return clients
+}
+
+func (g *Generator) birthday() time.Time {
+	days := int(g.to.Sub(g.from).Hours()/24) + 1
+
+	return g.from.AddDate(0, 0, g.rnd.Intn(days))
+}
+
+// login строит логин в одном из стилей, встречающихся в реальных данных:
+// ignatiy02091984, danila95, vasilisa1976, viktoriya.nilova.
+func (g *Generator) login(name, surname string, birthday time.Time) string {
+	first, last := translit(name), translit(surname)
+
+	var login string
+	switch g.rnd.Intn(4) {
+	case 0:
+		login = first + birthday.Format("02012006")
+	case 1:
+		login = fmt.Sprintf("%s%02d", first, birthday.Year()%100)
+	case 2:
+		login = fmt.Sprintf("%s%d", first, birthday.Year())
+	default:
+		login = first + "." + last
+	}
+
+	if len(login) > maxLoginLen {
+		login = login[:maxLoginLen]
+	}
+	if longest := maxEmailLen - 1 - maxDomainLen; len(login) > longest {
+		login = login[:longest]
+	}
+
+	return login
+}
+
+func pick[T any](rnd *rand.Rand, items []T) T {
+	return items[rnd.Intn(len(items))]
+}
+
+// translitTable — латинские соответствия строчных русских букв.
+var translitTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// translit переводит русское слово в латиницу в нижнем регистре.
+func translit(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if t, ok := translitTable[r]; ok {
+			b.WriteString(t)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package gen
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что при одном зерне генератор выдаёт одну и ту же
+// последовательность клиентов, а при разных — разные
+func Test_Generator_Deterministic(t *testing.T) {
+	first := New(42).Clients(100)
+	second := New(42).Clients(100)
+	assert.Equal(t, first, second, "same seed should produce the same clients")
+
+	other := New(43).Clients(100)
+	assert.NotEqual(t, first, other, "different seeds should produce different clients")
+
+	// Зафиксированный первый клиент: изменение алгоритма генерации
+	// меняет фикстуры всех потребителей и должно быть осознанным
+	assert.Equal(t, Client{
+		FIO:      "Гришин Виталий Андреевич",
+		Login:    "vitaliy1953",
+		Birthday: "19531031",
+		Email:    "vitaliy1953@outlook.com",
+	}, New(1).Client())
+}
+
+var (
+	cyrillicWord = `[А-ЯЁ][а-яё]+`
+	fioPattern   = regexp.MustCompile(`^` + cyrillicWord + ` ` + cyrillicWord + ` ` + cyrillicWord + `$`)
+	loginPattern = regexp.MustCompile(`^[a-z]+(\.[a-z]+|\d+)$`)
+)
+
+// Тест проверяет правдоподобие и корректность сгенерированных полей
+func Test_Generator_ValidClients(t *testing.T) {
+	from := time.Date(1980, time.February, 29, 0, 0, 0, 0, time.UTC)
+	to := time.Date(1980, time.March, 2, 0, 0, 0, 0, time.UTC)
+	g := New(7, WithBirthdayRange(from, to))
+
+	for _, cl := range g.Clients(1000) {
+		require.Regexp(t, fioPattern, cl.FIO)
+		parts := strings.Fields(cl.FIO)
+		// Род фамилии и отчества согласован
+		female := strings.HasSuffix(parts[2], "на")
+		assert.Equal(t, female, strings.HasSuffix(parts[0], "а"), "surname and patronymic gender mismatch: %s", cl.FIO)
+
+		assert.Regexp(t, loginPattern, cl.Login)
+		assert.LessOrEqual(t, len(cl.Login), maxLoginLen)
+
+		assert.Equal(t, cl.Login, strings.Split(cl.Email, "@")[0])
+		assert.LessOrEqual(t, len(cl.Email), maxEmailLen)
+
+		birthday, err := time.Parse(BirthdayLayout, cl.Birthday)
+		require.NoError(t, err, "invalid birthday %q", cl.Birthday)
+		assert.False(t, birthday.Before(from) || birthday.After(to), "birthday %s out of range", cl.Birthday)
+	}
+}
+
+// Тест проверяет транслитерацию, используемую в логинах
+func Test_Translit(t *testing.T) {
+	assert.Equal(t, "ignatiy", translit("Игнатий"))
+	assert.Equal(t, "viktoriya", translit("Виктория"))
+	assert.Equal(t, "fedorov", translit("Фёдоров"))
+	assert.Equal(t, "schukina", translit("Щукина"))
+}
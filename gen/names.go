@@ -0,0 +1,87 @@
+package gen
+
+// Фамилии в мужской форме; женская образуется добавлением «а», поэтому
+// в списке только фамилии на -ов, -ев, -ин.
+var surnames = []string{
+	"Иванов", "Смирнов", "Кузнецов", "Попов", "Васильев", "Петров", "Соколов",
+	"Михайлов", "Новиков", "Фёдоров", "Морозов", "Волков", "Алексеев", "Лебедев",
+	"Семёнов", "Егоров", "Павлов", "Козлов", "Степанов", "Николаев", "Орлов",
+	"Андреев", "Макаров", "Никитин", "Захаров", "Зайцев", "Соловьёв", "Борисов",
+	"Яковлев", "Григорьев", "Романов", "Воробьёв", "Сергеев", "Кузьмин", "Фролов",
+	"Александров", "Дмитриев", "Королёв", "Гусев", "Киселёв", "Ильин", "Максимов",
+	"Поляков", "Сорокин", "Виноградов", "Ковалёв", "Белов", "Медведев", "Антонов",
+	"Тарасов", "Жуков", "Баранов", "Филиппов", "Комаров", "Давыдов", "Беляев",
+	"Герасимов", "Богданов", "Осипов", "Сидоров", "Матвеев", "Титов", "Марков",
+	"Миронов", "Крылов", "Куликов", "Карпов", "Власов", "Мельников", "Денисов",
+	"Гаврилов", "Тихонов", "Казаков", "Афанасьев", "Данилов", "Савельев", "Тимофеев",
+	"Фомин", "Чернов", "Абрамов", "Мартынов", "Ефимов", "Федотов", "Щербаков",
+	"Назаров", "Калинин", "Исаев", "Чернышёв", "Быков", "Маслов", "Родионов",
+	"Коновалов", "Лазарев", "Воронин", "Климов", "Филатов", "Пономарёв", "Голубев",
+	"Кудрявцев", "Прохоров", "Наумов", "Потапов", "Журавлёв", "Овчинников", "Трофимов",
+	"Леонов", "Соболев", "Ермаков", "Колесников", "Гончаров", "Емельянов", "Никифоров",
+	"Грачёв", "Котов", "Гришин", "Ефремов", "Архипов", "Громов", "Кириллов",
+	"Малышев", "Панов", "Моисеев", "Румянцев", "Акимов", "Кондратьев", "Бирюков",
+	"Горбунов", "Анисимов", "Еремин", "Тихомиров", "Галкин", "Лукьянов", "Михеев",
+	"Скворцов", "Юдин", "Белоусов", "Нестеров", "Симонов", "Прокофьев", "Харитонов",
+	"Князев", "Цветков", "Левин", "Митрофанов", "Воронов", "Аксёнов", "Софронов",
+	"Мальцев", "Логинов", "Горшков", "Савин", "Краснов", "Майоров", "Демидов",
+	"Елисеев", "Рыбаков", "Сафонов", "Плотников", "Дёмин", "Хохлов", "Жданов",
+}
+
+var maleNames = []string{
+	"Александр", "Алексей", "Анатолий", "Андрей", "Антон", "Аркадий", "Арсений",
+	"Артём", "Борис", "Вадим", "Валентин", "Валерий", "Василий", "Вениамин",
+	"Виктор", "Виталий", "Владимир", "Владислав", "Вячеслав", "Геннадий", "Георгий",
+	"Глеб", "Григорий", "Данила", "Денис", "Дмитрий", "Евгений", "Егор", "Иван",
+	"Игнатий", "Игорь", "Илья", "Кирилл", "Константин", "Лев", "Леонид", "Максим",
+	"Матвей", "Михаил", "Никита", "Николай", "Олег", "Павел", "Пётр", "Роман",
+	"Руслан", "Семён", "Сергей", "Станислав", "Степан", "Тимофей", "Тимур",
+	"Фёдор", "Филипп", "Юрий", "Ярослав",
+}
+
+var femaleNames = []string{
+	"Агата", "Алевтина", "Александра", "Алина", "Алла", "Анастасия", "Ангелина",
+	"Анна", "Антонина", "Валентина", "Валерия", "Варвара", "Василиса", "Вера",
+	"Вероника", "Виктория", "Галина", "Дарья", "Диана", "Евгения", "Екатерина",
+	"Елена", "Елизавета", "Жанна", "Зинаида", "Злата", "Инна", "Ирина", "Кира",
+	"Ксения", "Лариса", "Лидия", "Любовь", "Людмила", "Маргарита", "Марина",
+	"Мария", "Надежда", "Наталья", "Нина", "Оксана", "Ольга", "Полина", "Раиса",
+	"Светлана", "София", "Таисия", "Тамара", "Татьяна", "Ульяна", "Юлия", "Яна",
+}
+
+// Отчества по имени отца в мужской и женской форме.
+var fathers = []struct {
+	son      string
+	daughter string
+}{
+	{"Александрович", "Александровна"}, {"Алексеевич", "Алексеевна"},
+	{"Анатольевич", "Анатольевна"}, {"Андреевич", "Андреевна"},
+	{"Антонович", "Антоновна"}, {"Аркадьевич", "Аркадьевна"},
+	{"Арсеньевич", "Арсеньевна"}, {"Борисович", "Борисовна"},
+	{"Вадимович", "Вадимовна"}, {"Валентинович", "Валентиновна"},
+	{"Валерьевич", "Валерьевна"}, {"Васильевич", "Васильевна"},
+	{"Викторович", "Викторовна"}, {"Витальевич", "Витальевна"},
+	{"Владимирович", "Владимировна"}, {"Вячеславович", "Вячеславовна"},
+	{"Геннадьевич", "Геннадьевна"}, {"Георгиевич", "Георгиевна"},
+	{"Григорьевич", "Григорьевна"}, {"Денисович", "Денисовна"},
+	{"Дмитриевич", "Дмитриевна"}, {"Евгеньевич", "Евгеньевна"},
+	{"Егорович", "Егоровна"}, {"Иванович", "Ивановна"},
+	{"Игоревич", "Игоревна"}, {"Ильич", "Ильинична"},
+	{"Кириллович", "Кирилловна"}, {"Константинович", "Константиновна"},
+	{"Львович", "Львовна"}, {"Леонидович", "Леонидовна"},
+	{"Максимович", "Максимовна"}, {"Михайлович", "Михайловна"},
+	{"Никитич", "Никитична"}, {"Николаевич", "Николаевна"},
+	{"Олегович", "Олеговна"}, {"Павлович", "Павловна"},
+	{"Петрович", "Петровна"}, {"Романович", "Романовна"},
+	{"Семёнович", "Семёновна"}, {"Сергеевич", "Сергеевна"},
+	{"Станиславович", "Станиславовна"}, {"Степанович", "Степановна"},
+	{"Тимофеевич", "Тимофеевна"}, {"Фёдорович", "Фёдоровна"},
+	{"Юрьевич", "Юрьевна"}, {"Ярославович", "Ярославовна"},
+}
+
+var emailDomains = []string{
+	"gmail.com", "mail.ru", "ya.ru", "yandex.ru", "rambler.ru", "outlook.com", "hotmail.com", "bk.ru", "inbox.ru", "list.ru",
+}
+
+// maxDomainLen — длина самого длинного домена из emailDomains.
+const maxDomainLen = len("outlook.com")
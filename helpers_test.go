@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// openMemoryDB открывает пустую БД в памяти для модульных тестов. БД
+// существует, пока открыто её единственное соединение.
+func openMemoryDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err, "database connection error: %v", err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// envOr возвращает значение переменной окружения key или def, если она
+// не задана.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
+
+// newTestClient возвращает корректного клиента без ID; функции modify
+// изменяют отдельные поля под конкретный сценарий.
+func newTestClient(modify ...func(*Client)) Client {
+	cl := Client{
+		FIO:      "Test",
+		Login:    "Test",
+		Birthday: "19700101",
+		Email:    "mail@mail.com",
+	}
+	for _, m := range modify {
+		m(&cl)
+	}
+
+	return cl
+}
+
+// fakeClients возвращает n правдоподобных клиентов из генератора gen
+// с зерном seed: одинаковое зерно даёт одинаковых клиентов.
+func fakeClients(seed int64, n int) []Client {
+	clients := make([]Client, n)
+	for i, fake := range gen.New(seed).Clients(n) {
+		clients[i] = Client{FIO: fake.FIO, Login: fake.Login, Birthday: fake.Birthday, Email: fake.Email}
+	}
+
+	return clients
+}
+
+// assertClientEqual проверяет совпадение ID и всех полей клиента, кроме
+// времени изменения согласия, которое назначается при записи. Статус
+// сравнивается, только если он задан в expected: при вставке он
+// назначается репозиторием.
+func assertClientEqual(t *testing.T, expected, actual Client) {
+	t.Helper()
+
+	assert.Equal(t, expected.ID, actual.ID, "ID mismatch: expected %v, actual %v", expected.ID, actual.ID)
+	assert.Equal(t, expected.FIO, actual.FIO, "FIO mismatch: expected %v, actual %v", expected.FIO, actual.FIO)
+	assert.Equal(t, expected.Login, actual.Login, "login mismatch: expected %v, actual %v", expected.Login, actual.Login)
+	assert.Equal(t, expected.Birthday, actual.Birthday, "birthday mismatch: expected %v, actual %v", expected.Birthday, actual.Birthday)
+	assert.Equal(t, expected.Email, actual.Email, "email mismatch: expected %v, actual %v", expected.Email, actual.Email)
+	assert.Equal(t, expected.OwnerID, actual.OwnerID, "owner mismatch: expected %v, actual %v", expected.OwnerID, actual.OwnerID)
+	assert.Equal(t, expected.MarketingConsent, actual.MarketingConsent, "marketing consent mismatch: expected %v, actual %v", expected.MarketingConsent, actual.MarketingConsent)
+	if expected.Status != "" {
+		assert.Equal(t, expected.Status, actual.Status, "status mismatch: expected %v, actual %v", expected.Status, actual.Status)
+	}
+}
+
+// assertRowCount проверяет число строк таблицы table, удовлетворяющих
+// условию cond (фрагмент WHERE) с параметрами args.
+func assertRowCount(t *testing.T, q querier, table string, want int, cond string, args ...any) {
+	t.Helper()
+
+	var got int
+	err := q.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM "+table+" WHERE "+cond, args...).Scan(&got)
+	require.NoError(t, err, "error counting rows in %s: %v", table, err)
+	assert.Equal(t, want, got, "unexpected number of rows in %s WHERE %s", table, cond)
+}
+
+// assertClientNotInDB проверяет в обход репозитория, что строки клиента
+// с указанным ID нет в таблице clients.
+func assertClientNotInDB(t *testing.T, q querier, id int) {
+	t.Helper()
+
+	assertRowCount(t, q, "clients", 0, "id = :id", sql.Named("id", id))
+}
+
+// updateGolden перезаписывает эталонные файлы фактическим результатом:
+// go test -run Golden -update
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// assertGolden сравнивает got с файлом testdata/golden/<name> побайтно.
+// С флагом -update файл перезаписывается, поэтому любое изменение
+// результата видно в диффе эталонного файла при ревью.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, got, 0o644), "error updating golden file %s", path)
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "error reading golden file %s (run with -update to create it)", path)
+	assert.Equal(t, string(want), string(got), "output differs from golden file %s", path)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// historyColumns — столбцы clients_history в порядке, ожидаемом scanClient.
+const historyColumns = "client_id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status"
+
+// recordHistory копирует текущую версию клиента в clients_history перед
+// её изменением или удалением операцией op. Возвращает время смены версии:
+// с него начинает действовать новая версия клиента.
+func (r *Repository) recordHistory(ctx context.Context, q querier, id int, op AuditOperation) (string, error) {
+	changedAt := formatTime(r.now())
+
+	_, err := q.ExecContext(ctx, recordHistoryQuery+"id = :id",
+		sql.Named("valid_to", changedAt),
+		sql.Named("operation", string(op)),
+		sql.Named("id", id))
+	if err != nil {
+		return "", err
+	}
+
+	return changedAt, nil
+}
+
+// recordHistoryOf копирует, как recordHistory, текущие версии клиентов ids
+// одним запросом.
+func (r *Repository) recordHistoryOf(ctx context.Context, q querier, ids []int, op AuditOperation) (string, error) {
+	changedAt := formatTime(r.now())
+
+	list, err := json.Marshal(ids)
+	if err != nil {
+		return "", err
+	}
+	_, err = q.ExecContext(ctx, recordHistoryQuery+"id IN (SELECT value FROM json_each(:ids))",
+		sql.Named("valid_to", changedAt),
+		sql.Named("operation", string(op)),
+		sql.Named("ids", string(list)))
+	if err != nil {
+		return "", err
+	}
+
+	return changedAt, nil
+}
+
+// recordHistoryQuery — запрос копирования текущих версий клиентов в
+// clients_history без условия отбора клиентов.
+const recordHistoryQuery = `INSERT INTO clients_history (` + historyColumns + `, valid_from, valid_to, operation)
+		SELECT id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status, valid_from, :valid_to, :operation
+		FROM clients WHERE `
+
+// SelectAsOf возвращает клиента в том виде, в каком он был в момент at.
+// Если в этот момент клиента ещё не было или он уже был удалён,
+// возвращается ErrClientNotFound.
+func (r *Repository) SelectAsOf(ctx context.Context, id int, at time.Time) (_ Client, err error) {
+	ctx, end := r.startClientOperation(ctx, "select_as_of", id)
+	defer end(&err)
+
+	return r.selectClientAsOf(ctx, r.conn(), id, at)
+}
+
+// selectClientAsOf ищет версию клиента, действовавшую в момент at, сначала
+// среди прежних версий в clients_history, затем среди текущих.
+func (r *Repository) selectClientAsOf(ctx context.Context, q querier, id int, at time.Time) (Client, error) {
+	scope, args := r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id), sql.Named("at", formatTime(at)))
+
+	for _, query := range []string{
+		"SELECT " + historyColumns + " FROM clients_history WHERE client_id = :id AND valid_from <= :at AND valid_to > :at" + scope + " ORDER BY valid_to LIMIT 1",
+		"SELECT " + clientColumns + " FROM clients WHERE id = :id AND valid_from <= :at" + notDeleted + scope,
+	} {
+		cl, err := scanClient(q.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return Client{}, err
+		}
+
+		return r.decrypt(cl)
+	}
+
+	return Client{}, ErrClientNotFound
+}
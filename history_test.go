@@ -0,0 +1,149 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет чтение клиента на момент времени между несколькими
+// изменениями, до его создания и после удаления
+func Test_SelectAsOf_Versions(t *testing.T) {
+	t.Parallel()
+	month := func(m time.Month) time.Time { return time.Date(2024, m, 1, 12, 0, 0, 0, time.UTC) }
+	clock := testutil.NewFakeClock(month(time.January))
+	tx, repo := setupTestTx(t, WithClock(clock))
+
+	ctx := context.Background()
+
+	v1 := newTestClient()
+	var err error
+	v1.ID, err = repo.Insert(ctx, v1)
+	require.NoError(t, err)
+	v1.Status = StatusActive
+
+	clock.Set(month(time.February))
+	v2 := v1
+	v2.FIO = "Renamed"
+	require.NoError(t, repo.Update(ctx, v2))
+
+	clock.Set(month(time.March))
+	require.NoError(t, repo.RecordConsent(ctx, v1.ID, true))
+	v3 := v2
+	v3.MarketingConsent = true
+
+	clock.Set(month(time.April))
+	require.NoError(t, repo.ChangeStatus(ctx, v1.ID, StatusBlocked))
+	v4 := v3
+	v4.Status = StatusBlocked
+
+	clock.Set(month(time.May))
+	v5 := v4
+	v5.Email = "renamed@mail.com"
+	require.NoError(t, repo.Update(ctx, v5))
+
+	clock.Set(month(time.June))
+	require.NoError(t, repo.Delete(ctx, v1.ID))
+
+	assertRowCount(t, tx, "clients_history", 5, "client_id = ?", v1.ID)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want *Client
+	}{
+		{"BeforeInsert", month(time.January).Add(-time.Second), nil},
+		{"AtInsert", month(time.January), &v1},
+		{"AfterUpdate", month(time.February).Add(time.Hour), &v2},
+		{"LastMarch", month(time.March).AddDate(0, 0, 14), &v3},
+		{"BeforeStatusChange", month(time.April).Add(-time.Second), &v3},
+		{"AfterStatusChange", month(time.April), &v4},
+		{"BeforeDelete", month(time.June).Add(-time.Second), &v5},
+		{"AfterDelete", month(time.June), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := repo.SelectAsOf(ctx, v1.ID, tt.at)
+			if tt.want == nil {
+				require.ErrorIs(t, err, ErrClientNotFound)
+				return
+			}
+
+			require.NoError(t, err)
+			assertClientEqual(t, *tt.want, client)
+		})
+	}
+}
+
+// Тест проверяет, что текущая версия клиента без изменений читается
+// на любой момент после создания, а право на забвение удаляет и историю
+func Test_SelectAsOf_CurrentAndErased(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(start)
+	tx, repo := setupTestTx(t, WithClock(clock))
+
+	ctx := context.Background()
+
+	cl := newTestClient()
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err)
+
+	client, err := repo.SelectAsOf(ctx, cl.ID, start.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assertClientEqual(t, cl, client)
+
+	clock.Advance(time.Hour)
+	cl.Login = "renamed"
+	require.NoError(t, repo.Update(ctx, cl))
+
+	_, err = repo.EraseClient(ctx, cl.ID)
+	require.NoError(t, err)
+
+	assertRowCount(t, tx, "clients_history", 0, "client_id = ?", cl.ID)
+	_, err = repo.SelectAsOf(ctx, cl.ID, start)
+	require.ErrorIs(t, err, ErrClientNotFound)
+}
+
+// Тест проверяет, что прежние версии хранятся зашифрованными и остаются
+// читаемыми после ротации ключей
+func Test_SelectAsOf_Encrypted(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(start)
+	tx, oldRepo := setupTestTx(t, WithClock(clock), WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	ctx := context.Background()
+
+	cl := newTestClient()
+	var err error
+	cl.ID, err = oldRepo.Insert(ctx, cl)
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	updated := cl
+	updated.Email = "updated@mail.com"
+	require.NoError(t, oldRepo.Update(ctx, updated))
+
+	var rawEmail string
+	require.NoError(t, tx.QueryRow("SELECT email FROM clients_history WHERE client_id = ?", cl.ID).Scan(&rawEmail))
+	assert.NotEqual(t, cl.Email, rawEmail, "history should be stored encrypted")
+
+	newRepo := NewTxRepository(tx, WithClock(clock), WithEncryption(NewStaticKeyProvider("v2", map[string][]byte{"v2": testKeyV2, "v1": testKeyV1})))
+	_, err = newRepo.RotateKeys(ctx)
+	require.NoError(t, err)
+
+	// Без старого ключа прежняя версия читается только после ротации
+	v2Only := NewTxRepository(tx, WithEncryption(NewStaticKeyProvider("v2", map[string][]byte{"v2": testKeyV2})))
+	client, err := v2Only.SelectAsOf(ctx, cl.ID, start)
+	require.NoError(t, err)
+	assertClientEqual(t, cl, client)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale — язык сообщений для пользователя.
+type Locale string
+
+const (
+	LocaleEN Locale = "en"
+	LocaleRU Locale = "ru"
+)
+
+// DefaultLocale — язык, на котором выводятся сообщения, если язык не
+// задан или не поддерживается, а также сообщения, у которых нет перевода.
+const DefaultLocale = LocaleEN
+
+type localeKey struct{}
+
+// WithLocale возвращает контекст, в котором сообщения для пользователя
+// выводятся на языке loc.
+func WithLocale(ctx context.Context, loc Locale) context.Context {
+	return context.WithValue(ctx, localeKey{}, loc)
+}
+
+// LocaleFromContext возвращает язык из контекста или DefaultLocale.
+func LocaleFromContext(ctx context.Context) Locale {
+	if loc, ok := ctx.Value(localeKey{}).(Locale); ok {
+		return loc
+	}
+
+	return DefaultLocale
+}
+
+// ParseLocale возвращает поддерживаемый язык для тега tag: «ru»,
+// «ru-RU» и «ru_RU.UTF-8» дают LocaleRU. Для неподдерживаемого языка
+// ok — false.
+func ParseLocale(tag string) (_ Locale, ok bool) {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	lang, _, _ = strings.Cut(lang, "_")
+	if _, ok := messages[Locale(lang)]; !ok {
+		return "", false
+	}
+
+	return Locale(lang), true
+}
+
+// RequestLocale выбирает язык по заголовку Accept-Language запроса r с
+// учётом весов q: первый поддерживаемый язык с наибольшим весом или
+// DefaultLocale.
+func RequestLocale(r *http.Request) Locale {
+	type weighted struct {
+		loc Locale
+		q   float64
+	}
+	var candidates []weighted
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if loc, ok := ParseLocale(tag); ok && q > 0 {
+			candidates = append(candidates, weighted{loc, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return DefaultLocale
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	return candidates[0].loc
+}
+
+// messages — шаблоны сообщений для пользователя по языкам. Ключи
+// validation.* получают название поля, а validation.too_long — ещё и
+// наибольшую длину.
+var messages = map[Locale]map[string]string{
+	LocaleEN: {
+		"field.fio":      "Full name",
+		"field.login":    "Login",
+		"field.birthday": "Birthday",
+		"field.email":    "Email",
+
+		"validation.required":     "%s is required",
+		"validation.invalid_utf8": "%s contains invalid characters",
+		"validation.too_long":     "%s must be at most %d characters long",
+		"validation.invalid_date": "%s must be a date in YYYYMMDD format",
+		"validation.out_of_range": "%s must be between 1900-01-01 and today",
+
+		"error.invalid_input":             "The request contains invalid data",
+		"error.client_not_found":          "Client not found",
+		"error.access_denied":             "Access denied",
+		"error.invalid_status_transition": "The client cannot be moved to this status",
+		"error.client_has_orders":         "The client has orders and cannot be deleted",
+		"error.too_many_clients":          "Too many clients match the filter",
+		"error.order_not_found":           "Order not found",
+		"error.note_not_found":            "Note not found",
+		"error.tag_not_found":             "Tag not found",
+		"error.document_not_found":        "Document not found",
+		"error.segment_not_found":         "Segment not found",
+		"error.method_not_allowed":        "This action is not supported",
+		"error.internal":                  "Something went wrong, please try again later",
+	},
+	LocaleRU: {
+		"field.fio":      "ФИО",
+		"field.login":    "Логин",
+		"field.birthday": "Дата рождения",
+		"field.email":    "Email",
+
+		"validation.required":     "Поле «%s» обязательно",
+		"validation.invalid_utf8": "Поле «%s» содержит недопустимые символы",
+		"validation.too_long":     "Поле «%s» должно быть не длиннее %d символов",
+		"validation.invalid_date": "Поле «%s» должно содержать дату в формате ГГГГММДД",
+		"validation.out_of_range": "Поле «%s» должно быть не раньше 01.01.1900 и не позже сегодняшнего дня",
+
+		"error.invalid_input":             "Запрос содержит некорректные данные",
+		"error.client_not_found":          "Клиент не найден",
+		"error.access_denied":             "Доступ запрещён",
+		"error.invalid_status_transition": "Клиента нельзя перевести в этот статус",
+		"error.client_has_orders":         "У клиента есть заказы, его нельзя удалить",
+		"error.too_many_clients":          "Условию подходит слишком много клиентов",
+		"error.order_not_found":           "Заказ не найден",
+		"error.note_not_found":            "Заметка не найдена",
+		"error.tag_not_found":             "Метка не найдена",
+		"error.document_not_found":        "Документ не найден",
+		"error.segment_not_found":         "Сегмент не найден",
+		"error.method_not_allowed":        "Это действие не поддерживается",
+		"error.internal":                  "Что-то пошло не так, попробуйте позже",
+	},
+}
+
+// errorMessages — ключи сообщений для ошибок, которые видит пользователь,
+// в порядке проверки: ErrValidation — последней, потому что его
+// оборачивают и более конкретные ошибки.
+var errorMessages = []struct {
+	err error
+	key string
+}{
+	{ErrClientNotFound, "error.client_not_found"},
+	{ErrAccessDenied, "error.access_denied"},
+	{ErrInvalidStatusTransition, "error.invalid_status_transition"},
+	{ErrClientHasOrders, "error.client_has_orders"},
+	{ErrTooManyClients, "error.too_many_clients"},
+	{ErrOrderNotFound, "error.order_not_found"},
+	{ErrNoteNotFound, "error.note_not_found"},
+	{ErrTagNotFound, "error.tag_not_found"},
+	{ErrDocumentNotFound, "error.document_not_found"},
+	{ErrSegmentNotFound, "error.segment_not_found"},
+	{errMethodNotAllowed, "error.method_not_allowed"},
+	{ErrValidation, "error.invalid_input"},
+}
+
+// translate возвращает сообщение key на языке loc с подставленными args.
+// Если языка или перевода нет, используется DefaultLocale, а если нет и
+// его — сам key.
+func translate(loc Locale, key string, args ...any) string {
+	tmpl, ok := messages[loc][key]
+	if !ok {
+		if tmpl, ok = messages[DefaultLocale][key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return tmpl
+	}
+
+	return fmt.Sprintf(tmpl, args...)
+}
+
+// LocalizeError возвращает сообщение для пользователя об ошибке err на
+// языке из контекста (см. WithLocale). Ошибка проверки поля (FieldError)
+// описывается полностью, известные ошибки репозитория — общей фразой, а
+// прочие ошибки — фразой о внутренней ошибке без подробностей. Сами
+// ошибки для управления ходом выполнения не меняются: их по-прежнему
+// проверяют errors.Is и errors.As. Для nil возвращается пустая строка.
+func LocalizeError(ctx context.Context, err error) string {
+	if err == nil {
+		return ""
+	}
+	loc := LocaleFromContext(ctx)
+
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		field := fieldErr.Field
+		if _, ok := messages[DefaultLocale]["field."+field]; ok {
+			field = translate(loc, "field."+field)
+		}
+		if fieldErr.Rule == RuleTooLong {
+			return translate(loc, "validation."+string(fieldErr.Rule), field, fieldErr.Max)
+		}
+		return translate(loc, "validation."+string(fieldErr.Rule), field)
+	}
+	for _, m := range errorMessages {
+		if errors.Is(err, m.err) {
+			return translate(loc, m.key)
+		}
+	}
+
+	return translate(loc, "error.internal")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет сообщения об ошибках проверки клиента на русском и
+// английском при неизменных внутренних ошибках
+func Test_LocalizeError_Validation(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		client Client
+		en, ru string
+	}{
+		{"Required", newTestClient(func(cl *Client) { cl.FIO = " " }),
+			"Full name is required", "Поле «ФИО» обязательно"},
+		{"InvalidUTF8", newTestClient(func(cl *Client) { cl.Login = "\xff" }),
+			"Login contains invalid characters", "Поле «Логин» содержит недопустимые символы"},
+		{"TooLong", newTestClient(func(cl *Client) { cl.Email = strings.Repeat("a", maxEmailLen+1) }),
+			"Email must be at most 64 characters long", "Поле «Email» должно быть не длиннее 64 символов"},
+		{"InvalidDate", newTestClient(func(cl *Client) { cl.Birthday = "19701301" }),
+			"Birthday must be a date in YYYYMMDD format", "Поле «Дата рождения» должно содержать дату в формате ГГГГММДД"},
+		{"OutOfRange", newTestClient(func(cl *Client) { cl.Birthday = "20300101" }),
+			"Birthday must be between 1900-01-01 and today", "Поле «Дата рождения» должно быть не раньше 01.01.1900 и не позже сегодняшнего дня"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.client.validateAt(now)
+			require.ErrorIs(t, err, ErrValidation)
+			var fieldErr *FieldError
+			require.ErrorAs(t, err, &fieldErr)
+			assert.True(t, strings.HasPrefix(err.Error(), "validation failed: "), "internal message stays in English: %s", err)
+
+			assert.Equal(t, tt.en, LocalizeError(WithLocale(context.Background(), LocaleEN), err))
+			assert.Equal(t, tt.ru, LocalizeError(WithLocale(context.Background(), LocaleRU), err))
+		})
+	}
+
+	// Ошибка проверки, обёрнутая операцией репозитория, переводится так же
+	err := &OpError{Op: "update", ClientID: 1, Err: &FieldError{Field: "fio", Rule: RuleRequired}}
+	assert.Equal(t, "Поле «ФИО» обязательно", LocalizeError(WithLocale(context.Background(), LocaleRU), err))
+}
+
+// Тест проверяет общие сообщения для ошибок репозитория и выбор языка по
+// умолчанию для отсутствующего или неподдерживаемого языка и перевода
+func Test_LocalizeError_Fallback(t *testing.T) {
+	ru := WithLocale(context.Background(), LocaleRU)
+	en := WithLocale(context.Background(), LocaleEN)
+
+	notFound := &OpError{Op: "select", ClientID: 42, Err: ErrClientNotFound}
+	assert.Equal(t, "Клиент не найден", LocalizeError(ru, notFound))
+	assert.Equal(t, "Client not found", LocalizeError(en, notFound))
+	assert.Equal(t, "Запрос содержит некорректные данные", LocalizeError(ru, fmt.Errorf("%w: limit must be positive", ErrValidation)))
+	assert.Equal(t, "Что-то пошло не так, попробуйте позже", LocalizeError(ru, errors.New("database is locked")),
+		"internal errors must not leak details")
+	assert.Empty(t, LocalizeError(ru, nil))
+
+	assert.Equal(t, "Client not found", LocalizeError(context.Background(), notFound), "no locale in the context")
+	assert.Equal(t, "Client not found", LocalizeError(WithLocale(context.Background(), "de"), notFound), "unsupported locale")
+
+	messages[LocaleEN]["error.test_only"] = "English only"
+	t.Cleanup(func() { delete(messages[LocaleEN], "error.test_only") })
+	assert.Equal(t, "English only", translate(LocaleRU, "error.test_only"), "missing translation")
+	assert.Equal(t, "error.unknown", translate(LocaleRU, "error.unknown"))
+
+	fieldErr := &FieldError{Field: "nickname", Rule: RuleRequired}
+	assert.Equal(t, "Поле «nickname» обязательно", LocalizeError(ru, fieldErr), "unknown field keeps its name")
+}
+
+// Тест проверяет выбор языка по тегу и заголовку Accept-Language
+func Test_RequestLocale(t *testing.T) {
+	for tag, want := range map[string]Locale{"ru": LocaleRU, "ru-RU": LocaleRU, "ru_RU.UTF-8": LocaleRU, "EN-us": LocaleEN} {
+		loc, ok := ParseLocale(tag)
+		assert.True(t, ok, tag)
+		assert.Equal(t, want, loc, tag)
+	}
+	_, ok := ParseLocale("de-DE")
+	assert.False(t, ok)
+
+	for header, want := range map[string]Locale{
+		"":                        DefaultLocale,
+		"ru-RU,ru;q=0.9,en;q=0.8": LocaleRU,
+		"de-DE,en;q=0.5":          LocaleEN,
+		"en;q=0.1, ru;q=0.9":      LocaleRU,
+		"fr, de":                  DefaultLocale,
+		"ru;q=0, en;q=0.2":        LocaleEN,
+		"ru;q=bad, en;q=0.2":      LocaleEN,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", header)
+		assert.Equal(t, want, RequestLocale(r), header)
+	}
+}
+
+// Тест проверяет, что ответ API с ошибкой содержит сообщение для
+// пользователя на языке из Accept-Language рядом с причиной на английском
+func Test_APIError_Localized(t *testing.T) {
+	_, repo, _ := setupAutocomplete(t)
+
+	for lang, want := range map[string]string{
+		"ru-RU,ru;q=0.9": "Запрос содержит некорректные данные",
+		"en":             "The request contains invalid data",
+		"de":             "The request contains invalid data",
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/autocomplete?q=", nil)
+		req.Header.Set("Accept-Language", lang)
+		AutocompleteHandler(repo).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		var apiErr APIError
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiErr))
+		assert.Equal(t, want, apiErr.Message, lang)
+		assert.Contains(t, apiErr.Error, "validation failed: ")
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/autocomplete?q=Ив", nil)
+	req.Header.Set("Accept-Language", "ru")
+	AutocompleteHandler(repo).ServeHTTP(rec, req)
+	assert.JSONEq(t, `{"error": "Method Not Allowed", "message": "Это действие не поддерживается"}`, rec.Body.String())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator назначает ID новым клиентам. NextID возвращает 0, если ID
+// должна назначить сама БД (AUTOINCREMENT).
+type IDGenerator interface {
+	NextID() (int64, error)
+}
+
+// WithIDGenerator задаёт способ назначения ID новым клиентам вместо
+// AUTOINCREMENT.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(r *Repository) {
+		r.ids = g
+	}
+}
+
+// AutoIncrement оставляет назначение ID базе данных. Используется по
+// умолчанию.
+type AutoIncrement struct{}
+
+func (AutoIncrement) NextID() (int64, error) { return 0, nil }
+
+// Разбиение ID по алгоритму snowflake: миллисекунды от snowflakeEpoch,
+// номер узла и порядковый номер в пределах миллисекунды.
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeTimeBits = 63 - snowflakeNodeBits - snowflakeSeqBits
+
+	// MaxSnowflakeNode — наибольший допустимый номер узла.
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+	maxSnowflakeSeq  = 1<<snowflakeSeqBits - 1
+)
+
+var snowflakeEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrClockOutOfRange возвращается Snowflake, если время часов раньше
+// snowflakeEpoch или не помещается в отведённые под него биты.
+var ErrClockOutOfRange = errors.New("clock is out of snowflake range")
+
+// Snowflake выдаёт возрастающие ID, уникальные между узлами с разными
+// номерами без обращения к БД: время в миллисекундах, номер узла и
+// порядковый номер внутри миллисекунды. Если часы отстают от уже выданных
+// ID или за миллисекунду запрошено больше 4096 ID, генератор продолжает
+// от последнего выданного ID, не дожидаясь часов.
+type Snowflake struct {
+	clock Clock
+	node  int64
+
+	mu     sync.Mutex
+	lastMs int64
+	seq    int64
+}
+
+// NewSnowflake создаёт генератор для узла node (от 0 до MaxSnowflakeNode).
+// Если clock равен nil, используются системные часы.
+func NewSnowflake(node int64, clock Clock) (*Snowflake, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node %d is out of range [0, %d]", node, MaxSnowflakeNode)
+	}
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	return &Snowflake{clock: clock, node: node, lastMs: -1}, nil
+}
+
+func (s *Snowflake) NextID() (int64, error) {
+	ms := s.clock.Now().Sub(snowflakeEpoch).Milliseconds()
+	if ms < 0 || ms >= 1<<snowflakeTimeBits {
+		return 0, ErrClockOutOfRange
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case ms > s.lastMs:
+		s.lastMs, s.seq = ms, 0
+	case s.seq < maxSnowflakeSeq:
+		s.seq++
+	default:
+		s.lastMs, s.seq = s.lastMs+1, 0
+	}
+
+	return s.lastMs<<(snowflakeNodeBits+snowflakeSeqBits) | s.node<<snowflakeSeqBits | s.seq, nil
+}
@@ -0,0 +1,50 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что клиенты получают ID из генератора и доступны по нему
+func Test_IDGenerator_Snowflake(t *testing.T) {
+	t.Parallel()
+	clock := testutil.NewFakeClock(snowflakeEpoch.Add(time.Second))
+	ids, err := NewSnowflake(7, clock)
+	require.NoError(t, err)
+	tx, repo := setupTestTx(t, WithIDGenerator(ids))
+
+	ctx := context.Background()
+
+	for i, want := range []int{1000<<22 | 7<<12, 1000<<22 | 7<<12 | 1} {
+		cl := newTestClient()
+		cl.ID, err = repo.Insert(ctx, cl)
+		require.NoError(t, err, "error inserting client %d", i)
+		require.Equal(t, want, cl.ID, "unexpected ID of client %d", i)
+
+		client, err := repo.Select(ctx, want)
+		require.NoError(t, err, "error retrieving client with ID %d", want)
+		assertClientEqual(t, cl, client)
+		assertRowCount(t, tx, "audit_log", 1, "client_id = ?", want)
+	}
+}
+
+// Тест проверяет, что по умолчанию ID назначает AUTOINCREMENT
+func Test_IDGenerator_AutoIncrement(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	var maxID int
+	require.NoError(t, tx.QueryRow("SELECT MAX(id) FROM clients").Scan(&maxID))
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	require.Greater(t, id, maxID, "autoincrement ID should exceed existing IDs")
+}
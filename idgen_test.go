@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что AutoIncrement оставляет назначение ID базе данных
+func Test_AutoIncrement_LeavesIDToDB(t *testing.T) {
+	id, err := AutoIncrement{}.NextID()
+	require.NoError(t, err)
+	assert.Zero(t, id)
+}
+
+// Тест проверяет состав ID snowflake: время, номер узла и порядковый номер
+func Test_Snowflake_ExactIDs(t *testing.T) {
+	clock := testutil.NewFakeClock(snowflakeEpoch.Add(time.Second))
+	ids, err := NewSnowflake(3, clock)
+	require.NoError(t, err)
+
+	next := func() int64 {
+		id, err := ids.NextID()
+		require.NoError(t, err)
+		return id
+	}
+
+	assert.Equal(t, int64(1000<<22|3<<12), next())
+	assert.Equal(t, int64(1000<<22|3<<12|1), next(), "same millisecond should increment the sequence")
+
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, int64(1001<<22|3<<12), next(), "new millisecond should reset the sequence")
+}
+
+// Тест проверяет, что ID возрастают при переполнении порядкового номера
+// и при переводе часов назад
+func Test_Snowflake_Monotonic(t *testing.T) {
+	clock := testutil.NewFakeClock(snowflakeEpoch.Add(time.Hour))
+	ids, err := NewSnowflake(0, clock)
+	require.NoError(t, err)
+
+	var last int64
+	for i := 0; i < 3*(maxSnowflakeSeq+1); i++ {
+		if i == maxSnowflakeSeq {
+			clock.Advance(-time.Minute)
+		}
+		id, err := ids.NextID()
+		require.NoError(t, err)
+		require.Greater(t, id, last, "ID %d is not greater than previous", i)
+		last = id
+	}
+}
+
+// Тест проверяет отказ при некорректном узле и времени вне диапазона
+func Test_Snowflake_OutOfRange(t *testing.T) {
+	_, err := NewSnowflake(MaxSnowflakeNode+1, nil)
+	require.Error(t, err)
+	_, err = NewSnowflake(-1, nil)
+	require.Error(t, err)
+
+	ids, err := NewSnowflake(MaxSnowflakeNode, testutil.NewFakeClock(snowflakeEpoch.Add(-time.Millisecond)))
+	require.NoError(t, err)
+	_, err = ids.NextID()
+	require.ErrorIs(t, err, ErrClockOutOfRange)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ImportOptions задаёт параметры загрузки.
+type ImportOptions struct {
+	// Format — формат данных; по умолчанию CSV.
+	Format ExportFormat
+	// Columns сопоставляет полям клиента названия столбцов CSV (см.
+	// ExportOptions.Columns).
+	Columns map[string]string
+	// DryRun проверяет и загружает данные в транзакции, которая затем
+	// откатывается: возвращается число клиентов, которые были бы загружены.
+	DryRun bool
+}
+
+// Import загружает клиентов из CSV в формате выгрузки Export (заголовок
+// csvHeader). Столбец id игнорируется: клиентам назначаются новые ID.
+// Все строки загружаются в одной транзакции, поэтому при ошибке в любой
+// строке не загружается ничего. Некорректный CSV и данные, не прошедшие
+// Client.Validate, возвращают ErrValidation с номером строки.
+func (r *Repository) Import(ctx context.Context, src io.Reader) (int, error) {
+	return r.ImportWith(ctx, src, ImportOptions{Format: FormatCSV})
+}
+
+// ImportWith загружает клиентов из src в формате opts.Format так же, как
+// Import. В JSON и NDJSON читаются поля fio, login, birthday и email
+// объектов, остальные поля игнорируются; дата рождения принимается в
+// формате ГГГГ-ММ-ДД (как в экспорте) или ГГГГММДД. Ошибка указывает
+// номер записи.
+func (r *Repository) ImportWith(ctx context.Context, src io.Reader, opts ImportOptions) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "import")
+	defer end(&err)
+
+	var dec clientDecoder
+	switch opts.Format {
+	case FormatCSV, "":
+		if dec, err = newCSVDecoder(src, opts.Columns); err != nil {
+			return 0, err
+		}
+	case FormatJSON, FormatNDJSON:
+		if len(opts.Columns) > 0 {
+			return 0, fmt.Errorf("%w: column mapping is supported only for CSV", ErrValidation)
+		}
+		if dec, err = newJSONDecoder(src, opts.Format == FormatJSON); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("%w: unsupported import format %q", ErrValidation, opts.Format)
+	}
+
+	var imported int
+	err = r.inTxDryRun(ctx, opts.DryRun, func(q querier) error {
+		for {
+			cl, err := dec.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			if _, err := r.insert(ctx, q, cl); err != nil {
+				return fmt.Errorf("%s: %w", dec.position(), err)
+			}
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return imported, nil
+}
+
+// clientDecoder читает клиентов из загружаемых данных.
+type clientDecoder interface {
+	// next возвращает следующего клиента или io.EOF после последнего.
+	next() (Client, error)
+	// position описывает место последнего прочитанного клиента для ошибок.
+	position() string
+}
+
+type csvDecoder struct {
+	r *csv.Reader
+	// idx — номера столбцов fio, login, birthday и email
+	idx [4]int
+}
+
+// newCSVDecoder читает заголовок CSV и находит в нём столбцы полей
+// клиента с учётом columns. Столбец id необязателен; другие столбцы не
+// допускаются, чтобы опечатка в сопоставлении не теряла данные молча.
+func newCSVDecoder(src io.Reader, columns map[string]string) (*csvDecoder, error) {
+	names, err := columnNames(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(src)
+	header, err := r.Read()
+	if err != nil {
+		return nil, importError(err)
+	}
+	r.FieldsPerRecord = len(header)
+
+	pos := make(map[string]int, len(header))
+	for i, name := range header {
+		pos[name] = i
+	}
+
+	d := &csvDecoder{r: r}
+	known := 0
+	if _, ok := pos[names[0]]; ok {
+		known++
+	}
+	for i, name := range names[1:] {
+		p, ok := pos[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected CSV header %q: missing column %q", ErrValidation, header, name)
+		}
+		d.idx[i] = p
+		known++
+	}
+	if known != len(header) {
+		return nil, fmt.Errorf("%w: unexpected CSV header %q", ErrValidation, header)
+	}
+
+	return d, nil
+}
+
+func (d *csvDecoder) next() (Client, error) {
+	record, err := d.r.Read()
+	if errors.Is(err, io.EOF) {
+		return Client{}, io.EOF
+	}
+	if err != nil {
+		return Client{}, importError(err)
+	}
+
+	return Client{FIO: record[d.idx[0]], Login: record[d.idx[1]], Birthday: record[d.idx[2]], Email: record[d.idx[3]]}, nil
+}
+
+func (d *csvDecoder) position() string {
+	line, _ := d.r.FieldPos(0)
+
+	return fmt.Sprintf("line %d", line)
+}
+
+// jsonDecoder читает массив объектов (JSON) или объекты подряд (NDJSON),
+// не загружая данные в память целиком.
+type jsonDecoder struct {
+	dec   *json.Decoder
+	array bool
+	count int
+}
+
+func newJSONDecoder(src io.Reader, array bool) (*jsonDecoder, error) {
+	d := &jsonDecoder{dec: json.NewDecoder(src), array: array}
+	if array {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, jsonImportError(err)
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, fmt.Errorf("%w: JSON import must be an array of clients", ErrValidation)
+		}
+	}
+
+	return d, nil
+}
+
+func (d *jsonDecoder) next() (Client, error) {
+	if d.array && !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil {
+			return Client{}, jsonImportError(err)
+		}
+		return Client{}, io.EOF
+	}
+
+	var record struct {
+		FIO      string `json:"fio"`
+		Login    string `json:"login"`
+		Birthday string `json:"birthday"`
+		Email    string `json:"email"`
+	}
+	d.count++
+	if err := d.dec.Decode(&record); err != nil {
+		if !d.array && errors.Is(err, io.EOF) {
+			return Client{}, io.EOF
+		}
+		return Client{}, fmt.Errorf("%s: %w", d.position(), jsonImportError(err))
+	}
+
+	return Client{FIO: record.FIO, Login: record.Login, Birthday: importBirthday(record.Birthday), Email: record.Email}, nil
+}
+
+func (d *jsonDecoder) position() string {
+	return fmt.Sprintf("record %d", d.count)
+}
+
+// importError оборачивает ошибку разбора CSV в ErrValidation.
+func importError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: empty CSV", ErrValidation)
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%w: %v", ErrValidation, parseErr)
+	}
+
+	return err
+}
+
+// jsonImportError оборачивает ошибку разбора JSON в ErrValidation.
+func jsonImportError(err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: unexpected end of JSON", ErrValidation)
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+
+	return err
+}
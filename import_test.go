@@ -0,0 +1,80 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что выгрузка загружается обратно, а ошибка в любой
+// строке отменяет загрузку целиком
+func Test_Import_RoundTripAndAtomicity(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.Export(ctx, &buf, ExportOptions{Format: FormatCSV}))
+	exported := buf.String()
+
+	var existing []Client
+	require.NoError(t, repo.ForEach(ctx, func(cl Client) error { existing = append(existing, cl); return nil }))
+
+	var n int
+	var err error
+	diff := testutil.CaptureDiff(t, tx, "clients", "id", func() {
+		n, err = repo.Import(ctx, strings.NewReader(exported))
+	})
+	require.NoError(t, err, "export should be importable: %v", err)
+	assert.Equal(t, len(existing), n)
+
+	// Загружены только новые копии выгруженных клиентов, прежние строки не изменены
+	assert.Empty(t, diff.Modified, "import must not modify existing rows")
+	assert.Empty(t, diff.Removed, "import must not remove rows")
+	require.Len(t, diff.Added, len(existing))
+	for i, row := range diff.Added {
+		cl := existing[i]
+		assert.Equal(t, []any{cl.FIO, cl.Login, cl.Birthday, cl.Email}, []any{row["fio"], row["login"], row["birthday"], row["email"]},
+			"imported row %d should copy exported client %d", i, cl.ID)
+		assert.Greater(t, row["id"], int64(cl.ID), "imported row should get a new ID")
+	}
+
+	broken := exported + "0,Test,Test,19990229,mail@mail.com\n"
+	diff = testutil.CaptureDiff(t, tx, "clients", "id", func() {
+		_, err = repo.Import(ctx, strings.NewReader(broken))
+	})
+	require.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "line ", "error should point to the broken line")
+	assert.True(t, diff.Empty(), "failed import must not change any rows: %+v", diff)
+
+	_, err = repo.Import(ctx, strings.NewReader("id,name\n"))
+	require.ErrorIs(t, err, ErrValidation, "unexpected header should be rejected")
+}
+
+// Фаззинг загрузки CSV: произвольный ввод либо загружается, либо
+// отклоняется с ErrValidation, но никогда не приводит к ошибке SQL
+func FuzzImport(f *testing.F) {
+	f.Add("id,fio,login,birthday,email\n0,Test,Test,19700101,mail@mail.com\n")
+	f.Add("id,fio,login,birthday,email\n0,\"Robert'); DROP TABLE clients; --\",bobby,19700101,b@mail.com\n")
+	f.Add("id,fio,login,birthday,email\n0,\"unterminated,Test,19700101,mail@mail.com\n")
+	f.Add("id,fio,login,birthday,email\n0,Test,Test\n")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		_, repo := setupTestTx(t)
+
+		n, err := repo.Import(context.Background(), strings.NewReader(data))
+		if err != nil {
+			require.ErrorIs(t, err, ErrValidation, "import should fail only on invalid input: %v", err)
+			assert.Zero(t, n)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// queryEvent описывает выполненный запрос для наблюдателей (журнал, метрики).
+type queryEvent struct {
+	Op       string
+	SQL      string
+	Args     []any
+	Start    time.Time
+	Duration time.Duration
+	// Rows — число затронутых строк; -1, если оно неизвестно (для выборок
+	// и неуспешных запросов).
+	Rows int64
+	Err  error
+	// Slow — запрос выполнялся дольше порога, заданного WithSlowQueryLog.
+	Slow bool
+}
+
+// queryHook получает сведения о каждом запросе, выполненном репозиторием.
+type queryHook interface {
+	afterQuery(ctx context.Context, ev queryEvent)
+}
+
+type operationKey struct{}
+
+// withOperation помечает контекст именем операции репозитория, в рамках
+// которой выполняются запросы.
+func withOperation(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+func operationFromContext(ctx context.Context) string {
+	op, _ := ctx.Value(operationKey{}).(string)
+
+	return op
+}
+
+// observe оборачивает q, если у репозитория есть наблюдатели за запросами.
+func (r *Repository) observe(q querier) querier {
+	if len(r.hooks) == 0 {
+		return q
+	}
+
+	return &instrumentedQuerier{q: q, hooks: r.hooks, slowThreshold: r.slowThreshold}
+}
+
+// instrumentedQuerier сообщает наблюдателям о каждом запросе, выполняемом через q.
+type instrumentedQuerier struct {
+	q             querier
+	hooks         []queryHook
+	slowThreshold time.Duration
+}
+
+func (i *instrumentedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := i.q.ExecContext(ctx, query, args...)
+
+	rows := int64(-1)
+	if err == nil {
+		// Не все драйверы сообщают число строк, в этом случае остаётся -1
+		if n, rowsErr := res.RowsAffected(); rowsErr == nil {
+			rows = n
+		}
+	}
+	i.notify(ctx, query, args, start, rows, err)
+
+	return res, err
+}
+
+func (i *instrumentedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := i.q.QueryContext(ctx, query, args...)
+	i.notify(ctx, query, args, start, -1, err)
+
+	return rows, err
+}
+
+func (i *instrumentedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := i.q.QueryRowContext(ctx, query, args...)
+	i.notify(ctx, query, args, start, -1, row.Err())
+
+	return row
+}
+
+// PrepareContext подготавливает запрос без уведомления наблюдателей: они
+// получают сведения о запросах при выполнении.
+func (i *instrumentedQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.q.PrepareContext(ctx, query)
+}
+
+func (i *instrumentedQuerier) notify(ctx context.Context, query string, args []any, start time.Time, rows int64, err error) {
+	elapsed := time.Since(start)
+	query, args = sqlcQuery(query, args)
+	ev := queryEvent{
+		Op:       operationFromContext(ctx),
+		SQL:      query,
+		Args:     args,
+		Start:    start,
+		Duration: elapsed,
+		Rows:     rows,
+		Err:      err,
+		Slow:     i.slowThreshold > 0 && elapsed > i.slowThreshold,
+	}
+	for _, h := range i.hooks {
+		h.afterQuery(ctx, ev)
+	}
+}
@@ -0,0 +1,204 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Интеграционные тесты работают с настоящей БД SQLite и запускаются
+// только с тегом сборки integration:
+//
+//	go test -tags integration ./...
+//
+// Где находится общая тестовая БД, задаёт переменная окружения
+// CLIENTS_TEST_BACKEND (см. testBackends).
+
+// TestBackendEnv — переменная окружения, выбирающая хранилище тестовой БД.
+const TestBackendEnv = "CLIENTS_TEST_BACKEND"
+
+// testBackends — поддерживаемые хранилища тестовой БД: функция возвращает
+// DSN новой БД, при необходимости используя временный каталог dir.
+var testBackends = map[string]func(dir string) string{
+	// Файл во временном каталоге (по умолчанию)
+	"file": func(dir string) string { return filepath.Join(dir, "test.db") },
+	// БД в памяти процесса (VFS memdb): быстрее файла и доступна всем
+	// соединениям процесса, пока открыто хотя бы одно из них
+	"memory": func(string) string { return "file:/clients-test?vfs=memdb" },
+}
+
+// goldenDataset — эталонные данные, которыми заполняется тестовая БД
+const goldenDataset = "testdata/golden.sql"
+
+// testDBDSN — DSN тестовой БД, созданной в TestMain
+var testDBDSN string
+
+// TestMain создаёт для всего запуска тестов пакета временную БД, применяет
+// миграции и заполняет её эталонными данными. После завершения тестов БД
+// удаляется, поэтому тесты не зависят от demo.db и не изменяют его.
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	backend := os.Getenv(TestBackendEnv)
+	if backend == "" {
+		backend = "file"
+	}
+	newDSN, ok := testBackends[backend]
+	if !ok {
+		// Запросы репозитория написаны для SQLite, поэтому другие СУБД
+		// (например, PostgreSQL) не поддерживаются
+		fmt.Fprintf(os.Stderr, "unsupported %s=%q: want file or memory\n", TestBackendEnv, backend)
+		return 1
+	}
+
+	dir, err := os.MkdirTemp("", "clients-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "create test database directory:", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	testDBDSN = newDSN(dir)
+	// Соединение держится открытым до конца тестов: БД в памяти
+	// удаляется при закрытии последнего соединения
+	db, err := provisionTestDB(context.Background(), testDBDSN)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "provision test database:", err)
+		return 1
+	}
+	defer db.Close()
+
+	return m.Run()
+}
+
+// provisionTestDB создаёт БД с актуальной схемой и эталонными данными.
+func provisionTestDB(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	seed, err := os.ReadFile(goldenDataset)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, string(seed)); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// testDSN возвращает DSN тестовой БД с дополнительными параметрами params.
+func testDSN(params ...string) string {
+	dsn := testDBDSN
+	for _, p := range params {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + p
+	}
+
+	return dsn
+}
+
+// Таблицы, строки которых ссылаются на клиентов, и столбец ссылки
+var clientRefTables = map[string]string{
+	"audit_log":           "client_id",
+	"client_autocomplete": "client_id",
+	"client_documents":    "client_id",
+	"client_notes":        "client_id",
+	"client_summary":      "client_id",
+	"client_tags":         "client_id",
+	"directory_links":     "client_id",
+	"clients_history":     "client_id",
+	"erasure_receipts":    "client_id",
+	"orders":              "client_id",
+	"sales":               "client",
+}
+
+// setupTestDB подключается к тестовой БД и возвращает соединение и
+// репозиторий с указанными опциями. После теста удаляются все клиенты,
+// созданные во время теста, и ссылающиеся на них строки, а соединение
+// закрывается.
+func setupTestDB(t testing.TB, opts ...Option) (*sql.DB, *Repository) {
+	t.Helper()
+
+	// Подключение к базе данных SQLite
+	db, err := sql.Open("sqlite", testDSN())
+	require.NoError(t, err, "database connection error: %v", err)
+
+	lastID := lastClientID(t, db)
+	t.Cleanup(func() { cleanupTestDB(t, db, lastID) })
+
+	return db, NewRepository(db, opts...)
+}
+
+// lastClientID возвращает максимальный выданный ID клиента: клиенты
+// с ID больше него созданы тестом.
+func lastClientID(t testing.TB, db *sql.DB) int {
+	t.Helper()
+
+	var lastID int
+	err := db.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM sqlite_sequence WHERE name = 'clients'").Scan(&lastID)
+	require.NoError(t, err, "error reading clients sequence: %v", err)
+
+	return lastID
+}
+
+// cleanupTestDB удаляет клиентов с ID больше lastID вместе со
+// ссылающимися на них строками и закрывает соединение.
+func cleanupTestDB(t testing.TB, db *sql.DB, lastID int) {
+	t.Helper()
+
+	for table, column := range clientRefTables {
+		_, err := db.Exec("DELETE FROM "+table+" WHERE "+column+" > :id", sql.Named("id", lastID))
+		require.NoError(t, err, "error cleaning up %s: %v", table, err)
+	}
+	_, err := db.Exec("DELETE FROM clients WHERE id > :id", sql.Named("id", lastID))
+	require.NoError(t, err, "error cleaning up clients: %v", err)
+
+	// Закрытие соединения после завершения теста
+	require.NoError(t, db.Close())
+}
+
+// setupTestTx открывает транзакцию в тестовой БД и возвращает её и
+// репозиторий, работающий внутри неё. После теста транзакция откатывается,
+// поэтому тест не оставляет следов в БД и может вызывать t.Parallel.
+// Транзакция сразу захватывает блокировку на запись (BEGIN IMMEDIATE), так
+// что параллельные тесты ожидают друг друга, а не получают SQLITE_BUSY.
+func setupTestTx(t testing.TB, opts ...Option) (*sql.Tx, *Repository) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", testDSN("_txlock=immediate", "_pragma=busy_timeout(30000)"))
+	require.NoError(t, err, "database connection error: %v", err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err, "error starting transaction: %v", err)
+
+	t.Cleanup(func() {
+		require.NoError(t, tx.Rollback(), "error rolling back test transaction")
+		require.NoError(t, db.Close())
+	})
+
+	return tx, NewTxRepository(tx, opts...)
+}
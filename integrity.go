@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Проверки, выполняемые IntegrityCheck.
+const (
+	// IntegritySQLite — сообщение PRAGMA integrity_check о повреждении файла БД.
+	IntegritySQLite = "sqlite"
+	// IntegrityOrphanedOrder — заказ клиента, которого нет в БД.
+	IntegrityOrphanedOrder = "orphaned_order"
+	// IntegrityOrphanedNote — заметка о клиенте, которого нет в БД.
+	IntegrityOrphanedNote = "orphaned_note"
+	// IntegrityInvalidEmail — email клиента, который не прошёл бы Validate.
+	IntegrityInvalidEmail = "invalid_email"
+	// IntegrityInvalidBirthday — дата рождения клиента, которая не прошла
+	// бы Validate.
+	IntegrityInvalidBirthday = "invalid_birthday"
+)
+
+// IntegrityIssue — нарушение, найденное IntegrityCheck.
+type IntegrityIssue struct {
+	Check string `json:"check"`
+	// Table и ID — таблица и ID строки с нарушением; для IntegritySQLite
+	// не заполняются.
+	Table  string `json:"table,omitempty"`
+	ID     int    `json:"id,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// IntegrityReport — результат IntegrityCheck.
+type IntegrityReport struct {
+	Issues []IntegrityIssue `json:"issues"`
+}
+
+// OK сообщает, что нарушений не найдено.
+func (r IntegrityReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// IntegrityCheck проверяет целостность файла БД (PRAGMA integrity_check)
+// и данных: заказы и заметки, ссылающиеся на несуществующих клиентов, и
+// клиентов с email или датой рождения, которые не прошли бы Validate.
+// Такие данные появляются только в обход репозитория, поэтому нарушения
+// возвращаются в отчёте, а не ошибкой. Сначала идут нарушения файла БД,
+// затем заказов, заметок и клиентов по возрастанию ID; поля клиентов
+// проверяются после расшифровки.
+func (r *Repository) IntegrityCheck(ctx context.Context) (_ IntegrityReport, err error) {
+	ctx, end := r.startOperation(ctx, "integrity_check")
+	defer end(&err)
+
+	var report IntegrityReport
+
+	messages, err := queryStrings(ctx, r.conn(), "PRAGMA integrity_check")
+	if err != nil {
+		return IntegrityReport{}, err
+	}
+	for _, msg := range messages {
+		if msg != "ok" {
+			report.Issues = append(report.Issues, IntegrityIssue{Check: IntegritySQLite, Detail: msg})
+		}
+	}
+
+	orphans := []struct{ check, table string }{
+		{IntegrityOrphanedOrder, "orders"},
+		{IntegrityOrphanedNote, "client_notes"},
+	}
+	for _, o := range orphans {
+		rows, err := r.conn().QueryContext(ctx, "SELECT t.id, t.client_id FROM "+o.table+
+			" t LEFT JOIN clients c ON c.id = t.client_id WHERE c.id IS NULL"+
+			" AND t.client_id NOT IN (SELECT id FROM clients_archive) ORDER BY t.id")
+		if err != nil {
+			return IntegrityReport{}, err
+		}
+		for rows.Next() {
+			var id, clientID int
+			if err := rows.Scan(&id, &clientID); err != nil {
+				rows.Close()
+				return IntegrityReport{}, err
+			}
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: o.check, Table: o.table, ID: id,
+				Detail: fmt.Sprintf("client %d does not exist", clientID),
+			})
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return IntegrityReport{}, err
+		}
+	}
+
+	now := r.now()
+	err = r.forEach(ctx, "", nil, func(cl Client) error {
+		if err := validateField("email", cl.Email, maxEmailLen); err != nil {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IntegrityInvalidEmail, Table: "clients", ID: cl.ID, Detail: err.Error(),
+			})
+		}
+		if _, err := parseBirthday(cl.Birthday, now); err != nil {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IntegrityInvalidBirthday, Table: "clients", ID: cl.ID, Detail: err.Error(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return IntegrityReport{}, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что IntegrityCheck находит заказы и заметки без клиента
+// и клиентов с некорректными полями, записанных в обход репозитория
+func Test_IntegrityCheck(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	clock := testutil.NewFakeClock(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	repo := NewRepository(db, WithClock(clock))
+
+	ids := make([]int, 3)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		_, err = repo.Orders().Create(ctx, Order{ClientID: ids[i], Amount: 100})
+		require.NoError(t, err)
+		_, err = repo.AddNote(ctx, ids[i], "note")
+		require.NoError(t, err)
+	}
+
+	report, err := repo.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.Issues)
+
+	// Удаление клиента в обход репозитория оставляет его заказ и заметку
+	_, err = db.ExecContext(ctx, "DELETE FROM clients WHERE id = ?", ids[1])
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "UPDATE clients SET email = ' ', birthday = '19991332' WHERE id = ?", ids[0])
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "UPDATE clients SET birthday = '20250602' WHERE id = ?", ids[2])
+	require.NoError(t, err)
+
+	report, err = repo.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, []IntegrityIssue{
+		{Check: IntegrityOrphanedOrder, Table: "orders", ID: 2, Detail: "client 2 does not exist"},
+		{Check: IntegrityOrphanedNote, Table: "client_notes", ID: 2, Detail: "client 2 does not exist"},
+		{Check: IntegrityInvalidEmail, Table: "clients", ID: ids[0], Detail: "validation failed: email is required"},
+		{Check: IntegrityInvalidBirthday, Table: "clients", ID: ids[0], Detail: `validation failed: birthday is not a valid YYYYMMDD date`},
+		{Check: IntegrityInvalidBirthday, Table: "clients", ID: ids[2], Detail: `validation failed: birthday is out of range`},
+	}, report.Issues)
+
+	// Дата рождения проверяется по часам репозитория
+	clock.Advance(48 * time.Hour)
+	report, err = repo.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	assert.Len(t, report.Issues, 4)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/gen"
+	"github.com/Yandex-Practicum/go-db-sql-query-test/loadtest"
+)
+
+// runLoadTest выполняет команду loadtest: подаёт на репозиторий нагрузку
+// с заданной частотой и долей чтений и выводит отчёт в w.
+func runLoadTest(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	fs.SetOutput(w)
+	configPath := fs.String("config", "", "YAML settings file; environment variables override it")
+	dsn := fs.String("db", "", "SQLite database DSN (overrides db.dsn and db.secrets_dir of -config)")
+	cfg := loadtest.Config{}
+	fs.Float64Var(&cfg.QPS, "qps", 50, "requests per second")
+	fs.DurationVar(&cfg.Duration, "duration", 10*time.Second, "load duration")
+	fs.Float64Var(&cfg.ReadRatio, "read-ratio", 0.9, "share of reads, from 0 to 1")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 64, "maximum concurrent requests")
+	fs.Int64Var(&cfg.Seed, "seed", 1, "seed for the read/write mix and generated clients")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	settings, err := Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if *dsn != "" {
+		settings.DB.DSN, settings.DB.SecretsDir = *dsn, ""
+	}
+	db, err := settings.DB.OpenDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := Migrate(ctx, db); err != nil {
+		return err
+	}
+
+	repo := NewRepository(db, WithBusyRetry(settings.DB.BusyRetryAttempts, settings.DB.BusyRetryBackoff))
+	target, err := newLoadTarget(ctx, repo, cfg.Seed)
+	if err != nil {
+		return err
+	}
+
+	report, err := loadtest.Run(ctx, cfg, target)
+	if err != nil {
+		return err
+	}
+	_, err = report.WriteTo(w)
+
+	return err
+}
+
+// newLoadTarget возвращает операции нагрузки на репозиторий: чтение
+// случайного существующего клиента и вставку клиента из генератора gen.
+// Вставленные клиенты тоже становятся кандидатами для чтения.
+func newLoadTarget(ctx context.Context, repo *Repository, seed int64) (loadtest.Target, error) {
+	var ids []int
+	err := repo.ForEach(ctx, func(cl Client) error {
+		ids = append(ids, cl.ID)
+		return nil
+	})
+	if err != nil {
+		return loadtest.Target{}, err
+	}
+
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewSource(seed))
+	fakes := gen.New(seed)
+
+	return loadtest.Target{
+		Read: func(ctx context.Context) error {
+			mu.Lock()
+			if len(ids) == 0 {
+				mu.Unlock()
+				return ErrClientNotFound
+			}
+			id := ids[rnd.Intn(len(ids))]
+			mu.Unlock()
+
+			_, err := repo.Select(ctx, id)
+			return err
+		},
+		Write: func(ctx context.Context) error {
+			mu.Lock()
+			fake := fakes.Client()
+			mu.Unlock()
+
+			id, err := repo.Insert(ctx, Client{FIO: fake.FIO, Login: fake.Login, Birthday: fake.Birthday, Email: fake.Email})
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			ids = append(ids, id)
+			mu.Unlock()
+			return nil
+		},
+	}, nil
+}
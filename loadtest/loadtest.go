@@ -0,0 +1,229 @@
+// Package loadtest подаёт нагрузку с заданной частотой запросов и долей
+// чтений на произвольную цель (репозиторий, HTTP API) и собирает
+// перцентили задержки и долю ошибок.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Op — одна операция нагрузки.
+type Op func(ctx context.Context) error
+
+// Target — операции чтения и записи, между которыми распределяется нагрузка.
+type Target struct {
+	Read  Op
+	Write Op
+}
+
+// Config задаёт профиль нагрузки.
+type Config struct {
+	// QPS — частота запусков запросов в секунду.
+	QPS float64
+	// Duration — длительность нагрузки.
+	Duration time.Duration
+	// ReadRatio — доля чтений от 0 до 1, остальное — записи.
+	ReadRatio float64
+	// Concurrency — наибольшее число одновременно выполняемых запросов.
+	// Если все заняты, очередной запрос ждёт освобождения, и фактическая
+	// частота оказывается ниже QPS. По умолчанию 64.
+	Concurrency int
+	// Seed — зерно выбора между чтением и записью.
+	Seed int64
+}
+
+const defaultConcurrency = 64
+
+// Stats — результаты запросов одного вида.
+type Stats struct {
+	Requests int
+	Errors   int
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// ErrorRate возвращает долю запросов, завершившихся ошибкой.
+func (s Stats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// Report — итог нагрузки: результаты по чтениям, записям и всем запросам.
+type Report struct {
+	Elapsed time.Duration
+	Read    Stats
+	Write   Stats
+	Total   Stats
+}
+
+// QPS возвращает фактическую частоту выполненных запросов.
+func (r Report) QPS() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(r.Total.Requests) / r.Elapsed.Seconds()
+}
+
+// WriteTo выводит отчёт в виде таблицы.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	write := func(format string, args ...any) error {
+		m, err := fmt.Fprintf(w, format, args...)
+		n += int64(m)
+		return err
+	}
+
+	if err := write("elapsed %v, %.1f req/s\n", r.Elapsed.Round(time.Millisecond), r.QPS()); err != nil {
+		return n, err
+	}
+	if err := write("%-6s %8s %8s %10s %10s %10s %10s\n", "op", "requests", "errors", "p50", "p90", "p99", "max"); err != nil {
+		return n, err
+	}
+	for _, row := range []struct {
+		name  string
+		stats Stats
+	}{{"read", r.Read}, {"write", r.Write}, {"total", r.Total}} {
+		s := row.stats
+		if err := write("%-6s %8d %7.2f%% %10v %10v %10v %10v\n", row.name, s.Requests, 100*s.ErrorRate(),
+			s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond)); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// sample — результат одного запроса.
+type sample struct {
+	latency time.Duration
+	failed  bool
+}
+
+// Run подаёт нагрузку на target в течение cfg.Duration или до отмены ctx.
+// Запросы запускаются через равные интервалы 1/QPS независимо от времени
+// выполнения предыдущих, поэтому замедление цели видно в задержках, а не
+// скрывается снижением частоты.
+func Run(ctx context.Context, cfg Config, target Target) (Report, error) {
+	if cfg.QPS <= 0 || cfg.Duration <= 0 {
+		return Report{}, errors.New("loadtest: QPS and duration must be positive")
+	}
+	if cfg.ReadRatio < 0 || cfg.ReadRatio > 1 {
+		return Report{}, fmt.Errorf("loadtest: read ratio %v is out of range [0, 1]", cfg.ReadRatio)
+	}
+	if target.Read == nil || target.Write == nil {
+		return Report{}, errors.New("loadtest: target must define both read and write operations")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	interval := time.Duration(float64(time.Second) / cfg.QPS)
+	slots := make(chan struct{}, cfg.Concurrency)
+
+	var (
+		mu            sync.Mutex
+		reads, writes []sample
+		wg            sync.WaitGroup
+	)
+
+	started := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+loop:
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-timer.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case slots <- struct{}{}:
+		}
+
+		op, samples := target.Write, &writes
+		if rnd.Float64() < cfg.ReadRatio {
+			op, samples = target.Read, &reads
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// Запросы, начатые до окончания нагрузки, выполняются до конца
+			opStarted := time.Now()
+			err := op(context.WithoutCancel(ctx))
+			s := sample{latency: time.Since(opStarted), failed: err != nil}
+
+			mu.Lock()
+			*samples = append(*samples, s)
+			mu.Unlock()
+		}()
+
+		timer.Reset(time.Until(started.Add(time.Duration(i+1) * interval)))
+	}
+	wg.Wait()
+
+	return Report{
+		Elapsed: time.Since(started),
+		Read:    summarize(reads),
+		Write:   summarize(writes),
+		Total:   summarize(append(reads, writes...)),
+	}, nil
+}
+
+// summarize считает число запросов, ошибок и перцентили задержки.
+func summarize(samples []sample) Stats {
+	stats := Stats{Requests: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	latencies := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		latencies[i] = s.latency
+		if s.failed {
+			stats.Errors++
+		}
+	}
+	slices.Sort(latencies)
+
+	stats.P50 = percentile(latencies, 50)
+	stats.P90 = percentile(latencies, 90)
+	stats.P99 = percentile(latencies, 99)
+	stats.Max = latencies[len(latencies)-1]
+
+	return stats
+}
+
+// percentile возвращает p-й перцентиль отсортированных значений методом
+// ближайшего ранга.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
@@ -0,0 +1,83 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет перцентили методом ближайшего ранга
+func Test_Percentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, time.Millisecond, percentile(sorted[:1], 99))
+}
+
+// Тест проверяет частоту, долю чтений, учёт ошибок и задержек
+func Test_Run_MixAndErrors(t *testing.T) {
+	var reads, writes atomic.Int64
+	target := Target{
+		Read: func(context.Context) error {
+			reads.Add(1)
+			time.Sleep(time.Millisecond)
+			return nil
+		},
+		Write: func(context.Context) error {
+			if writes.Add(1)%2 == 0 {
+				return errors.New("write failed")
+			}
+			return nil
+		},
+	}
+
+	report, err := Run(context.Background(), Config{QPS: 400, Duration: 500 * time.Millisecond, ReadRatio: 0.75, Seed: 1}, target)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 200, report.Total.Requests, 20, "requests should follow QPS")
+	assert.Equal(t, int(reads.Load()), report.Read.Requests)
+	assert.Equal(t, int(writes.Load()), report.Write.Requests)
+	assert.InDelta(t, 0.75, float64(report.Read.Requests)/float64(report.Total.Requests), 0.1, "read ratio")
+
+	assert.Zero(t, report.Read.Errors)
+	assert.Equal(t, report.Write.Requests/2, report.Write.Errors)
+	assert.InDelta(t, 0.5, report.Write.ErrorRate(), 0.05)
+	assert.GreaterOrEqual(t, report.Read.P50, time.Millisecond)
+	assert.LessOrEqual(t, report.Read.P50, report.Read.P99)
+
+	var buf bytes.Buffer
+	_, err = report.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "read")
+	assert.Contains(t, buf.String(), "p99")
+}
+
+// Тест проверяет отказ при некорректном профиле нагрузки
+func Test_Run_InvalidConfig(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+	target := Target{Read: noop, Write: noop}
+
+	for name, cfg := range map[string]Config{
+		"ZeroQPS":      {Duration: time.Second},
+		"ZeroDuration": {QPS: 1},
+		"BadRatio":     {QPS: 1, Duration: time.Second, ReadRatio: 1.5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Run(context.Background(), cfg, target)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := Run(context.Background(), Config{QPS: 1, Duration: time.Second}, Target{Read: noop})
+	require.Error(t, err, "missing write operation should be rejected")
+}
@@ -0,0 +1,52 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/loadtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Дымовой тест нагрузки: двухсекундная нагрузка на репозиторий
+// выполняется без ошибок с заданной частотой
+func Test_LoadTest_Smoke(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test burst is skipped in -short mode")
+	}
+	db, _ := setupTestDB(t)
+	repo := NewRepository(db, WithBusyRetry(5, 10*time.Millisecond))
+
+	ctx := context.Background()
+	before := lastClientID(t, db)
+	target, err := newLoadTarget(ctx, repo, 1)
+	require.NoError(t, err)
+
+	report, err := loadtest.Run(ctx, loadtest.Config{QPS: 100, Duration: 2 * time.Second, ReadRatio: 0.8, Seed: 1}, target)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 200, report.Total.Requests, 20, "requests should follow QPS")
+	assert.Positive(t, report.Read.Requests)
+	assert.Positive(t, report.Write.Requests)
+	assert.Zero(t, report.Total.Errors, "no request should fail under smoke load")
+	assertRowCount(t, db, "clients", report.Write.Requests, "id > ?", before)
+}
+
+// Тест проверяет разбор флагов и вывод отчёта командой loadtest
+func Test_Run_LoadTestCommand(t *testing.T) {
+	setupTestDB(t)
+
+	var out bytes.Buffer
+	err := run(context.Background(), []string{"loadtest", "-db", testDSN(), "-qps", "20", "-duration", "200ms"}, &out)
+	require.NoError(t, err, "loadtest command failed: %s", out.String())
+	assert.Contains(t, out.String(), "req/s")
+	assert.Contains(t, out.String(), "total")
+
+	require.Error(t, run(context.Background(), []string{"unknown"}, &out))
+	require.Error(t, run(context.Background(), nil, &out))
+}
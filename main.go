@@ -1,19 +1,37 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 )
 
-type Client struct {
-	ID       int
-	FIO      string
-	Login    string
-	Birthday string
-	Email    string
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-func main() {
+// run выполняет команду, заданную аргументами командной строки.
+func run(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: go-db-sql-query-test loadtest|clientctl|genmodel [flags]")
+	}
 
+	switch args[0] {
+	case "loadtest":
+		return runLoadTest(ctx, args[1:], w)
+	case "clientctl":
+		return runClientctl(ctx, args[1:], w)
+	case "genmodel":
+		return runGenModel(ctx, args[1:], w)
+	}
+
+	return fmt.Errorf("unknown command %q", args[0])
 }
 
 func selectClient(db *sql.DB, id int) (Client, error) {
@@ -29,6 +47,10 @@ func selectClient(db *sql.DB, id int) (Client, error) {
 }
 
 func insertClient(db *sql.DB, client Client) (int, error) {
+	if err := client.Validate(); err != nil {
+		return 0, err
+	}
+
 	res, err := db.Exec("INSERT INTO clients (fio, login, birthday, email) VALUES (:fio, :login, :birthday, :email)",
 		sql.Named("fio", client.FIO),
 		sql.Named("login", client.Login),
@@ -1,146 +1,218 @@
+//go:build integration
+
 package main
 
 import (
 	"database/sql"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
 	_ "modernc.org/sqlite"
 )
 
-// Тест проверяет корректность работы функции selectClient при успешном выполнении
-func Test_SelectClient_WhenOk(t *testing.T) {
-	// Подключение к базе данных SQLite
-	db, err := sql.Open("sqlite", "demo.db")
-	require.NoError(t, err, "database connection error: %v", err)
-	// Закрытие соединения после завершения теста
-	defer db.Close()
-
-	// ID клиента для тестирования
-	clientID := 1
-
-	// Получение данных клиента из базы
-	client, err := selectClient(db, clientID)
-	// Проверка, что при получении данных клиента из БД не было ошибок
-	require.NoError(t, err, "error retrieving client with ID %d: %v", clientID, err)
-
-	// Подтест для проверки полей клиента на корректность ID и заполненность всех строковых полей
-	t.Run("CheckClientFields", func(t *testing.T) {
-		// Проверка совпадения ID
-		assert.Equal(t, client.ID, clientID, "ID mismatch: expected %d, got %d", clientID, client.ID)
-		// Проверка обязательных полей
-		assert.NotEmpty(t, client.Birthday, "birthday field should not be empty for client ID %d", clientID)
-		assert.NotEmpty(t, client.Email, "email field should not be empty for client ID %d", clientID)
-		assert.NotEmpty(t, client.FIO, "FIO field should not be empty for client ID %d", clientID)
-		assert.NotEmpty(t, client.Login, "login field should not be empty for client ID %d", clientID)
-	})
+// clientsSuite — набор тестов функций работы с клиентами с общим
+// жизненным циклом: перед каждым тестом открывается соединение с тестовой
+// БД и загружается клиент-фикстура, после теста созданные им клиенты
+// удаляются.
+type clientsSuite struct {
+	suite.Suite
+
+	db *sql.DB
+	// lastID — максимальный ID клиента до начала теста
+	lastID int
+	// fixture — клиент, вставленный перед каждым тестом
+	fixture Client
 }
 
-// Тест проверяет корректность обработки кейсов, когда клиент с указанным ID отсутствует в БД
-func Test_SelectClient_WhenNoClient(t *testing.T) {
-	// Подключение к базе данных SQLite
-	db, err := sql.Open("sqlite", "demo.db")
-	require.NoError(t, err, "database connection error: %v", err)
-	// Закрытие соединения после завершения теста
-	defer db.Close()
-
-	// Невалидный ID клиента для тестирования (несуществующий в базе)
-	clientID := -1
-
-	// Попытка получения данных несуществующего клиента
-	client, err := selectClient(db, clientID)
-	// Проверка возникновения ошибки и проверка типа ошибки
-	require.Error(t, err, "expected error when selecting non-existent client with ID %d", clientID)
-	require.Equal(t, sql.ErrNoRows, err, "expected sql.ErrNoRows error when selecting client with ID %d", clientID)
-
-	// Подтест для проверки состояния объекта клиента на отсутствии данных в БД
-	t.Run("CheckClientFields", func(t *testing.T) {
-		// Проверка, что все поля пустые
-		assert.Empty(t, client.ID, "ID field should be empty for non-existent client with ID %d", clientID)
-		assert.Empty(t, client.Birthday, "birthday field should be empty for non-existent client with ID %d", clientID)
-		assert.Empty(t, client.Email, "email field should be empty for non-existent client with ID %d", clientID)
-		assert.Empty(t, client.FIO, "FIO field should be empty for non-existent client with ID %d", clientID)
-		assert.Empty(t, client.Login, "login field should be empty for non-existent client with ID %d", clientID)
-	})
+func TestClientsSuite(t *testing.T) {
+	suite.Run(t, new(clientsSuite))
+}
+
+func (s *clientsSuite) SetupTest() {
+	var err error
+	s.db, err = sql.Open("sqlite", testDSN())
+	s.Require().NoError(err, "database connection error: %v", err)
+	s.lastID = lastClientID(s.T(), s.db)
+
+	s.fixture = newTestClient(func(cl *Client) { cl.Email = "fixture@mail.com" })
+	s.fixture.ID, err = insertClient(s.db, s.fixture)
+	s.Require().NoError(err, "error loading fixture client: %v", err)
+}
+
+func (s *clientsSuite) TearDownTest() {
+	cleanupTestDB(s.T(), s.db, s.lastID)
+}
+
+// Тест проверяет корректность работы функции selectClient для существующего
+// и отсутствующего в БД клиента
+func (s *clientsSuite) TestSelectClient() {
+	tests := []struct {
+		name     string
+		clientID int
+		wantErr  error
+	}{
+		{name: "WhenOk", clientID: s.fixture.ID},
+		{name: "WhenNoClient", clientID: -1, wantErr: sql.ErrNoRows},
+		{name: "WhenZeroID", clientID: 0, wantErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			t := s.T()
+
+			// Получение данных клиента из базы
+			client, err := selectClient(s.db, tt.clientID)
+
+			if tt.wantErr != nil {
+				// Проверка возникновения ошибки и проверка типа ошибки
+				require.Error(t, err, "expected error when selecting client with ID %d", tt.clientID)
+				require.Equal(t, tt.wantErr, err, "unexpected error when selecting client with ID %d", tt.clientID)
+
+				// Проверка, что все поля пустые
+				assert.Empty(t, client.ID, "ID field should be empty for non-existent client with ID %d", tt.clientID)
+				assert.Empty(t, client.Birthday, "birthday field should be empty for non-existent client with ID %d", tt.clientID)
+				assert.Empty(t, client.Email, "email field should be empty for non-existent client with ID %d", tt.clientID)
+				assert.Empty(t, client.FIO, "FIO field should be empty for non-existent client with ID %d", tt.clientID)
+				assert.Empty(t, client.Login, "login field should be empty for non-existent client with ID %d", tt.clientID)
+				return
+			}
+
+			// Проверка, что при получении данных клиента из БД не было ошибок
+			require.NoError(t, err, "error retrieving client with ID %d: %v", tt.clientID, err)
+			assertClientEqual(t, s.fixture, client)
+		})
+	}
+}
+
+// insertClientCases — сценарии вставки клиента. Для корректных клиентов
+// wantErr пуст, для некорректных — ожидается ErrValidation.
+var insertClientCases = []struct {
+	name    string
+	client  Client
+	wantErr error
+}{
+	{name: "ValidClient", client: newTestClient()},
+	{name: "UnicodeFIO", client: newTestClient(func(cl *Client) { cl.FIO = "Щёлкова-Ёлкина Анна-Мария Львовна 👩" })},
+	{name: "MaxLengthStrings", client: newTestClient(func(cl *Client) {
+		cl.FIO = strings.Repeat("Ж", maxFIOLen)
+		cl.Login = strings.Repeat("l", maxLoginLen)
+		cl.Email = strings.Repeat("e", maxEmailLen-len("@mail.com")) + "@mail.com"
+	})},
+	{name: "EarliestBirthday", client: newTestClient(func(cl *Client) { cl.Birthday = "19000101" })},
+	{name: "LeapDayBirthday", client: newTestClient(func(cl *Client) { cl.Birthday = "20000229" })},
+	{name: "TodayBirthday", client: newTestClient(func(cl *Client) { cl.Birthday = time.Now().UTC().Format(birthdayLayout) })},
+	{name: "MissingFIO", client: newTestClient(func(cl *Client) { cl.FIO = "" }), wantErr: ErrValidation},
+	{name: "MissingLogin", client: newTestClient(func(cl *Client) { cl.Login = " " }), wantErr: ErrValidation},
+	{name: "MissingEmail", client: newTestClient(func(cl *Client) { cl.Email = "" }), wantErr: ErrValidation},
+	{name: "MissingBirthday", client: newTestClient(func(cl *Client) { cl.Birthday = "" }), wantErr: ErrValidation},
+	{name: "TooLongFIO", client: newTestClient(func(cl *Client) { cl.FIO = strings.Repeat("Ж", maxFIOLen+1) }), wantErr: ErrValidation},
+	{name: "TooLongLogin", client: newTestClient(func(cl *Client) { cl.Login = strings.Repeat("l", maxLoginLen+1) }), wantErr: ErrValidation},
+	{name: "BirthdayBefore1900", client: newTestClient(func(cl *Client) { cl.Birthday = "18991231" }), wantErr: ErrValidation},
+	{name: "NonLeapDayBirthday", client: newTestClient(func(cl *Client) { cl.Birthday = "19990229" }), wantErr: ErrValidation},
+	{name: "FutureBirthday", client: newTestClient(func(cl *Client) { cl.Birthday = time.Now().UTC().AddDate(0, 0, 2).Format(birthdayLayout) }), wantErr: ErrValidation},
+	{name: "MalformedBirthday", client: newTestClient(func(cl *Client) { cl.Birthday = "1970-01-01" }), wantErr: ErrValidation},
 }
 
 // Тест проверяет корректность вставки нового клиента в базу данных
-func Test_InsertClient_ThenSelectAndCheck(t *testing.T) {
-	// Подключение к базе данных SQLite
-	db, err := sql.Open("sqlite", "demo.db")
-	require.NoError(t, err, "database connection error: %v", err)
-	// Закрытие соединения после завершения теста
-	defer db.Close()
-
-	// Создание тестового объекта клиента с тестовыми данными
-	cl := Client{
-		FIO:      "Test",
-		Login:    "Test",
-		Birthday: "19700101",
-		Email:    "mail@mail.com",
+func (s *clientsSuite) TestInsertClient_ThenSelectAndCheck() {
+	for _, tt := range insertClientCases {
+		s.Run(tt.name, func() {
+			t := s.T()
+			cl := tt.client
+
+			// Вставка нового клиента в базу данных
+			var err error
+			cl.ID, err = insertClient(s.db, cl)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr, "expected error inserting client: %v", cl)
+				assert.Empty(t, cl.ID, "ID should be empty when insertion fails: %v", cl)
+				return
+			}
+
+			// Проверка, что у клиента появилось ID и не было ошибок при вставке
+			require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+			assert.NotEmpty(t, cl.ID, "ID should not be empty after client insertion: %v", cl)
+
+			// Получение вставленного клиента из базы и сравнение с исходными данными
+			client, err := selectClient(s.db, cl.ID)
+			require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
+			assertClientEqual(t, cl, client)
+		})
 	}
-	// Вставка нового клиента в базу данных
-	cl.ID, err = insertClient(db, cl)
-	// Проверка, что у клиента появилось ID и не было ошибок при вставке
-	assert.NotEmpty(t, cl.ID, "ID should not be empty after client insertion: %v", cl)
-	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
-
-	// Получение вставленного клиента из базы
-	client, err := selectClient(db, cl.ID)
-	require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
-
-	// Проверка соответствия полученных данных исходным
-	assert.Equal(t, client.ID, cl.ID, "ID mismatch: expected %v, actual %v", cl.ID, client.ID)
-	assert.Equal(t, client.FIO, cl.FIO, "FIO mismatch: expected %v, actual %v", cl.FIO, client.FIO)
-	assert.Equal(t, client.Login, cl.Login, "Login mismatch: expected %v, actual %v", cl.Login, client.Login)
-	assert.Equal(t, client.Birthday, cl.Birthday, "birthday mismatch: expected %v, actual %v", cl.Birthday, client.Birthday)
-	assert.Equal(t, client.Email, cl.Email, "email mismatch: expected %v, actual %v", cl.Email, client.Email)
-
-	// Очистка тестовых данных
-	err = deleteClient(db, cl.ID)
-	require.NoError(t, err, "Error deleting client with ID %d: %v", cl.ID, err)
 }
 
 // Тест проверяет корректность удаления нового клиента из БД
-func Test_InsertClient_DeleteClient_ThenCheck(t *testing.T) {
-	// Подключение к базе данных SQLite
-	db, err := sql.Open("sqlite", "demo.db")
-	require.NoError(t, err, "database connection error: %v", err)
-	// Закрытие соединения после завершения теста
-	defer db.Close()
-
-	// Создание тестового объекта клиента с тестовыми данными
-	cl := Client{
-		FIO:      "Test",
-		Login:    "Test",
-		Birthday: "19700101",
-		Email:    "mail@mail.com",
+func (s *clientsSuite) TestInsertClient_DeleteClient_ThenCheck() {
+	for _, tt := range insertClientCases {
+		if tt.wantErr != nil {
+			continue
+		}
+
+		s.Run(tt.name, func() {
+			t := s.T()
+			cl := tt.client
+
+			// Вставка нового клиента в базу данных
+			var err error
+			cl.ID, err = insertClient(s.db, cl)
+			require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+			require.NotEmpty(t, cl.ID, "ID should not be empty after client insertion: %v", cl)
+
+			// Получение вставленного клиента из базы
+			client, err := selectClient(s.db, cl.ID)
+			require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
+			assertClientEqual(t, cl, client)
+
+			// Удаление клиента из базы данных
+			diff := testutil.CaptureDiff(t, s.db, "clients", "id", func() {
+				err = deleteClient(s.db, client.ID)
+			})
+			require.NoError(t, err, "error deleting client with ID %d: %v", cl.ID, err)
+
+			// Проверка того, что удалён ровно этот клиент и ничего больше
+			assertClientNotInDB(t, s.db, client.ID)
+			assert.Empty(t, diff.Added, "delete must not add rows")
+			assert.Empty(t, diff.Modified, "delete must not modify other clients")
+			require.Len(t, diff.Removed, 1, "exactly one client should be removed")
+			assert.Equal(t, int64(client.ID), diff.Removed[0]["id"])
+		})
 	}
+}
 
-	// Вставка нового клиента в базу данных
-	cl.ID, err = insertClient(db, cl)
-	// Проверка, что у клиента появилось ID и не было ошибок при вставке
-	require.NotEmpty(t, cl.ID, "ID should not be empty after client insertion: %v", cl)
-	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
-
-	// Получение вставленного клиента из базы
-	client, err := selectClient(db, cl.ID)
-	require.NoError(t, err, "error retrieving client with ID %d: %v", cl.ID, err)
-
-	// Проверка соответствия полученных данных исходным
-	assert.Equal(t, client.ID, cl.ID, "ID mismatch: expected %v, actual %v", cl.ID, client.ID)
-	assert.Equal(t, client.FIO, cl.FIO, "FIO mismatch: expected %v, actual %v", cl.FIO, client.FIO)
-	assert.Equal(t, client.Login, cl.Login, "login mismatch: expected %v, actual %v", cl.Login, client.Login)
-	assert.Equal(t, client.Birthday, cl.Birthday, "birthday mismatch: expected %v, actual %v", cl.Birthday, client.Birthday)
-	assert.Equal(t, client.Email, cl.Email, "email mismatch: expected %v, actual %v", cl.Email, client.Email)
-
-	// Удаление клиента из базы данных
-	err = deleteClient(db, client.ID)
-	require.NoError(t, err, "error deleting client with ID %d: %v", cl.ID, err)
-
-	// Проверка того, что клиент действительно удален
-	_, err = selectClient(db, client.ID)
-	require.Error(t, err, "expected error when trying to retrieve deleted client with ID %d", client.ID)
-	require.Equal(t, sql.ErrNoRows, err, "expected specific sql.ErrNoRows error when searching for deleted client with ID %d", client.ID)
+// Фаззинг вставки клиента: произвольные значения полей, включая попытки
+// SQL-инъекций, либо отклоняются проверкой, либо сохраняются без изменений
+func FuzzInsertClient(f *testing.F) {
+	db, _ := setupTestDB(f)
+
+	f.Add("Test", "Test", "19700101", "mail@mail.com")
+	f.Add("Robert'); DROP TABLE clients; --", "bobby", "19700101", "bobby@mail.com")
+	f.Add("Test", "' OR '1'='1", "19700101", "x@mail.com\"; --")
+	f.Add("Ковшутин Игнатий", ":id", "20000229", "$1")
+	f.Add("\x00", "\xff", "1970010", "")
+	for _, cl := range fakeClients(1, 5) {
+		f.Add(cl.FIO, cl.Login, cl.Birthday, cl.Email)
+	}
+
+	f.Fuzz(func(t *testing.T, fio, login, birthday, email string) {
+		cl := Client{FIO: fio, Login: login, Birthday: birthday, Email: email}
+
+		id, err := insertClient(db, cl)
+		if cl.Validate() != nil {
+			require.ErrorIs(t, err, ErrValidation, "invalid client should be rejected by validation: %q", cl)
+			return
+		}
+		require.NoError(t, err, "error inserting client: %q", cl)
+		cl.ID = id
+
+		client, err := selectClient(db, id)
+		require.NoError(t, err, "error retrieving client with ID %d: %v", id, err)
+		assertClientEqual(t, cl, client)
+
+		require.NoError(t, deleteClient(db, id))
+	})
 }
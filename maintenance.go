@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// MaintenanceTask — операция обслуживания БД.
+type MaintenanceTask string
+
+const (
+	// MaintenanceVacuum перестраивает файл БД, возвращая место удалённых строк.
+	MaintenanceVacuum MaintenanceTask = "vacuum"
+	// MaintenanceAnalyze собирает статистику для планировщика запросов.
+	MaintenanceAnalyze MaintenanceTask = "analyze"
+	// MaintenanceOptimize выполняет PRAGMA optimize: обновляет статистику
+	// там, где она устарела.
+	MaintenanceOptimize MaintenanceTask = "optimize"
+)
+
+// maintenanceSQL — запрос каждой операции обслуживания. Порядок задаёт
+// порядок по умолчанию: статистика собирается по уже перестроенному файлу.
+var maintenanceSQL = []struct {
+	task  MaintenanceTask
+	query string
+}{
+	{MaintenanceVacuum, "VACUUM"},
+	{MaintenanceAnalyze, "ANALYZE"},
+	{MaintenanceOptimize, "PRAGMA optimize"},
+}
+
+// MaintenanceStep — результат операции обслуживания.
+type MaintenanceStep struct {
+	Task     MaintenanceTask `json:"task"`
+	Duration time.Duration   `json:"duration"`
+	// SizeBefore и SizeAfter — размер БД в байтах до и после операции.
+	SizeBefore int64 `json:"size_before"`
+	SizeAfter  int64 `json:"size_after"`
+}
+
+// Maintain выполняет операции обслуживания tasks (по умолчанию все) по
+// очереди на отдельном соединении и пишет ход выполнения в logger (nil —
+// не писать). Операции не блокируют чтение: в режиме WAL читатели
+// продолжают работать во время VACUUM, а занятость БД обрабатывается
+// политикой WithBusyRetry. Запись на время VACUUM приостанавливается.
+// Репозиторий, созданный через NewTxRepository, операции не выполняет:
+// VACUUM невозможен внутри транзакции.
+func (r *Repository) Maintain(ctx context.Context, logger *slog.Logger, tasks ...MaintenanceTask) (_ []MaintenanceStep, err error) {
+	ctx, end := r.startOperation(ctx, "maintain")
+	defer end(&err)
+
+	if r.tx != nil {
+		return nil, errors.New("maintenance cannot run inside a transaction")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	queries := make([]string, 0, len(maintenanceSQL))
+	plan := make([]MaintenanceTask, 0, len(maintenanceSQL))
+	for _, m := range maintenanceSQL {
+		if len(tasks) == 0 || slices.Contains(tasks, m.task) {
+			plan = append(plan, m.task)
+			queries = append(queries, m.query)
+		}
+	}
+	for _, task := range tasks {
+		if !slices.Contains(plan, task) {
+			return nil, fmt.Errorf("%w: unknown maintenance task %q", ErrValidation, task)
+		}
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	q := r.observe(conn)
+
+	steps := make([]MaintenanceStep, 0, len(plan))
+	for i, task := range plan {
+		step := MaintenanceStep{Task: task}
+		if step.SizeBefore, err = databaseSize(ctx, q); err != nil {
+			return steps, err
+		}
+		logger.InfoContext(ctx, "maintenance task started", slog.String("task", string(task)),
+			slog.Int("step", i+1), slog.Int("steps", len(plan)))
+
+		start := time.Now()
+		err := r.retry(ctx, func() error {
+			_, err := q.ExecContext(ctx, queries[i])
+			return err
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, "maintenance task failed", slog.String("task", string(task)), slog.Any("error", err))
+			return steps, fmt.Errorf("maintenance task %s: %w", task, err)
+		}
+		step.Duration = time.Since(start)
+
+		if step.SizeAfter, err = databaseSize(ctx, q); err != nil {
+			return steps, err
+		}
+		logger.InfoContext(ctx, "maintenance task finished", slog.String("task", string(task)),
+			slog.Duration("duration", step.Duration), slog.Int64("size_before", step.SizeBefore), slog.Int64("size_after", step.SizeAfter))
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,164 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openMaintenanceDB создаёт файловую БД в режиме WAL с n сгенерированными
+// клиентами
+func openMaintenanceDB(t *testing.T, n int) (*sql.DB, *Repository) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "maintain.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	_, err = db.ExecContext(ctx, "PRAGMA journal_mode = WAL")
+	require.NoError(t, err)
+	require.NoError(t, Migrate(ctx, db))
+
+	repo := NewRepository(db, WithBusyRetry(10, 5*time.Millisecond))
+	require.NoError(t, seedGenerated(ctx, repo, gen.New(1).Clients(n)))
+
+	return db, repo
+}
+
+// assertConsistent проверяет целостность файла БД и ссылок внешних ключей
+func assertConsistent(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	ctx := context.Background()
+	checks, err := queryStrings(ctx, db, "PRAGMA integrity_check")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ok"}, checks)
+
+	violations, err := queryStrings(ctx, db, "SELECT \"table\" FROM pragma_foreign_key_check")
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+// Тест проверяет, что обслуживание возвращает место удалённых строк,
+// собирает статистику, пишет ход выполнения и не меняет данные
+func Test_Maintain_ShrinksAndStaysConsistent(t *testing.T) {
+	db, repo := openMaintenanceDB(t, 2000)
+
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, "DELETE FROM clients WHERE id > 100")
+	require.NoError(t, err)
+
+	var before bytes.Buffer
+	require.NoError(t, repo.Export(ctx, &before, ExportOptions{Format: FormatCSV}))
+
+	var log bytes.Buffer
+	steps, err := repo.Maintain(ctx, slog.New(slog.NewTextHandler(&log, nil)))
+	require.NoError(t, err)
+
+	require.Len(t, steps, 3)
+	assert.Equal(t, []MaintenanceTask{MaintenanceVacuum, MaintenanceAnalyze, MaintenanceOptimize},
+		[]MaintenanceTask{steps[0].Task, steps[1].Task, steps[2].Task})
+	assert.Less(t, steps[0].SizeAfter, steps[0].SizeBefore, "vacuum should reclaim space of deleted rows")
+	assert.Equal(t, steps[0].SizeAfter, steps[1].SizeBefore)
+	for _, task := range steps {
+		assert.Contains(t, log.String(), fmt.Sprintf("msg=\"maintenance task finished\" task=%s", task.Task))
+	}
+
+	assertConsistent(t, db)
+	assertRowCount(t, db, "sqlite_stat1", 1, "tbl = ? AND idx = ?", "audit_log", "audit_log_client_id")
+
+	var after bytes.Buffer
+	require.NoError(t, repo.Export(ctx, &after, ExportOptions{Format: FormatCSV}))
+	assert.Equal(t, before.String(), after.String(), "maintenance must not change data")
+
+	// Выбранные операции выполняются в порядке по умолчанию
+	steps, err = repo.Maintain(ctx, nil, MaintenanceOptimize, MaintenanceAnalyze)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, MaintenanceAnalyze, steps[0].Task)
+	assert.Equal(t, MaintenanceOptimize, steps[1].Task)
+}
+
+// Тест проверяет, что чтение продолжается без ошибок во время обслуживания
+func Test_Maintain_ConcurrentReads(t *testing.T) {
+	db, repo := openMaintenanceDB(t, 1000)
+
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, "DELETE FROM clients WHERE id % 2 = 0")
+	require.NoError(t, err)
+
+	// Читатели обходят оставшиеся клиенты с нечётными ID; обслуживание
+	// начинается, когда каждый из них выполнил хотя бы одно чтение
+	done := make(chan struct{})
+	var (
+		wg, started sync.WaitGroup
+		mu          sync.Mutex
+		reads       int
+		errs        []error
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			first := true
+			for id := 1; ; id = (id + 2) % 1000 {
+				_, err := repo.Select(ctx, id)
+				mu.Lock()
+				reads++
+				if err != nil {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+				if first {
+					first = false
+					started.Done()
+				}
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+	started.Wait()
+
+	_, err = repo.Maintain(ctx, nil)
+	close(done)
+	wg.Wait()
+	require.NoError(t, err)
+
+	assert.Positive(t, reads)
+	assert.Empty(t, errs, "reads should not fail during maintenance")
+	assertConsistent(t, db)
+}
+
+// Тест проверяет ошибки: неизвестная операция и репозиторий в транзакции
+func Test_Maintain_Errors(t *testing.T) {
+	db, repo := openMaintenanceDB(t, 1)
+
+	ctx := context.Background()
+	_, err := repo.Maintain(ctx, nil, MaintenanceVacuum, "reindex")
+	require.ErrorIs(t, err, ErrValidation)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	_, err = NewTxRepository(tx).Maintain(ctx, nil)
+	require.Error(t, err)
+}
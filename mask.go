@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// Политика маскирования персональных данных для выгрузок в
+// непродуктивные окружения и для логов:
+//   - email: первая буква локальной части и домен — i***@mail.com;
+//   - ФИО: фамилия и инициалы — Иванов И. И.;
+//   - логин: первая буква — i***;
+//   - дата рождения: только год — 1970****.
+// ID клиента не маскируется.
+
+const maskFill = "***"
+
+// maskClient возвращает копию клиента с замаскированными полями.
+func maskClient(cl Client) Client {
+	cl.FIO = maskFIO(cl.FIO)
+	cl.Login = maskLogin(cl.Login)
+	cl.Birthday = maskBirthday(cl.Birthday)
+	cl.Email = maskEmail(cl.Email)
+
+	return cl
+}
+
+// String описывает клиента для отладки с замаскированными персональными
+// данными, поэтому клиента можно выводить через fmt без утечки email и
+// даты рождения.
+func (cl Client) String() string {
+	m := maskClient(cl)
+
+	return fmt.Sprintf("Client{ID: %d, FIO: %q, Login: %q, Birthday: %q, Email: %q, Status: %s}",
+		m.ID, m.FIO, m.Login, m.Birthday, m.Email, m.Status)
+}
+
+// GoString совпадает со String: формат %#v иначе вывел бы поля как есть.
+func (cl Client) GoString() string {
+	return cl.String()
+}
+
+// LogValue выводит клиента в slog группой полей с замаскированными
+// персональными данными.
+func (cl Client) LogValue() slog.Value {
+	m := maskClient(cl)
+	attrs := []slog.Attr{
+		slog.Int("id", m.ID),
+		slog.String("fio", m.FIO),
+		slog.String("login", m.Login),
+		slog.String("birthday", m.Birthday),
+		slog.String("email", m.Email),
+		slog.String("status", string(m.Status)),
+	}
+	if m.OwnerID != "" {
+		attrs = append(attrs, slog.String("owner_id", m.OwnerID))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return maskLogin(email)
+	}
+
+	return firstRune(local) + maskFill + "@" + domain
+}
+
+func maskFIO(fio string) string {
+	parts := strings.Fields(fio)
+	if len(parts) == 0 {
+		return ""
+	}
+
+	masked := []string{parts[0]}
+	for _, p := range parts[1:] {
+		masked = append(masked, firstRune(p)+".")
+	}
+
+	return strings.Join(masked, " ")
+}
+
+func maskLogin(login string) string {
+	if login == "" {
+		return ""
+	}
+
+	return firstRune(login) + maskFill
+}
+
+func maskBirthday(birthday string) string {
+	if len(birthday) < 4 {
+		return strings.Repeat("*", len(birthday))
+	}
+
+	return birthday[:4] + strings.Repeat("*", len(birthday)-4)
+}
+
+func firstRune(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return ""
+	}
+
+	return s[:size]
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тест проверяет правила маскирования отдельных полей
+func Test_Mask_Fields(t *testing.T) {
+	assert.Equal(t, "i***@mail.com", maskEmail("ivan@mail.com"))
+	assert.Equal(t, "и***@почта.рф", maskEmail("иван@почта.рф"))
+	assert.Equal(t, "n***", maskEmail("not-an-email"))
+	assert.Equal(t, "Иванов И. И.", maskFIO("Иванов Иван Иванович"))
+	assert.Equal(t, "Иванов", maskFIO("Иванов"))
+	assert.Equal(t, "", maskFIO("  "))
+	assert.Equal(t, "d***", maskLogin("danila95"))
+	assert.Equal(t, "", maskLogin(""))
+	assert.Equal(t, "1970****", maskBirthday("19700101"))
+	assert.Equal(t, "**", maskBirthday("19"))
+}
+
+// Тест проверяет, что маскирование клиента не затрагивает ID
+func Test_Mask_Client(t *testing.T) {
+	cl := Client{
+		ID:       7,
+		FIO:      "Башкатов Данила Валентинович",
+		Login:    "danila95",
+		Birthday: "19950505",
+		Email:    "danila95@gmail.com",
+	}
+
+	masked := maskClient(cl)
+	assert.Equal(t, Client{
+		ID:       7,
+		FIO:      "Башкатов Д. В.",
+		Login:    "d***",
+		Birthday: "1995****",
+		Email:    "d***@gmail.com",
+	}, masked)
+}
+
+// Тест проверяет, что клиент, выведенный через fmt или slog, не раскрывает
+// email и дату рождения, но показывает ID и сокращённое ФИО
+func Test_Mask_ClientFormatting(t *testing.T) {
+	cl := Client{
+		ID:       7,
+		FIO:      "Башкатов Данила Валентинович",
+		Login:    "danila95",
+		Birthday: "19950505",
+		Email:    "danila95@gmail.com",
+		Status:   StatusActive,
+	}
+	assert.Equal(t, `Client{ID: 7, FIO: "Башкатов Д. В.", Login: "d***", Birthday: "1995****", Email: "d***@gmail.com", Status: active}`,
+		cl.String())
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Info("client", "client", cl)
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("client", "client", cl, "ptr", &cl)
+	assert.Contains(t, buf.String(), `"client":{"id":7,"fio":"Башкатов Д. В.",`)
+	assert.Contains(t, buf.String(), "client.email=d***@gmail.com")
+
+	for _, out := range []string{
+		fmt.Sprint(cl),
+		fmt.Sprintf("%v %+v %#v %s", cl, cl, cl, &cl),
+		fmt.Sprintf("%v", []Client{cl}),
+		fmt.Sprintf("%+v", ClientWithOrders{Client: cl}),
+		buf.String(),
+	} {
+		assert.NotContains(t, out, cl.Email)
+		assert.NotContains(t, out, cl.Birthday)
+		assert.NotContains(t, out, "Данила")
+		assert.Contains(t, out, "Башкатов Д. В.")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mergeSteps — запросы, переносящие связанные строки дубликата :dup на
+// оставшегося клиента :keep. Метка, которая уже есть у оставшегося
+// клиента, повторно не добавляется. Перенесённые строки считаются по
+// table; у копирования меток table пустая, так как перенос меток
+// считается по их удалению у дубликата.
+var mergeSteps = []struct {
+	table string
+	query string
+}{
+	{"orders", "UPDATE orders SET client_id = :keep WHERE client_id = :dup"},
+	{"client_notes", "UPDATE client_notes SET client_id = :keep WHERE client_id = :dup"},
+	{"client_documents", "UPDATE client_documents SET client_id = :keep WHERE client_id = :dup"},
+	{"directory_links", "UPDATE directory_links SET client_id = :keep WHERE client_id = :dup"},
+	{"", "INSERT OR IGNORE INTO client_tags (client_id, tag_id) SELECT :keep, tag_id FROM client_tags WHERE client_id = :dup"},
+	{"client_tags", "DELETE FROM client_tags WHERE client_id = :dup"},
+}
+
+// MergeClients объединяет дубликаты duplicateIDs с клиентом keepID в одной
+// транзакции: заказы, заметки, документы и метки дубликатов переносятся на keepID,
+// незаполненные поля keepID заполняются значениями дубликатов, а сами
+// дубликаты мягко удаляются и больше не читаются репозиторием. При
+// конфликте (поле заполнено и у keepID, и у дубликата, но по-разному)
+// остаётся значение keepID. Объединение записывается в журнал аудита
+// и оставшегося клиента, и дубликатов.
+func (r *Repository) MergeClients(ctx context.Context, keepID int, duplicateIDs ...int) error {
+	_, err := r.MergeClientsWith(ctx, keepID, duplicateIDs, DestructiveOptions{})
+
+	return err
+}
+
+// MergeClientsWith объединяет дубликаты так же, как MergeClients, и
+// возвращает мягко удалённых клиентов и число перенесённых и изменённых
+// строк. С opts.DryRun БД не меняется, а результат описывает строки,
+// которые были бы затронуты.
+func (r *Repository) MergeClientsWith(ctx context.Context, keepID int, duplicateIDs []int, opts DestructiveOptions) (_ Affected, err error) {
+	ctx, end := r.startClientOperation(ctx, "merge_clients", keepID)
+	defer end(&err)
+
+	if len(duplicateIDs) == 0 {
+		return Affected{}, fmt.Errorf("%w: no duplicates to merge", ErrValidation)
+	}
+	for i, id := range duplicateIDs {
+		if id == keepID || slices.Contains(duplicateIDs[:i], id) {
+			return Affected{}, fmt.Errorf("%w: client %d is listed more than once", ErrValidation, id)
+		}
+	}
+
+	var affected Affected
+	err = r.inTxDryRun(ctx, opts.DryRun, func(q querier) error {
+		affected = Affected{Rows: make(map[string]int64)}
+
+		before, err := r.selectClient(ctx, q, keepID)
+		if err != nil {
+			return err
+		}
+
+		merged := before.Clone()
+		for _, id := range duplicateIDs {
+			dup, err := r.selectClient(ctx, q, id)
+			if err != nil {
+				return err
+			}
+			mergeFields(&merged, dup)
+
+			for _, step := range mergeSteps {
+				res, err := q.ExecContext(ctx, step.query, sql.Named("keep", keepID), sql.Named("dup", id))
+				if err != nil {
+					return err
+				}
+				if step.table == "" {
+					continue
+				}
+				if err := countRows(affected.Rows, step.table, res); err != nil {
+					return err
+				}
+			}
+
+			deletedAt, err := r.recordHistory(ctx, q, id, AuditMerge)
+			if err != nil {
+				return err
+			}
+			_, err = q.ExecContext(ctx, "UPDATE clients SET deleted_at = :deleted_at, merged_into = :keep, valid_from = :deleted_at, version = version + 1 WHERE id = :id",
+				sql.Named("deleted_at", deletedAt),
+				sql.Named("keep", keepID),
+				sql.Named("id", id))
+			if err != nil {
+				return err
+			}
+			affected.ClientIDs = append(affected.ClientIDs, id)
+			affected.Rows["clients"]++
+
+			into := strconv.Itoa(keepID)
+			if err := r.auditDiff(ctx, q, AuditMerge, id, map[string]FieldChange{"merged_into": {After: &into}}); err != nil {
+				return err
+			}
+		}
+
+		diff := diffClients(&before, &merged)
+		if !merged.Equal(before, EqualOptions{}) {
+			if err := r.updateMergedFields(ctx, q, merged); err != nil {
+				return err
+			}
+			affected.Rows["clients"]++
+		}
+
+		ids := make([]string, len(duplicateIDs))
+		for i, id := range duplicateIDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		from := strings.Join(ids, ",")
+		diff["merged_from"] = FieldChange{After: &from}
+
+		return r.auditDiff(ctx, q, AuditMerge, keepID, diff)
+	})
+	if err != nil {
+		return Affected{}, err
+	}
+
+	return affected, nil
+}
+
+// mergeFields заполняет пустые поля клиента dst значениями src.
+func mergeFields(dst *Client, src Client) {
+	for _, f := range []struct{ dst, src *string }{
+		{&dst.FIO, &src.FIO},
+		{&dst.Login, &src.Login},
+		{&dst.Birthday, &src.Birthday},
+		{&dst.Email, &src.Email},
+	} {
+		if *f.dst == "" {
+			*f.dst = *f.src
+		}
+	}
+}
+
+// updateMergedFields сохраняет поля оставшегося клиента, дополненные
+// при объединении; прежняя версия сохраняется в clients_history.
+func (r *Repository) updateMergedFields(ctx context.Context, q querier, cl Client) error {
+	stored, err := r.encrypt(cl)
+	if err != nil {
+		return err
+	}
+
+	changedAt, err := r.recordHistory(ctx, q, cl.ID, AuditMerge)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, "UPDATE clients SET fio = :fio, login = :login, birthday = :birthday, email = :email, valid_from = :valid_from, version = version + 1 WHERE id = :id",
+		sql.Named("fio", stored.FIO),
+		sql.Named("login", stored.Login),
+		sql.Named("birthday", stored.Birthday),
+		sql.Named("email", stored.Email),
+		sql.Named("valid_from", changedAt),
+		sql.Named("id", cl.ID))
+
+	return err
+}
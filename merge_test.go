@@ -0,0 +1,174 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет перенос заказов, заметок и меток дубликатов на оставшегося
+// клиента, мягкое удаление дубликатов и записи журнала аудита
+func Test_MergeClients_MovesRelatedRows(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+	orders := repo.Orders()
+
+	ctx := context.Background()
+
+	keep := newTestClient()
+	var err error
+	keep.ID, err = repo.Insert(ctx, keep)
+	require.NoError(t, err)
+	require.NoError(t, repo.TagClient(ctx, keep.ID, "vip"))
+
+	dups := make([]int, 2)
+	for i := range dups {
+		dups[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		_, err = orders.Create(ctx, Order{ClientID: dups[i], Amount: 100})
+		require.NoError(t, err)
+		_, err = repo.AddNote(ctx, dups[i], "duplicate")
+		require.NoError(t, err)
+		// Метка vip есть и у оставшегося клиента
+		require.NoError(t, repo.TagClient(ctx, dups[i], "vip"))
+	}
+	require.NoError(t, repo.TagClient(ctx, dups[1], "wholesale"))
+
+	require.NoError(t, repo.MergeClients(ctx, keep.ID, dups...))
+
+	moved, err := orders.ByClient(ctx, keep.ID)
+	require.NoError(t, err)
+	assert.Len(t, moved, 2)
+	notes, err := repo.ListNotes(ctx, keep.ID)
+	require.NoError(t, err)
+	assert.Len(t, notes, 2)
+	tags, err := repo.ClientTags(ctx, keep.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"vip", "wholesale"}, tags)
+
+	for _, id := range dups {
+		_, err := repo.Select(ctx, id)
+		require.ErrorIs(t, err, ErrClientNotFound, "merged duplicate should not be readable")
+		// Строка дубликата остаётся в БД помеченной как удалённая
+		assertRowCount(t, tx, "clients", 1, "id = ? AND deleted_at != '' AND merged_into = ?", id, keep.ID)
+		assertRowCount(t, tx, "orders", 0, "client_id = ?", id)
+		assertRowCount(t, tx, "client_notes", 0, "client_id = ?", id)
+		assertRowCount(t, tx, "client_tags", 0, "client_id = ?", id)
+
+		entries, err := repo.AuditLog(ctx, id)
+		require.NoError(t, err)
+		last := entries[len(entries)-1]
+		assert.Equal(t, AuditMerge, last.Operation)
+		assert.Equal(t, map[string]FieldChange{"merged_into": {After: strPtr(strconv.Itoa(keep.ID))}}, last.Diff)
+	}
+
+	entries, err := repo.AuditLog(ctx, keep.ID)
+	require.NoError(t, err)
+	last := entries[len(entries)-1]
+	assert.Equal(t, AuditMerge, last.Operation)
+	assert.Equal(t, map[string]FieldChange{"merged_from": {After: strPtr(strconv.Itoa(dups[0]) + "," + strconv.Itoa(dups[1]))}}, last.Diff)
+
+	var listed []int
+	require.NoError(t, repo.ForEach(ctx, func(cl Client) error {
+		listed = append(listed, cl.ID)
+		return nil
+	}))
+	assert.Contains(t, listed, keep.ID)
+	assert.NotContains(t, listed, dups[0], "merged duplicate should not be listed")
+
+	// Право на забвение распространяется и на удалённый дубликат
+	_, err = repo.EraseClient(ctx, dups[0])
+	require.NoError(t, err)
+	assertClientNotInDB(t, tx, dups[0])
+}
+
+// Тест проверяет объединение полей: пустые поля заполняются из дубликата,
+// при конфликте остаётся значение оставшегося клиента
+func Test_MergeClients_Fields(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	keep := newTestClient(func(cl *Client) { cl.FIO = "Keep" })
+	var err error
+	keep.ID, err = repo.Insert(ctx, keep)
+	require.NoError(t, err)
+	// Незаполненный email у клиента, созданного до проверки данных
+	_, err = tx.Exec("UPDATE clients SET email = '' WHERE id = ?", keep.ID)
+	require.NoError(t, err)
+
+	dup := newTestClient(func(cl *Client) {
+		cl.FIO = "Duplicate"
+		cl.Email = "duplicate@mail.com"
+	})
+	dup.ID, err = repo.Insert(ctx, dup)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.MergeClients(ctx, keep.ID, dup.ID))
+
+	want := keep
+	want.Email = dup.Email
+	got, err := repo.Select(ctx, keep.ID)
+	require.NoError(t, err)
+	assertClientEqual(t, want, got)
+
+	entries, err := repo.AuditLog(ctx, keep.ID)
+	require.NoError(t, err)
+	last := entries[len(entries)-1]
+	assert.Equal(t, map[string]FieldChange{
+		"email":       {Before: strPtr(""), After: strPtr(dup.Email)},
+		"merged_from": {After: strPtr(strconv.Itoa(dup.ID))},
+	}, last.Diff)
+}
+
+// Тест проверяет, что некорректное объединение отклоняется и не меняет
+// ни одного клиента
+func Test_MergeClients_Conflicts(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+	orders := repo.Orders()
+
+	ctx := context.Background()
+
+	ids := make([]int, 3)
+	var err error
+	for i := range ids {
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	keep, dup, merged := ids[0], ids[1], ids[2]
+	_, err = orders.Create(ctx, Order{ClientID: dup, Amount: 100})
+	require.NoError(t, err)
+	require.NoError(t, repo.MergeClients(ctx, keep, merged))
+
+	tests := []struct {
+		name    string
+		keep    int
+		dups    []int
+		wantErr error
+	}{
+		{"NoDuplicates", keep, nil, ErrValidation},
+		{"KeepIsDuplicate", keep, []int{dup, keep}, ErrValidation},
+		{"RepeatedDuplicate", keep, []int{dup, dup}, ErrValidation},
+		{"MissingDuplicate", keep, []int{dup, -1}, ErrClientNotFound},
+		{"AlreadyMergedDuplicate", keep, []int{dup, merged}, ErrClientNotFound},
+		{"MergedKeep", merged, []int{dup}, ErrClientNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, repo.MergeClients(ctx, tt.keep, tt.dups...), tt.wantErr)
+
+			// Заказ дубликата остался на месте, дубликат не удалён
+			assertRowCount(t, tx, "orders", 1, "client_id = ?", dup)
+			_, err := repo.Select(ctx, dup)
+			require.NoError(t, err)
+		})
+	}
+}
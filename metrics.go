@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Результаты запросов в метке outcome.
+const (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+// dbMetrics — метрики запросов репозитория к БД.
+type dbMetrics struct {
+	queries  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	rows     *prometheus.CounterVec
+	slow     *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+}
+
+// WithMetrics регистрирует метрики запросов в reg и включает их сбор.
+// Регистрация повторяющихся метрик приводит к панике, поэтому для каждого
+// реестра опция применяется один раз.
+func WithMetrics(reg prometheus.Registerer) Option {
+	m := &dbMetrics{
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "queries_total",
+			Help:      "Number of executed queries by repository operation and outcome.",
+		}, []string{"operation", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Query execution time by repository operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"operation"}),
+		rows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "rows_affected_total",
+			Help:      "Number of rows changed by repository operation.",
+		}, []string{"operation"}),
+		slow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "slow_queries_total",
+			Help:      "Number of queries slower than the WithSlowQueryLog threshold.",
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clients",
+			Subsystem: "repository",
+			Name:      "errors_total",
+			Help:      "Number of failed repository operations by error class (see Classify).",
+		}, []string{"operation", "class"}),
+	}
+	reg.MustRegister(m.queries, m.duration, m.rows, m.slow, m.errors)
+
+	return func(r *Repository) {
+		r.metrics = m
+		r.hooks = append(r.hooks, m)
+	}
+}
+
+// operationDone учитывает неуспешную операцию репозитория по категории ошибки.
+func (m *dbMetrics) operationDone(op string, err error) {
+	if err == nil {
+		return
+	}
+
+	m.errors.WithLabelValues(op, string(Classify(err))).Inc()
+}
+
+func (m *dbMetrics) afterQuery(_ context.Context, ev queryEvent) {
+	outcome := outcomeSuccess
+	if ev.Err != nil {
+		outcome = outcomeError
+	}
+
+	m.queries.WithLabelValues(ev.Op, outcome).Inc()
+	m.duration.WithLabelValues(ev.Op).Observe(ev.Duration.Seconds())
+	if ev.Rows > 0 {
+		m.rows.WithLabelValues(ev.Op).Add(float64(ev.Rows))
+	}
+	if ev.Slow {
+		m.slow.WithLabelValues(ev.Op).Inc()
+	}
+}
+
+// MetricsHandler возвращает HTTP-обработчик /metrics для метрик из reg.
+func MetricsHandler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
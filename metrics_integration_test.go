@@ -0,0 +1,55 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет значения метрик после нескольких операций репозитория
+func Test_Metrics_AfterOperations(t *testing.T) {
+	ctx := context.Background()
+
+	reg := prometheus.NewRegistry()
+	_, repo := setupTestDB(t, WithMetrics(reg))
+
+	cl := Client{FIO: "Test", Login: "Test", Birthday: "19700101", Email: "mail@mail.com"}
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	_, err = repo.Select(ctx, cl.ID)
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, cl.ID))
+
+	// Выборка отсутствующего клиента — успешный запрос без строк
+	_, err = repo.Select(ctx, cl.ID)
+	require.ErrorIs(t, err, ErrClientNotFound)
+
+	queries := func(op, outcome string) float64 {
+		return metricValue(t, reg, "clients_db_queries_total", map[string]string{"operation": op, "outcome": outcome})
+	}
+	rows := func(op string) float64 {
+		return metricValue(t, reg, "clients_db_rows_affected_total", map[string]string{"operation": op})
+	}
+
+	// Вставка: INSERT клиента, INSERT в журнал аудита и в outbox
+	assert.Equal(t, 3.0, queries("insert", outcomeSuccess))
+	assert.Equal(t, 2.0, queries("select", outcomeSuccess))
+	// Удаление: SELECT, проверка заказов, удаление заметок, меток, связей с
+	// каталогом и документов, сохранение прежней версии, DELETE и записи в
+	// журнал аудита и outbox
+	assert.Equal(t, 10.0, queries("delete", outcomeSuccess))
+	assert.Zero(t, queries("select", outcomeError))
+	assert.Equal(t, 3.0, rows("insert"))
+	// Удалённая строка клиента, его прежняя версия и записи в журнале
+	// аудита и outbox
+	assert.Equal(t, 4.0, rows("delete"))
+	assert.Equal(t, 2.0, metricValue(t, reg, "clients_db_query_duration_seconds", map[string]string{"operation": "select"}))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// metricValue возвращает значение счётчика (или число наблюдений
+// гистограммы) с указанными метками из реестра
+func metricValue(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	require.NoError(t, err, "error gathering metrics: %v", err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !labelsMatch(metric, labels) {
+				continue
+			}
+			if h := metric.GetHistogram(); h != nil {
+				return float64(h.GetSampleCount())
+			}
+			if g := metric.GetGauge(); g != nil {
+				return g.GetValue()
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+
+	return 0
+}
+
+func labelsMatch(metric *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, pair := range metric.GetLabel() {
+		if value, ok := labels[pair.GetName()]; ok {
+			if value != pair.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+
+	return matched == len(labels)
+}
+
+// Тест проверяет учёт ошибок и отдачу метрик через HTTP-обработчик
+func Test_Metrics_ErrorsAndHandler(t *testing.T) {
+	db := openMemoryDB(t)
+
+	reg := prometheus.NewRegistry()
+	repo := NewRepository(db, WithMetrics(reg))
+
+	_, err := repo.Select(context.Background(), 1)
+	require.Error(t, err, "select from missing table should fail")
+
+	assert.Equal(t, 1.0, metricValue(t, reg, "clients_db_queries_total", map[string]string{"operation": "select", "outcome": outcomeError}))
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `clients_db_queries_total{operation="select",outcome="error"} 1`)
+}
@@ -0,0 +1,733 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// migration — шаг изменения схемы БД. Шаги применяются строго по
+// возрастанию version, применённые версии хранятся в schema_migrations.
+// down отменяет up; данные удаляемых таблиц и столбцов теряются.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "base schema",
+		up: `
+CREATE TABLE IF NOT EXISTS clients (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	fio VARCHAR(128) NOT NULL DEFAULT "",
+	login VARCHAR(32) NOT NULL DEFAULT "",
+	birthday CHAR(8) NOT NULL DEFAULT "",
+	email VARCHAR(64) NOT NULL DEFAULT ""
+);
+CREATE TABLE IF NOT EXISTS products (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	product VARCHAR(64) NOT NULL DEFAULT "",
+	price INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS sales (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client INTEGER NOT NULL DEFAULT 0,
+	product INTEGER NOT NULL DEFAULT 0,
+	volume INTEGER NOT NULL DEFAULT 1,
+	date CHAR(8) NOT NULL DEFAULT ""
+);`,
+		down: `
+DROP TABLE sales;
+DROP TABLE products;
+DROP TABLE clients;`,
+	},
+	{
+		version: 2,
+		name:    "erasure receipts",
+		up: `
+CREATE TABLE erasure_receipts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL,
+	erased_at TEXT NOT NULL,
+	deleted TEXT NOT NULL DEFAULT "{}"
+);`,
+		down: `DROP TABLE erasure_receipts;`,
+	},
+	{
+		version: 3,
+		name:    "audit log",
+		up: `
+CREATE TABLE audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor TEXT NOT NULL DEFAULT "",
+	occurred_at TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	client_id INTEGER NOT NULL,
+	diff TEXT NOT NULL DEFAULT "{}"
+);
+CREATE INDEX audit_log_client_id ON audit_log (client_id);`,
+		down: `DROP TABLE audit_log;`,
+	},
+	{
+		version: 4,
+		name:    "client owner",
+		up: `
+ALTER TABLE clients ADD COLUMN owner_id TEXT NOT NULL DEFAULT "";
+CREATE INDEX clients_owner_id ON clients (owner_id);`,
+		down: `
+DROP INDEX clients_owner_id;
+ALTER TABLE clients DROP COLUMN owner_id;`,
+	},
+	{
+		version: 5,
+		name:    "marketing consent",
+		up: `
+ALTER TABLE clients ADD COLUMN marketing_consent INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE clients ADD COLUMN consent_updated_at TEXT NOT NULL DEFAULT "";`,
+		down: `
+ALTER TABLE clients DROP COLUMN consent_updated_at;
+ALTER TABLE clients DROP COLUMN marketing_consent;`,
+	},
+	{
+		version: 6,
+		name:    "audit request id",
+		up:      `ALTER TABLE audit_log ADD COLUMN request_id TEXT NOT NULL DEFAULT "";`,
+		down:    `ALTER TABLE audit_log DROP COLUMN request_id;`,
+	},
+	{
+		version: 7,
+		name:    "orders",
+		// Внешний ключ запрещает удалять клиента с заказами, если включён
+		// PRAGMA foreign_keys; Repository.Delete проверяет это сам, так как
+		// по умолчанию SQLite внешние ключи не проверяет.
+		up: `
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL REFERENCES clients (id) ON DELETE RESTRICT,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	status TEXT NOT NULL DEFAULT 'new',
+	created_at TEXT NOT NULL
+);
+CREATE INDEX orders_client_id ON orders (client_id);`,
+		down: `DROP TABLE orders;`,
+	},
+	{
+		version: 8,
+		name:    "client notes",
+		// Заметки удаляются вместе с клиентом; без PRAGMA foreign_keys это
+		// делает Repository.Delete.
+		up: `
+CREATE TABLE client_notes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	author TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+CREATE INDEX client_notes_client_id ON client_notes (client_id, created_at);`,
+		down: `DROP TABLE client_notes;`,
+	},
+	{
+		version: 9,
+		name:    "client tags",
+		up: `
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE client_tags (
+	client_id INTEGER NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (client_id, tag_id)
+);
+CREATE INDEX client_tags_tag_id ON client_tags (tag_id);`,
+		down: `
+DROP TABLE client_tags;
+DROP TABLE tags;`,
+	},
+	{
+		version: 10,
+		name:    "client status",
+		up:      `ALTER TABLE clients ADD COLUMN status TEXT NOT NULL DEFAULT 'active';`,
+		down:    `ALTER TABLE clients DROP COLUMN status;`,
+	},
+	{
+		version: 11,
+		name:    "client history",
+		// valid_from — начало действия текущей версии клиента; у клиентов,
+		// созданных до миграции, оно пустое и означает «всегда».
+		up: `
+ALTER TABLE clients ADD COLUMN valid_from TEXT NOT NULL DEFAULT "";
+CREATE TABLE clients_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL,
+	fio VARCHAR(128) NOT NULL,
+	login VARCHAR(32) NOT NULL,
+	birthday CHAR(8) NOT NULL,
+	email VARCHAR(64) NOT NULL,
+	owner_id TEXT NOT NULL,
+	marketing_consent INTEGER NOT NULL,
+	consent_updated_at TEXT NOT NULL,
+	status TEXT NOT NULL,
+	valid_from TEXT NOT NULL,
+	valid_to TEXT NOT NULL,
+	operation TEXT NOT NULL
+);
+CREATE INDEX clients_history_client_id ON clients_history (client_id, valid_to);`,
+		down: `
+DROP TABLE clients_history;
+ALTER TABLE clients DROP COLUMN valid_from;`,
+	},
+	{
+		version: 12,
+		name:    "client merges",
+		// Дубликаты, объединённые с другим клиентом, удаляются мягко:
+		// deleted_at заполняется, merged_into указывает на оставшегося клиента.
+		up: `
+ALTER TABLE clients ADD COLUMN deleted_at TEXT NOT NULL DEFAULT "";
+ALTER TABLE clients ADD COLUMN merged_into INTEGER NOT NULL DEFAULT 0;`,
+		down: `
+ALTER TABLE clients DROP COLUMN merged_into;
+ALTER TABLE clients DROP COLUMN deleted_at;`,
+	},
+	{
+		version: 13,
+		name:    "client documents",
+		// Содержимое документов хранится в BlobStore, здесь — только
+		// метаданные и ключ содержимого.
+		up: `
+CREATE TABLE client_documents (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	mime_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	blob_key TEXT NOT NULL UNIQUE,
+	created_at TEXT NOT NULL
+);
+CREATE INDEX client_documents_client_id ON client_documents (client_id);`,
+		down: `DROP TABLE client_documents;`,
+	},
+	{
+		version: 14,
+		name:    "client preferences",
+		up:      `ALTER TABLE clients ADD COLUMN preferences TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(preferences));`,
+		down:    `ALTER TABLE clients DROP COLUMN preferences;`,
+	},
+	{
+		version: 15,
+		name:    "segments",
+		up: `
+CREATE TABLE segments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	criteria TEXT NOT NULL CHECK (json_valid(criteria)),
+	updated_at TEXT NOT NULL
+);`,
+		down: `DROP TABLE segments;`,
+	},
+	{
+		version: 16,
+		name:    "environment",
+		// Единственная строка с назначением БД (см. Environment).
+		up: `
+CREATE TABLE environment (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	name TEXT NOT NULL
+);`,
+		down: `DROP TABLE environment;`,
+	},
+	{
+		version: 17,
+		name:    "legal hold",
+		// Клиентов на удержании PurgeSoftDeleted не удаляет (см. SetLegalHold).
+		up:   `ALTER TABLE clients ADD COLUMN legal_hold INTEGER NOT NULL DEFAULT 0;`,
+		down: `ALTER TABLE clients DROP COLUMN legal_hold;`,
+	},
+	{
+		version: 18,
+		name:    "clients archive",
+		// Неактивные клиенты переносятся сюда целиком (см. ArchiveClients) с
+		// прежними ID; связанные строки остаются на месте. Столбцы,
+		// добавляемые в clients, нужно добавлять и сюда.
+		up: `
+CREATE TABLE clients_archive (
+	id INTEGER PRIMARY KEY,
+	fio VARCHAR(128) NOT NULL,
+	login VARCHAR(32) NOT NULL,
+	birthday CHAR(8) NOT NULL,
+	email VARCHAR(64) NOT NULL,
+	owner_id TEXT NOT NULL,
+	marketing_consent INTEGER NOT NULL,
+	consent_updated_at TEXT NOT NULL,
+	status TEXT NOT NULL,
+	valid_from TEXT NOT NULL,
+	deleted_at TEXT NOT NULL,
+	merged_into INTEGER NOT NULL,
+	preferences TEXT NOT NULL,
+	legal_hold INTEGER NOT NULL,
+	archived_at TEXT NOT NULL
+);`,
+		down: `DROP TABLE clients_archive;`,
+	},
+	{
+		version: 19,
+		name:    "outbox",
+		// События об изменениях клиентов пишутся в одной транзакции с
+		// изменением и публикуются OutboxRelay; delivered_at пуст, пока
+		// событие не опубликовано.
+		up: `
+CREATE TABLE outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL,
+	operation TEXT NOT NULL,
+	occurred_at TEXT NOT NULL,
+	actor TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	fields TEXT NOT NULL DEFAULT '[]',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	delivered_at TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX outbox_pending ON outbox (delivered_at, id);`,
+		down: `DROP TABLE outbox;`,
+	},
+	{
+		version: 20,
+		name:    "webhooks",
+		// Доставки создаются WebhookDispatcher.Publish по одной на событие и
+		// подписанную точку; status — pending, delivered или dead.
+		up: `
+CREATE TABLE webhook_endpoints (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL DEFAULT '[]',
+	created_at TEXT NOT NULL
+);
+CREATE TABLE webhook_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+	event_id INTEGER NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TEXT NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	UNIQUE (endpoint_id, event_id)
+);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);`,
+		down: `
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;`,
+	},
+	{
+		version: 21,
+		name:    "webhook attempts",
+		// Журнал всех попыток доставки вебхуков; status_code равен 0, если
+		// ответа не было.
+		up: `
+CREATE TABLE webhook_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	delivery_id INTEGER NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+	attempted_at TEXT NOT NULL,
+	succeeded INTEGER NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	latency_ms INTEGER NOT NULL,
+	error TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX webhook_attempts_delivery_id ON webhook_attempts (delivery_id);`,
+		down: `DROP TABLE webhook_attempts;`,
+	},
+	{
+		version: 22,
+		name:    "client summary",
+		// Денормализованная сводка по клиентам, которую поддерживает
+		// ClientSummaryProjector; email хранится в том же виде, что и в
+		// clients, tags — JSON-массив названий меток.
+		up: `
+CREATE TABLE client_summary (
+	client_id INTEGER PRIMARY KEY,
+	fio TEXT NOT NULL,
+	login TEXT NOT NULL,
+	email TEXT NOT NULL,
+	status TEXT NOT NULL,
+	owner_id TEXT NOT NULL DEFAULT '',
+	order_count INTEGER NOT NULL DEFAULT 0,
+	last_activity TEXT NOT NULL DEFAULT '',
+	tags TEXT NOT NULL DEFAULT '[]',
+	updated_at TEXT NOT NULL
+);
+CREATE INDEX client_summary_last_activity ON client_summary (last_activity);`,
+		down: `DROP TABLE client_summary;`,
+	},
+	{
+		version: 23,
+		name:    "sync cursors",
+		// Позиции потребителей, читающих outbox самостоятельно (например,
+		// ElasticSync): position — ID последнего обработанного события.
+		up: `
+CREATE TABLE sync_cursors (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	position INTEGER NOT NULL DEFAULT 0,
+	updated_at TEXT NOT NULL
+);`,
+		down: `DROP TABLE sync_cursors;`,
+	},
+	{
+		version: 24,
+		name:    "change log",
+		// Журнал изменений clients, который ведут триггеры (см. Changes):
+		// old и new — значения столбцов строки в виде JSON-объекта или
+		// NULL, changed_at — время по часам БД с миллисекундами.
+		up: `
+CREATE TABLE change_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	table_name TEXT NOT NULL,
+	op TEXT NOT NULL CHECK (op IN ('insert', 'update', 'delete')),
+	row_id INTEGER NOT NULL,
+	old TEXT,
+	new TEXT,
+	changed_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE INDEX change_log_row ON change_log (table_name, row_id);` + changeLogTriggers("clients", changeLogColumnsV24),
+		down: `
+DROP TRIGGER clients_change_log_delete;
+DROP TRIGGER clients_change_log_update;
+DROP TRIGGER clients_change_log_insert;
+DROP TABLE change_log;`,
+	},
+	{
+		version: 25,
+		name:    "directory links",
+		// Связь клиентов с пользователями внешних каталогов (см.
+		// DirectorySync): source — имя каталога, external_id —
+		// неизменяемый ID пользователя в нём.
+		up: `
+CREATE TABLE directory_links (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	source TEXT NOT NULL,
+	external_id TEXT NOT NULL,
+	client_id INTEGER NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	synced_at TEXT NOT NULL,
+	UNIQUE (source, external_id)
+);
+CREATE INDEX directory_links_client_id ON directory_links (client_id);`,
+		down: `DROP TABLE directory_links;`,
+	},
+	{
+		version: 26,
+		name:    "autocomplete",
+		// Ключи подсказок для поля ввода, которые поддерживает
+		// AutocompleteProjector: key — ФИО с одного из слов или логин в
+		// нижнем регистре (см. autocompleteKey), field — поле клиента.
+		up: `
+CREATE TABLE client_autocomplete (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id INTEGER NOT NULL,
+	field TEXT NOT NULL CHECK (field IN ('fio', 'login')),
+	key TEXT NOT NULL
+);
+CREATE INDEX client_autocomplete_key ON client_autocomplete (key, client_id);
+CREATE INDEX client_autocomplete_client_id ON client_autocomplete (client_id);`,
+		down: `DROP TABLE client_autocomplete;`,
+	},
+	{
+		version: 27,
+		name:    "phonetic keys",
+		// Фонетический ключ первого слова ключа ФИО (см. phoneticKey) для
+		// поиска по звучанию; у ключей логина он пуст. В строках, записанных
+		// до миграции, ключ появляется после RebuildAutocomplete.
+		up: `
+ALTER TABLE client_autocomplete ADD COLUMN phonetic TEXT NOT NULL DEFAULT '';
+CREATE INDEX client_autocomplete_phonetic ON client_autocomplete (phonetic, client_id);`,
+		down: `
+DROP INDEX client_autocomplete_phonetic;
+ALTER TABLE client_autocomplete DROP COLUMN phonetic;`,
+	},
+	{
+		version: 28,
+		name:    "client created_at",
+		// Время добавления клиента для отчёта ClientsCreated. Существующим
+		// клиентам оно берётся из записи insert журнала аудита; у клиентов,
+		// добавленных в обход репозитория, остаётся пустым. Триггеры журнала
+		// изменений пересоздаются с новым столбцом и на время заполнения
+		// снимаются, чтобы оно не попало в журнал.
+		up: `
+DROP TRIGGER clients_change_log_delete;
+DROP TRIGGER clients_change_log_update;
+DROP TRIGGER clients_change_log_insert;
+ALTER TABLE clients ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients_archive ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+UPDATE clients SET created_at = COALESCE((SELECT MIN(a.occurred_at) FROM audit_log a
+	WHERE a.client_id = clients.id AND a.operation = 'insert'), '');
+UPDATE clients_archive SET created_at = COALESCE((SELECT MIN(a.occurred_at) FROM audit_log a
+	WHERE a.client_id = clients_archive.id AND a.operation = 'insert'), '');
+CREATE INDEX clients_created_at ON clients (created_at);` + changeLogTriggers("clients", append(changeLogColumnsV24[:len(changeLogColumnsV24):len(changeLogColumnsV24)], "created_at")),
+		down: `
+DROP TRIGGER clients_change_log_delete;
+DROP TRIGGER clients_change_log_update;
+DROP TRIGGER clients_change_log_insert;
+DROP INDEX clients_created_at;
+ALTER TABLE clients_archive DROP COLUMN created_at;
+ALTER TABLE clients DROP COLUMN created_at;` + changeLogTriggers("clients", changeLogColumnsV24),
+	},
+	{
+		version: 29,
+		name:    "client version",
+		// Версия строки клиента для оптимистической блокировки (см.
+		// UpdateClientsWhere): каждое изменение клиента её увеличивает.
+		// Журнал изменений версию не записывает — она меняется вместе с
+		// записываемыми столбцами.
+		up: `
+ALTER TABLE clients ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE clients_archive ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+		down: `
+ALTER TABLE clients_archive DROP COLUMN version;
+ALTER TABLE clients DROP COLUMN version;`,
+	},
+}
+
+// changeLogColumnsV24 — столбцы clients, которые записывают в журнал
+// изменений триггеры миграции 24.
+var changeLogColumnsV24 = []string{
+	"id", "fio", "login", "birthday", "email", "owner_id", "marketing_consent", "consent_updated_at",
+	"status", "valid_from", "deleted_at", "merged_into", "preferences", "legal_hold",
+}
+
+// MigrationStatus — состояние миграции в БД.
+type MigrationStatus struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// AppliedAt — время применения миграции; пустое, если она не применена.
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+// Applied сообщает, применена ли миграция.
+func (s MigrationStatus) Applied() bool {
+	return s.AppliedAt != ""
+}
+
+// ErrPendingMigrations возвращается CheckMigrated, если к БД применены не
+// все миграции текущего кода.
+var ErrPendingMigrations = errors.New("pending migrations")
+
+// CheckMigrated возвращает ErrPendingMigrations с первой из не применённых
+// миграций, если схема БД отстаёт от кода. В отличие от Migrate ничего не
+// меняет: отстающая схема может быть результатом намеренного отката.
+func CheckMigrated(ctx context.Context, db *sql.DB) error {
+	pending, err := migratePlan(ctx, db, migrations[len(migrations)-1].version)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d not applied, starting with %d %q", ErrPendingMigrations, len(pending), pending[0].version, pending[0].name)
+	}
+
+	return nil
+}
+
+// Migrate применяет к БД все ещё не применённые миграции.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	return MigrateTo(ctx, db, migrations[len(migrations)-1].version)
+}
+
+// MigrateTo применяет не применённые миграции с версиями до target
+// включительно.
+func MigrateTo(ctx context.Context, db *sql.DB, target int) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL DEFAULT "",
+	applied_at TEXT NOT NULL
+)`)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version > target {
+			break
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var applied int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = :version",
+		sql.Named("version", m.version)).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, m.up); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (:version, :name, :applied_at)",
+		sql.Named("version", m.version),
+		sql.Named("name", m.name),
+		sql.Named("applied_at", time.Now().UTC().Format(time.RFC3339)))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Rollback отменяет steps последних применённых миграций в порядке,
+// обратном применению.
+func Rollback(ctx context.Context, db *sql.DB, steps int) error {
+	plan, err := rollbackPlan(ctx, db, steps)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range plan {
+		if err := rollbackMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("rollback migration %d %s: %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func rollbackMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = :version", sql.Named("version", m.version))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// Миграцию уже отменил другой процесс
+	if n == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, m.down); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MigrationStatuses возвращает состояние всех известных миграций по
+// возрастанию версии. БД без таблицы schema_migrations считается пустой.
+func MigrationStatuses(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: m.version, Name: m.name, AppliedAt: applied[m.version]}
+	}
+
+	return statuses, nil
+}
+
+// migratePlan возвращает миграции, которые применит MigrateTo с тем же
+// target, в порядке применения.
+func migratePlan(ctx context.Context, db *sql.DB, target int) ([]migration, error) {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []migration
+	for _, m := range migrations {
+		if m.version > target {
+			break
+		}
+		if _, ok := applied[m.version]; !ok {
+			plan = append(plan, m)
+		}
+	}
+
+	return plan, nil
+}
+
+// rollbackPlan возвращает миграции, которые отменит Rollback с тем же
+// steps, в порядке отмены.
+func rollbackPlan(ctx context.Context, db *sql.DB, steps int) ([]migration, error) {
+	if steps < 0 {
+		return nil, fmt.Errorf("%w: rollback steps must not be negative, got %d", ErrValidation, steps)
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []migration
+	for i := len(migrations) - 1; i >= 0 && len(plan) < steps; i-- {
+		if _, ok := applied[migrations[i].version]; ok {
+			plan = append(plan, migrations[i])
+		}
+	}
+
+	return plan, nil
+}
+
+// appliedMigrations возвращает время применения миграций по версиям.
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]string, error) {
+	var exists int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]string)
+	if exists == 0 {
+		return applied, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt string
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
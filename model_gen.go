@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+//go:generate go run . genmodel -o client_model.go
+
+// Модель Client генерируется по схеме таблицы clients (см. runGenModel):
+// поле — на каждый столбец, кроме перечисленных в clientModelSkipped, в
+// порядке столбцов. Имя поля выводится из имени столбца, тип — из типа
+// столбца, если он не задан в clientModelTypes. Новый столбец clients
+// после миграции попадает в Client при следующем go generate, а тест
+// Test_ClientModel_UpToDate не даёт забыть о перегенерации.
+
+// clientModelTypes — типы полей Client, отличные от выводимых из типа
+// столбца.
+var clientModelTypes = map[string]string{
+	"marketing_consent":  "bool",
+	"consent_updated_at": "time.Time",
+	"status":             "ClientStatus",
+}
+
+// clientModelOmitEmpty — столбцы, пустые значения которых не выводятся
+// в JSON.
+var clientModelOmitEmpty = map[string]bool{
+	"owner_id": true,
+}
+
+// clientModelSkipped — столбцы clients, которых нет в Client, с причиной.
+var clientModelSkipped = map[string]string{
+	"valid_from":  "начало действия версии клиента, ведёт история изменений",
+	"deleted_at":  "мягкое удаление, скрывается условием notDeleted",
+	"merged_into": "слияние клиентов, см. MergeClients",
+	"preferences": "настройки клиента, см. SetPreference",
+	"legal_hold":  "запрет удаления, см. SetLegalHold",
+	"created_at":  "время регистрации, читается отчётами",
+	"version":     "версия строки для оптимистической блокировки, см. UpdateClientsWhere",
+}
+
+// fieldInitialisms — части имён столбцов, которые в именах полей пишутся
+// заглавными буквами.
+var fieldInitialisms = map[string]string{
+	"id":  "ID",
+	"fio": "FIO",
+}
+
+// modelField — поле генерируемой модели.
+type modelField struct {
+	column string
+	name   string
+	goType string
+}
+
+// runGenModel выполняет команду genmodel: генерирует файл с моделью
+// Client и константами столбцов по схеме таблицы clients. По умолчанию
+// схема берётся из пустой БД после всех миграций, флаг -db задаёт БД,
+// схему которой нужно прочитать.
+func runGenModel(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("genmodel", flag.ContinueOnError)
+	fs.SetOutput(w)
+	dsn := fs.String("db", "", "SQLite database DSN; by default the schema of a freshly migrated database")
+	out := fs.String("o", "", "output file; by default the code is written to stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *dsn
+	if path == "" {
+		path = ":memory:"
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if *dsn == "" {
+		if err := Migrate(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	src, err := generateClientModel(ctx, db)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = w.Write(src)
+		return err
+	}
+
+	return os.WriteFile(*out, src, 0o644)
+}
+
+// generateClientModel возвращает отформатированный исходный код модели
+// Client по схеме таблицы clients в db.
+func generateClientModel(ctx context.Context, db *sql.DB) ([]byte, error) {
+	fields, err := clientModelFields(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by \"go run . genmodel\"; DO NOT EDIT.\n\n")
+	b.WriteString("package main\n\n")
+	for _, f := range fields {
+		if strings.HasPrefix(f.goType, "time.") {
+			b.WriteString("import \"time\"\n\n")
+			break
+		}
+	}
+
+	b.WriteString("// Client — клиент: строка таблицы clients без служебных столбцов.\n")
+	b.WriteString("type Client struct {\n")
+	for _, f := range fields {
+		jsonTag := f.column
+		if clientModelOmitEmpty[f.column] {
+			jsonTag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q db:%q`\n", f.name, f.goType, jsonTag, f.column)
+	}
+	b.WriteString("}\n\n")
+
+	names := make([]string, len(fields))
+	b.WriteString("// Столбцы таблицы clients, из которых читается Client.\n")
+	b.WriteString("const (\n")
+	for i, f := range fields {
+		fmt.Fprintf(&b, "\tclientColumn%s = %q\n", f.name, f.column)
+		names[i] = f.column
+	}
+	b.WriteString(")\n\n")
+	b.WriteString("// clientColumns — столбцы Client через запятую для SELECT.\n")
+	fmt.Fprintf(&b, "const clientColumns = %q\n", strings.Join(names, ", "))
+
+	return format.Source(b.Bytes())
+}
+
+// clientModelFields читает столбцы таблицы clients и возвращает поля
+// модели Client в порядке столбцов. Типы и пропуски, заданные для
+// столбцов, которых нет в таблице, — ошибка: описание модели устарело.
+func clientModelFields(ctx context.Context, db *sql.DB) ([]modelField, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, type FROM pragma_table_info('clients') ORDER BY cid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []modelField
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var column, sqlType string
+		if err := rows.Scan(&column, &sqlType); err != nil {
+			return nil, err
+		}
+		seen[column] = true
+		if _, ok := clientModelSkipped[column]; ok {
+			continue
+		}
+
+		goType, ok := clientModelTypes[column]
+		if !ok {
+			if goType, ok = goTypeOf(sqlType); !ok {
+				return nil, fmt.Errorf("genmodel: no Go type for column clients.%s of type %s", column, sqlType)
+			}
+		}
+		fields = append(fields, modelField{column: column, name: fieldName(column), goType: goType})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("genmodel: table clients not found")
+	}
+
+	var configured []string
+	for column := range clientModelTypes {
+		configured = append(configured, column)
+	}
+	for column := range clientModelSkipped {
+		configured = append(configured, column)
+	}
+	for column := range clientModelOmitEmpty {
+		configured = append(configured, column)
+	}
+	sort.Strings(configured)
+	for _, column := range configured {
+		if !seen[column] {
+			return nil, fmt.Errorf("genmodel: column clients.%s is configured but does not exist", column)
+		}
+	}
+
+	return fields, nil
+}
+
+// goTypeOf возвращает тип Go для объявленного типа столбца по правилам
+// родства типов SQLite.
+func goTypeOf(sqlType string) (string, bool) {
+	t := strings.ToUpper(sqlType)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "int", true
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "string", true
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "float64", true
+	}
+
+	return "", false
+}
+
+// fieldName возвращает имя поля Go для столбца: owner_id — OwnerID.
+func fieldName(column string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(column, "_") {
+		if s, ok := fieldInitialisms[part]; ok {
+			b.WriteString(s)
+			continue
+		}
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+
+	return b.String()
+}
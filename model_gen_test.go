@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что перегенерация модели Client по схеме после всех
+// миграций не меняет закоммиченный client_model.go
+func Test_ClientModel_UpToDate(t *testing.T) {
+	committed, err := os.ReadFile("client_model.go")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, run(context.Background(), []string{"genmodel"}, &out))
+	assert.Equal(t, string(committed), out.String(), "client_model.go is out of date with the schema: run go generate")
+
+	path := filepath.Join(t.TempDir(), "client_model.go")
+	require.NoError(t, run(context.Background(), []string{"genmodel", "-o", path}, &out))
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, committed, written)
+}
+
+// Тест проверяет, что новый столбец clients попадает в модель с типом,
+// выведенным из типа столбца, а столбец неизвестного типа — ошибка
+func Test_ClientModel_NewColumn(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+
+	_, err := db.ExecContext(ctx, `ALTER TABLE clients ADD COLUMN phone_number VARCHAR(16) NOT NULL DEFAULT "";
+		ALTER TABLE clients ADD COLUMN visits INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE clients ADD COLUMN rating REAL NOT NULL DEFAULT 0`)
+	require.NoError(t, err)
+
+	src, err := generateClientModel(ctx, db)
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "PhoneNumber      string       `json:\"phone_number\" db:\"phone_number\"`")
+	assert.Contains(t, string(src), "Visits           int          `json:\"visits\" db:\"visits\"`")
+	assert.Contains(t, string(src), "Rating           float64      `json:\"rating\" db:\"rating\"`")
+	assert.Contains(t, string(src), "clientColumnPhoneNumber      = \"phone_number\"")
+	assert.Contains(t, string(src), `const clientColumns = "id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status, phone_number, visits, rating"`)
+
+	_, err = db.ExecContext(ctx, "ALTER TABLE clients ADD COLUMN photo BLOB")
+	require.NoError(t, err)
+	_, err = generateClientModel(ctx, db)
+	assert.EqualError(t, err, "genmodel: no Go type for column clients.photo of type BLOB")
+}
+
+// Тест проверяет, что описание модели, упоминающее несуществующий столбец
+// или таблицу, — ошибка генерации
+func Test_ClientModel_StaleConfig(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+
+	_, err := generateClientModel(ctx, db)
+	assert.EqualError(t, err, "genmodel: table clients not found")
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE clients (id INTEGER PRIMARY KEY, fio TEXT, login TEXT, birthday TEXT, email TEXT,
+		owner_id TEXT, marketing_consent INTEGER, consent_updated_at TEXT, status TEXT)`)
+	require.NoError(t, err)
+	_, err = generateClientModel(ctx, db)
+	assert.EqualError(t, err, "genmodel: column clients.created_at is configured but does not exist")
+}
+
+// Тест проверяет имена полей, выводимые из имён столбцов
+func Test_ClientModel_FieldName(t *testing.T) {
+	tests := map[string]string{
+		"id":                 "ID",
+		"fio":                "FIO",
+		"owner_id":           "OwnerID",
+		"consent_updated_at": "ConsentUpdatedAt",
+		"phone__number":      "PhoneNumber",
+	}
+	for column, want := range tests {
+		assert.Equal(t, want, fieldName(column), column)
+	}
+}
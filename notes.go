@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxNoteLen — наибольшая длина текста заметки в символах.
+const maxNoteLen = 2000
+
+// Note — заметка сотрудника о взаимодействии с клиентом.
+type Note struct {
+	ID        int       `json:"id"`
+	ClientID  int       `json:"client_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddNote добавляет заметку клиенту clientID. Автор берётся из контекста
+// (WithActor), время — из часов репозитория.
+func (r *Repository) AddNote(ctx context.Context, clientID int, body string) (_ Note, err error) {
+	ctx, end := r.startClientOperation(ctx, "add_note", clientID)
+	defer end(&err)
+
+	switch {
+	case strings.TrimSpace(body) == "":
+		return Note{}, fmt.Errorf("%w: note body is required", ErrValidation)
+	case !utf8.ValidString(body):
+		return Note{}, fmt.Errorf("%w: note body is not valid UTF-8", ErrValidation)
+	case utf8.RuneCountInString(body) > maxNoteLen:
+		return Note{}, fmt.Errorf("%w: note body is longer than %d characters", ErrValidation, maxNoteLen)
+	}
+
+	note := Note{
+		ClientID:  clientID,
+		Author:    ActorFromContext(ctx),
+		Body:      body,
+		CreatedAt: r.now(),
+	}
+	err = r.inTx(ctx, func(q querier) error {
+		if err := r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		res, err := q.ExecContext(ctx, "INSERT INTO client_notes (client_id, author, body, created_at) VALUES (:client_id, :author, :body, :created_at)",
+			sql.Named("client_id", note.ClientID),
+			sql.Named("author", note.Author),
+			sql.Named("body", note.Body),
+			sql.Named("created_at", note.CreatedAt.Format(time.RFC3339Nano)))
+		if err != nil {
+			return err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		note.ID = int(id)
+
+		return r.enqueueEvent(ctx, q, EventNotes, clientID, note.CreatedAt, nil)
+	})
+	if err != nil {
+		return Note{}, err
+	}
+
+	return note, nil
+}
+
+// ListNotes возвращает заметки клиента от старых к новым или
+// ErrClientNotFound, если клиента нет.
+func (r *Repository) ListNotes(ctx context.Context, clientID int) (_ []Note, err error) {
+	ctx, end := r.startClientOperation(ctx, "list_notes", clientID)
+	defer end(&err)
+
+	notes := []Note{}
+	err = r.inTx(ctx, func(q querier) error {
+		if err := r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		rows, err := q.QueryContext(ctx, "SELECT id, client_id, author, body, created_at FROM client_notes WHERE client_id = :client_id ORDER BY created_at, id",
+			sql.Named("client_id", clientID))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				note      Note
+				createdAt string
+			)
+			if err := rows.Scan(&note.ID, &note.ClientID, &note.Author, &note.Body, &createdAt); err != nil {
+				return err
+			}
+			if note.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+				return err
+			}
+			notes = append(notes, note)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// DeleteNote удаляет заметку по ID или возвращает ErrNoteNotFound, если её
+// нет или её клиент недоступен пользователю из контекста.
+func (r *Repository) DeleteNote(ctx context.Context, id int) (err error) {
+	ctx, end := r.startOperation(ctx, "delete_note")
+	defer end(&err)
+
+	scope, args := r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	return r.inTx(ctx, func(q querier) error {
+		var clientID int
+		err := q.QueryRowContext(ctx, "DELETE FROM client_notes WHERE id = :id AND client_id IN (SELECT id FROM clients WHERE 1"+scope+") RETURNING client_id", args...).Scan(&clientID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrNoteNotFound, id)
+		}
+		if err != nil {
+			return err
+		}
+
+		return r.enqueueEvent(ctx, q, EventNotes, clientID, r.now(), nil)
+	})
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет автора и время заметок из контекста и часов, порядок
+// заметок и их удаление
+func Test_Notes_AddListDelete(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(start)
+	_, repo := setupTestTx(t, WithClock(clock))
+
+	ctx := context.Background()
+	alice := WithActor(ctx, "alice@example.com")
+	bob := WithActor(ctx, "bob@example.com")
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	notes, err := repo.ListNotes(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, notes)
+
+	first, err := repo.AddNote(alice, id, "called, asked for an invoice")
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	second, err := repo.AddNote(bob, id, "invoice sent")
+	require.NoError(t, err)
+
+	assert.Equal(t, Note{ID: first.ID, ClientID: id, Author: "alice@example.com", Body: "called, asked for an invoice", CreatedAt: start}, first)
+	assert.Equal(t, "bob@example.com", second.Author)
+	assert.Equal(t, start.Add(time.Hour), second.CreatedAt)
+
+	notes, err = repo.ListNotes(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, []Note{first, second}, notes, "notes should be ordered from oldest to newest")
+
+	require.NoError(t, repo.DeleteNote(ctx, first.ID))
+	require.ErrorIs(t, repo.DeleteNote(ctx, first.ID), ErrNoteNotFound)
+
+	notes, err = repo.ListNotes(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, []Note{second}, notes)
+}
+
+// Тест проверяет проверку текста заметки и отсутствующего клиента
+func Test_Notes_Errors(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	for name, body := range map[string]string{
+		"Empty":   " \n",
+		"TooLong": strings.Repeat("ж", maxNoteLen+1),
+		"BadUTF8": "\xff",
+	} {
+		_, err := repo.AddNote(ctx, 1, body)
+		require.ErrorIs(t, err, ErrValidation, "%s note should be rejected", name)
+	}
+
+	_, err := repo.AddNote(ctx, -1, "note")
+	require.ErrorIs(t, err, ErrClientNotFound)
+	_, err = repo.ListNotes(ctx, -1)
+	require.ErrorIs(t, err, ErrClientNotFound)
+	require.ErrorIs(t, repo.DeleteNote(ctx, -1), ErrNoteNotFound)
+}
+
+// Тест проверяет, что заметки удаляются вместе с клиентом
+func Test_Notes_CascadeOnClientDelete(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	for _, body := range []string{"first", "second"} {
+		_, err := repo.AddNote(ctx, id, body)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, repo.Delete(ctx, id))
+	assertClientNotInDB(t, tx, id)
+	assertRowCount(t, tx, "client_notes", 0, "client_id = ?", id)
+}
+
+// Тест проверяет, что заметки чужого клиента недоступны пользователю
+func Test_Notes_OwnerRestriction(t *testing.T) {
+	_, repo := setupTestDB(t, WithOwnerRestriction())
+
+	owner := WithPrincipal(context.Background(), Principal{ID: "manager-1"})
+	stranger := WithPrincipal(context.Background(), Principal{ID: "manager-2"})
+
+	id, err := repo.Insert(owner, newTestClient())
+	require.NoError(t, err)
+	note, err := repo.AddNote(owner, id, "note")
+	require.NoError(t, err)
+
+	_, err = repo.AddNote(stranger, id, "note")
+	require.ErrorIs(t, err, ErrClientNotFound)
+	_, err = repo.ListNotes(stranger, id)
+	require.ErrorIs(t, err, ErrClientNotFound)
+	require.ErrorIs(t, repo.DeleteNote(stranger, note.ID), ErrNoteNotFound)
+
+	require.NoError(t, repo.DeleteNote(owner, note.ID))
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OrderStatus — состояние заказа.
+type OrderStatus string
+
+const (
+	OrderNew       OrderStatus = "new"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// Valid сообщает, что статус — один из известных.
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderNew, OrderPaid, OrderShipped, OrderCancelled:
+		return true
+	}
+
+	return false
+}
+
+// Order — заказ клиента. Amount хранится в копейках.
+type Order struct {
+	ID        int         `json:"id"`
+	ClientID  int         `json:"client_id"`
+	Amount    int64       `json:"amount"`
+	Status    OrderStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// ClientWithOrders — клиент вместе со всеми его заказами.
+type ClientWithOrders struct {
+	Client
+	Orders []Order `json:"orders"`
+}
+
+// MarshalJSON выводит поля клиента и его заказы одним объектом.
+func (c ClientWithOrders) MarshalJSON() ([]byte, error) {
+	return marshalWithClient(c.Client, struct {
+		Orders []Order `json:"orders"`
+	}{c.Orders})
+}
+
+// UnmarshalJSON разбирает объект, выведенный MarshalJSON.
+func (c *ClientWithOrders) UnmarshalJSON(data []byte) error {
+	var extra struct {
+		Orders []Order `json:"orders"`
+	}
+	if err := unmarshalWithClient(data, &c.Client, &extra, "orders"); err != nil {
+		return err
+	}
+	c.Orders = extra.Orders
+
+	return nil
+}
+
+// orderColumns — столбцы orders в порядке, ожидаемом scanOrder.
+const orderColumns = "id, client_id, amount, status, created_at"
+
+func selectOrdersByClient(db *sql.DB, clientID int) ([]Order, error) {
+	rows, err := db.Query("SELECT "+orderColumns+" FROM orders WHERE client_id = :client_id ORDER BY id", sql.Named("client_id", clientID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanOrders(rows)
+}
+
+// OrderRepository — слой доступа к заказам. Использует соединение,
+// транзакцию и настройки создавшего его Repository; заказы доступны
+// только вместе с их клиентом (см. WithOwnerRestriction).
+type OrderRepository struct {
+	r *Repository
+}
+
+// Orders возвращает репозиторий заказов клиентов r.
+func (r *Repository) Orders() *OrderRepository {
+	return &OrderRepository{r: r}
+}
+
+// Create добавляет заказ клиенту order.ClientID и возвращает его ID. Пустой
+// статус означает OrderNew, время создания берётся из часов репозитория.
+func (o *OrderRepository) Create(ctx context.Context, order Order) (_ int, err error) {
+	ctx, end := o.r.startClientOperation(ctx, "order_create", order.ClientID)
+	defer end(&err)
+
+	if order.Status == "" {
+		order.Status = OrderNew
+	}
+	if !order.Status.Valid() {
+		return 0, fmt.Errorf("%w: unknown order status %q", ErrValidation, order.Status)
+	}
+	if order.Amount < 0 {
+		return 0, fmt.Errorf("%w: order amount %d is negative", ErrValidation, order.Amount)
+	}
+
+	var id int
+	err = o.r.inTx(ctx, func(q querier) error {
+		if err := o.r.clientExists(ctx, q, order.ClientID); err != nil {
+			return err
+		}
+
+		res, err := q.ExecContext(ctx, "INSERT INTO orders (client_id, amount, status, created_at) VALUES (:client_id, :amount, :status, :created_at)",
+			sql.Named("client_id", order.ClientID),
+			sql.Named("amount", order.Amount),
+			sql.Named("status", string(order.Status)),
+			sql.Named("created_at", o.r.now().Format(time.RFC3339Nano)))
+		if err != nil {
+			return err
+		}
+
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = int(lastID)
+
+		return o.r.enqueueEvent(ctx, q, EventOrders, order.ClientID, o.r.now(), nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Select возвращает заказ по ID или ErrOrderNotFound, если его нет.
+func (o *OrderRepository) Select(ctx context.Context, id int) (_ Order, err error) {
+	ctx, end := o.r.startOperation(ctx, "order_select")
+	defer end(&err)
+
+	scope, args := o.r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	row := o.r.conn().QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = :id AND client_id IN (SELECT id FROM clients WHERE 1"+scope+")", args...)
+	order, err := scanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Order{}, fmt.Errorf("%w: %d", ErrOrderNotFound, id)
+	}
+
+	return order, err
+}
+
+// ByClient возвращает заказы клиента в порядке создания или
+// ErrClientNotFound, если клиента нет.
+func (o *OrderRepository) ByClient(ctx context.Context, clientID int) (_ []Order, err error) {
+	ctx, end := o.r.startClientOperation(ctx, "orders_by_client", clientID)
+	defer end(&err)
+
+	var orders []Order
+	err = o.r.inTx(ctx, func(q querier) error {
+		if err := o.r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		orders, err = o.byClient(ctx, q, clientID)
+		return err
+	})
+
+	return orders, err
+}
+
+func (o *OrderRepository) byClient(ctx context.Context, q querier, clientID int) ([]Order, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE client_id = :client_id ORDER BY id", sql.Named("client_id", clientID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanOrders(rows)
+}
+
+// SelectWithOrders возвращает клиента вместе с его заказами. Клиент и
+// заказы читаются в одной транзакции, поэтому согласованы между собой.
+func (r *Repository) SelectWithOrders(ctx context.Context, id int) (_ ClientWithOrders, err error) {
+	ctx, end := r.startClientOperation(ctx, "select_with_orders", id)
+	defer end(&err)
+
+	var result ClientWithOrders
+	err = r.inTx(ctx, func(q querier) error {
+		cl, err := r.selectClient(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		orders, err := r.Orders().byClient(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		result = ClientWithOrders{Client: cl, Orders: orders}
+		return nil
+	})
+	if err != nil {
+		return ClientWithOrders{}, err
+	}
+
+	return result, nil
+}
+
+func scanOrders(rows *sql.Rows) ([]Order, error) {
+	orders := []Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+func scanOrder(row rowScanner) (Order, error) {
+	var (
+		order     Order
+		status    string
+		createdAt string
+	)
+	if err := row.Scan(&order.ID, &order.ClientID, &order.Amount, &status, &createdAt); err != nil {
+		return Order{}, err
+	}
+
+	var err error
+	order.Status = OrderStatus(status)
+	if order.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
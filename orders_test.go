@@ -0,0 +1,147 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет создание заказов, их выборку по клиенту в порядке создания
+// и получение клиента вместе с заказами
+func Test_Orders_CreateAndSelectByClient(t *testing.T) {
+	t.Parallel()
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, repo := setupTestTx(t, WithClock(testutil.NewFakeClock(createdAt)))
+	orders := repo.Orders()
+
+	ctx := context.Background()
+
+	cl := newTestClient()
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	// Клиент без заказов
+	got, err := repo.SelectWithOrders(ctx, cl.ID)
+	require.NoError(t, err)
+	assertClientEqual(t, cl, got.Client)
+	assert.Empty(t, got.Orders)
+
+	want := []Order{
+		{ClientID: cl.ID, Amount: 150000, Status: OrderPaid, CreatedAt: createdAt},
+		{ClientID: cl.ID, Amount: 0, Status: OrderNew, CreatedAt: createdAt},
+	}
+	for i := range want {
+		order := want[i]
+		if order.Status == OrderNew {
+			order.Status = "" // статус по умолчанию
+		}
+		want[i].ID, err = orders.Create(ctx, order)
+		require.NoError(t, err, "error creating order %d: %v", i, err)
+	}
+
+	order, err := orders.Select(ctx, want[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, want[0], order)
+
+	byClient, err := orders.ByClient(ctx, cl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, want, byClient)
+
+	got, err = repo.SelectWithOrders(ctx, cl.ID)
+	require.NoError(t, err)
+	assertClientEqual(t, cl, got.Client)
+	assert.Equal(t, want, got.Orders)
+
+	// Заказы записаны в таблицу orders
+	assertRowCount(t, db, "orders", len(want), "client_id = ?", cl.ID)
+}
+
+// Тест проверяет отказы при некорректном заказе и отсутствующем клиенте
+func Test_Orders_Errors(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+	orders := repo.Orders()
+
+	ctx := context.Background()
+
+	_, err := orders.Create(ctx, Order{ClientID: 1, Amount: -1})
+	require.ErrorIs(t, err, ErrValidation, "negative amount should be rejected")
+	_, err = orders.Create(ctx, Order{ClientID: 1, Status: "lost"})
+	require.ErrorIs(t, err, ErrValidation, "unknown status should be rejected")
+
+	_, err = orders.Create(ctx, Order{ClientID: -1})
+	require.ErrorIs(t, err, ErrClientNotFound)
+	_, err = orders.ByClient(ctx, -1)
+	require.ErrorIs(t, err, ErrClientNotFound)
+	_, err = repo.SelectWithOrders(ctx, -1)
+	require.ErrorIs(t, err, ErrClientNotFound)
+
+	_, err = orders.Select(ctx, -1)
+	require.ErrorIs(t, err, ErrOrderNotFound)
+	assert.Equal(t, ClassNotFound, Classify(err))
+}
+
+// Тест проверяет, что клиента с заказами нельзя удалить через Delete ни в
+// репозитории, ни на уровне схемы при включённых внешних ключах, а
+// EraseClient удаляет его вместе с заказами
+func Test_Orders_ClientDeletion(t *testing.T) {
+	db, repo := setupTestDB(t)
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	_, err = repo.Orders().Create(ctx, Order{ClientID: id, Amount: 100})
+	require.NoError(t, err)
+
+	err = repo.Delete(ctx, id)
+	require.ErrorIs(t, err, ErrClientHasOrders)
+	assert.Equal(t, ClassConflict, Classify(err))
+	stored, err := selectOrdersByClient(db, id)
+	require.NoError(t, err)
+	require.Len(t, stored, 1, "order should survive the rejected delete")
+
+	fkDB, err := sql.Open("sqlite", testDSN("_pragma=foreign_keys(1)"))
+	require.NoError(t, err)
+	defer fkDB.Close()
+	_, err = fkDB.Exec("DELETE FROM clients WHERE id = ?", id)
+	require.Error(t, err, "foreign key should restrict deleting a client with orders")
+	assert.Equal(t, ClassConflict, Classify(err))
+
+	_, err = repo.EraseClient(ctx, id)
+	require.NoError(t, err)
+	assertClientNotInDB(t, db, id)
+	assertRowCount(t, db, "orders", 0, "client_id = ?", id)
+}
+
+// Тест проверяет, что заказы чужого клиента недоступны пользователю
+func Test_Orders_OwnerRestriction(t *testing.T) {
+	_, repo := setupTestDB(t, WithOwnerRestriction())
+	orders := repo.Orders()
+
+	owner := WithPrincipal(context.Background(), Principal{ID: "manager-1"})
+	stranger := WithPrincipal(context.Background(), Principal{ID: "manager-2"})
+
+	id, err := repo.Insert(owner, newTestClient())
+	require.NoError(t, err)
+	orderID, err := orders.Create(owner, Order{ClientID: id, Amount: 100})
+	require.NoError(t, err)
+
+	_, err = orders.Create(stranger, Order{ClientID: id, Amount: 100})
+	require.ErrorIs(t, err, ErrClientNotFound)
+	_, err = orders.ByClient(stranger, id)
+	require.ErrorIs(t, err, ErrClientNotFound)
+	_, err = orders.Select(stranger, orderID)
+	require.ErrorIs(t, err, ErrOrderNotFound)
+
+	_, err = orders.Select(owner, orderID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultOutboxBatch — сколько событий OutboxRelay публикует за одну
+// синхронизацию по умолчанию.
+const DefaultOutboxBatch = 100
+
+// ClientEvent — событие об изменении клиента. Событие не содержит PII:
+// только ID клиента, вид изменения и имена изменённых полей.
+type ClientEvent struct {
+	// ID — возрастающий номер события; потребители отбрасывают повторы по
+	// нему, так как событие может быть опубликовано больше одного раза.
+	ID         int64          `json:"id"`
+	ClientID   int            `json:"client_id"`
+	Operation  AuditOperation `json:"operation"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Actor      string         `json:"actor"`
+	RequestID  string         `json:"request_id,omitempty"`
+	// Fields — имена изменённых полей в порядке возрастания.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Операции событий об изменении связанных с клиентом данных. Такие
+// изменения не записываются в журнал аудита, но публикуются, чтобы
+// потребители (например, ClientSummaryProjector) видели их.
+const (
+	EventOrders AuditOperation = "orders"
+	EventNotes  AuditOperation = "notes"
+	EventTags   AuditOperation = "tags"
+)
+
+// EventSink — получатель событий outbox, например брокер сообщений.
+type EventSink interface {
+	// Publish публикует событие. Ошибка означает, что событие не
+	// опубликовано и будет отправлено повторно.
+	Publish(ctx context.Context, event ClientEvent) error
+}
+
+// EventSinks публикует событие во все получатели по порядку, так как на
+// одну БД запускается один OutboxRelay. Ошибка получателя прерывает
+// публикацию, и событие отправляется повторно всем получателям, поэтому
+// получатели должны обрабатывать повторы.
+type EventSinks []EventSink
+
+// Publish публикует событие в каждый получатель.
+func (s EventSinks) Publish(ctx context.Context, event ClientEvent) error {
+	for _, sink := range s {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueueEvent записывает событие об изменении клиента в outbox в
+// транзакции изменения q: событие сохраняется тогда и только тогда, когда
+// фиксируется само изменение.
+func (r *Repository) enqueueEvent(ctx context.Context, q querier, op AuditOperation, clientID int, at time.Time, diff map[string]FieldChange) error {
+	fields := make([]string, 0, len(diff))
+	for name := range diff {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `INSERT INTO outbox (client_id, operation, occurred_at, actor, request_id, fields)
+		VALUES (:client_id, :operation, :occurred_at, :actor, :request_id, :fields)`,
+		sql.Named("client_id", clientID),
+		sql.Named("operation", string(op)),
+		sql.Named("occurred_at", at.Format(time.RFC3339Nano)),
+		sql.Named("actor", ActorFromContext(ctx)),
+		sql.Named("request_id", RequestIDFromContext(ctx)),
+		sql.Named("fields", string(data)))
+
+	return err
+}
+
+// OutboxRelay публикует события из outbox в EventSink в порядке их
+// записи и отмечает опубликованные. Событие отмечается после успешной
+// публикации, поэтому после сбоя между публикацией и отметкой оно
+// публикуется повторно (доставка «хотя бы один раз»). На одну БД
+// запускается один OutboxRelay.
+type OutboxRelay struct {
+	repo *Repository
+	sink EventSink
+	// batch — сколько событий публикуется за одну синхронизацию.
+	batch int
+
+	mu sync.Mutex
+}
+
+// NewOutboxRelay создаёт публикацию событий outbox БД репозитория repo в
+// sink.
+func NewOutboxRelay(repo *Repository, sink EventSink) *OutboxRelay {
+	return &OutboxRelay{repo: repo, sink: sink, batch: DefaultOutboxBatch}
+}
+
+// Relay публикует до DefaultOutboxBatch неопубликованных событий и
+// возвращает число опубликованных. Неудачная публикация увеличивает
+// счётчик попыток события, сохраняет ошибку и прерывает синхронизацию,
+// чтобы события не публиковались не по порядку; событие будет отправлено
+// при следующей синхронизации.
+func (o *OutboxRelay) Relay(ctx context.Context) (_ int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	r := o.repo
+	ctx, end := r.startOperation(ctx, "outbox_relay")
+	defer end(&err)
+
+	events, err := r.pendingEvents(ctx, o.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, event := range events {
+		if err := o.sink.Publish(ctx, event); err != nil {
+			_, markErr := r.conn().ExecContext(ctx, "UPDATE outbox SET attempts = attempts + 1, last_error = :last_error WHERE id = :id",
+				sql.Named("last_error", err.Error()),
+				sql.Named("id", event.ID))
+			if markErr != nil {
+				return i, markErr
+			}
+			return i, fmt.Errorf("publish event %d: %w", event.ID, err)
+		}
+
+		_, err := r.conn().ExecContext(ctx, "UPDATE outbox SET attempts = attempts + 1, last_error = '', delivered_at = :delivered_at WHERE id = :id",
+			sql.Named("delivered_at", r.now().Format(time.RFC3339Nano)),
+			sql.Named("id", event.ID))
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(events), nil
+}
+
+// Run публикует события каждые poll, пока не отменён ctx. Ошибка
+// публикации передаётся в onError (если задан); событие, на котором она
+// произошла, остаётся первым в очереди и публикуется снова через poll.
+func (o *OutboxRelay) Run(ctx context.Context, poll time.Duration, onError func(error)) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.Relay(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingEvents возвращает до limit неопубликованных событий по
+// возрастанию ID.
+func (r *Repository) pendingEvents(ctx context.Context, limit int) ([]ClientEvent, error) {
+	return r.queryEvents(ctx, `SELECT id, client_id, operation, occurred_at, actor, request_id, fields
+	FROM outbox WHERE delivered_at = '' ORDER BY id LIMIT :limit`, sql.Named("limit", limit))
+}
+
+// queryEvents возвращает события, выбранные запросом query со столбцами
+// id, client_id, operation, occurred_at, actor, request_id и fields.
+func (r *Repository) queryEvents(ctx context.Context, query string, args ...any) ([]ClientEvent, error) {
+	rows, err := r.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []ClientEvent
+	for rows.Next() {
+		var (
+			event              ClientEvent
+			occurredAt, fields string
+		)
+		if err := rows.Scan(&event.ID, &event.ClientID, &event.Operation, &occurredAt, &event.Actor, &event.RequestID, &fields); err != nil {
+			return nil, err
+		}
+		if event.OccurredAt, err = time.Parse(time.RFC3339Nano, occurredAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(fields), &event.Fields); err != nil {
+			return nil, err
+		}
+		if len(event.Fields) == 0 {
+			event.Fields = nil
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
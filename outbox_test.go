@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink запоминает опубликованные события и возвращает err, пока
+// он задан.
+type recordingSink struct {
+	events []ClientEvent
+	err    error
+}
+
+func (s *recordingSink) Publish(_ context.Context, event ClientEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+// setupOutbox возвращает БД после миграций и репозиторий с часами clock.
+func setupOutbox(t *testing.T, clock *testutil.FakeClock) (*sql.DB, *Repository) {
+	t.Helper()
+
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+
+	return db, NewRepository(db, WithClock(clock))
+}
+
+// Тест проверяет, что каждое изменение клиента записывает событие в outbox
+// вместе с самим изменением, а откаченное изменение событий не оставляет
+func Test_Outbox_Enqueue(t *testing.T) {
+	ctx := WithActor(context.Background(), "operator")
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, repo := setupOutbox(t, testutil.NewFakeClock(at))
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	cl, err := repo.Select(ctx, id)
+	require.NoError(t, err)
+	cl.Email = "new@mail.com"
+	require.NoError(t, repo.Update(ctx, cl))
+
+	// Пробное удаление откатывается вместе со своим событием
+	_, err = repo.DeleteClients(ctx, []int{id}, DestructiveOptions{DryRun: true})
+	require.NoError(t, err)
+
+	events, err := repo.pendingEvents(ctx, DefaultOutboxBatch)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, ClientEvent{ID: events[0].ID, ClientID: id, Operation: AuditInsert, OccurredAt: at, Actor: "operator",
+		Fields: []string{"birthday", "email", "fio", "login"}}, events[0])
+	assert.Equal(t, ClientEvent{ID: events[1].ID, ClientID: id, Operation: AuditUpdate, OccurredAt: at, Actor: "operator",
+		Fields: []string{"email"}}, events[1])
+	assert.Less(t, events[0].ID, events[1].ID)
+
+	// События не содержат значений полей
+	assertRowCount(t, db, "outbox", 0, "fields LIKE '%new@mail.com%'")
+}
+
+// Тест проверяет публикацию событий по порядку и отметку опубликованных
+func Test_OutboxRelay_Relay(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	db, repo := setupOutbox(t, clock)
+
+	ids := make([]int, 3)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+
+	sink := &recordingSink{}
+	relay := NewOutboxRelay(repo, sink)
+	relay.batch = 2
+
+	n, err := relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.Len(t, sink.events, 3)
+	for i, event := range sink.events {
+		assert.Equal(t, ids[i], event.ClientID)
+	}
+	assertRowCount(t, db, "outbox", 3, "delivered_at = :at AND attempts = 1", sql.Named("at", clock.Now().Format(time.RFC3339Nano)))
+}
+
+// Тест проверяет восстановление после сбоев: неопубликованное событие
+// остаётся в outbox с числом попыток и ошибкой, публикуется повторно
+// новым экземпляром OutboxRelay и не обгоняется следующими событиями
+func Test_OutboxRelay_Retry(t *testing.T) {
+	ctx := context.Background()
+	db, repo := setupOutbox(t, testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)))
+
+	first, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	second, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	sink := &recordingSink{err: errors.New("broker unavailable")}
+	for i := 0; i < 2; i++ {
+		n, err := NewOutboxRelay(repo, sink).Relay(ctx)
+		require.ErrorContains(t, err, "broker unavailable")
+		assert.Zero(t, n)
+	}
+	assertRowCount(t, db, "outbox", 1, "client_id = :id AND attempts = 2 AND last_error = 'broker unavailable' AND delivered_at = ''",
+		sql.Named("id", first))
+	// Следующее событие не публиковалось
+	assertRowCount(t, db, "outbox", 1, "client_id = :id AND attempts = 0", sql.Named("id", second))
+
+	// Перезапуск после восстановления брокера публикует оба события по порядку
+	sink.err = nil
+	n, err := NewOutboxRelay(repo, sink).Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, sink.events, 2)
+	assert.Equal(t, first, sink.events[0].ClientID)
+	assert.Equal(t, second, sink.events[1].ClientID)
+	assertRowCount(t, db, "outbox", 0, "delivered_at = ''")
+	assertRowCount(t, db, "outbox", 1, "client_id = :id AND attempts = 3 AND last_error = ''", sql.Named("id", first))
+}
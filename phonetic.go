@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// phoneticVowels — замена гласных в phoneticKey: безударные гласные на
+// слух различаются плохо, поэтому «о», «ы», «я» сводятся к «а», «е», «ё»,
+// «э», «й» — к «и», «ю» — к «у».
+var phoneticVowels = map[rune]rune{
+	'А': 'А', 'О': 'А', 'Ы': 'А', 'Я': 'А',
+	'И': 'И', 'Е': 'И', 'Ё': 'И', 'Э': 'И', 'Й': 'И',
+	'У': 'У', 'Ю': 'У',
+}
+
+// phoneticDevoiced — звонкие согласные и их глухие пары: перед глухой
+// согласной и в конце слова звонкая произносится как глухая.
+var phoneticDevoiced = map[rune]rune{
+	'Б': 'П', 'В': 'Ф', 'Г': 'К', 'Д': 'Т', 'Ж': 'Ш', 'З': 'С',
+}
+
+// phoneticEndings — окончания фамилий после замены гласных и согласных,
+// которые phoneticKey сводит к одному коду: «-ов» и «-ев», «-ова» и
+// «-ева», «-овский» и «-евский», «-овская» и «-евская».
+var phoneticEndings = []struct {
+	from []string
+	to   string
+}{
+	{[]string{"АФСКИ", "ИФСКИ"}, "@"},
+	{[]string{"АФСКА", "ИФСКА"}, "#"},
+	{[]string{"АВА", "ИВА"}, "9"},
+	{[]string{"АФ", "ИФ"}, "4"},
+}
+
+// phoneticVoiceless сообщает, глухая ли согласная c.
+func phoneticVoiceless(c rune) bool {
+	return strings.ContainsRune("ПФКТШСХЦЧЩ", c)
+}
+
+// phoneticKey возвращает фонетический ключ слова — вариант русского
+// метафона, по которому совпадают фамилии, одинаково звучащие при разном
+// написании: «Смирнов» и «Смернов», «Кузнецов» и «Кузнецоф». В ключе
+// безударные гласные и сочетания «йо», «ио», «йе», «ие» сведены к «а»,
+// «и», «у», звонкие согласные перед глухими и в конце слова оглушены,
+// «тс» и «дс» заменены на «ц», «ь» и «ъ» отброшены, повторы букв схлопнуты,
+// а окончания из phoneticEndings заменены кодом. Буквы, кроме кириллических,
+// не учитываются, поэтому для слова без них ключ пуст.
+func phoneticKey(word string) string {
+	var letters []rune
+	for _, c := range strings.ToUpper(word) {
+		if c == 'Ё' || c >= 'А' && c <= 'Я' {
+			if c != 'Ь' && c != 'Ъ' {
+				letters = append(letters, c)
+			}
+		}
+	}
+
+	var key []rune
+	add := func(c rune) {
+		if len(key) == 0 || key[len(key)-1] != c {
+			key = append(key, c)
+		}
+	}
+	for i := 0; i < len(letters); i++ {
+		c := letters[i]
+		var next rune
+		if i+1 < len(letters) {
+			next = letters[i+1]
+		}
+
+		if v, ok := phoneticVowels[c]; ok {
+			if (c == 'Й' || c == 'И') && (next == 'О' || next == 'Е') {
+				i++
+			}
+			add(v)
+			continue
+		}
+		if d, ok := phoneticDevoiced[c]; ok && (next == 0 || phoneticVoiceless(next)) {
+			c = d
+		}
+		if c == 'С' && len(key) > 0 && key[len(key)-1] == 'Т' {
+			key[len(key)-1] = 'Ц'
+			continue
+		}
+		add(c)
+	}
+
+	s := string(key)
+	for _, e := range phoneticEndings {
+		for _, from := range e.from {
+			if len(s) > len(from) && strings.HasSuffix(s, from) {
+				return strings.TrimSuffix(s, from) + e.to
+			}
+		}
+	}
+
+	return s
+}
+
+// phoneticKeys возвращает фонетические ключи слов текста s; слова без
+// кириллических букв пропускаются.
+func phoneticKeys(s string) []string {
+	var keys []string
+	for _, w := range strings.Fields(s) {
+		if key := phoneticKey(w); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// SearchClientsPhonetic возвращает в порядке ID клиентов, у которых каждое
+// слово name звучит как какое-либо слово ФИО (см. phoneticKey), например
+// «Смирнов» и «Смернов». Кандидаты отбираются по фонетическим ключам
+// client_autocomplete, а клиенты, ключей которых AutocompleteProjector ещё
+// не записал, — по всем клиентам без ключей; совпадение каждого
+// кандидата проверяется по текущему ФИО, поэтому отставание проекции не
+// теряет новых клиентов и не находит клиентов по прежнему ФИО. Клиенты
+// читаются с ограничением по владельцу (см. WithOwnerRestriction) и
+// расшифровываются. Имя без кириллических букв отклоняется с
+// ErrValidation.
+func (r *Repository) SearchClientsPhonetic(ctx context.Context, name string) (_ []Client, err error) {
+	ctx, end := r.startOperation(ctx, "search_phonetic")
+	defer end(&err)
+
+	keys := phoneticKeys(name)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: phonetic search needs a name in Cyrillic, got %q", ErrValidation, name)
+	}
+
+	// Каждое слово name должно совпасть по звучанию с каким-либо словом ФИО
+	var indexed strings.Builder
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		fmt.Fprintf(&indexed, " AND id IN (SELECT client_id FROM client_autocomplete WHERE field = 'fio' AND phonetic = :p%d)", i)
+		args[i] = sql.Named(fmt.Sprintf("p%d", i), key)
+	}
+	cond := " AND (NOT EXISTS (SELECT 1 FROM client_autocomplete a WHERE a.client_id = clients.id) OR (1" + indexed.String() + "))"
+
+	clients := []Client{}
+	err = r.forEach(ctx, cond, args, func(cl Client) error {
+		if soundsLike(cl.FIO, keys) {
+			clients = append(clients, cl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// soundsLike сообщает, есть ли каждый из фонетических ключей keys среди
+// ключей слов fio.
+func soundsLike(fio string, keys []string) bool {
+	words := phoneticKeys(fio)
+	for _, key := range keys {
+		if !slices.Contains(words, key) {
+			return false
+		}
+	}
+
+	return true
+}
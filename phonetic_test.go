@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что распространённые варианты написания русских фамилий
+// дают один фонетический ключ, а разные фамилии — разные
+func Test_PhoneticKey(t *testing.T) {
+	same := [][]string{
+		{"Смирнов", "Смернов", "СМИРНОВ", "смирноф"},
+		{"Кузнецов", "Кузнецоф", "Кузнетсов", "Кузнецев"},
+		{"Соловьёв", "Соловьев", "Саловьев"},
+		{"Сергеев", "Сергиев"},
+		{"Достоевский", "Дастаевский", "Достоевскей"},
+		{"Иванова", "Иванава", "Ивонова"},
+		{"Ткаченко", "Ткачинко"},
+		{"Лебедев", "Лебидев", "Лебедеф"},
+		{"Бабкин", "Бапкин"},
+		{"Шевчук", "Шевчюк"},
+	}
+	for _, group := range same {
+		want := phoneticKey(group[0])
+		require.NotEmpty(t, want)
+		for _, name := range group[1:] {
+			assert.Equal(t, want, phoneticKey(name), "%s and %s should sound alike", group[0], name)
+		}
+	}
+
+	different := [][2]string{
+		{"Смирнов", "Смирнова"},
+		{"Смирнов", "Семенов"},
+		{"Иванов", "Ильин"},
+		{"Петров", "Петрищев"},
+	}
+	for _, pair := range different {
+		assert.NotEqual(t, phoneticKey(pair[0]), phoneticKey(pair[1]), "%s and %s", pair[0], pair[1])
+	}
+
+	assert.Equal(t, "СМИРН4", phoneticKey("Смирнов"))
+	assert.Empty(t, phoneticKey("smirnov"))
+	assert.Empty(t, phoneticKey("ЬЪ"))
+}
+
+// clientIDs возвращает ID клиентов clients.
+func clientIDs(clients []Client) []int {
+	ids := []int{}
+	for _, cl := range clients {
+		ids = append(ids, cl.ID)
+	}
+	return ids
+}
+
+// Тест проверяет поиск клиентов по звучанию фамилии и имени, пропуск
+// удалённых клиентов и обновление ключа при изменении ФИО
+func Test_SearchClientsPhonetic(t *testing.T) {
+	ctx := context.Background()
+	_, repo, ids := setupAutocomplete(t,
+		newTestClient(func(cl *Client) { cl.FIO = "Смирнов Алексей Петрович" }),
+		newTestClient(func(cl *Client) { cl.FIO = "Смирнова Ольга" }),
+		newTestClient(func(cl *Client) { cl.FIO = "Смернов Алексей" }),
+		newTestClient(func(cl *Client) { cl.FIO = "Кузнецов Олег" }),
+		newTestClient(func(cl *Client) { cl.FIO = "Соловьёв Пётр" }),
+	)
+	smirnov, smirnova, smernov, kuznetsov, solovyov := ids[0], ids[1], ids[2], ids[3], ids[4]
+
+	search := func(name string) []int {
+		t.Helper()
+		clients, err := repo.SearchClientsPhonetic(ctx, name)
+		require.NoError(t, err)
+		return clientIDs(clients)
+	}
+
+	assert.Equal(t, []int{smirnov, smernov}, search("Смирнов"))
+	assert.Equal(t, []int{smirnov, smernov}, search("смернов"))
+	assert.Equal(t, []int{smirnova}, search("Смирнова"))
+	assert.Equal(t, []int{kuznetsov}, search("Кузнецоф"))
+	assert.Equal(t, []int{solovyov}, search("Соловьев Петр"))
+	assert.Equal(t, []int{smirnov, smernov}, search("Алексей Смирноф"))
+	assert.Equal(t, []int{smirnov}, search("Смирнов Петрович"))
+	assert.Equal(t, []int{}, search("Смирнов Олег"))
+
+	clients, err := repo.SearchClientsPhonetic(ctx, "Кузнецов")
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	assert.Equal(t, "Кузнецов Олег", clients[0].FIO)
+
+	projector := NewAutocompleteProjector(repo)
+	cl, err := repo.Select(ctx, smernov)
+	require.NoError(t, err)
+	cl.FIO = "Соловьев Алексей"
+	require.NoError(t, repo.Update(ctx, cl))
+	require.NoError(t, projector.Publish(ctx, ClientEvent{ClientID: smernov}))
+	assert.Equal(t, []int{smirnov}, search("Смирнов"))
+	assert.Equal(t, []int{smernov, solovyov}, search("Саловьев"))
+
+	require.NoError(t, repo.Delete(ctx, solovyov))
+	assert.Equal(t, []int{smernov}, search("Саловьев"), "deleted clients should not be found")
+
+	for _, name := range []string{"", " ", "smirnov"} {
+		_, err := repo.SearchClientsPhonetic(ctx, name)
+		assert.ErrorIs(t, err, ErrValidation, "%q", name)
+	}
+}
+
+// Тест проверяет, что клиенты, ключи которых проекция ещё не записала,
+// находятся по ФИО, а клиент с изменённым ФИО не находится по прежнему
+func Test_SearchClientsPhonetic_Unprojected(t *testing.T) {
+	ctx := context.Background()
+	db, repo, ids := setupAutocomplete(t, newTestClient(func(cl *Client) { cl.FIO = "Смирнов Алексей" }))
+	smirnov := ids[0]
+
+	smernov, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Смернов Олег" }))
+	require.NoError(t, err)
+	assertRowCount(t, db, "client_autocomplete", 0, "client_id = ?", smernov)
+
+	found, err := repo.SearchClientsPhonetic(ctx, "Смирнов")
+	require.NoError(t, err)
+	assert.Equal(t, []int{smirnov, smernov}, clientIDs(found), "a client without keys should be found by FIO")
+
+	cl, err := repo.Select(ctx, smirnov)
+	require.NoError(t, err)
+	cl.FIO = "Кузнецов Алексей"
+	require.NoError(t, repo.Update(ctx, cl))
+	found, err = repo.SearchClientsPhonetic(ctx, "Смирнов")
+	require.NoError(t, err)
+	assert.Equal(t, []int{smernov}, clientIDs(found), "stale keys should not match a changed FIO")
+}
+
+// Тест проверяет, что найденные клиенты расшифровываются, а при
+// ограничении по владельцу находятся только свои клиенты
+func Test_SearchClientsPhonetic_Access(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithOwnerRestriction(), WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	owner := WithPrincipal(ctx, Principal{ID: "manager-1"})
+	stranger := WithPrincipal(ctx, Principal{ID: "manager-2"})
+	admin := WithPrincipal(ctx, Principal{ID: "root", Admin: true})
+
+	own, err := repo.Insert(owner, newTestClient(func(cl *Client) { cl.FIO = "Смирнов Алексей"; cl.Email = "smirnov@mail.com" }))
+	require.NoError(t, err)
+	other, err := repo.Insert(stranger, newTestClient(func(cl *Client) { cl.FIO = "Смернов Олег" }))
+	require.NoError(t, err)
+	_, err = repo.RebuildAutocomplete(admin)
+	require.NoError(t, err)
+
+	found, err := repo.SearchClientsPhonetic(owner, "Смирнов")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, own, found[0].ID)
+	assert.Equal(t, "smirnov@mail.com", found[0].Email, "the email should be decrypted")
+	assert.Equal(t, "19700101", found[0].Birthday)
+
+	found, err = repo.SearchClientsPhonetic(admin, "Смирнов")
+	require.NoError(t, err)
+	assert.Equal(t, []int{own, other}, clientIDs(found))
+
+	found, err = repo.SearchClientsPhonetic(ctx, "Смирнов")
+	require.NoError(t, err)
+	assert.Empty(t, found, "no clients should be found without a principal")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// preferenceKeyRe — допустимые ключи настроек. Ключ подставляется в путь
+// JSON ($.key), поэтому точки, кавычки и скобки в нём запрещены.
+var preferenceKeyRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+func preferencePath(key string) (string, error) {
+	if !preferenceKeyRe.MatchString(key) {
+		return "", fmt.Errorf("%w: invalid preference key %q", ErrValidation, key)
+	}
+
+	return "$." + key, nil
+}
+
+// GetPreference возвращает значение настройки key клиента id, прочитанное
+// в тип T. Если настройка не задана, ok равно false. Значение, которое
+// нельзя прочитать в T, возвращает ErrValidation.
+func GetPreference[T any](ctx context.Context, r *Repository, id int, key string) (_ T, ok bool, err error) {
+	ctx, end := r.startClientOperation(ctx, "get_preference", id)
+	defer end(&err)
+
+	var value T
+	if _, err := preferencePath(key); err != nil {
+		return value, false, err
+	}
+
+	prefs, err := r.preferences(ctx, r.conn(), id)
+	if err != nil {
+		return value, false, err
+	}
+
+	raw, ok := prefs[key]
+	if !ok {
+		return value, false, nil
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return value, false, fmt.Errorf("%w: preference %q: %v", ErrValidation, key, err)
+	}
+
+	return value, true, nil
+}
+
+// SetPreference записывает значение настройки key клиента id. Изменение
+// записывается в журнал аудита как поле preferences.key.
+func SetPreference[T any](ctx context.Context, r *Repository, id int, key string, value T) (err error) {
+	ctx, end := r.startClientOperation(ctx, "set_preference", id)
+	defer end(&err)
+
+	path, err := preferencePath(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%w: preference %q: %v", ErrValidation, key, err)
+	}
+
+	return r.updatePreference(ctx, id, key, "json_set(preferences, :path, json(:value))", data,
+		sql.Named("path", path), sql.Named("value", string(data)))
+}
+
+// DeletePreference удаляет настройку key клиента id; удаление незаданной
+// настройки ничего не меняет.
+func (r *Repository) DeletePreference(ctx context.Context, id int, key string) (err error) {
+	ctx, end := r.startClientOperation(ctx, "delete_preference", id)
+	defer end(&err)
+
+	path, err := preferencePath(key)
+	if err != nil {
+		return err
+	}
+
+	return r.updatePreference(ctx, id, key, "json_remove(preferences, :path)", nil, sql.Named("path", path))
+}
+
+// updatePreference заменяет настройки клиента выражением expr и записывает
+// в журнал аудита смену значения key на after (nil — настройка удалена).
+func (r *Repository) updatePreference(ctx context.Context, id int, key, expr string, after json.RawMessage, args ...any) error {
+	return r.inTx(ctx, func(q querier) error {
+		prefs, err := r.preferences(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		var change FieldChange
+		if before, ok := prefs[key]; ok {
+			s := string(before)
+			change.Before = &s
+		}
+		if after != nil {
+			s := string(after)
+			change.After = &s
+		}
+		if change.Before == nil && change.After == nil ||
+			change.Before != nil && change.After != nil && *change.Before == *change.After {
+			return nil
+		}
+
+		_, err = q.ExecContext(ctx, "UPDATE clients SET preferences = "+expr+", version = version + 1 WHERE id = :id", append(args, sql.Named("id", id))...)
+		if err != nil {
+			return err
+		}
+
+		return r.auditDiff(ctx, q, AuditPreferences, id, map[string]FieldChange{"preferences." + key: change})
+	})
+}
+
+// preferences читает настройки клиента или возвращает ErrClientNotFound.
+func (r *Repository) preferences(ctx context.Context, q querier, id int) (map[string]json.RawMessage, error) {
+	scope, args := r.ownerScope(ctx)
+	args = append(args, sql.Named("id", id))
+
+	var data string
+	err := q.QueryRowContext(ctx, "SELECT preferences FROM clients WHERE id = :id"+notDeleted+scope, args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &prefs); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+// ClientsByPreference вызывает fn для каждого клиента, у которого настройка
+// key равна value, в порядке возрастания ID. Сравнение выполняется в БД
+// функциями JSON1, например для поиска подписанных на рассылку:
+//
+//	repo.ClientsByPreference(ctx, "newsletter", true, fn)
+func (r *Repository) ClientsByPreference(ctx context.Context, key string, value any, fn func(Client) error) (err error) {
+	ctx, end := r.startOperation(ctx, "clients_by_preference")
+	defer end(&err)
+
+	path, err := preferencePath(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%w: preference %q: %v", ErrValidation, key, err)
+	}
+
+	cond := " AND json_extract(preferences, :pref_path) = json_extract(:pref_value, '$')"
+
+	return r.forEach(ctx, cond, []any{sql.Named("pref_path", path), sql.Named("pref_value", string(data))}, fn)
+}
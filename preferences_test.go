@@ -0,0 +1,170 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contactPreferences — пример составной настройки
+type contactPreferences struct {
+	Channel string `json:"channel"`
+	Hours   []int  `json:"hours"`
+}
+
+// Тест проверяет запись и чтение настроек разных типов, перезапись,
+// удаление и отсутствующую настройку
+func Test_Preferences_GetSet(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	_, ok, err := GetPreference[bool](ctx, repo, id, "newsletter")
+	require.NoError(t, err)
+	assert.False(t, ok, "new client should have no preferences")
+
+	contact := contactPreferences{Channel: "sms", Hours: []int{9, 18}}
+	require.NoError(t, SetPreference(ctx, repo, id, "newsletter", true))
+	require.NoError(t, SetPreference(ctx, repo, id, "language", "ru"))
+	require.NoError(t, SetPreference(ctx, repo, id, "discount", 15))
+	require.NoError(t, SetPreference(ctx, repo, id, "contact", contact))
+
+	newsletter, ok, err := GetPreference[bool](ctx, repo, id, "newsletter")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, newsletter)
+	language, _, err := GetPreference[string](ctx, repo, id, "language")
+	require.NoError(t, err)
+	assert.Equal(t, "ru", language)
+	discount, _, err := GetPreference[int](ctx, repo, id, "discount")
+	require.NoError(t, err)
+	assert.Equal(t, 15, discount)
+	gotContact, _, err := GetPreference[contactPreferences](ctx, repo, id, "contact")
+	require.NoError(t, err)
+	assert.Equal(t, contact, gotContact)
+
+	require.NoError(t, SetPreference(ctx, repo, id, "language", "en"))
+	language, _, err = GetPreference[string](ctx, repo, id, "language")
+	require.NoError(t, err)
+	assert.Equal(t, "en", language)
+
+	require.NoError(t, repo.DeletePreference(ctx, id, "language"))
+	_, ok, err = GetPreference[string](ctx, repo, id, "language")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, repo.DeletePreference(ctx, id, "language"), "deleting a missing preference should succeed")
+
+	// Настройки не мешают обычной работе с клиентом
+	client, err := repo.Select(ctx, id)
+	require.NoError(t, err)
+	client.Login = "changed"
+	require.NoError(t, repo.Update(ctx, client))
+	newsletter, _, err = GetPreference[bool](ctx, repo, id, "newsletter")
+	require.NoError(t, err)
+	assert.True(t, newsletter)
+}
+
+// Тест проверяет ошибки: некорректный ключ, несовпадение типа и
+// отсутствующий клиент
+func Test_Preferences_Errors(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	require.NoError(t, SetPreference(ctx, repo, id, "language", "ru"))
+
+	for _, key := range []string{"", "a.b", "$.x", `a"b`, "1st", "ключ"} {
+		require.ErrorIs(t, SetPreference(ctx, repo, id, key, true), ErrValidation, "key %q", key)
+		_, _, err := GetPreference[bool](ctx, repo, id, key)
+		require.ErrorIs(t, err, ErrValidation, "key %q", key)
+	}
+
+	_, _, err = GetPreference[bool](ctx, repo, id, "language")
+	require.ErrorIs(t, err, ErrValidation, "string preference should not be read as bool")
+	require.ErrorIs(t, SetPreference(ctx, repo, id, "callback", func() {}), ErrValidation)
+
+	_, _, err = GetPreference[bool](ctx, repo, -1, "newsletter")
+	require.ErrorIs(t, err, ErrClientNotFound)
+	require.ErrorIs(t, SetPreference(ctx, repo, -1, "newsletter", true), ErrClientNotFound)
+	require.ErrorIs(t, repo.DeletePreference(ctx, -1, "newsletter"), ErrClientNotFound)
+}
+
+// Тест проверяет, что изменения настроек записываются в журнал аудита,
+// а запись того же значения — нет
+func Test_Preferences_Audit(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	require.NoError(t, SetPreference(ctx, repo, id, "newsletter", true))
+	require.NoError(t, SetPreference(ctx, repo, id, "newsletter", true))
+	require.NoError(t, SetPreference(ctx, repo, id, "newsletter", false))
+	require.NoError(t, repo.DeletePreference(ctx, id, "newsletter"))
+
+	entries, err := repo.AuditLog(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, entries, 4, "insert and three preference changes")
+	for _, entry := range entries[1:] {
+		assert.Equal(t, AuditPreferences, entry.Operation)
+	}
+	assert.Equal(t, map[string]FieldChange{"preferences.newsletter": {After: strPtr("true")}}, entries[1].Diff)
+	assert.Equal(t, map[string]FieldChange{"preferences.newsletter": {Before: strPtr("true"), After: strPtr("false")}}, entries[2].Diff)
+	assert.Equal(t, map[string]FieldChange{"preferences.newsletter": {Before: strPtr("false")}}, entries[3].Diff)
+}
+
+// Тест проверяет отбор клиентов по значению настройки средствами JSON1
+func Test_ClientsByPreference(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	ids := make([]int, 4)
+	var err error
+	for i := range ids {
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	subscribed, unsubscribed, other, unset := ids[0], ids[1], ids[2], ids[3]
+	require.NoError(t, SetPreference(ctx, repo, subscribed, "newsletter", true))
+	require.NoError(t, SetPreference(ctx, repo, subscribed, "language", "ru"))
+	require.NoError(t, SetPreference(ctx, repo, unsubscribed, "newsletter", false))
+	require.NoError(t, SetPreference(ctx, repo, other, "language", "ru"))
+	require.NoError(t, SetPreference(ctx, repo, other, "contact", contactPreferences{Channel: "sms", Hours: []int{9}}))
+
+	matching := func(key string, value any) []int {
+		t.Helper()
+		var found []int
+		require.NoError(t, repo.ClientsByPreference(ctx, key, value, func(cl Client) error {
+			if cl.ID >= subscribed {
+				found = append(found, cl.ID)
+			}
+			return nil
+		}))
+		return found
+	}
+
+	assert.Equal(t, []int{subscribed}, matching("newsletter", true))
+	assert.Equal(t, []int{unsubscribed}, matching("newsletter", false))
+	assert.Equal(t, []int{subscribed, other}, matching("language", "ru"))
+	assert.Equal(t, []int{other}, matching("contact", contactPreferences{Channel: "sms", Hours: []int{9}}))
+	assert.Empty(t, matching("language", "en"))
+	assert.NotContains(t, matching("newsletter", true), unset)
+
+	require.ErrorIs(t, repo.ClientsByPreference(ctx, "a.b", true, func(Client) error { return nil }), ErrValidation)
+}
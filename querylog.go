@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// redactedValue подставляется в журнал вместо значений параметров,
+// которых нет в списке разрешённых.
+const redactedValue = "[REDACTED]"
+
+// defaultLoggableParams — параметры запросов, значения которых не являются
+// персональными данными и по умолчанию пишутся в журнал как есть.
+var defaultLoggableParams = []string{"id", "client_id", "owner_id", "operation", "request_id"}
+
+var defaultLoggable = func() map[string]bool {
+	allow := make(map[string]bool, len(defaultLoggableParams))
+	for _, name := range defaultLoggableParams {
+		allow[name] = true
+	}
+
+	return allow
+}()
+
+// queryLogger пишет выполненные запросы в slog.
+type queryLogger struct {
+	logger       *slog.Logger
+	loggable     map[string]bool
+	successLevel slog.Level
+	failureLevel slog.Level
+}
+
+// WithQueryLogger включает журналирование выполняемых запросов в logger.
+// Для каждого запроса пишутся операция репозитория, текст SQL, параметры,
+// длительность, число затронутых строк и ошибка.
+//
+// Значения параметров заменяются на [REDACTED], кроме параметров из
+// loggable (по умолчанию — defaultLoggableParams), поэтому ФИО, email и
+// даты рождения в журнал не попадают ни при успехе, ни при ошибке.
+func WithQueryLogger(logger *slog.Logger, loggable ...string) Option {
+	if len(loggable) == 0 {
+		loggable = defaultLoggableParams
+	}
+
+	allow := make(map[string]bool, len(loggable))
+	for _, name := range loggable {
+		allow[name] = true
+	}
+
+	return func(r *Repository) {
+		r.queryLog = &queryLogger{
+			logger:       logger,
+			loggable:     allow,
+			successLevel: slog.LevelDebug,
+			failureLevel: slog.LevelError,
+		}
+		r.hooks = append(r.hooks, r.queryLog)
+	}
+}
+
+// WithQueryLogLevels задаёт уровни журнала для успешных и неуспешных
+// запросов (по умолчанию DEBUG и ERROR). Действует вместе с WithQueryLogger
+// и должна указываться после неё.
+func WithQueryLogLevels(success, failure slog.Level) Option {
+	return func(r *Repository) {
+		if r.queryLog != nil {
+			r.queryLog.successLevel = success
+			r.queryLog.failureLevel = failure
+		}
+	}
+}
+
+func (l *queryLogger) afterQuery(ctx context.Context, ev queryEvent) {
+	attrs := []slog.Attr{
+		slog.String("op", ev.Op),
+		slog.String("sql", ev.SQL),
+		slog.Group("args", redactArgs(ev.Args, l.loggable)...),
+		slog.Duration("duration", ev.Duration),
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if ev.Rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", ev.Rows))
+	}
+
+	if ev.Err != nil {
+		attrs = append(attrs, slog.String("error", ev.Err.Error()))
+		l.logger.LogAttrs(ctx, l.failureLevel, "query failed", attrs...)
+		return
+	}
+
+	l.logger.LogAttrs(ctx, l.successLevel, "query", attrs...)
+}
+
+// redactArgs возвращает параметры запроса в виде атрибутов журнала.
+// Позиционные параметры получают имена $1, $2, ... и всегда скрываются.
+func redactArgs(args []any, loggable map[string]bool) []any {
+	attrs := make([]any, 0, len(args))
+	for i, arg := range args {
+		name := fmt.Sprintf("$%d", i+1)
+		value := arg
+		if named, ok := arg.(sql.NamedArg); ok {
+			name, value = named.Name, named.Value
+		}
+
+		if !loggable[name] || name[0] == '$' {
+			value = redactedValue
+		}
+		attrs = append(attrs, slog.Any(name, value))
+	}
+
+	return attrs
+}
@@ -0,0 +1,61 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что при успешных запросах значения PII скрываются,
+// а разрешённые параметры пишутся как есть
+func Test_QueryLogger_RedactsOnSuccess(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	_, repo := setupTestDB(t, WithQueryLogger(newTestLogger(&buf)))
+
+	id, err := repo.Insert(ctx, loggedClient)
+	require.NoError(t, err, "error inserting client: %v", err)
+
+	_, err = repo.Select(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, id))
+
+	out := buf.String()
+	assert.Contains(t, out, "INSERT INTO clients", "insert should be logged")
+	assert.Contains(t, out, "args.fio="+redactedValue)
+	assert.Contains(t, out, "args.email="+redactedValue)
+	assert.Contains(t, out, "args.id=", "id is loggable by default")
+	assertNoPII(t, out)
+}
+
+// Тест проверяет поля структурированной записи для успешного запроса
+func Test_QueryLogger_SuccessFields(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, repo := setupTestDB(t, WithQueryLogger(logger), WithQueryLogLevels(slog.LevelInfo, slog.LevelWarn))
+
+	_, err := repo.Insert(ctx, loggedClient)
+	require.NoError(t, err, "error inserting client: %v", err)
+
+	records := readLogRecords(t, &buf)
+	require.NotEmpty(t, records)
+
+	insert := records[0]
+	assert.Equal(t, "query", insert["msg"])
+	assert.Equal(t, "INFO", insert["level"], "success level should be configurable")
+	assert.Equal(t, "insert", insert["op"])
+	assert.Contains(t, insert["sql"], "INSERT INTO clients")
+	assert.EqualValues(t, 1, insert["rows"], "rows affected should be logged for exec")
+	assert.Contains(t, insert, "duration")
+	assert.NotContains(t, insert, "error")
+}
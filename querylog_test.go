@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Персональные данные, которые не должны попасть в журнал
+var loggedClient = Client{
+	FIO:      "Журналов Тест Редактович",
+	Login:    "redacted_login",
+	Birthday: "19691231",
+	Email:    "redacted369@mail.com",
+}
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func assertNoPII(t *testing.T, out string) {
+	t.Helper()
+
+	for _, value := range []string{loggedClient.FIO, loggedClient.Login, loggedClient.Birthday, loggedClient.Email} {
+		assert.NotContains(t, out, value, "PII leaked into query log")
+	}
+}
+
+// Тест проверяет, что значения PII скрываются и в журнале ошибок
+func Test_QueryLogger_RedactsOnError(t *testing.T) {
+	// База без схемы: любой запрос к clients завершится ошибкой
+	db := openMemoryDB(t)
+
+	var buf bytes.Buffer
+	repo := NewRepository(db, WithQueryLogger(newTestLogger(&buf)))
+
+	_, err := repo.Insert(context.Background(), loggedClient)
+	require.Error(t, err, "insert into missing table should fail")
+
+	out := buf.String()
+	assert.Contains(t, out, "query failed")
+	assert.Contains(t, out, "level=ERROR")
+	assertNoPII(t, out)
+}
+
+// Тест проверяет настройку списка разрешённых параметров
+func Test_RedactArgs_Allowlist(t *testing.T) {
+	args := []any{sql.Named("id", 7), sql.Named("email", "a@b.c"), "positional"}
+
+	attrs := redactArgs(args, map[string]bool{"email": true})
+	require.Len(t, attrs, 3)
+	assert.Equal(t, slog.Any("id", redactedValue), attrs[0], "id is not in the custom allowlist")
+	assert.Equal(t, slog.Any("email", "a@b.c"), attrs[1])
+	assert.Equal(t, slog.Any("$3", redactedValue), attrs[2], "positional args are always redacted")
+}
+
+// readLogRecords разбирает вывод slog.JSONHandler на отдельные записи
+func readLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var rec map[string]any
+		require.NoError(t, dec.Decode(&rec), "log output is not valid JSON")
+		records = append(records, rec)
+	}
+
+	return records
+}
+
+// Тест проверяет поля структурированной записи для запроса с ошибкой
+func Test_QueryLogger_FailureFields(t *testing.T) {
+	db := openMemoryDB(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	repo := NewRepository(db, WithQueryLogger(logger), WithQueryLogLevels(slog.LevelDebug, slog.LevelWarn))
+
+	_, err := repo.Select(context.Background(), 1)
+	require.Error(t, err)
+
+	records := readLogRecords(t, &buf)
+	require.Len(t, records, 1, "only the failing query should pass the INFO handler level")
+
+	failed := records[0]
+	assert.Equal(t, "query failed", failed["msg"])
+	assert.Equal(t, "WARN", failed["level"])
+	assert.Equal(t, "select", failed["op"])
+	assert.Contains(t, failed["error"], "no such table")
+	assert.Equal(t, map[string]any{"id": float64(1)}, failed["args"])
+	assert.Contains(t, failed, "duration")
+	assert.NotContains(t, failed, "rows", "rows are unknown for failed queries")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+)
+
+// ReadinessStatus — ответ ReadinessHandler.
+type ReadinessStatus struct {
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+	// SchemaDrift — расхождения схемы БД с миграциями текущего кода.
+	SchemaDrift []SchemaDifference `json:"schema_drift,omitempty"`
+}
+
+// ReadinessHandler возвращает обработчик проверки готовности: БД должна
+// отвечать, а её схема — совпадать со схемой миграций текущего кода
+// (SchemaDrift). Готовое приложение получает 200, иначе — 503 с причиной
+// и расхождениями схемы в JSON, поэтому экземпляр с неприменёнными или
+// изменёнными вручную миграциями не получает трафик.
+func ReadinessHandler(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := readiness(r, db)
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+func readiness(r *http.Request, db *sql.DB) ReadinessStatus {
+	ctx := r.Context()
+	if err := db.PingContext(ctx); err != nil {
+		return ReadinessStatus{Error: err.Error()}
+	}
+
+	report, err := SchemaDrift(ctx, db)
+	if err != nil {
+		return ReadinessStatus{Error: err.Error()}
+	}
+	if !report.OK() {
+		return ReadinessStatus{Error: ErrSchemaDrift.Error(), SchemaDrift: report.Differences}
+	}
+
+	return ReadinessStatus{Ready: true}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что проверка готовности проходит только при схеме,
+// совпадающей с миграциями, и возвращает расхождения схемы
+func Test_ReadinessHandler(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	_, err := db.Exec("ALTER TABLE audit_log DROP COLUMN request_id")
+	require.NoError(t, err)
+
+	// probe выполняет проверку и возвращает код и разобранный ответ
+	probe := func() (int, ReadinessStatus) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ReadinessHandler(db).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var status ReadinessStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		return rec.Code, status
+	}
+
+	code, status := probe()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, status.Ready)
+	assert.Equal(t, ErrSchemaDrift.Error(), status.Error)
+	require.Len(t, status.SchemaDrift, 1)
+	assert.Equal(t, "audit_log.request_id", status.SchemaDrift[0].Name)
+
+	_, err = db.Exec(`ALTER TABLE audit_log ADD COLUMN request_id TEXT NOT NULL DEFAULT ""`)
+	require.NoError(t, err)
+	code, status = probe()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ReadinessStatus{Ready: true}, status)
+
+	require.NoError(t, db.Close())
+	code, status = probe()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, status.Error, "closed")
+}
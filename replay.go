@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReplayEvents повторно публикует в sink события об изменениях клиентов,
+// произошедших в интервале [from, to), и возвращает число опубликованных
+// событий; нулевая граница не ограничивает интервал. Так новые
+// потребители загружают историю. События публикуются в порядке
+// фиксации изменений: сначала восстановленные по журналу аудита изменения,
+// записанные до появления outbox (их ID — отрицательный ID записи
+// журнала), затем события outbox по возрастанию ID — с теми же ID, что и
+// при обычной публикации, поэтому потребитель отбрасывает уже полученные.
+// Публикация прерывается на первой ошибке sink; повторный вызов публикует
+// интервал с начала. Состояние публикации outbox ReplayEvents не меняет.
+func (r *Repository) ReplayEvents(ctx context.Context, from, to time.Time, sink EventSink) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "replay_events")
+	defer end(&err)
+
+	window := ""
+	var args []any
+	if !from.IsZero() {
+		window += " AND julianday(occurred_at) >= julianday(:from)"
+		args = append(args, sql.Named("from", from.UTC().Format(time.RFC3339Nano)))
+	}
+	if !to.IsZero() {
+		window += " AND julianday(occurred_at) < julianday(:to)"
+		args = append(args, sql.Named("to", to.UTC().Format(time.RFC3339Nano)))
+	}
+
+	// Записи журнала до первого события outbox; имена полей берутся из
+	// ключей разницы
+	history := `SELECT -id, client_id, operation, occurred_at, actor, request_id,
+	(SELECT json_group_array(key) FROM (SELECT key FROM json_each(diff) WHERE key IS NOT NULL ORDER BY key))
+	FROM audit_log WHERE -id < :after
+	AND (NOT EXISTS (SELECT 1 FROM outbox) OR julianday(occurred_at) < (SELECT julianday(occurred_at) FROM outbox ORDER BY id LIMIT 1))` +
+		window + ` ORDER BY id LIMIT :limit`
+	outbox := `SELECT id, client_id, operation, occurred_at, actor, request_id, fields
+	FROM outbox WHERE id > :after` + window + ` ORDER BY id LIMIT :limit`
+
+	replayed := 0
+	for _, query := range []string{history, outbox} {
+		// Запросы выбирают события после after в порядке публикации
+		var after int64
+		for {
+			// События читаются порциями, чтобы sink мог писать в ту же БД
+			events, err := r.queryEvents(ctx, query, append(args, sql.Named("after", after), sql.Named("limit", DefaultOutboxBatch))...)
+			if err != nil {
+				return replayed, err
+			}
+			for _, event := range events {
+				if err := sink.Publish(ctx, event); err != nil {
+					return replayed, fmt.Errorf("replay event %d: %w", event.ID, err)
+				}
+				replayed++
+			}
+			if len(events) < DefaultOutboxBatch {
+				break
+			}
+			after = events[len(events)-1].ID
+		}
+	}
+
+	return replayed, nil
+}
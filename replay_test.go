@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет повторную публикацию событий известного интервала с
+// прежними ID в порядке изменений без изменения состояния outbox
+func Test_ReplayEvents(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(start)
+	db, repo := setupOutbox(t, clock)
+
+	// По одному изменению в час: вставка, изменение, изменение, удаление
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	for _, fio := range []string{"Second", "Third"} {
+		clock.Advance(time.Hour)
+		cl, err := repo.Select(ctx, id)
+		require.NoError(t, err)
+		cl.FIO = fio
+		require.NoError(t, repo.Update(ctx, cl))
+	}
+	clock.Advance(time.Hour)
+	require.NoError(t, repo.Delete(ctx, id))
+
+	live, err := repo.pendingEvents(ctx, DefaultOutboxBatch)
+	require.NoError(t, err)
+	require.Len(t, live, 4)
+	// Уже опубликованные события тоже воспроизводятся
+	_, err = NewOutboxRelay(repo, &recordingSink{}).Relay(ctx)
+	require.NoError(t, err)
+
+	sink := &recordingSink{}
+	n, err := repo.ReplayEvents(ctx, start.Add(time.Hour), start.Add(3*time.Hour), sink)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, live[1:3], sink.events)
+
+	// Нулевые границы не ограничивают интервал
+	sink = &recordingSink{}
+	n, err = repo.ReplayEvents(ctx, time.Time{}, time.Time{}, sink)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, live, sink.events)
+
+	sink = &recordingSink{}
+	n, err = repo.ReplayEvents(ctx, start.Add(3*time.Hour), time.Time{}, sink)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, AuditDelete, sink.events[0].Operation)
+
+	assertRowCount(t, db, "outbox", 4, "delivered_at != '' AND attempts = 1")
+}
+
+// Тест проверяет воспроизведение изменений, записанных в журнал аудита до
+// появления outbox: они публикуются первыми с отрицательными ID
+func Test_ReplayEvents_AuditHistory(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, MigrateTo(ctx, db, 18))
+
+	// История до появления outbox
+	for i, entry := range []struct {
+		at, op, diff string
+	}{
+		{"2023-01-01T10:00:00Z", "insert", `{"fio":{"after":"A"},"email":{"after":"a@mail.com"}}`},
+		{"2023-01-02T10:00:00Z", "update", `{"email":{"before":"a@mail.com","after":"b@mail.com"}}`},
+		{"2023-01-03T10:00:00Z", "consent", `null`},
+	} {
+		_, err := db.ExecContext(ctx, `INSERT INTO audit_log (actor, occurred_at, operation, client_id, diff, request_id)
+	VALUES ('operator', :at, :op, 1, :diff, '')`,
+			sql.Named("at", entry.at), sql.Named("op", entry.op), sql.Named("diff", entry.diff))
+		require.NoError(t, err, "entry %d", i)
+	}
+
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, WithClock(testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))))
+	_, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	sink := &recordingSink{}
+	n, err := repo.ReplayEvents(ctx, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), time.Time{}, sink)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	require.Len(t, sink.events, 3)
+	assert.Equal(t, ClientEvent{ID: -2, ClientID: 1, Operation: AuditUpdate, OccurredAt: time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC),
+		Actor: "operator", Fields: []string{"email"}}, sink.events[0])
+	assert.Equal(t, int64(-3), sink.events[1].ID)
+	assert.Nil(t, sink.events[1].Fields)
+	assert.Equal(t, AuditInsert, sink.events[2].Operation)
+	assert.Positive(t, sink.events[2].ID)
+}
+
+// Тест проверяет, что ошибка получателя прерывает воспроизведение, не
+// нарушая порядка событий
+func Test_ReplayEvents_SinkError(t *testing.T) {
+	ctx := context.Background()
+	_, repo := setupOutbox(t, testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)))
+	for i := 0; i < 3; i++ {
+		_, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+
+	sink := &failingAfterSink{limit: 2}
+	n, err := repo.ReplayEvents(ctx, time.Time{}, time.Time{}, sink)
+	require.ErrorContains(t, err, "consumer unavailable")
+	assert.Equal(t, 2, n)
+	require.Len(t, sink.events, 2)
+	assert.Less(t, sink.events[0].ID, sink.events[1].ID)
+}
+
+// failingAfterSink принимает limit событий, а затем возвращает ошибку.
+type failingAfterSink struct {
+	recordingSink
+	limit int
+}
+
+func (s *failingAfterSink) Publish(ctx context.Context, event ClientEvent) error {
+	if len(s.events) >= s.limit {
+		return errors.New("consumer unavailable")
+	}
+
+	return s.recordingSink.Publish(ctx, event)
+}
@@ -0,0 +1,594 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/clientsdb"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Repository — слой доступа к таблице clients. В отличие от функций
+// selectClient/insertClient/deleteClient поддерживает контекст и
+// дополнительные (опциональные) возможности, подключаемые через Option.
+// Все изменения клиентов записываются в журнал аудита.
+type Repository struct {
+	db              *sql.DB
+	tx              *sql.Tx
+	cipher          *fieldCipher
+	ownerRestricted bool
+	queryLog        *queryLogger
+	hooks           []queryHook
+	tracer          trace.Tracer
+	slowThreshold   time.Duration
+	metrics         *dbMetrics
+	retryPolicy     RetryPolicy
+	clock           Clock
+	ids             IDGenerator
+	blobs           BlobStore
+	birthdayLoc     *time.Location
+	leapDay         LeapDayPolicy
+	schemaDriftLog  *slog.Logger
+}
+
+// ClientRepository — основные операции с клиентами. Реализуется Repository
+// и позволяет подменять его в вышестоящих слоях, например обёрткой
+// testutil.FaultyRepository, внедряющей отказы.
+type ClientRepository interface {
+	Select(ctx context.Context, id int) (Client, error)
+	Insert(ctx context.Context, client Client) (int, error)
+	Update(ctx context.Context, client Client) error
+	Delete(ctx context.Context, id int) error
+}
+
+var _ ClientRepository = (*Repository)(nil)
+
+// Option настраивает Repository при создании.
+type Option func(*Repository)
+
+// WithEncryption включает шифрование email и birthday ключами из kp.
+func WithEncryption(kp KeyProvider) Option {
+	return func(r *Repository) {
+		r.cipher = newFieldCipher(kp)
+	}
+}
+
+// NewRepository создаёт репозиторий поверх открытого соединения с БД.
+// Схема БД должна быть приведена к актуальной версии через Migrate.
+// Если задана переменная окружения DEBUG_SQL, запросы дополнительно
+// выводятся в stderr (см. DebugSQLEnv).
+func NewRepository(db *sql.DB, opts ...Option) *Repository {
+	return newRepository(&Repository{db: db}, opts)
+}
+
+// NewTxRepository создаёт репозиторий, все запросы которого выполняются в
+// уже открытой транзакции tx. Фиксация и откат tx остаются за вызывающим,
+// а операции, которым нужна своя транзакция, выполняются в точках
+// сохранения внутри tx: ошибка операции откатывает только её изменения.
+func NewTxRepository(tx *sql.Tx, opts ...Option) *Repository {
+	return newRepository(&Repository{tx: tx}, opts)
+}
+
+func newRepository(r *Repository, opts []Option) *Repository {
+	r.clock = systemClock{}
+	r.ids = AutoIncrement{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if opt := debugSQLFromEnv(); opt != nil {
+		opt(r)
+	}
+
+	return r
+}
+
+// querier — общие методы *sql.DB и *sql.Tx, позволяющие выполнять одни
+// и те же запросы как вне транзакции, так и внутри неё. querier подходит
+// и как clientsdb.DBTX для запросов, сгенерированных sqlc.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn возвращает querier для запросов вне транзакции (для репозитория,
+// созданного через NewTxRepository, — в его транзакции).
+func (r *Repository) conn() querier {
+	if r.tx != nil {
+		return r.observe(r.tx)
+	}
+
+	return r.observe(r.db)
+}
+
+// inTx выполняет fn в транзакции: фиксирует её при успехе и откатывает при
+// ошибке. При занятости БД транзакция повторяется целиком (см. WithBusyRetry),
+// поэтому fn может быть вызвана несколько раз.
+func (r *Repository) inTx(ctx context.Context, fn func(q querier) error) error {
+	if r.tx != nil {
+		return r.inSavepoint(ctx, fn)
+	}
+
+	return r.retry(ctx, func() error {
+		return r.runTx(ctx, fn)
+	})
+}
+
+func (r *Repository) runTx(ctx context.Context, fn func(q querier) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(r.observe(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		// Если COMMIT не выполнен (например, из-за занятости БД), SQLite
+		// оставляет транзакцию открытой, а database/sql считает её
+		// завершённой. Откатываем её явно, иначе соединение вернётся в пул
+		// с открытой транзакцией.
+		conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return err
+	}
+
+	return nil
+}
+
+// inSavepoint выполняет fn в точке сохранения внутри транзакции репозитория:
+// при ошибке изменения fn откатываются, а транзакция остаётся открытой.
+func (r *Repository) inSavepoint(ctx context.Context, fn func(q querier) error) error {
+	if _, err := r.tx.ExecContext(ctx, "SAVEPOINT repository"); err != nil {
+		return err
+	}
+
+	if err := fn(r.observe(r.tx)); err != nil {
+		// Откат выполняется и после отмены ctx, иначе в транзакции
+		// останутся частичные изменения.
+		rollbackCtx := context.WithoutCancel(ctx)
+		r.tx.ExecContext(rollbackCtx, "ROLLBACK TO repository")
+		r.tx.ExecContext(rollbackCtx, "RELEASE repository")
+		return err
+	}
+
+	_, err := r.tx.ExecContext(ctx, "RELEASE repository")
+
+	return err
+}
+
+// Select возвращает клиента по ID или ErrClientNotFound, если его нет.
+func (r *Repository) Select(ctx context.Context, id int) (_ Client, err error) {
+	ctx, end := r.startClientOperation(ctx, "select", id)
+	defer end(&err)
+
+	var cl Client
+	err = r.retry(ctx, func() error {
+		cl, err = r.selectClient(ctx, r.conn(), id)
+		return err
+	})
+
+	return cl, err
+}
+
+func (r *Repository) selectClient(ctx context.Context, q querier, id int) (Client, error) {
+	owner, restricted, ok := r.ownerFilter(ctx)
+	if !ok {
+		return Client{}, ErrClientNotFound
+	}
+
+	var (
+		row clientsdb.GetClientRow
+		err error
+	)
+	if restricted {
+		var owned clientsdb.GetClientByOwnerRow
+		owned, err = clientsdb.New(q).GetClientByOwner(ctx, clientsdb.GetClientByOwnerParams{ID: int64(id), OwnerID: owner})
+		row = clientsdb.GetClientRow(owned)
+	} else {
+		row, err = clientsdb.New(q).GetClient(ctx, int64(id))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return Client{}, ErrClientNotFound
+	}
+	if err != nil {
+		return Client{}, err
+	}
+
+	cl, err := clientFromRow(row)
+	if err != nil {
+		return Client{}, err
+	}
+
+	return r.decrypt(cl)
+}
+
+// ForEach вызывает fn для каждого клиента в порядке возрастания ID.
+// Ошибка из fn прерывает обход и возвращается вызывающему.
+func (r *Repository) ForEach(ctx context.Context, fn func(Client) error) (err error) {
+	ctx, end := r.startOperation(ctx, "for_each")
+	defer end(&err)
+
+	return r.forEach(ctx, "", nil, fn)
+}
+
+// forEach обходит клиентов, удовлетворяющих дополнительному условию cond
+// (фрагмент WHERE, начинающийся с AND) с параметрами condArgs.
+func (r *Repository) forEach(ctx context.Context, cond string, condArgs []any, fn func(Client) error) error {
+	scope, args := r.ownerScope(ctx)
+	args = append(args, condArgs...)
+
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+clientColumns+" FROM clients WHERE 1"+notDeleted+scope+cond+" ORDER BY id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		cl, err := scanClient(rows)
+		if err != nil {
+			return err
+		}
+		if cl, err = r.decrypt(cl); err != nil {
+			return err
+		}
+		if err := fn(cl); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// notDeleted — условие, исключающее мягко удалённых клиентов (см. MergeClients).
+// Такие клиенты не читаются и не изменяются репозиторием, кроме EraseClient.
+const notDeleted = " AND deleted_at = ''"
+
+// rowScanner — общий метод *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanClient читает клиента из строки запроса, выбравшего clientColumns.
+func scanClient(row rowScanner) (Client, error) {
+	var cl Client
+	if err := scanStruct(row, columnsOf(reflect.TypeOf(cl)).names, &cl); err != nil {
+		return Client{}, err
+	}
+
+	return cl, nil
+}
+
+// formatTime приводит время к формату хранения в БД; нулевое время
+// хранится как пустая строка.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Insert проверяет клиента (см. Client.Validate), добавляет его и возвращает ID.
+func (r *Repository) Insert(ctx context.Context, client Client) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "insert")
+	defer end(&err)
+
+	var id int
+	err = r.inTx(ctx, func(q querier) error {
+		id, err = r.insert(ctx, q, client)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// insert добавляет клиента и запись аудита в рамках транзакции q.
+func (r *Repository) insert(ctx context.Context, q querier, client Client) (int, error) {
+	if err := client.validateAt(r.now()); err != nil {
+		return 0, err
+	}
+
+	owner, err := r.ownerFor(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	client.OwnerID = owner
+	// Новый клиент всегда активен; статус меняется через ChangeStatus
+	client.Status = StatusActive
+	client.ConsentUpdatedAt = time.Time{}
+	if client.MarketingConsent {
+		client.ConsentUpdatedAt = r.now().Truncate(time.Second)
+	}
+
+	next, err := r.ids.NextID()
+	if err != nil {
+		return 0, err
+	}
+	if next == 0 && r.cipher != nil {
+		// шифротекст привязан к ID клиента, поэтому ID нужен до вставки
+		if next, err = nextClientID(ctx, q); err != nil {
+			return 0, err
+		}
+	}
+	// NULL в качестве id оставляет назначение ID базе данных
+	id := sql.NullInt64{Int64: next, Valid: next != 0}
+	client.ID = int(next)
+
+	stored, err := r.encrypt(client)
+	if err != nil {
+		return 0, err
+	}
+
+	now := formatTime(r.now())
+	res, err := clientsdb.New(q).InsertClient(ctx, clientsdb.InsertClientParams{
+		ID:               id,
+		Fio:              stored.FIO,
+		Login:            stored.Login,
+		Birthday:         stored.Birthday,
+		Email:            stored.Email,
+		OwnerID:          stored.OwnerID,
+		MarketingConsent: boolToInt(stored.MarketingConsent),
+		ConsentUpdatedAt: formatTime(stored.ConsentUpdatedAt),
+		ValidFrom:        now,
+		CreatedAt:        now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	client.ID = int(lastID)
+
+	if err := r.audit(ctx, q, AuditInsert, client.ID, nil, &client); err != nil {
+		return 0, err
+	}
+
+	return client.ID, nil
+}
+
+// nextClientID возвращает ID, который AUTOINCREMENT назначил бы следующему
+// клиенту: он больше и наибольшего ID в clients, и любого назначенного
+// раньше. Одновременная вставка с тем же ID завершится ошибкой занятости
+// или ограничения уникальности в транзакции q, а не перезапишет клиента.
+func nextClientID(ctx context.Context, q querier) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, `SELECT MAX(
+		COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'clients'), 0),
+		COALESCE((SELECT MAX(id) FROM clients), 0)) + 1`).Scan(&id)
+
+	return id, err
+}
+
+// Update проверяет и сохраняет изменения клиента с ID client.ID. Владелец, согласие
+// и статус клиента при этом не меняются: согласие записывается через
+// RecordConsent, статус — через ChangeStatus. Прежняя версия клиента
+// сохраняется в clients_history.
+func (r *Repository) Update(ctx context.Context, client Client) (err error) {
+	ctx, end := r.startClientOperation(ctx, "update", client.ID)
+	defer end(&err)
+
+	return r.inTx(ctx, func(q querier) error {
+		return r.update(ctx, q, client)
+	})
+}
+
+// update проверяет и сохраняет изменения клиента в рамках транзакции q.
+func (r *Repository) update(ctx context.Context, q querier, client Client) error {
+	if err := client.validateAt(r.now()); err != nil {
+		return err
+	}
+
+	stored, err := r.encrypt(client)
+	if err != nil {
+		return err
+	}
+
+	before, err := r.selectClient(ctx, q, client.ID)
+	if err != nil {
+		return err
+	}
+	changedAt, err := r.recordHistory(ctx, q, client.ID, AuditUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = clientsdb.New(q).UpdateClient(ctx, clientsdb.UpdateClientParams{
+		Fio:       stored.FIO,
+		Login:     stored.Login,
+		Birthday:  stored.Birthday,
+		Email:     stored.Email,
+		ValidFrom: changedAt,
+		ID:        int64(client.ID),
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.audit(ctx, q, AuditUpdate, client.ID, &before, &client)
+}
+
+// Delete удаляет клиента по ID или возвращает ErrClientNotFound, если его нет.
+// Клиента с заказами удалить нельзя (ErrClientHasOrders): заказы — учётные
+// данные, которые не должны исчезать вместе с клиентом. Полностью удаляет
+// клиента вместе с заказами только EraseClient. Заметки, метки, документы
+// и связи с записями каталога пользователей клиента удаляются вместе с ним
+// (содержимое документов — после фиксации удаления), последняя версия
+// клиента остаётся в clients_history.
+func (r *Repository) Delete(ctx context.Context, id int) (err error) {
+	ctx, end := r.startClientOperation(ctx, "delete", id)
+	defer end(&err)
+
+	var blobKeys []string
+	err = r.inTx(ctx, func(q querier) error {
+		var err error
+		blobKeys, err = r.delete(ctx, q, id, nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.deleteBlobs(ctx, blobKeys)
+}
+
+// DeleteClients удаляет клиентов ids в одной транзакции так же, как Delete:
+// если хотя бы одного из них удалить нельзя, не удаляется никто, а ошибка
+// указывает ID клиента. С opts.DryRun БД не меняется, а результат
+// описывает строки, которые были бы удалены.
+func (r *Repository) DeleteClients(ctx context.Context, ids []int, opts DestructiveOptions) (_ Affected, err error) {
+	ctx, end := r.startOperation(ctx, "delete_clients")
+	defer end(&err)
+
+	if len(ids) == 0 {
+		return Affected{}, fmt.Errorf("%w: no clients to delete", ErrValidation)
+	}
+	for i, id := range ids {
+		if slices.Contains(ids[:i], id) {
+			return Affected{}, fmt.Errorf("%w: client %d is listed more than once", ErrValidation, id)
+		}
+	}
+
+	var (
+		affected Affected
+		blobKeys []string
+	)
+	err = r.inTxDryRun(ctx, opts.DryRun, func(q querier) error {
+		affected = Affected{Rows: make(map[string]int64)}
+		blobKeys = nil
+		for _, id := range ids {
+			keys, err := r.delete(ctx, q, id, affected.Rows)
+			if err != nil {
+				return fmt.Errorf("client %d: %w", id, err)
+			}
+			affected.ClientIDs = append(affected.ClientIDs, id)
+			blobKeys = append(blobKeys, keys...)
+		}
+		return nil
+	})
+	if err != nil {
+		return Affected{}, err
+	}
+	if opts.DryRun {
+		return affected, nil
+	}
+
+	return affected, r.deleteBlobs(ctx, blobKeys)
+}
+
+// delete удаляет клиента id в транзакции q так же, как Delete, и, если
+// rows не nil, добавляет в него число удалённых строк по таблицам.
+// Возвращает ключи содержимого документов клиента, которые нужно удалить
+// из хранилища после фиксации транзакции.
+func (r *Repository) delete(ctx context.Context, q querier, id int, rows map[string]int64) ([]string, error) {
+	before, err := r.selectClient(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := clientsdb.New(q).CountClientOrders(ctx, int64(id))
+	if err != nil {
+		return nil, err
+	}
+	if orders > 0 {
+		return nil, ErrClientHasOrders
+	}
+
+	for _, step := range []struct{ table, query string }{
+		{"client_notes", "DELETE FROM client_notes WHERE client_id = :id"},
+		{"client_tags", "DELETE FROM client_tags WHERE client_id = :id"},
+		{"directory_links", "DELETE FROM directory_links WHERE client_id = :id"},
+	} {
+		res, err := q.ExecContext(ctx, step.query, sql.Named("id", id))
+		if err != nil {
+			return nil, err
+		}
+		if err := countRows(rows, step.table, res); err != nil {
+			return nil, err
+		}
+	}
+	blobKeys, err := queryStrings(ctx, q, "DELETE FROM client_documents WHERE client_id = :id RETURNING blob_key", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	if rows != nil && len(blobKeys) > 0 {
+		rows["client_documents"] += int64(len(blobKeys))
+	}
+
+	if _, err := r.recordHistory(ctx, q, id, AuditDelete); err != nil {
+		return nil, err
+	}
+	res, err := clientsdb.New(q).DeleteClient(ctx, int64(id))
+	if err != nil {
+		return nil, err
+	}
+	if err := countRows(rows, "clients", res); err != nil {
+		return nil, err
+	}
+
+	return blobKeys, r.audit(ctx, q, AuditDelete, id, &before, nil)
+}
+
+// encrypt шифрует поля клиента, привязывая шифротекст к cl.ID.
+func (r *Repository) encrypt(cl Client) (Client, error) {
+	var err error
+	if cl.Email, err = r.encryptField(cl.ID, "email", cl.Email); err != nil {
+		return Client{}, err
+	}
+	if cl.Birthday, err = r.encryptField(cl.ID, "birthday", cl.Birthday); err != nil {
+		return Client{}, err
+	}
+
+	return cl, nil
+}
+
+func (r *Repository) decrypt(cl Client) (Client, error) {
+	var err error
+	if cl.Email, err = r.decryptField(cl.ID, "email", cl.Email); err != nil {
+		return Client{}, err
+	}
+	if cl.Birthday, err = r.decryptField(cl.ID, "birthday", cl.Birthday); err != nil {
+		return Client{}, err
+	}
+
+	return cl, nil
+}
+
+// encryptedFields — поля клиента, которые шифруются при включённом шифровании.
+var encryptedFields = map[string]bool{"email": true, "birthday": true}
+
+func (r *Repository) encryptField(clientID int, field, value string) (string, error) {
+	if r.cipher == nil || !encryptedFields[field] {
+		return value, nil
+	}
+
+	return r.cipher.encrypt(clientID, field, value)
+}
+
+func (r *Repository) decryptField(clientID int, field, value string) (string, error) {
+	if r.cipher == nil || !encryptedFields[field] {
+		return value, nil
+	}
+
+	return r.cipher.decrypt(clientID, field, value)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Запросы репозитория, которые проверяются без настоящей БД. Запросы
+// clientsdb — в том виде, в котором их выполняет код, сгенерированный sqlc.
+const (
+	mockSelectSQL = "-- name: GetClient :one\nSELECT " + clientColumns + " FROM clients WHERE id = ? AND deleted_at = ''"
+	mockOwnerSQL  = "-- name: GetClientByOwner :one\nSELECT " + clientColumns + " FROM clients WHERE id = ? AND deleted_at = '' AND owner_id = ?"
+	mockInsertSQL = `-- name: InsertClient :execresult
+		INSERT INTO clients (id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, valid_from, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	mockUpdateSQL    = "-- name: UpdateClient :exec\nUPDATE clients SET fio = ?, login = ?, birthday = ?, email = ?, valid_from = ?, version = version + 1 WHERE id = ?"
+	mockDeleteSQL    = "-- name: DeleteClient :execresult\nDELETE FROM clients WHERE id = ?"
+	mockOrdersSQL    = "-- name: CountClientOrders :one\nSELECT COUNT(*) FROM orders WHERE client_id = ?"
+	mockNotesSQL     = "DELETE FROM client_notes WHERE client_id = :id"
+	mockTagsSQL      = "DELETE FROM client_tags WHERE client_id = :id"
+	mockLinksSQL     = "DELETE FROM directory_links WHERE client_id = :id"
+	mockDocumentsSQL = "DELETE FROM client_documents WHERE client_id = :id RETURNING blob_key"
+	mockHistorySQL   = `INSERT INTO clients_history (` + historyColumns + `, valid_from, valid_to, operation)
+		SELECT id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status, valid_from, :valid_to, :operation
+		FROM clients WHERE id = :id`
+	mockAuditSQL = `INSERT INTO audit_log (actor, occurred_at, operation, client_id, diff, request_id)
+		VALUES (:actor, :occurred_at, :operation, :client_id, :diff, :request_id)`
+	mockOutboxSQL = `INSERT INTO outbox (client_id, operation, occurred_at, actor, request_id, fields)
+		VALUES (:client_id, :operation, :occurred_at, :actor, :request_id, :fields)`
+)
+
+var mockClient = Client{ID: 7, FIO: "Test", Login: "Test", Birthday: "19700101", Email: "mail@mail.com"}
+
+// newMockRepository создаёт репозиторий поверх sqlmock, сравнивающего
+// запросы с ожидаемыми дословно (с точностью до пробелов)
+func newMockRepository(t *testing.T, opts ...Option) (*Repository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err, "sqlmock error: %v", err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet(), "unmet sqlmock expectations")
+		db.Close()
+	})
+
+	return NewRepository(db, opts...), mock
+}
+
+func mockClientRows(clients ...Client) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "fio", "login", "birthday", "email", "owner_id", "marketing_consent", "consent_updated_at", "status"})
+	for _, cl := range clients {
+		rows.AddRow(cl.ID, cl.FIO, cl.Login, cl.Birthday, cl.Email, cl.OwnerID, boolToInt(cl.MarketingConsent), formatTime(cl.ConsentUpdatedAt), string(cl.Status))
+	}
+
+	return rows
+}
+
+// expectHistory ожидает сохранение прежней версии клиента перед операцией op
+func expectHistory(mock sqlmock.Sqlmock, op AuditOperation, clientID int) {
+	mock.ExpectExec(mockHistorySQL).
+		WithArgs(sqlmock.AnyArg(), sql.Named("operation", string(op)), sql.Named("id", clientID)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectAudit ожидает запись аудита и события outbox об операции op
+func expectAudit(mock sqlmock.Sqlmock, op AuditOperation, clientID int) {
+	mock.ExpectExec(mockAuditSQL).
+		WithArgs(sql.Named("actor", systemActor), sqlmock.AnyArg(), sql.Named("operation", string(op)),
+			sql.Named("client_id", clientID), sqlmock.AnyArg(), sql.Named("request_id", "")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(mockOutboxSQL).
+		WithArgs(sql.Named("client_id", clientID), sql.Named("operation", string(op)), sqlmock.AnyArg(),
+			sql.Named("actor", systemActor), sql.Named("request_id", ""), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// Тест проверяет запрос Select и преобразование ошибок
+func Test_RepositoryMock_Select(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows(mockClient))
+
+		client, err := repo.Select(ctx, mockClient.ID)
+		require.NoError(t, err)
+		assert.Equal(t, mockClient, client)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows())
+
+		_, err := repo.Select(ctx, mockClient.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+	})
+
+	t.Run("DriverError", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		errDriver := errors.New("driver failure")
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnError(errDriver)
+
+		_, err := repo.Select(ctx, mockClient.ID)
+		require.ErrorIs(t, err, errDriver)
+	})
+
+	t.Run("OwnerScope", func(t *testing.T) {
+		repo, mock := newMockRepository(t, WithOwnerRestriction())
+		mock.ExpectQuery(mockOwnerSQL).
+			WithArgs(int64(mockClient.ID), "manager-1").
+			WillReturnRows(mockClientRows())
+
+		_, err := repo.Select(WithPrincipal(ctx, Principal{ID: "manager-1"}), mockClient.ID)
+		require.ErrorIs(t, err, ErrClientNotFound)
+	})
+}
+
+// Тест проверяет запросы Insert, запись аудита и откат при ошибке
+func Test_RepositoryMock_Insert(t *testing.T) {
+	ctx := context.Background()
+	insertArgs := []driver.Value{
+		nil,
+		mockClient.FIO,
+		mockClient.Login,
+		mockClient.Birthday,
+		mockClient.Email,
+		"",
+		int64(0),
+		"",
+		sqlmock.AnyArg(),
+		sqlmock.AnyArg(),
+	}
+
+	t.Run("Ok", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(mockInsertSQL).WithArgs(insertArgs...).WillReturnResult(sqlmock.NewResult(int64(mockClient.ID), 1))
+		expectAudit(mock, AuditInsert, mockClient.ID)
+		mock.ExpectCommit()
+
+		id, err := repo.Insert(ctx, mockClient)
+		require.NoError(t, err)
+		assert.Equal(t, mockClient.ID, id)
+	})
+
+	t.Run("GeneratedID", func(t *testing.T) {
+		ids, err := NewSnowflake(1, testutil.NewFakeClock(snowflakeEpoch))
+		require.NoError(t, err)
+		repo, mock := newMockRepository(t, WithIDGenerator(ids))
+		wantID := 1 << snowflakeSeqBits
+
+		args := append([]driver.Value{int64(wantID)}, insertArgs[1:]...)
+		mock.ExpectBegin()
+		mock.ExpectExec(mockInsertSQL).WithArgs(args...).WillReturnResult(sqlmock.NewResult(int64(wantID), 1))
+		expectAudit(mock, AuditInsert, wantID)
+		mock.ExpectCommit()
+
+		id, err := repo.Insert(ctx, mockClient)
+		require.NoError(t, err)
+		assert.Equal(t, wantID, id)
+	})
+
+	t.Run("AuditFailureRollsBack", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		errDriver := errors.New("disk full")
+		mock.ExpectBegin()
+		mock.ExpectExec(mockInsertSQL).WithArgs(insertArgs...).WillReturnResult(sqlmock.NewResult(int64(mockClient.ID), 1))
+		mock.ExpectExec(mockAuditSQL).WillReturnError(errDriver)
+		mock.ExpectRollback()
+
+		_, err := repo.Insert(ctx, mockClient)
+		require.ErrorIs(t, err, errDriver)
+	})
+}
+
+// Тест проверяет запросы Update: чтение прежнего состояния, изменение и аудит
+func Test_RepositoryMock_Update(t *testing.T) {
+	ctx := context.Background()
+	updated := mockClient
+	updated.FIO = "Updated"
+
+	t.Run("Ok", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows(mockClient))
+		expectHistory(mock, AuditUpdate, mockClient.ID)
+		mock.ExpectExec(mockUpdateSQL).
+			WithArgs(updated.FIO, updated.Login, updated.Birthday, updated.Email, sqlmock.AnyArg(), int64(updated.ID)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, AuditUpdate, mockClient.ID)
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.Update(ctx, updated))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows())
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, repo.Update(ctx, updated), ErrClientNotFound)
+	})
+}
+
+// Тест проверяет запросы Delete и откат при ошибке удаления
+func Test_RepositoryMock_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Ok", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows(mockClient))
+		mock.ExpectQuery(mockOrdersSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(mockNotesSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(mockTagsSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(mockLinksSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(mockDocumentsSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnRows(sqlmock.NewRows([]string{"blob_key"}))
+		expectHistory(mock, AuditDelete, mockClient.ID)
+		mock.ExpectExec(mockDeleteSQL).WithArgs(int64(mockClient.ID)).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, AuditDelete, mockClient.ID)
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.Delete(ctx, mockClient.ID))
+	})
+
+	t.Run("DriverError", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		errDriver := errors.New("database is locked")
+		mock.ExpectBegin()
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows(mockClient))
+		mock.ExpectQuery(mockOrdersSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(mockNotesSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(mockTagsSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(mockLinksSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(mockDocumentsSQL).WithArgs(sql.Named("id", mockClient.ID)).WillReturnRows(sqlmock.NewRows([]string{"blob_key"}))
+		expectHistory(mock, AuditDelete, mockClient.ID)
+		mock.ExpectExec(mockDeleteSQL).WithArgs(int64(mockClient.ID)).WillReturnError(errDriver)
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, repo.Delete(ctx, mockClient.ID), errDriver)
+	})
+
+	t.Run("HasOrders", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows(mockClient))
+		mock.ExpectQuery(mockOrdersSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		err := repo.Delete(ctx, mockClient.ID)
+		require.ErrorIs(t, err, ErrClientHasOrders)
+		assert.Equal(t, ClassConflict, Classify(err))
+	})
+}
+
+// Тест проверяет, что обёртка с внедрением отказов реализует
+// ClientRepository и не обращается к БД при отказе
+func Test_RepositoryMock_FaultyRepository(t *testing.T) {
+	ctx := context.Background()
+	repo, mock := newMockRepository(t)
+	var faulty ClientRepository = testutil.NewFaultyRepository[Client](repo, testutil.NewFaultInjector(testutil.FaultConfig{FailEvery: 2}))
+
+	mock.ExpectQuery(mockSelectSQL).WithArgs(int64(mockClient.ID)).WillReturnRows(mockClientRows(mockClient))
+
+	client, err := faulty.Select(ctx, mockClient.ID)
+	require.NoError(t, err)
+	assertClientEqual(t, mockClient, client)
+
+	_, err = faulty.Select(ctx, mockClient.ID)
+	require.ErrorIs(t, err, testutil.ErrInjected)
+}
+
+// Тест проверяет запрос ForEach и прерывание обхода ошибкой из fn
+func Test_RepositoryMock_ForEach(t *testing.T) {
+	ctx := context.Background()
+	second := mockClient
+	second.ID = 8
+
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery("SELECT " + clientColumns + " FROM clients WHERE 1 AND deleted_at = '' ORDER BY id").
+		WillReturnRows(mockClientRows(mockClient, second))
+
+	errStop := errors.New("stop")
+	var seen []int
+	err := repo.ForEach(ctx, func(cl Client) error {
+		seen = append(seen, cl.ID)
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	assert.Equal(t, []int{mockClient.ID}, seen, "iteration should stop on the first error")
+}
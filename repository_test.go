@@ -0,0 +1,69 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что изменения репозитория, привязанного к транзакции,
+// видны только внутри неё и исчезают после отката
+func Test_TxRepository_RollbackIsolation(t *testing.T) {
+	db, err := sql.Open("sqlite", testDSN("_pragma=busy_timeout(30000)"))
+	require.NoError(t, err, "database connection error: %v", err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	repo := NewTxRepository(tx)
+
+	cl := Client{FIO: "Test", Login: "Test", Birthday: "19700101", Email: "tx383@mail.com"}
+	cl.ID, err = repo.Insert(ctx, cl)
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	_, err = repo.Select(ctx, cl.ID)
+	require.NoError(t, err, "client should be visible inside the transaction")
+
+	require.NoError(t, tx.Rollback())
+
+	assertClientNotInDB(t, db, cl.ID)
+}
+
+// Тест проверяет, что ошибка операции откатывает только её изменения,
+// а транзакция остаётся пригодной для дальнейшей работы
+func Test_TxRepository_FailedOperationKeepsTx(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	var inserted int64
+	errBoom := errors.New("boom")
+	err := repo.inTx(ctx, func(q querier) error {
+		res, err := q.ExecContext(ctx, "INSERT INTO clients (fio, login, birthday, email) VALUES ('Test', 'Test', '19700101', 'tx383@mail.com')")
+		require.NoError(t, err)
+		inserted, err = res.LastInsertId()
+		require.NoError(t, err)
+
+		return errBoom
+	})
+	require.ErrorIs(t, err, errBoom)
+
+	assertClientNotInDB(t, tx, int(inserted))
+
+	id, err := repo.Insert(ctx, Client{FIO: "Test", Login: "Test", Birthday: "19700101", Email: "tx383@mail.com"})
+	require.NoError(t, err, "transaction should stay usable after a failed operation")
+
+	_, err = repo.EraseClient(ctx, id)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,60 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что идентификатор запроса из заголовка доходит до ответа,
+// журнала запросов и журнала аудита
+func Test_RequestID_PropagatesThroughLayers(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, repo := setupTestDB(t, WithQueryLogger(logger))
+
+	// Обработчик, создающий клиента и возвращающий его ID
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := repo.Insert(r.Context(), Client{FIO: "Test", Login: "Test", Birthday: "19700101", Email: "mail@mail.com"})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(strconv.Itoa(id)))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/clients", nil)
+	req.Header.Set(RequestIDHeader, "req-376")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	id, err := strconv.Atoi(rec.Body.String())
+	require.NoError(t, err)
+
+	// Ответ
+	assert.Equal(t, "req-376", rec.Header().Get(RequestIDHeader))
+
+	// Журнал запросов
+	records := readLogRecords(t, &logs)
+	require.NotEmpty(t, records)
+	for _, rec := range records {
+		assert.Equal(t, "req-376", rec["request_id"], "every query record should carry the request ID")
+	}
+
+	// Журнал аудита
+	entries, err := repo.AuditLog(context.Background(), id)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "req-376", entries[0].RequestID)
+}
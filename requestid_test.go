@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет генерацию идентификатора при отсутствии или
+// некорректном значении заголовка
+func Test_RequestIDMiddleware_Generates(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	for _, header := range []string{"", "bad id\nforged=1", strings.Repeat("x", maxRequestIDLen+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Len(t, seen, 32, "generated ID should be 16 random bytes in hex")
+		assert.NotEqual(t, header, seen)
+		assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+	}
+}
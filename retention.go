@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultRetention — срок хранения мягко удалённых клиентов по умолчанию.
+const DefaultRetention = 90 * 24 * time.Hour
+
+// RetentionPolicy — правило безвозвратного удаления мягко удалённых
+// клиентов.
+type RetentionPolicy struct {
+	// SoftDeletedFor — сколько хранить клиента после мягкого удаления.
+	SoftDeletedFor time.Duration
+}
+
+// SetLegalHold ставит клиента на удержание (hold = true) или снимает его.
+// Клиентов на удержании PurgeSoftDeleted не удаляет. Удержание можно
+// поставить и на мягко удалённого клиента; изменение записывается в
+// журнал аудита.
+func (r *Repository) SetLegalHold(ctx context.Context, id int, hold bool) (err error) {
+	ctx, end := r.startClientOperation(ctx, "set_legal_hold", id)
+	defer end(&err)
+
+	return r.inTx(ctx, func(q querier) error {
+		if err := r.clientStored(ctx, q, id, ""); err != nil {
+			return err
+		}
+
+		var before bool
+		err := q.QueryRowContext(ctx, "SELECT legal_hold FROM clients WHERE id = :id", sql.Named("id", id)).Scan(&before)
+		if err != nil {
+			return err
+		}
+		if before == hold {
+			return nil
+		}
+
+		_, err = q.ExecContext(ctx, "UPDATE clients SET legal_hold = :legal_hold, version = version + 1 WHERE id = :id",
+			sql.Named("legal_hold", hold),
+			sql.Named("id", id))
+		if err != nil {
+			return err
+		}
+
+		was, now := strconv.FormatBool(before), strconv.FormatBool(hold)
+
+		return r.auditDiff(ctx, q, AuditLegalHold, id, map[string]FieldChange{
+			"legal_hold": {Before: &was, After: &now},
+		})
+	})
+}
+
+// PurgeSoftDeleted безвозвратно удаляет клиентов, мягко удалённых раньше,
+// чем policy.SoftDeletedFor назад по часам репозитория, кроме клиентов на
+// удержании. Каждый клиент удаляется так же, как EraseClient, с
+// квитанцией; всё выполняется в одной транзакции. Возвращает квитанции
+// по возрастанию ID клиента. С opts.DryRun БД не меняется, а квитанции
+// без ID описывают строки, которые были бы удалены.
+func (r *Repository) PurgeSoftDeleted(ctx context.Context, policy RetentionPolicy, opts DestructiveOptions) (_ []ErasureReceipt, err error) {
+	ctx, end := r.startOperation(ctx, "purge_soft_deleted")
+	defer end(&err)
+
+	if policy.SoftDeletedFor <= 0 {
+		return nil, fmt.Errorf("%w: retention period must be positive, got %s", ErrValidation, policy.SoftDeletedFor)
+	}
+	cutoff := formatTime(r.now().Add(-policy.SoftDeletedFor))
+
+	var (
+		receipts []ErasureReceipt
+		blobKeys []string
+	)
+	err = r.inTxDryRun(ctx, opts.DryRun, func(q querier) error {
+		receipts, blobKeys = nil, nil
+		scope, args := r.ownerScope(ctx)
+		args = append(args, sql.Named("cutoff", cutoff))
+		ids, err := queryStrings(ctx, q, "SELECT id FROM clients WHERE deleted_at != '' AND deleted_at <= :cutoff AND legal_hold = 0"+scope+" ORDER BY id", args...)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range ids {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			receipt, keys, err := r.erase(ctx, q, id)
+			if err != nil {
+				return err
+			}
+			if opts.DryRun {
+				receipt.ID = 0
+			}
+			receipts = append(receipts, receipt)
+			blobKeys = append(blobKeys, keys...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return receipts, nil
+	}
+
+	return receipts, r.deleteBlobs(ctx, blobKeys)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что мягко удалённые клиенты удаляются безвозвратно по
+// истечении срока хранения, а клиенты на удержании сохраняются
+func Test_PurgeSoftDeleted(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(start)
+	repo := NewRepository(db, WithClock(clock))
+
+	ids := make([]int, 4)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	keep, early, held, late := ids[0], ids[1], ids[2], ids[3]
+	require.NoError(t, repo.MergeClients(ctx, keep, early, held))
+	require.NoError(t, repo.SetLegalHold(ctx, held, true))
+	clock.Advance(30 * 24 * time.Hour)
+	require.NoError(t, repo.MergeClients(ctx, keep, late))
+
+	policy := RetentionPolicy{SoftDeletedFor: DefaultRetention}
+	purge := func() []int {
+		t.Helper()
+		receipts, err := repo.PurgeSoftDeleted(ctx, policy, DestructiveOptions{})
+		require.NoError(t, err)
+		purged := make([]int, len(receipts))
+		for i, receipt := range receipts {
+			purged[i] = receipt.ClientID
+			assert.Equal(t, clock.Now().Truncate(time.Second), receipt.ErasedAt)
+		}
+		return purged
+	}
+
+	// За секунду до истечения срока никто не удаляется
+	clock.Set(start.Add(DefaultRetention - time.Second))
+	assert.Empty(t, purge())
+
+	clock.Set(start.Add(DefaultRetention))
+	assert.Equal(t, []int{early}, purge())
+	assertRowCount(t, db, "clients", 0, "id = ?", early)
+	assertRowCount(t, db, "erasure_receipts", 1, "client_id = ?", early)
+	assertRowCount(t, db, "clients", 1, "id = ?", held)
+
+	// После снятия удержания клиент удаляется при следующем запуске
+	require.NoError(t, repo.SetLegalHold(ctx, held, false))
+	assert.Equal(t, []int{held}, purge())
+
+	clock.Set(start.Add(30*24*time.Hour + DefaultRetention))
+	assert.Equal(t, []int{late}, purge())
+
+	// Оставшийся клиент не удалялся мягко и не удаляется никогда
+	clock.Advance(10 * 365 * 24 * time.Hour)
+	assert.Empty(t, purge())
+	_, err := repo.Select(ctx, keep)
+	require.NoError(t, err)
+
+	_, err = repo.PurgeSoftDeleted(ctx, RetentionPolicy{}, DestructiveOptions{})
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+// Тест проверяет запись удержания в журнал аудита и ошибку для
+// несуществующего клиента
+func Test_SetLegalHold(t *testing.T) {
+	db := openMemoryDB(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	require.NoError(t, repo.SetLegalHold(ctx, id, true))
+	require.NoError(t, repo.SetLegalHold(ctx, id, true), "repeated hold is a no-op")
+	assertRowCount(t, db, "clients", 1, "id = ? AND legal_hold = 1", id)
+
+	entries, err := repo.AuditLog(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, AuditLegalHold, entries[1].Operation)
+	was, now := "false", "true"
+	assert.Equal(t, map[string]FieldChange{"legal_hold": {Before: &was, After: &now}}, entries[1].Diff)
+
+	require.ErrorIs(t, repo.SetLegalHold(ctx, id+1, true), ErrClientNotFound)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// maxBusyBackoff ограничивает паузу между повторами при занятости БД.
+const maxBusyBackoff = time.Second
+
+// RetryPolicy — политика повтора операции: сколько попыток выполнять,
+// какие паузы делать между ними и после каких ошибок повторять. Одна и та
+// же политика описывает повторы транзакций репозитория (WithRetryPolicy),
+// запросов к Elasticsearch и доставок вебхуков. Нулевое значение выполняет
+// операцию один раз.
+type RetryPolicy struct {
+	// MaxAttempts — наибольшее число попыток, включая первую; 0 и 1 —
+	// без повторов.
+	MaxAttempts int
+	// Backoff — пауза перед первым повтором; каждая следующая пауза вдвое
+	// длиннее, но не длиннее MaxBackoff (0 — без ограничения).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter — случайная добавка к паузе в долях паузы: пауза d
+	// превращается в случайную из [d, d+Jitter·d), чтобы конкурирующие
+	// клиенты не повторяли запросы одновременно. 0 — паузы точные.
+	Jitter float64
+	// Retryable сообщает, повторять ли операцию после ошибки: например,
+	// RetryOnBusy или несколько классов ошибок через RetryOnAny. nil —
+	// повторять после любой ошибки.
+	Retryable func(error) bool
+	// Sleep ждёт паузу d и возвращает ошибку, если ожидание прервано
+	// отменой ctx. nil — ожидание по таймеру; в тестах подменяется, чтобы
+	// не ждать по-настоящему.
+	Sleep func(ctx context.Context, d time.Duration) error
+	// Rand — источник случайной добавки; nil — общий источник math/rand.
+	Rand *rand.Rand
+}
+
+// Do выполняет fn и повторяет его по политике, пока fn возвращает ошибку,
+// которую можно повторить, и не исчерпаны попытки. Возвращается ошибка
+// последней попытки; при отмене ctx во время паузы — тоже она, а не
+// ошибка контекста, чтобы причина неудачи не терялась.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !p.ShouldRetry(err, attempt) {
+			return err
+		}
+
+		if p.sleep(ctx, p.Delay(attempt)) != nil {
+			return err
+		}
+	}
+}
+
+// ShouldRetry сообщает, нужно ли повторить операцию, attempt-я попытка
+// которой завершилась ошибкой err.
+func (p RetryPolicy) ShouldRetry(err error, attempt int) bool {
+	if err == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// Delay возвращает паузу перед повтором после attempt неудачных попыток,
+// включая случайную добавку Jitter.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 {
+		delay = min(delay, p.MaxBackoff)
+	}
+
+	if n := int64(p.Jitter * float64(delay)); n > 0 {
+		if p.Rand != nil {
+			delay += time.Duration(p.Rand.Int63n(n))
+		} else {
+			delay += time.Duration(rand.Int63n(n))
+		}
+	}
+
+	return delay
+}
+
+func (p RetryPolicy) sleep(ctx context.Context, d time.Duration) error {
+	if p.Sleep != nil {
+		return p.Sleep(ctx, d)
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// RetryOnAny объединяет классы ошибок: ошибка повторяется, если её
+// повторяет хотя бы один из классов.
+func RetryOnAny(classes ...func(error) bool) func(error) bool {
+	return func(err error) bool {
+		for _, retryable := range classes {
+			if retryable(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnBusy — класс ошибок занятости БД другим соединением
+// (SQLITE_BUSY, SQLITE_LOCKED).
+func RetryOnBusy(err error) bool {
+	return isBusy(err)
+}
+
+// RetryOnSerialization — класс ошибок сериализации: транзакцию нужно
+// выполнить заново, так как конкурирующая транзакция изменила прочитанные
+// данные (SQLITE_BUSY_SNAPSHOT, в PostgreSQL — SQLSTATE 40001 и 40P01).
+func RetryOnSerialization(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_BUSY_SNAPSHOT
+	}
+
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		return state == "40001" || state == "40P01"
+	}
+
+	return false
+}
+
+// RetryOnTransientNetwork — класс временных сетевых ошибок: таймаут,
+// отказ или сброс соединения, обрыв ответа. Отмена контекста временной
+// ошибкой не считается.
+func RetryOnTransientNetwork(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WithBusyRetry повторяет транзакции репозитория и выборку клиента по ID,
+// если они завершились из-за занятости БД другим соединением
+// (SQLITE_BUSY, SQLITE_LOCKED). Всего выполняется не более attempts
+// попыток; пауза перед повтором начинается с backoff, удваивается с каждой
+// попыткой (но не превышает секунды) и содержит случайную добавку, чтобы
+// конкурирующие соединения не повторяли запросы одновременно.
+func WithBusyRetry(attempts int, backoff time.Duration) Option {
+	return WithRetryPolicy(RetryPolicy{
+		MaxAttempts: attempts,
+		Backoff:     backoff,
+		MaxBackoff:  maxBusyBackoff,
+		Jitter:      1,
+		Retryable:   RetryOnBusy,
+	})
+}
+
+// WithRetryPolicy повторяет транзакции репозитория и выборку клиента по
+// ID по политике p. Без Retryable повторяются ошибки занятости БД и
+// сериализации: повтор других ошибок (нарушения ограничений, ошибки
+// проверки) только откладывает их.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(r *Repository) {
+		if p.Retryable == nil {
+			p.Retryable = RetryOnAny(RetryOnBusy, RetryOnSerialization)
+		}
+		r.retryPolicy = p
+	}
+}
+
+// retry выполняет fn, повторяя его по политике WithRetryPolicy.
+func (r *Repository) retry(ctx context.Context, fn func() error) error {
+	return r.retryPolicy.Do(ctx, fn)
+}
+
+// isBusy сообщает, вызвана ли ошибка занятостью БД другим соединением.
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	code := sqliteErr.Code() & 0xff
+
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSleeper запоминает паузы RetryPolicy вместо ожидания; err
+// возвращается из каждого ожидания.
+type fakeSleeper struct {
+	delays []time.Duration
+	err    error
+}
+
+func (s *fakeSleeper) Sleep(_ context.Context, d time.Duration) error {
+	s.delays = append(s.delays, d)
+	return s.err
+}
+
+// sqlStateError — ошибка драйвера с кодом SQLSTATE, как у PostgreSQL.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// timeoutError — сетевая ошибка таймаута.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var errRetryable = errors.New("retryable")
+
+// Тест проверяет число попыток, удвоение пауз с ограничением MaxBackoff,
+// остановку на ошибке, которую нельзя повторить, и на успехе
+func Test_RetryPolicy_Do(t *testing.T) {
+	errPermanent := errors.New("permanent")
+
+	tests := []struct {
+		name       string
+		errs       []error
+		wantErr    error
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		{
+			name:       "Exhausted",
+			errs:       []error{errRetryable, errRetryable, errRetryable, errRetryable},
+			wantErr:    errRetryable,
+			wantCalls:  4,
+			wantDelays: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond},
+		},
+		{
+			name:       "SucceedsAfterRetries",
+			errs:       []error{errRetryable, errRetryable, nil},
+			wantCalls:  3,
+			wantDelays: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+		},
+		{
+			name:      "NotRetryable",
+			errs:      []error{fmt.Errorf("insert: %w", errPermanent)},
+			wantErr:   errPermanent,
+			wantCalls: 1,
+		},
+		{
+			name:       "WrappedRetryable",
+			errs:       []error{fmt.Errorf("tx: %w", errRetryable), errPermanent},
+			wantErr:    errPermanent,
+			wantCalls:  2,
+			wantDelays: []time.Duration{10 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sleeper fakeSleeper
+			p := RetryPolicy{
+				MaxAttempts: 4,
+				Backoff:     10 * time.Millisecond,
+				MaxBackoff:  30 * time.Millisecond,
+				Retryable:   func(err error) bool { return errors.Is(err, errRetryable) },
+				Sleep:       sleeper.Sleep,
+			}
+
+			calls := 0
+			err := p.Do(context.Background(), func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Equal(t, tt.wantDelays, sleeper.delays)
+		})
+	}
+}
+
+// Тест проверяет, что нулевая политика выполняет операцию один раз, а без
+// Retryable повторяется любая ошибка
+func Test_RetryPolicy_Defaults(t *testing.T) {
+	calls := 0
+	err := RetryPolicy{}.Do(context.Background(), func() error {
+		calls++
+		return errRetryable
+	})
+	assert.ErrorIs(t, err, errRetryable)
+	assert.Equal(t, 1, calls)
+
+	var sleeper fakeSleeper
+	calls = 0
+	err = RetryPolicy{MaxAttempts: 3, Sleep: sleeper.Sleep}.Do(context.Background(), func() error {
+		calls++
+		return io.EOF
+	})
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{0, 0}, sleeper.delays)
+}
+
+// Тест проверяет, что прерванная пауза останавливает повторы и
+// возвращает ошибку операции, а не контекста
+func Test_RetryPolicy_Canceled(t *testing.T) {
+	sleeper := fakeSleeper{err: context.Canceled}
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, Sleep: sleeper.Sleep}
+
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return errRetryable
+	})
+	assert.Equal(t, errRetryable, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []time.Duration{time.Second}, sleeper.delays)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.Sleep = nil
+	start := time.Now()
+	assert.Equal(t, errRetryable, p.Do(ctx, func() error { return errRetryable }))
+	assert.Less(t, time.Since(start), time.Second, "a canceled context must not wait for the backoff")
+}
+
+// Тест проверяет, что случайная добавка лежит в [d, d+Jitter·d) и при
+// одном зерне повторяется
+func Test_RetryPolicy_Jitter(t *testing.T) {
+	delays := func(seed int64) []time.Duration {
+		p := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 400 * time.Millisecond, Jitter: 0.5, Rand: rand.New(rand.NewSource(seed))}
+		var got []time.Duration
+		for attempt := 1; attempt <= 5; attempt++ {
+			got = append(got, p.Delay(attempt))
+		}
+		return got
+	}
+
+	got := delays(1)
+	base := []time.Duration{100, 200, 400, 400, 400}
+	for i, d := range got {
+		want := base[i] * time.Millisecond
+		assert.GreaterOrEqual(t, d, want, "attempt %d", i+1)
+		assert.Less(t, d, want+want/2, "attempt %d", i+1)
+	}
+	assert.Equal(t, got, delays(1), "the same seed gives the same delays")
+	assert.NotEqual(t, got, delays(2))
+}
+
+// Тест проверяет классы ошибок, после которых операция повторяется
+func Test_RetryPolicy_Classes(t *testing.T) {
+	tests := []struct {
+		name  string
+		class func(error) bool
+		err   error
+		want  bool
+	}{
+		{"SerializationFailure", RetryOnSerialization, fmt.Errorf("commit: %w", sqlStateError("40001")), true},
+		{"Deadlock", RetryOnSerialization, sqlStateError("40P01"), true},
+		{"UniqueViolation", RetryOnSerialization, sqlStateError("23505"), false},
+		{"ConnectionReset", RetryOnTransientNetwork, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"ConnectionRefused", RetryOnTransientNetwork, fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"Timeout", RetryOnTransientNetwork, &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, true},
+		{"TruncatedResponse", RetryOnTransientNetwork, fmt.Errorf("decode: %w", io.ErrUnexpectedEOF), true},
+		{"Canceled", RetryOnTransientNetwork, fmt.Errorf("request: %w", context.Canceled), false},
+		{"NotNetwork", RetryOnTransientNetwork, errRetryable, false},
+		{"NotBusy", RetryOnBusy, sqlStateError("40001"), false},
+		{"AnyMatches", RetryOnAny(RetryOnBusy, RetryOnTransientNetwork), io.ErrUnexpectedEOF, true},
+		{"AnyNone", RetryOnAny(RetryOnBusy, RetryOnSerialization), io.ErrUnexpectedEOF, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.class(tt.err))
+		})
+	}
+}
+
+// Тест проверяет, что репозиторий с WithRetryPolicy по умолчанию
+// повторяет ошибки сериализации и не повторяет остальные, а WithBusyRetry
+// задаёт прежнюю политику повтора при занятости БД
+func Test_Repository_RetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+
+	var sleeper fakeSleeper
+	repo := NewRepository(db, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Sleep: sleeper.Sleep}))
+
+	calls := 0
+	err := repo.retry(ctx, func() error {
+		calls++
+		return sqlStateError("40001")
+	})
+	assert.Equal(t, sqlStateError("40001"), err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond}, sleeper.delays)
+
+	calls = 0
+	require.ErrorIs(t, repo.retry(ctx, func() error { calls++; return ErrValidation }), ErrValidation)
+	assert.Equal(t, 1, calls)
+
+	busy := NewRepository(db, WithBusyRetry(5, 10*time.Millisecond)).retryPolicy
+	assert.Equal(t, 5, busy.MaxAttempts)
+	assert.Equal(t, maxBusyBackoff, busy.MaxBackoff)
+	assert.Equal(t, 1.0, busy.Jitter)
+	assert.False(t, busy.Retryable(sqlStateError("40001")))
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Строки результата запроса читаются в структуры по тегам db: столбец
+// записывается в поле с тем же именем в теге. Поля без тега db (и с тегом
+// «-») не читаются, поля встроенных структур — читаются. Поэтому поле,
+// добавленное в Client при генерации модели (см. runGenModel), scanClient
+// читает без изменений.
+
+// structColumns — описание полей структуры с тегами db.
+type structColumns struct {
+	// names — столбцы в порядке объявления полей.
+	names []string
+	// fields — путь к полю (reflect.Value.FieldByIndex) по имени столбца.
+	fields map[string][]int
+}
+
+// structColumnsCache — structColumns по типам структур: описание
+// строится через reflect один раз на тип.
+var structColumnsCache sync.Map
+
+// columnsOf возвращает описание полей структуры t с тегами db.
+func columnsOf(t reflect.Type) *structColumns {
+	if cached, ok := structColumnsCache.Load(t); ok {
+		return cached.(*structColumns)
+	}
+
+	cols := &structColumns{fields: make(map[string][]int)}
+	for _, f := range reflect.VisibleFields(t) {
+		name, ok := f.Tag.Lookup("db")
+		if !ok || name == "-" || !f.IsExported() {
+			continue
+		}
+		if _, dup := cols.fields[name]; dup {
+			continue
+		}
+		cols.names = append(cols.names, name)
+		cols.fields[name] = f.Index
+	}
+	cached, _ := structColumnsCache.LoadOrStore(t, cols)
+
+	return cached.(*structColumns)
+}
+
+// columnList возвращает столбцы структуры типа T через запятую для SELECT.
+func columnList[T any]() string {
+	return strings.Join(columnsOf(reflect.TypeOf((*T)(nil)).Elem()).names, ", ")
+}
+
+// scanStruct читает строку row в поля структуры *dest: i-й столбец
+// результата — в поле с тегом db columns[i]. Поля, которых нет в columns,
+// обнуляются, а столбец без поля — ошибка: обычно это опечатка в запросе.
+// NULL записывается как нулевое значение поля.
+func scanStruct(row rowScanner, columns []string, dest any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("scan: destination must be a non-nil pointer to struct, got %T", dest)
+	}
+	v = v.Elem()
+	cols := columnsOf(v.Type())
+
+	targets := make([]any, len(columns))
+	for i, name := range columns {
+		index, ok := cols.fields[name]
+		if !ok {
+			return fmt.Errorf("scan: column %q has no field in %s", name, v.Type())
+		}
+		targets[i] = fieldScanner{v.FieldByIndex(index)}
+	}
+
+	v.SetZero()
+	if err := row.Scan(targets...); err != nil {
+		v.SetZero()
+		return err
+	}
+
+	return nil
+}
+
+// scanRows читает текущую строку rows в поля структуры *dest по именам
+// столбцов результата так же, как scanStruct.
+func scanRows(rows *sql.Rows, dest any) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	return scanStruct(rows, columns, dest)
+}
+
+// fieldScanner записывает значение столбца в поле структуры. Помимо
+// преобразований database/sql понимает NULL (нулевое значение поля),
+// флаги, хранимые числами, и время в формате хранения (см. formatTime).
+type fieldScanner struct {
+	v reflect.Value
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// errScanType — значение столбца нельзя записать в поле такого типа.
+var errScanType = errors.New("unsupported conversion")
+
+func (f fieldScanner) Scan(src any) error {
+	if f.v.Addr().Type().Implements(scannerType) {
+		return f.v.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+	if src == nil {
+		f.v.SetZero()
+		return nil
+	}
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+	}
+
+	if f.v.Type() == timeType {
+		switch s := src.(type) {
+		case time.Time:
+			f.v.Set(reflect.ValueOf(s))
+			return nil
+		case string:
+			t, err := parseTime(s)
+			if err != nil {
+				return err
+			}
+			f.v.Set(reflect.ValueOf(t))
+			return nil
+		}
+		return fmt.Errorf("%w: %T into %s", errScanType, src, f.v.Type())
+	}
+
+	switch f.v.Kind() {
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			f.v.SetString(s)
+			return nil
+		case int64:
+			f.v.SetString(strconv.FormatInt(s, 10))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch s := src.(type) {
+		case int64:
+			if f.v.OverflowInt(s) {
+				return fmt.Errorf("%w: %d overflows %s", errScanType, s, f.v.Type())
+			}
+			f.v.SetInt(s)
+			return nil
+		case string:
+			n, err := strconv.ParseInt(s, 10, f.v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			f.v.SetInt(n)
+			return nil
+		}
+	case reflect.Bool:
+		switch s := src.(type) {
+		case bool:
+			f.v.SetBool(s)
+			return nil
+		case int64:
+			f.v.SetBool(s != 0)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch s := src.(type) {
+		case float64:
+			f.v.SetFloat(s)
+			return nil
+		case int64:
+			f.v.SetFloat(float64(s))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %T into %s", errScanType, src, f.v.Type())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanQuery выполняет запрос и читает первую строку результата в *dest
+// по именам столбцов.
+func scanQuery(t *testing.T, db *sql.DB, query string, dest any) error {
+	t.Helper()
+
+	rows, err := db.Query(query)
+	require.NoError(t, err)
+	defer rows.Close()
+	require.True(t, rows.Next(), "query returned no rows")
+
+	return scanRows(rows, dest)
+}
+
+// Тест проверяет, что столбцы из тегов db перечисляются в порядке
+// объявления полей и совпадают со сгенерированным списком столбцов клиента
+func Test_ColumnList(t *testing.T) {
+	assert.Equal(t, clientColumns, columnList[Client]())
+	assert.Same(t, columnsOf(reflect.TypeOf(Client{})), columnsOf(reflect.TypeOf(Client{})), "the description is cached")
+}
+
+// Тест проверяет чтение строки в клиента по именам столбцов, в том числе
+// флагов, хранимых числами, и времени в формате хранения
+func Test_ScanRows(t *testing.T) {
+	db := openMemoryDB(t)
+
+	var cl Client
+	require.NoError(t, scanQuery(t, db, `SELECT 7 AS id, 'Иванов Иван' AS fio, 'ivanov' AS login, '19850615' AS birthday,
+		'ivanov@mail.com' AS email, 'alice' AS owner_id, 1 AS marketing_consent, '2024-03-01T12:00:00Z' AS consent_updated_at,
+		'blocked' AS status`, &cl))
+	assert.Equal(t, Client{
+		ID: 7, FIO: "Иванов Иван", Login: "ivanov", Birthday: "19850615", Email: "ivanov@mail.com", OwnerID: "alice",
+		MarketingConsent: true, ConsentUpdatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Status: StatusBlocked,
+	}, cl)
+}
+
+// Тест проверяет, что NULL записывается как нулевое значение поля любого
+// типа, а прежние значения полей не остаются
+func Test_ScanRows_NULL(t *testing.T) {
+	db := openMemoryDB(t)
+
+	cl := newTestClient(func(cl *Client) { cl.ID = 1; cl.MarketingConsent = true; cl.ConsentUpdatedAt = time.Now() })
+	require.NoError(t, scanQuery(t, db, `SELECT NULL AS id, NULL AS fio, NULL AS marketing_consent, NULL AS consent_updated_at, NULL AS status`, &cl))
+	assert.Equal(t, Client{}, cl)
+}
+
+// Тест проверяет, что поля, столбцов которых нет в результате, обнуляются
+func Test_ScanRows_MissingColumns(t *testing.T) {
+	db := openMemoryDB(t)
+
+	cl := newTestClient()
+	require.NoError(t, scanQuery(t, db, `SELECT 7 AS id, 'a@mail.com' AS email`, &cl))
+	assert.Equal(t, Client{ID: 7, Email: "a@mail.com"}, cl)
+}
+
+// Тест проверяет, что столбец без поля и значение неподходящего типа
+// возвращают ошибку и оставляют структуру нулевой
+func Test_ScanRows_Errors(t *testing.T) {
+	db := openMemoryDB(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"ExtraColumn", `SELECT 7 AS id, '+7 900' AS phone`, `column "phone" has no field in main.Client`},
+		{"NotANumber", `SELECT 'seven' AS id`, `parsing "seven"`},
+		{"BadTime", `SELECT 'yesterday' AS consent_updated_at`, `cannot parse "yesterday"`},
+		{"BlobIntoBool", `SELECT x'01' AS marketing_consent`, "unsupported conversion: string into bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := newTestClient()
+			err := scanQuery(t, db, tt.query, &cl)
+			require.ErrorContains(t, err, tt.want)
+			if tt.name != "ExtraColumn" {
+				assert.Equal(t, Client{}, cl)
+			}
+		})
+	}
+
+	var cl Client
+	assert.ErrorContains(t, scanStruct(nil, nil, cl), "must be a non-nil pointer to struct")
+	assert.ErrorContains(t, scanStruct(nil, nil, (*Client)(nil)), "must be a non-nil pointer to struct")
+}
+
+// Тест проверяет поля встроенных структур, поля, реализующие sql.Scanner,
+// и пропуск полей без тега db или с тегом «-»
+func Test_ScanRows_StructTags(t *testing.T) {
+	db := openMemoryDB(t)
+
+	type row struct {
+		Client
+		Note     sql.NullString `db:"note"`
+		Amount   float64        `db:"amount"`
+		Internal string         `db:"-"`
+		Untagged string
+	}
+	assert.Equal(t, "id, fio, login, birthday, email, owner_id, marketing_consent, consent_updated_at, status, note, amount", columnList[row]())
+
+	var got row
+	require.NoError(t, scanQuery(t, db, `SELECT 7 AS id, 'Иванов' AS fio, NULL AS note, 2.5 AS amount`, &got))
+	assert.Equal(t, row{Client: Client{ID: 7, FIO: "Иванов"}, Amount: 2.5}, got)
+
+	require.NoError(t, scanQuery(t, db, `SELECT 'call back' AS note, 3 AS amount`, &got))
+	assert.Equal(t, row{Note: sql.NullString{String: "call back", Valid: true}, Amount: 3}, got)
+
+	require.ErrorContains(t, scanQuery(t, db, `SELECT 'x' AS Untagged`, &got), `column "Untagged" has no field`)
+}
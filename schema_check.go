@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Режимы проверки столбцов clients при создании репозитория (см.
+// NewCheckedRepository, DBConfig.SchemaCheck).
+const (
+	// SchemaCheckError — расхождение столбцов не даёт создать репозиторий.
+	SchemaCheckError = "error"
+	// SchemaCheckWarn — расхождение пишется в журнал с уровнем WARN.
+	SchemaCheckWarn = "warn"
+)
+
+// WithSchemaDriftWarning понижает расхождение столбцов clients при
+// создании репозитория через NewCheckedRepository до предупреждения в
+// logger: репозиторий создаётся, например, на время выкатки миграции.
+func WithSchemaDriftWarning(logger *slog.Logger) Option {
+	return func(r *Repository) {
+		r.schemaDriftLog = logger
+	}
+}
+
+// NewCheckedRepository создаёт репозиторий, как NewRepository, и сначала
+// сверяет столбцы таблицы clients и их типы с теми, которые ожидает код
+// (см. ClientColumnsDrift). При расхождении возвращается ErrSchemaDrift с
+// перечнем столбцов: без этого отсутствующий столбец обнаружился бы
+// только ошибкой первого запроса, а изменённый тип — не обнаружился бы
+// совсем. С WithSchemaDriftWarning расхождение только пишется в журнал.
+func NewCheckedRepository(ctx context.Context, db *sql.DB, opts ...Option) (*Repository, error) {
+	r := NewRepository(db, opts...)
+
+	diffs, err := ClientColumnsDrift(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("check clients schema: %w", err)
+	}
+	if len(diffs) == 0 {
+		return r, nil
+	}
+
+	list := make([]string, len(diffs))
+	for i, d := range diffs {
+		list[i] = d.String()
+	}
+	if r.schemaDriftLog != nil {
+		r.schemaDriftLog.WarnContext(ctx, "clients schema drift", slog.Any("differences", list))
+		return r, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrSchemaDrift, strings.Join(list, "; "))
+}
+
+// ClientColumnsDrift сравнивает столбцы таблицы clients в db и их
+// объявленные типы со столбцами, которые дают миграции текущего кода.
+// В отличие от SchemaDrift ограничения, значения по умолчанию, индексы и
+// другие таблицы не сравниваются: проверка дешёвая и подходит для запуска.
+func ClientColumnsDrift(ctx context.Context, db *sql.DB) ([]SchemaDifference, error) {
+	want, err := expectedClientColumns()
+	if err != nil {
+		return nil, fmt.Errorf("build expected schema: %w", err)
+	}
+	got, err := clientColumnTypes(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var report SchemaReport
+	diffSchemaObjects(&report, SchemaColumn, want, got)
+	sort.Slice(report.Differences, func(i, j int) bool {
+		return report.Differences[i].Name < report.Differences[j].Name
+	})
+
+	return report.Differences, nil
+}
+
+// expectedClientColumns — типы столбцов clients пустой БД после всех
+// миграций; вычисляются один раз.
+var expectedClientColumns = sync.OnceValues(func() (map[string]string, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := Migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return clientColumnTypes(ctx, db)
+})
+
+// clientColumnTypes возвращает объявленные типы столбцов clients по
+// именам вида «clients.столбец».
+func clientColumnTypes(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, type FROM pragma_table_info('clients')")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		columns["clients."+name] = typ
+	}
+
+	return columns, rows.Err()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createClientsTable создаёт в пустой БД таблицу clients со столбцами,
+// которые ожидает код, изменёнными функцией mod (имя столбца — тип).
+func createClientsTable(t *testing.T, mod func(columns map[string]string)) *sql.DB {
+	t.Helper()
+
+	want, err := expectedClientColumns()
+	require.NoError(t, err)
+	columns := make(map[string]string)
+	for name, typ := range want {
+		columns[strings.TrimPrefix(name, "clients.")] = typ
+	}
+	mod(columns)
+
+	defs := make([]string, 0, len(columns))
+	for name, typ := range columns {
+		defs = append(defs, name+" "+typ)
+	}
+	sort.Strings(defs)
+
+	db := openMemoryDB(t)
+	_, err = db.Exec("CREATE TABLE clients (" + strings.Join(defs, ", ") + ")")
+	require.NoError(t, err)
+
+	return db
+}
+
+// Тест проверяет, что репозиторий над БД с актуальной схемой создаётся
+// без ошибок
+func Test_CheckedRepository(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+
+	repo, err := NewCheckedRepository(ctx, db)
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient())
+	assert.NoError(t, err)
+
+	diffs, err := ClientColumnsDrift(ctx, db)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+// Тест проверяет, что отсутствующий столбец, изменённый тип и лишний
+// столбец clients не дают создать репозиторий и перечисляются в ошибке
+func Test_CheckedRepository_Drift(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		mod  func(columns map[string]string)
+		want string
+	}{
+		{
+			name: "MissingColumn",
+			mod:  func(columns map[string]string) { delete(columns, "status") },
+			want: "missing column clients.status",
+		},
+		{
+			name: "TypeMismatch",
+			mod:  func(columns map[string]string) { columns["birthday"] = "TEXT" },
+			want: "changed column clients.birthday (want CHAR(8), got TEXT)",
+		},
+		{
+			name: "UnexpectedColumn",
+			mod:  func(columns map[string]string) { columns["phone"] = "TEXT" },
+			want: "unexpected column clients.phone",
+		},
+		{
+			name: "Several",
+			mod: func(columns map[string]string) {
+				delete(columns, "status")
+				columns["marketing_consent"] = "TEXT"
+			},
+			want: "changed column clients.marketing_consent (want INTEGER, got TEXT); missing column clients.status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := createClientsTable(t, tt.mod)
+
+			repo, err := NewCheckedRepository(ctx, db)
+			require.ErrorIs(t, err, ErrSchemaDrift)
+			assert.Equal(t, ErrSchemaDrift.Error()+": "+tt.want, err.Error())
+			assert.Nil(t, repo)
+		})
+	}
+
+	_, err := NewCheckedRepository(ctx, openMemoryDB(t))
+	assert.ErrorContains(t, err, "missing column clients.id")
+}
+
+// Тест проверяет, что с WithSchemaDriftWarning, в том числе заданным
+// настройкой db.schema_check, расхождение пишется в журнал, а репозиторий
+// создаётся
+func Test_CheckedRepository_Warning(t *testing.T) {
+	ctx := context.Background()
+	db := createClientsTable(t, func(columns map[string]string) { delete(columns, "status") })
+
+	var logs bytes.Buffer
+	repo, err := NewCheckedRepository(ctx, db, WithSchemaDriftWarning(slog.New(slog.NewTextHandler(&logs, nil))))
+	require.NoError(t, err)
+	assert.NotNil(t, repo)
+	assert.Contains(t, logs.String(), `level=WARN msg="clients schema drift" differences="[missing column clients.status]"`)
+
+	cfg := DefaultConfig()
+	cfg.DB.SchemaCheck = SchemaCheckWarn
+	require.NoError(t, cfg.Validate())
+	opts, err := cfg.RepositoryOptions()
+	require.NoError(t, err)
+	_, err = NewCheckedRepository(ctx, db, opts...)
+	assert.NoError(t, err)
+
+	opts, err = DefaultConfig().RepositoryOptions()
+	require.NoError(t, err)
+	_, err = NewCheckedRepository(ctx, db, opts...)
+	assert.ErrorIs(t, err, ErrSchemaDrift)
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrSchemaDrift возвращается CheckSchema, если схема БД отличается от
+// схемы, которую дают миграции текущего кода.
+var ErrSchemaDrift = errors.New("database schema drift")
+
+// Объекты схемы, сравниваемые SchemaDrift.
+const (
+	SchemaTable      = "table"
+	SchemaColumn     = "column"
+	SchemaIndex      = "index"
+	SchemaForeignKey = "foreign_key"
+)
+
+// Виды расхождений схемы.
+const (
+	// SchemaMissing — объекта, который создают миграции, нет в БД.
+	SchemaMissing = "missing"
+	// SchemaUnexpected — объект есть в БД, но миграции его не создают.
+	SchemaUnexpected = "unexpected"
+	// SchemaChanged — объект есть в БД, но определён иначе.
+	SchemaChanged = "changed"
+)
+
+// auxiliaryTables — служебные таблицы, которые создаются не миграциями и
+// не считаются расхождением.
+var auxiliaryTables = map[string]bool{
+	"transfer_progress": true,
+}
+
+// SchemaDifference — расхождение схемы БД с ожидаемой.
+type SchemaDifference struct {
+	Object string `json:"object"`
+	// Name — имя таблицы или индекса; для столбцов и внешних ключей —
+	// «таблица.столбец».
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	// Expected и Actual — определение объекта по миграциям и в БД.
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (d SchemaDifference) String() string {
+	s := fmt.Sprintf("%s %s %s", d.Change, d.Object, d.Name)
+	if d.Change == SchemaChanged {
+		s += fmt.Sprintf(" (want %s, got %s)", d.Expected, d.Actual)
+	}
+
+	return s
+}
+
+// SchemaReport — результат SchemaDrift.
+type SchemaReport struct {
+	Differences []SchemaDifference `json:"differences"`
+}
+
+// OK сообщает, что схема совпадает с ожидаемой.
+func (r SchemaReport) OK() bool {
+	return len(r.Differences) == 0
+}
+
+// SchemaDrift сравнивает схему БД — таблицы, столбцы с типами,
+// ограничениями NOT NULL, значениями по умолчанию и первичными ключами,
+// индексы (в том числе уникальные ограничения) и внешние ключи — со
+// схемой, которую дают все миграции текущего кода, применённые к пустой
+// БД. Расхождения возвращаются в отчёте по объектам в порядке имён.
+func SchemaDrift(ctx context.Context, db *sql.DB) (SchemaReport, error) {
+	want, err := expectedSchema(ctx)
+	if err != nil {
+		return SchemaReport{}, fmt.Errorf("build expected schema: %w", err)
+	}
+	got, err := inspectSchema(ctx, db)
+	if err != nil {
+		return SchemaReport{}, err
+	}
+	for table := range auxiliaryTables {
+		got.drop(table)
+	}
+
+	var report SchemaReport
+	diffSchemaObjects(&report, SchemaTable, want.tables, got.tables)
+	for table := range want.tables {
+		if _, ok := got.tables[table]; ok {
+			diffSchemaObjects(&report, SchemaColumn, want.columns[table], got.columns[table])
+		}
+	}
+	diffSchemaObjects(&report, SchemaIndex, want.indexes, got.indexes)
+	diffSchemaObjects(&report, SchemaForeignKey, want.foreignKeys, got.foreignKeys)
+
+	sort.Slice(report.Differences, func(i, j int) bool {
+		a, b := report.Differences[i], report.Differences[j]
+		if a.Object != b.Object {
+			return schemaObjectOrder[a.Object] < schemaObjectOrder[b.Object]
+		}
+		return a.Name < b.Name
+	})
+
+	return report, nil
+}
+
+var schemaObjectOrder = map[string]int{SchemaTable: 0, SchemaColumn: 1, SchemaIndex: 2, SchemaForeignKey: 3}
+
+// CheckSchema возвращает ErrSchemaDrift с перечнем расхождений, если
+// схема БД отличается от ожидаемой (см. SchemaDrift).
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	report, err := SchemaDrift(ctx, db)
+	if err != nil {
+		return err
+	}
+	if report.OK() {
+		return nil
+	}
+
+	diffs := make([]string, len(report.Differences))
+	for i, d := range report.Differences {
+		diffs[i] = d.String()
+	}
+
+	return fmt.Errorf("%w: %s", ErrSchemaDrift, strings.Join(diffs, "; "))
+}
+
+// diffSchemaObjects добавляет в отчёт расхождения между ожидаемыми и
+// найденными определениями объектов одного вида.
+func diffSchemaObjects(report *SchemaReport, object string, want, got map[string]string) {
+	for name, def := range want {
+		actual, ok := got[name]
+		switch {
+		case !ok:
+			report.Differences = append(report.Differences, SchemaDifference{Object: object, Name: name, Change: SchemaMissing, Expected: def})
+		case actual != def:
+			report.Differences = append(report.Differences, SchemaDifference{Object: object, Name: name, Change: SchemaChanged, Expected: def, Actual: actual})
+		}
+	}
+	for name, def := range got {
+		if _, ok := want[name]; !ok {
+			report.Differences = append(report.Differences, SchemaDifference{Object: object, Name: name, Change: SchemaUnexpected, Actual: def})
+		}
+	}
+}
+
+// schemaSnapshot — определения объектов схемы по именам.
+type schemaSnapshot struct {
+	tables map[string]string
+	// columns — определения столбцов по таблицам.
+	columns     map[string]map[string]string
+	indexes     map[string]string
+	foreignKeys map[string]string
+	// owners — таблица каждого индекса и внешнего ключа.
+	owners map[string]string
+}
+
+// drop удаляет из снимка таблицу вместе с её индексами и внешними ключами.
+func (s *schemaSnapshot) drop(table string) {
+	delete(s.tables, table)
+	delete(s.columns, table)
+	for name, owner := range s.owners {
+		if owner == table {
+			delete(s.indexes, name)
+			delete(s.foreignKeys, name)
+		}
+	}
+}
+
+// expectedSchema возвращает схему пустой БД после всех миграций.
+func expectedSchema(ctx context.Context) (*schemaSnapshot, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := Migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return inspectSchema(ctx, db)
+}
+
+// inspectSchema читает схему БД из sqlite_master и PRAGMA table_info,
+// index_list и foreign_key_list.
+func inspectSchema(ctx context.Context, db *sql.DB) (*schemaSnapshot, error) {
+	tables, err := queryStrings(ctx, db, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schemaSnapshot{
+		tables:      make(map[string]string),
+		columns:     make(map[string]map[string]string),
+		indexes:     make(map[string]string),
+		foreignKeys: make(map[string]string),
+		owners:      make(map[string]string),
+	}
+	for _, table := range tables {
+		columns, err := inspectColumns(ctx, db, table)
+		if err != nil {
+			return nil, err
+		}
+		// Таблицы сравниваются по наличию, определения — по столбцам
+		s.tables[table] = ""
+		s.columns[table] = columns
+
+		if err := inspectIndexes(ctx, db, table, s); err != nil {
+			return nil, err
+		}
+		if err := inspectForeignKeys(ctx, db, table, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func inspectColumns(ctx context.Context, db *sql.DB, table string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var (
+			name, typ string
+			notNull   bool
+			def       sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&name, &typ, &notNull, &def, &pk); err != nil {
+			return nil, err
+		}
+
+		parts := []string{typ}
+		if notNull {
+			parts = append(parts, "NOT NULL")
+		}
+		if def.Valid {
+			parts = append(parts, "DEFAULT "+def.String)
+		}
+		if pk > 0 {
+			parts = append(parts, fmt.Sprintf("PRIMARY KEY %d", pk))
+		}
+		columns[table+"."+name] = strings.Join(parts, " ")
+	}
+
+	return columns, rows.Err()
+}
+
+// inspectIndexes добавляет в снимок индексы таблицы. Индексы, которые
+// SQLite создаёт для UNIQUE и PRIMARY KEY, получают имена вида
+// «таблица (столбцы)», так как их собственные имена зависят от порядка
+// объявления ограничений.
+func inspectIndexes(ctx context.Context, db *sql.DB, table string, s *schemaSnapshot) error {
+	rows, err := db.QueryContext(ctx, `SELECT name, "unique", origin FROM pragma_index_list(?)`, table)
+	if err != nil {
+		return err
+	}
+	type index struct {
+		name, origin string
+		unique       bool
+	}
+	var indexes []index
+	for rows.Next() {
+		var idx index
+		if err := rows.Scan(&idx.name, &idx.unique, &idx.origin); err != nil {
+			rows.Close()
+			return err
+		}
+		indexes = append(indexes, idx)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		columns, err := queryStrings(ctx, db, "SELECT name FROM pragma_index_info(?) ORDER BY seqno", idx.name)
+		if err != nil {
+			return err
+		}
+		def := fmt.Sprintf("ON %s (%s)", table, strings.Join(columns, ", "))
+		if idx.unique {
+			def = "UNIQUE " + def
+		}
+
+		name := idx.name
+		if idx.origin != "c" {
+			name = fmt.Sprintf("%s (%s)", table, strings.Join(columns, ", "))
+		}
+		s.indexes[name] = def
+		s.owners[name] = table
+	}
+
+	return nil
+}
+
+func inspectForeignKeys(ctx context.Context, db *sql.DB, table string, s *schemaSnapshot) error {
+	rows, err := db.QueryContext(ctx, `SELECT "table", "from", "to", on_delete FROM pragma_foreign_key_list(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			parent, from, onDelete string
+			to                     sql.NullString
+		)
+		if err := rows.Scan(&parent, &from, &to, &onDelete); err != nil {
+			return err
+		}
+
+		name := table + "." + from
+		s.foreignKeys[name] = fmt.Sprintf("REFERENCES %s (%s) ON DELETE %s", parent, to.String, onDelete)
+		s.owners[name] = table
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет, что у БД после всех миграций расхождений схемы нет, а
+// служебная таблица переноса расхождением не считается
+func Test_SchemaDrift_Clean(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	_, err := db.Exec(transferProgressSchema)
+	require.NoError(t, err)
+
+	report, err := SchemaDrift(ctx, db)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%v", report.Differences)
+	require.NoError(t, CheckSchema(ctx, db))
+}
+
+// Тест проверяет обнаружение добавленных, удалённых и изменённых таблиц,
+// столбцов и индексов
+func Test_SchemaDrift(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+
+	for _, stmt := range []string{
+		"CREATE TABLE extra (id INTEGER PRIMARY KEY)",
+		"ALTER TABLE clients ADD COLUMN nickname TEXT",
+		"ALTER TABLE audit_log DROP COLUMN request_id",
+		"DROP INDEX orders_client_id",
+		"CREATE INDEX clients_email ON clients (email)",
+		// Таблица пересоздаётся без NOT NULL и UNIQUE
+		"DROP TABLE tags",
+		"CREATE TABLE tags (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)",
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	report, err := SchemaDrift(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, []SchemaDifference{
+		{Object: SchemaTable, Name: "extra", Change: SchemaUnexpected},
+		{Object: SchemaColumn, Name: "audit_log.request_id", Change: SchemaMissing, Expected: `TEXT NOT NULL DEFAULT ""`},
+		{Object: SchemaColumn, Name: "clients.nickname", Change: SchemaUnexpected, Actual: "TEXT"},
+		{Object: SchemaColumn, Name: "tags.name", Change: SchemaChanged, Expected: "TEXT NOT NULL", Actual: "TEXT"},
+		{Object: SchemaIndex, Name: "clients_email", Change: SchemaUnexpected, Actual: "ON clients (email)"},
+		{Object: SchemaIndex, Name: "orders_client_id", Change: SchemaMissing, Expected: "ON orders (client_id)"},
+		{Object: SchemaIndex, Name: "tags (name)", Change: SchemaMissing, Expected: "UNIQUE ON tags (name)"},
+	}, report.Differences)
+
+	err = CheckSchema(ctx, db)
+	require.ErrorIs(t, err, ErrSchemaDrift)
+	assert.Contains(t, err.Error(), "changed column tags.name (want TEXT NOT NULL, got TEXT)")
+}
+
+// Тест проверяет, что неприменённые миграции тоже считаются расхождением
+func Test_SchemaDrift_PendingMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	require.NoError(t, Rollback(ctx, db, 1))
+
+	report, err := SchemaDrift(ctx, db)
+	require.NoError(t, err)
+	require.False(t, report.OK())
+	for _, d := range report.Differences {
+		assert.Equal(t, SchemaMissing, d.Change, "%s", d)
+	}
+}
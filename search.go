@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Параметры поиска SearchIndex.
+const (
+	// DefaultSearchLimit — сколько результатов Search возвращает, если
+	// limit не задан.
+	DefaultSearchLimit = 20
+	// SearchFuzziness — сколько опечаток (правок Левенштейна) в слове
+	// запроса допускает Search.
+	SearchFuzziness = 1
+)
+
+// searchFields — индексируемые поля клиента.
+var searchFields = []string{"fio", "login", "email"}
+
+// searchDocument — документ индекса для клиента.
+type searchDocument struct {
+	FIO   string `json:"fio"`
+	Login string `json:"login"`
+	Email string `json:"email"`
+}
+
+// SearchHit — найденный клиент и релевантность совпадения.
+type SearchHit struct {
+	ClientID int     `json:"client_id"`
+	Score    float64 `json:"score"`
+}
+
+// SearchIndex — встроенный полнотекстовый индекс Bleve по ФИО, логину и
+// email клиентов для поиска с опечатками без внешнего сервиса. Индекс —
+// EventSink для OutboxRelay: по каждому событию документ клиента
+// индексируется заново по clients или удаляется, если клиента больше нет,
+// поэтому повторы событий безопасны. Индекс хранит расшифрованные
+// значения, поэтому его каталог защищается так же, как БД.
+type SearchIndex struct {
+	repo  *Repository
+	index bleve.Index
+}
+
+// OpenSearchIndex открывает индекс в каталоге path или создаёт его, если
+// каталога нет; пустой path создаёт индекс в памяти. Документы читаются
+// из БД репозитория repo. Новый индекс пуст: существующих клиентов в него
+// добавляет Reindex.
+func OpenSearchIndex(path string, repo *Repository) (*SearchIndex, error) {
+	if path == "" {
+		index, err := bleve.NewMemOnly(searchMapping())
+		if err != nil {
+			return nil, err
+		}
+		return &SearchIndex{repo: repo, index: index}, nil
+	}
+
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, searchMapping())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchIndex{repo: repo, index: index}, nil
+}
+
+// searchMapping возвращает схему индекса: только поля searchFields со
+// стандартным анализатором, без хранения исходных значений.
+func searchMapping() mapping.IndexMapping {
+	doc := bleve.NewDocumentStaticMapping()
+	for _, name := range searchFields {
+		field := bleve.NewTextFieldMapping()
+		field.Analyzer = "standard"
+		field.Store = false
+		field.IncludeTermVectors = false
+		doc.AddFieldMappingsAt(name, field)
+	}
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = doc
+	m.StoreDynamic = false
+
+	return m
+}
+
+// Close закрывает индекс.
+func (s *SearchIndex) Close() error {
+	return s.index.Close()
+}
+
+// Publish обновляет документ клиента события.
+func (s *SearchIndex) Publish(ctx context.Context, event ClientEvent) (err error) {
+	ctx, end := s.repo.startOperation(ctx, "search_index")
+	defer end(&err)
+
+	cl, err := s.repo.indexedClient(ctx, event.ClientID)
+	if errors.Is(err, ErrClientNotFound) {
+		return s.index.Delete(strconv.Itoa(event.ClientID))
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.index.Index(strconv.Itoa(cl.ID), searchDocument{FIO: cl.FIO, Login: cl.Login, Email: cl.Email})
+}
+
+// indexedClient возвращает клиента для индекса без учёта ограничения по
+// владельцу (см. WithOwnerRestriction): индекс общий для всех
+// пользователей.
+func (r *Repository) indexedClient(ctx context.Context, id int) (Client, error) {
+	row := r.conn().QueryRowContext(ctx, "SELECT "+clientColumns+" FROM clients WHERE id = :id"+notDeleted, sql.Named("id", id))
+	cl, err := scanClient(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Client{}, ErrClientNotFound
+	}
+	if err != nil {
+		return Client{}, err
+	}
+
+	return r.decrypt(cl)
+}
+
+// Reindex заново индексирует всех клиентов и удаляет из индекса
+// документы клиентов, которых больше нет, и возвращает число
+// проиндексированных клиентов. Используется для заполнения нового индекса
+// и после сбоев синхронизации.
+func (s *SearchIndex) Reindex(ctx context.Context) (_ int, err error) {
+	ctx, end := s.repo.startOperation(ctx, "search_reindex")
+	defer end(&err)
+
+	batch := s.index.NewBatch()
+	indexed := make(map[string]struct{})
+	err = s.repo.forEachIndexed(ctx, " ORDER BY id", nil, func(cl Client) error {
+		id := strconv.Itoa(cl.ID)
+		indexed[id] = struct{}{}
+		if err := batch.Index(id, searchDocument{FIO: cl.FIO, Login: cl.Login, Email: cl.Email}); err != nil {
+			return err
+		}
+		if batch.Size() < DefaultTransferBatch {
+			return nil
+		}
+		if err := s.index.Batch(batch); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := s.index.DocCount()
+	if err != nil {
+		return 0, err
+	}
+	req := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), int(total), 0, false)
+	res, err := s.index.SearchInContext(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	for _, hit := range res.Hits {
+		if _, ok := indexed[hit.ID]; !ok {
+			batch.Delete(hit.ID)
+		}
+	}
+
+	if err := s.index.Batch(batch); err != nil {
+		return 0, err
+	}
+
+	return len(indexed), nil
+}
+
+// Search ищет клиентов, у которых ФИО, логин или email содержат слова
+// text с точностью до SearchFuzziness опечаток в слове, и возвращает до
+// limit (0 — DefaultSearchLimit) результатов по убыванию релевантности.
+// Доступ к найденным клиентам не проверяется: их данные читаются через
+// репозиторий с ограничением по владельцу.
+func (s *SearchIndex) Search(ctx context.Context, text string, limit int) ([]SearchHit, error) {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	fields := make([]query.Query, len(searchFields))
+	for i, name := range searchFields {
+		q := bleve.NewMatchQuery(text)
+		q.SetField(name)
+		q.SetFuzziness(SearchFuzziness)
+		fields[i] = q
+	}
+
+	res, err := s.index.SearchInContext(ctx, bleve.NewSearchRequestOptions(bleve.NewDisjunctionQuery(fields...), limit, 0, false))
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		id, err := strconv.Atoi(hit.ID)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, SearchHit{ClientID: id, Score: hit.Score})
+	}
+
+	return hits, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSearch возвращает репозиторий, индекс в памяти и публикацию
+// событий outbox в индекс.
+func setupSearch(t *testing.T) (*Repository, *SearchIndex, *OutboxRelay) {
+	t.Helper()
+
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+	repo := NewRepository(db)
+	index, err := OpenSearchIndex("", repo)
+	require.NoError(t, err)
+	t.Cleanup(func() { index.Close() })
+
+	return repo, index, NewOutboxRelay(repo, index)
+}
+
+// searchIDs возвращает ID клиентов, найденных по text.
+func searchIDs(t *testing.T, index *SearchIndex, text string) []int {
+	t.Helper()
+
+	hits, err := index.Search(context.Background(), text, 0)
+	require.NoError(t, err)
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ClientID
+	}
+
+	return ids
+}
+
+// Тест проверяет поиск с опечатками по ФИО, логину и email
+func Test_SearchIndex_Search(t *testing.T) {
+	ctx := context.Background()
+	repo, index, relay := setupSearch(t)
+
+	ivanov, err := repo.Insert(ctx, newTestClient(func(cl *Client) {
+		cl.FIO = "Иванов Иван Иванович"
+		cl.Login = "ivanov"
+		cl.Email = "ivan@example.com"
+	}))
+	require.NoError(t, err)
+	petrov, err := repo.Insert(ctx, newTestClient(func(cl *Client) {
+		cl.FIO = "Петров Пётр"
+		cl.Login = "petrov"
+		cl.Email = "peter@mail.com"
+	}))
+	require.NoError(t, err)
+	_, err = relay.Relay(ctx)
+	require.NoError(t, err)
+
+	for text, want := range map[string][]int{
+		"иванов":      {ivanov},
+		"ИВОНОВ":      {ivanov},
+		"petrof":      {petrov},
+		"peter":       {petrov},
+		"сидоров":     {},
+		"петров иван": {ivanov, petrov},
+	} {
+		assert.ElementsMatch(t, want, searchIDs(t, index, text), text)
+	}
+
+	hits, err := index.Search(ctx, "иванов", 0)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Positive(t, hits[0].Score)
+}
+
+// Тест проверяет, что индекс следует за изменением клиента: старые
+// значения больше не находятся, новые находятся
+func Test_SearchIndex_SyncAfterUpdate(t *testing.T) {
+	ctx := context.Background()
+	repo, index, relay := setupSearch(t)
+
+	id, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Smirnova Anna" }))
+	require.NoError(t, err)
+	_, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{id}, searchIDs(t, index, "smirnova"))
+
+	cl, err := repo.Select(ctx, id)
+	require.NoError(t, err)
+	cl.FIO = "Kuznetsova Anna"
+	require.NoError(t, repo.Update(ctx, cl))
+
+	// До публикации события индекс отстаёт
+	assert.Equal(t, []int{id}, searchIDs(t, index, "smirnova"))
+	_, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, searchIDs(t, index, "smirnova"))
+	assert.Equal(t, []int{id}, searchIDs(t, index, "kuznetsowa"))
+}
+
+// Тест проверяет удаление из индекса удалённых и объединённых клиентов
+func Test_SearchIndex_Deletion(t *testing.T) {
+	ctx := context.Background()
+	repo, index, relay := setupSearch(t)
+
+	deleted, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Deleted Person" }))
+	require.NoError(t, err)
+	keep, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Merged Person" }))
+	require.NoError(t, err)
+	duplicate, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.FIO = "Merged Person" }))
+	require.NoError(t, err)
+	_, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{deleted, keep, duplicate}, searchIDs(t, index, "person"))
+
+	require.NoError(t, repo.Delete(ctx, deleted))
+	require.NoError(t, repo.MergeClients(ctx, keep, duplicate))
+	_, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{keep}, searchIDs(t, index, "person"))
+
+	// Повторное событие удалённого клиента ничего не ломает
+	n, err := repo.ReplayEvents(ctx, time.Time{}, time.Time{}, index)
+	require.NoError(t, err)
+	assert.Positive(t, n)
+	assert.Equal(t, []int{keep}, searchIDs(t, index, "person"))
+}
+
+// Тест проверяет полную переиндексацию индекса на диске: добавляются
+// клиенты, изменённые без публикации событий, и удаляются устаревшие
+// документы
+func Test_SearchIndex_Reindex(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db)
+	path := filepath.Join(t.TempDir(), "clients.bleve")
+
+	index, err := OpenSearchIndex(path, repo)
+	require.NoError(t, err)
+	ids := make([]int, 3)
+	for i := range ids {
+		ids[i], err = repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Login = "orlova" }))
+		require.NoError(t, err)
+	}
+	_, err = NewOutboxRelay(repo, index).Relay(ctx)
+	require.NoError(t, err)
+	require.NoError(t, index.Close())
+
+	// Изменения без индекса: удаление и новый клиент
+	require.NoError(t, repo.Delete(ctx, ids[0]))
+	added, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Login = "orlova" }))
+	require.NoError(t, err)
+
+	index, err = OpenSearchIndex(path, repo)
+	require.NoError(t, err)
+	t.Cleanup(func() { index.Close() })
+	assert.ElementsMatch(t, ids, searchIDs(t, index, "orlova"))
+
+	n, err := index.Reindex(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.ElementsMatch(t, []int{ids[1], ids[2], added}, searchIDs(t, index, "orlova"))
+}
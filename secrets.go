@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DSNSecret — имя секрета со строкой подключения к БД.
+const DSNSecret = "CLIENTS_DB_DSN"
+
+// ErrSecretNotFound возвращается провайдером, если секрета с таким именем нет.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretsProvider выдаёт секреты (строку подключения, пароли) по имени.
+// Реализации для внешних хранилищ (Vault, AWS Secrets Manager) подключаются
+// через этот интерфейс.
+type SecretsProvider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// EnvSecrets читает секреты из переменных окружения.
+type EnvSecrets struct{}
+
+func (EnvSecrets) Secret(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+
+	return value, nil
+}
+
+// FileSecrets читает секреты из файлов каталога Dir: имя файла совпадает
+// с именем секрета (так монтируются секреты Docker и Kubernetes).
+type FileSecrets struct {
+	Dir string
+}
+
+func (p FileSecrets) Secret(_ context.Context, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SecretConnector — driver.Connector, который берёт строку подключения к
+// БД из SecretsProvider при каждом новом соединении. *sql.DB, открытая
+// через sql.OpenDB(connector), переживает ротацию секрета: новые
+// соединения открываются по новой строке, а уже открытые работают, пока
+// пул их не закроет (см. DBConfig.ConnMaxLifetime), поэтому репозиторий
+// над ней не нужно пересоздавать. Ошибочный секрет не ломает открытые
+// соединения: ошибку получают только запросы, которым нужно новое.
+type SecretConnector struct {
+	provider SecretsProvider
+	driver   driver.Driver
+}
+
+// NewSecretConnector создаёт connector для драйвера driverName,
+// зарегистрированного в database/sql, со строкой подключения из
+// секрета DSNSecret.
+func NewSecretConnector(provider SecretsProvider, driverName string) (*SecretConnector, error) {
+	// sql.Open не подключается к БД, а только находит драйвер
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return &SecretConnector{provider: provider, driver: db.Driver()}, nil
+}
+
+// Connect перечитывает строку подключения и открывает по ней соединение.
+func (c *SecretConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.provider.Secret(ctx, DSNSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+
+	return c.driver.Open(dsn)
+}
+
+func (c *SecretConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,104 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет, что один репозиторий над БД из SecretConnector
+// продолжает работать после ротации строки подключения посреди работы,
+// а новые соединения открываются уже по новой строке
+func Test_SecretConnector_RotatesMidRun(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secrets, 0o700))
+	setDSN := func(path string) {
+		t.Helper()
+		// mode=rw не создаёт файл, поэтому устаревшая строка подключения
+		// не откроет новую пустую БД
+		require.NoError(t, os.WriteFile(filepath.Join(secrets, DSNSecret), []byte("file:"+path+"?mode=rw\n"), 0o600))
+	}
+
+	first := filepath.Join(dir, "first.db")
+	setup, err := sql.Open("sqlite", first)
+	require.NoError(t, err)
+	require.NoError(t, Migrate(ctx, setup))
+	require.NoError(t, setup.Close())
+	setDSN(first)
+
+	cfg := DefaultConfig()
+	cfg.DB.SecretsDir = secrets
+	require.NoError(t, cfg.Validate())
+	db, err := cfg.DB.OpenDB()
+	require.NoError(t, err)
+	defer db.Close()
+	// Каждый запрос открывает новое соединение, как после истечения
+	// ConnMaxLifetime
+	db.SetMaxIdleConns(0)
+
+	repo := NewRepository(db)
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	// Ротация: БД переезжает, секрет указывает на новое место, а по
+	// старой строке подключиться больше нельзя
+	second := filepath.Join(dir, "second.db")
+	require.NoError(t, os.Rename(first, second))
+	setDSN(second)
+
+	_, err = repo.Select(ctx, id)
+	require.NoError(t, err, "repository should keep working after rotation")
+	moved, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	assert.Greater(t, moved, id)
+
+	// Ошибочный секрет ломает только новые соединения
+	setDSN(filepath.Join(dir, "missing.db"))
+	_, err = repo.Select(ctx, id)
+	require.Error(t, err)
+	setDSN(second)
+	_, err = repo.Select(ctx, moved)
+	require.NoError(t, err)
+
+	// Без секрета соединение не открывается
+	require.NoError(t, os.Remove(filepath.Join(secrets, DSNSecret)))
+	require.ErrorIs(t, db.PingContext(ctx), ErrSecretNotFound)
+}
+
+// Тест проверяет чтение секретов из переменных окружения и файлов
+func Test_SecretsProviders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv(DSNSecret, "env.db")
+
+		value, err := EnvSecrets{}.Secret(ctx, DSNSecret)
+		require.NoError(t, err)
+		assert.Equal(t, "env.db", value)
+
+		_, err = EnvSecrets{}.Secret(ctx, "CLIENTS_MISSING_SECRET")
+		require.ErrorIs(t, err, ErrSecretNotFound)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, DSNSecret), []byte("file.db\n"), 0o600))
+
+		value, err := FileSecrets{Dir: dir}.Secret(ctx, DSNSecret)
+		require.NoError(t, err)
+		assert.Equal(t, "file.db", value, "trailing newline should be trimmed")
+
+		_, err = FileSecrets{Dir: dir}.Secret(ctx, "missing")
+		require.ErrorIs(t, err, ErrSecretNotFound)
+	})
+}
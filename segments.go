@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxSegmentNameLen — наибольшая длина названия сегмента в символах.
+const maxSegmentNameLen = 64
+
+// SegmentCriteria — условия отбора клиентов в сегмент. Пустое условие
+// не ограничивает отбор; заданные условия должны выполняться одновременно.
+// Условия хранятся в JSON, поэтому при добавлении новых полей старые
+// определения остаются корректными.
+type SegmentCriteria struct {
+	// FIOContains — подстрока ФИО без учёта регистра.
+	FIOContains string `json:"fio_contains,omitempty"`
+	// EmailDomain — домен email, например "mail.com", без учёта регистра.
+	EmailDomain string `json:"email_domain,omitempty"`
+	// BornFrom и BornTo — границы даты рождения включительно в формате ГГГГММДД.
+	BornFrom string `json:"born_from,omitempty"`
+	BornTo   string `json:"born_to,omitempty"`
+	// Tags — метки клиента: все, а при AnyTag — хотя бы одна.
+	Tags     []string       `json:"tags,omitempty"`
+	AnyTag   bool           `json:"any_tag,omitempty"`
+	Statuses ClientStatuses `json:"statuses,omitempty"`
+}
+
+// Segment — сохранённый именованный сегмент клиентов.
+type Segment struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Criteria  SegmentCriteria `json:"criteria"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Validate проверяет условия сегмента. Ошибка оборачивает ErrValidation.
+func (c SegmentCriteria) Validate() error {
+	if strings.Contains(c.EmailDomain, "@") {
+		return fmt.Errorf("%w: email domain %q must not contain @", ErrValidation, c.EmailDomain)
+	}
+	for _, born := range []string{c.BornFrom, c.BornTo} {
+		if born == "" {
+			continue
+		}
+		if _, err := time.Parse(birthdayLayout, born); err != nil {
+			return fmt.Errorf("%w: birthday bound %q is not a valid YYYYMMDD date", ErrValidation, born)
+		}
+	}
+	if c.BornFrom != "" && c.BornTo != "" && c.BornFrom > c.BornTo {
+		return fmt.Errorf("%w: born_from %s is after born_to %s", ErrValidation, c.BornFrom, c.BornTo)
+	}
+	for _, tag := range c.Tags {
+		if _, err := normalizeTag(tag); err != nil {
+			return err
+		}
+	}
+	if c.AnyTag && len(c.Tags) == 0 {
+		return fmt.Errorf("%w: any_tag requires tags", ErrValidation)
+	}
+
+	return c.Statuses.Validate()
+}
+
+// ParseSegmentCriteria разбирает условия сегмента из JSON. Неизвестные
+// поля считаются ошибкой, чтобы опечатка в условии не расширяла сегмент.
+func ParseSegmentCriteria(data []byte) (SegmentCriteria, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var c SegmentCriteria
+	if err := dec.Decode(&c); err != nil {
+		return SegmentCriteria{}, fmt.Errorf("%w: segment criteria: %v", ErrValidation, err)
+	}
+	if err := c.Validate(); err != nil {
+		return SegmentCriteria{}, err
+	}
+
+	return c, nil
+}
+
+// match проверяет условия по полям клиента. Метки и статус отбираются
+// запросом (см. cond); поля проверяются после расшифровки.
+func (c SegmentCriteria) match(cl Client) bool {
+	if c.FIOContains != "" && !strings.Contains(strings.ToLower(cl.FIO), strings.ToLower(c.FIOContains)) {
+		return false
+	}
+	if c.EmailDomain != "" {
+		_, domain, _ := strings.Cut(cl.Email, "@")
+		if !strings.EqualFold(domain, c.EmailDomain) {
+			return false
+		}
+	}
+	if c.BornFrom != "" && cl.Birthday < c.BornFrom || c.BornTo != "" && cl.Birthday > c.BornTo {
+		return false
+	}
+
+	return true
+}
+
+// cond возвращает условие для forEach по меткам и статусам и его параметры.
+func (c SegmentCriteria) cond() (string, []any, error) {
+	var (
+		cond string
+		args []any
+	)
+	if len(c.Tags) > 0 {
+		match := MatchAllTags
+		if c.AnyTag {
+			match = MatchAnyTag
+		}
+		var err error
+		if cond, args, err = tagsCond(match, c.Tags); err != nil {
+			return "", nil, err
+		}
+	}
+
+	statusCond, statusArgs := c.Statuses.cond()
+	cond += statusCond
+	args = append(args, statusArgs...)
+
+	return cond, args, nil
+}
+
+func validateSegmentName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("%w: segment name is required", ErrValidation)
+	case !utf8.ValidString(name):
+		return fmt.Errorf("%w: segment name is not valid UTF-8", ErrValidation)
+	case utf8.RuneCountInString(name) > maxSegmentNameLen:
+		return fmt.Errorf("%w: segment name is longer than %d characters", ErrValidation, maxSegmentNameLen)
+	}
+
+	return nil
+}
+
+// SaveSegment сохраняет сегмент name с условиями criteria, заменяя условия
+// существующего сегмента с тем же названием, и возвращает его ID.
+func (r *Repository) SaveSegment(ctx context.Context, name string, criteria SegmentCriteria) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "save_segment")
+	defer end(&err)
+
+	if err := validateSegmentName(name); err != nil {
+		return 0, err
+	}
+	if err := criteria.Validate(); err != nil {
+		return 0, err
+	}
+	data, err := json.Marshal(criteria)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = r.conn().QueryRowContext(ctx, `INSERT INTO segments (name, criteria, updated_at) VALUES (:name, :criteria, :updated_at)
+		ON CONFLICT (name) DO UPDATE SET criteria = excluded.criteria, updated_at = excluded.updated_at
+		RETURNING id`,
+		sql.Named("name", name),
+		sql.Named("criteria", string(data)),
+		sql.Named("updated_at", r.now().Format(time.RFC3339Nano))).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Segment возвращает сохранённый сегмент или ErrSegmentNotFound.
+func (r *Repository) Segment(ctx context.Context, name string) (_ Segment, err error) {
+	ctx, end := r.startOperation(ctx, "segment")
+	defer end(&err)
+
+	return r.segment(ctx, name)
+}
+
+func (r *Repository) segment(ctx context.Context, name string) (Segment, error) {
+	var (
+		s         Segment
+		criteria  string
+		updatedAt string
+	)
+	err := r.conn().QueryRowContext(ctx, "SELECT id, name, criteria, updated_at FROM segments WHERE name = :name", sql.Named("name", name)).
+		Scan(&s.ID, &s.Name, &criteria, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Segment{}, fmt.Errorf("%w: %q", ErrSegmentNotFound, name)
+	}
+	if err != nil {
+		return Segment{}, err
+	}
+
+	if s.Criteria, err = ParseSegmentCriteria([]byte(criteria)); err != nil {
+		return Segment{}, err
+	}
+	if s.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+		return Segment{}, err
+	}
+
+	return s, nil
+}
+
+// DeleteSegment удаляет сегмент или возвращает ErrSegmentNotFound.
+func (r *Repository) DeleteSegment(ctx context.Context, name string) (err error) {
+	ctx, end := r.startOperation(ctx, "delete_segment")
+	defer end(&err)
+
+	res, err := r.conn().ExecContext(ctx, "DELETE FROM segments WHERE name = :name", sql.Named("name", name))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %q", ErrSegmentNotFound, name)
+	}
+
+	return nil
+}
+
+// SegmentClients вызывает fn для каждого клиента сегмента name в порядке
+// возрастания ID. Сегмент вычисляется при каждом вызове.
+func (r *Repository) SegmentClients(ctx context.Context, name string, fn func(Client) error) (err error) {
+	ctx, end := r.startOperation(ctx, "segment_clients")
+	defer end(&err)
+
+	s, err := r.segment(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return r.evaluateSegment(ctx, s.Criteria, fn)
+}
+
+// SegmentCount возвращает число клиентов в сегменте name.
+func (r *Repository) SegmentCount(ctx context.Context, name string) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "segment_count")
+	defer end(&err)
+
+	s, err := r.segment(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	err = r.evaluateSegment(ctx, s.Criteria, func(Client) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// evaluateSegment отбирает клиентов по условиям criteria: метки и статус —
+// запросом, остальные условия — по расшифрованным полям.
+func (r *Repository) evaluateSegment(ctx context.Context, criteria SegmentCriteria, fn func(Client) error) error {
+	cond, args, err := criteria.cond()
+	if err != nil {
+		return err
+	}
+
+	return r.forEach(ctx, cond, args, func(cl Client) error {
+		if !criteria.match(cl) {
+			return nil
+		}
+
+		return fn(cl)
+	})
+}
@@ -0,0 +1,115 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет сохранение, замену, чтение и удаление сегментов
+func Test_Segments_SaveLoadDelete(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	criteria := SegmentCriteria{Tags: []string{"vip"}, Statuses: []ClientStatus{StatusActive}}
+	id, err := repo.SaveSegment(ctx, "active vip", criteria)
+	require.NoError(t, err)
+
+	s, err := repo.Segment(ctx, "active vip")
+	require.NoError(t, err)
+	assert.Equal(t, id, s.ID)
+	assert.Equal(t, criteria, s.Criteria)
+
+	replaced := SegmentCriteria{EmailDomain: "mail.com"}
+	sameID, err := repo.SaveSegment(ctx, "active vip", replaced)
+	require.NoError(t, err)
+	assert.Equal(t, id, sameID, "saving an existing segment should replace it")
+	s, err = repo.Segment(ctx, "active vip")
+	require.NoError(t, err)
+	assert.Equal(t, replaced, s.Criteria)
+
+	_, err = repo.SaveSegment(ctx, " ", replaced)
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = repo.SaveSegment(ctx, "broken", SegmentCriteria{BornFrom: "1980"})
+	require.ErrorIs(t, err, ErrValidation)
+
+	require.NoError(t, repo.DeleteSegment(ctx, "active vip"))
+	_, err = repo.Segment(ctx, "active vip")
+	require.ErrorIs(t, err, ErrSegmentNotFound)
+	require.ErrorIs(t, repo.DeleteSegment(ctx, "active vip"), ErrSegmentNotFound)
+	_, err = repo.SegmentCount(ctx, "active vip")
+	require.ErrorIs(t, err, ErrSegmentNotFound)
+}
+
+// Тест проверяет вычисление сегментов по всем видам условий и их сочетаниям
+func Test_Segments_Evaluate(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	clients := map[string]Client{
+		"ivanov":  {FIO: "Иванов Иван", Login: "ivanov", Birthday: "19850615", Email: "ivanov@mail.com"},
+		"petrov":  {FIO: "Петров Пётр", Login: "petrov", Birthday: "19900101", Email: "petrov@corp.ru"},
+		"sidorov": {FIO: "Сидоров Сидор", Login: "sidorov", Birthday: "19791231", Email: "sidorov@mail.com"},
+		"blocked": {FIO: "Блокированный", Login: "blocked", Birthday: "19850101", Email: "blocked@mail.com"},
+	}
+	ids := make(map[string]int, len(clients))
+	for name, cl := range clients {
+		var err error
+		ids[name], err = repo.Insert(ctx, cl)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.TagClient(ctx, ids["ivanov"], "vip"))
+	require.NoError(t, repo.TagClient(ctx, ids["ivanov"], "wholesale"))
+	require.NoError(t, repo.TagClient(ctx, ids["petrov"], "vip"))
+	require.NoError(t, repo.TagClient(ctx, ids["blocked"], "vip"))
+	require.NoError(t, repo.ChangeStatus(ctx, ids["blocked"], StatusBlocked))
+
+	tests := []struct {
+		name     string
+		criteria SegmentCriteria
+		want     []string
+	}{
+		{"All", SegmentCriteria{}, []string{"ivanov", "petrov", "sidorov", "blocked"}},
+		{"FIO", SegmentCriteria{FIOContains: "иванов"}, []string{"ivanov"}},
+		{"EmailDomain", SegmentCriteria{EmailDomain: "MAIL.com"}, []string{"ivanov", "sidorov", "blocked"}},
+		{"BornIn1980s", SegmentCriteria{BornFrom: "19800101", BornTo: "19891231"}, []string{"ivanov", "blocked"}},
+		{"AllTags", SegmentCriteria{Tags: []string{"vip", "wholesale"}}, []string{"ivanov"}},
+		{"AnyTag", SegmentCriteria{Tags: []string{"wholesale", "VIP"}, AnyTag: true}, []string{"ivanov", "petrov", "blocked"}},
+		{"Status", SegmentCriteria{Statuses: []ClientStatus{StatusBlocked, StatusArchived}}, []string{"blocked"}},
+		{"Combined", SegmentCriteria{EmailDomain: "mail.com", Tags: []string{"vip"}, Statuses: []ClientStatus{StatusActive}}, []string{"ivanov"}},
+		{"Nothing", SegmentCriteria{FIOContains: "иванов", Statuses: []ClientStatus{StatusArchived}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.SaveSegment(ctx, tt.name, tt.criteria)
+			require.NoError(t, err)
+
+			// В БД могут быть и другие клиенты, проверяются только клиенты теста
+			var got []string
+			total := 0
+			require.NoError(t, repo.SegmentClients(ctx, tt.name, func(cl Client) error {
+				total++
+				for name, id := range ids {
+					if id == cl.ID {
+						got = append(got, name)
+					}
+				}
+				return nil
+			}))
+			assert.ElementsMatch(t, tt.want, got)
+
+			n, err := repo.SegmentCount(ctx, tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, total, n)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест проверяет сериализацию условий сегмента: формат JSON, обратный
+// разбор и отказ от неизвестных полей
+func Test_SegmentCriteria_Serialization(t *testing.T) {
+	criteria := SegmentCriteria{
+		FIOContains: "иванов",
+		EmailDomain: "mail.com",
+		BornFrom:    "19800101",
+		BornTo:      "19891231",
+		Tags:        []string{"vip", "wholesale"},
+		AnyTag:      true,
+		Statuses:    []ClientStatus{StatusActive, StatusBlocked},
+	}
+
+	data, err := json.Marshal(criteria)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"fio_contains": "иванов",
+		"email_domain": "mail.com",
+		"born_from": "19800101",
+		"born_to": "19891231",
+		"tags": ["vip", "wholesale"],
+		"any_tag": true,
+		"statuses": ["active", "blocked"]
+	}`, string(data))
+
+	parsed, err := ParseSegmentCriteria(data)
+	require.NoError(t, err)
+	assert.Equal(t, criteria, parsed)
+
+	empty, err := json.Marshal(SegmentCriteria{})
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(empty), "empty criteria should serialize without fields")
+
+	for name, data := range map[string]string{
+		"UnknownField": `{"fio_contain": "иванов"}`,
+		"WrongType":    `{"tags": "vip"}`,
+		"Malformed":    `{"tags": [`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseSegmentCriteria([]byte(data))
+			require.ErrorIs(t, err, ErrValidation)
+		})
+	}
+}
+
+// Тест проверяет проверку условий сегмента
+func Test_SegmentCriteria_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria SegmentCriteria
+		valid    bool
+	}{
+		{"Empty", SegmentCriteria{}, true},
+		{"BirthdayRange", SegmentCriteria{BornFrom: "19800101", BornTo: "19800101"}, true},
+		{"InvalidBirthday", SegmentCriteria{BornFrom: "19801301"}, false},
+		{"ReversedRange", SegmentCriteria{BornFrom: "19900101", BornTo: "19800101"}, false},
+		{"EmailAddressAsDomain", SegmentCriteria{EmailDomain: "user@mail.com"}, false},
+		{"EmptyTag", SegmentCriteria{Tags: []string{" "}}, false},
+		{"AnyTagWithoutTags", SegmentCriteria{AnyTag: true}, false},
+		{"UnknownStatus", SegmentCriteria{Statuses: []ClientStatus{"deleted"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.criteria.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrValidation)
+			}
+		})
+	}
+}
+
+// Тест проверяет отбор по полям клиента
+func Test_SegmentCriteria_Match(t *testing.T) {
+	cl := Client{FIO: "Иванов Иван Иванович", Email: "ivan@Mail.com", Birthday: "19850615"}
+
+	tests := []struct {
+		name     string
+		criteria SegmentCriteria
+		want     bool
+	}{
+		{"Empty", SegmentCriteria{}, true},
+		{"FIOCaseInsensitive", SegmentCriteria{FIOContains: "ИВАН иван"}, true},
+		{"FIOMismatch", SegmentCriteria{FIOContains: "Петров"}, false},
+		{"EmailDomain", SegmentCriteria{EmailDomain: "mail.com"}, true},
+		{"EmailSubdomain", SegmentCriteria{EmailDomain: "l.com"}, false},
+		{"BornOnLowerBound", SegmentCriteria{BornFrom: "19850615"}, true},
+		{"BornOnUpperBound", SegmentCriteria{BornTo: "19850615"}, true},
+		{"BornBefore", SegmentCriteria{BornFrom: "19850616"}, false},
+		{"BornAfter", SegmentCriteria{BornTo: "19850614"}, false},
+		{"AllFields", SegmentCriteria{FIOContains: "иванов", EmailDomain: "mail.com", BornFrom: "19800101", BornTo: "19891231"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.criteria.match(cl))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithSlowQueryLog пишет в logger с уровнем WARN каждый запрос, выполнявшийся
+// дольше threshold. При включённых метриках такие запросы также учитываются
+// в счётчике clients_db_slow_queries_total.
+func WithSlowQueryLog(logger *slog.Logger, threshold time.Duration) Option {
+	return func(r *Repository) {
+		r.slowThreshold = threshold
+		r.hooks = append(r.hooks, &slowQueryLogger{logger: logger})
+	}
+}
+
+// slowQueryLogger пишет в журнал медленные запросы.
+type slowQueryLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slowQueryLogger) afterQuery(ctx context.Context, ev queryEvent) {
+	if !ev.Slow {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("op", ev.Op),
+		slog.String("sql", ev.SQL),
+		slog.Duration("duration", ev.Duration),
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+
+	l.logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Запрос, искусственно выполняющийся заметное время
+const delayedQuery = `WITH RECURSIVE seq(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM seq WHERE x < 1000000)
+SELECT COUNT(*) FROM seq`
+
+// Тест проверяет, что запрос дольше порога пишется в журнал с уровнем WARN
+// и учитывается в счётчике, а быстрый запрос — нет
+func Test_SlowQueryLog_ThresholdCrossed(t *testing.T) {
+	db := openMemoryDB(t)
+
+	var buf bytes.Buffer
+	reg := prometheus.NewRegistry()
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	repo := NewRepository(db, WithMetrics(reg), WithSlowQueryLog(logger, 20*time.Millisecond))
+
+	ctx := withOperation(context.Background(), "report")
+	q := repo.conn()
+
+	// Быстрый запрос порог не пересекает
+	var n int
+	require.NoError(t, q.QueryRowContext(ctx, "SELECT 1").Scan(&n))
+	assert.Empty(t, buf.String(), "fast query should not be logged")
+
+	start := time.Now()
+	require.NoError(t, q.QueryRowContext(ctx, delayedQuery).Scan(&n))
+	require.Greater(t, time.Since(start), 20*time.Millisecond, "delayed query should exceed the threshold")
+
+	records := readLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "slow query", records[0]["msg"])
+	assert.Equal(t, "report", records[0]["op"])
+	assert.Equal(t, delayedQuery, records[0]["sql"])
+	assert.Greater(t, records[0]["duration"], float64(20*time.Millisecond))
+
+	assert.Equal(t, 1.0, metricValue(t, reg, "clients_db_slow_queries_total", map[string]string{"operation": "report"}))
+}
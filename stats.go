@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DBStats — снимок статистики БД.
+type DBStats struct {
+	// TableRows — число строк в каждой пользовательской таблице.
+	TableRows map[string]int64 `json:"table_rows"`
+	// IndexSizes — размер каждого индекса в байтах.
+	IndexSizes map[string]int64 `json:"index_sizes"`
+	// FileSize — размер основного файла БД в байтах.
+	FileSize int64 `json:"file_size"`
+	// WALSize — размер файла журнала WAL в байтах; 0, если WAL не используется.
+	WALSize     int64     `json:"wal_size"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// Stats собирает статистику БД: число строк по таблицам, размеры файла
+// БД, журнала WAL и индексов.
+func (r *Repository) Stats(ctx context.Context) (_ DBStats, err error) {
+	ctx, end := r.startOperation(ctx, "stats")
+	defer end(&err)
+
+	q := r.conn()
+	stats := DBStats{
+		IndexSizes:  make(map[string]int64),
+		CollectedAt: r.now(),
+	}
+
+	if stats.TableRows, err = tableRowCounts(ctx, q); err != nil {
+		return DBStats{}, err
+	}
+
+	// dbstat возвращает размер страниц каждого объекта схемы
+	rows, err := q.QueryContext(ctx, `SELECT m.name, COALESCE(SUM(s.pgsize), 0)
+		FROM sqlite_master m LEFT JOIN dbstat s ON s.name = m.name
+		WHERE m.type = 'index'
+		GROUP BY m.name`)
+	if err != nil {
+		return DBStats{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			name string
+			size int64
+		)
+		if err := rows.Scan(&name, &size); err != nil {
+			return DBStats{}, err
+		}
+		stats.IndexSizes[name] = size
+	}
+	if err := rows.Err(); err != nil {
+		return DBStats{}, err
+	}
+
+	if stats.FileSize, err = databaseSize(ctx, q); err != nil {
+		return DBStats{}, err
+	}
+
+	path, err := r.databaseFile(ctx)
+	if err != nil {
+		return DBStats{}, err
+	}
+	if path != "" {
+		info, err := os.Stat(path + "-wal")
+		switch {
+		case err == nil:
+			stats.WALSize = info.Size()
+		case !errors.Is(err, os.ErrNotExist):
+			return DBStats{}, err
+		}
+	}
+
+	return stats, nil
+}
+
+// tableRowCounts возвращает число строк в каждой пользовательской таблице.
+func tableRowCounts(ctx context.Context, q querier) (map[string]int64, error) {
+	tables, err := queryStrings(ctx, q, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		var count int64
+		if err := q.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %q", table)).Scan(&count); err != nil {
+			return nil, err
+		}
+		counts[table] = count
+	}
+
+	return counts, nil
+}
+
+// databaseFile возвращает путь к файлу основной БД или пустую строку
+// для БД в памяти.
+func (r *Repository) databaseFile(ctx context.Context) (string, error) {
+	var (
+		seq        int
+		name, file string
+	)
+	err := r.conn().QueryRowContext(ctx, "SELECT seq, name, file FROM pragma_database_list WHERE name = 'main'").Scan(&seq, &name, &file)
+
+	return file, err
+}
+
+// databaseSize возвращает размер БД в байтах по числу и размеру страниц.
+func databaseSize(ctx context.Context, q querier) (int64, error) {
+	var pageCount, pageSize int64
+	if err := q.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := q.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+
+	return pageCount * pageSize, nil
+}
+
+func queryStrings(ctx context.Context, q querier, query string, args ...any) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, rows.Err()
+}
+
+func queryInts(ctx context.Context, q querier, query string, args ...any) ([]int, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, rows.Err()
+}
+
+// StatsExporter периодически собирает Stats и публикует их как метрики Prometheus.
+type StatsExporter struct {
+	repo *Repository
+
+	tableRows *prometheus.GaugeVec
+	indexSize *prometheus.GaugeVec
+	fileSize  prometheus.Gauge
+	walSize   prometheus.Gauge
+}
+
+// NewStatsExporter регистрирует метрики статистики БД в reg.
+func NewStatsExporter(repo *Repository, reg prometheus.Registerer) *StatsExporter {
+	e := &StatsExporter{
+		repo: repo,
+		tableRows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "table_rows",
+			Help:      "Number of rows per table.",
+		}, []string{"table"}),
+		indexSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "index_size_bytes",
+			Help:      "Size of each index in bytes.",
+		}, []string{"index"}),
+		fileSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "file_size_bytes",
+			Help:      "Size of the main database file in bytes.",
+		}),
+		walSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "clients",
+			Subsystem: "db",
+			Name:      "wal_size_bytes",
+			Help:      "Size of the write-ahead log in bytes.",
+		}),
+	}
+	reg.MustRegister(e.tableRows, e.indexSize, e.fileSize, e.walSize)
+
+	return e
+}
+
+// Refresh собирает статистику и обновляет метрики.
+func (e *StatsExporter) Refresh(ctx context.Context) error {
+	stats, err := e.repo.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Удалённые таблицы и индексы не должны оставаться в метриках
+	e.tableRows.Reset()
+	for table, rows := range stats.TableRows {
+		e.tableRows.WithLabelValues(table).Set(float64(rows))
+	}
+	e.indexSize.Reset()
+	for index, size := range stats.IndexSizes {
+		e.indexSize.WithLabelValues(index).Set(float64(size))
+	}
+	e.fileSize.Set(float64(stats.FileSize))
+	e.walSize.Set(float64(stats.WALSize))
+
+	return nil
+}
+
+// Run обновляет метрики сразу и затем каждые interval до отмены ctx.
+// Ошибки сбора передаются в onError, предыдущие значения метрик сохраняются.
+func (e *StatsExporter) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Refresh(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
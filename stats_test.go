@@ -0,0 +1,73 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет статистику на заранее заполненной БД в режиме WAL
+func Test_Stats_SeededDatabase(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "stats.db"))
+	require.NoError(t, err, "database connection error: %v", err)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err = db.Exec("PRAGMA journal_mode = WAL")
+	require.NoError(t, err)
+	require.NoError(t, Migrate(ctx, db), "migration error")
+
+	repo := NewRepository(db)
+	for i := 0; i < 5; i++ {
+		_, err := repo.Insert(ctx, Client{FIO: "Test", Login: fmt.Sprintf("user%d", i), Birthday: "19700101", Email: "mail@mail.com"})
+		require.NoError(t, err)
+	}
+
+	stats, err := repo.Stats(ctx)
+	require.NoError(t, err, "error collecting stats: %v", err)
+
+	assert.EqualValues(t, 5, stats.TableRows["clients"])
+	assert.EqualValues(t, 5, stats.TableRows["audit_log"], "every insert is audited")
+	assert.EqualValues(t, 0, stats.TableRows["sales"])
+	assert.NotContains(t, stats.TableRows, "sqlite_sequence", "internal tables should be skipped")
+	assert.Contains(t, stats.IndexSizes, "clients_owner_id")
+	assert.Greater(t, stats.IndexSizes["audit_log_client_id"], int64(0))
+	assert.Greater(t, stats.FileSize, int64(0))
+	assert.Greater(t, stats.WALSize, int64(0), "WAL should contain uncheckpointed pages")
+	assert.False(t, stats.CollectedAt.IsZero())
+
+	// Экспорт статистики в метрики
+	reg := prometheus.NewRegistry()
+	exporter := NewStatsExporter(repo, reg)
+	require.NoError(t, exporter.Refresh(ctx))
+
+	assert.Equal(t, 5.0, metricValue(t, reg, "clients_db_table_rows", map[string]string{"table": "clients"}))
+	assert.Equal(t, float64(stats.FileSize), metricValue(t, reg, "clients_db_file_size_bytes", nil))
+	assert.Greater(t, metricValue(t, reg, "clients_db_index_size_bytes", map[string]string{"index": "clients_owner_id"}), 0.0)
+
+	// Периодическое обновление подхватывает новые строки
+	_, err = repo.Insert(ctx, Client{FIO: "Test", Login: "user5", Birthday: "19700101", Email: "mail@mail.com"})
+	require.NoError(t, err)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		exporter.Run(runCtx, 10*time.Millisecond, nil)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return metricValue(t, reg, "clients_db_table_rows", map[string]string{"table": "clients"}) == 6
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
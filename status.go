@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ClientStatus — состояние клиента.
+type ClientStatus string
+
+const (
+	StatusActive   ClientStatus = "active"
+	StatusBlocked  ClientStatus = "blocked"
+	StatusArchived ClientStatus = "archived"
+)
+
+// statusTransitions — переходы, разрешённые ChangeStatus. Архивный клиент
+// возвращается в работу только через RestoreClient.
+var statusTransitions = map[ClientStatus][]ClientStatus{
+	StatusActive:   {StatusBlocked, StatusArchived},
+	StatusBlocked:  {StatusActive, StatusArchived},
+	StatusArchived: nil,
+}
+
+// Valid сообщает, что статус — один из известных.
+func (s ClientStatus) Valid() bool {
+	_, ok := statusTransitions[s]
+
+	return ok
+}
+
+// ClientStatuses — допустимые статусы клиента в условиях отбора (Filter,
+// SegmentCriteria, SummaryFilter): клиент подходит, если его статус —
+// один из перечисленных. Пустой набор статус не ограничивает.
+type ClientStatuses []ClientStatus
+
+// Validate проверяет, что все статусы известны. Ошибка оборачивает
+// ErrValidation.
+func (s ClientStatuses) Validate() error {
+	for _, status := range s {
+		if !status.Valid() {
+			return fmt.Errorf("%w: unknown client status %q", ErrValidation, status)
+		}
+	}
+
+	return nil
+}
+
+// in возвращает условие «status IN (...)», значения которого подставляет
+// param; для пустого набора — пустую строку.
+func (s ClientStatuses) in(param func(v any) string) string {
+	if len(s) == 0 {
+		return ""
+	}
+
+	placeholders := make([]string, len(s))
+	for i, status := range s {
+		placeholders[i] = param(string(status))
+	}
+
+	return "status IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+// cond возвращает условие « AND status IN (...)» с параметрами :status0,
+// :status1, … и значения параметров; для пустого набора — пустое условие.
+func (s ClientStatuses) cond() (string, []any) {
+	var args []any
+	in := s.in(func(v any) string {
+		name := fmt.Sprintf("status%d", len(args))
+		args = append(args, sql.Named(name, v))
+		return ":" + name
+	})
+	if in == "" {
+		return "", nil
+	}
+
+	return " AND " + in, args
+}
+
+// ChangeStatus переводит клиента в статус status, если переход разрешён
+// (см. statusTransitions), иначе возвращает ErrInvalidStatusTransition.
+// Переход записывается в журнал аудита.
+func (r *Repository) ChangeStatus(ctx context.Context, id int, status ClientStatus) (err error) {
+	ctx, end := r.startClientOperation(ctx, "change_status", id)
+	defer end(&err)
+
+	if !status.Valid() {
+		return fmt.Errorf("%w: unknown client status %q", ErrValidation, status)
+	}
+
+	return r.setStatus(ctx, id, status, func(from ClientStatus) bool {
+		return slices.Contains(statusTransitions[from], status)
+	})
+}
+
+// RestoreClient возвращает архивного клиента в активные. Для клиента в
+// другом статусе возвращается ErrInvalidStatusTransition.
+func (r *Repository) RestoreClient(ctx context.Context, id int) (err error) {
+	ctx, end := r.startClientOperation(ctx, "restore_client", id)
+	defer end(&err)
+
+	return r.setStatus(ctx, id, StatusActive, func(from ClientStatus) bool {
+		return from == StatusArchived
+	})
+}
+
+// setStatus переводит клиента в статус to, если allowed разрешает переход
+// из текущего статуса.
+func (r *Repository) setStatus(ctx context.Context, id int, to ClientStatus, allowed func(from ClientStatus) bool) error {
+	return r.inTx(ctx, func(q querier) error {
+		before, err := r.selectClient(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if !allowed(before.Status) {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, before.Status, to)
+		}
+
+		changedAt, err := r.recordHistory(ctx, q, id, AuditStatus)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(ctx, "UPDATE clients SET status = :status, valid_from = :valid_from, version = version + 1 WHERE id = :id",
+			sql.Named("status", string(to)),
+			sql.Named("valid_from", changedAt),
+			sql.Named("id", id))
+		if err != nil {
+			return err
+		}
+
+		was, now := string(before.Status), string(to)
+
+		return r.auditDiff(ctx, q, AuditStatus, id, map[string]FieldChange{
+			"status": {Before: &was, After: &now},
+		})
+	})
+}
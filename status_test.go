@@ -0,0 +1,152 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClientInStatus вставляет клиента и напрямую переводит его в статус
+// status, минуя проверку переходов
+func newClientInStatus(t *testing.T, tx *sql.Tx, repo *Repository, status ClientStatus) int {
+	t.Helper()
+
+	id, err := repo.Insert(context.Background(), newTestClient())
+	require.NoError(t, err)
+	_, err = tx.Exec("UPDATE clients SET status = ? WHERE id = ?", string(status), id)
+	require.NoError(t, err)
+
+	return id
+}
+
+// assertStatusChange проверяет результат перехода: при разрешённом
+// переходе — новый статус и запись в журнале аудита, при запрещённом —
+// ErrInvalidStatusTransition без изменений
+func assertStatusChange(t *testing.T, repo *Repository, id int, from, to ClientStatus, legal bool, err error) {
+	t.Helper()
+
+	ctx := context.Background()
+	client, selectErr := repo.Select(ctx, id)
+	require.NoError(t, selectErr)
+	entries, auditErr := repo.AuditLog(ctx, id)
+	require.NoError(t, auditErr)
+	last := entries[len(entries)-1]
+
+	if !legal {
+		require.ErrorIs(t, err, ErrInvalidStatusTransition, "%s -> %s should be rejected", from, to)
+		assert.Equal(t, ClassConflict, Classify(err))
+		assert.Equal(t, from, client.Status, "status should not change")
+		assert.Equal(t, AuditInsert, last.Operation, "rejected transition should not be audited")
+		return
+	}
+
+	require.NoError(t, err, "%s -> %s should be allowed", from, to)
+	assert.Equal(t, to, client.Status)
+	assert.Equal(t, AuditStatus, last.Operation)
+	assert.Equal(t, map[string]FieldChange{"status": {Before: strPtr(string(from)), After: strPtr(string(to))}}, last.Diff)
+}
+
+// Тест проверяет каждый переход ChangeStatus между статусами
+func Test_ChangeStatus_TransitionMatrix(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	tests := []struct {
+		from, to ClientStatus
+		legal    bool
+	}{
+		{StatusActive, StatusActive, false},
+		{StatusActive, StatusBlocked, true},
+		{StatusActive, StatusArchived, true},
+		{StatusBlocked, StatusActive, true},
+		{StatusBlocked, StatusBlocked, false},
+		{StatusBlocked, StatusArchived, true},
+		{StatusArchived, StatusActive, false},
+		{StatusArchived, StatusBlocked, false},
+		{StatusArchived, StatusArchived, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"To"+string(tt.to), func(t *testing.T) {
+			id := newClientInStatus(t, tx, repo, tt.from)
+
+			err := repo.ChangeStatus(ctx, id, tt.to)
+			assertStatusChange(t, repo, id, tt.from, tt.to, tt.legal, err)
+		})
+	}
+}
+
+// Тест проверяет, что в активные из архива клиент возвращается только
+// через RestoreClient
+func Test_RestoreClient(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		from  ClientStatus
+		legal bool
+	}{
+		{StatusArchived, true},
+		{StatusActive, false},
+		{StatusBlocked, false},
+	} {
+		t.Run(string(tt.from), func(t *testing.T) {
+			id := newClientInStatus(t, tx, repo, tt.from)
+
+			err := repo.RestoreClient(ctx, id)
+			assertStatusChange(t, repo, id, tt.from, StatusActive, tt.legal, err)
+		})
+	}
+}
+
+// Тест проверяет статус нового клиента, неизвестный статус и отсутствующего
+// клиента
+func Test_ChangeStatus_Errors(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Status = StatusArchived }))
+	require.NoError(t, err)
+	client, err := repo.Select(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, client.Status, "new client should always be active")
+
+	require.ErrorIs(t, repo.ChangeStatus(ctx, id, "deleted"), ErrValidation)
+	require.ErrorIs(t, repo.ChangeStatus(ctx, -1, StatusBlocked), ErrClientNotFound)
+	require.ErrorIs(t, repo.RestoreClient(ctx, -1), ErrClientNotFound)
+
+	// Update не меняет статус
+	require.NoError(t, repo.ChangeStatus(ctx, id, StatusBlocked))
+	client.Login = "changed"
+	client.Status = StatusActive
+	require.NoError(t, repo.Update(ctx, client))
+	client, err = repo.Select(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusBlocked, client.Status)
+}
+
+// Тест проверяет проверку набора статусов и условие отбора по нему
+func Test_ClientStatuses(t *testing.T) {
+	assert.NoError(t, ClientStatuses(nil).Validate())
+	assert.NoError(t, ClientStatuses{StatusActive, StatusBlocked}.Validate())
+	assert.ErrorIs(t, ClientStatuses{StatusActive, "deleted"}.Validate(), ErrValidation)
+
+	cond, args := ClientStatuses(nil).cond()
+	assert.Empty(t, cond)
+	assert.Empty(t, args)
+
+	cond, args = ClientStatuses{StatusActive, StatusBlocked}.cond()
+	assert.Equal(t, " AND status IN (:status0, :status1)", cond)
+	assert.Equal(t, []any{sql.Named("status0", "active"), sql.Named("status1", "blocked")}, args)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ClientSummary — сводка по клиенту из модели чтения client_summary:
+// данные клиента вместе с агрегатами по связанным таблицам.
+type ClientSummary struct {
+	ClientID   int          `json:"client_id"`
+	FIO        string       `json:"fio"`
+	Login      string       `json:"login"`
+	Email      string       `json:"email"`
+	Status     ClientStatus `json:"status"`
+	OwnerID    string       `json:"owner_id,omitempty"`
+	OrderCount int          `json:"order_count"`
+	// LastActivity — время последнего изменения клиента, его заказов или
+	// заметок; нулевое, если оно неизвестно.
+	LastActivity time.Time `json:"last_activity"`
+	// Tags — метки клиента в алфавитном порядке.
+	Tags []string `json:"tags"`
+	// UpdatedAt — время обновления сводки.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SummaryFilter — условия отбора сводок клиентов. Пустое условие не
+// ограничивает отбор; заданные условия должны выполняться одновременно.
+type SummaryFilter struct {
+	// Query — подстрока ФИО, логина или email без учёта регистра.
+	Query string
+	// Tag — метка клиента.
+	Tag      string
+	Statuses ClientStatuses
+	// ActiveSince — наименьшее время последней активности.
+	ActiveSince time.Time
+	// MinOrders — наименьшее число заказов.
+	MinOrders int
+}
+
+// summarySource — запрос, вычисляющий сводки клиентов по исходным
+// таблицам в порядке столбцов client_summary. Мягко удалённые клиенты в
+// сводку не попадают.
+const summarySource = `SELECT c.id, c.fio, c.login, c.email, c.status, c.owner_id,
+	(SELECT COUNT(*) FROM orders o WHERE o.client_id = c.id),
+	COALESCE((SELECT at FROM (
+		SELECT c.valid_from AS at
+		UNION ALL SELECT created_at FROM orders WHERE client_id = c.id
+		UNION ALL SELECT created_at FROM client_notes WHERE client_id = c.id
+	) WHERE at != '' ORDER BY julianday(at) DESC LIMIT 1), ''),
+	(SELECT json_group_array(name) FROM (SELECT t.name FROM client_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.client_id = c.id ORDER BY t.name)),
+	:updated_at
+	FROM clients c WHERE c.deleted_at = ''`
+
+// summaryColumns — столбцы client_summary в порядке, ожидаемом scanSummary.
+const summaryColumns = "client_id, fio, login, email, status, owner_id, order_count, last_activity, tags, updated_at"
+
+// ClientSummaryProjector — EventSink для OutboxRelay, поддерживающий
+// модель чтения client_summary: по каждому событию сводка клиента
+// вычисляется заново по исходным таблицам. Поэтому обработка события
+// идемпотентна и не зависит от порядка, а повторы и пропущенные
+// промежуточные события не нарушают сводку. Сводка отстаёт от исходных
+// таблиц на время публикации событий.
+type ClientSummaryProjector struct {
+	repo *Repository
+}
+
+// NewClientSummaryProjector создаёт проекцию событий в client_summary БД
+// репозитория repo.
+func NewClientSummaryProjector(repo *Repository) *ClientSummaryProjector {
+	return &ClientSummaryProjector{repo: repo}
+}
+
+// Publish обновляет сводку клиента события: пересчитывает её или удаляет,
+// если клиент удалён или перенесён в архив.
+func (p *ClientSummaryProjector) Publish(ctx context.Context, event ClientEvent) (err error) {
+	r := p.repo
+	ctx, end := r.startOperation(ctx, "project_summary")
+	defer end(&err)
+
+	return r.inTx(ctx, func(q querier) error {
+		if _, err := q.ExecContext(ctx, "DELETE FROM client_summary WHERE client_id = :id", sql.Named("id", event.ClientID)); err != nil {
+			return err
+		}
+
+		_, err := q.ExecContext(ctx, "INSERT INTO client_summary ("+summaryColumns+") "+summarySource+" AND c.id = :id",
+			sql.Named("updated_at", r.now().Format(time.RFC3339Nano)),
+			sql.Named("id", event.ClientID))
+		return err
+	})
+}
+
+// RebuildClientSummaries пересоздаёт client_summary по исходным таблицам
+// в одной транзакции и возвращает число сводок. Используется при
+// подключении модели чтения к существующей БД и после сбоев проекции.
+func (r *Repository) RebuildClientSummaries(ctx context.Context) (_ int, err error) {
+	ctx, end := r.startOperation(ctx, "rebuild_summaries")
+	defer end(&err)
+
+	var n int64
+	err = r.inTx(ctx, func(q querier) error {
+		if _, err := q.ExecContext(ctx, "DELETE FROM client_summary"); err != nil {
+			return err
+		}
+
+		res, err := q.ExecContext(ctx, "INSERT INTO client_summary ("+summaryColumns+") "+summarySource,
+			sql.Named("updated_at", r.now().Format(time.RFC3339Nano)))
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// Validate проверяет условия отбора. Ошибка оборачивает ErrValidation.
+func (f SummaryFilter) Validate() error {
+	if f.Tag != "" {
+		if _, err := normalizeTag(f.Tag); err != nil {
+			return err
+		}
+	}
+	if err := f.Statuses.Validate(); err != nil {
+		return err
+	}
+	if f.MinOrders < 0 {
+		return fmt.Errorf("%w: min orders %d is negative", ErrValidation, f.MinOrders)
+	}
+
+	return nil
+}
+
+// cond возвращает условие WHERE по всем полям, кроме Query, и его
+// параметры.
+func (f SummaryFilter) cond() (string, []any) {
+	var (
+		cond string
+		args []any
+	)
+	if f.Tag != "" {
+		tag, _ := normalizeTag(f.Tag)
+		cond += " AND EXISTS (SELECT 1 FROM json_each(tags) WHERE value = :tag)"
+		args = append(args, sql.Named("tag", tag))
+	}
+	statusCond, statusArgs := f.Statuses.cond()
+	cond += statusCond
+	args = append(args, statusArgs...)
+	if !f.ActiveSince.IsZero() {
+		cond += " AND last_activity != '' AND julianday(last_activity) >= julianday(:active_since)"
+		args = append(args, sql.Named("active_since", f.ActiveSince.UTC().Format(time.RFC3339Nano)))
+	}
+	if f.MinOrders > 0 {
+		cond += " AND order_count >= :min_orders"
+		args = append(args, sql.Named("min_orders", f.MinOrders))
+	}
+
+	return cond, args
+}
+
+// match проверяет Query по полям сводки после расшифровки.
+func (f SummaryFilter) match(s ClientSummary) bool {
+	if f.Query == "" {
+		return true
+	}
+	query := strings.ToLower(f.Query)
+	for _, value := range []string{s.FIO, s.Login, s.Email} {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientSummaries вызывает fn для каждой сводки клиента, удовлетворяющей
+// filter, в порядке возрастания ID клиента. Сводки читаются из
+// client_summary без обращения к исходным таблицам, поэтому могут
+// отставать от них (см. ClientSummaryProjector).
+func (r *Repository) ClientSummaries(ctx context.Context, filter SummaryFilter, fn func(ClientSummary) error) (err error) {
+	ctx, end := r.startOperation(ctx, "client_summaries")
+	defer end(&err)
+
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	scope, args := r.ownerScope(ctx)
+	cond, condArgs := filter.cond()
+	args = append(args, condArgs...)
+
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+summaryColumns+" FROM client_summary WHERE 1"+scope+cond+" ORDER BY client_id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSummary(rows)
+		if err != nil {
+			return err
+		}
+		if s.Email, err = r.decryptField(s.ClientID, "email", s.Email); err != nil {
+			return err
+		}
+		if !filter.match(s) {
+			continue
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func scanSummary(row rowScanner) (ClientSummary, error) {
+	var (
+		s                             ClientSummary
+		lastActivity, tags, updatedAt string
+	)
+	err := row.Scan(&s.ClientID, &s.FIO, &s.Login, &s.Email, &s.Status, &s.OwnerID, &s.OrderCount, &lastActivity, &tags, &updatedAt)
+	if err != nil {
+		return ClientSummary{}, err
+	}
+
+	if lastActivity != "" {
+		if s.LastActivity, err = time.Parse(time.RFC3339Nano, lastActivity); err != nil {
+			return ClientSummary{}, err
+		}
+	}
+	if err := json.Unmarshal([]byte(tags), &s.Tags); err != nil {
+		return ClientSummary{}, err
+	}
+	if s.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+		return ClientSummary{}, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSummaries создаёт трёх клиентов с заказами, заметками и метками и
+// возвращает БД, репозиторий, часы и ID клиентов. Время каждого изменения
+// своё, чтобы последняя активность клиентов различалась.
+func setupSummaries(t *testing.T, opts ...Option) (*sql.DB, *Repository, *testutil.FakeClock, []int) {
+	t.Helper()
+
+	ctx := context.Background()
+	clock := testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+	repo := NewRepository(db, append([]Option{WithClock(clock)}, opts...)...)
+
+	ids := make([]int, 3)
+	for i, fio := range []string{"Ivanov Ivan", "Petrov Petr", "Sidorov Sidor"} {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient(func(cl *Client) {
+			cl.FIO = fio
+			cl.Login = fio[:6]
+		}))
+		require.NoError(t, err)
+		clock.Advance(time.Minute)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.Orders().Create(ctx, Order{ClientID: ids[0], Amount: 100})
+		require.NoError(t, err)
+		clock.Advance(time.Minute)
+	}
+	_, err := repo.AddNote(ctx, ids[1], "called back")
+	require.NoError(t, err)
+	clock.Advance(time.Minute)
+	require.NoError(t, repo.TagClient(ctx, ids[0], "vip"))
+	require.NoError(t, repo.TagClient(ctx, ids[0], "b2b"))
+	require.NoError(t, repo.TagClient(ctx, ids[2], "vip"))
+
+	return db, repo, clock, ids
+}
+
+// assertSummariesConsistent проверяет, что client_summary совпадает со
+// сводками, вычисленными по исходным таблицам через репозиторий.
+func assertSummariesConsistent(t *testing.T, db *sql.DB, repo *Repository) {
+	t.Helper()
+
+	ctx := context.Background()
+	// БД теста открыта с одним соединением, поэтому клиенты читаются
+	// целиком до остальных запросов
+	var clients []Client
+	require.NoError(t, repo.ForEach(ctx, func(cl Client) error {
+		clients = append(clients, cl)
+		return nil
+	}))
+
+	var want []ClientSummary
+	for _, cl := range clients {
+		s := ClientSummary{ClientID: cl.ID, FIO: cl.FIO, Login: cl.Login, Email: cl.Email, Status: cl.Status, OwnerID: cl.OwnerID, Tags: []string{}}
+
+		var validFrom string
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT valid_from FROM clients WHERE id = :id", sql.Named("id", cl.ID)).Scan(&validFrom))
+		activity := []time.Time{}
+		if validFrom != "" {
+			at, err := time.Parse(time.RFC3339Nano, validFrom)
+			require.NoError(t, err)
+			activity = append(activity, at)
+		}
+
+		orders, err := repo.Orders().ByClient(ctx, cl.ID)
+		require.NoError(t, err)
+		s.OrderCount = len(orders)
+		for _, order := range orders {
+			activity = append(activity, order.CreatedAt)
+		}
+		notes, err := repo.ListNotes(ctx, cl.ID)
+		require.NoError(t, err)
+		for _, note := range notes {
+			activity = append(activity, note.CreatedAt)
+		}
+		sort.Slice(activity, func(i, j int) bool { return activity[i].Before(activity[j]) })
+		if len(activity) > 0 {
+			s.LastActivity = activity[len(activity)-1]
+		}
+
+		tags, err := repo.ClientTags(ctx, cl.ID)
+		require.NoError(t, err)
+		s.Tags = append(s.Tags, tags...)
+
+		want = append(want, s)
+	}
+
+	var got []ClientSummary
+	require.NoError(t, repo.ClientSummaries(ctx, SummaryFilter{}, func(s ClientSummary) error {
+		assert.False(t, s.UpdatedAt.IsZero())
+		s.UpdatedAt = time.Time{}
+		got = append(got, s)
+		return nil
+	}))
+	assert.Equal(t, want, got)
+}
+
+// Тест проверяет, что сводки, обновляемые по событиям, совпадают с
+// исходными таблицами после изменений клиентов, заказов, заметок и меток
+func Test_ClientSummaryProjector(t *testing.T) {
+	ctx := context.Background()
+	db, repo, clock, ids := setupSummaries(t)
+	relay := NewOutboxRelay(repo, NewClientSummaryProjector(repo))
+
+	// До публикации событий сводок нет
+	assertRowCount(t, db, "client_summary", 0, "1")
+	_, err := relay.Relay(ctx)
+	require.NoError(t, err)
+	assertSummariesConsistent(t, db, repo)
+
+	var summary ClientSummary
+	require.NoError(t, repo.ClientSummaries(ctx, SummaryFilter{Query: "ivanov"}, func(s ClientSummary) error {
+		summary = s
+		return nil
+	}))
+	assert.Equal(t, 2, summary.OrderCount)
+	assert.Equal(t, []string{"b2b", "vip"}, summary.Tags)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 4, 0, 0, time.UTC), summary.LastActivity)
+
+	clock.Advance(time.Hour)
+	cl, err := repo.Select(ctx, ids[2])
+	require.NoError(t, err)
+	cl.Email = "new@mail.com"
+	require.NoError(t, repo.Update(ctx, cl))
+	notes, err := repo.ListNotes(ctx, ids[1])
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteNote(ctx, notes[0].ID))
+	require.NoError(t, repo.UntagClient(ctx, ids[0], "b2b"))
+	require.NoError(t, repo.DeleteTag(ctx, "vip"))
+	_, err = repo.Orders().Create(ctx, Order{ClientID: ids[2], Amount: 50})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, ids[1]))
+
+	_, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assertSummariesConsistent(t, db, repo)
+	assertRowCount(t, db, "client_summary", 0, "client_id = ?", ids[1])
+
+	// Повторная обработка событий не меняет сводки
+	n, err := repo.ReplayEvents(ctx, time.Time{}, time.Time{}, NewClientSummaryProjector(repo))
+	require.NoError(t, err)
+	assert.Positive(t, n)
+	assertSummariesConsistent(t, db, repo)
+}
+
+// Тест проверяет пересоздание сводок по исходным таблицам без событий
+func Test_RebuildClientSummaries(t *testing.T) {
+	ctx := context.Background()
+	db, repo, _, ids := setupSummaries(t, WithEncryption(NewStaticKeyProvider("v1", map[string][]byte{"v1": testKeyV1})))
+
+	// Устаревшая сводка удалённого клиента и сводка с неверными данными
+	_, err := db.ExecContext(ctx, `INSERT INTO client_summary (client_id, fio, login, email, status, order_count, updated_at)
+	VALUES (100, 'Gone', 'gone', '', 'active', 0, '2024-01-01T00:00:00Z'),
+	(:id, 'Stale', 'stale', '', 'active', 7, '2024-01-01T00:00:00Z')`, sql.Named("id", ids[1]))
+	require.NoError(t, err)
+
+	n, err := repo.RebuildClientSummaries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(ids), n)
+	assertSummariesConsistent(t, db, repo)
+	// Email в сводке хранится зашифрованным, как и в clients
+	assertRowCount(t, db, "client_summary", 0, "email LIKE '%@%'")
+}
+
+// Тест проверяет отбор сводок по подстроке, метке, статусу, активности и
+// числу заказов
+func Test_ClientSummaries_Filter(t *testing.T) {
+	ctx := context.Background()
+	_, repo, _, ids := setupSummaries(t)
+	require.NoError(t, repo.ChangeStatus(ctx, ids[2], StatusBlocked))
+	_, err := repo.RebuildClientSummaries(ctx)
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		filter SummaryFilter
+		want   []int
+	}{
+		"all":          {SummaryFilter{}, ids},
+		"query fio":    {SummaryFilter{Query: "PETROV"}, ids[1:2]},
+		"query email":  {SummaryFilter{Query: "mail.com"}, ids},
+		"tag":          {SummaryFilter{Tag: " VIP "}, []int{ids[0], ids[2]}},
+		"status":       {SummaryFilter{Statuses: []ClientStatus{StatusBlocked}}, ids[2:]},
+		"active since": {SummaryFilter{ActiveSince: time.Date(2024, 3, 1, 12, 5, 0, 0, time.UTC)}, ids[1:]},
+		"min orders":   {SummaryFilter{MinOrders: 1}, ids[:1]},
+		"combined":     {SummaryFilter{Query: "o", Tag: "vip", Statuses: []ClientStatus{StatusActive}}, ids[:1]},
+	} {
+		var got []int
+		require.NoError(t, repo.ClientSummaries(ctx, tc.filter, func(s ClientSummary) error {
+			got = append(got, s.ClientID)
+			return nil
+		}), name)
+		assert.Equal(t, tc.want, got, name)
+	}
+
+	for _, filter := range []SummaryFilter{
+		{Tag: " "},
+		{Statuses: []ClientStatus{"unknown"}},
+		{MinOrders: -1},
+	} {
+		err := repo.ClientSummaries(ctx, filter, func(ClientSummary) error { return nil })
+		assert.ErrorIs(t, err, ErrValidation, "%+v", filter)
+	}
+}
+
+// Тест проверяет публикацию событий сразу в несколько получателей и
+// повторную публикацию всем после ошибки одного из них
+func Test_EventSinks(t *testing.T) {
+	ctx := context.Background()
+	db, repo := setupOutbox(t, testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)))
+	_, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	recorder := &recordingSink{}
+	failing := &recordingSink{err: assert.AnError}
+	relay := NewOutboxRelay(repo, EventSinks{NewClientSummaryProjector(repo), recorder, failing})
+	_, err = relay.Relay(ctx)
+	require.ErrorIs(t, err, assert.AnError)
+	assertRowCount(t, db, "client_summary", 1, "1")
+
+	failing.err = nil
+	n, err := relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, recorder.events, 2)
+	assert.Len(t, failing.events, 1)
+	assertRowCount(t, db, "client_summary", 1, "1")
+}
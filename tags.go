@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTagLen — наибольшая длина названия метки в символах.
+const maxTagLen = 64
+
+// TagMatch — способ отбора клиентов по нескольким меткам.
+type TagMatch int
+
+const (
+	// MatchAllTags отбирает клиентов, отмеченных всеми метками (AND).
+	MatchAllTags TagMatch = iota
+	// MatchAnyTag отбирает клиентов, отмеченных хотя бы одной меткой (OR).
+	MatchAnyTag
+)
+
+// normalizeTag приводит название метки к виду, в котором оно хранится:
+// без пробелов по краям и в нижнем регистре, поэтому "VIP" и " vip " —
+// одна метка.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "":
+		return "", fmt.Errorf("%w: tag is required", ErrValidation)
+	case !utf8.ValidString(tag):
+		return "", fmt.Errorf("%w: tag is not valid UTF-8", ErrValidation)
+	case utf8.RuneCountInString(tag) > maxTagLen:
+		return "", fmt.Errorf("%w: tag is longer than %d characters", ErrValidation, maxTagLen)
+	}
+
+	return tag, nil
+}
+
+// TagClient отмечает клиента меткой tag, создавая метку при необходимости.
+// Повторная отметка той же меткой ничего не меняет.
+func (r *Repository) TagClient(ctx context.Context, clientID int, tag string) (err error) {
+	ctx, end := r.startClientOperation(ctx, "tag_client", clientID)
+	defer end(&err)
+
+	if tag, err = normalizeTag(tag); err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(q querier) error {
+		if err := r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		if _, err := q.ExecContext(ctx, "INSERT OR IGNORE INTO tags (name) VALUES (:name)", sql.Named("name", tag)); err != nil {
+			return err
+		}
+
+		res, err := q.ExecContext(ctx, `INSERT OR IGNORE INTO client_tags (client_id, tag_id)
+			SELECT :client_id, id FROM tags WHERE name = :name`,
+			sql.Named("client_id", clientID),
+			sql.Named("name", tag))
+		if err != nil {
+			return err
+		}
+
+		return r.tagsChanged(ctx, q, res, clientID)
+	})
+}
+
+// UntagClient снимает с клиента метку tag. Снятие отсутствующей метки
+// ничего не меняет.
+func (r *Repository) UntagClient(ctx context.Context, clientID int, tag string) (err error) {
+	ctx, end := r.startClientOperation(ctx, "untag_client", clientID)
+	defer end(&err)
+
+	if tag, err = normalizeTag(tag); err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(q querier) error {
+		if err := r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		res, err := q.ExecContext(ctx, "DELETE FROM client_tags WHERE client_id = :client_id AND tag_id IN (SELECT id FROM tags WHERE name = :name)",
+			sql.Named("client_id", clientID),
+			sql.Named("name", tag))
+		if err != nil {
+			return err
+		}
+
+		return r.tagsChanged(ctx, q, res, clientID)
+	})
+}
+
+// tagsChanged записывает событие EventTags, если запрос с результатом res
+// изменил метки клиента clientID.
+func (r *Repository) tagsChanged(ctx context.Context, q querier, res sql.Result, clientID int) error {
+	changed, err := res.RowsAffected()
+	if err != nil || changed == 0 {
+		return err
+	}
+
+	return r.enqueueEvent(ctx, q, EventTags, clientID, r.now(), nil)
+}
+
+// ClientTags возвращает метки клиента в алфавитном порядке.
+func (r *Repository) ClientTags(ctx context.Context, clientID int) (_ []string, err error) {
+	ctx, end := r.startClientOperation(ctx, "client_tags", clientID)
+	defer end(&err)
+
+	var tags []string
+	err = r.inTx(ctx, func(q querier) error {
+		if err := r.clientExists(ctx, q, clientID); err != nil {
+			return err
+		}
+
+		tags, err = queryStrings(ctx, q, "SELECT t.name FROM client_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.client_id = :client_id ORDER BY t.name",
+			sql.Named("client_id", clientID))
+		return err
+	})
+
+	return tags, err
+}
+
+// ClientsByTags вызывает fn для каждого клиента, отмеченного метками tags
+// (всеми или хотя бы одной в зависимости от match), в порядке возрастания ID.
+func (r *Repository) ClientsByTags(ctx context.Context, match TagMatch, tags []string, fn func(Client) error) (err error) {
+	ctx, end := r.startOperation(ctx, "clients_by_tags")
+	defer end(&err)
+
+	cond, args, err := tagsCond(match, tags)
+	if err != nil {
+		return err
+	}
+
+	return r.forEach(ctx, cond, args, fn)
+}
+
+// tagsCond возвращает условие для forEach, отбирающее клиентов по меткам
+// tags, и его параметры.
+func tagsCond(match TagMatch, tags []string) (string, []any, error) {
+	if len(tags) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one tag is required", ErrValidation)
+	}
+
+	names := make(map[string]struct{}, len(tags))
+	placeholders := make([]string, 0, len(tags))
+	args := make([]any, 0, len(tags)+1)
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, ok := names[tag]; ok {
+			continue
+		}
+		names[tag] = struct{}{}
+
+		name := fmt.Sprintf("tag%d", len(placeholders))
+		placeholders = append(placeholders, ":"+name)
+		args = append(args, sql.Named(name, tag))
+	}
+
+	having := ""
+	if match == MatchAllTags {
+		having = " HAVING COUNT(*) = :tag_count"
+		args = append(args, sql.Named("tag_count", len(placeholders)))
+	}
+
+	cond := ` AND id IN (SELECT ct.client_id FROM client_tags ct JOIN tags t ON t.id = ct.tag_id
+		WHERE t.name IN (` + strings.Join(placeholders, ", ") + `) GROUP BY ct.client_id` + having + `)`
+
+	return cond, args, nil
+}
+
+// DeleteTag удаляет метку tag и снимает её со всех клиентов или возвращает
+// ErrTagNotFound, если такой метки нет.
+func (r *Repository) DeleteTag(ctx context.Context, tag string) (err error) {
+	ctx, end := r.startOperation(ctx, "delete_tag")
+	defer end(&err)
+
+	if tag, err = normalizeTag(tag); err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(q querier) error {
+		clients, err := queryStrings(ctx, q, "DELETE FROM client_tags WHERE tag_id IN (SELECT id FROM tags WHERE name = :name) RETURNING client_id", sql.Named("name", tag))
+		if err != nil {
+			return err
+		}
+		at := r.now()
+		for _, s := range clients {
+			clientID, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			if err := r.enqueueEvent(ctx, q, EventTags, clientID, at, nil); err != nil {
+				return err
+			}
+		}
+
+		res, err := q.ExecContext(ctx, "DELETE FROM tags WHERE name = :name", sql.Named("name", tag))
+		if err != nil {
+			return err
+		}
+
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return fmt.Errorf("%w: %q", ErrTagNotFound, tag)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,124 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientIDsByTags возвращает ID клиентов, отобранных ClientsByTags
+func clientIDsByTags(t *testing.T, repo *Repository, match TagMatch, tags ...string) []int {
+	t.Helper()
+
+	var ids []int
+	err := repo.ClientsByTags(context.Background(), match, tags, func(cl Client) error {
+		ids = append(ids, cl.ID)
+		return nil
+	})
+	require.NoError(t, err, "error selecting clients by tags %v", tags)
+
+	return ids
+}
+
+// Тест проверяет отбор клиентов по меткам с семантикой AND и OR
+func Test_Tags_ClientsByTags(t *testing.T) {
+	t.Parallel()
+	_, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	ids := make([]int, 3)
+	for i := range ids {
+		var err error
+		ids[i], err = repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+	}
+	tagged := map[int][]string{
+		ids[0]: {"vip", "moscow"},
+		ids[1]: {"vip"},
+		ids[2]: {"moscow", "newsletter"},
+	}
+	for id, tags := range tagged {
+		for _, tag := range tags {
+			require.NoError(t, repo.TagClient(ctx, id, tag))
+		}
+	}
+
+	assert.Equal(t, []int{ids[0], ids[1]}, clientIDsByTags(t, repo, MatchAllTags, "vip"))
+	assert.Equal(t, []int{ids[0]}, clientIDsByTags(t, repo, MatchAllTags, "vip", "moscow"))
+	assert.Equal(t, []int{ids[0], ids[1], ids[2]}, clientIDsByTags(t, repo, MatchAnyTag, "vip", "moscow"))
+	assert.Equal(t, []int{ids[2]}, clientIDsByTags(t, repo, MatchAnyTag, "newsletter", "unknown"))
+	assert.Empty(t, clientIDsByTags(t, repo, MatchAllTags, "newsletter", "unknown"))
+	assert.Equal(t, []int{ids[0]}, clientIDsByTags(t, repo, MatchAllTags, "VIP", " moscow ", "vip"), "tags should be normalized and deduplicated")
+
+	err := repo.ClientsByTags(ctx, MatchAnyTag, nil, func(Client) error { return nil })
+	require.ErrorIs(t, err, ErrValidation, "empty tag list should be rejected")
+}
+
+// Тест проверяет, что повторная отметка и снятие отсутствующей метки
+// ничего не меняют
+func Test_Tags_DuplicateTagging(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	for _, tag := range []string{"vip", "VIP", " vip"} {
+		require.NoError(t, repo.TagClient(ctx, id, tag))
+	}
+	tags, err := repo.ClientTags(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vip"}, tags)
+	assertRowCount(t, tx, "client_tags", 1, "client_id = ?", id)
+	assertRowCount(t, tx, "tags", 1, "name = 'vip'")
+
+	require.NoError(t, repo.UntagClient(ctx, id, "vip"))
+	require.NoError(t, repo.UntagClient(ctx, id, "vip"), "removing a missing tag should be a no-op")
+	tags, err = repo.ClientTags(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	require.ErrorIs(t, repo.TagClient(ctx, id, " "), ErrValidation)
+	require.ErrorIs(t, repo.TagClient(ctx, id, strings.Repeat("x", maxTagLen+1)), ErrValidation)
+	require.ErrorIs(t, repo.TagClient(ctx, -1, "vip"), ErrClientNotFound)
+	_, err = repo.ClientTags(ctx, -1)
+	require.ErrorIs(t, err, ErrClientNotFound)
+}
+
+// Тест проверяет, что удаление метки снимает её со всех клиентов, а
+// удаление клиента — его отметки
+func Test_Tags_Deletion(t *testing.T) {
+	t.Parallel()
+	tx, repo := setupTestTx(t)
+
+	ctx := context.Background()
+
+	first, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	second, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	for _, id := range []int{first, second} {
+		require.NoError(t, repo.TagClient(ctx, id, "churned"))
+		require.NoError(t, repo.TagClient(ctx, id, "vip"))
+	}
+
+	require.NoError(t, repo.DeleteTag(ctx, "Churned"))
+	require.ErrorIs(t, repo.DeleteTag(ctx, "churned"), ErrTagNotFound)
+	assertRowCount(t, tx, "tags", 0, "name = 'churned'")
+	assert.Empty(t, clientIDsByTags(t, repo, MatchAnyTag, "churned"))
+	tags, err := repo.ClientTags(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vip"}, tags)
+
+	require.NoError(t, repo.Delete(ctx, first))
+	assertRowCount(t, tx, "client_tags", 0, "client_id = ?", first)
+	assert.Equal(t, []int{second}, clientIDsByTags(t, repo, MatchAllTags, "vip"))
+}
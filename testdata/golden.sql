@@ -0,0 +1,36 @@
+-- Эталонный набор данных для тестов (см. TestMain в integration_test.go).
+-- Тесты, изменяющие данные, удаляют свои строки после завершения.
+
+INSERT INTO products (id, product, price) VALUES
+	(1, 'Мои финансы', 750),
+	(2, 'Суперпланировщик', 600),
+	(3, 'Заметки', 450);
+
+INSERT INTO clients (id, fio, login, birthday, email) VALUES
+	(1, 'Ковшутин Игнатий Вячеславович', 'ignatiy02091984', '19840902', 'ignatiy02091984@gmail.com'),
+	(2, 'Башкатов Данила Валентинович', 'danila95', '19950505', 'danila95@gmail.com'),
+	(3, 'Яфаева Василиса Арсеньевна', 'vasilisa1976', '19761109', 'vasilisa1976@rambler.ru'),
+	(4, 'Нилова Виктория Саввановна', 'viktoriya.nilova', '19840405', 'viktoriya.nilova@hotmail.com'),
+	(5, 'Полотенцев Вениамин Аркадьевич', 'veniamin22061991', '19910622', 'veniamin22061991@outlook.com'),
+	(6, 'Мандрыка Евгения Никандровна', 'evgeniya04071993', '19930704', 'evgeniya04071993@mail.ru'),
+	(7, 'Розанова Юлия Семеновна', 'yuliya9103', '19770504', 'yuliya9103@gmail.com'),
+	(8, 'Меликов Николай Акимович', 'nikolay1978', '19780915', 'nikolay1978@ya.ru'),
+	(9, 'Еркулаева Альбина Константиновна', 'albina.erkulaeva', '19930527', 'albina.erkulaeva@hotmail.com'),
+	(10, 'Меледин Константин Аркадьевич', 'konstantin77', '19630715', 'konstantin77@outlook.com');
+
+INSERT INTO sales (id, client, product, volume, date) VALUES
+	(1, 1, 1, 1, '20230422'),
+	(2, 2, 2, 1, '20231106'),
+	(3, 3, 3, 1, '20231114'),
+	(4, 4, 2, 1, '20230221'),
+	(5, 4, 3, 1, '20231031'),
+	(6, 4, 3, 7, '20230326'),
+	(7, 4, 3, 1, '20230930'),
+	(8, 5, 3, 1, '20231106'),
+	(9, 6, 2, 2, '20230107'),
+	(10, 7, 1, 1, '20230211'),
+	(11, 8, 3, 1, '20230222'),
+	(12, 8, 1, 1, '20230308'),
+	(13, 9, 3, 5, '20230404'),
+	(14, 9, 3, 9, '20231026'),
+	(15, 10, 3, 1, '20230217');
@@ -0,0 +1,44 @@
+// Package testutil содержит вспомогательные типы для тестов.
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock — часы, время которых меняется только явно через Set и
+// Advance. Удовлетворяет интерфейсу Clock репозитория и безопасен для
+// одновременного использования.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock возвращает часы, показывающие время now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now возвращает текущее время часов.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Set переводит часы на время now.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Advance переводит часы вперёд на d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,24 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тест проверяет, что время часов меняется только через Set и Advance
+func Test_FakeClock_SetAndAdvance(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	assert.Equal(t, start, clock.Now())
+	assert.Equal(t, start, clock.Now(), "time should not move by itself")
+
+	clock.Advance(90 * time.Minute)
+	assert.Equal(t, start.Add(90*time.Minute), clock.Now())
+
+	later := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock.Set(later)
+	assert.Equal(t, later, clock.Now())
+}
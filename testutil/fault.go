@@ -0,0 +1,136 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrInjected — ошибка внедрённого отказа по умолчанию.
+var ErrInjected = errors.New("injected fault")
+
+// FaultConfig описывает внедряемые отказы.
+type FaultConfig struct {
+	// FailEvery — каждый FailEvery-й вызов завершается ошибкой; 0 — без отказов.
+	FailEvery int
+	// Err — ошибка отказа; по умолчанию ErrInjected.
+	Err error
+	// Latency — задержка перед каждым вызовом. Если контекст вызова
+	// отменяется раньше, вызов завершается ошибкой контекста.
+	Latency time.Duration
+	// LatencyDist — случайная задержка, добавляемая к Latency.
+	LatencyDist LatencyDist
+	// Seed — зерно генератора случайной задержки: одинаковое зерно даёт
+	// одинаковую последовательность задержек.
+	Seed int64
+	// HangEvery — каждый HangEvery-й вызов зависает до истечения срока
+	// контекста и завершается его ошибкой; если срок не задан, вызов сразу
+	// завершается context.DeadlineExceeded. 0 — без зависаний.
+	HangEvery int
+	// Ops — операции, к которым применяются отказы и задержка (например,
+	// "select", "insert"); пустой список — все операции.
+	Ops []string
+}
+
+// FaultInjector решает, какие вызовы завершаются отказом, и вносит
+// задержку. Безопасен для одновременного использования.
+type FaultInjector struct {
+	cfg FaultConfig
+
+	mu    sync.Mutex
+	calls int
+	rnd   *rand.Rand
+}
+
+// NewFaultInjector создаёт FaultInjector с конфигурацией cfg.
+func NewFaultInjector(cfg FaultConfig) *FaultInjector {
+	if cfg.Err == nil {
+		cfg.Err = ErrInjected
+	}
+
+	return &FaultInjector{cfg: cfg, rnd: rand.New(rand.NewSource(cfg.Seed))}
+}
+
+// Inject вызывается перед операцией op и возвращает ошибку, которой
+// операция должна завершиться вместо выполнения, или nil.
+func (f *FaultInjector) Inject(ctx context.Context, op string) error {
+	if len(f.cfg.Ops) > 0 && !slices.Contains(f.cfg.Ops, op) {
+		return nil
+	}
+
+	f.mu.Lock()
+	f.calls++
+	n := f.calls
+	latency := f.cfg.Latency
+	if f.cfg.LatencyDist != nil {
+		latency += f.cfg.LatencyDist(f.rnd)
+	}
+	f.mu.Unlock()
+
+	if f.cfg.HangEvery > 0 && n%f.cfg.HangEvery == 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			return context.DeadlineExceeded
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if f.cfg.FailEvery > 0 && n%f.cfg.FailEvery == 0 {
+		return f.cfg.Err
+	}
+
+	return nil
+}
+
+// Calls возвращает число вызовов, к которым применялись отказы.
+func (f *FaultInjector) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// LatencyDist возвращает случайную задержку, используя генератор rnd.
+type LatencyDist func(rnd *rand.Rand) time.Duration
+
+// UniformLatency — задержка, равномерно распределённая на [lo, hi).
+func UniformLatency(lo, hi time.Duration) LatencyDist {
+	return func(rnd *rand.Rand) time.Duration {
+		if hi <= lo {
+			return lo
+		}
+		return lo + time.Duration(rnd.Int63n(int64(hi-lo)))
+	}
+}
+
+// NormalLatency — нормально распределённая задержка; отрицательные
+// значения заменяются нулём.
+func NormalLatency(mean, stddev time.Duration) LatencyDist {
+	return func(rnd *rand.Rand) time.Duration {
+		return max(0, mean+time.Duration(rnd.NormFloat64()*float64(stddev)))
+	}
+}
+
+// ExponentialLatency — экспоненциально распределённая задержка со средним
+// mean: в основном короткие задержки с длинным хвостом редких медленных
+// вызовов.
+func ExponentialLatency(mean time.Duration) LatencyDist {
+	return func(rnd *rand.Rand) time.Duration {
+		return time.Duration(math.Min(rnd.ExpFloat64()*float64(mean), math.MaxInt64))
+	}
+}
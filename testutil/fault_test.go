@@ -0,0 +1,151 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepository — репозиторий строк в памяти для проверки обёртки
+type memRepository struct {
+	rows  map[int]string
+	calls int
+}
+
+func newMemRepository() *memRepository {
+	return &memRepository{rows: make(map[int]string)}
+}
+
+func (m *memRepository) Select(_ context.Context, id int) (string, error) {
+	m.calls++
+	return m.rows[id], nil
+}
+
+func (m *memRepository) Insert(_ context.Context, s string) (int, error) {
+	m.calls++
+	m.rows[len(m.rows)+1] = s
+	return len(m.rows), nil
+}
+
+func (m *memRepository) Update(_ context.Context, _ string) error {
+	m.calls++
+	return nil
+}
+
+func (m *memRepository) Delete(_ context.Context, id int) error {
+	m.calls++
+	delete(m.rows, id)
+	return nil
+}
+
+// Тест проверяет отказ каждого N-го вызова и пропуск отказавших вызовов
+// к обёрнутому репозиторию
+func Test_FaultyRepository_FailEvery(t *testing.T) {
+	mem := newMemRepository()
+	repo := NewFaultyRepository[string](mem, NewFaultInjector(FaultConfig{FailEvery: 3}))
+
+	ctx := context.Background()
+	var failed []int
+	for i := 1; i <= 7; i++ {
+		if _, err := repo.Insert(ctx, "row"); err != nil {
+			require.ErrorIs(t, err, ErrInjected)
+			failed = append(failed, i)
+		}
+	}
+
+	assert.Equal(t, []int{3, 6}, failed)
+	assert.Equal(t, 5, mem.calls, "failed calls should not reach the wrapped repository")
+}
+
+// Тест проверяет заданную ошибку и ограничение отказов операциями
+func Test_FaultyRepository_ErrAndOps(t *testing.T) {
+	errDown := errors.New("database is down")
+	faults := NewFaultInjector(FaultConfig{FailEvery: 1, Err: errDown, Ops: []string{"delete"}})
+	repo := NewFaultyRepository[string](newMemRepository(), faults)
+
+	ctx := context.Background()
+	id, err := repo.Insert(ctx, "row")
+	require.NoError(t, err)
+	_, err = repo.Select(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, repo.Update(ctx, "row"))
+
+	require.ErrorIs(t, repo.Delete(ctx, id), errDown)
+	assert.Equal(t, 1, faults.Calls(), "only targeted operations should be counted")
+}
+
+// Тест проверяет внесение задержки и её прерывание по контексту
+func Test_FaultyRepository_Latency(t *testing.T) {
+	const latency = 20 * time.Millisecond
+	repo := NewFaultyRepository[string](newMemRepository(), NewFaultInjector(FaultConfig{Latency: latency}))
+
+	started := time.Now()
+	_, err := repo.Select(context.Background(), 1)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(started), latency)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = repo.Select(ctx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// Тест проверяет границы и воспроизводимость случайных задержек
+func Test_LatencyDist(t *testing.T) {
+	const samples = 10000
+	tests := []struct {
+		name     string
+		dist     LatencyDist
+		lo, hi   time.Duration
+		meanWant time.Duration
+	}{
+		{name: "Uniform", dist: UniformLatency(10*time.Millisecond, 30*time.Millisecond), lo: 10 * time.Millisecond, hi: 30 * time.Millisecond, meanWant: 20 * time.Millisecond},
+		{name: "Normal", dist: NormalLatency(20*time.Millisecond, 5*time.Millisecond), lo: 0, hi: time.Second, meanWant: 20 * time.Millisecond},
+		{name: "Exponential", dist: ExponentialLatency(20 * time.Millisecond), lo: 0, hi: time.Hour, meanWant: 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(1))
+			var sum time.Duration
+			for i := 0; i < samples; i++ {
+				d := tt.dist(rnd)
+				require.GreaterOrEqual(t, d, tt.lo)
+				require.Less(t, d, tt.hi)
+				sum += d
+			}
+			assert.InDelta(t, float64(tt.meanWant), float64(sum/samples), float64(tt.meanWant)/10, "mean latency")
+
+			assert.Equal(t, tt.dist(rand.New(rand.NewSource(7))), tt.dist(rand.New(rand.NewSource(7))), "same seed should give same latency")
+		})
+	}
+}
+
+// Тест проверяет зависание каждого N-го вызова до истечения срока контекста
+func Test_FaultyRepository_HangEvery(t *testing.T) {
+	mem := newMemRepository()
+	repo := NewFaultyRepository[string](mem, NewFaultInjector(FaultConfig{HangEvery: 2}))
+
+	_, err := repo.Select(context.Background(), 1)
+	require.NoError(t, err)
+
+	// Без срока зависший вызов сразу завершается превышением срока
+	_, err = repo.Select(context.Background(), 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = repo.Select(context.Background(), 1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err = repo.Select(ctx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, time.Since(started), 10*time.Millisecond, "call should hang until the deadline")
+	assert.Equal(t, 2, mem.calls, "hung calls should not reach the wrapped repository")
+}
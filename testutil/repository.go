@@ -0,0 +1,59 @@
+package testutil
+
+import "context"
+
+// Repository — основные операции репозитория сущностей типа C; совпадает
+// с ClientRepository основного пакета для C = Client.
+type Repository[C any] interface {
+	Select(ctx context.Context, id int) (C, error)
+	Insert(ctx context.Context, entity C) (int, error)
+	Update(ctx context.Context, entity C) error
+	Delete(ctx context.Context, id int) error
+}
+
+// FaultyRepository — обёртка над репозиторием, внедряющая перед каждой
+// операцией отказы и задержки FaultInjector. Предназначена для проверки
+// повторов и прерывателей в вышестоящих слоях. Операции называются
+// "select", "insert", "update" и "delete".
+type FaultyRepository[C any] struct {
+	next   Repository[C]
+	faults *FaultInjector
+}
+
+// NewFaultyRepository оборачивает next отказами faults.
+func NewFaultyRepository[C any](next Repository[C], faults *FaultInjector) *FaultyRepository[C] {
+	return &FaultyRepository[C]{next: next, faults: faults}
+}
+
+func (r *FaultyRepository[C]) Select(ctx context.Context, id int) (C, error) {
+	if err := r.faults.Inject(ctx, "select"); err != nil {
+		var zero C
+		return zero, err
+	}
+
+	return r.next.Select(ctx, id)
+}
+
+func (r *FaultyRepository[C]) Insert(ctx context.Context, entity C) (int, error) {
+	if err := r.faults.Inject(ctx, "insert"); err != nil {
+		return 0, err
+	}
+
+	return r.next.Insert(ctx, entity)
+}
+
+func (r *FaultyRepository[C]) Update(ctx context.Context, entity C) error {
+	if err := r.faults.Inject(ctx, "update"); err != nil {
+		return err
+	}
+
+	return r.next.Update(ctx, entity)
+}
+
+func (r *FaultyRepository[C]) Delete(ctx context.Context, id int) error {
+	if err := r.faults.Inject(ctx, "delete"); err != nil {
+		return err
+	}
+
+	return r.next.Delete(ctx, id)
+}
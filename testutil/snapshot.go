@@ -0,0 +1,118 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Queryer — выполнение запросов; реализуется *sql.DB, *sql.Tx и *sql.Conn.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Row — строка таблицы: значения по именам столбцов.
+type Row map[string]any
+
+// RowChange — строка, изменённая между снимками.
+type RowChange struct {
+	Before Row
+	After  Row
+}
+
+// Diff — разница между двумя снимками таблицы. Строки в каждом списке
+// упорядочены по ключу.
+type Diff struct {
+	Added    []Row
+	Modified []RowChange
+	Removed  []Row
+}
+
+// Empty сообщает, что таблица не изменилась.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Modified) == 0 && len(d.Removed) == 0
+}
+
+// Snapshot — содержимое таблицы на момент снятия, упорядоченное по ключу.
+type Snapshot struct {
+	key  string
+	keys []string
+	rows map[string]Row
+}
+
+// TakeSnapshot читает все строки таблицы table; key — столбец, по которому
+// сопоставляются строки разных снимков (обычно первичный ключ).
+func TakeSnapshot(t testing.TB, q Queryer, table, key string) Snapshot {
+	t.Helper()
+
+	rows, err := q.QueryContext(context.Background(), fmt.Sprintf("SELECT * FROM %q ORDER BY %q", table, key))
+	require.NoError(t, err, "error reading table %s: %v", table, err)
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+
+	snap := Snapshot{key: key, rows: make(map[string]Row)}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(ptrs...), "error scanning row of %s", table)
+
+		row := make(Row, len(columns))
+		for i, c := range columns {
+			// []byte не сравнимы и переиспользуются драйвером
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[c] = values[i]
+		}
+
+		id := fmt.Sprint(row[key])
+		snap.keys = append(snap.keys, id)
+		snap.rows[id] = row
+	}
+	require.NoError(t, rows.Err(), "error reading table %s", table)
+
+	return snap
+}
+
+// Diff возвращает строки, добавленные, изменённые и удалённые в after
+// по сравнению с s.
+func (s Snapshot) Diff(after Snapshot) Diff {
+	var d Diff
+	for _, id := range after.keys {
+		before, ok := s.rows[id]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, after.rows[id])
+		case !reflect.DeepEqual(before, after.rows[id]):
+			d.Modified = append(d.Modified, RowChange{Before: before, After: after.rows[id]})
+		}
+	}
+	for _, id := range s.keys {
+		if _, ok := after.rows[id]; !ok {
+			d.Removed = append(d.Removed, s.rows[id])
+		}
+	}
+
+	return d
+}
+
+// CaptureDiff снимает таблицу table до и после выполнения fn и возвращает
+// разницу, чтобы тест мог проверить ровно те побочные эффекты, которые
+// произвёл fn.
+func CaptureDiff(t testing.TB, q Queryer, table, key string, fn func()) Diff {
+	t.Helper()
+
+	before := TakeSnapshot(t, q, table, key)
+	fn()
+
+	return before.Diff(TakeSnapshot(t, q, table, key))
+}
@@ -0,0 +1,42 @@
+package testutil
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет добавленные, изменённые и удалённые строки между снимками
+func Test_CaptureDiff(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, data BLOB);
+		INSERT INTO items (id, name, data) VALUES (1, 'one', x'01'), (2, 'two', x'02'), (3, 'three', x'03')`)
+	require.NoError(t, err)
+
+	diff := CaptureDiff(t, db, "items", "id", func() {
+		_, err := db.Exec(`UPDATE items SET name = 'TWO' WHERE id = 2;
+			DELETE FROM items WHERE id = 3;
+			INSERT INTO items (id, name, data) VALUES (10, 'ten', x'0a'), (4, 'four', x'04')`)
+		require.NoError(t, err)
+	})
+
+	assert.Equal(t, []Row{
+		{"id": int64(4), "name": "four", "data": "\x04"},
+		{"id": int64(10), "name": "ten", "data": "\x0a"},
+	}, diff.Added, "added rows should be ordered by key")
+	assert.Equal(t, []RowChange{{
+		Before: Row{"id": int64(2), "name": "two", "data": "\x02"},
+		After:  Row{"id": int64(2), "name": "TWO", "data": "\x02"},
+	}}, diff.Modified)
+	assert.Equal(t, []Row{{"id": int64(3), "name": "three", "data": "\x03"}}, diff.Removed)
+	assert.False(t, diff.Empty())
+
+	assert.True(t, CaptureDiff(t, db, "items", "id", func() {}).Empty(), "no changes should give an empty diff")
+}
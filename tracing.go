@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Yandex-Practicum/go-db-sql-query-test"
+
+// WithTracing включает трассировку OpenTelemetry: каждая операция
+// репозитория создаёт span, дочерний к span из контекста вызывающего,
+// а каждый запрос — span, дочерний к span операции. В db.statement
+// попадает только параметризованный текст SQL, значения параметров не
+// записываются.
+func WithTracing(tp trace.TracerProvider) Option {
+	return func(r *Repository) {
+		r.tracer = tp.Tracer(tracerName)
+		r.hooks = append(r.hooks, &queryTracer{tracer: r.tracer})
+	}
+}
+
+// startOperation помечает контекст именем операции репозитория и, если
+// включена трассировка, открывает span операции. Возвращаемая функция
+// закрывает span, отмечая в нём ошибку операции, учитывает ошибку в
+// метриках и оборачивает её в OpError с именем операции; она вызывается
+// отложенно с адресом именованного результата: defer end(&err).
+func (r *Repository) startOperation(ctx context.Context, op string) (context.Context, func(*error)) {
+	return r.startClientOperation(ctx, op, 0)
+}
+
+// startClientOperation начинает операцию над клиентом id как
+// startOperation; ID клиента попадает в OpError.
+func (r *Repository) startClientOperation(ctx context.Context, op string, id int) (context.Context, func(*error)) {
+	ctx = withOperation(ctx, op)
+	if r.tracer == nil {
+		return ctx, func(err *error) {
+			r.operationDone(op, *err)
+			wrapOpError(err, op, id)
+		}
+	}
+
+	ctx, span := r.tracer.Start(ctx, "clients."+op,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", op),
+		))
+
+	return ctx, func(err *error) {
+		recordSpanError(span, *err)
+		span.End()
+		r.operationDone(op, *err)
+		wrapOpError(err, op, id)
+	}
+}
+
+func (r *Repository) operationDone(op string, err error) {
+	if r.metrics != nil {
+		r.metrics.operationDone(op, err)
+	}
+}
+
+// queryTracer создаёт span для каждого выполненного запроса.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+func (t *queryTracer) afterQuery(ctx context.Context, ev queryEvent) {
+	_, span := t.tracer.Start(ctx, "db.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(ev.Start),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", ev.Op),
+			attribute.String("db.statement", ev.SQL),
+		))
+	if ev.Rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", ev.Rows))
+	}
+
+	recordSpanError(span, ev.Err)
+	span.End(trace.WithTimestamp(ev.Start.Add(ev.Duration)))
+}
+
+// recordSpanError отмечает span ошибкой. Отсутствие клиента — ожидаемый
+// результат, а не сбой, поэтому статус Error для него не ставится.
+func recordSpanError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, ErrClientNotFound) {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
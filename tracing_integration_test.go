@@ -0,0 +1,57 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	_ "modernc.org/sqlite"
+)
+
+// Тест проверяет иерархию span для транзакционной операции:
+// span вызывающего → span операции → span каждого запроса
+func Test_Tracing_SpanHierarchy(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	_, repo := setupTestDB(t, WithTracing(tp))
+
+	cl := Client{FIO: "Test", Login: "Test", Birthday: "19700101", Email: "trace373@mail.com"}
+
+	ctx, caller := tp.Tracer("test").Start(context.Background(), "caller")
+	var err error
+	cl.ID, err = repo.Insert(ctx, cl)
+	caller.End()
+	require.NoError(t, err, "error inserting client: %v, error: %v", cl, err)
+
+	spans := recorder.Ended()
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+
+	require.Len(t, byName["caller"], 1)
+	require.Len(t, byName["clients.insert"], 1)
+	// INSERT клиента, INSERT в журнал аудита и в outbox в одной транзакции
+	require.Len(t, byName["db.query"], 3)
+
+	callerSpan := byName["caller"][0]
+	opSpan := byName["clients.insert"][0]
+
+	assert.Equal(t, callerSpan.SpanContext().TraceID(), opSpan.SpanContext().TraceID(), "operation should join the caller's trace")
+	assert.Equal(t, callerSpan.SpanContext().SpanID(), opSpan.Parent().SpanID(), "operation span should be a child of the caller span")
+	assert.Equal(t, "sqlite", spanAttr(opSpan, "db.system").AsString())
+
+	for _, q := range byName["db.query"] {
+		assert.Equal(t, opSpan.SpanContext().SpanID(), q.Parent().SpanID(), "query span should be a child of the operation span")
+		assert.Equal(t, "sqlite", spanAttr(q, "db.system").AsString())
+		assert.Contains(t, spanAttr(q, "db.statement").AsString(), "INSERT INTO")
+		assert.NotContains(t, spanAttr(q, "db.statement").AsString(), cl.Email, "statement must not contain parameter values")
+		assert.Equal(t, codes.Unset, q.Status().Code)
+	}
+}
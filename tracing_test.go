@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	_ "modernc.org/sqlite"
+)
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+// Тест проверяет статус ошибки в span операции и запроса
+func Test_Tracing_ErrorStatus(t *testing.T) {
+	db := openMemoryDB(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	repo := NewRepository(db, WithTracing(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	_, err := repo.Select(context.Background(), 1)
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		assert.Equal(t, codes.Error, s.Status().Code, "span %s should have error status", s.Name())
+	}
+}
+
+// Тест проверяет, что ошибка проверки попадает в span без значения
+// отклонённой даты рождения: span экспортируются во внешнюю систему
+func Test_Tracing_ErrorWithoutPII(t *testing.T) {
+	ctx := context.Background()
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(ctx, db))
+
+	recorder := tracetest.NewSpanRecorder()
+	repo := NewRepository(db, WithTracing(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	for _, birthday := range []string{"19991332", "21000101"} {
+		_, err := repo.Insert(ctx, newTestClient(func(cl *Client) { cl.Birthday = birthday }))
+		require.ErrorIs(t, err, ErrValidation)
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, birthday, fieldErr.Value, "the value stays available to callers")
+		assert.NotContains(t, err.Error(), birthday)
+	}
+
+	spans := recorder.Ended()
+	require.NotEmpty(t, spans)
+	for _, s := range spans {
+		exported := []string{s.Status().Description}
+		for _, ev := range s.Events() {
+			for _, kv := range ev.Attributes {
+				exported = append(exported, kv.Value.Emit())
+			}
+		}
+		for _, kv := range s.Attributes() {
+			exported = append(exported, kv.Value.Emit())
+		}
+		for _, value := range exported {
+			assert.NotContains(t, value, "19991332", "span %s", s.Name())
+			assert.NotContains(t, value, "21000101", "span %s", s.Name())
+		}
+	}
+	assert.Contains(t, spans[len(spans)-1].Status().Description, "birthday is out of range")
+}
@@ -0,0 +1,654 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ErrTransferMismatch возвращается CopyDatabase, если после переноса число
+// строк или контрольная сумма таблицы в целевой БД отличается от исходной.
+var ErrTransferMismatch = errors.New("transferred data does not match the source")
+
+// DefaultTransferBatch — сколько строк CopyDatabase переносит одной
+// транзакцией целевой БД по умолчанию.
+const DefaultTransferBatch = 500
+
+// transferTable — переносимая таблица и столбцы её первичного ключа.
+type transferTable struct {
+	name string
+	key  []string
+}
+
+// transferTables — таблицы, переносимые CopyDatabase. Таблицы, на которые
+// ссылаются внешние ключи, идут раньше ссылающихся на них. Журнал
+// изменений change_log не переносится: его ведут триггеры SQLite, и
+// целевая БД SQLite записывает в свой журнал вставку перенесённых
+// клиентов.
+var transferTables = []transferTable{
+	{"clients", []string{"id"}},
+	{"products", []string{"id"}},
+	{"sales", []string{"id"}},
+	{"erasure_receipts", []string{"id"}},
+	{"audit_log", []string{"id"}},
+	{"orders", []string{"id"}},
+	{"client_notes", []string{"id"}},
+	{"tags", []string{"id"}},
+	{"client_tags", []string{"client_id", "tag_id"}},
+	{"clients_history", []string{"id"}},
+	{"clients_archive", []string{"id"}},
+	{"client_documents", []string{"id"}},
+	{"segments", []string{"id"}},
+	{"environment", []string{"id"}},
+	{"outbox", []string{"id"}},
+	{"webhook_endpoints", []string{"id"}},
+	{"webhook_deliveries", []string{"id"}},
+	{"webhook_attempts", []string{"id"}},
+	{"client_summary", []string{"client_id"}},
+	{"sync_cursors", []string{"id"}},
+	{"directory_links", []string{"id"}},
+	{"client_autocomplete", []string{"id"}},
+}
+
+// postgresSchema — схема Postgres, соответствующая последней миграции
+// SQLite. Целые числа хранятся как BIGINT, строки — как TEXT: SQLite не
+// проверяет длину VARCHAR, и длинные значения не должны ломать перенос.
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS clients (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	fio TEXT NOT NULL DEFAULT '',
+	login TEXT NOT NULL DEFAULT '',
+	birthday TEXT NOT NULL DEFAULT '',
+	email TEXT NOT NULL DEFAULT '',
+	owner_id TEXT NOT NULL DEFAULT '',
+	marketing_consent BIGINT NOT NULL DEFAULT 0,
+	consent_updated_at TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'active',
+	valid_from TEXT NOT NULL DEFAULT '',
+	deleted_at TEXT NOT NULL DEFAULT '',
+	merged_into BIGINT NOT NULL DEFAULT 0,
+	preferences TEXT NOT NULL DEFAULT '{}',
+	legal_hold BIGINT NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL DEFAULT '',
+	version BIGINT NOT NULL DEFAULT 1
+)`,
+	`CREATE INDEX IF NOT EXISTS clients_owner_id ON clients (owner_id)`,
+	`CREATE INDEX IF NOT EXISTS clients_created_at ON clients (created_at)`,
+	`CREATE TABLE IF NOT EXISTS products (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	product TEXT NOT NULL DEFAULT '',
+	price BIGINT NOT NULL DEFAULT 0
+)`,
+	`CREATE TABLE IF NOT EXISTS sales (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client BIGINT NOT NULL DEFAULT 0,
+	product BIGINT NOT NULL DEFAULT 0,
+	volume BIGINT NOT NULL DEFAULT 1,
+	date TEXT NOT NULL DEFAULT ''
+)`,
+	`CREATE TABLE IF NOT EXISTS erasure_receipts (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client_id BIGINT NOT NULL,
+	erased_at TEXT NOT NULL,
+	deleted TEXT NOT NULL DEFAULT '{}'
+)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	actor TEXT NOT NULL DEFAULT '',
+	occurred_at TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	client_id BIGINT NOT NULL,
+	diff TEXT NOT NULL DEFAULT '{}',
+	request_id TEXT NOT NULL DEFAULT ''
+)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_client_id ON audit_log (client_id)`,
+	`CREATE TABLE IF NOT EXISTS orders (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client_id BIGINT NOT NULL REFERENCES clients (id) ON DELETE RESTRICT,
+	amount BIGINT NOT NULL CHECK (amount >= 0),
+	status TEXT NOT NULL DEFAULT 'new',
+	created_at TEXT NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS orders_client_id ON orders (client_id)`,
+	`CREATE TABLE IF NOT EXISTS client_notes (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client_id BIGINT NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	author TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL,
+	created_at TEXT NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS client_notes_client_id ON client_notes (client_id, created_at)`,
+	`CREATE TABLE IF NOT EXISTS tags (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+)`,
+	`CREATE TABLE IF NOT EXISTS client_tags (
+	client_id BIGINT NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	tag_id BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (client_id, tag_id)
+)`,
+	`CREATE INDEX IF NOT EXISTS client_tags_tag_id ON client_tags (tag_id)`,
+	`CREATE TABLE IF NOT EXISTS clients_history (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client_id BIGINT NOT NULL,
+	fio TEXT NOT NULL,
+	login TEXT NOT NULL,
+	birthday TEXT NOT NULL,
+	email TEXT NOT NULL,
+	owner_id TEXT NOT NULL,
+	marketing_consent BIGINT NOT NULL,
+	consent_updated_at TEXT NOT NULL,
+	status TEXT NOT NULL,
+	valid_from TEXT NOT NULL,
+	valid_to TEXT NOT NULL,
+	operation TEXT NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS clients_history_client_id ON clients_history (client_id, valid_to)`,
+	`CREATE TABLE IF NOT EXISTS clients_archive (
+	id BIGINT PRIMARY KEY,
+	fio TEXT NOT NULL,
+	login TEXT NOT NULL,
+	birthday TEXT NOT NULL,
+	email TEXT NOT NULL,
+	owner_id TEXT NOT NULL,
+	marketing_consent BIGINT NOT NULL,
+	consent_updated_at TEXT NOT NULL,
+	status TEXT NOT NULL,
+	valid_from TEXT NOT NULL,
+	deleted_at TEXT NOT NULL,
+	merged_into BIGINT NOT NULL,
+	preferences TEXT NOT NULL,
+	legal_hold BIGINT NOT NULL,
+	archived_at TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT '',
+	version BIGINT NOT NULL DEFAULT 1
+)`,
+	`CREATE TABLE IF NOT EXISTS client_documents (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client_id BIGINT NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	mime_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	blob_key TEXT NOT NULL UNIQUE,
+	created_at TEXT NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS client_documents_client_id ON client_documents (client_id)`,
+	`CREATE TABLE IF NOT EXISTS segments (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	criteria TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS environment (
+	id BIGINT PRIMARY KEY CHECK (id = 1),
+	name TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS outbox (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client_id BIGINT NOT NULL,
+	operation TEXT NOT NULL,
+	occurred_at TEXT NOT NULL,
+	actor TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	fields TEXT NOT NULL DEFAULT '[]',
+	attempts BIGINT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	delivered_at TEXT NOT NULL DEFAULT ''
+)`,
+	`CREATE INDEX IF NOT EXISTS outbox_pending ON outbox (delivered_at, id)`,
+	`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL DEFAULT '[]',
+	created_at TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+	event_id BIGINT NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts BIGINT NOT NULL DEFAULT 0,
+	next_attempt_at TEXT NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	UNIQUE (endpoint_id, event_id)
+)`,
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at)`,
+	`CREATE TABLE IF NOT EXISTS webhook_attempts (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+	attempted_at TEXT NOT NULL,
+	succeeded BIGINT NOT NULL,
+	status_code BIGINT NOT NULL DEFAULT 0,
+	latency_ms BIGINT NOT NULL,
+	error TEXT NOT NULL DEFAULT ''
+)`,
+	`CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_id ON webhook_attempts (delivery_id)`,
+	`CREATE TABLE IF NOT EXISTS client_summary (
+	client_id BIGINT PRIMARY KEY,
+	fio TEXT NOT NULL,
+	login TEXT NOT NULL,
+	email TEXT NOT NULL,
+	status TEXT NOT NULL,
+	owner_id TEXT NOT NULL DEFAULT '',
+	order_count BIGINT NOT NULL DEFAULT 0,
+	last_activity TEXT NOT NULL DEFAULT '',
+	tags TEXT NOT NULL DEFAULT '[]',
+	updated_at TEXT NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS client_summary_last_activity ON client_summary (last_activity)`,
+	`CREATE TABLE IF NOT EXISTS sync_cursors (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	position BIGINT NOT NULL DEFAULT 0,
+	updated_at TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS directory_links (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	source TEXT NOT NULL,
+	external_id TEXT NOT NULL,
+	client_id BIGINT NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+	synced_at TEXT NOT NULL,
+	UNIQUE (source, external_id)
+)`,
+	`CREATE INDEX IF NOT EXISTS directory_links_client_id ON directory_links (client_id)`,
+	`CREATE TABLE IF NOT EXISTS client_autocomplete (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	client_id BIGINT NOT NULL,
+	field TEXT NOT NULL CHECK (field IN ('fio', 'login')),
+	key TEXT NOT NULL,
+	phonetic TEXT NOT NULL DEFAULT ''
+)`,
+	`CREATE INDEX IF NOT EXISTS client_autocomplete_key ON client_autocomplete (key, client_id)`,
+	`CREATE INDEX IF NOT EXISTS client_autocomplete_client_id ON client_autocomplete (client_id)`,
+	`CREATE INDEX IF NOT EXISTS client_autocomplete_phonetic ON client_autocomplete (phonetic, client_id)`,
+}
+
+// transferProgressSchema — таблица хода переноса в целевой БД: для каждой
+// таблицы — rowid последней перенесённой строки исходной БД и число
+// перенесённых строк. Запрос подходит и для SQLite, и для Postgres.
+const transferProgressSchema = `CREATE TABLE IF NOT EXISTS transfer_progress (
+	table_name TEXT PRIMARY KEY,
+	last_rowid BIGINT NOT NULL,
+	copied BIGINT NOT NULL
+)`
+
+// TransferOptions настраивает CopyDatabase.
+type TransferOptions struct {
+	// BatchSize — число строк в одной транзакции целевой БД; 0 —
+	// DefaultTransferBatch.
+	BatchSize int
+	// Progress, если задан, вызывается после каждой перенесённой порции.
+	Progress func(TransferProgress)
+}
+
+// TransferProgress — ход переноса таблицы.
+type TransferProgress struct {
+	Table string `json:"table"`
+	// Copied — число перенесённых строк таблицы с учётом прерванных
+	// запусков.
+	Copied int64 `json:"copied"`
+}
+
+// TransferReport — результат CopyDatabase.
+type TransferReport struct {
+	Tables []TransferredTable `json:"tables"`
+}
+
+// TransferredTable — итог переноса таблицы.
+type TransferredTable struct {
+	Table string `json:"table"`
+	// Copied — число строк, перенесённых этим запуском.
+	Copied int64 `json:"copied"`
+	// Rows и Checksum — число строк и контрольная сумма SHA-256 таблицы,
+	// совпавшие в обеих БД.
+	Rows     int64  `json:"rows"`
+	Checksum string `json:"checksum"`
+}
+
+// transferDialect — особенности SQL целевой БД.
+type transferDialect struct {
+	postgres bool
+}
+
+func (d transferDialect) placeholder(n int) string {
+	if d.postgres {
+		return "$" + strconv.Itoa(n)
+	}
+
+	return "?"
+}
+
+// CopyDatabase переносит данные клиентов и связанных таблиц из БД SQLite
+// src в dst — Postgres (драйвер pgx) или другую БД SQLite — с сохранением
+// ID. Схема целевой БД создаётся при необходимости. Строки переносятся
+// порциями по BatchSize, каждая порция фиксируется вместе с ходом переноса
+// в таблице transfer_progress целевой БД, поэтому повторный вызов после
+// прерывания продолжает с первой неперенесённой строки. Таблица без хода
+// переноса в целевой БД должна быть пустой.
+//
+// Исходная БД читается в одной транзакции, поэтому данные переносятся на
+// один момент; строки, изменённые или удалённые после прерванного
+// запуска, повторно не переносятся. После переноса сравниваются число
+// строк и контрольные суммы всех таблиц; расхождение возвращает
+// ErrTransferMismatch. Исходная схема должна быть приведена к последней
+// версии (Migrate).
+func CopyDatabase(ctx context.Context, src, dst *sql.DB, opts TransferOptions) (TransferReport, error) {
+	if opts.BatchSize < 0 {
+		return TransferReport{}, fmt.Errorf("%w: batch size must not be negative, got %d", ErrValidation, opts.BatchSize)
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultTransferBatch
+	}
+
+	applied, err := appliedMigrations(ctx, src)
+	if err != nil {
+		return TransferReport{}, err
+	}
+	if latest := migrations[len(migrations)-1].version; applied[latest] == "" {
+		return TransferReport{}, fmt.Errorf("%w: source schema is not at version %d", ErrValidation, latest)
+	}
+
+	_, postgres := dst.Driver().(*stdlib.Driver)
+	d := transferDialect{postgres: postgres}
+	if err := prepareTransferTarget(ctx, dst, d); err != nil {
+		return TransferReport{}, err
+	}
+
+	tx, err := src.BeginTx(ctx, nil)
+	if err != nil {
+		return TransferReport{}, err
+	}
+	defer tx.Rollback()
+
+	var report TransferReport
+	for _, t := range transferTables {
+		columns, err := tableColumns(ctx, tx, t.name)
+		if err != nil {
+			return TransferReport{}, err
+		}
+		copied, err := copyTable(ctx, tx, dst, d, t, columns, opts)
+		if err != nil {
+			return TransferReport{}, fmt.Errorf("copy %s: %w", t.name, err)
+		}
+		report.Tables = append(report.Tables, TransferredTable{Table: t.name, Copied: copied})
+	}
+
+	if d.postgres {
+		if err := resetPostgresSequences(ctx, dst); err != nil {
+			return TransferReport{}, err
+		}
+	}
+
+	var mismatches []string
+	for i, t := range transferTables {
+		columns, err := tableColumns(ctx, tx, t.name)
+		if err != nil {
+			return TransferReport{}, err
+		}
+		srcRows, srcSum, err := tableChecksum(ctx, tx, t, columns)
+		if err != nil {
+			return TransferReport{}, err
+		}
+		dstRows, dstSum, err := tableChecksum(ctx, dst, t, columns)
+		if err != nil {
+			return TransferReport{}, err
+		}
+		switch {
+		case srcRows != dstRows:
+			mismatches = append(mismatches, fmt.Sprintf("%s has %d rows in the source and %d in the target", t.name, srcRows, dstRows))
+		case srcSum != dstSum:
+			mismatches = append(mismatches, fmt.Sprintf("%s checksum differs", t.name))
+		}
+		report.Tables[i].Rows, report.Tables[i].Checksum = srcRows, srcSum
+	}
+	if len(mismatches) > 0 {
+		return report, fmt.Errorf("%w: %s", ErrTransferMismatch, strings.Join(mismatches, "; "))
+	}
+
+	return report, nil
+}
+
+// prepareTransferTarget создаёт в целевой БД схему и таблицу хода переноса.
+func prepareTransferTarget(ctx context.Context, dst *sql.DB, d transferDialect) error {
+	if d.postgres {
+		for _, stmt := range postgresSchema {
+			if _, err := dst.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+	} else if err := Migrate(ctx, dst); err != nil {
+		return err
+	}
+
+	_, err := dst.ExecContext(ctx, transferProgressSchema)
+
+	return err
+}
+
+// tableColumns возвращает столбцы таблицы исходной БД.
+func tableColumns(ctx context.Context, q querier, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %q LIMIT 0", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return rows.Columns()
+}
+
+// quoteIdents заключает имена столбцов в двойные кавычки.
+func quoteIdents(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+
+	return quoted
+}
+
+// copyTable переносит строки таблицы после сохранённого хода переноса и
+// возвращает их число.
+func copyTable(ctx context.Context, src querier, dst *sql.DB, d transferDialect, t transferTable, columns []string, opts TransferOptions) (int64, error) {
+	var lastRowID, copied int64
+	err := dst.QueryRowContext(ctx, "SELECT last_rowid, copied FROM transfer_progress WHERE table_name = "+d.placeholder(1), t.name).
+		Scan(&lastRowID, &copied)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		var n int64
+		if err := dst.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %q", t.name)).Scan(&n); err != nil {
+			return 0, err
+		}
+		if n > 0 {
+			return 0, fmt.Errorf("%w: target table %s is not empty", ErrValidation, t.name)
+		}
+	case err != nil:
+		return 0, err
+	}
+
+	quoted := quoteIdents(columns)
+	selectQuery := fmt.Sprintf("SELECT rowid, %s FROM %q WHERE rowid > ? ORDER BY rowid LIMIT ?", strings.Join(quoted, ", "), t.name)
+
+	var total int64
+	for {
+		batch, last, err := readTransferBatch(ctx, src, selectQuery, len(columns), lastRowID, opts.BatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		copied += int64(len(batch))
+		if err := writeTransferBatch(ctx, dst, d, t.name, quoted, batch, last, copied); err != nil {
+			return total, err
+		}
+		lastRowID = last
+		total += int64(len(batch))
+		if opts.Progress != nil {
+			opts.Progress(TransferProgress{Table: t.name, Copied: copied})
+		}
+	}
+}
+
+// readTransferBatch читает до limit строк после rowid after и возвращает
+// их значения и rowid последней строки.
+func readTransferBatch(ctx context.Context, src querier, query string, columns int, after int64, limit int) ([][]any, int64, error) {
+	rows, err := src.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var batch [][]any
+	last := after
+	for rows.Next() {
+		values := make([]any, columns)
+		dest := make([]any, columns+1)
+		dest[0] = &last
+		for i := range values {
+			dest[i+1] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, 0, err
+		}
+		batch = append(batch, values)
+	}
+
+	return batch, last, rows.Err()
+}
+
+// writeTransferBatch вставляет порцию строк и сохраняет ход переноса в
+// одной транзакции целевой БД.
+func writeTransferBatch(ctx context.Context, dst *sql.DB, d transferDialect, table string, quoted []string, batch [][]any, lastRowID, copied int64) error {
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		query strings.Builder
+		args  = make([]any, 0, len(batch)*len(quoted))
+	)
+	fmt.Fprintf(&query, "INSERT INTO %q (%s) VALUES ", table, strings.Join(quoted, ", "))
+	for i, values := range batch {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j, v := range values {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			args = append(args, v)
+			query.WriteString(d.placeholder(len(args)))
+		}
+		query.WriteString(")")
+	}
+	if _, err := tx.ExecContext(ctx, query.String(), args...); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO transfer_progress (table_name, last_rowid, copied) VALUES (%s, %s, %s)
+ON CONFLICT (table_name) DO UPDATE SET last_rowid = excluded.last_rowid, copied = excluded.copied`,
+		d.placeholder(1), d.placeholder(2), d.placeholder(3)), table, lastRowID, copied)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// resetPostgresSequences продолжает генераторы ID Postgres после
+// перенесённых ID, иначе новые строки получили бы уже занятые ID. ID
+// клиентов продолжаются и после архивных клиентов, которые могут
+// вернуться в clients.
+func resetPostgresSequences(ctx context.Context, dst *sql.DB) error {
+	for _, t := range transferTables {
+		if len(t.key) != 1 || t.key[0] != "id" || t.name == "environment" || t.name == "clients_archive" {
+			continue
+		}
+		ids := fmt.Sprintf("SELECT id FROM %q", t.name)
+		if t.name == "clients" {
+			ids += " UNION ALL SELECT id FROM clients_archive"
+		}
+		_, err := dst.ExecContext(ctx, fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM (%s) ids", t.name, ids))
+		if err != nil {
+			return fmt.Errorf("reset %s id sequence: %w", t.name, err)
+		}
+	}
+
+	return nil
+}
+
+// tableChecksum возвращает число строк таблицы и контрольную сумму
+// SHA-256 её значений в порядке первичного ключа. Значения приводятся к
+// общему виду, поэтому суммы одинаковых данных в SQLite и Postgres
+// совпадают.
+func tableChecksum(ctx context.Context, q querier, t transferTable, columns []string) (int64, string, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %q ORDER BY %s",
+		strings.Join(quoteIdents(columns), ", "), t.name, strings.Join(quoteIdents(t.key), ", ")))
+	if err != nil {
+		return 0, "", err
+	}
+	defer rows.Close()
+
+	var (
+		n      int64
+		sum    = sha256.New()
+		values = make([]any, len(columns))
+		dest   = make([]any, len(columns))
+	)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return 0, "", err
+		}
+		for _, v := range values {
+			writeChecksumValue(sum, v)
+		}
+		sum.Write([]byte{'\n'})
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+
+	return n, hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// writeChecksumValue добавляет в сумму значение с префиксом типа и длиной,
+// чтобы соседние значения нельзя было склеить по-другому.
+func writeChecksumValue(sum hash.Hash, v any) {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		s = "n"
+	case int64:
+		s = "i" + strconv.FormatInt(v, 10)
+	case float64:
+		s = "f" + strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		s = "s" + string(v)
+	case string:
+		s = "s" + v
+	default:
+		s = fmt.Sprintf("?%v", v)
+	}
+	fmt.Fprintf(sum, "%d:%s", len(s), s)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedTransferSource заполняет БД клиентами с пропусками в ID и строками
+// связанных таблиц и возвращает число клиентов.
+func seedTransferSource(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, db))
+	require.NoError(t, SetEnvironment(ctx, db, EnvStaging))
+	repo := NewRepository(db)
+
+	var ids []int
+	for i, cl := range fakeClients(1, 25) {
+		id, err := repo.Insert(ctx, cl)
+		require.NoError(t, err)
+		ids = append(ids, id)
+		if i%3 == 0 {
+			_, err := repo.AddNote(ctx, id, fmt.Sprintf("note %d", i))
+			require.NoError(t, err)
+			require.NoError(t, repo.TagClient(ctx, id, "vip"))
+		}
+		if i%4 == 0 {
+			_, err := db.Exec("INSERT INTO orders (client_id, amount, created_at) VALUES (?, ?, '2025-01-01T00:00:00Z')", id, 100*i)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, repo.TagClient(ctx, ids[1], "new"))
+	require.NoError(t, repo.SetLegalHold(ctx, ids[2], true))
+	for _, id := range []int{ids[5], ids[7]} {
+		require.NoError(t, repo.Delete(ctx, id))
+	}
+
+	return len(ids) - 2
+}
+
+// Тест проверяет перенос БД с сохранением ID и данных связанных таблиц и
+// продолжение ID в целевой БД после переноса
+func Test_CopyDatabase(t *testing.T) {
+	ctx := context.Background()
+	src, dst := openMemoryDB(t), openMemoryDB(t)
+	clients := seedTransferSource(t, src)
+
+	report, err := CopyDatabase(ctx, src, dst, TransferOptions{BatchSize: 7})
+	require.NoError(t, err)
+	require.Len(t, report.Tables, len(transferTables))
+	assert.Equal(t, "clients", report.Tables[0].Table)
+	assert.EqualValues(t, clients, report.Tables[0].Copied)
+	assert.EqualValues(t, clients, report.Tables[0].Rows)
+	assert.NotEmpty(t, report.Tables[0].Checksum)
+
+	assert.Equal(t, dumpClients(t, src), dumpClients(t, dst))
+	for _, table := range []string{"client_notes", "client_tags", "orders", "audit_log"} {
+		_, want, err := tableChecksum(ctx, src, transferTableByName(t, table), mustColumns(t, src, table))
+		require.NoError(t, err)
+		_, got, err := tableChecksum(ctx, dst, transferTableByName(t, table), mustColumns(t, src, table))
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "table %s", table)
+	}
+	env, err := DatabaseEnvironment(ctx, dst)
+	require.NoError(t, err)
+	assert.Equal(t, EnvStaging, env)
+
+	var maxID int
+	require.NoError(t, src.QueryRow("SELECT MAX(id) FROM clients").Scan(&maxID))
+	id, err := NewRepository(dst).Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	assert.Greater(t, id, maxID, "new clients should not reuse transferred IDs")
+}
+
+// Тест проверяет продолжение прерванного переноса: уже перенесённые
+// порции не переносятся повторно, а итог совпадает с исходной БД
+func Test_CopyDatabase_Resume(t *testing.T) {
+	ctx := context.Background()
+	src, dst := openMemoryDB(t), openMemoryDB(t)
+	clients := seedTransferSource(t, src)
+
+	interrupted, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var batches int
+	_, err := CopyDatabase(interrupted, src, dst, TransferOptions{
+		BatchSize: 5,
+		Progress: func(p TransferProgress) {
+			batches++
+			if batches == 2 {
+				cancel()
+			}
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	var progress []TransferProgress
+	report, err := CopyDatabase(ctx, src, dst, TransferOptions{
+		BatchSize: 5,
+		Progress:  func(p TransferProgress) { progress = append(progress, p) },
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, clients-10, report.Tables[0].Copied, "resumed copy should skip committed batches")
+	assert.EqualValues(t, clients, report.Tables[0].Rows)
+	require.NotEmpty(t, progress)
+	assert.Equal(t, TransferProgress{Table: "clients", Copied: 15}, progress[0])
+	assert.Equal(t, dumpClients(t, src), dumpClients(t, dst))
+
+	// Повторный запуск после завершения ничего не переносит
+	report, err = CopyDatabase(ctx, src, dst, TransferOptions{})
+	require.NoError(t, err)
+	for _, table := range report.Tables {
+		assert.Zero(t, table.Copied, "table %s", table.Table)
+	}
+}
+
+// Тест проверяет, что расхождение данных после переноса обнаруживается
+// сравнением контрольных сумм
+func Test_CopyDatabase_Mismatch(t *testing.T) {
+	ctx := context.Background()
+	src, dst := openMemoryDB(t), openMemoryDB(t)
+	seedTransferSource(t, src)
+
+	_, err := CopyDatabase(ctx, src, dst, TransferOptions{})
+	require.NoError(t, err)
+	_, err = dst.Exec("UPDATE clients SET email = 'changed@mail.com' WHERE id = (SELECT MIN(id) FROM clients)")
+	require.NoError(t, err)
+
+	_, err = CopyDatabase(ctx, src, dst, TransferOptions{})
+	require.ErrorIs(t, err, ErrTransferMismatch)
+	assert.Contains(t, err.Error(), "clients checksum differs")
+}
+
+// Тест проверяет отказ переноса: отрицательный размер порции, исходная
+// БД без последней миграции и непустая целевая таблица без хода переноса
+func Test_CopyDatabase_Errors(t *testing.T) {
+	ctx := context.Background()
+	src, dst := openMemoryDB(t), openMemoryDB(t)
+
+	_, err := CopyDatabase(ctx, src, dst, TransferOptions{BatchSize: -1})
+	require.ErrorIs(t, err, ErrValidation)
+
+	require.NoError(t, MigrateTo(ctx, src, 16))
+	_, err = CopyDatabase(ctx, src, dst, TransferOptions{})
+	require.ErrorIs(t, err, ErrValidation)
+
+	require.NoError(t, Migrate(ctx, src))
+	require.NoError(t, Migrate(ctx, dst))
+	_, err = NewRepository(dst).Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	_, err = CopyDatabase(ctx, src, dst, TransferOptions{})
+	require.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "clients is not empty")
+}
+
+func transferTableByName(t *testing.T, name string) transferTable {
+	t.Helper()
+
+	for _, table := range transferTables {
+		if table.name == name {
+			return table
+		}
+	}
+	t.Fatalf("unknown transfer table %s", name)
+
+	return transferTable{}
+}
+
+func mustColumns(t *testing.T, db *sql.DB, table string) []string {
+	t.Helper()
+
+	columns, err := tableColumns(context.Background(), db, table)
+	require.NoError(t, err)
+
+	return columns
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения полей клиента; длины соответствуют столбцам таблицы clients
+// и считаются в символах.
+const (
+	maxFIOLen      = 128
+	maxLoginLen    = 32
+	maxEmailLen    = 64
+	birthdayLayout = "20060102"
+)
+
+// minBirthday — самая ранняя допустимая дата рождения.
+var minBirthday = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ValidationRule — правило проверки поля клиента, которое нарушает FieldError.
+type ValidationRule string
+
+const (
+	RuleRequired    ValidationRule = "required"
+	RuleInvalidUTF8 ValidationRule = "invalid_utf8"
+	RuleTooLong     ValidationRule = "too_long"
+	RuleInvalidDate ValidationRule = "invalid_date"
+	RuleOutOfRange  ValidationRule = "out_of_range"
+)
+
+// FieldError — ошибка проверки поля клиента; оборачивает ErrValidation.
+// Error возвращает сообщение на английском для журналов и разбора
+// ошибок, а сообщение для пользователя на его языке строит LocalizeError.
+// Значения полей в Error не попадают: сообщение уходит в журналы и span
+// трассировки, а дата рождения — персональные данные.
+type FieldError struct {
+	// Field — имя поля: fio, login, birthday или email.
+	Field string
+	Rule  ValidationRule
+	// Value — отклонённое значение для RuleInvalidDate и RuleOutOfRange;
+	// в Error не выводится.
+	Value string
+	// Len и Max — длина значения и наибольшая допустимая для RuleTooLong.
+	Len, Max int
+}
+
+func (e *FieldError) Error() string {
+	var detail string
+	switch e.Rule {
+	case RuleRequired:
+		detail = fmt.Sprintf("%s is required", e.Field)
+	case RuleInvalidUTF8:
+		detail = fmt.Sprintf("%s is not valid UTF-8", e.Field)
+	case RuleTooLong:
+		detail = fmt.Sprintf("%s is %d characters long, max %d", e.Field, e.Len, e.Max)
+	case RuleInvalidDate:
+		detail = fmt.Sprintf("%s is not a valid YYYYMMDD date", e.Field)
+	case RuleOutOfRange:
+		detail = fmt.Sprintf("%s is out of range", e.Field)
+	default:
+		detail = fmt.Sprintf("%s violates rule %s", e.Field, e.Rule)
+	}
+
+	return ErrValidation.Error() + ": " + detail
+}
+
+func (e *FieldError) Unwrap() error {
+	return ErrValidation
+}
+
+// Validate проверяет, что все поля клиента заполнены корректным UTF-8 и
+// не длиннее столбцов БД, а дата рождения — существующая дата в формате
+// ГГГГММДД не раньше 1900 года и не в будущем. Ошибка оборачивает
+// ErrValidation.
+func (c Client) Validate() error {
+	return c.validateAt(time.Now())
+}
+
+// validateAt выполняет проверки Validate, считая текущим временем now.
+func (c Client) validateAt(now time.Time) error {
+	fields := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"fio", c.FIO, maxFIOLen},
+		{"login", c.Login, maxLoginLen},
+		{"email", c.Email, maxEmailLen},
+	}
+	for _, f := range fields {
+		if err := validateField(f.name, f.value, f.max); err != nil {
+			return err
+		}
+	}
+
+	if _, err := parseBirthday(c.Birthday, now); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateField проверяет, что текстовое поле name заполнено корректным
+// UTF-8 и не длиннее max символов.
+func validateField(name, value string, max int) error {
+	if strings.TrimSpace(value) == "" {
+		return &FieldError{Field: name, Rule: RuleRequired}
+	}
+	if !utf8.ValidString(value) {
+		return &FieldError{Field: name, Rule: RuleInvalidUTF8}
+	}
+	if n := utf8.RuneCountInString(value); n > max {
+		return &FieldError{Field: name, Rule: RuleTooLong, Len: n, Max: max}
+	}
+
+	return nil
+}
+
+// parseBirthday разбирает дату рождения в формате ГГГГММДД; дата не может
+// быть позже now.
+func parseBirthday(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, &FieldError{Field: "birthday", Rule: RuleRequired}
+	}
+
+	birthday, err := time.Parse(birthdayLayout, s)
+	if err != nil {
+		return time.Time{}, &FieldError{Field: "birthday", Rule: RuleInvalidDate, Value: s}
+	}
+	if birthday.Before(minBirthday) || birthday.After(now.UTC()) {
+		return time.Time{}, &FieldError{Field: "birthday", Rule: RuleOutOfRange, Value: s}
+	}
+
+	return birthday, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Фаззинг разбора даты рождения: разбор не паникует, а принятая дата
+// лежит в допустимом диапазоне и форматируется обратно в исходную строку
+func FuzzParseBirthday(f *testing.F) {
+	for _, seed := range []string{"19700101", "20000229", "19990229", "19000101", "18991231", "99991231", "1970-01-01", "", "0", "197001011"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		birthday, err := parseBirthday(s, time.Now())
+		if err != nil {
+			require.ErrorIs(t, err, ErrValidation)
+			return
+		}
+
+		assert.Equal(t, s, birthday.Format(birthdayLayout), "accepted birthday should round-trip")
+		assert.False(t, birthday.Before(minBirthday), "accepted birthday %q is before %v", s, minBirthday)
+	})
+}
+
+// Тест проверяет, что дата рождения «в будущем» определяется относительно
+// переданного времени, а не системных часов
+func Test_ValidateAt_BirthdayRelativeToNow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC)
+
+	cl := newTestClient(func(cl *Client) { cl.Birthday = "20240301" })
+	assert.NoError(t, cl.validateAt(now), "birthday today should be accepted")
+
+	cl.Birthday = "20240302"
+	assert.ErrorIs(t, cl.validateAt(now), ErrValidation, "birthday tomorrow should be rejected")
+}
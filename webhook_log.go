@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WebhookAttempt — попытка доставки вебхука из журнала попыток.
+type WebhookAttempt struct {
+	ID          int64     `json:"id"`
+	DeliveryID  int64     `json:"delivery_id"`
+	AttemptedAt time.Time `json:"attempted_at"`
+	Succeeded   bool      `json:"succeeded"`
+	// StatusCode — код ответа точки; 0, если ответа не было.
+	StatusCode int `json:"status_code"`
+	// Latency — время от отправки запроса до ответа или ошибки с
+	// точностью до миллисекунды.
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// WebhookAttempts возвращает журнал попыток доставки id в порядке
+// выполнения или ErrWebhookDeliveryNotFound, если доставки нет.
+func (r *Repository) WebhookAttempts(ctx context.Context, id int64) (_ []WebhookAttempt, err error) {
+	ctx, end := r.startOperation(ctx, "webhook_attempts")
+	defer end(&err)
+
+	var exists int
+	err = r.conn().QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries WHERE id = :id", sql.Named("id", id)).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrWebhookDeliveryNotFound, id)
+	}
+
+	rows, err := r.conn().QueryContext(ctx, `SELECT id, delivery_id, attempted_at, succeeded, status_code, latency_ms, error
+	FROM webhook_attempts WHERE delivery_id = :id ORDER BY id`, sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []WebhookAttempt
+	for rows.Next() {
+		var (
+			a           WebhookAttempt
+			attemptedAt string
+			latencyMS   int64
+		)
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &attemptedAt, &a.Succeeded, &a.StatusCode, &latencyMS, &a.Error); err != nil {
+			return nil, err
+		}
+		if a.AttemptedAt, err = time.Parse(time.RFC3339, attemptedAt); err != nil {
+			return nil, err
+		}
+		a.Latency = time.Duration(latencyMS) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+
+	return attempts, rows.Err()
+}
+
+// WebhookFailures возвращает неудавшиеся доставки по возрастанию ID:
+// исчерпавшие попытки (WebhookDead) и ожидающие повтора после неудачной
+// попытки.
+func (r *Repository) WebhookFailures(ctx context.Context) (_ []WebhookDelivery, err error) {
+	ctx, end := r.startOperation(ctx, "webhook_failures")
+	defer end(&err)
+
+	return r.webhookDeliveries(ctx, "status = :dead OR (status = :pending AND attempts > 0)",
+		sql.Named("dead", WebhookDead),
+		sql.Named("pending", WebhookPending))
+}
+
+// RedeliverWebhooks ставит доставки ids в очередь на немедленную
+// отправку независимо от их состояния: доставка становится WebhookPending
+// с обнулённым счётчиком попыток, журнал прежних попыток сохраняется.
+// Если какой-либо доставки нет, ни одна не меняется и возвращается
+// ErrWebhookDeliveryNotFound.
+func (r *Repository) RedeliverWebhooks(ctx context.Context, ids ...int64) (err error) {
+	ctx, end := r.startOperation(ctx, "redeliver_webhooks")
+	defer end(&err)
+
+	now := formatTime(r.now())
+	return r.inTx(ctx, func(q querier) error {
+		for _, id := range ids {
+			res, err := q.ExecContext(ctx, `UPDATE webhook_deliveries
+	SET status = :status, attempts = 0, next_attempt_at = :now WHERE id = :id`,
+				sql.Named("status", WebhookPending),
+				sql.Named("now", now),
+				sql.Named("id", id))
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("%w: %d", ErrWebhookDeliveryNotFound, id)
+			}
+		}
+
+		return nil
+	})
+}
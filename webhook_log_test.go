@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupDeadWebhook создаёт доставку, исчерпавшую попытки из-за отказов
+// получателя, и возвращает репозиторий, доставку и функцию восстановления
+// получателя.
+func setupDeadWebhook(t *testing.T) (*Repository, *WebhookDispatcher, *webhookReceiver, WebhookDelivery, func()) {
+	t.Helper()
+
+	ctx := context.Background()
+	repo, clock := setupWebhooks(t)
+	var recovered atomic.Bool
+	rcv := newWebhookReceiver(t, func(int) bool { return !recovered.Load() })
+	_, err := repo.RegisterWebhook(ctx, rcv.URL, testWebhookSecret, nil)
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	dispatcher := NewWebhookDispatcher(repo, WebhookConfig{MaxAttempts: 2, Backoff: time.Minute})
+	_, err = NewOutboxRelay(repo, dispatcher).Relay(ctx)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := dispatcher.Deliver(ctx)
+		require.NoError(t, err)
+		clock.Advance(time.Hour)
+	}
+
+	dead, err := repo.DeadWebhookDeliveries(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+
+	return repo, dispatcher, rcv, dead[0], func() { recovered.Store(true) }
+}
+
+// Тест проверяет журнал попыток доставки и повторную доставку вручную
+// после восстановления получателя
+func Test_RedeliverWebhooks(t *testing.T) {
+	ctx := context.Background()
+	repo, dispatcher, rcv, dead, recoverReceiver := setupDeadWebhook(t)
+
+	failures, err := repo.WebhookFailures(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []WebhookDelivery{dead}, failures)
+
+	attempts, err := repo.WebhookAttempts(ctx, dead.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	for _, a := range attempts {
+		assert.Equal(t, dead.ID, a.DeliveryID)
+		assert.False(t, a.Succeeded)
+		assert.Equal(t, http.StatusInternalServerError, a.StatusCode)
+		assert.GreaterOrEqual(t, a.Latency, time.Duration(0))
+		assert.Equal(t, "unexpected status 500 Internal Server Error", a.Error)
+	}
+	assert.Equal(t, time.Hour, attempts[1].AttemptedAt.Sub(attempts[0].AttemptedAt))
+
+	// Доставка в dead letter не отправляется, пока её не поставят в очередь
+	recoverReceiver()
+	n, err := dispatcher.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, repo.RedeliverWebhooks(ctx, dead.ID))
+	n, err = dispatcher.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, rcv.payloads(), 1)
+	assert.Equal(t, dead.EventID, rcv.payloads()[0].EventID)
+
+	attempts, err = repo.WebhookAttempts(ctx, dead.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 3)
+	assert.True(t, attempts[2].Succeeded)
+	assert.Equal(t, http.StatusOK, attempts[2].StatusCode)
+	assert.Empty(t, attempts[2].Error)
+
+	failures, err = repo.WebhookFailures(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+// Тест проверяет, что повторная доставка с несуществующим ID не меняет
+// ни одной доставки
+func Test_RedeliverWebhooks_NotFound(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _, dead, _ := setupDeadWebhook(t)
+
+	err := repo.RedeliverWebhooks(ctx, dead.ID, dead.ID+100)
+	require.ErrorIs(t, err, ErrWebhookDeliveryNotFound)
+
+	failures, err := repo.WebhookFailures(ctx)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, WebhookDead, failures[0].Status)
+
+	_, err = repo.WebhookAttempts(ctx, dead.ID+100)
+	require.ErrorIs(t, err, ErrWebhookDeliveryNotFound)
+}
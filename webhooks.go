@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Типы событий вебхуков.
+const (
+	WebhookClientCreated = "client.created"
+	WebhookClientUpdated = "client.updated"
+	WebhookClientDeleted = "client.deleted"
+)
+
+// webhookEventTypes — допустимые типы событий подписки.
+var webhookEventTypes = map[string]bool{
+	WebhookClientCreated: true,
+	WebhookClientUpdated: true,
+	WebhookClientDeleted: true,
+}
+
+// Заголовки запроса доставки вебхука.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Состояния доставки вебхука.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	// WebhookDead — доставка, исчерпавшая попытки (dead letter); повторно
+	// она не отправляется.
+	WebhookDead = "dead"
+)
+
+// Значения WebhookConfig по умолчанию.
+const (
+	DefaultWebhookAttempts   = 8
+	DefaultWebhookBackoff    = 30 * time.Second
+	DefaultWebhookMaxBackoff = time.Hour
+	DefaultWebhookBatch      = 100
+	DefaultWebhookTimeout    = 10 * time.Second
+)
+
+// WebhookEndpoint — точка доставки вебхуков. Секрет подписи не
+// возвращается после регистрации.
+type WebhookEndpoint struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Events — типы событий подписки; пустой список — все события.
+	Events    []string  `json:"events,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookPayload — тело запроса доставки вебхука. Как и ClientEvent, не
+// содержит PII.
+type WebhookPayload struct {
+	// EventID — ID события outbox; по нему получатель отбрасывает повторы.
+	EventID    int64     `json:"event_id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	ClientID   int       `json:"client_id"`
+	Fields     []string  `json:"fields,omitempty"`
+}
+
+// WebhookDelivery — доставка события в точку.
+type WebhookDelivery struct {
+	ID         int64  `json:"id"`
+	EndpointID int    `json:"endpoint_id"`
+	EventID    int64  `json:"event_id"`
+	EventType  string `json:"event_type"`
+	Status     string `json:"status"`
+	Attempts   int    `json:"attempts"`
+	// NextAttemptAt — время следующей попытки доставки в состоянии
+	// WebhookPending.
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RegisterWebhook регистрирует точку доставки url, подписывающую запросы
+// секретом secret, на события events (пустой список — все события).
+func (r *Repository) RegisterWebhook(ctx context.Context, rawURL, secret string, events []string) (_ WebhookEndpoint, err error) {
+	ctx, end := r.startOperation(ctx, "register_webhook")
+	defer end(&err)
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return WebhookEndpoint{}, fmt.Errorf("%w: webhook url %q must be an absolute http(s) URL", ErrValidation, rawURL)
+	}
+	if secret == "" {
+		return WebhookEndpoint{}, fmt.Errorf("%w: webhook secret is required", ErrValidation)
+	}
+	for _, event := range events {
+		if !webhookEventTypes[event] {
+			return WebhookEndpoint{}, fmt.Errorf("%w: unknown webhook event %q", ErrValidation, event)
+		}
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	if len(events) == 0 {
+		data = []byte("[]")
+	}
+
+	endpoint := WebhookEndpoint{URL: rawURL, Events: events, CreatedAt: r.now().Truncate(time.Second)}
+	res, err := r.conn().ExecContext(ctx, "INSERT INTO webhook_endpoints (url, secret, events, created_at) VALUES (:url, :secret, :events, :created_at)",
+		sql.Named("url", rawURL),
+		sql.Named("secret", secret),
+		sql.Named("events", string(data)),
+		sql.Named("created_at", formatTime(endpoint.CreatedAt)))
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	endpoint.ID = int(id)
+
+	return endpoint, nil
+}
+
+// Webhooks возвращает зарегистрированные точки доставки по возрастанию ID.
+func (r *Repository) Webhooks(ctx context.Context) (_ []WebhookEndpoint, err error) {
+	ctx, end := r.startOperation(ctx, "webhooks")
+	defer end(&err)
+
+	rows, err := r.conn().QueryContext(ctx, "SELECT id, url, events, created_at FROM webhook_endpoints ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []WebhookEndpoint
+	for rows.Next() {
+		var (
+			endpoint          WebhookEndpoint
+			events, createdAt string
+		)
+		if err := rows.Scan(&endpoint.ID, &endpoint.URL, &events, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &endpoint.Events); err != nil {
+			return nil, err
+		}
+		if len(endpoint.Events) == 0 {
+			endpoint.Events = nil
+		}
+		if endpoint.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, rows.Err()
+}
+
+// DeleteWebhook удаляет точку доставки id вместе с её доставками и их
+// попытками или возвращает ErrWebhookNotFound.
+func (r *Repository) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, end := r.startOperation(ctx, "delete_webhook")
+	defer end(&err)
+
+	return r.inTx(ctx, func(q querier) error {
+		_, err := q.ExecContext(ctx, "DELETE FROM webhook_attempts WHERE delivery_id IN (SELECT id FROM webhook_deliveries WHERE endpoint_id = :id)",
+			sql.Named("id", id))
+		if err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE endpoint_id = :id", sql.Named("id", id)); err != nil {
+			return err
+		}
+		res, err := q.ExecContext(ctx, "DELETE FROM webhook_endpoints WHERE id = :id", sql.Named("id", id))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: %d", ErrWebhookNotFound, id)
+		}
+
+		return nil
+	})
+}
+
+// DeadWebhookDeliveries возвращает доставки, исчерпавшие попытки, по
+// возрастанию ID.
+func (r *Repository) DeadWebhookDeliveries(ctx context.Context) (_ []WebhookDelivery, err error) {
+	ctx, end := r.startOperation(ctx, "dead_webhook_deliveries")
+	defer end(&err)
+
+	return r.webhookDeliveries(ctx, "status = :status", sql.Named("status", WebhookDead))
+}
+
+// webhookDeliveries возвращает доставки, подходящие под условие cond, по
+// возрастанию ID.
+func (r *Repository) webhookDeliveries(ctx context.Context, cond string, args ...any) ([]WebhookDelivery, error) {
+	rows, err := r.conn().QueryContext(ctx, `SELECT id, endpoint_id, event_id, event_type, status, attempts, next_attempt_at, last_error, created_at
+	FROM webhook_deliveries WHERE `+cond+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var (
+			d                        WebhookDelivery
+			nextAttemptAt, createdAt string
+		)
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &nextAttemptAt, &d.LastError, &createdAt); err != nil {
+			return nil, err
+		}
+		if d.NextAttemptAt, err = time.Parse(time.RFC3339, nextAttemptAt); err != nil {
+			return nil, err
+		}
+		if d.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// webhookEventType возвращает тип события вебхука для изменения op.
+func webhookEventType(op AuditOperation) string {
+	switch op {
+	case AuditInsert:
+		return WebhookClientCreated
+	case AuditDelete, AuditErase:
+		return WebhookClientDeleted
+	default:
+		return WebhookClientUpdated
+	}
+}
+
+// SignWebhookPayload возвращает подпись тела запроса body секретом secret
+// в формате заголовка WebhookSignatureHeader: «sha256=» и HMAC-SHA256 в
+// шестнадцатеричном виде.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature сообщает, что signature — подпись body секретом
+// secret. Сравнение выполняется за постоянное время.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, body)), []byte(signature))
+}
+
+// WebhookConfig — параметры WebhookDispatcher; нулевые значения заменяются
+// значениями по умолчанию.
+type WebhookConfig struct {
+	// Client отправляет запросы; по умолчанию — клиент с таймаутом
+	// DefaultWebhookTimeout.
+	Client *http.Client
+	// MaxAttempts — число попыток, после которого доставка становится
+	// WebhookDead.
+	MaxAttempts int
+	// Backoff — пауза после первой неудачной попытки; каждая следующая
+	// пауза вдвое длиннее, но не длиннее MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Batch — сколько доставок отправляется за одну синхронизацию.
+	Batch int
+}
+
+// WebhookDispatcher доставляет события клиентов в зарегистрированные
+// точки. Как EventSink для OutboxRelay он сохраняет по доставке на каждую
+// подписанную точку, а Deliver отправляет их POST-запросами с телом
+// WebhookPayload, подписанным секретом точки (см. SignWebhookPayload).
+// Доставка успешна при ответе 2xx; после неудачи она повторяется с
+// экспоненциально растущей паузой, а исчерпав попытки — остаётся в БД как
+// WebhookDead. На одну БД запускается один WebhookDispatcher.
+type WebhookDispatcher struct {
+	repo *Repository
+	cfg  WebhookConfig
+	// retry — паузы между попытками доставки и их число; повторяется
+	// любая неудача.
+	retry RetryPolicy
+
+	mu sync.Mutex
+}
+
+// NewWebhookDispatcher создаёт доставку вебхуков точек БД репозитория repo.
+func NewWebhookDispatcher(repo *Repository, cfg WebhookConfig) *WebhookDispatcher {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultWebhookAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultWebhookBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultWebhookMaxBackoff
+	}
+	if cfg.Batch <= 0 {
+		cfg.Batch = DefaultWebhookBatch
+	}
+
+	retry := RetryPolicy{MaxAttempts: cfg.MaxAttempts, Backoff: cfg.Backoff, MaxBackoff: cfg.MaxBackoff}
+
+	return &WebhookDispatcher{repo: repo, cfg: cfg, retry: retry}
+}
+
+// Publish сохраняет доставку события каждой подписанной на него точке.
+// Повторная публикация того же события доставок не добавляет.
+func (d *WebhookDispatcher) Publish(ctx context.Context, event ClientEvent) (err error) {
+	r := d.repo
+	ctx, end := r.startOperation(ctx, "webhook_publish")
+	defer end(&err)
+
+	payload := WebhookPayload{
+		EventID:    event.ID,
+		Type:       webhookEventType(event.Operation),
+		OccurredAt: event.OccurredAt,
+		ClientID:   event.ClientID,
+		Fields:     event.Fields,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := r.Webhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := formatTime(r.now())
+	return r.inTx(ctx, func(q querier) error {
+		for _, endpoint := range endpoints {
+			if !endpoint.subscribed(payload.Type) {
+				continue
+			}
+			_, err := q.ExecContext(ctx, `INSERT OR IGNORE INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, next_attempt_at, created_at)
+	VALUES (:endpoint_id, :event_id, :event_type, :payload, :now, :now)`,
+				sql.Named("endpoint_id", endpoint.ID),
+				sql.Named("event_id", event.ID),
+				sql.Named("event_type", payload.Type),
+				sql.Named("payload", string(body)),
+				sql.Named("now", now))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// subscribed сообщает, подписана ли точка на события типа eventType.
+func (e WebhookEndpoint) subscribed(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, event := range e.Events {
+		if event == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Deliver отправляет до WebhookConfig.Batch доставок, время попытки
+// которых наступило, и возвращает число успешных. Неудачная попытка не
+// считается ошибкой Deliver: она сохраняется в доставке и повторяется
+// позже.
+func (d *WebhookDispatcher) Deliver(ctx context.Context) (_ int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r := d.repo
+	ctx, end := r.startOperation(ctx, "webhook_deliver")
+	defer end(&err)
+
+	due, err := d.dueDeliveries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range due {
+		attempt := WebhookAttempt{DeliveryID: delivery.id, AttemptedAt: r.now().Truncate(time.Second)}
+		start := time.Now()
+		code, sendErr := d.send(ctx, delivery)
+		attempt.Latency = time.Since(start)
+		attempt.StatusCode = code
+		attempt.Succeeded = sendErr == nil
+		if sendErr != nil {
+			attempt.Error = sendErr.Error()
+		}
+
+		if err := d.record(ctx, delivery, attempt, sendErr); err != nil {
+			return delivered, err
+		}
+		if attempt.Succeeded {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// Run доставляет вебхуки каждые poll, пока не отменён ctx. Неудачные
+// попытки доставки повторяются по расписанию повторов и в onError не
+// попадают; туда (если он задан) передаются только ошибки чтения и записи
+// доставок в БД.
+func (d *WebhookDispatcher) Run(ctx context.Context, poll time.Duration, onError func(error)) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Deliver(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dueDelivery — доставка вместе с точкой, в которую она отправляется.
+type dueDelivery struct {
+	id        int64
+	eventType string
+	payload   string
+	attempts  int
+	url       string
+	secret    string
+}
+
+func (d *WebhookDispatcher) dueDeliveries(ctx context.Context) ([]dueDelivery, error) {
+	rows, err := d.repo.conn().QueryContext(ctx, `SELECT d.id, d.event_type, d.payload, d.attempts, e.url, e.secret
+	FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id
+	WHERE d.status = :status AND julianday(d.next_attempt_at) <= julianday(:now)
+	ORDER BY d.id LIMIT :limit`,
+		sql.Named("status", WebhookPending),
+		sql.Named("now", formatTime(d.repo.now())),
+		sql.Named("limit", d.cfg.Batch))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []dueDelivery
+	for rows.Next() {
+		var delivery dueDelivery
+		if err := rows.Scan(&delivery.id, &delivery.eventType, &delivery.payload, &delivery.attempts, &delivery.url, &delivery.secret); err != nil {
+			return nil, err
+		}
+		due = append(due, delivery)
+	}
+
+	return due, rows.Err()
+}
+
+// send отправляет доставку и возвращает код ответа точки (0, если ответа
+// нет) и ошибку, если точка не ответила 2xx.
+func (d *WebhookDispatcher) send(ctx context.Context, delivery dueDelivery) (int, error) {
+	body := []byte(delivery.payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.eventType)
+	req.Header.Set(WebhookDeliveryHeader, fmt.Sprint(delivery.id))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(delivery.secret, body))
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// record сохраняет попытку доставки в журнал и её результат в доставке:
+// успешная доставка становится WebhookDelivered, неудачная с ошибкой
+// sendErr откладывается на паузу политики повтора или, исчерпав попытки,
+// становится WebhookDead.
+func (d *WebhookDispatcher) record(ctx context.Context, delivery dueDelivery, attempt WebhookAttempt, sendErr error) error {
+	attempts := delivery.attempts + 1
+	status := WebhookDelivered
+	next := d.repo.now()
+	if !attempt.Succeeded {
+		status = WebhookPending
+		if !d.retry.ShouldRetry(sendErr, attempts) {
+			status = WebhookDead
+		}
+		next = next.Add(d.retry.Delay(attempts))
+	}
+
+	return d.repo.inTx(ctx, func(q querier) error {
+		_, err := q.ExecContext(ctx, `INSERT INTO webhook_attempts (delivery_id, attempted_at, succeeded, status_code, latency_ms, error)
+	VALUES (:delivery_id, :attempted_at, :succeeded, :status_code, :latency_ms, :error)`,
+			sql.Named("delivery_id", attempt.DeliveryID),
+			sql.Named("attempted_at", formatTime(attempt.AttemptedAt)),
+			sql.Named("succeeded", attempt.Succeeded),
+			sql.Named("status_code", attempt.StatusCode),
+			sql.Named("latency_ms", attempt.Latency.Milliseconds()),
+			sql.Named("error", attempt.Error))
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(ctx, `UPDATE webhook_deliveries
+	SET status = :status, attempts = :attempts, next_attempt_at = :next_attempt_at, last_error = :last_error WHERE id = :id`,
+			sql.Named("status", status),
+			sql.Named("attempts", attempts),
+			sql.Named("next_attempt_at", formatTime(next)),
+			sql.Named("last_error", attempt.Error),
+			sql.Named("id", delivery.id))
+
+		return err
+	})
+}
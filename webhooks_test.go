@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Yandex-Practicum/go-db-sql-query-test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// webhookReceiver — получатель вебхуков, который проверяет подпись и
+// отвечает ошибкой на запросы, для которых fail возвращает true.
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests int
+	// received — успешно принятые события.
+	received []WebhookPayload
+	fail     func(request int) bool
+}
+
+func newWebhookReceiver(t *testing.T, fail func(request int) bool) *webhookReceiver {
+	t.Helper()
+
+	rcv := &webhookReceiver{fail: fail}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		if !VerifyWebhookSignature(testWebhookSecret, body, req.Header.Get(WebhookSignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		rcv.requests++
+		if rcv.fail != nil && rcv.fail(rcv.requests) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var payload WebhookPayload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, payload.Type, req.Header.Get(WebhookEventHeader))
+		rcv.received = append(rcv.received, payload)
+	}))
+	t.Cleanup(rcv.Close)
+
+	return rcv
+}
+
+func (rcv *webhookReceiver) payloads() []WebhookPayload {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	return append([]WebhookPayload(nil), rcv.received...)
+}
+
+// setupWebhooks возвращает репозиторий после миграций и его часы.
+func setupWebhooks(t *testing.T) (*Repository, *testutil.FakeClock) {
+	t.Helper()
+
+	clock := testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	db := openMemoryDB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+
+	return NewRepository(db, WithClock(clock)), clock
+}
+
+// Тест проверяет регистрацию, перечисление и удаление точек доставки и
+// проверку их параметров
+func Test_RegisterWebhook(t *testing.T) {
+	ctx := context.Background()
+	repo, clock := setupWebhooks(t)
+
+	all, err := repo.RegisterWebhook(ctx, "https://example.com/hooks", testWebhookSecret, nil)
+	require.NoError(t, err)
+	deleted, err := repo.RegisterWebhook(ctx, "http://localhost:8080/deleted", testWebhookSecret, []string{WebhookClientDeleted})
+	require.NoError(t, err)
+
+	endpoints, err := repo.Webhooks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []WebhookEndpoint{
+		{ID: all.ID, URL: "https://example.com/hooks", CreatedAt: clock.Now()},
+		{ID: deleted.ID, URL: "http://localhost:8080/deleted", Events: []string{WebhookClientDeleted}, CreatedAt: clock.Now()},
+	}, endpoints)
+
+	for name, tc := range map[string]struct {
+		url, secret string
+		events      []string
+	}{
+		"relative url":  {"/hooks", testWebhookSecret, nil},
+		"ftp url":       {"ftp://example.com/hooks", testWebhookSecret, nil},
+		"empty secret":  {"https://example.com/hooks", "", nil},
+		"unknown event": {"https://example.com/hooks", testWebhookSecret, []string{"order.created"}},
+	} {
+		_, err := repo.RegisterWebhook(ctx, tc.url, tc.secret, tc.events)
+		assert.ErrorIs(t, err, ErrValidation, name)
+	}
+
+	require.NoError(t, repo.DeleteWebhook(ctx, all.ID))
+	require.ErrorIs(t, repo.DeleteWebhook(ctx, all.ID), ErrWebhookNotFound)
+	endpoints, err = repo.Webhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, deleted.ID, endpoints[0].ID)
+}
+
+// Тест проверяет доставку событий из outbox подписанным точкам с
+// подписью секретом точки и без повторной доставки при повторной
+// публикации события
+func Test_WebhookDispatcher_Deliver(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := setupWebhooks(t)
+	all := newWebhookReceiver(t, nil)
+	deleted := newWebhookReceiver(t, nil)
+	_, err := repo.RegisterWebhook(ctx, all.URL, testWebhookSecret, nil)
+	require.NoError(t, err)
+	_, err = repo.RegisterWebhook(ctx, deleted.URL, testWebhookSecret, []string{WebhookClientDeleted})
+	require.NoError(t, err)
+
+	id, err := repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+	cl, err := repo.Select(ctx, id)
+	require.NoError(t, err)
+	cl.FIO = "Updated"
+	require.NoError(t, repo.Update(ctx, cl))
+	require.NoError(t, repo.Delete(ctx, id))
+
+	dispatcher := NewWebhookDispatcher(repo, WebhookConfig{})
+	events, err := repo.pendingEvents(ctx, DefaultOutboxBatch)
+	require.NoError(t, err)
+	_, err = NewOutboxRelay(repo, dispatcher).Relay(ctx)
+	require.NoError(t, err)
+	// Повторная публикация (доставка outbox «хотя бы один раз»)
+	for _, event := range events {
+		require.NoError(t, dispatcher.Publish(ctx, event))
+	}
+
+	n, err := dispatcher.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	got := all.payloads()
+	require.Len(t, got, 3)
+	for i, want := range []string{WebhookClientCreated, WebhookClientUpdated, WebhookClientDeleted} {
+		assert.Equal(t, want, got[i].Type)
+		assert.Equal(t, id, got[i].ClientID)
+		assert.Equal(t, events[i].ID, got[i].EventID)
+	}
+	assert.Equal(t, []string{"fio"}, got[1].Fields)
+	require.Len(t, deleted.payloads(), 1)
+	assert.Equal(t, WebhookClientDeleted, deleted.payloads()[0].Type)
+
+	n, err = dispatcher.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+// Тест проверяет повтор неудачных доставок с экспоненциально растущей
+// паузой, пока получатель не ответит успешно
+func Test_WebhookDispatcher_Retry(t *testing.T) {
+	ctx := context.Background()
+	repo, clock := setupWebhooks(t)
+	// Получатель отвечает ошибкой на первые два запроса
+	rcv := newWebhookReceiver(t, func(request int) bool { return request <= 2 })
+	_, err := repo.RegisterWebhook(ctx, rcv.URL, testWebhookSecret, nil)
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	dispatcher := NewWebhookDispatcher(repo, WebhookConfig{Backoff: time.Minute, MaxBackoff: 90 * time.Second})
+	_, err = NewOutboxRelay(repo, dispatcher).Relay(ctx)
+	require.NoError(t, err)
+
+	// Каждая неудачная попытка откладывает следующую на паузу: минута,
+	// затем две, ограниченные MaxBackoff
+	for _, pause := range []time.Duration{time.Minute, 90 * time.Second} {
+		start := clock.Now()
+		n, err := dispatcher.Deliver(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		pending, err := repo.webhookDeliveries(ctx, "status = 'pending'")
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, start.Add(pause), pending[0].NextAttemptAt)
+		assert.Equal(t, "unexpected status 500 Internal Server Error", pending[0].LastError)
+
+		// До наступления времени попытки доставка не отправляется
+		clock.Advance(pause - time.Second)
+		n, err = dispatcher.Deliver(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		clock.Advance(time.Second)
+	}
+
+	n, err := dispatcher.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, rcv.payloads(), 1)
+
+	delivered, err := repo.webhookDeliveries(ctx, "status = 'delivered'")
+	require.NoError(t, err)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, 3, delivered[0].Attempts)
+	assert.Empty(t, delivered[0].LastError)
+}
+
+// Тест проверяет, что при периодически отказывающем получателе каждое
+// событие в итоге доставляется ровно один раз
+func Test_WebhookDispatcher_Intermittent(t *testing.T) {
+	ctx := context.Background()
+	repo, clock := setupWebhooks(t)
+	rcv := newWebhookReceiver(t, func(request int) bool { return request%3 != 0 })
+	_, err := repo.RegisterWebhook(ctx, rcv.URL, testWebhookSecret, nil)
+	require.NoError(t, err)
+	ids := make(map[int]bool)
+	for i := 0; i < 5; i++ {
+		id, err := repo.Insert(ctx, newTestClient())
+		require.NoError(t, err)
+		ids[id] = true
+	}
+
+	dispatcher := NewWebhookDispatcher(repo, WebhookConfig{MaxAttempts: 20, Backoff: time.Second, MaxBackoff: time.Second})
+	_, err = NewOutboxRelay(repo, dispatcher).Relay(ctx)
+	require.NoError(t, err)
+
+	total := 0
+	for i := 0; i < 20 && total < len(ids); i++ {
+		n, err := dispatcher.Deliver(ctx)
+		require.NoError(t, err)
+		total += n
+		clock.Advance(time.Second)
+	}
+	assert.Equal(t, len(ids), total)
+
+	got := make(map[int]bool)
+	for _, payload := range rcv.payloads() {
+		assert.False(t, got[payload.ClientID], "client %d delivered twice", payload.ClientID)
+		got[payload.ClientID] = true
+	}
+	assert.Equal(t, ids, got)
+
+	dead, err := repo.DeadWebhookDeliveries(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+}
+
+// Тест проверяет перенос доставки, исчерпавшей попытки, в dead letter:
+// она больше не отправляется и возвращается DeadWebhookDeliveries
+func Test_WebhookDispatcher_DeadLetter(t *testing.T) {
+	ctx := context.Background()
+	repo, clock := setupWebhooks(t)
+	rcv := newWebhookReceiver(t, func(int) bool { return true })
+	endpoint, err := repo.RegisterWebhook(ctx, rcv.URL, testWebhookSecret, nil)
+	require.NoError(t, err)
+	_, err = repo.Insert(ctx, newTestClient())
+	require.NoError(t, err)
+
+	dispatcher := NewWebhookDispatcher(repo, WebhookConfig{MaxAttempts: 3, Backoff: time.Second})
+	_, err = NewOutboxRelay(repo, dispatcher).Relay(ctx)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err := dispatcher.Deliver(ctx)
+		require.NoError(t, err)
+		clock.Advance(time.Hour)
+	}
+	rcv.mu.Lock()
+	assert.Equal(t, 3, rcv.requests)
+	rcv.mu.Unlock()
+	assert.Empty(t, rcv.payloads())
+
+	dead, err := repo.DeadWebhookDeliveries(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, endpoint.ID, dead[0].EndpointID)
+	assert.Equal(t, WebhookClientCreated, dead[0].EventType)
+	assert.Equal(t, WebhookDead, dead[0].Status)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Equal(t, "unexpected status 500 Internal Server Error", dead[0].LastError)
+}
+
+// Тест проверяет подпись тела запроса и её проверку
+func Test_SignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event_id":1}`)
+	signature := SignWebhookPayload(testWebhookSecret, body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.True(t, VerifyWebhookSignature(testWebhookSecret, body, signature))
+	assert.False(t, VerifyWebhookSignature("other", body, signature))
+	assert.False(t, VerifyWebhookSignature(testWebhookSecret, []byte(`{"event_id":2}`), signature))
+}